@@ -0,0 +1,170 @@
+package dicescript
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// momentsItem 分析时栈上的一项，记录其期望和方差
+type momentsItem struct {
+	mean     float64
+	variance float64
+}
+
+func (m momentsItem) isConst() bool {
+	return m.variance == 0
+}
+
+// analyzeMoments 精确计算语句结果的期望和方差。
+// 只支持常数、普通骰子(不含取高取低等)以及加减法和与常数的乘法，各骰子视为相互独立；其他情况ok为false。
+// defaultTimes为省略个数的骰子的个数
+func analyzeMoments(code []ByteCode, defaultTimes IntType) (mean float64, variance float64, ok bool) {
+	var stack []momentsItem
+	pop := func() (momentsItem, bool) {
+		if len(stack) == 0 {
+			return momentsItem{}, false
+		}
+		v := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		return v, true
+	}
+	if defaultTimes <= 0 {
+		defaultTimes = 1
+	}
+	times := float64(defaultTimes)
+
+	for _, c := range code {
+		switch c.T {
+		case typePushIntNumber:
+			stack = append(stack, momentsItem{mean: float64(c.Value.(IntType))})
+		case typePushFloatNumber:
+			stack = append(stack, momentsItem{mean: c.Value.(float64)})
+		case typeDetailMark, typeNop:
+		case typeDiceInit:
+			times = float64(defaultTimes)
+		case typeDiceSetTimes:
+			v, ok := pop()
+			if !ok || !v.isConst() || v.mean < 0 {
+				return 0, 0, false
+			}
+			times = v.mean
+		case typeDice:
+			v, ok := pop()
+			if !ok || !v.isConst() || v.mean < 1 {
+				return 0, 0, false
+			}
+			// 单个dN的期望为(N+1)/2，方差为(N²-1)/12
+			sides := v.mean
+			stack = append(stack, momentsItem{mean: times * (sides + 1) / 2, variance: times * (sides*sides - 1) / 12})
+		case typeAdd, typeSubtract:
+			b, ok1 := pop()
+			a, ok2 := pop()
+			if !ok1 || !ok2 {
+				return 0, 0, false
+			}
+			if c.T == typeSubtract {
+				b.mean = -b.mean
+			}
+			stack = append(stack, momentsItem{mean: a.mean + b.mean, variance: a.variance + b.variance})
+		case typeMultiply:
+			b, ok1 := pop()
+			a, ok2 := pop()
+			if !ok1 || !ok2 {
+				return 0, 0, false
+			}
+			if !a.isConst() && !b.isConst() {
+				return 0, 0, false
+			}
+			if !b.isConst() {
+				a, b = b, a
+			}
+			stack = append(stack, momentsItem{mean: a.mean * b.mean, variance: a.variance * b.mean * b.mean})
+		case typeNegation:
+			v, ok := pop()
+			if !ok {
+				return 0, 0, false
+			}
+			stack = append(stack, momentsItem{mean: -v.mean, variance: v.variance})
+		case typePositive:
+		case typeHalt:
+			if len(stack) != 1 {
+				return 0, 0, false
+			}
+			return stack[0].mean, stack[0].variance, true
+		default:
+			return 0, 0, false
+		}
+	}
+	return 0, 0, false
+}
+
+// simulateMoments 多次执行语句，以样本估计期望和方差，消耗的算力计入ctx
+func (ctx *Context) simulateMoments(vm *Context, samples int) (mean float64, variance float64, err error) {
+	var m2 float64
+	for i := 1; i <= samples; i++ {
+		vm.NumOpCount = 0
+		if err := vm.RunAfterParsed(); err != nil {
+			return 0, 0, err
+		}
+		ctx.NumOpCount += vm.NumOpCount
+		if ctx.Config.OpCountLimit > 0 && ctx.NumOpCount > ctx.Config.OpCountLimit {
+			return 0, 0, errOpCountLimit
+		}
+		x, ok := readNumber(operandValue(vm.Ret))
+		if !ok {
+			return 0, 0, fmt.Errorf("类型错误: 结果必须为数字，不能为 %s", vm.Ret.GetTypeName())
+		}
+		// Welford算法
+		delta := x - mean
+		mean += delta / float64(i)
+		m2 += delta * (x - mean)
+	}
+	return mean, m2 / float64(samples), nil
+}
+
+// exprMoments 计算表达式结果的期望和方差，能够精确计算时不进行模拟
+func (ctx *Context) exprMoments(params []*VMValue) (mean float64, variance float64, err error) {
+	expr, ok := params[0].ReadString()
+	if !ok {
+		return 0, 0, errors.New("类型错误: 表达式必须为str")
+	}
+	samples, ok := params[1].ReadInt()
+	if !ok || samples < 1 || samples > 100000 {
+		return 0, 0, errors.New("值错误: 模拟次数的范围是1到100000")
+	}
+
+	vm := NewVM()
+	vm.Config = ctx.Config
+	vm.Config.QuotaFunc = nil // 消耗的算力计入ctx，由ctx报告
+	vm.RandSrc = ctx.RandSrc
+	vm.builtins = ctx.builtins
+	if err := vm.Parse(expr); err != nil {
+		return 0, 0, err
+	}
+	if rest := strings.TrimSpace(string(vm.parser.data[vm.parser.pt.offset:])); rest != "" {
+		return 0, 0, fmt.Errorf("语法错误: 无法解析 %s", rest)
+	}
+	if mean, variance, ok := analyzeMoments(vm.code[:vm.codeIndex], vm.Config.DefaultDiceCount); ok {
+		return mean, variance, nil
+	}
+	return ctx.simulateMoments(vm, int(samples))
+}
+
+func funcMean(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
+	mean, _, err := ctx.exprMoments(params)
+	if err != nil {
+		ctx.Error = errors.New("(mean)" + err.Error())
+		return nil
+	}
+	return NewFloatVal(mean)
+}
+
+func funcVariance(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
+	_, variance, err := ctx.exprMoments(params)
+	if err != nil {
+		ctx.Error = errors.New("(variance)" + err.Error())
+		return nil
+	}
+	return NewFloatVal(variance)
+}
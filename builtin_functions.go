@@ -1,14 +1,24 @@
 package dicescript
 
 import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
 	"errors"
+	"fmt"
 	"math"
+	"regexp"
 	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
 )
 
 func funcCeil(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
-	if params[0].TypeId == VMTypeInt {
-		return params[0]
+	if v := operandValue(params[0]); v.TypeId == VMTypeInt {
+		return v
 	}
 	v, ok := params[0].ReadFloat()
 	if ok {
@@ -20,8 +30,8 @@ func funcCeil(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
 }
 
 func funcRound(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
-	if params[0].TypeId == VMTypeInt {
-		return params[0]
+	if v := operandValue(params[0]); v.TypeId == VMTypeInt {
+		return v
 	}
 	v, ok := params[0].ReadFloat()
 	if ok {
@@ -33,8 +43,8 @@ func funcRound(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
 }
 
 func funcFloor(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
-	if params[0].TypeId == VMTypeInt {
-		return params[0]
+	if v := operandValue(params[0]); v.TypeId == VMTypeInt {
+		return v
 	}
 	v, ok := params[0].ReadFloat()
 	if ok {
@@ -46,7 +56,7 @@ func funcFloor(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
 }
 
 func funcAbs(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
-	v := params[0]
+	v := operandValue(params[0])
 	switch v.TypeId {
 	case VMTypeInt:
 		val := v.MustReadInt()
@@ -66,21 +76,81 @@ func funcAbs(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
 	return nil
 }
 
-func funcToBool(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
-	v := params[0]
-	if v.AsBool() {
-		return NewIntVal(1)
+// funcClamp 将数值限制在 [min, max] 之间，如 clamp(1d20+7, 1, 20)。参数都为int时结果为int，否则为float
+func funcClamp(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
+	v, lo, hi := operandValue(params[0]), operandValue(params[1]), operandValue(params[2])
+	x, ok1 := readNumber(v)
+	a, ok2 := readNumber(lo)
+	b, ok3 := readNumber(hi)
+	if !ok1 || !ok2 || !ok3 {
+		ctx.Error = errors.New("(clamp)类型错误: 参数必须为int或float")
+		return nil
 	}
-	return NewIntVal(0)
+	if a > b {
+		ctx.Error = fmt.Errorf("(clamp)值错误: 下限%s大于上限%s", lo.ToString(), hi.ToString())
+		return nil
+	}
+	if v.TypeId == VMTypeInt && lo.TypeId == VMTypeInt && hi.TypeId == VMTypeInt {
+		switch {
+		case x < a:
+			return lo
+		case x > b:
+			return hi
+		}
+		return v
+	}
+	return NewFloatVal(math.Min(math.Max(x, a), b))
+}
+
+// funcStep 按步长取整，如 step(17, 5) 为15。mode可为 round(默认，四舍五入)、floor(向下)、ceil(向上)
+func funcStep(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
+	v, size := operandValue(params[0]), operandValue(params[1])
+	x, ok1 := readNumber(v)
+	n, ok2 := readNumber(size)
+	if !ok1 || !ok2 {
+		ctx.Error = errors.New("(step)类型错误: 数值和步长必须为int或float")
+		return nil
+	}
+	if n <= 0 {
+		ctx.Error = errors.New("(step)值错误: 步长必须大于0")
+		return nil
+	}
+	mode, ok := params[2].ReadString()
+	if !ok {
+		ctx.Error = errors.New("(step)类型错误: 取整方式必须为str")
+		return nil
+	}
+	var r float64
+	switch mode {
+	case "round":
+		r = math.Round(x/n) * n
+	case "floor":
+		r = math.Floor(x/n) * n
+	case "ceil":
+		r = math.Ceil(x/n) * n
+	default:
+		ctx.Error = fmt.Errorf("(step)值错误: 取整方式只能为 round、floor 或 ceil，不能为 %s", mode)
+		return nil
+	}
+	if v.TypeId == VMTypeInt && size.TypeId == VMTypeInt {
+		return NewIntVal(IntType(r))
+	}
+	return NewFloatVal(r)
+}
+
+func funcToBool(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
+	return NewBoolVal(params[0].AsBool())
 }
 
 func funcToInt(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
 	switch params[0].TypeId {
 	case VMTypeInt:
 		return params[0]
+	case VMTypeBool:
+		return operandValue(params[0])
 	case VMTypeFloat:
 		v, _ := params[0].ReadFloat()
-		return NewIntVal(IntType(v))
+		return NewIntVal(roundToInt(v, ctx.Config.RoundMode, RoundTruncate))
 	case VMTypeString:
 		s, _ := params[0].ReadString()
 		val, err := strconv.ParseInt(s, 10, 64)
@@ -89,6 +159,12 @@ func funcToInt(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
 		} else {
 			ctx.Error = errors.New("(toInt)值错误: 无法进行 toInt() 转换: " + s)
 		}
+	case VMTypeTime:
+		t, _ := params[0].ReadTime()
+		return NewIntVal(IntType(t.Unix()))
+	case VMTypeDuration:
+		d, _ := params[0].ReadDuration()
+		return NewIntVal(IntType(d / time.Second))
 	default:
 		ctx.Error = errors.New("(toInt)类型错误: 只能是数字类型")
 	}
@@ -100,6 +176,9 @@ func funcToFloat(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
 	case VMTypeInt:
 		v, _ := params[0].ReadInt()
 		return NewFloatVal(float64(v))
+	case VMTypeBool:
+		v, _ := operandValue(params[0]).ReadInt()
+		return NewFloatVal(float64(v))
 	case VMTypeFloat:
 		return params[0]
 	case VMTypeString:
@@ -110,12 +189,589 @@ func funcToFloat(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
 		} else {
 			ctx.Error = errors.New("(toFloat)值错误: 无法进行 toFloat() 转换: " + s)
 		}
+	case VMTypeDuration:
+		d, _ := params[0].ReadDuration()
+		return NewFloatVal(d.Seconds())
 	default:
 		ctx.Error = errors.New("(toFloat)类型错误: 只能是数字类型")
 	}
 	return nil
 }
 
+func funcNow(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
+	return NewTimeVal(time.Now())
+}
+
+// funcToTime 整数视为unix时间戳(秒)，字符串支持 2006-01-02 15:04:05、2006-01-02 以及RFC3339格式
+func funcToTime(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
+	switch params[0].TypeId {
+	case VMTypeTime:
+		return params[0]
+	case VMTypeInt:
+		v, _ := params[0].ReadInt()
+		return NewTimeVal(time.Unix(int64(v), 0))
+	case VMTypeString:
+		s, _ := params[0].ReadString()
+		for _, layout := range []string{"2006-01-02 15:04:05", "2006-01-02", time.RFC3339} {
+			if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+				return NewTimeVal(t)
+			}
+		}
+		ctx.Error = errors.New("(toTime)值错误: 无法进行 toTime() 转换: " + s)
+	default:
+		ctx.Error = errors.New("(toTime)类型错误: 只能是int或str")
+	}
+	return nil
+}
+
+// funcToDuration 数字视为秒数，字符串格式同时长字面量，如 1h30m
+func funcToDuration(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
+	switch params[0].TypeId {
+	case VMTypeDuration:
+		return params[0]
+	case VMTypeInt:
+		v, _ := params[0].ReadInt()
+		return NewDurationVal(time.Duration(v) * time.Second)
+	case VMTypeFloat:
+		v, _ := params[0].ReadFloat()
+		return NewDurationVal(time.Duration(v * float64(time.Second)))
+	case VMTypeString:
+		s, _ := params[0].ReadString()
+		d, err := time.ParseDuration(s)
+		if err == nil {
+			return NewDurationVal(d)
+		}
+		ctx.Error = errors.New("(toDuration)值错误: 无法进行 toDuration() 转换: " + s)
+	default:
+		ctx.Error = errors.New("(toDuration)类型错误: 只能是数字或str")
+	}
+	return nil
+}
+
+// defaultNameLists pick_name() 内置的名字列表
+var defaultNameLists = map[string][]string{
+	"cn": {"李明", "王芳", "张伟", "刘洋", "陈静", "杨帆", "赵磊", "黄蕾", "周杰", "吴倩", "徐峰", "孙悦", "马超", "朱琳", "胡斌", "郭瑶"},
+	"en": {"Alice", "Bob", "Charlie", "Diana", "Edward", "Fiona", "George", "Hannah", "Isaac", "Julia", "Kevin", "Laura", "Michael", "Nora", "Oliver", "Grace"},
+	"jp": {"佐藤", "鈴木", "高橋", "田中", "伊藤", "渡辺", "山本", "中村", "小林", "加藤", "吉田", "山田"},
+}
+
+func checkRandomTextEnabled(ctx *Context, name string) bool {
+	if ctx.Config.DisableRandomTextFuncs {
+		ctx.Error = fmt.Errorf("(%s)调用失败: 此函数已被禁用", name)
+		return false
+	}
+	return true
+}
+
+func funcUUID(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
+	if !checkRandomTextEnabled(ctx, "uuid") {
+		return nil
+	}
+	src := ctx.RandSrc
+	if src == nil {
+		src = randSource
+	}
+	var b [16]byte
+	binary.BigEndian.PutUint64(b[:8], src.Uint64())
+	binary.BigEndian.PutUint64(b[8:], src.Uint64())
+	b[6] = (b[6] & 0x0f) | 0x40 // version 4
+	b[8] = (b[8] & 0x3f) | 0x80 // variant 10
+	return NewStrVal(fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]))
+}
+
+// funcChance 按给定的概率得到1或0。小数为概率，如 chance(35%) 即 chance(0.35)；整数为百分比，如 chance(35)
+func funcChance(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
+	var p float64
+	switch params[0].TypeId {
+	case VMTypeInt:
+		p = float64(params[0].MustReadInt()) / 100
+	case VMTypeFloat:
+		p = params[0].MustReadFloat()
+	default:
+		ctx.Error = errors.New("(chance)类型错误: 参数必须为数字")
+		return nil
+	}
+	const precision = 1000000
+	ret := boolToVMValue(float64(Roll(ctx.RandSrc, precision, 0)) <= p*precision)
+	ctx.recordBuiltinRoll("chance("+params[0].ToString()+")", ret, "chance")
+	return ret
+}
+
+// funcPool 骰times个sides面骰，得到各骰子结果组成的数组，如 pool(8, 10)。常与 Array.count 一起用于计算成功数
+func funcPool(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
+	times, ok1 := params[0].ReadInt()
+	sides, ok2 := params[1].ReadInt()
+	if !ok1 || !ok2 {
+		ctx.Error = errors.New("(pool)类型错误: 骰数和面数必须为int")
+		return nil
+	}
+	if times < 1 || times > 20000 {
+		ctx.Error = errors.New("(pool)值错误: 骰数必须在1到20000之间")
+		return nil
+	}
+	if sides <= 0 {
+		ctx.Error = errors.New("(pool)值错误: 面数必须为正整数")
+		return nil
+	}
+	if !ctx.AddOpCount(times) {
+		return nil
+	}
+	mode := ctx.rollMode()
+	lst := make([]*VMValue, times)
+	for i := range lst {
+		lst[i] = NewIntVal(Roll(ctx.RandSrc, sides, mode))
+	}
+	ret := NewArrayValRaw(lst)
+	ctx.recordBuiltinRoll(fmt.Sprintf("pool(%d, %d)", times, sides), ret, "dice-pool")
+	ctx.setCallDetail(BufferSpan{Ret: ret, Tag: "dice-pool"})
+	return ret
+}
+
+func funcRandStr(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
+	if !checkRandomTextEnabled(ctx, "randstr") {
+		return nil
+	}
+	n, ok := params[0].ReadInt()
+	if !ok {
+		ctx.Error = errors.New("(randstr)类型错误: 长度必须为int")
+		return nil
+	}
+	if n < 0 || n > 256 {
+		ctx.Error = errors.New("(randstr)值错误: 长度范围是0到256")
+		return nil
+	}
+	charset := "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789"
+	if !params[1].IsNullish() {
+		s, ok := params[1].ReadString()
+		if !ok || s == "" {
+			ctx.Error = errors.New("(randstr)类型错误: 字符集必须为非空的str")
+			return nil
+		}
+		charset = s
+	}
+	chars := []rune(charset)
+	ret := make([]rune, n)
+	for i := range ret {
+		ret[i] = chars[Roll(ctx.RandSrc, IntType(len(chars)), 0)-1]
+	}
+	return NewStrVal(string(ret))
+}
+
+func funcPickName(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
+	if !checkRandomTextEnabled(ctx, "pick_name") {
+		return nil
+	}
+	culture, ok := params[0].ReadString()
+	if !ok {
+		ctx.Error = errors.New("(pick_name)类型错误: 参数必须为str")
+		return nil
+	}
+	var names []string
+	if ctx.Config.NameListFunc != nil {
+		names = ctx.Config.NameListFunc(ctx, culture)
+	}
+	if names == nil {
+		names = defaultNameLists[culture]
+	}
+	if len(names) == 0 {
+		ctx.Error = errors.New("(pick_name)值错误: 没有可用的名字列表: " + culture)
+		return nil
+	}
+	return NewStrVal(names[Roll(ctx.RandSrc, IntType(len(names)), 0)-1])
+}
+
+// readStrParam 读取字符串参数，同时检查 MaxStringLen
+func readStrParam(ctx *Context, funcName string, v *VMValue) (string, bool) {
+	s, ok := v.ReadString()
+	if !ok {
+		ctx.Error = fmt.Errorf("(%s)类型错误: 参数必须为str", funcName)
+		return "", false
+	}
+	return s, checkStrLen(ctx, funcName, s)
+}
+
+func checkStrLen(ctx *Context, funcName string, s string) bool {
+	if ctx.Config.MaxStringLen > 0 && len(s) > ctx.Config.MaxStringLen {
+		ctx.Error = fmt.Errorf("(%s)值错误: 字符串长度超过上限%d", funcName, ctx.Config.MaxStringLen)
+		return false
+	}
+	return true
+}
+
+func funcMD5(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
+	s, ok := readStrParam(ctx, "md5", params[0])
+	if !ok {
+		return nil
+	}
+	sum := md5.Sum([]byte(s))
+	return NewStrVal(hex.EncodeToString(sum[:]))
+}
+
+func funcSHA256(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
+	s, ok := readStrParam(ctx, "sha256", params[0])
+	if !ok {
+		return nil
+	}
+	sum := sha256.Sum256([]byte(s))
+	return NewStrVal(hex.EncodeToString(sum[:]))
+}
+
+func funcB64Encode(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
+	s, ok := readStrParam(ctx, "b64encode", params[0])
+	if !ok {
+		return nil
+	}
+	ret := base64.StdEncoding.EncodeToString([]byte(s))
+	if !checkStrLen(ctx, "b64encode", ret) {
+		return nil
+	}
+	return NewStrVal(ret)
+}
+
+func funcB64Decode(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
+	s, ok := readStrParam(ctx, "b64decode", params[0])
+	if !ok {
+		return nil
+	}
+	ret, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		ctx.Error = errors.New("(b64decode)值错误: 不是合法的base64字符串")
+		return nil
+	}
+	if !utf8.Valid(ret) {
+		ctx.Error = errors.New("(b64decode)值错误: 解码结果不是合法的文本")
+		return nil
+	}
+	return NewStrVal(string(ret))
+}
+
+// funcHex 整数转为十六进制，字符串则按字节转为十六进制
+func funcHex(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
+	switch params[0].TypeId {
+	case VMTypeInt:
+		v, _ := params[0].ReadInt()
+		if v < 0 {
+			return NewStrVal("-" + strconv.FormatUint(uint64(-v), 16))
+		}
+		return NewStrVal(strconv.FormatUint(uint64(v), 16))
+	case VMTypeString:
+		s, ok := readStrParam(ctx, "hex", params[0])
+		if !ok {
+			return nil
+		}
+		ret := hex.EncodeToString([]byte(s))
+		if !checkStrLen(ctx, "hex", ret) {
+			return nil
+		}
+		return NewStrVal(ret)
+	}
+	ctx.Error = errors.New("(hex)类型错误: 参数必须为int或str")
+	return nil
+}
+
+// splitNumberText 将数字拆为符号、整数部分和小数部分(含小数点)的文本，用于分组格式化。
+// 使用科学计数法的浮点数整数部分为空，由调用者原样输出
+func splitNumberText(ctx *Context, funcName string, v *VMValue) (string, string, string, bool) {
+	v = operandValue(v)
+	var text string
+	switch v.TypeId {
+	case VMTypeInt:
+		text = strconv.FormatInt(int64(v.MustReadInt()), 10)
+	case VMTypeFloat:
+		text = formatFloat(v.MustReadFloat())
+		if strings.ContainsAny(text, "eEIN") {
+			return "", "", text, true
+		}
+	default:
+		ctx.Error = fmt.Errorf("(%s)类型错误: 参数必须为int或float", funcName)
+		return "", "", "", false
+	}
+	sign := ""
+	if strings.HasPrefix(text, "-") {
+		sign, text = "-", text[1:]
+	}
+	intPart, frac := text, ""
+	if i := strings.IndexByte(text, '.'); i >= 0 {
+		intPart, frac = text[:i], text[i:]
+	}
+	return sign, intPart, frac, true
+}
+
+// funcFmtThousands 每三位加分隔符，如 fmt_thousands(1234567) 为 '1,234,567'
+func funcFmtThousands(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
+	sign, intPart, frac, ok := splitNumberText(ctx, "fmt_thousands", params[0])
+	if !ok {
+		return nil
+	}
+	sep, ok := readStrParam(ctx, "fmt_thousands", params[1])
+	if !ok {
+		return nil
+	}
+	var sb strings.Builder
+	sb.WriteString(sign)
+	for i, c := range intPart {
+		if i > 0 && (len(intPart)-i)%3 == 0 {
+			sb.WriteString(sep)
+		}
+		sb.WriteRune(c)
+	}
+	sb.WriteString(frac)
+	return NewStrVal(sb.String())
+}
+
+// cnNumberUnits 中文数字每四位的单位
+var cnNumberUnits = []string{"", "万", "亿", "万亿", "亿亿"}
+
+// funcFmtCn 按中文习惯每四位加上万、亿，如 fmt_cn(123456789) 为 '1亿2345万6789'。
+// 高位之后的分组补足四位，全为0的分组省略，如 100000005 为 '1亿0005'
+func funcFmtCn(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
+	sign, intPart, frac, ok := splitNumberText(ctx, "fmt_cn", params[0])
+	if !ok {
+		return nil
+	}
+	if len(intPart) <= 4 {
+		return NewStrVal(sign + intPart + frac)
+	}
+
+	var groups []string
+	for end := len(intPart); end > 0; end -= 4 {
+		start := end - 4
+		if start < 0 {
+			start = 0
+		}
+		groups = append(groups, intPart[start:end])
+	}
+	if len(groups) > len(cnNumberUnits) {
+		// 超出单位的范围(如 1e20)，与更大的数一样使用科学计数法
+		f, _ := operandValue(params[0]).ReadFloat()
+		return NewStrVal(strconv.FormatFloat(f, 'g', -1, 64))
+	}
+	var sb strings.Builder
+	sb.WriteString(sign)
+	for i := len(groups) - 1; i >= 0; i-- {
+		g := groups[i]
+		if i < len(groups)-1 && strings.Trim(g, "0") == "" {
+			continue
+		}
+		sb.WriteString(g)
+		sb.WriteString(cnNumberUnits[i])
+	}
+	sb.WriteString(frac)
+	return NewStrVal(sb.String())
+}
+
+func funcJSONParse(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
+	s, ok := readStrParam(ctx, "json_parse", params[0])
+	if !ok {
+		return nil
+	}
+	v, err := VMValueFromPlainJSON([]byte(s))
+	if err != nil {
+		ctx.Error = errors.New("(json_parse)值错误: " + err.Error())
+		return nil
+	}
+	return v
+}
+
+func funcJSONStr(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
+	data, err := params[0].ToPlainJSON()
+	if err != nil {
+		ctx.Error = errors.New("(json_str)" + err.Error())
+		return nil
+	}
+	if !checkStrLen(ctx, "json_str", string(data)) {
+		return nil
+	}
+	return NewStrVal(string(data))
+}
+
+// funcRange 同python，range(end) 或 range(start, end, step)，不包含end。结果是惰性的，不会一次性生成数组
+func funcRange(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
+	var nums [3]IntType
+	for i, p := range params {
+		if i == 1 && p.IsNullish() {
+			// range(end)
+			nums[0], nums[1] = 0, nums[0]
+			continue
+		}
+		v, ok := p.ReadInt()
+		if !ok {
+			ctx.Error = errors.New("(range)类型错误: 参数必须为int")
+			return nil
+		}
+		nums[i] = v
+	}
+	if nums[2] == 0 {
+		ctx.Error = errors.New("(range)值错误: 步长不能为0")
+		return nil
+	}
+	return NewIteratorVal("range", &rangeIterator{cur: nums[0], end: nums[1], step: nums[2]})
+}
+
+func funcMap(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
+	it, err := params[0].iter()
+	if err != nil {
+		ctx.Error = errors.New("(map)" + err.Error())
+		return nil
+	}
+	return NewIteratorVal("map", &mapIterator{src: it, fn: params[1]})
+}
+
+func funcFilter(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
+	it, err := params[0].iter()
+	if err != nil {
+		ctx.Error = errors.New("(filter)" + err.Error())
+		return nil
+	}
+	return NewIteratorVal("filter", &filterIterator{src: it, fn: params[1]})
+}
+
+// groupRollParams 字符串或computed作为 group_roll 的expr时，目标以参数的形式绑定在本次执行的变量空间中，
+// 不修改computed共用的变量
+var groupRollParams = []string{"it", "target"}
+
+// funcGroupRoll 对每个目标各执行一次expr，如 group_roll('d20 + 感知[it]', ['甲', '乙'])，结果为 (目标, 结果) 组成的数组。
+// expr为字符串或computed时，目标可以用 it 或 target 读取；为函数或带一个参数的computed时，目标作为参数传入
+func funcGroupRoll(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
+	fn := params[0]
+	var cd *ComputedData
+	switch fn.TypeId {
+	case VMTypeString:
+		expr, _ := fn.ReadString()
+		cd = &ComputedData{Expr: expr, Params: groupRollParams}
+	case VMTypeComputedValue:
+		c, _ := fn.ReadComputed()
+		if len(c.Params) == 0 {
+			// 每个目标的结果不同，不使用缓存
+			cd = &ComputedData{Expr: c.Expr, Attrs: c.Attrs, Params: groupRollParams, code: c.code, codeIndex: c.codeIndex}
+		}
+	case VMTypeFunction, VMTypeNativeFunction:
+	default:
+		ctx.Error = errors.New("(group_roll)类型错误: expr必须为str、computed或函数")
+		return nil
+	}
+
+	targets := ctx.iterToList(params[1])
+	if ctx.Error != nil {
+		ctx.Error = errors.New("(group_roll)" + ctx.Error.Error())
+		return nil
+	}
+	items := make([]*VMValue, 0, len(targets))
+	for _, t := range targets {
+		var ret *VMValue
+		if cd != nil {
+			ret = NewComputedValRaw(cd).ComputedInvoke(ctx, []*VMValue{t, t}, nil)
+		} else {
+			ret = invokeCallable(ctx, fn, []*VMValue{t})
+		}
+		if ctx.Error != nil {
+			return nil
+		}
+		items = append(items, NewTupleVal(t, ret))
+	}
+	return NewArrayValRaw(items)
+}
+
+// funcNext 从迭代器中取出下一个值，已取完时返回默认值
+func funcNext(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
+	id, ok := params[0].ReadIterator()
+	if !ok {
+		ctx.Error = errors.New("(next)类型错误: 参数必须为迭代器")
+		return nil
+	}
+	v, ok := id.it.next(ctx)
+	if ctx.Error != nil {
+		return nil
+	}
+	if !ok {
+		return params[1]
+	}
+	return v
+}
+
+// funcList 取出迭代器中的所有值，组成数组
+func funcList(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
+	lst := ctx.iterToList(params[0])
+	if ctx.Error != nil {
+		return nil
+	}
+	return NewArrayValRaw(lst)
+}
+
+// funcJoin 将各项以sep连接为字符串，如 join([3, 5], ' + ') 为 '3 + 5'，字符串项不带引号
+func funcJoin(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
+	sep, ok := params[1].ReadString()
+	if !ok {
+		ctx.Error = errors.New("(join)类型错误: 分隔符必须为str")
+		return nil
+	}
+	lst := ctx.iterToList(params[0])
+	if ctx.Error != nil {
+		return nil
+	}
+	items := make([]string, len(lst))
+	for i, item := range lst {
+		items[i] = ctx.ToString(item)
+	}
+	s := strings.Join(items, sep)
+	if !checkStrLen(ctx, "join", s) {
+		return nil
+	}
+	return NewStrVal(s)
+}
+
+var joinfPlaceholder = regexp.MustCompile(`\{([^{}]*)\}`)
+
+// funcJoinf 将各项按模板格式化后连接，模板中 {} 为该项，{i} 为序号(从1开始)，
+// 项为字典时 {名字} 为其中的值，如 joinf(结果, '{name}: {hp}', '\n')
+func funcJoinf(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
+	tmpl, ok := params[1].ReadString()
+	if !ok {
+		ctx.Error = errors.New("(joinf)类型错误: 模板必须为str")
+		return nil
+	}
+	sep, ok := params[2].ReadString()
+	if !ok {
+		ctx.Error = errors.New("(joinf)类型错误: 分隔符必须为str")
+		return nil
+	}
+	lst := ctx.iterToList(params[0])
+	if ctx.Error != nil {
+		return nil
+	}
+	items := make([]string, len(lst))
+	for i, item := range lst {
+		var err error
+		items[i] = joinfPlaceholder.ReplaceAllStringFunc(tmpl, func(m string) string {
+			name := strings.TrimSpace(m[1 : len(m)-1])
+			switch name {
+			case "":
+				return ctx.ToString(item)
+			case "i":
+				return strconv.Itoa(i + 1)
+			}
+			if dd, ok := item.ReadDictData(); ok {
+				if v, ok := dd.Dict.Load(name); ok {
+					return ctx.ToString(v)
+				}
+			}
+			if err == nil {
+				err = fmt.Errorf("(joinf)值错误: 第%d项没有 %s", i+1, name)
+			}
+			return ""
+		})
+		if err != nil {
+			ctx.Error = err
+			return nil
+		}
+	}
+	s := strings.Join(items, sep)
+	if !checkStrLen(ctx, "joinf", s) {
+		return nil
+	}
+	return NewStrVal(s)
+}
+
 func funcToStr(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
 	return NewStrVal(params[0].ToString())
 }
@@ -164,6 +820,105 @@ func funcStore(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
 	return val
 }
 
+func funcExists(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
+	name := params[0]
+	if name.TypeId != VMTypeString {
+		ctx.Error = errors.New("(exists)类型错误: 参数类型必须为str")
+		return nil
+	}
+	return boolToVMValue(ctx.ExistsName(name.Value.(string), true))
+}
+
+func funcMemo(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
+	cd, ok := params[0].ReadComputed()
+	if !ok {
+		ctx.Error = errors.New("(memo)类型错误: 参数类型必须为computed，如 memo(&a) 或 memo(&(d20))")
+		return nil
+	}
+	return NewComputedValRaw(&ComputedData{
+		Expr:      cd.Expr,
+		Params:    cd.Params,
+		Memo:      true,
+		Attrs:     cd.Attrs,
+		code:      cd.code,
+		codeIndex: cd.codeIndex,
+	})
+}
+
+func funcInvalidate(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
+	v := params[0]
+	if v.IsNullish() {
+		// 使所有memo失效
+		ctx.rootCtx().memoEpoch++
+		return nil
+	}
+	cd, ok := v.ReadComputed()
+	if !ok {
+		ctx.Error = errors.New("(invalidate)类型错误: 参数类型必须为computed，如 invalidate(&a)")
+		return nil
+	}
+	cd.setMemo(nil)
+	return nil
+}
+
+// funcEvalAll 依次执行一组computed，得到其结果组成的数组，如 eval_all([&hp, &mp, &san])，其余的项原样保留。
+// 共用一个子vm，并在执行前一次性读取它们用到的全局变量
+func funcEvalAll(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
+	items := ctx.iterToList(params[0])
+	if ctx.Error != nil {
+		ctx.Error = errors.New("(eval_all)" + ctx.Error.Error())
+		return nil
+	}
+
+	var names []string
+	seen := map[string]bool{}
+	for _, item := range items {
+		cd, ok := item.ReadComputed()
+		if !ok {
+			continue
+		}
+		if err := cd.compile(ctx); err != nil {
+			ctx.Error = fmt.Errorf("(eval_all)解析失败: %s", err.Error())
+			return nil
+		}
+		for _, c := range cd.code[:cd.codeIndex] {
+			if c.T != typeLoadName && c.T != typeLoadNameWithDetail && c.T != typeLoadNameRaw {
+				continue
+			}
+			name := c.Value.(string)
+			if seen[name] || ctx.prefetch.has(name) {
+				continue
+			}
+			seen[name] = true
+			if cd.Attrs != nil {
+				if _, ok := cd.Attrs.Load(name); ok {
+					continue
+				}
+			}
+			if r, _ := ctx.getScopeResolver(name); r != nil {
+				continue
+			}
+			names = append(names, name)
+		}
+	}
+
+	vm := ctx.newComputedVM()
+	vm.prefetch = ctx.loadPrefetch(names, ctx.prefetch)
+	ret := make([]*VMValue, len(items))
+	for i, item := range items {
+		cd, ok := item.ReadComputed()
+		if !ok {
+			ret[i] = item
+			continue
+		}
+		ret[i] = cd.invoke(ctx, vm, nil, nil)
+		if ctx.Error != nil {
+			return nil
+		}
+	}
+	return NewArrayValRaw(ret)
+}
+
 func funcDir(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
 	typeId := params[0].TypeId
 	var arr []*VMValue
@@ -198,16 +953,85 @@ var builtinValues = map[string]*VMValue{
 	"floor": nnf(&ndf{"floor", []string{"value"}, nil, nil, funcFloor}),
 	"round": nnf(&ndf{"round", []string{"value"}, nil, nil, funcRound}),
 	"abs":   nnf(&ndf{"abs", []string{"value"}, nil, nil, funcAbs}),
+	"clamp": nnf(&ndf{"clamp", []string{"value", "min", "max"}, nil, nil, funcClamp}),
+	"step":  nnf(&ndf{"step", []string{"value", "size", "mode"}, []*VMValue{nil, nil, NewStrVal("round")}, nil, funcStep}),
 
 	"toInt":   nnf(&ndf{"toInt", []string{"value"}, nil, nil, funcToInt}),
 	"toFloat": nnf(&ndf{"toFloat", []string{"value"}, nil, nil, funcToFloat}),
 	"toStr":   nnf(&ndf{"toStr", []string{"value"}, nil, nil, funcToStr}),
 	"toBool":  nnf(&ndf{"toBool", []string{"value"}, nil, nil, funcToBool}),
 
+	"now":        nnf(&ndf{"now", []string{}, nil, nil, funcNow}),
+	"toTime":     nnf(&ndf{"toTime", []string{"value"}, nil, nil, funcToTime}),
+	"toDuration": nnf(&ndf{"toDuration", []string{"value"}, nil, nil, funcToDuration}),
+	"toQuantity": nnf(&ndf{"toQuantity", []string{"value", "unit"}, nil, nil, funcToQuantity}),
+	"toMoney":    nnf(&ndf{"toMoney", []string{"value", "denomination"}, []*VMValue{nil, NewNullVal()}, nil, funcToMoney}),
+
 	"repr":    nnf(&ndf{"repr", []string{"value"}, nil, nil, funcRepr}),
 	"load":    nnf(&ndf{"load", []string{"value"}, nil, nil, nil}),
 	"loadRaw": nnf(&ndf{"loadRaw", []string{"value"}, nil, nil, nil}),
 	"store":   nnf(&ndf{"store", []string{"name", "value"}, nil, nil, nil}),
+	"exists":  nnf(&ndf{"exists", []string{"name"}, nil, nil, nil}),
+
+	"chance":   nnf(&ndf{"chance", []string{"p"}, nil, nil, funcChance}),
+	"opposed":  nnf(&ndf{"opposed", []string{"a", "b"}, nil, nil, funcOpposed}),
+	"pool":     nnf(&ndf{"pool", []string{"times", "sides"}, nil, nil, funcPool}),
+	"mean":     nnf(&ndf{"mean", []string{"expr", "samples"}, []*VMValue{nil, NewIntVal(1000)}, nil, nil}),
+	"variance": nnf(&ndf{"variance", []string{"expr", "samples"}, []*VMValue{nil, NewIntVal(1000)}, nil, nil}),
+	"output":   nnf(&ndf{"output", []string{"label", "value"}, nil, nil, funcOutput}),
+	"reply":    nnf(&ndf{"reply", []string{"template", "vars"}, []*VMValue{nil, NewNullVal()}, nil, nil}),
+	"emit":     nnf(&ndf{"emit", []string{"name", "data"}, []*VMValue{nil, NewNullVal()}, nil, funcEmit}),
+	"lastroll": nnf(&ndf{"lastroll", []string{"n"}, []*VMValue{NewIntVal(1)}, nil, funcLastRoll}),
+
+	"await_input": nnf(&ndf{"await_input", []string{"prompt"}, nil, nil, funcAwaitInput}),
+
+	"hit_location":    nnf(&ndf{"hit_location", []string{"roll"}, []*VMValue{NewNullVal()}, nil, funcHitLocation}),
+	"damage_type_mod": nnf(&ndf{"damage_type_mod", []string{"type", "armor"}, nil, nil, funcDamageTypeMod}),
+
+	"uuid":      nnf(&ndf{"uuid", []string{}, nil, nil, funcUUID}),
+	"randstr":   nnf(&ndf{"randstr", []string{"n", "charset"}, []*VMValue{nil, NewNullVal()}, nil, funcRandStr}),
+	"pick_name": nnf(&ndf{"pick_name", []string{"culture"}, []*VMValue{NewStrVal("cn")}, nil, funcPickName}),
+
+	"md5":       nnf(&ndf{"md5", []string{"value"}, nil, nil, funcMD5}),
+	"sha256":    nnf(&ndf{"sha256", []string{"value"}, nil, nil, funcSHA256}),
+	"b64encode": nnf(&ndf{"b64encode", []string{"value"}, nil, nil, funcB64Encode}),
+	"b64decode": nnf(&ndf{"b64decode", []string{"value"}, nil, nil, funcB64Decode}),
+	"hex":       nnf(&ndf{"hex", []string{"value"}, nil, nil, funcHex}),
+
+	"fmt_thousands": nnf(&ndf{"fmt_thousands", []string{"value", "sep"}, []*VMValue{nil, NewStrVal(",")}, nil, funcFmtThousands}),
+	"fmt_cn":        nnf(&ndf{"fmt_cn", []string{"value"}, nil, nil, funcFmtCn}),
+
+	"json_parse": nnf(&ndf{"json_parse", []string{"value"}, nil, nil, funcJSONParse}),
+	"json_str":   nnf(&ndf{"json_str", []string{"value"}, nil, nil, funcJSONStr}),
+
+	"range":  nnf(&ndf{"range", []string{"start", "end", "step"}, []*VMValue{nil, NewNullVal(), NewIntVal(1)}, nil, funcRange}),
+	"map":    nnf(&ndf{"map", []string{"iterable", "func"}, nil, nil, funcMap}),
+	"filter": nnf(&ndf{"filter", []string{"iterable", "func"}, nil, nil, funcFilter}),
+	"list":   nnf(&ndf{"list", []string{"iterable"}, nil, nil, funcList}),
+	"join":   nnf(&ndf{"join", []string{"iterable", "sep"}, []*VMValue{nil, NewStrVal(", ")}, nil, funcJoin}),
+	"joinf":  nnf(&ndf{"joinf", []string{"iterable", "format", "sep"}, []*VMValue{nil, nil, NewStrVal(", ")}, nil, funcJoinf}),
+	"tuple":  nnf(&ndf{"tuple", []string{"iterable"}, nil, nil, funcTuple}),
+	"set":    nnf(&ndf{"set", []string{"iterable"}, []*VMValue{NewNullVal()}, nil, funcSet}),
+	"deck":   nnf(&ndf{"deck", []string{"cards", "shuffle"}, []*VMValue{nil, NewIntVal(1)}, nil, funcDeck}),
+	"next":   nnf(&ndf{"next", []string{"iterator", "default"}, []*VMValue{nil, NewNullVal()}, nil, funcNext}),
+
+	"resource":   nnf(&ndf{"resource", []string{"value", "max", "min", "name"}, []*VMValue{nil, nil, NewIntVal(0), NewNullVal()}, nil, funcResource}),
+	"init_roll":  nnf(&ndf{"init_roll", []string{"actors", "sides"}, []*VMValue{nil, NewIntVal(20)}, nil, funcInitRoll}),
+	"group_roll": nnf(&ndf{"group_roll", []string{"expr", "targets"}, nil, nil, nil}),
+
+	"error":    nnf(&ndf{"error", []string{"message", "code"}, []*VMValue{nil, NewStrVal(ErrorCodeError)}, nil, funcError}),
+	"raise":    nnf(&ndf{"raise", []string{"error"}, nil, nil, funcRaise}),
+	"try":      nnf(&ndf{"try", []string{"expr", "handler"}, []*VMValue{nil, NewNullVal()}, nil, nil}),
+	"is_error": nnf(&ndf{"is_error", []string{"value"}, nil, nil, funcIsError}),
+
+	"assert":      nnf(&ndf{"assert", []string{"cond", "msg"}, []*VMValue{nil, NewNullVal()}, nil, funcAssert}),
+	"require_int": nnf(&ndf{"require_int", []string{"value", "name"}, []*VMValue{nil, NewNullVal()}, nil, funcRequireInt}),
+	"require_num": nnf(&ndf{"require_num", []string{"value", "name"}, []*VMValue{nil, NewNullVal()}, nil, funcRequireNum}),
+	"require_str": nnf(&ndf{"require_str", []string{"value", "name"}, []*VMValue{nil, NewNullVal()}, nil, funcRequireStr}),
+
+	"memo":       nnf(&ndf{"memo", []string{"value"}, nil, nil, funcMemo}),
+	"invalidate": nnf(&ndf{"invalidate", []string{"value"}, []*VMValue{NewNullVal()}, nil, funcInvalidate}),
+	"eval_all":   nnf(&ndf{"eval_all", []string{"values"}, nil, nil, nil}),
 
 	// TODO: roll()
 
@@ -227,6 +1051,27 @@ func _init() bool {
 
 	nfd, _ = builtinValues["store"].ReadNativeFunctionData()
 	nfd.NativeFunc = funcStore
+
+	nfd, _ = builtinValues["exists"].ReadNativeFunctionData()
+	nfd.NativeFunc = funcExists
+
+	nfd, _ = builtinValues["mean"].ReadNativeFunctionData()
+	nfd.NativeFunc = funcMean
+
+	nfd, _ = builtinValues["variance"].ReadNativeFunctionData()
+	nfd.NativeFunc = funcVariance
+
+	nfd, _ = builtinValues["reply"].ReadNativeFunctionData()
+	nfd.NativeFunc = funcReply
+
+	nfd, _ = builtinValues["group_roll"].ReadNativeFunctionData()
+	nfd.NativeFunc = funcGroupRoll
+
+	nfd, _ = builtinValues["try"].ReadNativeFunctionData()
+	nfd.NativeFunc = funcTry
+
+	nfd, _ = builtinValues["eval_all"].ReadNativeFunctionData()
+	nfd.NativeFunc = funcEvalAll
 	return false
 }
 
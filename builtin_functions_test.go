@@ -1,8 +1,10 @@
 package dicescript
 
 import (
-	"github.com/stretchr/testify/assert"
+	"strings"
 	"testing"
+
+	"github.com/stretchr/testify/assert"
 )
 
 func TestNativeFunctionCall(t *testing.T) {
@@ -35,6 +37,38 @@ func TestNativeFunctionFloat(t *testing.T) {
 	vm.Error = nil
 }
 
+func TestRoundMode(t *testing.T) {
+	cases := []struct {
+		mode RoundMode
+		want string
+	}{
+		{RoundDefault, "[2, -2, 3, 1sp3cp, 2, -2, 3]"}, // toInt() 和整数相除向零取整，金额四舍五入
+		{RoundTruncate, "[2, -2, 3, 1sp2cp, 2, -2, 3]"},
+		{RoundFloor, "[2, -3, 3, 1sp2cp, 2, -3, 3]"},
+		{RoundHalfUp, "[3, -3, 4, 1sp3cp, 3, -3, 4]"},
+		{RoundHalfEven, "[2, -2, 4, 1sp2cp, 2, -2, 4]"},
+	}
+	for _, c := range cases {
+		vm := NewVM()
+		vm.Config.RoundMode = c.mode
+		err := vm.Run("[toInt(2.5), toInt(-2.5), toInt(3.5), 5cp * 2.5, 5 / 2, -5 / 2, 7 / 2]")
+		if assert.NoError(t, err) {
+			assert.Equal(t, c.want, vm.Ret.ToString(), c.mode)
+		}
+	}
+
+	// 下标、骰子个数等处的小数
+	vm := NewVM()
+	vm.Config.RoundMode = RoundHalfUp
+	err := vm.Run("a = [10, 20, 30]; [a[1.5], (2.5)d1]")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, na(ni(30), ni(3))))
+	}
+	vm = NewVM()
+	err = vm.Run("a = [10, 20, 30]; a[1.5]")
+	assert.Error(t, err)
+}
+
 func TestNativeFunctionConvert(t *testing.T) {
 	vm := NewVM()
 	assert.True(t, valueEqual(funcToInt(vm, nil, []*VMValue{nf(1.1)}), ni(1)))
@@ -100,13 +134,13 @@ func TestNativeFunctionStore(t *testing.T) {
 
 func TestNativeFunctionBool(t *testing.T) {
 	vm := NewVM()
-	assert.True(t, valueEqual(funcToBool(vm, nil, []*VMValue{ni(1)}), ni(1)))
-	assert.True(t, valueEqual(funcToBool(vm, nil, []*VMValue{ni(0)}), ni(0)))
-	assert.True(t, valueEqual(funcToBool(vm, nil, []*VMValue{ns("hello")}), ni(1)))
-	assert.True(t, valueEqual(funcToBool(vm, nil, []*VMValue{ns("")}), ni(0)))
-	assert.True(t, valueEqual(funcToBool(vm, nil, []*VMValue{NewNullVal()}), ni(0)))
-	assert.True(t, valueEqual(funcToBool(vm, nil, []*VMValue{na(ni(1))}), ni(1)))
-	assert.True(t, valueEqual(funcToBool(vm, nil, []*VMValue{na()}), ni(0)))
+	assert.True(t, valueEqual(funcToBool(vm, nil, []*VMValue{ni(1)}), nb(true)))
+	assert.True(t, valueEqual(funcToBool(vm, nil, []*VMValue{ni(0)}), nb(false)))
+	assert.True(t, valueEqual(funcToBool(vm, nil, []*VMValue{ns("hello")}), nb(true)))
+	assert.True(t, valueEqual(funcToBool(vm, nil, []*VMValue{ns("")}), nb(false)))
+	assert.True(t, valueEqual(funcToBool(vm, nil, []*VMValue{NewNullVal()}), nb(false)))
+	assert.True(t, valueEqual(funcToBool(vm, nil, []*VMValue{na(ni(1))}), nb(true)))
+	assert.True(t, valueEqual(funcToBool(vm, nil, []*VMValue{na()}), nb(false)))
 }
 
 func TestNativeFunctionRepr(t *testing.T) {
@@ -140,3 +174,465 @@ func TestNativeFunctionAbs(t *testing.T) {
 	assert.Error(t, vm.Error)
 	vm.Error = nil
 }
+
+func TestNativeFunctionClampStep(t *testing.T) {
+	vm := NewVM()
+	err := vm.Run("[clamp(25, 1, 20), clamp(-3, 1, 20), clamp(7, 1, 20), clamp(2.5, 1, 2)]")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, na(ni(20), ni(1), ni(7), nf(2))))
+	}
+
+	vm = NewVM()
+	err = vm.Run("[step(17, 5), step(17, 5, 'floor'), step(11, 5, 'ceil'), step(10, 5, 'ceil'), step(1.26, 0.5)]")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, na(ni(15), ni(15), ni(15), ni(10), nf(1.5))))
+	}
+
+	vm = NewVM()
+	err = vm.Run("clamp(d20+30, 1, 20)")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, ni(20)))
+	}
+
+	for _, expr := range []string{"clamp(5, 10, 1)", "clamp('a', 1, 2)", "step(5, 0)", "step(5, 2, 'up')"} {
+		vm = NewVM()
+		assert.Error(t, vm.Run(expr), expr)
+	}
+}
+
+func TestNativeFunctionExists(t *testing.T) {
+	vm := NewVM()
+	loadPostCalled := false
+	vm.Config.HookValueLoadPost = func(ctx *Context, name string, curVal *VMValue, doCompute func(curVal *VMValue) *VMValue, detail *BufferSpan) *VMValue {
+		if name == "San值" {
+			loadPostCalled = true
+		}
+		return doCompute(curVal)
+	}
+	err := vm.Run("a = 1; [exists('a'), exists('San值')]")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, na(nb(true), nb(false))))
+	}
+	assert.False(t, loadPostCalled)
+
+	vm = NewVM()
+	vm.GlobalValueLoadFunc = func(name string) *VMValue {
+		if name == "San值" {
+			return ni(60)
+		}
+		return nil
+	}
+	err = vm.Run("exists('San值')")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, nb(true)))
+	}
+
+	// 设置了存在检查时不再调用读取回调
+	loads := 0
+	vm = NewVM()
+	vm.GlobalValueLoadFunc = func(name string) *VMValue {
+		if name == "San值" || name == "力量" {
+			loads++
+			return ni(1)
+		}
+		return nil
+	}
+	vm.GlobalValueExistsFunc = func(name string) bool {
+		return name == "San值"
+	}
+	err = vm.Run("[exists('San值'), exists('力量')]")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, na(nb(true), nb(false))))
+	}
+	assert.Equal(t, 0, loads)
+
+	vm = NewVM()
+	vm.Config.HookValueExists = func(ctx *Context, name string) (bool, bool) {
+		return name == "力量", true
+	}
+	err = vm.Run("a = 1; [exists('a'), exists('力量')]")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, na(nb(false), nb(true))))
+	}
+
+	vm = NewVM()
+	err = vm.Run("exists(1)")
+	assert.Error(t, err)
+}
+
+func TestNativeFunctionRandomText(t *testing.T) {
+	vm := NewVM()
+	err := vm.Run("uuid()")
+	if assert.NoError(t, err) {
+		assert.Regexp(t, `^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`, vm.Ret.ToString())
+	}
+
+	vm = NewVM()
+	err = vm.Run("[randstr(8), randstr(5, 'ab'), randstr(0)]")
+	if assert.NoError(t, err) {
+		arr := vm.Ret.MustReadArray().List
+		assert.Regexp(t, `^[A-Za-z0-9]{8}$`, arr[0].ToString())
+		assert.Regexp(t, `^[ab]{5}$`, arr[1].ToString())
+		assert.Equal(t, "", arr[2].ToString())
+	}
+
+	vm = NewVM()
+	err = vm.Run("randstr(1000)")
+	assert.Error(t, err)
+
+	vm = NewVM()
+	err = vm.Run("pick_name('en')")
+	if assert.NoError(t, err) {
+		assert.Contains(t, defaultNameLists["en"], vm.Ret.ToString())
+	}
+
+	vm = NewVM()
+	vm.Config.NameListFunc = func(ctx *Context, culture string) []string {
+		if culture == "elf" {
+			return []string{"Legolas"}
+		}
+		return nil
+	}
+	err = vm.Run("[pick_name('elf'), pick_name()]")
+	if assert.NoError(t, err) {
+		arr := vm.Ret.MustReadArray().List
+		assert.Equal(t, "Legolas", arr[0].ToString())
+		assert.Contains(t, defaultNameLists["cn"], arr[1].ToString())
+	}
+
+	vm = NewVM()
+	err = vm.Run("pick_name('dwarf')")
+	assert.Error(t, err)
+
+	vm = NewVM()
+	vm.Config.DisableRandomTextFuncs = true
+	err = vm.Run("uuid()")
+	assert.Error(t, err)
+}
+
+func TestNativeFunctionNumberFormat(t *testing.T) {
+	vm := NewVM()
+	err := vm.Run("[fmt_thousands(1234567), fmt_thousands(-1000), fmt_thousands(999), fmt_thousands(12345.25, ' '), fmt_thousands(1e30)]")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, na(
+			ns("1,234,567"), ns("-1,000"), ns("999"), ns("12 345.25"), ns("1e+30"),
+		)))
+	}
+
+	err = vm.Run("[fmt_cn(123456789), fmt_cn(100000005), fmt_cn(100050000), fmt_cn(-12345.5), fmt_cn(9999), fmt_cn(10000000000000000), fmt_cn(1e20), fmt_cn(-1.5e20)]")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, na(
+			ns("1亿2345万6789"), ns("1亿0005"), ns("1亿0005万"), ns("-1万2345.5"), ns("9999"), ns("1亿亿"),
+			ns("1e+20"), ns("-1.5e+20"),
+		)))
+	}
+
+	err = vm.Run("fmt_cn('1')")
+	assert.Error(t, err)
+}
+
+func TestNativeFunctionHashAndEncoding(t *testing.T) {
+	vm := NewVM()
+	err := vm.Run("[md5('abc'), sha256(''), b64encode('骰子'), b64decode(b64encode('hello')), hex(255), hex(-16), hex('AB')]")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, na(
+			ns("900150983cd24fb0d6963f7d28e17f72"),
+			ns("e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"),
+			ns("6aqw5a2Q"),
+			ns("hello"),
+			ns("ff"),
+			ns("-10"),
+			ns("4142"),
+		)))
+	}
+
+	vm = NewVM()
+	err = vm.Run("b64decode('!!!')")
+	assert.Error(t, err)
+
+	vm = NewVM()
+	err = vm.Run("md5(123)")
+	assert.Error(t, err)
+
+	vm = NewVM()
+	vm.Config.MaxStringLen = 8
+	err = vm.Run("md5('12345678')")
+	assert.NoError(t, err)
+	err = vm.Run("md5('123456789')")
+	assert.Error(t, err)
+	err = vm.Run("b64encode('1234567')") // 结果长度为12
+	assert.Error(t, err)
+}
+
+func TestNativeFunctionJSON(t *testing.T) {
+	vm := NewVM()
+	err := vm.Run(`x = json_parse('{"hp": 12, "rate": 0.5, "tags": ["a", null], "ok": true}'); [x.hp, x.rate, x.tags, x.ok]`)
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, na(ni(12), nf(0.5), na(ns("a"), NewNullVal()), nb(true))))
+	}
+
+	vm = NewVM()
+	err = vm.Run(`json_str({'b': [1, 2.5, 'x'], 'a': null})`)
+	if assert.NoError(t, err) {
+		assert.Equal(t, `{"a":null,"b":[1,2.5,"x"]}`, vm.Ret.ToString())
+	}
+
+	vm = NewVM()
+	err = vm.Run(`json_parse(json_str([1, {'a': 2}])) == [1, {'a': 2}]`)
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, nb(true)))
+	}
+
+	vm = NewVM()
+	err = vm.Run(`json_parse('{"a": ')`)
+	assert.Error(t, err)
+
+	vm = NewVM()
+	err = vm.Run(`func f() { return 1 }; json_str([f])`)
+	assert.Error(t, err)
+
+	vm = NewVM()
+	err = vm.Run(`a = [1]; a[0] = a; json_str(a)`)
+	assert.Error(t, err)
+}
+
+func TestNativeFunctionChance(t *testing.T) {
+	vm := NewVM()
+	vm.Config.EnablePercentLiteral = true
+	err := vm.Run("[chance(0%), chance(100%), chance(0), chance(100), chance(0.0), chance(1.0)]")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, na(nb(false), nb(true), nb(false), nb(true), nb(false), nb(true))))
+	}
+
+	vm = NewVM()
+	vm.Config.EnablePercentLiteral = true
+	err = vm.Run("n = 0; for i in range(1000) { n = n + chance(35%) }; n")
+	if assert.NoError(t, err) {
+		n := vm.Ret.MustReadInt()
+		assert.True(t, n > 250 && n < 450, n)
+	}
+
+	vm = NewVM()
+	err = vm.Run("chance('a')")
+	assert.Error(t, err)
+}
+
+func TestNativeFunctionOpposed(t *testing.T) {
+	test := func(rs *RuleSet, expr string, winner IntType, margin *VMValue) {
+		vm := NewVM()
+		vm.Config.RuleSet = rs
+		err := vm.Run("r = " + expr + "; [r.winner, r.tie, r.margin]")
+		tie := winner == 0
+		if assert.NoError(t, err, expr) {
+			assert.True(t, valueEqual(vm.Ret, na(ni(winner), nb(tie), margin)), "%s: %s", expr, vm.Ret.ToString())
+		}
+	}
+
+	test(nil, "opposed(15, 12)", 1, ni(3))
+	test(nil, "opposed(3, 9.5)", 2, nf(6.5))
+	test(nil, "opposed(7, 7)", 0, ni(0))
+
+	// 默认规则: 先比较是否成功，再比较成功等级和差值
+	rich := &RuleSet{RichCheck: true}
+	test(rich, "opposed(18 >= 15, 12 >= 10)", 1, ni(1))
+	test(rich, "opposed(13 >= 15, 9 >= 10)", 2, ni(1))
+	test(rich, "opposed(16 >= 15, 9 >= 10)", 1, ni(2))
+
+	// COC: 等级高者胜，等级相同时技能高者胜，双方失败为平局
+	test(RuleSetCoC, "opposed(10 <= 60, 20 <= 50)", 1, ni(20))
+	test(RuleSetCoC, "opposed(40 <= 60, 30 <= 50)", 1, ni(0))
+	test(RuleSetCoC, "opposed(45 <= 50, 45 <= 60)", 2, ni(10))
+	test(RuleSetCoC, "opposed(70 <= 60, 80 <= 50)", 0, ni(20))
+
+	// 双方的骰点都出现在过程中
+	vm := NewVM()
+	err := vm.Run("opposed(d20 + 5, d20 + 3)")
+	if assert.NoError(t, err) {
+		assert.Equal(t, 2, strings.Count(vm.GetDetailText(), "d20"))
+	}
+
+	for _, expr := range []string{"opposed('a', 1)", "opposed(1, 1 < 2)"} {
+		vm = NewVM()
+		vm.Config.RuleSet = rich
+		err = vm.Run(expr)
+		assert.Error(t, err, expr)
+	}
+}
+
+func TestRuleSetBuiltins(t *testing.T) {
+	rs := &RuleSet{
+		HitLocations: []HitLocation{{4, "右腿"}, {8, "左腿"}, {11, "腹部"}, {15, "胸部"}, {17, "右臂"}, {19, "左臂"}, {20, "头部"}},
+		DamageMods: map[string]map[string]float64{
+			"火焰": {"皮甲": 1.5, "板甲": 1},
+			"穿刺": {"板甲": 0.5, "锁甲": 0},
+		},
+	}
+
+	vm := NewVM()
+	vm.Config.RuleSet = rs
+	err := vm.Run("[hit_location(1), hit_location(9), hit_location(20), damage_type_mod('火焰', '皮甲'), damage_type_mod('穿刺', '锁甲'), damage_type_mod('钝击', '板甲')]")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, na(ns("右腿"), ns("腹部"), ns("头部"), nf(1.5), ni(0), ni(1))))
+	}
+
+	vm = NewVM()
+	vm.Config.RuleSet = rs
+	err = vm.Run("hit_location()")
+	if assert.NoError(t, err) {
+		assert.Contains(t, []string{"右腿", "左腿", "腹部", "胸部", "右臂", "左臂", "头部"}, vm.Ret.ToString())
+	}
+
+	// 规则集可以覆盖内置函数，WithBuiltins 的优先级更高
+	rs2 := &RuleSet{Builtins: map[string]*VMValue{
+		"hit_location": NewNativeFunctionVal(&NativeFunctionData{"hit_location", []string{}, nil, nil, func(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
+			return NewStrVal("躯干")
+		}}),
+	}}
+	vm = NewVM()
+	vm.Config.RuleSet = rs2
+	err = vm.Run("hit_location()")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "躯干", vm.Ret.ToString())
+	}
+
+	vm = NewVM(WithBuiltins(map[string]*VMValue{"hit_location": ns("头部")}))
+	vm.Config.RuleSet = rs2
+	err = vm.Run("hit_location")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "头部", vm.Ret.ToString())
+	}
+
+	for _, expr := range []string{"hit_location(21)", "hit_location('a')", "damage_type_mod(1, '皮甲')"} {
+		vm = NewVM()
+		vm.Config.RuleSet = rs
+		err = vm.Run(expr)
+		assert.Error(t, err, expr)
+	}
+	vm = NewVM()
+	err = vm.Run("hit_location()")
+	assert.Error(t, err)
+}
+
+func TestNativeFunctionMeanVariance(t *testing.T) {
+	vm := NewVM()
+	err := vm.Run("[mean('3d6'), variance('3d6'), mean('d20 + 5'), variance('2 * (d6 - 1)'), mean('-d4'), variance('7')]")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, na(nf(10.5), nf(8.75), nf(15.5), nf(35.0/3), nf(-2.5), nf(0))), vm.Ret.ToString())
+	}
+
+	// 无法精确计算时进行模拟
+	vm = NewVM()
+	err = vm.Run("[mean('d20优势', 5000), variance('d20优势', 5000)]")
+	if assert.NoError(t, err) {
+		lst := vm.Ret.MustReadArray().List
+		mean, variance := lst[0].MustReadFloat(), lst[1].MustReadFloat()
+		assert.InDelta(t, 13.825, mean, 0.5)
+		assert.InDelta(t, 22.0, variance, 3)
+	}
+
+	for _, expr := range []string{"mean(1)", "mean('d6 +')", "mean('\"a\"')", "mean('d6', 0)"} {
+		vm = NewVM()
+		err = vm.Run(expr)
+		assert.Error(t, err, expr)
+	}
+
+	// 模拟消耗的算力计入当前vm
+	vm = NewVM()
+	vm.Config.OpCountLimit = 1000
+	err = vm.Run("mean('d20优势', 10000)")
+	assert.Error(t, err)
+}
+
+func TestNativeFunctionJoin(t *testing.T) {
+	vm := NewVM()
+	err := vm.Run("[join([3, 5], ' + '), join(['a', 'b']), join([]), join((1, 2), '/')]")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, na(ns("3 + 5"), ns("a, b"), ns(""), ns("1/2"))))
+	}
+
+	vm = NewVM()
+	err = vm.Run("[joinf([3, 5], '第{i}次: {}', '; '), joinf([{'name': 'A', 'hp': 3}, {'name': 'B', 'hp': 0}], '{name}: {hp}')]")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, na(ns("第1次: 3; 第2次: 5"), ns("A: 3, B: 0"))))
+	}
+
+	for _, expr := range []string{"join(1)", "join([1], 2)", "joinf([1], 2)", "joinf([1], '{x}')"} {
+		vm = NewVM()
+		err = vm.Run(expr)
+		assert.Error(t, err, expr)
+	}
+
+	vm = NewVM()
+	vm.Config.MaxStringLen = 8
+	err = vm.Run("join(list(range(100)))")
+	assert.Error(t, err)
+}
+
+func TestGroupRoll(t *testing.T) {
+	vm := NewVM()
+	err := vm.Run("感知 = {'甲': 100, '乙': -100}; group_roll('d20 + 感知[it] > 0', ['甲', '乙'])")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "[('甲', true), ('乙', false)]", vm.Ret.ToString())
+	}
+
+	vm = NewVM()
+	err = vm.Run("it = 'x'; &c = target * 2; [group_roll(&c, [1, 2]), group_roll(&(it + 1), range(2)), it]")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "[[(1, 2), (2, 4)], [(0, 1), (1, 2)], 'x']", vm.Ret.ToString())
+	}
+
+	vm = NewVM()
+	err = vm.Run("func f(x) { return x * 10 }; &g(x) = x - 1; [group_roll(f, [1, 2]), group_roll(&g, [5])]")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "[[(1, 10), (2, 20)], [(5, 4)]]", vm.Ret.ToString())
+	}
+
+	// 目标不会留在computed的变量中
+	vm = NewVM()
+	err = vm.Run("&c = this.it ?? 0; group_roll(&c, [1, 2]); [&c.it ?? 'none', c]")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "['none', 0]", vm.Ret.ToString())
+	}
+
+	// 带缓存的computed对每个目标重新计算
+	vm = NewVM()
+	err = vm.Run("&m = target + 1; memo(&m); group_roll(&m, [1, 2])")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "[(1, 2), (2, 3)]", vm.Ret.ToString())
+	}
+
+	for _, expr := range []string{"group_roll(1, [1])", "group_roll('d20', 3)", "group_roll('it - \"a\"', [1])"} {
+		vm = NewVM()
+		err = vm.Run(expr)
+		assert.Error(t, err, expr)
+	}
+}
+
+func TestNativeFunctionPool(t *testing.T) {
+	// 每颗骰子计入算力
+	vm := NewVM()
+	vm.Config.OpCountLimit = 1000
+	err := vm.Run("pool(5000, 6)")
+	assert.Error(t, err)
+
+	// 使用当前的结算模式
+	vm = NewVM()
+	err = vm.Run("max(pool(3, 6))")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, na(ni(6), ni(6), ni(6))))
+	}
+	vm = NewVM()
+	vm.Config.DiceMinMode = true
+	err = vm.Run("pool(2, 6)")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, na(ni(1), ni(1))))
+	}
+
+	// 计算过程放在调用处
+	vm = NewVM()
+	vm.Config.DiceMaxMode = true
+	err = vm.Run("pool(2, 6).len() + d6")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "[6, 6][pool(2, 6)].len() + 6[d6]", vm.GetDetailText())
+	}
+}
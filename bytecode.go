@@ -15,10 +15,17 @@ type ByteCode struct {
 const (
 	typePushIntNumber CodeType = iota
 	typePushFloatNumber
+	typePushBool
+	typePushQuantity
+	typePushMoney
 	typePushString
 	typePushArray
 	typePushDict
+	typePushTable
+	typePushTuple
+	typePushSet
 	typePushRange
+	typeRange // 范围 a..b，不同于 typePushRange 不会创建数组
 	typePushComputed
 	typePushNull
 	typePushThis
@@ -26,14 +33,19 @@ const (
 	typePushFunction
 	typePushLast
 	typePushDefaultExpr
+	typeSpread // 将栈顶的值标记为待展开，用于 [...x]
 
 	typeLoadFormatString
 	typeLoadName
 	typeLoadNameWithDetail
-	typeLoadNameRaw // 如遇到computed，这个版本不取出其内容
+	typeLoadNameRaw         // 如遇到computed，这个版本不取出其内容
+	typeLoadConstWithDetail // Specialize 代入的常量，计算过程与 typeLoadNameWithDetail 相同
 	typeStoreName
 	typeStoreNameGlobal
 	typeStoreNameLocal
+	typeStoreNameConst
+	typeDeleteName
+	typeUnpack // 解构赋值，将栈顶的元组或数组的各项倒序压栈，原值保留在下方
 
 	typeInvoke
 	typeInvokeSelf
@@ -41,6 +53,7 @@ const (
 	typeItemSet
 	typeAttrGet
 	typeAttrSet
+	typeAttrGetPath // 用于多级属性赋值，可自动创建中间的字典
 	typeSliceGet
 	typeSliceSet
 
@@ -61,8 +74,12 @@ const (
 
 	typeBitwiseAnd
 	typeBitwiseOr
-	typeLogicAnd
+	typeBitwiseXor
+	typeShiftLeft
+	typeShiftRight
+	typeIn
 	typeLogicOr
+	typeLogicNot
 
 	typeNegation
 	typePositive
@@ -75,12 +92,21 @@ const (
 	typeDiceSetDropHighNum
 	typeDiceSetMin
 	typeDiceSetMax
+	typeDiceSetExplode
 	typeDice
 	typeCustomDice
+	typeRollModePush // 其中的骰子以最大值(1)或最小值(-1)结算，如 max(3d6)
+	typeRollModePop
+	typeDetailNote  // 在计算过程中加入注释，如 note('触发背刺')
+	typeDetailQuiet // 计算过程中不展开其中的骰点，如 quiet(d100)
+	typeCSEMark     // 公共子表达式开始，见 eliminateCommonSubexpr
+	typeCSEStore    // 保存公共子表达式的结果
+	typeCSELoad     // 读取保存的结果，成功时压入结果和1，否则压入0，随后的je跳过子表达式
 
 	typeDiceCocPenalty
 	typeDiceCocBonus
 	typeDiceFate
+	typeDiceFateN // 4dF，个数来自骰子状态，同 typeDice
 	typeDiceWod
 	typeWodSetInit       // 重置参数
 	typeWodSetPool       // 设置骰池(骰数)
@@ -103,18 +129,29 @@ const (
 	typeJe
 	typeJne
 	typeJeDup
+	typeJneDup     // 为假时保留栈顶的值并跳转，用于 && 的短路求值
+	typeJnnDup     // 不为空值时保留栈顶的值并跳转，用于 ?? 的短路求值
+	typeJmpTable   // match的跳转表，栈顶为整数时按表跳转到对应分支，见 ParserData.MatchEnd
+	typeMatchValue // match的值分支，栈顶的值与次栈顶(被匹配的值)相等时压入true
+	typeMatchRange // match的范围分支 a..b，栈顶的两个值为范围的两端，其下为被匹配的值
 	typeReturn
+	typeIterBegin // 将栈顶的值替换为其迭代器，用于for-in
+	typeIterNext  // 从for-in的迭代器中取下一项并赋值给循环变量，取到时压入1，否则压入0
+	typeYield     // 生成器函数交出一个值
 
 	typeFStringBlockPush // fstring标记 用于栈平衡
 	typeFStringBlockPop
 
 	typeBlockPush
 	typeBlockPop
+	typeBlockUnwind // 弹出n层语句块但不改变栈，用于break/continue
 
 	typeStSetName
 	typeStModify
 	typeStX0
 	typeStX1
+
+	typePushDuration // 时长，如 1h30m
 )
 
 func (code *ByteCode) CodeString() string {
@@ -123,14 +160,30 @@ func (code *ByteCode) CodeString() string {
 		return "push.int " + strconv.FormatInt(int64(code.Value.(IntType)), 10)
 	case typePushFloatNumber:
 		return "push.flt " + strconv.FormatFloat(code.Value.(float64), 'f', 2, 64)
+	case typePushBool:
+		return "push.bool " + strconv.FormatBool(code.Value.(bool))
+	case typePushDuration:
+		return fmt.Sprintf("push.dur %v", code.Value)
+	case typePushQuantity:
+		return "push.qty " + code.Value.(QuantityData).String()
+	case typePushMoney:
+		return "push.money " + code.Value.(MoneyData).String()
 	case typePushString:
 		return "push.str " + code.Value.(string)
+	case typeRange:
+		return "range"
 	case typePushRange:
 		return "push.range"
 	case typePushArray:
 		return "push.arr " + strconv.FormatInt(int64(code.Value.(IntType)), 10)
 	case typePushDict:
 		return "push.dict " + strconv.FormatInt(int64(code.Value.(IntType)), 10)
+	case typePushTable:
+		return "push.table " + strconv.FormatInt(int64(code.Value.(IntType)), 10)
+	case typePushTuple:
+		return "push.tuple " + strconv.FormatInt(int64(code.Value.(IntType)), 10)
+	case typePushSet:
+		return "push.set " + strconv.FormatInt(int64(code.Value.(IntType)), 10)
 	case typePushComputed:
 		computed, _ := code.Value.(*VMValue).ReadComputed()
 		return "push.computed " + computed.Expr
@@ -143,6 +196,8 @@ func (code *ByteCode) CodeString() string {
 	case typePushFunction:
 		computed, _ := code.Value.(*VMValue).ReadFunctionData()
 		return "push.func " + computed.Name
+	case typeSpread:
+		return "spread"
 
 	case typeInvoke:
 		return "invoke " + strconv.FormatInt(int64(code.Value.(IntType)), 10)
@@ -157,6 +212,8 @@ func (code *ByteCode) CodeString() string {
 		return "attr.set " + code.Value.(string)
 	case typeAttrGet:
 		return "attr.get " + code.Value.(string)
+	case typeAttrGetPath:
+		return fmt.Sprintf("attr.get.path %s", code.Value)
 	case typeSliceGet:
 		return "slice.get"
 	case typeSliceSet:
@@ -177,15 +234,23 @@ func (code *ByteCode) CodeString() string {
 	case typeNullCoalescing:
 		return "nullCoalescing"
 
-	case typeLogicAnd:
-		return "and"
 	case typeLogicOr:
 		return "or"
+	case typeLogicNot:
+		return "not"
 
 	case typeBitwiseAnd:
 		return "&"
 	case typeBitwiseOr:
 		return "|"
+	case typeBitwiseXor:
+		return "^"
+	case typeShiftLeft:
+		return "<<"
+	case typeShiftRight:
+		return ">>"
+	case typeIn:
+		return "in"
 
 	case typeNegation:
 		return "neg"
@@ -208,10 +273,26 @@ func (code *ByteCode) CodeString() string {
 		return "dice.setMin"
 	case typeDiceSetMax:
 		return "dice.setMax"
+	case typeDiceSetExplode:
+		return "dice.setExplode"
 	case typeDice:
 		return "dice"
 	case typeCustomDice:
 		return "dice.custom"
+	case typeRollModePush:
+		return "roll.mode.push " + strconv.FormatInt(int64(code.Value.(IntType)), 10)
+	case typeRollModePop:
+		return "roll.mode.pop"
+	case typeDetailNote:
+		return "detail.note"
+	case typeDetailQuiet:
+		return "detail.quiet"
+	case typeCSEMark:
+		return "cse.mark " + strconv.FormatInt(int64(code.Value.(IntType)), 10)
+	case typeCSEStore:
+		return "cse.store " + strconv.FormatInt(int64(code.Value.(IntType)), 10)
+	case typeCSELoad:
+		return "cse.load " + strconv.FormatInt(int64(code.Value.(IntType)), 10)
 
 	case typeDiceCocPenalty:
 		return "coc.penalty"
@@ -219,6 +300,8 @@ func (code *ByteCode) CodeString() string {
 		return "coc.bonus"
 	case typeDiceFate:
 		return "dice.fate"
+	case typeDiceFateN:
+		return "dice.fateN"
 	case typeWodSetInit:
 		return "wod.init"
 	case typeWodSetPool:
@@ -245,6 +328,8 @@ func (code *ByteCode) CodeString() string {
 		return "ld.d " + code.Value.(string)
 	case typeLoadNameRaw:
 		return "ld.raw " + code.Value.(string)
+	case typeLoadConstWithDetail:
+		return "ld.const.d " + code.Value.(*VMValue).ToString()
 	case typeLoadFormatString:
 		return fmt.Sprintf("ld.fs %d", code.Value)
 	case typeStoreName:
@@ -253,6 +338,12 @@ func (code *ByteCode) CodeString() string {
 		return fmt.Sprintf("store.global %s", code.Value)
 	case typeStoreNameLocal:
 		return fmt.Sprintf("store.local %s", code.Value)
+	case typeStoreNameConst:
+		return fmt.Sprintf("store.const %s", code.Value)
+	case typeDeleteName:
+		return fmt.Sprintf("del %s", code.Value)
+	case typeUnpack:
+		return fmt.Sprintf("unpack %d", code.Value)
 	case typeHalt:
 		return "halt"
 	case typeDetailMark:
@@ -264,8 +355,18 @@ func (code *ByteCode) CodeString() string {
 		return fmt.Sprintf("je %d", code.Value)
 	case typeJeDup:
 		return fmt.Sprintf("je.dup %d", code.Value)
+	case typeJneDup:
+		return fmt.Sprintf("jne.dup %d", code.Value)
+	case typeJnnDup:
+		return fmt.Sprintf("jnn.dup %d", code.Value)
 	case typeJne:
 		return fmt.Sprintf("jne %d", code.Value)
+	case typeJmpTable:
+		return fmt.Sprintf("jmp.table %v", code.Value)
+	case typeMatchValue:
+		return "match.value"
+	case typeMatchRange:
+		return "match.range"
 	case typeCompLT:
 		return "comp.lt"
 	case typeCompLE:
@@ -290,7 +391,15 @@ func (code *ByteCode) CodeString() string {
 		return "nop"
 	case typeReturn:
 		return "ret"
+	case typeYield:
+		return "yield"
+	case typeIterBegin:
+		return "iter.begin"
+	case typeIterNext:
+		return fmt.Sprintf("iter.next %s", code.Value)
 
+	case typeBlockUnwind:
+		return fmt.Sprintf("block.unwind %v", code.Value)
 	case typeBlockPush:
 		return "block.push"
 	case typeBlockPop:
@@ -0,0 +1,45 @@
+package dicescript
+
+import "fmt"
+
+// 内置函数所需的能力，见 WithCapabilityTags 和 RollConfig.AllowedCapabilities
+const (
+	CapNet     = "net"     // 访问网络
+	CapTime    = "time"    // 读取当前时间
+	CapStorage = "storage" // 读写宿主程序的储存
+)
+
+// builtinCapabilities 默认内置函数所需的能力
+var builtinCapabilities = map[string][]string{
+	"now": {CapTime},
+}
+
+// capabilitiesOf 函数所需的能力，按函数名查找
+func (ctx *Context) capabilitiesOf(name string) []string {
+	if caps, ok := ctx.capTags[name]; ok {
+		return caps
+	}
+	return builtinCapabilities[name]
+}
+
+// checkCapabilities 检查本次执行是否允许调用函数，不允许时设置错误
+func (ctx *Context) checkCapabilities(name string) bool {
+	allowed := ctx.Config.AllowedCapabilities
+	if allowed == nil {
+		return true
+	}
+	for _, c := range ctx.capabilitiesOf(name) {
+		ok := false
+		for _, a := range allowed {
+			if a == c {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			ctx.Error = fmt.Errorf("(%s)调用失败: 需要 %s 权限", name, c)
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,183 @@
+package dicescript
+
+import (
+	"encoding/json"
+	"errors"
+
+	"golang.org/x/exp/rand"
+)
+
+// Checkpoint 在 await_input 处暂停的执行，可以用 ToJSON 保存，收到用户的输入后用 Context.Resume 继续。
+// 继续时以暂停前的随机源状态从头重新执行语句，之前的 await_input 依次得到之前的输入，因此骰点与暂停前一致
+type Checkpoint struct {
+	Expr   string
+	Seed   []byte     // 执行前的随机源状态
+	Inputs []*VMValue // 之前各次 await_input 得到的输入
+	Prompt string     // 正在等待的输入的提示，如 "选择目标"
+}
+
+// AwaitInputError 执行到 await_input 时暂停，Run 返回此错误
+type AwaitInputError struct {
+	Checkpoint *Checkpoint
+}
+
+func (e *AwaitInputError) Error() string {
+	return "等待输入: " + e.Checkpoint.Prompt
+}
+
+type checkpointJSON struct {
+	Expr   string            `json:"expr"`
+	Seed   []byte            `json:"seed"`
+	Inputs []json.RawMessage `json:"inputs"`
+	Prompt string            `json:"prompt"`
+}
+
+// ToJSON 序列化，可用 CheckpointFromJSON 读取
+func (cp *Checkpoint) ToJSON() ([]byte, error) {
+	data := checkpointJSON{Expr: cp.Expr, Seed: cp.Seed, Prompt: cp.Prompt}
+	for _, v := range cp.Inputs {
+		raw, err := v.ToJSON()
+		if err != nil {
+			return nil, err
+		}
+		data.Inputs = append(data.Inputs, raw)
+	}
+	return json.Marshal(data)
+}
+
+// CheckpointFromJSON 读取 Checkpoint.ToJSON 的结果，输入中的函数等值按opts检查，opts为nil时不检查
+func CheckpointFromJSON(data []byte, opts *JSONLoadOptions) (*Checkpoint, error) {
+	var raw checkpointJSON
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	cp := &Checkpoint{Expr: raw.Expr, Seed: raw.Seed, Prompt: raw.Prompt}
+	for _, item := range raw.Inputs {
+		var v *VMValue
+		var err error
+		if opts != nil {
+			v, err = VMValueFromJSONWithOptions(item, opts)
+		} else {
+			v, err = VMValueFromJSON(item)
+		}
+		if err != nil {
+			return nil, err
+		}
+		cp.Inputs = append(cp.Inputs, v)
+	}
+	return cp, nil
+}
+
+// Resume 以input作为暂停处 await_input 的结果继续执行。如果之后又遇到 await_input，会再次返回 AwaitInputError。
+// 应当使用与暂停前相同的配置
+func (ctx *Context) Resume(cp *Checkpoint, input *VMValue) error {
+	if cp == nil {
+		return errors.New("没有可以继续的执行")
+	}
+	ctx.awaitInputs = append(append([]*VMValue(nil), cp.Inputs...), input)
+	defer func() { ctx.awaitInputs = nil }()
+	return ctx.RunWith(cp.Expr, RunOptions{Seed: cp.Seed})
+}
+
+// funcAwaitInput 等待用户输入，如 target = await_input('选择目标')。
+// 已有输入时(继续执行时)返回对应的输入，否则暂停执行，见 Checkpoint
+func funcAwaitInput(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
+	prompt, ok := params[0].ReadString()
+	if !ok {
+		ctx.Error = errors.New("(await_input)类型错误: 提示必须为str")
+		return nil
+	}
+	root := ctx.rootCtx()
+	if root.awaitIndex < len(root.awaitInputs) {
+		v := root.awaitInputs[root.awaitIndex]
+		root.awaitIndex++
+		return v.Clone()
+	}
+	ctx.Error = &AwaitInputError{Checkpoint: &Checkpoint{Prompt: prompt}}
+	return nil
+}
+
+// writeJournal 可能暂停的执行中被修改的变量原来的值。暂停时还原，继续执行时从头重新修改，
+// 因此 hp = hp - d6; await_input(...) 这样的语句不会重复扣除
+type writeJournal struct {
+	names []string
+	olds  []func() *VMValue // 返回nil表示原来不存在
+	seen  map[string]bool
+}
+
+func (j *writeJournal) record(ctx *Context, name string) {
+	if j == nil || j.seen[name] {
+		return
+	}
+	j.seen[name] = true
+	j.names = append(j.names, name)
+	// 尚在预读的全局变量等到需要还原时再取值，不暂停时无需等待
+	if wait := ctx.pendingGlobal(name); wait != nil {
+		j.olds = append(j.olds, wait)
+		return
+	}
+	old := ctx.peekName(name)
+	j.olds = append(j.olds, func() *VMValue { return old })
+}
+
+func (j *writeJournal) rollback(ctx *Context) {
+	for i := len(j.names) - 1; i >= 0; i-- {
+		if old := j.olds[i](); old == nil {
+			ctx.DeleteName(j.names[i], true)
+		} else {
+			ctx.StoreName(j.names[i], old, true)
+		}
+	}
+}
+
+// journalWrite 修改变量前记录原来的值，记录在根vm上。子vm(函数、computed)的局部变量随子vm丢弃，
+// 只记录会留到执行之后的修改，即作用域变量和可能由钩子处理的变量
+func (ctx *Context) journalWrite(name string, useHook bool) {
+	root := ctx.rootCtx()
+	if root.journal == nil {
+		return
+	}
+	if ctx != root {
+		r, _ := ctx.getScopeResolver(name)
+		hooked := useHook && (ctx.Config.HookValueStore != nil || ctx.Config.HookValueDelete != nil)
+		if r == nil && !hooked {
+			return
+		}
+	}
+	root.journal.record(root, name)
+}
+
+// beginAwait 执行前的准备，开始记录变量的修改。
+// await_input 可能以别名或在函数中调用，无法事先判断，因此总是记录
+func (ctx *Context) beginAwait() {
+	if ctx.UpCtx != nil {
+		return
+	}
+	ctx.awaitIndex = 0
+	ctx.journal = &writeJournal{seen: map[string]bool{}}
+}
+
+// endAwait 执行后如果暂停了，补全 Checkpoint 并还原修改过的变量
+func (ctx *Context) endAwait(randState *rand.PCGSource) {
+	if ctx.UpCtx != nil {
+		return
+	}
+	journal := ctx.journal
+	ctx.journal = nil
+	var ae *AwaitInputError
+	if !errors.As(ctx.Error, &ae) {
+		return
+	}
+	cp := ae.Checkpoint
+	cp.Expr = string(ctx.parser.data)
+	cp.Seed, _ = randState.MarshalBinary()
+	cp.Inputs = append([]*VMValue(nil), ctx.awaitInputs[:ctx.awaitIndex]...)
+	if journal != nil {
+		err := ctx.Error
+		ctx.Error = nil
+		journal.rollback(ctx)
+		if ctx.Error == nil {
+			ctx.Error = err
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package dicescript
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAwaitInputResume(t *testing.T) {
+	vm := NewVM()
+	vm.StoreName("hp", ni(20), false)
+	expr := "hp = hp - d6; t = await_input('选择目标'); n = await_input('次数'); `{t}:{n}:{hp}`"
+	err := vm.Run(expr)
+	var ae *AwaitInputError
+	if !assert.True(t, errors.As(err, &ae)) {
+		return
+	}
+	cp := ae.Checkpoint
+	assert.Equal(t, "选择目标", cp.Prompt)
+	assert.Equal(t, expr, cp.Expr)
+	assert.Empty(t, cp.Inputs)
+	// 暂停时还原修改过的变量
+	assert.True(t, valueEqual(vm.Attrs.MustLoad("hp"), ni(20)))
+	_, exists := vm.Attrs.Load("t")
+	assert.False(t, exists)
+
+	// 保存后在新的vm上继续
+	data, err := cp.ToJSON()
+	assert.NoError(t, err)
+	cp, err = CheckpointFromJSON(data, nil)
+	assert.NoError(t, err)
+	vm2 := NewVM()
+	vm2.StoreName("hp", ni(20), false)
+	err = vm2.Resume(cp, NewStrVal("哥布林"))
+	if !assert.True(t, errors.As(err, &ae)) {
+		return
+	}
+	cp = ae.Checkpoint
+	assert.Equal(t, "次数", cp.Prompt)
+	assert.Len(t, cp.Inputs, 1)
+
+	assert.NoError(t, vm2.Resume(cp, ni(2)))
+	hp := vm2.LoadName("hp", false, false).MustReadInt()
+	assert.True(t, hp >= 14 && hp <= 19)
+	assert.Equal(t, "哥布林:2:"+ni(hp).ToString(), vm2.Ret.ToString())
+
+	// 同样的检查点得到同样的骰点
+	vm3 := NewVM()
+	vm3.StoreName("hp", ni(20), false)
+	assert.NoError(t, vm3.Resume(cp, ni(2)))
+	assert.Equal(t, vm2.Ret.ToString(), vm3.Ret.ToString())
+	assert.Equal(t, vm2.GetDetailText(), vm3.GetDetailText())
+}
+
+func TestAwaitInputInFunction(t *testing.T) {
+	vm := NewVM()
+	err := vm.Run("func ask(p) { return await_input(p) }; ask('目标') + 1")
+	var ae *AwaitInputError
+	if assert.True(t, errors.As(err, &ae)) {
+		assert.Equal(t, "目标", ae.Checkpoint.Prompt)
+		_, exists := vm.Attrs.Load("ask")
+		assert.False(t, exists)
+		assert.NoError(t, vm.Resume(ae.Checkpoint, ni(2)))
+		assert.True(t, valueEqual(vm.Ret, ni(3)))
+	}
+
+	err = vm.Run("await_input(1)")
+	assert.Error(t, err)
+	assert.False(t, errors.As(err, &ae))
+}
+
+func TestAwaitInputJournal(t *testing.T) {
+	// 以别名调用 await_input 时同样还原修改
+	vm := NewVM()
+	vm.StoreName("hp", ni(20), false)
+	err := vm.Run("ask = await_input; hp = hp - 1; ask('目标'); hp")
+	var ae *AwaitInputError
+	if assert.True(t, errors.As(err, &ae)) {
+		assert.True(t, valueEqual(vm.Attrs.MustLoad("hp"), ni(20)))
+		assert.NoError(t, vm.Resume(ae.Checkpoint, ni(1)))
+		assert.True(t, valueEqual(vm.Ret, ni(19)))
+	}
+
+	// 函数中经钩子写入的变量也会还原，继续执行时不会重复扣除
+	store := map[string]*VMValue{"hp": ni(20)}
+	vm = NewVM()
+	vm.GlobalValueLoadFunc = func(name string) *VMValue {
+		return store[name]
+	}
+	vm.Config.HookValueStore = func(ctx *Context, name string, v *VMValue) (*VMValue, bool) {
+		if name == "hp" {
+			store[name] = v
+			return nil, true
+		}
+		return nil, false
+	}
+	err = vm.Run("func hit() { hp = hp - 1 }; hit(); await_input('目标'); hp")
+	if assert.True(t, errors.As(err, &ae)) {
+		assert.True(t, valueEqual(store["hp"], ni(20)))
+		assert.NoError(t, vm.Resume(ae.Checkpoint, ni(1)))
+		assert.True(t, valueEqual(vm.Ret, ni(19)))
+		assert.True(t, valueEqual(store["hp"], ni(19)))
+	}
+}
@@ -0,0 +1,309 @@
+package dicescript
+
+import (
+	"crypto/sha256"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// CodeCache 字节码缓存的储存，由宿主程序实现或使用 NewDirCodeCache。
+// 解析过的语句以字节码形式保存，再次解析同样的语句时直接读取，省去解析的开销。
+// key由引擎版本、语法和影响解析的配置共同决定，语法或指令变动后旧的缓存自然失效
+type CodeCache interface {
+	Get(key string) ([]byte, bool)
+	Set(key string, data []byte)
+}
+
+// DirCodeCache 将字节码缓存写入目录，每条语句一个文件
+type DirCodeCache struct {
+	Dir string
+}
+
+// NewDirCodeCache 使用给定目录作为字节码缓存，目录不存在时自动创建
+func NewDirCodeCache(dir string) (*DirCodeCache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &DirCodeCache{Dir: dir}, nil
+}
+
+func (c *DirCodeCache) Get(key string) ([]byte, bool) {
+	data, err := os.ReadFile(filepath.Join(c.Dir, key))
+	if err != nil {
+		return nil, false
+	}
+	return data, true
+}
+
+// Set 写入缓存，失败时忽略，下次重新解析即可
+func (c *DirCodeCache) Set(key string, data []byte) {
+	tmp, err := os.CreateTemp(c.Dir, key+".*.tmp")
+	if err != nil {
+		return
+	}
+	_, err = tmp.Write(data)
+	if err1 := tmp.Close(); err == nil {
+		err = err1
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), filepath.Join(c.Dir, key))
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+	}
+}
+
+//go:embed roll.peg
+var grammarSource []byte
+
+// codeCacheVersion 引擎版本、语法和指令集的指纹，任何一方变动都会使旧的缓存失效
+var codeCacheVersion = func() string {
+	h := sha256.New()
+	h.Write([]byte(engineVersion))
+	h.Write(grammarSource)
+	fmt.Fprintf(h, "|%d", typeStX1)
+	return hex.EncodeToString(h.Sum(nil))
+}()
+
+// codeCacheKey 缓存的key，包含影响解析结果的配置项
+func (ctx *Context) codeCacheKey(expr string) string {
+	c := &ctx.Config
+	h := sha256.New()
+	fmt.Fprintf(h, "%s|%v%v%v%v%v%v|%v%v%v%v%v|%v%v|%d|", codeCacheVersion,
+		c.EnableDiceWoD, c.EnableDiceCoC, c.EnableDiceFate, c.EnableDiceDoubleCross, c.EnablePercentDice, c.EnablePercentLiteral,
+		c.DisableBitwiseOp, c.DisableStmts, c.DisableNDice, c.PercentAsInt, c.CaretAsXor,
+		c.Compat.ImplicitMultiply, c.EliminateCommonSubexpr, c.ParseExprLimit)
+	h.Write([]byte(expr))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// codeCacheable 单位、货币、自定义骰子和骰子别名由宿主程序在运行时注册，无法计入key，此时不使用缓存
+func (ctx *Context) codeCacheable() bool {
+	c := &ctx.Config
+	if c.RuleSet != nil && len(c.RuleSet.DiceAliases) > 0 || c.LenientParse {
+		// 宽松解析时实际解析的语句与输入不同
+		return false
+	}
+	return c.CodeCache != nil && c.Units == nil && c.Currency == nil && len(ctx.CustomDiceInfo) == 0
+}
+
+type cachedByteCode struct {
+	T    CodeType        `json:"t"`
+	Kind string          `json:"k,omitempty"`
+	V    json.RawMessage `json:"v,omitempty"`
+}
+
+// cachedBody 函数和computed的代码
+type cachedBody struct {
+	Computed  bool             `json:"computed,omitempty"`
+	Expr      string           `json:"expr"`
+	Name      string           `json:"name,omitempty"`
+	Params    []string         `json:"params,omitempty"`
+	Generator bool             `json:"generator,omitempty"`
+	Code      []cachedByteCode `json:"code"`
+}
+
+type cachedProgram struct {
+	Offset int              `json:"offset"` // 解析结束的位置
+	Code   []cachedByteCode `json:"code"`
+
+	Names     []string  `json:"names,omitempty"`
+	Stores    []string  `json:"stores,omitempty"`
+	HasAssign bool      `json:"hasAssign,omitempty"`
+	HasLoop   bool      `json:"hasLoop,omitempty"`
+	HasFunc   bool      `json:"hasFunc,omitempty"`
+	DiceSides []IntType `json:"diceSides,omitempty"`
+}
+
+func encodeCode(code []ByteCode) ([]cachedByteCode, error) {
+	ret := make([]cachedByteCode, len(code))
+	for i, c := range code {
+		item := cachedByteCode{T: c.T}
+		var v any
+		switch val := c.Value.(type) {
+		case nil:
+		case IntType:
+			item.Kind, v = "i", val
+		case float64:
+			item.Kind, v = "f", val
+		case bool:
+			item.Kind, v = "b", val
+		case string:
+			item.Kind, v = "s", val
+		case time.Duration:
+			item.Kind, v = "dur", int64(val)
+		case []IntType:
+			item.Kind, v = "ints", val
+		case BufferSpan:
+			item.Kind, v = "span", [2]IntType{val.Begin, val.End}
+		case StInfo:
+			item.Kind, v = "st", [2]string{val.Op, val.Text}
+		case *VMValue:
+			body := cachedBody{}
+			var err error
+			switch val.TypeId {
+			case VMTypeFunction:
+				fd, _ := val.ReadFunctionData()
+				body = cachedBody{Expr: fd.Expr, Name: fd.Name, Params: fd.Params, Generator: fd.isGenerator}
+				body.Code, err = encodeCode(fd.code[:fd.codeIndex])
+			case VMTypeComputedValue:
+				cd, _ := val.ReadComputed()
+				body = cachedBody{Computed: true, Expr: cd.Expr, Params: cd.Params}
+				body.Code, err = encodeCode(cd.code[:cd.codeIndex])
+			default:
+				return nil, fmt.Errorf("无法缓存的指令参数: %s", val.GetTypeName())
+			}
+			if err != nil {
+				return nil, err
+			}
+			item.Kind, v = "body", body
+		default:
+			// 金额、带单位的数、自定义骰子等与运行时注册的信息相关
+			return nil, fmt.Errorf("无法缓存的指令参数: %T", val)
+		}
+		if item.Kind != "" {
+			data, err := json.Marshal(v)
+			if err != nil {
+				return nil, err
+			}
+			item.V = data
+		}
+		ret[i] = item
+	}
+	return ret, nil
+}
+
+func decodeCode(items []cachedByteCode) ([]ByteCode, error) {
+	code := make([]ByteCode, len(items))
+	for i, item := range items {
+		code[i].T = item.T
+		var err error
+		switch item.Kind {
+		case "":
+		case "i":
+			var v IntType
+			err = json.Unmarshal(item.V, &v)
+			code[i].Value = v
+		case "f":
+			var v float64
+			err = json.Unmarshal(item.V, &v)
+			code[i].Value = v
+		case "b":
+			var v bool
+			err = json.Unmarshal(item.V, &v)
+			code[i].Value = v
+		case "s":
+			var v string
+			err = json.Unmarshal(item.V, &v)
+			code[i].Value = v
+		case "dur":
+			var v int64
+			err = json.Unmarshal(item.V, &v)
+			code[i].Value = time.Duration(v)
+		case "ints":
+			var v []IntType
+			err = json.Unmarshal(item.V, &v)
+			code[i].Value = v
+		case "span":
+			var v [2]IntType
+			err = json.Unmarshal(item.V, &v)
+			code[i].Value = BufferSpan{Begin: v[0], End: v[1]}
+		case "st":
+			var v [2]string
+			err = json.Unmarshal(item.V, &v)
+			code[i].Value = StInfo{Op: v[0], Text: v[1]}
+		case "body":
+			var body cachedBody
+			if err = json.Unmarshal(item.V, &body); err != nil {
+				break
+			}
+			var bodyCode []ByteCode
+			if bodyCode, err = decodeCode(body.Code); err != nil {
+				break
+			}
+			if body.Computed {
+				code[i].Value = NewComputedValRaw(&ComputedData{
+					Expr:      body.Expr,
+					Params:    body.Params,
+					code:      bodyCode,
+					codeIndex: len(bodyCode),
+				})
+			} else {
+				code[i].Value = NewFunctionValRaw(&FunctionData{
+					Expr:        body.Expr,
+					Name:        body.Name,
+					Params:      body.Params,
+					code:        bodyCode,
+					codeIndex:   len(bodyCode),
+					isGenerator: body.Generator,
+				})
+			}
+		default:
+			err = fmt.Errorf("未知的指令参数类型: %s", item.Kind)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return code, nil
+}
+
+// saveCodeCache 解析成功后写入缓存，含有无法缓存的指令时跳过
+func (ctx *Context) saveCodeCache(key string, d *ParserCustomData) {
+	c1, c2 := &ctx.Config, &d.Config
+	if c1.EnableDiceWoD != c2.EnableDiceWoD || c1.EnableDiceCoC != c2.EnableDiceCoC ||
+		c1.EnableDiceFate != c2.EnableDiceFate || c1.EnableDiceDoubleCross != c2.EnableDiceDoubleCross {
+		// 语句中以 // #EnableDice 修改了解析配置
+		return
+	}
+	code, err := encodeCode(ctx.code[:ctx.codeIndex])
+	if err != nil {
+		return
+	}
+	info := &d.program
+	data, err := json.Marshal(cachedProgram{
+		Offset:    ctx.parser.pt.offset,
+		Code:      code,
+		Names:     info.Names,
+		Stores:    info.Stores,
+		HasAssign: info.HasAssign,
+		HasLoop:   info.HasLoop,
+		HasFunc:   info.HasFunc,
+		DiceSides: info.DiceSides,
+	})
+	if err != nil {
+		return
+	}
+	ctx.Config.CodeCache.Set(key, data)
+}
+
+// loadCodeCache 读取缓存，成功时设置好字节码和解析器状态，如同刚刚解析完成
+func (ctx *Context) loadCodeCache(key string, expr string) bool {
+	data, ok := ctx.Config.CodeCache.Get(key)
+	if !ok {
+		return false
+	}
+	var prog cachedProgram
+	if err := json.Unmarshal(data, &prog); err != nil || prog.Offset > len(expr) {
+		return false
+	}
+	code, err := decodeCode(prog.Code)
+	if err != nil {
+		return false
+	}
+
+	ctx.installCode(expr, prog.Offset, code, ProgramInfo{
+		Names:     prog.Names,
+		Stores:    prog.Stores,
+		HasAssign: prog.HasAssign,
+		HasLoop:   prog.HasLoop,
+		HasFunc:   prog.HasFunc,
+		DiceSides: prog.DiceSides,
+	})
+	return true
+}
@@ -0,0 +1,89 @@
+package dicescript
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/exp/rand"
+)
+
+type mapCodeCache map[string][]byte
+
+func (m mapCodeCache) Get(key string) ([]byte, bool) {
+	data, ok := m[key]
+	return data, ok
+}
+
+func (m mapCodeCache) Set(key string, data []byte) {
+	m[key] = data
+}
+
+func TestCodeCache(t *testing.T) {
+	exprs := []string{
+		"d20 + 5 ## 攻击",
+		"3d6k2 + 1.5 * 2",
+		"a = 1; if a > 0 { 'yes' } else { 'no' }",
+		"func f(x) { return x * 2 }; f(d6)",
+		"&hp = d8 + 1; hp + hp",
+		"i = 0; while i < 3 { i = i + 1 }; `{i}`",
+		"x = {'a': [1, 2]}; x.a[1]",
+		"1h30m",
+		"max(2d6) + quiet(d4) ; note('n')",
+		"match d6 { 1..2 => '低', 3..4 => '中', _ => '高' }",
+	}
+	seed, _ := (&rand.PCGSource{}).MarshalBinary()
+	cache := mapCodeCache{}
+	for _, expr := range exprs {
+		vm := NewVM()
+		assert.NoError(t, vm.RunWith(expr, RunOptions{Seed: seed}), expr)
+		want := vm.result()
+
+		for i := 0; i < 2; i++ {
+			// 第一次写入缓存，第二次读取缓存
+			vm = NewVM()
+			vm.Config.CodeCache = cache
+			if assert.NoError(t, vm.RunWith(expr, RunOptions{Seed: seed}), expr) {
+				got := vm.result()
+				assert.True(t, valueEqual(want.Value, got.Value), expr)
+				assert.Equal(t, want.Detail, got.Detail, expr)
+				assert.Equal(t, want.RestInput, got.RestInput, expr)
+			}
+		}
+	}
+	assert.Len(t, cache, len(exprs))
+
+	// 影响解析的配置不同时不共用缓存
+	vm := NewVM()
+	vm.Config.CodeCache = cache
+	vm.Config.DisableNDice = true
+	assert.NoError(t, vm.Run("1 + 2"))
+	assert.NoError(t, NewVM(WithConfig(&RollConfig{CodeCache: cache})).Run("1 + 2"))
+	assert.Len(t, cache, len(exprs)+2)
+
+	// 损坏的缓存被忽略
+	for k := range cache {
+		cache[k] = []byte("{")
+	}
+	vm = NewVM()
+	vm.Config.CodeCache = cache
+	assert.NoError(t, vm.Run("d20 + 5 ## 攻击"))
+}
+
+func TestDirCodeCache(t *testing.T) {
+	dir := t.TempDir()
+	cache, err := NewDirCodeCache(dir)
+	if !assert.NoError(t, err) {
+		return
+	}
+	vm := NewVM(WithCodeCache(cache))
+	assert.NoError(t, vm.Run("func f(x) { x + 1 }; f(2)"))
+	entries, _ := os.ReadDir(dir)
+	assert.Len(t, entries, 1)
+
+	vm = NewVM()
+	vm.Config.CodeCache = cache
+	assert.NoError(t, vm.Run("func f(x) { x + 1 }; f(2)"))
+	assert.True(t, valueEqual(vm.Ret, ni(3)))
+	assert.Len(t, vm.ProgramInfo().Stores, 1)
+}
@@ -0,0 +1,330 @@
+package dicescript
+
+import "math"
+
+// CostEstimate 执行代价的静态估计，见 ProgramInfo.CostEstimate
+type CostEstimate struct {
+	Ops  IntType // 算力上限
+	Dice IntType // 骰子个数上限
+	// 为false时语句中存在次数无法确定的循环(包括展开 ...x 这样逐项取值的写法)、函数定义、代价无法估计的函数调用、
+	// 次数无法确定的骰子或重复、会加骰的骰池，Ops和Dice只是其中可以确定的部分，其中每条指令只计一次
+	Bounded bool
+}
+
+// costNatives 可以估计代价的内置函数，args为各参数的字面量，不是整数字面量时为-1。
+// 返回调用消耗的算力和骰子个数的上限，无法估计时ok为false
+var costNatives = map[string]func(args []IntType) (ops IntType, dice IntType, ok bool){
+	"pool": func(args []IntType) (IntType, IntType, bool) {
+		if len(args) != 2 || args[0] < 0 {
+			return 0, 0, false
+		}
+		// 每颗骰子计1点算力
+		return args[0], args[0], true
+	},
+}
+
+// costConstNatives 代价与参数无关的内置函数和方法
+var costConstNatives = map[string]bool{
+	"ceil": true, "floor": true, "round": true, "abs": true, "clamp": true, "step": true,
+	"toInt": true, "toFloat": true, "toStr": true, "toBool": true, "repr": true, "typeId": true,
+	"chance": true, "hit_location": true, "damage_type_mod": true, "resource": true, "range": true,
+	"uuid": true, "now": true, "fmt_thousands": true, "fmt_cn": true,
+	"error": true, "is_error": true, "require_int": true, "require_num": true, "require_str": true,
+}
+
+// costConstMethods 代价与参数无关的方法，如 [1, 2].len()、3d6.kh
+var costConstMethods = map[string]bool{
+	"len": true, "kh": true, "kl": true, "dh": true, "dl": true,
+}
+
+// costStackEffect 估计代价时可以向前追溯操作数的指令，消耗和产生的栈上的值的个数
+func costStackEffect(c ByteCode) (pop int, push int, ok bool) {
+	switch c.T {
+	case typePushNull:
+		return 0, 1, true
+	case typePushArray:
+		return int(c.Value.(IntType)), 1, true
+	case typeAttrGet:
+		return 1, 1, true
+	case typeInvoke:
+		return int(c.Value.(IntType)) + 1, 1, true
+	}
+	return cseStackEffect(c)
+}
+
+// costOperandStart 找到在end处结束、压入一个值的表达式的起点，遇到无法追溯的指令时返回-1
+func costOperandStart(code []ByteCode, end int) int {
+	need := 1
+	for j := end; j >= 0; j-- {
+		pop, push, ok := costStackEffect(code[j])
+		if !ok {
+			return -1
+		}
+		need += pop - push
+		if need == 0 {
+			return j
+		}
+	}
+	return -1
+}
+
+// costIntLiteral end处结束的操作数为整数字面量时返回其值，否则返回-1
+func costIntLiteral(code []ByteCode, begin, end int) IntType {
+	if begin == end && code[end].T == typePushIntNumber {
+		return code[end].Value.(IntType)
+	}
+	return -1
+}
+
+type costScanner struct {
+	code         []ByteCode
+	defaultTimes IntType
+}
+
+// callArgs 找到i处的函数调用的被调用者和各参数，无法追溯时ok为false
+func (s *costScanner) callArgs(i int) (callee ByteCode, args []IntType, ok bool) {
+	n := int(s.code[i].Value.(IntType))
+	args = make([]IntType, n)
+	end := i - 1
+	for k := n - 1; k >= 0; k-- {
+		begin := costOperandStart(s.code, end)
+		if begin < 0 {
+			return callee, nil, false
+		}
+		args[k] = costIntLiteral(s.code, begin, end)
+		end = begin - 1
+	}
+	if end = s.skipMarks(end); end < 0 {
+		return callee, nil, false
+	}
+	return s.code[end], args, true
+}
+
+// callCost i处的函数调用的代价，被调用者为自定义函数或无法估计的内置函数时ok为false
+func (s *costScanner) callCost(i int) (ops IntType, dice IntType, ok bool) {
+	callee, args, ok := s.callArgs(i)
+	if !ok {
+		return 0, 0, false
+	}
+	switch callee.T {
+	case typeLoadName, typeLoadNameWithDetail, typeLoadNameRaw:
+		name := callee.Value.(string)
+		if f := costNatives[name]; f != nil {
+			return f(args)
+		}
+		return 0, 0, costConstNatives[name]
+	case typeAttrGet:
+		return 0, 0, costConstMethods[callee.Value.(string)]
+	}
+	return 0, 0, false
+}
+
+// iterLen end处结束的值逐项取值的次数，来自 1..10、[1, 2, 3]、range(10) 这样的字面量，无法确定时返回-1
+func (s *costScanner) iterLen(end int) IntType {
+	if end < 0 {
+		return -1
+	}
+	c := s.code[end]
+	switch c.T {
+	case typePushArray:
+		return c.Value.(IntType)
+	case typePushString:
+		return IntType(len([]rune(c.Value.(string))))
+	case typeRange:
+		b := costOperandStart(s.code, end-1)
+		if b < 0 {
+			return -1
+		}
+		a := costOperandStart(s.code, b-1)
+		if a < 0 || s.code[a].T != typePushIntNumber || s.code[b].T != typePushIntNumber || a != b-1 {
+			return -1
+		}
+		rd := RangeData{Start: s.code[a].Value.(IntType), End: s.code[b].Value.(IntType)}
+		return rd.Len()
+	case typeInvoke:
+		callee, args, ok := s.callArgs(end)
+		if !ok || !isLoadOp(callee.T) || callee.Value.(string) != "range" {
+			return -1
+		}
+		switch {
+		case len(args) == 1 && args[0] >= 0:
+			return args[0]
+		case len(args) == 2 && args[0] >= 0 && args[1] >= args[0]:
+			return args[1] - args[0]
+		}
+	}
+	return -1
+}
+
+// repeatCost i处的乘法为重复数组或字符串时的次数，次数不是字面量时ok为false。不是重复时返回0
+func (s *costScanner) repeatCost(i int) (IntType, bool) {
+	right := costOperandStart(s.code, i-1)
+	if right < 0 {
+		return 0, true
+	}
+	leftEnd := s.skipMarks(right - 1)
+	left := costOperandStart(s.code, leftEnd)
+	if left < 0 {
+		return 0, true
+	}
+	isSeq := func(end int) bool {
+		t := s.code[end].T
+		return t == typePushArray || t == typePushString
+	}
+	switch {
+	case isSeq(leftEnd):
+		n := costIntLiteral(s.code, right, i-1)
+		return n, n >= 0
+	case isSeq(i - 1):
+		n := costIntLiteral(s.code, left, leftEnd)
+		return n, n >= 0
+	}
+	return 0, true
+}
+
+// skipMarks 从end向前跳过记录计算过程的指令
+func (s *costScanner) skipMarks(end int) int {
+	for end >= 0 && s.code[end].T == typeDetailMark {
+		end--
+	}
+	return end
+}
+
+// scan 估计 [lo, hi) 中指令的代价，head为所在的for-in循环取下一项的位置，跳回该处的为continue
+func (s *costScanner) scan(lo, hi, head int) CostEstimate {
+	est := CostEstimate{Bounded: true}
+	addOps := func(n IntType) {
+		if n < 0 || est.Ops > math.MaxInt64-n {
+			est.Bounded = false
+			return
+		}
+		est.Ops += n
+	}
+	addDice := func(n IntType) {
+		if n < 0 || est.Dice > math.MaxInt64-n {
+			est.Bounded = false
+			return
+		}
+		est.Dice += n
+		addOps(n) // 执行时每个骰子消耗1点算力
+	}
+
+	last := IntType(-1) // 上一条指令得到的值的上限，未知时为-1
+	times := s.defaultTimes
+	for i := lo; i < hi; i++ {
+		c := s.code[i]
+		cur := IntType(-1)
+		addOps(1)
+		switch c.T {
+		case typePushIntNumber:
+			cur = c.Value.(IntType)
+			if cur < 0 {
+				cur = -cur
+			}
+		case typeDetailMark:
+			cur = last
+		case typeDiceInit:
+			times = s.defaultTimes
+			cur = last
+		case typeDiceSetTimes:
+			times = last
+		case typeDiceSetExplode:
+			// 追加的次数不定
+			est.Bounded = false
+		case typeDice:
+			addDice(times)
+			if times >= 0 && last >= 0 && (last == 0 || times <= math.MaxInt64/last) {
+				cur = times * last
+			}
+		case typeDiceCocBonus, typeDiceCocPenalty:
+			if last >= 0 {
+				addDice(last + 1)
+			} else {
+				addDice(-1)
+			}
+		case typeDiceFate:
+			addDice(4)
+		case typeDiceFateN:
+			addDice(times)
+		case typeDiceWod, typeDiceDC:
+			// 加骰的轮数不定
+			est.Bounded = false
+		case typeInvoke:
+			ops, dice, ok := s.callCost(i)
+			if !ok {
+				est.Bounded = false
+			}
+			addOps(ops - dice)
+			addDice(dice)
+		case typeInvokeSelf:
+			est.Bounded = false
+		case typeMultiply:
+			n, ok := s.repeatCost(i)
+			if !ok {
+				est.Bounded = false
+			}
+			addOps(n)
+		case typeJmp, typeJe, typeJne, typeJeDup, typeJneDup, typeJnnDup:
+			if offset := int(c.Value.(IntType)); offset < 0 && i+offset+1 != head {
+				est.Bounded = false
+			}
+		case typeIterBegin:
+			// for-in循环: iter.begin; iter.next; jne 结束; 循环体; jmp iter.next
+			n := s.iterLen(i - 1)
+			if n < 0 || i+2 >= hi || s.code[i+1].T != typeIterNext || s.code[i+2].T != typeJne {
+				est.Bounded = false
+				break
+			}
+			exit := i + 2 + int(s.code[i+2].Value.(IntType)) + 1
+			back := exit - 1
+			if back <= i+2 || exit > hi || s.code[back].T != typeJmp || back+int(s.code[back].Value.(IntType))+1 != i+1 {
+				est.Bounded = false
+				break
+			}
+			body := s.scan(i+3, back, i+1)
+			est.Bounded = est.Bounded && body.Bounded
+			// 每轮执行取下一项、判断、循环体和跳回，最后一次取值时结束
+			perRound := body.Ops - body.Dice + 3
+			ops, ok1 := mulInt(perRound, n+1)
+			dice, ok2 := mulInt(body.Dice, n)
+			if !ok1 || !ok2 {
+				est.Bounded = false
+				break
+			}
+			addOps(ops)
+			addDice(dice)
+			i = back
+		case typeSpread:
+			// 逐项取值的次数取决于值的长度
+			est.Bounded = false
+		}
+		last = cur
+	}
+	return est
+}
+
+// CostEstimate 不执行语句，根据字节码估计执行代价的上限，可以在保存宏时拒绝明显过大的语句。
+// 没有循环时每条指令至多执行一次；for-in循环的次数取自 1..10、range(10)、[1, 2, 3] 这样的字面量，其他循环视为次数不定。
+// 骰子的次数取自字面量，如 (2d6)d6 至多骰 2+12 个骰子；内置函数中 pool() 等按参数的字面量估计，调用自定义函数时无法估计。
+// 读取的变量若为computed或函数，其中的代价无法估计，执行时仍受 OpCountLimit 限制
+func (info *ProgramInfo) CostEstimate() CostEstimate {
+	s := &costScanner{code: info.code, defaultTimes: 1}
+	if info.defaultDiceCount > 0 {
+		s.defaultTimes = info.defaultDiceCount
+	}
+	est := s.scan(0, len(info.code), -1)
+	if info.HasFunc {
+		est.Bounded = false
+	}
+	return est
+}
+
+// ProgramInfo 最近一次解析得到的程序结构信息，未解析过时为nil
+func (ctx *Context) ProgramInfo() *ProgramInfo {
+	if ctx.parser == nil {
+		return nil
+	}
+	info := &ctx.parser.cur.data.program
+	info.defaultDiceCount = ctx.Config.DefaultDiceCount
+	return info
+}
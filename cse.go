@@ -0,0 +1,214 @@
+package dicescript
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// cseSafeOps 公共子表达式消除只处理由这些指令组成的程序。
+// 赋值、函数调用、循环等可能在两次出现之间修改变量，含有它们时不做处理
+var cseSafeOps = map[CodeType]bool{
+	typePushIntNumber: true, typePushFloatNumber: true, typePushBool: true, typePushString: true, typePushNull: true,
+	typePushDefaultExpr: true,
+	typeLoadName:        true, typeLoadNameWithDetail: true, typeLoadNameRaw: true, typeLoadConstWithDetail: true,
+
+	typeAdd: true, typeSubtract: true, typeMultiply: true, typeDivide: true, typeModulus: true, typeExponentiation: true,
+	typeNullCoalescing: true,
+	typeCompLT:         true, typeCompLE: true, typeCompEQ: true, typeCompNE: true, typeCompGE: true, typeCompGT: true,
+	typeBitwiseAnd: true, typeBitwiseOr: true, typeBitwiseXor: true, typeShiftLeft: true, typeShiftRight: true, typeIn: true, typeRange: true,
+	typeNegation: true, typePositive: true, typeLogicNot: true,
+
+	typeDiceInit: true, typeDiceSetTimes: true, typeDiceSetKeepLowNum: true, typeDiceSetKeepHighNum: true,
+	typeDiceSetDropLowNum: true, typeDiceSetDropHighNum: true, typeDiceSetMin: true, typeDiceSetMax: true, typeDiceSetExplode: true, typeDice: true,
+	typeDiceCocPenalty: true, typeDiceCocBonus: true, typeDiceFate: true, typeDiceFateN: true,
+	typeRollModePush: true, typeRollModePop: true, typeDetailNote: true, typeDetailQuiet: true,
+
+	typeDetailMark: true, typeHalt: true, typePop: true, typeNop: true,
+	typeJmp: true, typeJe: true, typeJne: true, typeJeDup: true, typeJneDup: true, typeJnnDup: true,
+}
+
+// cseStackEffect 可以出现在公共子表达式中的指令，消耗和产生的栈上的值的个数。骰子等带有随机性的指令不在其中
+func cseStackEffect(c ByteCode) (pop int, push int, ok bool) {
+	switch c.T {
+	case typePushIntNumber, typePushFloatNumber, typePushBool, typePushString,
+		typeLoadName, typeLoadNameWithDetail, typeLoadNameRaw, typeLoadConstWithDetail:
+		return 0, 1, true
+	case typeAdd, typeSubtract, typeMultiply, typeDivide, typeModulus, typeExponentiation, typeNullCoalescing,
+		typeCompLT, typeCompLE, typeCompEQ, typeCompNE, typeCompGE, typeCompGT, typeBitwiseAnd, typeBitwiseOr,
+		typeBitwiseXor, typeShiftLeft, typeShiftRight, typeIn, typeRange:
+		return 2, 1, true
+	case typeNegation, typePositive, typeLogicNot:
+		return 1, 1, true
+	case typeDetailMark:
+		return 0, 0, true
+	}
+	return 0, 0, false
+}
+
+func isLoadOp(t CodeType) bool {
+	return t == typeLoadName || t == typeLoadNameWithDetail || t == typeLoadNameRaw
+}
+
+// cseSlot 执行时保存的公共子表达式的结果
+type cseSlot struct {
+	valid       bool
+	val         *VMValue
+	spans       []BufferSpan // 计算过程中产生的detail
+	detailStart int
+	computed    bool // 进入子表达式前的 IsComputedLoaded
+}
+
+type cseWindow struct {
+	begin, end int // 包含end
+	key        string
+}
+
+// cseWindows 找出所有读取了变量的子表达式
+func cseWindows(code []ByteCode, targets map[int]bool) []cseWindow {
+	var ret []cseWindow
+	for end := range code {
+		if _, _, ok := cseStackEffect(code[end]); !ok || code[end].T == typeDetailMark {
+			continue
+		}
+		need, begin, hasLoad := 1, -1, false
+		for j := end; j >= 0; j-- {
+			pop, push, ok := cseStackEffect(code[j])
+			if !ok || (j < end && targets[j+1]) {
+				break
+			}
+			hasLoad = hasLoad || isLoadOp(code[j].T)
+			need += pop - push
+			if need == 0 {
+				begin = j
+				break
+			}
+		}
+		if begin < 0 || !hasLoad {
+			continue
+		}
+		// 变量之前的detail标记属于这个变量
+		if begin > 0 && code[begin-1].T == typeDetailMark && !targets[begin] {
+			begin--
+		}
+
+		var sb strings.Builder
+		for _, c := range code[begin : end+1] {
+			if c.T == typeDetailMark {
+				// 标记的位置不同，只比较结构
+				sb.WriteString("mark;")
+			} else {
+				fmt.Fprintf(&sb, "%d:%v;", c.T, c.Value)
+			}
+		}
+		ret = append(ret, cseWindow{begin: begin, end: end, key: sb.String()})
+	}
+	return ret
+}
+
+// eliminateCommonSubexpr 公共子表达式消除。
+// 重复出现的子表达式(如 (力量+敏捷)/2 + (力量+敏捷)%2 中的 力量+敏捷)第一次计算后保存结果，
+// 之后再遇到时直接使用并跳过其代码，减少变量的读取。
+// 第一次计算中读取到computed时不保存，后面仍然重新计算；计算过程照常显示
+func eliminateCommonSubexpr(code []ByteCode) []ByteCode {
+	for _, c := range code {
+		if !cseSafeOps[c.T] {
+			return code
+		}
+	}
+	targets := jumpTargets(code)
+	windows := cseWindows(code, targets)
+
+	groups := map[string][]cseWindow{}
+	var keys []string
+	for _, w := range windows {
+		if _, ok := groups[w.key]; !ok {
+			keys = append(keys, w.key)
+		}
+		groups[w.key] = append(groups[w.key], w)
+	}
+	// 优先处理较长的子表达式
+	sort.SliceStable(keys, func(i, j int) bool {
+		a, b := groups[keys[i]][0], groups[keys[j]][0]
+		return a.end-a.begin > b.end-b.begin
+	})
+
+	var defs, uses []cseWindow
+	// 可以选用的位置: 不与已跳过的代码相交，与已保存的子表达式不相交或被其包含
+	usable := func(w cseWindow) bool {
+		for _, u := range uses {
+			if w.begin <= u.end && u.begin <= w.end {
+				return false
+			}
+		}
+		for _, d := range defs {
+			if w.begin <= d.end && d.begin <= w.end && !(d.begin <= w.begin && w.end <= d.end) {
+				return false
+			}
+		}
+		return true
+	}
+
+	before := map[int][]ByteCode{}
+	after := map[int][]ByteCode{}
+	useEnd := map[int]int{} // cse.load 对应的子表达式的结束位置
+	slot := IntType(0)
+	for _, key := range keys {
+		var items []cseWindow
+		for _, w := range groups[key] {
+			if usable(w) {
+				items = append(items, w)
+			}
+		}
+		if len(items) < 2 {
+			continue
+		}
+		def := items[0]
+		defs = append(defs, def)
+		before[def.begin] = append(before[def.begin], ByteCode{T: typeCSEMark, Value: slot})
+		after[def.end] = append([]ByteCode{{T: typeCSEStore, Value: slot}}, after[def.end]...)
+		for _, u := range items[1:] {
+			if !usable(u) {
+				continue
+			}
+			uses = append(uses, u)
+			before[u.begin] = append(before[u.begin], ByteCode{T: typeCSELoad, Value: slot}, ByteCode{T: typeJe})
+			useEnd[u.begin] = u.end
+		}
+		slot++
+	}
+	if slot == 0 {
+		return code
+	}
+
+	// 重新排列，修正跳转距离
+	newIndex := make([]int, len(code)+1) // 原来第i条指令之前插入的第一条指令的位置
+	pos := make([]int, len(code))        // 原来第i条指令的新位置
+	n := 0
+	for i := range code {
+		newIndex[i] = n
+		n += len(before[i])
+		pos[i] = n
+		n += 1 + len(after[i])
+	}
+	newIndex[len(code)] = n
+
+	ret := make([]ByteCode, 0, n)
+	for i, c := range code {
+		for _, b := range before[i] {
+			if b.T == typeJe {
+				// 跳过子表达式，落在其最后一条指令之后
+				b.Value = IntType(pos[useEnd[i]] + 1 - (len(ret) + 1))
+			}
+			ret = append(ret, b)
+		}
+		switch c.T {
+		case typeJmp, typeJe, typeJne, typeJeDup, typeJneDup, typeJnnDup:
+			target := i + int(c.Value.(IntType)) + 1
+			c.Value = IntType(newIndex[target] - pos[i] - 1)
+		}
+		ret = append(ret, c)
+		ret = append(ret, after[i]...)
+	}
+	return ret
+}
@@ -0,0 +1,93 @@
+package dicescript
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type countingAttrProvider struct {
+	testAttrProvider
+	loads int
+}
+
+func (p *countingAttrProvider) Load(name string) *VMValue {
+	p.loads++
+	return p.testAttrProvider.Load(name)
+}
+
+func TestEliminateCommonSubexpr(t *testing.T) {
+	tests := []struct {
+		expr   string
+		reduce bool
+	}{
+		{"(力量+敏捷)/2 + (力量+敏捷)%2", true},
+		{"(力量 + 1) * (力量 + 1) + 力量", true},
+		{"力量 > 50 ? 力量 : 敏捷 + 力量", true},
+		{"(1 ? 力量+1 : 0) + (力量+1)", true},
+		// 第一次出现在未执行的分支中，之后照常计算
+		{"(0 ? 力量+1 : 0) + (力量+1) + (力量+1)", false},
+	}
+	for _, tt := range tests {
+		expr := tt.expr
+		var rets []*VMValue
+		var details []string
+		var loads []int
+		for _, enabled := range []bool{false, true} {
+			p := &countingAttrProvider{}
+			p.m.Store("力量", ni(60))
+			p.m.Store("敏捷", ni(51))
+			vm := NewVM(WithAttrProvider(p))
+			vm.Config.EliminateCommonSubexpr = enabled
+			if !assert.NoError(t, vm.Run(expr), expr) {
+				return
+			}
+			rets = append(rets, vm.Ret)
+			details = append(details, vm.GetDetailText())
+			loads = append(loads, p.loads)
+		}
+		assert.True(t, valueEqual(rets[0], rets[1]), expr)
+		assert.Equal(t, details[0], details[1], expr)
+		if tt.reduce {
+			assert.Less(t, loads[1], loads[0], expr)
+		} else {
+			assert.Equal(t, loads[0], loads[1], expr)
+		}
+	}
+}
+
+func TestEliminateCommonSubexprAsm(t *testing.T) {
+	vm := NewVM()
+	vm.Config.EliminateCommonSubexpr = true
+	assert.NoError(t, vm.Parse("(力量+敏捷)/2 + (力量+敏捷)%2"))
+	assert.True(t, strings.Contains(vm.GetAsmText(), "cse.load 0"))
+
+	// 含有赋值时不做处理
+	assert.NoError(t, vm.Parse("力量 = 力量 + 1; 力量 + 1"))
+	assert.False(t, strings.Contains(vm.GetAsmText(), "cse."))
+
+	// 骰子不会被合并
+	assert.NoError(t, vm.Parse("d6 + d6"))
+	assert.False(t, strings.Contains(vm.GetAsmText(), "cse."))
+}
+
+func TestEliminateCommonSubexprComputed(t *testing.T) {
+	vm := NewVM()
+	vm.Config.EliminateCommonSubexpr = true
+	vm.Config.DiceMinMode = true
+	assert.NoError(t, vm.Run("&a = d6 + 1"))
+	assert.NoError(t, vm.Run("a + a"))
+	assert.True(t, valueEqual(vm.Ret, ni(4)))
+	assert.Equal(t, "2[a=1 + 1=2] + 2[a=1 + 1=2]", vm.GetDetailText())
+
+	// computed每次都会重新计算
+	vm.Config.DiceMinMode = false
+	for i := 0; i < 20; i++ {
+		assert.NoError(t, vm.Run("(a*10) + (a*10)"))
+		if vm.Ret.MustReadInt()%20 != 0 {
+			return
+		}
+	}
+	t.Error("computed的结果被复用")
+}
@@ -127,6 +127,9 @@ func (s *CustomDiceStream) ReadExpr(entry string) (*VMValue, bool, error) {
 	if _, err := parser.parse(nil); err != nil {
 		return nil, false, err
 	}
+	if data.numberErr != nil {
+		return nil, false, data.numberErr
+	}
 
 	consumed := parser.pt.offset
 	if consumed <= 0 {
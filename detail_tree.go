@@ -0,0 +1,132 @@
+package dicescript
+
+import "sort"
+
+// 计算过程树中常见的节点类型，即 DetailNode.Tag。其余取自 BufferSpan.Tag，如 dice-fate、dice-custom、quiet
+const (
+	DetailNodeRoot = "root" // 根节点，对应整个原文
+	DetailNodeText = ""     // 文本节点，没有计算过程的原文，如运算符、数字
+	DetailNodeSpan = "span" // 没有来源标记的计算过程
+	DetailNodeDice = "dice" // 普通骰子，Dice 为各个骰点
+	DetailNodeLoad = "load" // 读取变量
+)
+
+// DetailNode 计算过程树的节点，供宿主程序自行渲染为HTML、Markdown、图片等格式。
+// 子节点按原文顺序排列，依次拼接各叶子节点的 Expr 即为原文: 没有计算过程的部分(如运算符、数字)为文本节点，
+// 骰点、变量读取等为带有结果的节点，嵌套的计算过程(如 (2d3)d4 中的 2d3)为其子节点。
+// 树只由记录了计算过程的部分构成，不是语法树: 运算符没有对应的节点，也不以运算数为子节点，
+// 如 1d6 + x 的根节点下依次为骰子节点、文本节点 " + " 和变量节点，需要运算的结合方式时应另行解析
+type DetailNode struct {
+	Expr  string   // 对应的原文
+	Tag   string   // 节点类型，见 DetailNodeRoot 等
+	Value *VMValue // 结果，文本节点为nil
+	Text  string   // 计算过程，如 4d6k3 的 {6 4 4 | 1}
+	// 普通骰子按显示顺序的各个骰点，其他节点为nil
+	Dice     []DieFace
+	Children []*DetailNode
+}
+
+// DieFace 一颗骰子的骰点
+type DieFace struct {
+	Value    IntType // 爆炸骰为累加后的值
+	Kept     bool    // 是否计入结果，如 4d6k3 中被舍弃的骰子为false
+	Exploded IntType // 爆炸骰追加骰点的次数
+}
+
+// IsText 是否为文本节点
+func (n *DetailNode) IsText() bool {
+	return n.Tag == DetailNodeText
+}
+
+// GetDetailTree 以树的形式给出上一次执行的计算过程，与 GetDetailText 同样来自 DetailSpans，
+// 因此暗骰等被隐藏的骰点也在其中(Tag为quiet的节点)，由宿主程序决定是否显示。没有执行过或执行出错时返回nil
+func (ctx *Context) GetDetailTree() *DetailNode {
+	if ctx.parser == nil || ctx.Ret == nil || ctx.Error != nil {
+		return nil
+	}
+	data := ctx.parser.data[:len(ctx.Matched)]
+	end := IntType(len(data))
+
+	spans := make([]BufferSpan, 0, len(ctx.DetailSpans))
+	for _, s := range ctx.DetailSpans {
+		if s.Begin < 0 || s.Begin > s.End || s.End > end {
+			continue
+		}
+		spans = append(spans, s)
+	}
+	// 外层的span排在前面，范围相同时后记录的视为子节点
+	sort.SliceStable(spans, func(i, j int) bool {
+		if spans[i].Begin != spans[j].Begin {
+			return spans[i].Begin < spans[j].Begin
+		}
+		return spans[i].End > spans[j].End
+	})
+
+	type frame struct {
+		node *DetailNode
+		end  IntType
+		pos  IntType // 已经处理到的位置，其后的原文尚未放入子节点
+	}
+	addText := func(f *frame, to IntType) {
+		if to > f.pos {
+			f.node.Children = append(f.node.Children, &DetailNode{Expr: string(data[f.pos:to])})
+			f.pos = to
+		}
+	}
+
+	root := &DetailNode{Expr: string(data), Tag: DetailNodeRoot, Value: ctx.Ret.Clone()}
+	stack := []*frame{{node: root, end: end}}
+	closeTop := func() {
+		f := stack[len(stack)-1]
+		addText(f, f.end)
+		stack = stack[:len(stack)-1]
+	}
+
+	for _, s := range spans {
+		for len(stack) > 1 && s.Begin >= stack[len(stack)-1].end {
+			closeTop()
+		}
+		parent := stack[len(stack)-1]
+		if s.End > parent.end {
+			// 与外层部分重叠，不是严格的嵌套，视为外层的一部分
+			continue
+		}
+		addText(parent, s.Begin)
+
+		node := &DetailNode{Expr: string(data[s.Begin:s.End]), Tag: s.Tag, Text: s.Text}
+		if node.Tag == DetailNodeText {
+			node.Tag = DetailNodeSpan
+		}
+		if s.Ret != nil {
+			node.Value = s.Ret.Clone()
+		}
+		if p := s.Pool; p != nil {
+			node.Dice = make([]DieFace, len(p.Order))
+			for i, idx := range p.Order {
+				node.Dice[i] = DieFace{Value: p.Rolls[idx], Kept: i < p.Kept}
+				if p.Exploded != nil {
+					node.Dice[i].Exploded = p.Exploded[idx]
+				}
+			}
+		}
+		parent.node.Children = append(parent.node.Children, node)
+		parent.pos = s.End
+		stack = append(stack, &frame{node: node, end: s.End, pos: s.Begin})
+	}
+	for len(stack) > 0 {
+		closeTop()
+	}
+
+	// 没有子span的节点不再拆出文本节点
+	var trim func(n *DetailNode)
+	trim = func(n *DetailNode) {
+		if len(n.Children) == 1 && n.Children[0].IsText() && n.Tag != DetailNodeRoot {
+			n.Children = nil
+		}
+		for _, c := range n.Children {
+			trim(c)
+		}
+	}
+	trim(root)
+	return root
+}
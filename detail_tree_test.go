@@ -0,0 +1,82 @@
+package dicescript
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// joinLeaves 依次拼接叶子节点的原文
+func joinLeaves(n *DetailNode) string {
+	if len(n.Children) == 0 {
+		return n.Expr
+	}
+	var sb strings.Builder
+	for _, c := range n.Children {
+		sb.WriteString(joinLeaves(c))
+	}
+	return sb.String()
+}
+
+func TestDetailTree(t *testing.T) {
+	vm := NewVM()
+	vm.Config.DiceMaxMode = true
+	err := vm.Run("x = 2; 4d6k3 + x 攻击")
+	if !assert.NoError(t, err) {
+		return
+	}
+	tree := vm.GetDetailTree()
+	assert.Equal(t, DetailNodeRoot, tree.Tag)
+	assert.Equal(t, "x = 2; 4d6k3 + x", tree.Expr)
+	assert.Equal(t, "x = 2; 4d6k3 + x", joinLeaves(tree))
+	assert.True(t, valueEqual(tree.Value, ni(20)))
+	if assert.Len(t, tree.Children, 4) {
+		assert.Equal(t, "x = 2; ", tree.Children[0].Expr)
+		dice := tree.Children[1]
+		assert.Equal(t, DetailNodeDice, dice.Tag)
+		assert.True(t, valueEqual(dice.Value, ni(18)))
+		assert.Equal(t, []DieFace{{6, true, 0}, {6, true, 0}, {6, true, 0}, {6, false, 0}}, dice.Dice)
+		assert.Nil(t, dice.Children)
+
+		assert.True(t, tree.Children[2].IsText())
+		assert.Equal(t, " + ", tree.Children[2].Expr)
+		assert.Equal(t, DetailNodeLoad, tree.Children[3].Tag)
+		assert.True(t, valueEqual(tree.Children[3].Value, ni(2)))
+	}
+
+	// 嵌套的计算过程
+	err = vm.Run("(2d3)d4 + 1")
+	if assert.NoError(t, err) {
+		tree = vm.GetDetailTree()
+		assert.Equal(t, "(2d3)d4 + 1", joinLeaves(tree))
+		if assert.Len(t, tree.Children, 2) {
+			outer := tree.Children[0]
+			assert.Equal(t, "(2d3)d4", outer.Expr)
+			assert.Len(t, outer.Dice, 6)
+			if assert.Len(t, outer.Children, 3) {
+				assert.Equal(t, "2d3", outer.Children[1].Expr)
+				assert.True(t, valueEqual(outer.Children[1].Value, ni(6)))
+			}
+		}
+	}
+
+	err = vm.Run("2d6!")
+	if assert.NoError(t, err) {
+		tree = vm.GetDetailTree()
+		assert.Equal(t, IntType(100), tree.Children[0].Dice[0].Exploded)
+	}
+
+	// 没有计算过程时只有一个文本节点
+	err = vm.Run("1 + 2")
+	if assert.NoError(t, err) {
+		tree = vm.GetDetailTree()
+		if assert.Len(t, tree.Children, 1) {
+			assert.True(t, tree.Children[0].IsText())
+		}
+	}
+
+	assert.Error(t, vm.Run("1 + 'a'"))
+	assert.Nil(t, vm.GetDetailTree())
+	assert.Nil(t, NewVM().GetDetailTree())
+}
@@ -0,0 +1,157 @@
+package dicescript
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// DiceAlgorithm 骰池算法，供宿主程序按规则注册特有的骰池，如 Shadowrun 的 6sr、
+// Double Cross 的 5dx8，无需把每个跑团规则都写进核心语法。
+// 骰池写作 个数+后缀+参数，个数不能省略，以免与变量名混淆。如 Params 为 {"", "c"} 时 10ww8c9 的参数依次为8、9
+type DiceAlgorithm struct {
+	// 后缀，不区分大小写
+	Suffix string
+	// 后缀后可跟的参数，第一项为空时表示紧跟后缀的数字，其余各项为参数前的标记
+	Params []string
+	// 参数未写出时的默认值，长度应与 Params 相同
+	Defaults []IntType
+	// 执行骰池，mode 为-1时取最小值，1时取最大值，与 maxroll()、DiceMaxMode 等一致。返回结果与计算过程。
+	// 调用前已按个数计入算力，加骰等额外的骰子应调用 ctx.AddOpCount 计入
+	Roll func(ctx *Context, times IntType, args []IntType, mode int) (*VMValue, string, error)
+}
+
+type diceAlgorithmPayload struct {
+	times IntType
+	args  []IntType
+}
+
+// RegDiceAlgorithm 注册骰池算法，算法的解析先于内置的骰子语法，因此可以覆盖同样后缀的内置骰子
+func (ctx *Context) RegDiceAlgorithm(algo *DiceAlgorithm) error {
+	if algo == nil || algo.Roll == nil {
+		return errors.New("骰池算法不能为空")
+	}
+	if algo.Suffix == "" {
+		return errors.New("骰池算法的后缀不能为空")
+	}
+	if len(algo.Defaults) != len(algo.Params) {
+		return errors.New("骰池算法的默认值个数与参数个数不一致")
+	}
+	for i, p := range algo.Params {
+		if p == "" && i != 0 {
+			return errors.New("骰池算法只有第一个参数可以没有标记")
+		}
+	}
+	suffix := strings.ToLower(algo.Suffix)
+
+	parser := func(ctx *Context, stream *CustomDiceStream) (*CustomDiceParseResult, error) {
+		miss := &CustomDiceParseResult{Matched: false}
+		s, ok := stream.ReadDigits()
+		if !ok {
+			return miss, nil
+		}
+		times, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return miss, nil
+		}
+		if !streamReadWord(stream, suffix) {
+			return miss, nil
+		}
+
+		args := append([]IntType{}, algo.Defaults...)
+		for i, p := range algo.Params {
+			if p != "" && !streamReadWord(stream, strings.ToLower(p)) {
+				continue
+			}
+			s, ok := stream.ReadDigits()
+			if !ok {
+				if p == "" {
+					continue
+				}
+				return miss, nil
+			}
+			v, err := strconv.ParseInt(s, 10, 64)
+			if err != nil {
+				return miss, nil
+			}
+			args[i] = IntType(v)
+		}
+
+		// 后面紧跟文字时，不视为骰池，如 2src
+		if r, ok := stream.Peek(); ok && (r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			return miss, nil
+		}
+		stream.Commit()
+		return &CustomDiceParseResult{
+			Payload: &diceAlgorithmPayload{IntType(times), args},
+			Matched: true,
+		}, nil
+	}
+
+	handler := func(ctx *Context, groups []string, raw any) (*VMValue, string, error) {
+		info := raw.(*diceAlgorithmPayload)
+		// 每颗骰子计1点算力，加骰等额外的骰子由算法自行调用 ctx.AddOpCount
+		if !ctx.AddOpCount(info.times) {
+			return nil, "", ctx.Error
+		}
+		return algo.Roll(ctx, info.times, append([]IntType{}, info.args...), ctx.rollMode())
+	}
+
+	return ctx.RegCustomDiceParser(parser, handler)
+}
+
+// streamReadWord 不区分大小写地读取指定文字，不匹配时不前移游标
+func streamReadWord(stream *CustomDiceStream, word string) bool {
+	n := 0
+	for _, want := range word {
+		r, ok := stream.Read()
+		if ok {
+			n++
+		}
+		if !ok || unicode.ToLower(r) != want {
+			for ; n > 0; n-- {
+				stream.Unread()
+			}
+			return false
+		}
+	}
+	return true
+}
+
+// DiceAlgorithmShadowrun Shadowrun 骰池，如 6sr
+// 骰 个数 颗d6，5和6为命中，结果为命中数。超过一半的骰子为1时为失误，同时没有命中时为严重失误
+var DiceAlgorithmShadowrun = &DiceAlgorithm{
+	Suffix: "sr",
+	Roll: func(ctx *Context, times IntType, args []IntType, mode int) (*VMValue, string, error) {
+		if times < 1 || times > 1000 {
+			return nil, "", errors.New("骰池的骰子个数应在1至1000之间")
+		}
+		var hits, ones IntType
+		var points []string
+		for i := IntType(0); i < times; i++ {
+			one := Roll(ctx.RandSrc, 6, mode)
+			if one >= 5 {
+				hits++
+			} else if one == 1 {
+				ones++
+			}
+			if times <= 20 {
+				points = append(points, strconv.FormatInt(int64(one), 10))
+			}
+		}
+
+		detail := "命中" + strconv.FormatInt(int64(hits), 10)
+		if len(points) > 0 {
+			detail = "{" + strings.Join(points, ",") + "} " + detail
+		}
+		if ones*2 > times {
+			if hits == 0 {
+				detail += " 严重失误"
+			} else {
+				detail += " 失误"
+			}
+		}
+		return NewIntVal(hits), detail, nil
+	},
+}
@@ -0,0 +1,100 @@
+package dicescript
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiceAlgorithm(t *testing.T) {
+	vm := NewVM()
+	var got []IntType
+	err := vm.RegDiceAlgorithm(&DiceAlgorithm{
+		Suffix:   "ww",
+		Params:   []string{"", "c"},
+		Defaults: []IntType{10, 8},
+		Roll: func(ctx *Context, times IntType, args []IntType, mode int) (*VMValue, string, error) {
+			got = append([]IntType{times}, args...)
+			return ni(times), "ok", nil
+		},
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	// 覆盖内置的 ww 骰子
+	err = vm.Run("10ww8c9")
+	if assert.NoError(t, err) {
+		assert.Equal(t, []IntType{10, 8, 9}, got)
+		assert.Equal(t, "10[10ww8c9=ok]", vm.GetDetailText())
+	}
+	err = vm.Run("5WW + 1")
+	if assert.NoError(t, err) {
+		assert.Equal(t, []IntType{5, 10, 8}, got)
+		assert.True(t, valueEqual(vm.Ret, ni(6)))
+	}
+	err = vm.Run("3wwc7")
+	if assert.NoError(t, err) {
+		assert.Equal(t, []IntType{3, 10, 7}, got)
+	}
+
+	// 不匹配时按原有语法处理
+	got = nil
+	err = vm.Run("ww = 2; ww")
+	if assert.NoError(t, err) {
+		assert.Nil(t, got)
+		assert.True(t, valueEqual(vm.Ret, ni(2)))
+	}
+
+	assert.Error(t, vm.RegDiceAlgorithm(&DiceAlgorithm{Suffix: "x", Params: []string{"a"}}))
+	assert.Error(t, vm.RegDiceAlgorithm(&DiceAlgorithm{Suffix: "", Roll: DiceAlgorithmShadowrun.Roll}))
+}
+
+func TestDiceAlgorithmShadowrun(t *testing.T) {
+	vm := NewVM()
+	_ = vm.RegDiceAlgorithm(DiceAlgorithmShadowrun)
+
+	vm.Config.DiceMaxMode = true
+	err := vm.Run("6sr")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, ni(6)))
+		assert.Equal(t, "6[6sr={6,6,6,6,6,6} 命中6]", vm.GetDetailText())
+	}
+
+	vm.Config.DiceMaxMode = false
+	vm.Config.DiceMinMode = true
+	err = vm.Run("4sr")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, ni(0)))
+		assert.Equal(t, "0[4sr={1,1,1,1} 命中0 严重失误]", vm.GetDetailText())
+	}
+
+	// max(...)、min(...) 中的结算模式优先于全局设置
+	vm.Config.DiceMinMode = false
+	handler := vm.CustomDiceInfo[len(vm.CustomDiceInfo)-1].fn
+	vm.rollModes = []int{1}
+	ret, _, err := handler(vm, nil, &diceAlgorithmPayload{times: 3})
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(ret, ni(3)))
+	}
+	vm.rollModes = nil
+
+	// 每颗骰子计1点算力
+	vm.Config.OpCountLimit = 1000
+	err = vm.Run("100000000sr")
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "算力")
+	}
+	vm.Config.OpCountLimit = 0
+
+	for i := 0; i < 100; i++ {
+		err = vm.Run("10sr")
+		if assert.NoError(t, err) {
+			hits := vm.Ret.MustReadInt()
+			assert.True(t, hits >= 0 && hits <= 10)
+		}
+	}
+
+	err = vm.Run("0sr")
+	assert.Error(t, err)
+}
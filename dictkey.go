@@ -0,0 +1,215 @@
+package dicescript
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// 字典的键在内部以字符串储存: 字符串键原样储存，其他类型的键编码为以 dictKeyMark 开头的字符串。
+// 因此只有字符串键的字典与之前完全相同，ValueMap 也无需区分键的类型
+const dictKeyMark = "\x00"
+
+const (
+	dictKeyInt   = dictKeyMark + "i"
+	dictKeyFloat = dictKeyMark + "f"
+	dictKeyTuple = dictKeyMark + "t"
+)
+
+// AsDictKey 将值转为字典内部使用的键。字符串原样使用；整数作为整数键，但与同样内容的字符串互为别名，见 dictAliasKey；
+// 整数值的浮点数与整数相同(1.0 与 1 是同一个键)；由这些值组成的元组作为元组键，如 (1, 'a')，数组视为同样内容的元组
+func (v *VMValue) AsDictKey() (string, error) {
+	switch v.TypeId {
+	case VMTypeString:
+		s, _ := v.ReadString()
+		return s, nil
+	case VMTypeInt:
+		return dictKeyInt + strconv.FormatInt(int64(v.MustReadInt()), 10), nil
+	case VMTypeBool:
+		// 与 true == 1 一致，true 与 1 是同一个键
+		return operandValue(v).AsDictKey()
+	case VMTypeFloat:
+		f, _ := v.ReadFloat()
+		if f == math.Trunc(f) && f >= math.MinInt64 && f < math.MaxInt64 {
+			return dictKeyInt + strconv.FormatInt(int64(f), 10), nil
+		}
+		return dictKeyFloat + strconv.FormatFloat(f, 'g', -1, 64), nil
+	case VMTypeTuple, VMTypeArray:
+		items, _ := v.readSequence()
+		var sb strings.Builder
+		sb.WriteString(dictKeyTuple)
+		for _, item := range items {
+			k, err := item.AsDictKey()
+			if err != nil {
+				return "", err
+			}
+			// 以长度分隔各项
+			sb.WriteString(strconv.Itoa(len(k)))
+			sb.WriteByte(':')
+			sb.WriteString(k)
+		}
+		return sb.String(), nil
+	}
+	return "", fmt.Errorf("类型错误: 字典键只能为字符串、数字或由它们组成的元组，不支持 %s", v.GetTypeName())
+}
+
+// DictKeyValue 将字典内部的键还原为值，如 {1: 'a'}.keys() 得到 [1]
+func DictKeyValue(key string) *VMValue {
+	if !strings.HasPrefix(key, dictKeyMark) {
+		return NewStrVal(key)
+	}
+	switch {
+	case strings.HasPrefix(key, dictKeyInt):
+		if n, err := strconv.ParseInt(key[len(dictKeyInt):], 10, 64); err == nil {
+			return NewIntVal(IntType(n))
+		}
+	case strings.HasPrefix(key, dictKeyFloat):
+		if f, err := strconv.ParseFloat(key[len(dictKeyFloat):], 64); err == nil {
+			return NewFloatVal(f)
+		}
+	case strings.HasPrefix(key, dictKeyTuple):
+		var items []*VMValue
+		rest := key[len(dictKeyTuple):]
+		for rest != "" {
+			i := strings.IndexByte(rest, ':')
+			if i < 0 {
+				return NewStrVal(key)
+			}
+			n, err := strconv.Atoi(rest[:i])
+			if err != nil || i+1+n > len(rest) {
+				return NewStrVal(key)
+			}
+			items = append(items, DictKeyValue(rest[i+1:i+1+n]))
+			rest = rest[i+1+n:]
+		}
+		return NewTupleVal(items...)
+	}
+	return NewStrVal(key)
+}
+
+// dictKeyText 键的文本形式，用于转换为只支持字符串键的格式，如 {1: 'a'} 转为json时键为 "1"
+func dictKeyText(key string) string {
+	if !strings.HasPrefix(key, dictKeyMark) {
+		return key
+	}
+	return DictKeyValue(key).ToString()
+}
+
+// dictKeyRepr 显示字典时键的写法，字符串键带引号
+func dictKeyRepr(key string) string {
+	if !strings.HasPrefix(key, dictKeyMark) {
+		return "'" + key + "'"
+	}
+	return DictKeyValue(key).ToRepr()
+}
+
+// dictKeyLess sortKeys 使用的顺序: 数字键按大小排在前面，其余按字典序
+func dictKeyLess(a, b string) bool {
+	an := strings.HasPrefix(a, dictKeyInt) || strings.HasPrefix(a, dictKeyFloat)
+	bn := strings.HasPrefix(b, dictKeyInt) || strings.HasPrefix(b, dictKeyFloat)
+	if an && bn {
+		return dictKeyNumber(a) < dictKeyNumber(b)
+	}
+	if an != bn {
+		return an
+	}
+	return a < b
+}
+
+func dictKeyNumber(key string) float64 {
+	v := DictKeyValue(key)
+	if v.TypeId == VMTypeInt {
+		return float64(v.MustReadInt())
+	}
+	f, _ := v.ReadFloat()
+	return f
+}
+
+// dictAliasKey 数字键与其文本形式的字符串键互为别名，如 1 与 '1'。旧版本中数字键被转换为字符串储存，
+// 因此两者视为同一个键。返回key的别名，没有别名时ok为false
+func dictAliasKey(key string) (alias string, ok bool) {
+	switch {
+	case strings.HasPrefix(key, dictKeyInt):
+		return key[len(dictKeyInt):], true
+	case strings.HasPrefix(key, dictKeyFloat):
+		return key[len(dictKeyFloat):], true
+	case strings.HasPrefix(key, dictKeyMark):
+		return "", false
+	}
+	if n, err := strconv.ParseInt(key, 10, 64); err == nil && strconv.FormatInt(n, 10) == key {
+		return dictKeyInt + key, true
+	}
+	if f, err := strconv.ParseFloat(key, 64); err == nil && f != math.Trunc(f) && strconv.FormatFloat(f, 'g', -1, 64) == key {
+		return dictKeyFloat + key, true
+	}
+	return "", false
+}
+
+// dictResolveKey 字典中已有key的别名时使用别名，读取、写入和 in 都经过这里，因此 1 与 '1' 不会同时存在
+func dictResolveKey(m *ValueMap, key string) string {
+	if _, ok := m.Load(key); ok {
+		return key
+	}
+	if alias, ok := dictAliasKey(key); ok {
+		if _, ok := m.Load(alias); ok {
+			return alias
+		}
+	}
+	return key
+}
+
+// keyOf index在字典中对应的键
+func (d *VMDictValue) keyOf(index *VMValue) (string, error) {
+	key, err := index.AsDictKey()
+	if err != nil {
+		return "", err
+	}
+	if dd, ok := d.V().ReadDictData(); ok {
+		key = dictResolveKey(dd.Dict, key)
+	}
+	return key, nil
+}
+
+// dictTextKeys 序列化时使用的字典: 键为文本形式，非字符串键另外记录在typed中，以便读取时还原。
+// 文本形式相同的两个键无法区分，返回错误
+func dictTextKeys(m *ValueMap) (plain *ValueMap, typed []*VMValue, err error) {
+	plain = &ValueMap{}
+	m.Range(func(key string, value *VMValue) bool {
+		text := dictKeyText(key)
+		if _, dup := plain.Load(text); dup {
+			err = fmt.Errorf("值错误: 字典的键 %s 转换为文本后重复", dictKeyRepr(key))
+			return false
+		}
+		plain.Store(text, value)
+		if text != key {
+			typed = append(typed, DictKeyValue(key))
+		}
+		return true
+	})
+	return plain, typed, err
+}
+
+// dictFromTextKeys dictTextKeys 的逆过程
+func dictFromTextKeys(plain *ValueMap, typed []*VMValue) (*ValueMap, error) {
+	if len(typed) == 0 {
+		return plain, nil
+	}
+	keys := map[string]string{}
+	for _, k := range typed {
+		key, err := k.AsDictKey()
+		if err != nil {
+			return nil, err
+		}
+		keys[dictKeyText(key)] = key
+	}
+	m := &ValueMap{}
+	plain.Range(func(text string, value *VMValue) bool {
+		if key, ok := keys[text]; ok {
+			text = key
+		}
+		m.Store(text, value)
+		return true
+	})
+	return m, nil
+}
@@ -0,0 +1,121 @@
+package dicescript
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDictIntKeys(t *testing.T) {
+	vm := NewVM()
+	err := vm.Run("m = {1: 'a', 2: 'b'}; [m[1], m['1'], m[1.0], '2' in m, m.keys(), `{m}`]")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, na(
+			ns("a"), ns("a"), ns("a"), NewBoolVal(true),
+			na(ni(1), ni(2)),
+			ns("{1: 'a', 2: 'b'}"),
+		)))
+	}
+
+	// 1 与 '1' 是同一个键
+	vm = NewVM()
+	err = vm.Run("m = {1: 'a', '1': 'b'}; m['1'] = 'c'; [m[1], m.len()]")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, na(ns("c"), ni(1))))
+	}
+
+	// 以骰点结果为索引的查询表
+	vm = NewVM()
+	err = vm.Run("表 = {1: '大失败', 2: '失败', 3: '成功'}; 表[d3]")
+	if assert.NoError(t, err) {
+		assert.Equal(t, VMTypeString, vm.Ret.TypeId)
+	}
+}
+
+func TestDictIntKeysSort(t *testing.T) {
+	vm := NewVM()
+	err := vm.Run("m = {'b': 1, 10: 2, 2: 3, 1.5: 4, 'a': 5}; m.sortKeys().keys()")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, na(nf(1.5), ni(2), ni(10), ns("a"), ns("b"))))
+	}
+}
+
+func TestDictTupleKeys(t *testing.T) {
+	vm := NewVM()
+	err := vm.Run("m = {}; m[(1, 'a')] = 2; m[(1, 'a')] + 1")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, ni(3)))
+	}
+
+	vm = NewVM()
+	err = vm.Run("m = {}; m[(1, 'a')] = 2; [m.keys(), m[(1, 'a', 0)] ?? 0, `{m}`]")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, na(na(NewTupleVal(ni(1), ns("a"))), ni(0), ns("{(1, 'a'): 2}"))))
+	}
+
+	// 数组视为同样内容的元组
+	vm = NewVM()
+	err = vm.Run("m = {}; m[[1, 'a']] = 2; m[(1, 'a')]")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, ni(2)))
+	}
+
+	vm = NewVM()
+	err = vm.Run("m = {}; m[{}] = 1")
+	assert.Error(t, err)
+}
+
+func TestDictKeysRoundTrip(t *testing.T) {
+	vm := NewVM()
+	err := vm.Run("m = {1: 'a', 'x': 2, 1.5: 3}")
+	if !assert.NoError(t, err) {
+		return
+	}
+	m := vm.Attrs.MustLoad("m")
+
+	data, err := m.ToJSON()
+	if assert.NoError(t, err) {
+		v, err := VMValueFromJSON(data)
+		if assert.NoError(t, err) {
+			assert.True(t, valueEqual(v, m))
+			assert.True(t, valueEqual(funcDictKeys(nil, v, nil), na(ni(1), ns("x"), nf(1.5))))
+		}
+		// 键以文本形式储存
+		assert.NotContains(t, string(data), `\u0000`)
+	}
+
+	plain, err := m.ToPlainJSON()
+	if assert.NoError(t, err) {
+		assert.Equal(t, `{"1":"a","1.5":3,"x":2}`, string(plain))
+	}
+}
+
+func TestDictLegacyKeys(t *testing.T) {
+	// 旧版本储存的数据中数字键为字符串
+	data := &ValueMap{}
+	data.Store("1", ns("a"))
+	vm := NewVM()
+	vm.StoreNameLocal("m", NewDictVal(data).V())
+	err := vm.Run("m[1]")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, ns("a")))
+	}
+
+	// 写入和 in 同样使用已有的字符串键
+	err = vm.Run("m[1] = 'b'; [m['1'], 1 in m, m.len()]")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, na(ns("b"), NewBoolVal(true), ni(1))))
+	}
+}
+
+func TestDictKeysCollision(t *testing.T) {
+	// 宿主直接构造的字典中可能同时有 1 与 '1'，转换为文本键时报错
+	data := &ValueMap{}
+	data.Store("1", ns("a"))
+	data.Store(dictKeyInt+"1", ns("b"))
+	m := NewDictVal(data).V()
+	_, err := m.ToPlainJSON()
+	assert.Error(t, err)
+	_, err = m.ToJSON()
+	assert.Error(t, err)
+}
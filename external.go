@@ -0,0 +1,110 @@
+package dicescript
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// ErrTimeLimit 执行时间超过 RollConfig.TimeLimit
+var ErrTimeLimit = errors.New("执行超时")
+
+// ErrInterrupted 执行被 Interrupt 中止
+var ErrInterrupted = errors.New("执行被中止")
+
+// errOpCountLimit 算力超过 RollConfig.OpCountLimit
+var errOpCountLimit = errors.New("允许算力上限")
+
+// externalCallCost 每次外部调用计入的算力
+const externalCallCost = 100
+
+// deadlineCheckInterval 算力每增长这么多检查一次是否超时
+const deadlineCheckInterval = 256
+
+// CallExternal 在原生函数中执行可能阻塞的外部调用(如查询数据库)，调用受本次执行的时间限制和算力限制约束。
+// fn 总是在单独的goroutine中执行，超时或被 Interrupt 中止后立即返回 ErrTimeLimit 或 ErrInterrupted，
+// fn 仍会在后台执行完毕，但其结果被丢弃，因此 fn 不应修改vm的状态。
+// fn 中的 panic 会被转为错误。返回错误时原生函数应将其赋给 ctx.Error 以中止执行，例如:
+//
+//	v, err := ctx.CallExternal(func() (*VMValue, error) { return lookup(name) })
+//	if err != nil {
+//		ctx.Error = err
+//		return nil
+//	}
+func (ctx *Context) CallExternal(fn func() (*VMValue, error)) (*VMValue, error) {
+	// 中止、超时、算力和配额的检查与执行指令时相同，失败时 ctx.Error 已被设置
+	if !ctx.AddOpCount(externalCallCost) {
+		return nil, ctx.Error
+	}
+
+	type result struct {
+		v   *VMValue
+		err error
+	}
+	// 带缓冲，超时后后台的调用结束时不会阻塞
+	ch := make(chan result, 1)
+	go func() {
+		v, err := callExternalSafe(fn)
+		ch <- result{v, err}
+	}()
+
+	// 没有时间限制时只等待调用完成或被中止，nil的通道不会就绪
+	var timeout <-chan time.Time
+	if deadline := ctx.rootCtx().deadline; !deadline.IsZero() {
+		timer := time.NewTimer(time.Until(deadline))
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	select {
+	case r := <-ch:
+		return r.v, r.err
+	case <-timeout:
+		return nil, ErrTimeLimit
+	case <-ctx.interruptDone():
+		return nil, ErrInterrupted
+	}
+}
+
+// Interrupt 中止正在进行的执行，可以在其他goroutine中调用，如管理员中止失控的宏而不必结束整个进程。
+// 执行会在下一条指令处停止并返回 ErrInterrupted，该错误不能被 try() 捕获。每次执行开始时清除中止状态，
+// 因此没有正在进行的执行时调用不会影响之后的执行
+func (ctx *Context) Interrupt() {
+	root := ctx.rootCtx()
+	root.interruptMu.Lock()
+	defer root.interruptMu.Unlock()
+	if atomic.SwapInt32(&root.interrupted, 1) == 0 && root.interruptCh != nil {
+		close(root.interruptCh)
+	}
+}
+
+// resetInterrupt 执行开始时清除中止状态
+func (ctx *Context) resetInterrupt() {
+	ctx.interruptMu.Lock()
+	defer ctx.interruptMu.Unlock()
+	atomic.StoreInt32(&ctx.interrupted, 0)
+	ctx.interruptCh = make(chan struct{})
+}
+
+// interruptDone 本次执行被中止时关闭的通道
+func (ctx *Context) interruptDone() <-chan struct{} {
+	root := ctx.rootCtx()
+	root.interruptMu.Lock()
+	defer root.interruptMu.Unlock()
+	return root.interruptCh
+}
+
+// isInterrupted 本次执行是否已被 Interrupt 中止
+func (ctx *Context) isInterrupted() bool {
+	return atomic.LoadInt32(&ctx.rootCtx().interrupted) != 0
+}
+
+func callExternalSafe(fn func() (*VMValue, error)) (v *VMValue, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			v = nil
+			err = fmt.Errorf("外部调用出错: %v", r)
+		}
+	}()
+	return fn()
+}
@@ -0,0 +1,108 @@
+package dicescript
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newLookupVM(delay time.Duration) *Context {
+	lookup := NewNativeFunctionVal(&NativeFunctionData{
+		Name:   "lookup",
+		Params: []string{"name"},
+		NativeFunc: func(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
+			v, err := ctx.CallExternal(func() (*VMValue, error) {
+				time.Sleep(delay)
+				name, _ := params[0].ReadString()
+				if name == "" {
+					return nil, errors.New("不存在")
+				}
+				if name == "panic" {
+					panic("boom")
+				}
+				return ns("查询:" + name), nil
+			})
+			if err != nil {
+				ctx.Error = err
+				return nil
+			}
+			return v
+		},
+	})
+	return NewVM(WithBuiltins(map[string]*VMValue{"lookup": lookup}))
+}
+
+func TestCallExternal(t *testing.T) {
+	vm := newLookupVM(0)
+	err := vm.Run("func f(x) { lookup(x) }; f('a')")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, ns("查询:a")))
+	}
+
+	// fn返回的错误和panic都会中止执行
+	err = vm.Run("lookup('')")
+	if assert.Error(t, err) {
+		assert.Equal(t, "不存在", err.Error())
+	}
+	err = vm.Run("lookup('panic')")
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "boom")
+	}
+
+	// 外部调用计入算力
+	vm.Config.OpCountLimit = 150
+	err = vm.Run("lookup('a'); lookup('b')")
+	if assert.Error(t, err) {
+		assert.Equal(t, "允许算力上限", err.Error())
+	}
+}
+
+func TestCallExternalTimeout(t *testing.T) {
+	vm := newLookupVM(time.Second)
+	vm.Config.TimeLimit = 20 * time.Millisecond
+	start := time.Now()
+	err := vm.Run("lookup('a')")
+	assert.Equal(t, ErrTimeLimit, err)
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+
+	// 时间限制同样约束脚本本身
+	vm = NewVM()
+	err = vm.RunWith("while 1 {}", RunOptions{Limits: &RunLimits{TimeLimit: 20 * time.Millisecond}})
+	assert.Equal(t, ErrTimeLimit, err)
+	assert.Equal(t, time.Duration(0), vm.Config.TimeLimit)
+
+	// 没有时间限制时等待调用完成
+	vm = newLookupVM(30 * time.Millisecond)
+	err = vm.Run("lookup('a')")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, ns("查询:a")))
+	}
+}
+
+func TestInterrupt(t *testing.T) {
+	vm := NewVM()
+	for _, expr := range []string{"while 1 {}", "func f() { while 1 {} }; f()", "func f() { while 1 {} }; try(f)"} {
+		timer := time.AfterFunc(20*time.Millisecond, vm.Interrupt)
+		err := vm.Run(expr)
+		timer.Stop()
+		assert.Equal(t, ErrInterrupted, err, expr)
+	}
+
+	// 等待外部调用时同样立即返回
+	vm = newLookupVM(2 * time.Second)
+	timer := time.AfterFunc(50*time.Millisecond, vm.Interrupt)
+	start := time.Now()
+	err := vm.Run("lookup('a')")
+	timer.Stop()
+	assert.Equal(t, ErrInterrupted, err)
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+
+	// 只影响正在进行的执行
+	vm.Interrupt()
+	err = vm.Run("1 + 1")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, ni(2)))
+	}
+}
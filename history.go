@@ -0,0 +1,113 @@
+package dicescript
+
+import "errors"
+
+// rollHistory 最近几次执行结果的环形缓冲区
+type rollHistory struct {
+	items []*RollResult
+	next  int // 下一次写入的位置
+	count int
+}
+
+func (h *rollHistory) push(r *RollResult) {
+	h.items[h.next] = r
+	h.next = (h.next + 1) % len(h.items)
+	if h.count < len(h.items) {
+		h.count++
+	}
+}
+
+// get 取得倒数第n次的结果，n从1开始
+func (h *rollHistory) get(n int) *RollResult {
+	if n <= 0 || n > h.count {
+		return nil
+	}
+	return h.items[(h.next-n+len(h.items))%len(h.items)]
+}
+
+// recordHistory 成功执行后记录结果，容量由 RollConfig.HistorySize 决定
+func (ctx *Context) recordHistory() {
+	size := ctx.Config.HistorySize
+	if size <= 0 {
+		ctx.history = nil
+		return
+	}
+	if ctx.history == nil || len(ctx.history.items) != size {
+		// 容量变化时保留最近的记录
+		h := &rollHistory{items: make([]*RollResult, size)}
+		old := ctx.History()
+		if len(old) > size {
+			old = old[len(old)-size:]
+		}
+		for _, r := range old {
+			h.push(r)
+		}
+		ctx.history = h
+	}
+	ctx.history.push(ctx.result())
+}
+
+// History 最近几次执行的结果，从旧到新排列。需要设置 RollConfig.HistorySize
+func (ctx *Context) History() []*RollResult {
+	h := ctx.history
+	if h == nil {
+		return nil
+	}
+	ret := make([]*RollResult, h.count)
+	for i := 0; i < h.count; i++ {
+		ret[i] = h.get(h.count - i)
+	}
+	return ret
+}
+
+func funcLastRoll(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
+	n, ok := params[0].ReadInt()
+	if !ok || n <= 0 {
+		ctx.Error = errors.New("(lastroll)值错误: 次数必须为正整数")
+		return nil
+	}
+	// 在函数中调用时，使用最外层vm的记录
+	root := ctx.rootCtx()
+	if root.history == nil {
+		return NewNullVal()
+	}
+	r := root.history.get(int(n))
+	if r == nil {
+		return NewNullVal()
+	}
+	return NewDictValWithArrayMust(
+		NewStrVal("expr"), NewStrVal(r.Matched),
+		NewStrVal("value"), r.Value,
+		NewStrVal("detail"), NewStrVal(r.Detail),
+	).V()
+}
+
+// lastRunInfo 上一次执行的语句，以及执行前的随机源状态
+type lastRunInfo struct {
+	expr string
+	seed []byte
+}
+
+// RerollLast 将上一次执行的语句加上括号，再加上mod重新执行，如 "+2" 时执行 (3d20)+2。
+// 执行时恢复上一次的随机源状态，因此原有的骰子得到同样的点数，只有新增的骰子会重新投掷。
+// 规则集可以通过 RerollRewrite 改写语句(如转为优势骰)，或通过 RerollNewDice 要求全部重骰。
+// 需要开启 RollConfig.HistorySize
+func (ctx *Context) RerollLast(mod string) error {
+	last := ctx.lastRun
+	if last == nil {
+		return errors.New("没有可以重骰的记录")
+	}
+	expr := "(" + last.expr + ")" + mod
+	var opts RunOptions
+	if rs := ctx.Config.RuleSet; rs != nil {
+		if rs.RerollRewrite != nil {
+			expr = rs.RerollRewrite(ctx, last.expr, mod)
+		}
+		if !rs.RerollNewDice {
+			opts.Seed = last.seed
+		}
+	} else {
+		opts.Seed = last.seed
+	}
+	return ctx.RunWith(expr, opts)
+}
@@ -0,0 +1,116 @@
+package dicescript
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHistory(t *testing.T) {
+	vm := NewVM()
+	assert.NoError(t, vm.Run("1 + 1"))
+	assert.Nil(t, vm.History())
+
+	vm.Config.HistorySize = 2
+	for _, expr := range []string{"1", "2", "3"} {
+		assert.NoError(t, vm.Run(expr))
+	}
+	h := vm.History()
+	if assert.Len(t, h, 2) {
+		assert.Equal(t, "2", h[0].Matched)
+		assert.Equal(t, "3", h[1].Matched)
+	}
+
+	// 出错的执行不会被记录
+	assert.Error(t, vm.Run("1 + 'a'"))
+	assert.Len(t, vm.History(), 2)
+
+	// 容量变化时保留最近的记录
+	vm.Config.HistorySize = 3
+	assert.NoError(t, vm.Run("4"))
+	h = vm.History()
+	if assert.Len(t, h, 3) {
+		assert.Equal(t, "2", h[0].Matched)
+		assert.Equal(t, "4", h[2].Matched)
+	}
+}
+
+func TestLastRoll(t *testing.T) {
+	vm := NewVM()
+	assert.NoError(t, vm.Run("lastroll()"))
+	assert.Equal(t, VMTypeNull, vm.Ret.TypeId)
+
+	vm.Config.HistorySize = 5
+	assert.NoError(t, vm.Run("d100"))
+	first := vm.Ret.MustReadInt()
+	assert.NoError(t, vm.Run("7"))
+
+	err := vm.Run("r = lastroll(2); [r.expr, r.value, lastroll().value, lastroll(3)]")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, na(ns("d100"), ni(first), ni(7), NewNullVal())))
+	}
+
+	// 在函数中调用时读取外层vm的记录
+	assert.NoError(t, vm.Run("3"))
+	err = vm.Run("func f() { return lastroll().value }; f()")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, ni(3)))
+	}
+
+	assert.Error(t, vm.Run("lastroll(0)"))
+}
+
+func TestRerollLast(t *testing.T) {
+	vm := NewVM()
+	assert.Error(t, vm.RerollLast("+2"))
+
+	// 没有开启执行记录
+	assert.NoError(t, vm.Run("3d20"))
+	assert.Error(t, vm.RerollLast("+2"))
+
+	// 沿用原有的骰点，只加上调整值
+	vm.Config.HistorySize = 1
+	assert.NoError(t, vm.Run("3d20"))
+	first := vm.Ret.MustReadInt()
+	if assert.NoError(t, vm.RerollLast("+2")) {
+		assert.True(t, valueEqual(vm.Ret, ni(first+2)))
+		assert.Equal(t, "(3d20)+2", vm.Matched)
+	}
+	// 可以连续重骰，每次都将上一次的语句作为整体
+	if assert.NoError(t, vm.RerollLast("*2")) {
+		assert.Equal(t, "((3d20)+2)*2", vm.Matched)
+		assert.True(t, valueEqual(vm.Ret, ni((first+2)*2)))
+	}
+
+	// 由规则集改写为优势骰，第一颗骰子沿用，结果不会更低
+	vm = NewVM()
+	vm.Config.HistorySize = 1
+	vm.Config.RuleSet = &RuleSet{RerollRewrite: func(ctx *Context, expr string, mod string) string {
+		if mod == "优势" {
+			return strings.ReplaceAll(expr, "d20", "d20优势")
+		}
+		return expr + mod
+	}}
+	for i := 0; i < 20; i++ {
+		assert.NoError(t, vm.Run("d20"))
+		first = vm.Ret.MustReadInt()
+		if assert.NoError(t, vm.RerollLast("优势")) {
+			assert.Equal(t, "d20优势", vm.Matched)
+			assert.True(t, vm.Ret.MustReadInt() >= first)
+		}
+	}
+
+	// 规则集要求全部重骰
+	vm = NewVM()
+	vm.Config.HistorySize = 1
+	vm.Config.RuleSet = &RuleSet{RerollNewDice: true}
+	changed := false
+	for i := 0; i < 20 && !changed; i++ {
+		assert.NoError(t, vm.Run("d1000"))
+		first = vm.Ret.MustReadInt()
+		assert.NoError(t, vm.RerollLast("+0"))
+		changed = vm.Ret.MustReadInt() != first
+	}
+	assert.True(t, changed)
+}
@@ -0,0 +1,77 @@
+package dicescript
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// lenientMaxRetry 宽松解析时最多忽略几处杂散字符
+const lenientMaxRetry = 16
+
+// isParseNoise 宽松解析时可以忽略的字符
+func isParseNoise(r rune) bool {
+	switch r {
+	case ' ', '\t', '　', ',', '，', '、':
+		return true
+	}
+	return false
+}
+
+// noiseRun 找到s中第一段连续的杂散字符，没有时begin为-1
+func noiseRun(s string) (begin int, end int) {
+	begin = strings.IndexFunc(s, isParseNoise)
+	if begin < 0 {
+		return -1, -1
+	}
+	end = begin
+	for end < len(s) {
+		r, size := utf8.DecodeRuneInString(s[end:])
+		if !isParseNoise(r) {
+			break
+		}
+		end += size
+	}
+	return begin, end
+}
+
+func isDigitByte(s string, i int) bool {
+	return i >= 0 && i < len(s) && s[i] >= '0' && s[i] <= '9'
+}
+
+// parseLenient 宽松解析。解析停下的位置之后若有杂散字符，去掉后能解析得更远，就忽略这些字符重新解析，
+// 如 "3 d6" 解析为 "3d6"。每次只尝试停下位置之后的第一段，没有进展时停止，因此 "3d6, 谢谢" 中的 ", 谢谢" 仍作为 RestInput
+func (ctx *Context) parseLenient(value string) error {
+	err := ctx.parseCode(value)
+	for i := 0; i < lenientMaxRetry; i++ {
+		offset := 0
+		if err == nil {
+			offset = ctx.parser.pt.offset
+			// 解析时可能已经吃掉了末尾的空白
+			for offset > 0 {
+				r, size := utf8.DecodeLastRuneInString(value[:offset])
+				if !isParseNoise(r) {
+					break
+				}
+				offset -= size
+			}
+		}
+		begin, end := noiseRun(value[offset:])
+		if begin < 0 {
+			break
+		}
+		begin, end = offset+begin, offset+end
+		if isDigitByte(value, begin-1) && isDigitByte(value, end) {
+			// "1 2" 不能合成12
+			break
+		}
+
+		candidate := value[:begin] + value[end:]
+		parser, code, codeIndex := ctx.parser, ctx.code, ctx.codeIndex
+		if err1 := ctx.parseCode(candidate); err1 != nil || ctx.parser.pt.offset <= begin {
+			ctx.parser, ctx.code, ctx.codeIndex = parser, code, codeIndex
+			break
+		}
+		value, err = candidate, nil
+	}
+	return err
+}
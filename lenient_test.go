@@ -0,0 +1,47 @@
+package dicescript
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLenientParse(t *testing.T) {
+	cases := []struct {
+		expr    string
+		ret     IntType
+		matched string
+		rest    string
+	}{
+		{"3 d6", 18, "3d6", ""},
+		{"3 d 6 + 2", 20, "3d6 + 2", ""},
+		{"d20+ ,5", 25, "d20+5", ""},
+		{"，d20", 20, "d20", ""},
+		{"3d6 + 2, 谢谢", 20, "3d6 + 2", ", 谢谢"},
+		{"1 2", 1, "1", " 2"}, // 不合并数字
+		{"3d6,2d6", 18, "3d6", ",2d6"},
+	}
+	for _, c := range cases {
+		vm := NewVM()
+		vm.Config.LenientParse = true
+		vm.Config.DiceMaxMode = true
+		err := vm.Run(c.expr)
+		if assert.NoError(t, err, c.expr) {
+			assert.True(t, valueEqual(vm.Ret, ni(c.ret)), c.expr)
+			assert.Equal(t, c.matched, vm.Matched, c.expr)
+			assert.Equal(t, c.rest, vm.RestInput, c.expr)
+		}
+	}
+
+	// 未开启时不受影响
+	vm := NewVM()
+	err := vm.Run("3 d6")
+	if assert.NoError(t, err) {
+		assert.Equal(t, " d6", vm.RestInput)
+	}
+
+	vm = NewVM()
+	vm.Config.LenientParse = true
+	err = vm.Run("，，")
+	assert.Error(t, err)
+}
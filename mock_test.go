@@ -6,19 +6,27 @@ import "testing"
 // 因此这个文件用来水掉没意义的函数
 
 func TestMockByteCodeString(t *testing.T) {
-	for i := 0; i < 87; i++ {
+	for i := 0; i < 100; i++ {
 		c := &ByteCode{T: CodeType(i), Value: IntType(1)}
 		switch c.T {
 		case typePushFloatNumber:
 			c.Value = 1.1
+		case typePushBool:
+			c.Value = true
 		case typePushString:
 			c.Value = ""
+		case typePushQuantity:
+			c.Value = QuantityData{Value: 1, Unit: "kg"}
+		case typePushMoney:
+			c.Value = MoneyData{Amount: 1, System: DefaultCurrency}
 		case typePushComputed:
 			c.Value = NewComputedVal("1")
 		case typePushFunction:
 			c.Value = NewFunctionValRaw(&FunctionData{Expr: "1"})
 		case typeLoadName, typeLoadNameWithDetail, typeLoadNameRaw, typeInvokeSelf, typeAttrSet, typeAttrGet:
 			c.Value = "name"
+		case typeLoadConstWithDetail:
+			c.Value = NewIntVal(1)
 		case typeDetailMark:
 			c.Value = BufferSpan{}
 		}
@@ -0,0 +1,42 @@
+package dicescript
+
+import "fmt"
+
+// MockNative 在测试中用fn替换名为name的内置函数(包括 WithBuiltins 和规则集注册的函数)，返回恢复原函数的restore。
+// 参数表和默认值沿用原函数，用于将 now()、随机表、宿主程序的查询等固定下来:
+//
+//	restore := vm.MockNative("now", func(ctx *Context, this *VMValue, params []*VMValue) *VMValue { ... })
+//	t.Cleanup(restore)
+//
+// 可以多次替换同一个函数，restore 只撤销对应的一次。name 不是已注册的函数时 panic
+func (ctx *Context) MockNative(name string, fn NativeFunctionDef) (restore func()) {
+	var cd *NativeFunctionData
+	if orig := ctx.loadInnerVar(name); orig != nil {
+		cd, _ = orig.ReadNativeFunctionData()
+	}
+	if cd == nil {
+		panic(fmt.Sprintf("MockNative: 函数 %s 不存在", name))
+	}
+	mock := NewNativeFunctionVal(&NativeFunctionData{
+		Name:       cd.Name,
+		Params:     cd.Params,
+		Defaults:   cd.Defaults,
+		NativeFunc: fn,
+	})
+	if ctx.mocks == nil {
+		ctx.mocks = map[string][]*VMValue{}
+	}
+	ctx.mocks[name] = append(ctx.mocks[name], mock)
+	return func() {
+		stack := ctx.mocks[name]
+		for i, v := range stack {
+			if v == mock {
+				ctx.mocks[name] = append(stack[:i:i], stack[i+1:]...)
+				break
+			}
+		}
+		if len(ctx.mocks[name]) == 0 {
+			delete(ctx.mocks, name)
+		}
+	}
+}
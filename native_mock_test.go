@@ -0,0 +1,48 @@
+package dicescript
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMockNative(t *testing.T) {
+	vm := NewVM()
+	restore := vm.MockNative("now", func(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
+		return ni(100)
+	})
+	assert.NoError(t, vm.Run("func f() { return now() }; now() + f()"))
+	assert.True(t, valueEqual(vm.Ret, ni(200)))
+
+	// 沿用原函数的参数表和默认值
+	restore2 := vm.MockNative("randstr", func(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
+		return NewStrVal(params[0].ToString() + ":" + params[1].ToString())
+	})
+	assert.NoError(t, vm.Run("randstr(3)"))
+	assert.Equal(t, "3:null", vm.Ret.ToString())
+	assert.Error(t, vm.Run("randstr()"))
+	restore2()
+
+	// 多次替换，restore只撤销对应的一次
+	restore3 := vm.MockNative("now", func(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
+		return ni(1)
+	})
+	assert.NoError(t, vm.Run("now()"))
+	assert.True(t, valueEqual(vm.Ret, ni(1)))
+	restore()
+	assert.NoError(t, vm.Run("now()"))
+	assert.True(t, valueEqual(vm.Ret, ni(1)))
+	restore3()
+	assert.NoError(t, vm.Run("now()"))
+	assert.Equal(t, VMTypeTime, vm.Ret.TypeId)
+
+	// 不影响其他vm
+	other := NewVM()
+	vm.MockNative("ceil", func(ctx *Context, this *VMValue, params []*VMValue) *VMValue { return ni(0) })
+	assert.NoError(t, other.Run("ceil(1.5)"))
+	assert.True(t, valueEqual(other.Ret, ni(2)))
+
+	assert.Panics(t, func() {
+		vm.MockNative("no_such_func", func(ctx *Context, this *VMValue, params []*VMValue) *VMValue { return nil })
+	})
+}
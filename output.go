@@ -0,0 +1,151 @@
+package dicescript
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+)
+
+// OutputItem output() 给出的一项带标签的结果
+type OutputItem struct {
+	Label string
+	Value *VMValue
+}
+
+// funcOutput 给出一项带标签的结果，如 output('命中', d20+5)，按调用顺序记录在 ctx.Outputs 中，返回值为value本身
+func funcOutput(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
+	label, ok := params[0].ReadString()
+	if !ok {
+		ctx.Error = errors.New("(output)类型错误: 标签必须为str")
+		return nil
+	}
+	root := ctx.rootCtx()
+	root.Outputs = append(root.Outputs, OutputItem{Label: label, Value: params[1].Clone()})
+	ctx.streamOutput(label + ": " + ctx.ToString(params[1]))
+	return params[1]
+}
+
+// streamOutput 将一段输出交给最外层vm的 OnOutput
+func (ctx *Context) streamOutput(part string) {
+	if root := ctx.rootCtx(); root.OnOutput != nil {
+		root.OnOutput(part)
+	}
+}
+
+// EventItem emit() 给出的事件，由宿主程序决定如何处理，如播放音效
+type EventItem struct {
+	Name string
+	Data *VMValue // 只由数字、字符串、数组、字典等数据组成，不含函数
+}
+
+// funcEmit 给出一个事件，如 emit('play_sound', 'crit')，按调用顺序记录在 ctx.Events 中。
+// 脚本只能声明事件，无法调用宿主程序的函数，因此是安全的
+func funcEmit(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
+	name, ok := params[0].ReadString()
+	if !ok || name == "" {
+		ctx.Error = errors.New("(emit)类型错误: 事件名必须为非空的str")
+		return nil
+	}
+	if _, err := params[1].ToGo(); err != nil {
+		ctx.Error = errors.New("(emit)类型错误: 事件数据只能由数字、字符串、数组、字典组成")
+		return nil
+	}
+	root := ctx.rootCtx()
+	root.Events = append(root.Events, EventItem{Name: name, Data: params[1].Clone()})
+	return NewNullVal()
+}
+
+// RollRecord 一次骰点的记录，包括函数和计算类型中的骰点
+type RollRecord struct {
+	Expr  string   // 骰子的原文，如 4d6k3
+	Value *VMValue // 骰点结果
+	Text  string   // 计算过程，如 {6 4 4 | 1}
+	Tag   string   // 骰子的种类，同 BufferSpan.Tag，如 dice、dice-fate
+	// 普通骰子按显示顺序计入结果和未计入结果的骰点，如 4d6k3 中取的3个和舍弃的1个。其他骰子为nil
+	Kept    []IntType
+	Dropped []IntType
+	Depth   int // 为0时是语句本身的骰点，大于0时在函数或计算类型中
+}
+
+// String 骰点的文本，如 4d6k3={6 4 4 | 1}=14
+func (r *RollRecord) String() string {
+	ret := r.Value.ToString()
+	if r.Text == "" || r.Text == ret {
+		return r.Expr + "=" + ret
+	}
+	return r.Expr + "=" + r.Text + "=" + ret
+}
+
+// recordRoll 将刚完成的骰点记录在最外层vm的 Rolls 中
+func (ctx *Context) recordRoll(span *BufferSpan) {
+	expr := span.Expr
+	if expr == "" && ctx.parser != nil && int(span.End) <= len(ctx.parser.data) && span.Begin < span.End {
+		expr = strings.TrimSpace(string(ctx.parser.data[span.Begin:span.End]))
+	}
+	r := RollRecord{Expr: expr, Value: span.Ret, Text: span.Text, Tag: span.Tag, Depth: ctx.subThreadDepth}
+	if p := span.Pool; p != nil {
+		sorted := p.Sorted()
+		r.Kept, r.Dropped = sorted[:p.Kept], sorted[p.Kept:]
+	}
+	root := ctx.rootCtx()
+	root.Rolls = append(root.Rolls, r)
+}
+
+// recordBuiltinRoll 记录内置函数中的骰点，这些骰点没有对应的原文，expr 为其写法，如 pool(3, 6)
+func (ctx *Context) recordBuiltinRoll(expr string, ret *VMValue, tag string) {
+	ctx.recordRoll(&BufferSpan{Expr: expr, Ret: ret, Tag: tag})
+}
+
+var replyPlaceholder = regexp.MustCompile(`\{([^{}]+)\}`)
+
+// funcReply 渲染回复模板，如 reply('{$角色} 攻击命中 {hit}，伤害 {dmg}')。
+// {名字} 的值优先取自vars，其次为同名变量。设置了 ReplyTemplateFunc 时交由宿主程序渲染(以便转义等处理)，否则直接替换为值的文本
+func funcReply(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
+	tmpl, ok := params[0].ReadString()
+	if !ok {
+		ctx.Error = errors.New("(reply)类型错误: 模板必须为str")
+		return nil
+	}
+	var given *DictData
+	if !params[1].IsNullish() {
+		if given, ok = params[1].ReadDictData(); !ok {
+			ctx.Error = errors.New("(reply)类型错误: 变量表必须为dict")
+			return nil
+		}
+	}
+
+	vars := map[string]*VMValue{}
+	for _, m := range replyPlaceholder.FindAllStringSubmatch(tmpl, -1) {
+		name := strings.TrimSpace(m[1])
+		if _, exists := vars[name]; exists {
+			continue
+		}
+		var v *VMValue
+		if given != nil {
+			v, _ = given.Dict.Load(name)
+		}
+		if v == nil {
+			v = ctx.LoadName(name, false, true)
+			if ctx.Error != nil {
+				ctx.Error = errors.New("(reply)" + ctx.Error.Error())
+				return nil
+			}
+		}
+		vars[name] = v
+	}
+
+	if ctx.Config.ReplyTemplateFunc != nil {
+		text, err := ctx.Config.ReplyTemplateFunc(ctx, tmpl, vars)
+		if err != nil {
+			ctx.Error = errors.New("(reply)" + err.Error())
+			return nil
+		}
+		ctx.streamOutput(text)
+		return NewStrVal(text)
+	}
+	text := replyPlaceholder.ReplaceAllStringFunc(tmpl, func(s string) string {
+		return ctx.ToString(vars[strings.TrimSpace(s[1:len(s)-1])])
+	})
+	ctx.streamOutput(text)
+	return NewStrVal(text)
+}
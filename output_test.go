@@ -0,0 +1,200 @@
+package dicescript
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOutput(t *testing.T) {
+	vm := NewVM()
+	vm.Config.DiceMaxMode = true
+	r, err := vm.Evaluate("output('命中', d20+5); output('伤害', 2d6+3); '攻击'")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(r.Value, ns("攻击")))
+		if assert.Len(t, r.Outputs, 2) {
+			assert.Equal(t, "命中", r.Outputs[0].Label)
+			assert.True(t, valueEqual(r.Outputs[0].Value, ni(25)))
+			assert.Equal(t, "伤害", r.Outputs[1].Label)
+			assert.True(t, valueEqual(r.Outputs[1].Value, ni(15)))
+		}
+	}
+
+	// 函数中给出的结果同样记录，返回值为value本身
+	vm = NewVM()
+	err = vm.Run("func f(x) { output('x', x) }; f(1) + f(2)")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, ni(3)))
+		assert.Len(t, vm.Outputs, 2)
+	}
+
+	// 每次执行前清空
+	assert.NoError(t, vm.Run("1"))
+	assert.Len(t, vm.Outputs, 0)
+
+	assert.Error(t, vm.Run("output(1, 2)"))
+}
+
+func TestEmit(t *testing.T) {
+	vm := NewVM()
+	vm.Config.DiceMaxMode = true
+	r, err := vm.Evaluate("x = d20; x == 20 ? emit('play_sound', 'crit') : 0; emit('hp', {'delta': -3}); emit('done'); x")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(r.Value, ni(20)))
+		if assert.Len(t, r.Events, 3) {
+			assert.Equal(t, "play_sound", r.Events[0].Name)
+			assert.True(t, valueEqual(r.Events[0].Data, ns("crit")))
+			assert.Equal(t, "hp", r.Events[1].Name)
+			data, _ := r.Events[1].Data.ToGo()
+			assert.Equal(t, map[string]any{"delta": int64(-3)}, data)
+			assert.True(t, r.Events[2].Data.IsNullish())
+		}
+	}
+
+	// 函数中给出的事件同样记录，每次执行前清空
+	assert.NoError(t, vm.Run("func f() { emit('a') }; f(); f()"))
+	assert.Len(t, vm.Events, 2)
+	assert.NoError(t, vm.Run("1"))
+	assert.Len(t, vm.Events, 0)
+
+	assert.Error(t, vm.Run("emit(1)"))
+	assert.Error(t, vm.Run("emit('')"))
+	assert.Error(t, vm.Run("func f() {}; emit('a', [f])"))
+}
+
+func TestRolls(t *testing.T) {
+	vm := NewVM()
+	vm.Config.DiceMaxMode = true
+	r, err := vm.Evaluate("d20 + 5 + 4d6k3")
+	if assert.NoError(t, err) {
+		if assert.Len(t, r.Rolls, 2) {
+			assert.Equal(t, "d20=20", r.Rolls[0].String())
+			assert.Equal(t, "4d6k3", r.Rolls[1].Expr)
+			assert.True(t, valueEqual(r.Rolls[1].Value, ni(18)))
+			assert.Equal(t, []IntType{6, 6, 6}, r.Rolls[1].Kept)
+			assert.Equal(t, []IntType{6}, r.Rolls[1].Dropped)
+			assert.Equal(t, "dice", r.Rolls[1].Tag)
+			assert.Equal(t, 0, r.Rolls[1].Depth)
+		}
+	}
+
+	// 函数与计算类型中的骰点同样记录，每次执行前清空
+	vm.Config.EnableDiceFate = true
+	assert.NoError(t, vm.Run("func g() { return 2d6 }; &a = d4 + 1; g() + a + df"))
+	if assert.Len(t, vm.Rolls, 3) {
+		assert.Equal(t, "2d6=6+6=12", vm.Rolls[0].String())
+		assert.Equal(t, 1, vm.Rolls[0].Depth)
+		assert.Equal(t, "d4=4", vm.Rolls[1].String())
+		assert.Equal(t, 1, vm.Rolls[1].Depth)
+		assert.Equal(t, "dice-fate", vm.Rolls[2].Tag)
+		assert.Nil(t, vm.Rolls[2].Kept)
+	}
+	assert.NoError(t, vm.Run("1"))
+	assert.Len(t, vm.Rolls, 0)
+
+	// 内置函数中的骰点同样记录
+	assert.NoError(t, vm.Run("pool(2, 6).len() + d6"))
+	if assert.Len(t, vm.Rolls, 2) {
+		assert.Equal(t, "pool(2, 6)=[6, 6]", vm.Rolls[0].String())
+		assert.Equal(t, "dice-pool", vm.Rolls[0].Tag)
+		assert.Equal(t, "d6=6", vm.Rolls[1].String())
+	}
+	assert.NoError(t, vm.Run("init_roll(['a', 'b'], 10)"))
+	if assert.Len(t, vm.Rolls, 2) {
+		assert.Equal(t, "d10", vm.Rolls[1].Expr)
+	}
+}
+
+func TestReply(t *testing.T) {
+	vm := NewVM()
+	err := vm.Run("hit = 15; dmg = 8; reply('{角色} 攻击命中 {hit}，伤害 {dmg}', {'角色': '阿尔'})")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, ns("阿尔 攻击命中 15，伤害 8")))
+	}
+
+	// 交由宿主程序渲染
+	vm = NewVM()
+	var got map[string]*VMValue
+	vm.Config.ReplyTemplateFunc = func(ctx *Context, tmpl string, vars map[string]*VMValue) (string, error) {
+		got = vars
+		return "<" + vars["hit"].ToString() + ">", nil
+	}
+	err = vm.Run("hit = 15; reply('命中 {hit} {hit}')")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, ns("<15>")))
+		assert.Len(t, got, 1)
+	}
+
+	vm = NewVM()
+	called := false
+	vm.Config.ReplyTemplateFunc = func(ctx *Context, tmpl string, vars map[string]*VMValue) (string, error) {
+		called = true
+		return "", errors.New("模板错误")
+	}
+	err = vm.Run("a = 1; reply('{a}')")
+	if assert.Error(t, err) {
+		assert.True(t, called)
+		assert.Contains(t, err.Error(), "模板错误")
+	}
+	assert.Error(t, NewVM().Run("reply(1)"))
+}
+
+func TestOnOutput(t *testing.T) {
+	var parts []string
+	var retWhenCalled []bool
+	vm := NewVM()
+	vm.Config.DiceMaxMode = true
+	vm.OnOutput = func(part string) {
+		parts = append(parts, part)
+		retWhenCalled = append(retWhenCalled, vm.Ret != nil)
+	}
+	err := vm.Run("hp = 10; output('命中', d20+5); func f() { reply('剩余 {hp}') }; f(); hp = 3; reply('剩余 {hp}'); 'end'")
+	if assert.NoError(t, err) {
+		assert.Equal(t, []string{"命中: 25", "剩余 10", "剩余 3"}, parts)
+		// 执行中逐段给出，而不是执行完毕后
+		assert.Equal(t, []bool{false, false, false}, retWhenCalled)
+	}
+
+	// 出错前已经给出的部分不会撤回
+	parts = nil
+	vm.Ret = nil
+	err = vm.Run("output('a', 1); 1 + 'x'")
+	assert.Error(t, err)
+	assert.Equal(t, []string{"a: 1"}, parts)
+}
+
+func TestContextStringer(t *testing.T) {
+	var parts []string
+	vm := NewVM()
+	vm.Config.DiceMaxMode = true
+	vm.Stringer = ValueStringerFunc(func(v *VMValue) (string, bool) {
+		switch v.TypeId {
+		case VMTypeBool:
+			if v.AsBool() {
+				return "成功", true
+			}
+			return "失败", true
+		case VMTypeFloat:
+			return strings.Replace(v.ToString(), ".", ",", 1), true
+		}
+		return "", false
+	})
+	vm.OnOutput = func(part string) {
+		parts = append(parts, part)
+	}
+	err := vm.Run("output('检定', d20 >= 10); `{1.5} {[d1 > 1]} {'a'}` + join([2.5, 3], ' ')")
+	if assert.NoError(t, err) {
+		assert.Equal(t, []string{"检定: 成功"}, parts)
+		assert.True(t, valueEqual(vm.Ret, ns("1,5 [失败] a2,5 3")))
+	}
+
+	// 计算过程中同样使用
+	err = vm.Run("x = 0.5; d4 + x")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "x = 0.5; 4[d4] + 0,5[x]", vm.GetDetailText())
+		// ToString本身不受影响
+		assert.Equal(t, "4.5", vm.Ret.ToString())
+	}
+}
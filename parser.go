@@ -2,7 +2,12 @@ package dicescript
 
 import (
 	"errors"
+	"fmt"
 	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
 )
 
 type ParserData struct {
@@ -19,13 +24,91 @@ type ParserData struct {
 	loopInfo      []struct {
 		continueIndex int
 		breakIndex    int
+		blockDepth    int
 	}
-	loopLayer int // 当前loop层数
-	codeStack []struct {
+	matchStack []*matchInfo
+	loopLayer  int // 当前loop层数
+	blockDepth int // 当前语句块层数，break/continue时需要退出loop内层的语句块
+	codeStack  []struct {
 		code    []ByteCode
 		index   int
 		textPos int
 	}
+
+	program ProgramInfo
+
+	numberErr *NumberLiteralError // 第一个超出限制的数字字面量，解析结束后作为错误返回
+}
+
+// MaxNumberLiteralDigits 数字字面量最多的位数(含小数点和指数，不含分隔符_)，过长的数字会使解析和输出变慢
+const MaxNumberLiteralDigits = 64
+
+// NumberLiteralError 数字字面量超出限制，如位数过多、超出整数范围
+type NumberLiteralError struct {
+	Text   string // 字面量原文，过长时只保留开头
+	Reason string
+}
+
+func (e *NumberLiteralError) Error() string {
+	return fmt.Sprintf("数字 %s %s", e.Text, e.Reason)
+}
+
+func (e *ParserData) numberLiteralErr(text string, reason string) {
+	if e.numberErr != nil {
+		return
+	}
+	if len(text) > 20 {
+		text = text[:20] + "..."
+	}
+	e.numberErr = &NumberLiteralError{Text: text, Reason: reason}
+}
+
+// parseIntLiteral 解析整数字面量，超出限制时记录错误并得到0
+func (e *ParserData) parseIntLiteral(text string) IntType {
+	digits := strings.ReplaceAll(text, "_", "")
+	base := 10
+	if len(digits) > 2 && digits[0] == '0' && (digits[1] == 'x' || digits[1] == 'X') {
+		digits, base = digits[2:], 16
+	}
+	if len(digits) > MaxNumberLiteralDigits {
+		e.numberLiteralErr(text, fmt.Sprintf("超过%d位", MaxNumberLiteralDigits))
+		return 0
+	}
+	val, err := strconv.ParseInt(digits, base, IntTypeSize*8)
+	if err != nil {
+		e.numberLiteralErr(text, "超出整数范围")
+		return 0
+	}
+	return IntType(val)
+}
+
+// parseFloatLiteral 解析小数字面量，超出限制时记录错误并得到0
+func (e *ParserData) parseFloatLiteral(text string) float64 {
+	digits := strings.ReplaceAll(text, "_", "")
+	if len(digits) > MaxNumberLiteralDigits {
+		e.numberLiteralErr(text, fmt.Sprintf("超过%d位", MaxNumberLiteralDigits))
+		return 0
+	}
+	val, err := strconv.ParseFloat(digits, 64)
+	if err != nil {
+		e.numberLiteralErr(text, "超出浮点数范围")
+		return 0
+	}
+	return val
+}
+
+// ProgramInfo 生成字节码时收集的程序摘要，供 RollConfig.CompilePolicy 检查。
+// 这不是语法树: 只记录字节码中出现的变量名、赋值、循环等，不保留表达式的结构与位置
+type ProgramInfo struct {
+	Names     []string  // 读取的变量名，包括函数名，如 ceil(1.5) 中的 ceil
+	Stores    []string  // 赋值或删除的变量名
+	HasAssign bool      // 存在任意形式的赋值，包括属性和下标赋值
+	HasLoop   bool      // 存在循环
+	HasFunc   bool      // 存在函数定义
+	DiceSides []IntType // 骰子面数，只记录字面量，如 d1000 记为 1000
+
+	code             []ByteCode
+	defaultDiceCount IntType // 省略个数的骰子的个数，来自 RollConfig.DefaultDiceCount
 }
 
 type BufferSpan struct {
@@ -38,14 +121,18 @@ type BufferSpan struct {
 	Tag        string // 来源标记
 	TextOnly   bool   // 不显示expr，只显示text
 	ExprSuffix string // expr后缀，如不写为=，即 [力量=10] 这种格式
+
+	Pool *DicePool // 普通骰子的骰池，记录骰出的顺序
 }
 
 func (e *ParserData) LoopBegin() {
+	e.program.HasLoop = true
 	e.loopLayer += 1
 	e.loopInfo = append(e.loopInfo, struct {
 		continueIndex int
 		breakIndex    int
-	}{continueIndex: len(e.continueStack), breakIndex: len(e.breakStack)})
+		blockDepth    int
+	}{continueIndex: len(e.continueStack), breakIndex: len(e.breakStack), blockDepth: e.blockDepth})
 }
 
 func (e *ParserData) LoopEnd() {
@@ -80,6 +167,27 @@ func (e *ParserData) WriteCode(T CodeType, value any) {
 	c.T = T
 	c.Value = value
 	e.codeIndex += 1
+
+	switch T {
+	case typeLoadName, typeLoadNameWithDetail, typeLoadNameRaw:
+		e.program.Names = append(e.program.Names, value.(string))
+	case typeStoreName, typeStoreNameGlobal, typeStoreNameLocal, typeStoreNameConst, typeDeleteName, typeIterNext:
+		e.program.Stores = append(e.program.Stores, value.(string))
+		e.program.HasAssign = true
+	case typeAttrSet, typeItemSet, typeSliceSet:
+		e.program.HasAssign = true
+	case typeBlockPush:
+		e.blockDepth += 1
+	case typeBlockPop:
+		e.blockDepth -= 1
+	}
+}
+
+// AddDiceSides 记录字面量骰子面数，不产生字节码
+func (e *ParserData) AddDiceSides(text string) {
+	if v, err := strconv.ParseInt(text, 10, 64); err == nil {
+		e.program.DiceSides = append(e.program.DiceSides, IntType(v))
+	}
 }
 
 func (p *ParserData) AddDiceDetail(begin IntType, end IntType) {
@@ -99,8 +207,7 @@ func (e *ParserData) AddLoadName(value string) {
 }
 
 func (e *ParserData) PushIntNumber(value string) {
-	val, _ := strconv.ParseInt(value, 10, 64)
-	e.WriteCode(typePushIntNumber, IntType(val))
+	e.WriteCode(typePushIntNumber, e.parseIntLiteral(value))
 }
 
 func (e *ParserData) PushStr(value string) {
@@ -111,6 +218,18 @@ func (e *ParserData) PushArray(value IntType) {
 	e.WriteCode(typePushArray, value)
 }
 
+func (e *ParserData) PushTable(value IntType) {
+	e.WriteCode(typePushTable, value)
+}
+
+func (e *ParserData) PushTuple(value IntType) {
+	e.WriteCode(typePushTuple, value)
+}
+
+func (e *ParserData) PushSet(value IntType) {
+	e.WriteCode(typePushSet, value)
+}
+
 func (e *ParserData) PushDict(value IntType) {
 	e.WriteCode(typePushDict, value)
 }
@@ -133,8 +252,104 @@ func (e *ParserData) AddFormatString(num IntType) {
 }
 
 func (e *ParserData) PushFloatNumber(value string) {
-	val, _ := strconv.ParseFloat(value, 64)
-	e.WriteCode(typePushFloatNumber, float64(val))
+	e.WriteCode(typePushFloatNumber, e.parseFloatLiteral(value))
+}
+
+func (e *ParserData) PushBool(value bool) {
+	e.WriteCode(typePushBool, value)
+}
+
+func (e *ParserData) PushDuration(value string) {
+	val, _ := time.ParseDuration(value)
+	e.WriteCode(typePushDuration, val)
+}
+
+// IsUnit 是否为注册过的单位
+func (e *ParserData) IsUnit(name string) bool {
+	_, ok := e.Config.Units.Get(name)
+	return ok
+}
+
+// IsQuantityAhead 接下来的输入是否为带单位的数，即数字后紧跟一个注册过的单位。
+// 单位与时长的单位(h m s ms)同名时，注册过的单位优先: 注册了m作为米后 3m 为3米，
+// 1h30m 这样多段的时长不受影响(其后还有数字，不能作为带单位的数)，单独的时长可以写作 toDuration('3m')
+func (d *ParserCustomData) IsQuantityAhead(p *parser) bool {
+	if d.Config.Units == nil {
+		return false
+	}
+	data := p.data[p.pt.offset:]
+	i := 0
+	for i < len(data) && (data[i] >= '0' && data[i] <= '9' || data[i] == '.') {
+		i++
+	}
+	j := i
+	for j < len(data) {
+		r, size := utf8.DecodeRune(data[j:])
+		if !unicode.IsLetter(r) {
+			break
+		}
+		j += size
+	}
+	return i > 0 && j > i && d.IsUnit(string(data[i:j]))
+}
+
+// PushPercent 百分数默认转为小数，开启PercentAsInt时为整数
+func (e *ParserData) PushPercent(text string) {
+	text = text[:len(text)-1]
+	if e.Config.PercentAsInt {
+		if strings.Contains(text, ".") {
+			e.PushFloatNumber(text)
+		} else {
+			e.PushIntNumber(text)
+		}
+		return
+	}
+	val := e.parseFloatLiteral(text)
+	e.WriteCode(typePushFloatNumber, val/100)
+}
+
+// PushRollMode max(...) 中的骰子以最大值结算，min(...) 以最小值结算
+func (e *ParserData) PushRollMode(mode string) {
+	if mode == "max" {
+		e.WriteCode(typeRollModePush, IntType(1))
+	} else {
+		e.WriteCode(typeRollModePush, IntType(-1))
+	}
+}
+
+// ImplicitMultiply 兼容模式下，数字或右括号后紧跟的括号视为乘法，如 3(1d6)。中间有空白时不算
+func (d *ParserCustomData) ImplicitMultiply(p *parser) bool {
+	if !d.Config.Compat.ImplicitMultiply || p.pt.offset == 0 {
+		return false
+	}
+	prev := p.data[p.pt.offset-1]
+	return (prev >= '0' && prev <= '9') || prev == ')'
+}
+
+// IsMoneyAhead 接下来的输入是否为金额，如 3gp5sp
+func (d *ParserCustomData) IsMoneyAhead(p *parser) bool {
+	data := p.data[p.pt.offset:]
+	end := 0
+	for end < len(data) {
+		r, size := utf8.DecodeRune(data[end:])
+		if !(unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			break
+		}
+		end += size
+	}
+	_, ok := d.Config.currency().parse(string(data[:end]))
+	return ok
+}
+
+func (e *ParserData) PushMoney(text string) {
+	cs := e.Config.currency()
+	amount, _ := cs.parse(text)
+	e.WriteCode(typePushMoney, MoneyData{Amount: amount, System: cs})
+}
+
+func (e *ParserData) PushQuantity(value string, unit string) {
+	val := e.parseFloatLiteral(value)
+	e.WriteCode(typePushQuantity, QuantityData{Value: val, Unit: unit})
 }
 
 func (e *ParserData) AddStName() {
@@ -154,6 +369,32 @@ func (e *ParserData) AddStore(text string) {
 	e.WriteCode(typeStoreName, text)
 }
 
+// AddStoreCompound 复合赋值 x += v，此前已载入x并算出v
+func (e *ParserData) AddStoreCompound(name string, op string) {
+	switch op {
+	case "+":
+		e.AddOp(typeAdd)
+	case "-":
+		e.AddOp(typeSubtract)
+	case "*":
+		e.AddOp(typeMultiply)
+	case "/":
+		e.AddOp(typeDivide)
+	case "%":
+		e.AddOp(typeModulus)
+	}
+	e.WriteCode(typeStoreName, name)
+}
+
+// AddStoreUnpack 解构赋值 (a, b) = x，依次赋值后栈上保留x
+func (e *ParserData) AddStoreUnpack(names []string) {
+	e.WriteCode(typeUnpack, IntType(len(names)))
+	for _, name := range names {
+		e.WriteCode(typeStoreName, name)
+		e.AddOp(typePop)
+	}
+}
+
 func (e *ParserData) AddStoreGlobal(text string) {
 	e.WriteCode(typeStoreNameGlobal, text)
 }
@@ -162,6 +403,14 @@ func (e *ParserData) AddStoreLocal(text string) {
 	e.WriteCode(typeStoreNameLocal, text)
 }
 
+func (e *ParserData) AddStoreConst(text string) {
+	e.WriteCode(typeStoreNameConst, text)
+}
+
+func (e *ParserData) AddDelete(text string) {
+	e.WriteCode(typeDeleteName, text)
+}
+
 func (e *ParserData) NamePush(test string) {
 	e.varnameStack = append(e.varnameStack, test)
 }
@@ -182,6 +431,7 @@ func (p *ParserData) ContinuePush() error {
 		if p.continueStack == nil {
 			p.continueStack = []IntType{}
 		}
+		p.addBlockUnwind()
 		p.AddOp(typeJmp)
 		p.continueStack = append(p.continueStack, IntType(p.codeIndex)-1)
 	} else {
@@ -190,6 +440,14 @@ func (p *ParserData) ContinuePush() error {
 	return nil
 }
 
+// addBlockUnwind 跳出循环内层的语句块(如if)，否则这些语句块不会被弹出
+func (p *ParserData) addBlockUnwind() {
+	info := p.loopInfo[len(p.loopInfo)-1]
+	if n := p.blockDepth - info.blockDepth; n > 0 {
+		p.WriteCode(typeBlockUnwind, IntType(n))
+	}
+}
+
 func (p *ParserData) ContinueSet(offsetB int) {
 	if p.continueStack != nil {
 		info := p.loopInfo[len(p.loopInfo)-1]
@@ -216,6 +474,7 @@ func (p *ParserData) BreakPush() error {
 		if p.breakStack == nil {
 			p.breakStack = []IntType{}
 		}
+		p.addBlockUnwind()
 		p.AddOp(typeJmp)
 		p.breakStack = append(p.breakStack, IntType(p.codeIndex)-1)
 		return nil
@@ -251,6 +510,157 @@ func (e *ParserData) OffsetJmpSetX(offsetA int, offsetB int, rev bool) {
 	}
 }
 
+// matchInfo 正在解析的match表达式
+type matchInfo struct {
+	table    int // 开头的nop，分支密集时替换为跳转表
+	patStart int // 当前分支的模式代码的起始位置
+	jne      int // 当前分支匹配失败时的跳转，_ 分支为-1
+	arms     []matchArm
+	hasElse  bool  // 已出现 _ 分支，其后的分支不会被匹配到
+	elseBody int   // _ 分支的起始位置
+	ends     []int // 各分支结尾跳出match的jmp
+}
+
+// matchArm 出现在 _ 之前的分支，模式为整数常量时记录其范围
+type matchArm struct {
+	lo, hi  IntType
+	isConst bool
+	body    int // 分支代码的起始位置(弹出被匹配的值的pop)
+}
+
+// matchTableMaxSize 跳转表的最大长度
+const matchTableMaxSize = 256
+
+func (e *ParserData) MatchBegin() {
+	e.AddOp(typeNop)
+	e.matchStack = append(e.matchStack, &matchInfo{table: e.codeIndex - 1})
+}
+
+func (e *ParserData) MatchPatternBegin() {
+	e.matchStack[len(e.matchStack)-1].patStart = e.codeIndex
+}
+
+// MatchArmBody 模式之后、分支代码之前调用，isElse为 _ 分支
+func (e *ParserData) MatchArmBody(isElse bool) {
+	m := e.matchStack[len(e.matchStack)-1]
+	arm := matchArm{}
+	m.jne = -1
+	if !isElse {
+		// 最后一条是 match.value 或 match.range
+		arm.lo, arm.hi, arm.isConst = readConstPattern(e.code[m.patStart:e.codeIndex])
+		e.AddOp(typeJne)
+		m.jne = e.codeIndex - 1
+	}
+	arm.body = e.codeIndex
+	e.AddOp(typePop)
+	switch {
+	case m.hasElse:
+	case isElse:
+		m.hasElse = true
+		m.elseBody = arm.body
+	default:
+		m.arms = append(m.arms, arm)
+	}
+}
+
+func (e *ParserData) MatchArmEnd() {
+	m := e.matchStack[len(e.matchStack)-1]
+	e.AddOp(typeJmp)
+	m.ends = append(m.ends, e.codeIndex-1)
+	if m.jne >= 0 {
+		e.code[m.jne].Value = IntType(e.codeIndex - m.jne - 1)
+	}
+}
+
+// MatchEnd 没有 _ 分支时结果为null。分支都是整数常量且足够密集时，将开头的nop替换为跳转表:
+// [最小值, 默认分支, 各值对应的分支...]，后两者为相对于跳转表的偏移
+func (e *ParserData) MatchEnd() {
+	m := e.matchStack[len(e.matchStack)-1]
+	e.matchStack = e.matchStack[:len(e.matchStack)-1]
+	if !m.hasElse {
+		m.elseBody = e.codeIndex
+		e.AddOp(typePop)
+		e.PushNull()
+	}
+	for _, idx := range m.ends {
+		e.code[idx].Value = IntType(e.codeIndex - idx - 1)
+	}
+
+	if len(m.arms) < 2 {
+		return
+	}
+	lo, hi := m.arms[0].lo, m.arms[0].hi
+	for _, arm := range m.arms {
+		if !arm.isConst {
+			return
+		}
+		if arm.lo < lo {
+			lo = arm.lo
+		}
+		if arm.hi > hi {
+			hi = arm.hi
+		}
+	}
+	if hi-lo < 0 || hi-lo >= matchTableMaxSize {
+		return
+	}
+	size := int(hi-lo) + 1
+	offset := func(target int) IntType {
+		return IntType(target - m.table - 1)
+	}
+	table := make([]IntType, size+2)
+	table[0], table[1] = lo, offset(m.elseBody)
+	filled := 0
+	for _, arm := range m.arms {
+		// 以表中的下标计数，arm.hi 为整数上限时 v <= arm.hi 恒成立
+		for i := arm.lo - lo + 2; i <= arm.hi-lo+2; i++ {
+			// 分支的偏移总是大于0，0为空位；重叠时前面的分支优先
+			if table[i] == 0 {
+				table[i] = offset(arm.body)
+				filled++
+			}
+		}
+	}
+	if filled*2 < size {
+		return
+	}
+	for i := 2; i < len(table); i++ {
+		if table[i] == 0 {
+			table[i] = table[1]
+		}
+	}
+	e.code[m.table] = ByteCode{T: typeJmpTable, Value: table}
+}
+
+// readConstPattern 模式为整数常量或整数常量的范围时，返回其范围
+func readConstPattern(code []ByteCode) (IntType, IntType, bool) {
+	if len(code) == 0 {
+		return 0, 0, false
+	}
+	last := code[len(code)-1].T
+	lo, n := readConstInt(code)
+	if n == 0 {
+		return 0, 0, false
+	}
+	if last == typeMatchValue {
+		return lo, lo, n == len(code)-1
+	}
+	hi, m := readConstInt(code[n:])
+	return lo, hi, m > 0 && n+m == len(code)-1
+}
+
+// readConstInt 开头的整数常量(可以带负号)，返回其值和占用的指令数，不是常数时为0
+func readConstInt(code []ByteCode) (IntType, int) {
+	if len(code) == 0 || code[0].T != typePushIntNumber {
+		return 0, 0
+	}
+	v := code[0].Value.(IntType)
+	if len(code) > 1 && code[1].T == typeNegation {
+		return -v, 2
+	}
+	return v, 1
+}
+
 func (e *ParserData) CounterPush() {
 	e.counterStack = append(e.counterStack, 0)
 }
@@ -314,6 +724,26 @@ func (p *ParserData) AddStoreComputed(name string, text string) {
 	p.WriteCode(typeStoreName, name)
 }
 
+func (p *ParserData) AddStoreComputedWithParams(name string, paramsReversed []string, text string) {
+	code, length, offset := p.CodePop()
+	fixCodeByOffset(code, offset)
+
+	// 翻转一次
+	for i, j := 0, len(paramsReversed)-1; i < j; i, j = i+1, j-1 {
+		paramsReversed[i], paramsReversed[j] = paramsReversed[j], paramsReversed[i]
+	}
+
+	val := NewComputedValRaw(&ComputedData{
+		Expr:      text,
+		Params:    paramsReversed,
+		code:      code,
+		codeIndex: length,
+	})
+
+	p.WriteCode(typePushComputed, val)
+	p.WriteCode(typeStoreName, name)
+}
+
 func (p *ParserData) AddStoreComputedOnStack(text string) {
 	code, length, offset := p.CodePop()
 	fixCodeByOffset(code, offset)
@@ -327,6 +757,7 @@ func (p *ParserData) AddStoreComputedOnStack(text string) {
 }
 
 func (p *ParserData) AddStoreFunction(name string, paramsReversed []string, text string) {
+	p.program.HasFunc = true
 	code, length, offset := p.CodePop()
 	fixCodeByOffset(code, offset)
 
@@ -341,6 +772,8 @@ func (p *ParserData) AddStoreFunction(name string, paramsReversed []string, text
 		Params:    paramsReversed,
 		code:      code,
 		codeIndex: length,
+
+		isGenerator: containsYield(code[:length]),
 	})
 
 	p.WriteCode(typePushFunction, val)
@@ -358,6 +791,15 @@ func (p *ParserData) AddAttrSet(objName string, attr string, isRaw bool) {
 	p.WriteCode(typeAttrSet, attr)
 }
 
+// AddAttrSetPath 多级属性赋值，如 a.b.c = 1，中间的属性使用 attr.get.path 读取
+func (p *ParserData) AddAttrSetPath(objName string, path []string) {
+	p.WriteCode(typeLoadName, objName)
+	for _, i := range path[:len(path)-1] {
+		p.WriteCode(typeAttrGetPath, i)
+	}
+	p.WriteCode(typeAttrSet, path[len(path)-1])
+}
+
 func (p *ParserData) CodePush(textPos int) {
 	p.codeStack = append(p.codeStack, struct {
 		code    []ByteCode
@@ -196,7 +196,7 @@ func isValidStartChar(r rune) bool {
 		return true
 	}
 	switch r {
-	case '_', '$', '(', '[', '{', '"', '\'', '`', '\x1e', '+', '-', '.', '&':
+	case '_', '$', '(', '[', '{', '"', '\'', '`', '\x1e', '+', '-', '.', '&', '!':
 		return true
 	case '优', '劣', '（', '【': // 中文字符
 		return true
@@ -212,3 +212,26 @@ func TestGetPrevNonSpaceChar(t *testing.T) {
 		}
 	}
 }
+
+func TestNumberLiteralLimits(t *testing.T) {
+	for _, expr := range []string{
+		"99999999999999999999",
+		"1d99999999999999999999",
+		"1 + " + strings.Repeat("1", 70) + ".5",
+		"3 + " + strings.Repeat("9", 500),
+		"1e400",
+		"0x8000000000000000",
+		"1_" + strings.Repeat("0", 70),
+	} {
+		vm := NewVM()
+		err := vm.Run(expr)
+		var numErr *NumberLiteralError
+		if assert.ErrorAs(t, err, &numErr, expr) {
+			assert.LessOrEqual(t, len(numErr.Text), 23)
+		}
+	}
+
+	vm := NewVM()
+	err := vm.Run("9223372036854775807 + 0.5 * 2")
+	assert.NoError(t, err)
+}
@@ -0,0 +1,264 @@
+package dicescript
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Profiler 性能分析，按调用栈记录各函数和各指令的执行次数与耗时，指令同时记录所在表达式在源码中的位置，
+// 用于找出宏中较慢的部分，见 WithProfiler。
+// 可以累计多次执行的结果，但不能同时用于多个正在执行的vm
+type Profiler struct {
+	frames []profileFrame
+	stack  string       // 当前的调用栈，以;连接，如 main;攻击;floor
+	cur    *ProfileStat // 正在计时的项
+	last   time.Time    // 上次计时的时刻
+
+	opNames map[CodeType]string
+	spans   map[*ByteCode][]profileSpan // 各段字节码中每条指令所在的表达式
+	samples map[profileKey]*ProfileStat
+	funcs   map[string]*ProfileStat
+}
+
+// ProfileStat 执行次数与总耗时
+type ProfileStat struct {
+	Count int64
+	Time  time.Duration
+}
+
+// ProfileSample 按调用栈、指令和源码位置汇总的执行次数与耗时
+type ProfileSample struct {
+	Stack string // 调用栈，如 main;攻击
+	Op    string // 指令名，为空时是函数自身的耗时，如内置函数的执行、参数的设置
+	// 指令所在表达式在源码中的位置，函数和computed中从其表达式的开头算起。
+	// 只有骰子、读取变量等记录计算过程的表达式有位置，其他指令均为0
+	Begin IntType
+	End   IntType
+	ProfileStat
+}
+
+type profileKey struct {
+	stack string
+	op    CodeType
+	isOp  bool // 为false时是函数自身的耗时，如内置函数的执行、参数的设置
+	span  profileSpan
+}
+
+type profileSpan struct {
+	begin IntType
+	end   IntType
+}
+
+type profileFrame struct {
+	name  string
+	stack string       // 进入前的调用栈
+	cur   *ProfileStat // 进入前正在计时的项
+	start time.Time
+}
+
+func NewProfiler() *Profiler {
+	p := &Profiler{}
+	p.Reset()
+	return p
+}
+
+// Reset 清空已记录的结果
+func (p *Profiler) Reset() {
+	p.frames = nil
+	p.stack = ""
+	p.cur = nil
+	p.opNames = map[CodeType]string{}
+	p.spans = map[*ByteCode][]profileSpan{}
+	p.samples = map[profileKey]*ProfileStat{}
+	p.funcs = map[string]*ProfileStat{}
+}
+
+func (p *Profiler) sample(key profileKey) *ProfileStat {
+	s := p.samples[key]
+	if s == nil {
+		s = &ProfileStat{}
+		p.samples[key] = s
+	}
+	return s
+}
+
+// charge 将上次计时以来的时间计入正在计时的项
+func (p *Profiler) charge(now time.Time) {
+	if p.cur != nil {
+		p.cur.Time += now.Sub(p.last)
+	}
+	p.last = now
+}
+
+// codeSpans 每条指令所在的表达式，没有时为零值。计算过程的记录紧挨在产生该值的指令之前，如骰子、读取变量
+func codeSpans(code []ByteCode) []profileSpan {
+	ret := make([]profileSpan, len(code))
+	for m, c := range code {
+		if c.T != typeDetailMark {
+			continue
+		}
+		span := c.Value.(BufferSpan)
+		ret[m] = profileSpan{begin: span.Begin, end: span.End}
+		if m+1 < len(code) {
+			ret[m+1] = ret[m]
+		}
+	}
+	return ret
+}
+
+// step 开始执行 code 中的第 i 条指令
+func (p *Profiler) step(code []ByteCode, i int) {
+	p.charge(time.Now())
+	spans, ok := p.spans[&code[0]]
+	if !ok {
+		spans = codeSpans(code)
+		p.spans[&code[0]] = spans
+	}
+	c := &code[i]
+	if _, ok := p.opNames[c.T]; !ok {
+		// 只取指令名，不含参数
+		name := c.CodeString()
+		if i := strings.IndexByte(name, ' '); i >= 0 {
+			name = name[:i]
+		}
+		p.opNames[c.T] = name
+	}
+	p.cur = p.sample(profileKey{stack: p.stack, op: c.T, isOp: true, span: spans[i]})
+	p.cur.Count++
+}
+
+// enter 进入函数，此后的耗时计入该函数
+func (p *Profiler) enter(name string) {
+	now := time.Now()
+	p.charge(now)
+	p.frames = append(p.frames, profileFrame{name: name, stack: p.stack, cur: p.cur, start: now})
+	// ; 是调用栈的分隔符
+	name = strings.ReplaceAll(name, ";", ",")
+	if p.stack == "" {
+		p.stack = name
+	} else {
+		p.stack += ";" + name
+	}
+	p.cur = p.sample(profileKey{stack: p.stack})
+}
+
+// exit 离开最近进入的函数
+func (p *Profiler) exit() {
+	now := time.Now()
+	p.charge(now)
+	f := p.frames[len(p.frames)-1]
+	p.frames = p.frames[:len(p.frames)-1]
+	p.stack, p.cur = f.stack, f.cur
+
+	st := p.funcs[f.name]
+	if st == nil {
+		st = &ProfileStat{}
+		p.funcs[f.name] = st
+	}
+	st.Count++
+	// 递归调用时只在最外层计入耗时，避免重复计算
+	for _, i := range p.frames {
+		if i.name == f.name {
+			return
+		}
+	}
+	st.Time += now.Sub(f.start)
+}
+
+// Functions 各函数的调用次数和总耗时(包括其中调用的其他函数)。最外层的执行记为 main
+func (p *Profiler) Functions() map[string]ProfileStat {
+	ret := map[string]ProfileStat{}
+	for name, st := range p.funcs {
+		ret[name] = *st
+	}
+	return ret
+}
+
+// Ops 各指令的执行次数和耗时，键为指令名，如 dice、call
+func (p *Profiler) Ops() map[string]ProfileStat {
+	ret := map[string]ProfileStat{}
+	for key, st := range p.samples {
+		if !key.isOp {
+			continue
+		}
+		name := p.opNames[key.op]
+		x := ret[name]
+		x.Count += st.Count
+		x.Time += st.Time
+		ret[name] = x
+	}
+	return ret
+}
+
+// Samples 按调用栈、指令和源码位置列出执行次数与耗时，耗时多的在前
+func (p *Profiler) Samples() []ProfileSample {
+	ret := make([]ProfileSample, 0, len(p.samples))
+	for key, st := range p.samples {
+		item := ProfileSample{Stack: key.stack, Begin: key.span.begin, End: key.span.end, ProfileStat: *st}
+		if key.isOp {
+			item.Op = p.opNames[key.op]
+		}
+		ret = append(ret, item)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		a, b := ret[i], ret[j]
+		if a.Time != b.Time {
+			return a.Time > b.Time
+		}
+		if a.Stack != b.Stack {
+			return a.Stack < b.Stack
+		}
+		if a.Op != b.Op {
+			return a.Op < b.Op
+		}
+		return a.Begin < b.Begin
+	})
+	return ret
+}
+
+// WriteFolded 以 folded stacks 格式输出，每行为调用栈和耗时(纳秒)，如 main;攻击;dice@3-6 12000。
+// 调用栈的最后一项为指令名，有源码位置时以 @开始-结束 附在其后，可以直接交给 flamegraph.pl 等工具生成火焰图
+func (p *Profiler) WriteFolded(w io.Writer) error {
+	lines := make([]string, 0, len(p.samples))
+	for key, st := range p.samples {
+		if st.Time <= 0 {
+			continue
+		}
+		stack := key.stack
+		if key.isOp {
+			stack += ";" + p.opNames[key.op]
+			if key.span != (profileSpan{}) {
+				stack += fmt.Sprintf("@%d-%d", key.span.begin, key.span.end)
+			}
+		}
+		lines = append(lines, fmt.Sprintf("%s %d", stack, st.Time.Nanoseconds()))
+	}
+	sort.Strings(lines)
+	for _, line := range lines {
+		if _, err := io.WriteString(w, line+"\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// computedFrameName computed在调用栈中的名字，取其表达式的开头
+func computedFrameName(expr string) string {
+	const maxLen = 20
+	r := []rune(strings.TrimSpace(expr))
+	if len(r) > maxLen {
+		return "&(" + string(r[:maxLen]) + "...)"
+	}
+	return "&(" + string(r) + ")"
+}
+
+// funcFrameName 函数在调用栈中的名字
+func funcFrameName(name string) string {
+	if name == "" {
+		return "<lambda>"
+	}
+	return name
+}
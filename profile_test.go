@@ -0,0 +1,61 @@
+package dicescript
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProfiler(t *testing.T) {
+	p := NewProfiler()
+	vm := NewVM(WithProfiler(p))
+	err := vm.Run("func fib(n) { if n < 2 { return n }; return fib(n - 1) + fib(n - 2) }; a = &(floor(2d6 / 2)); [fib(5), a]")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	funcs := p.Functions()
+	assert.Equal(t, int64(1), funcs["main"].Count)
+	assert.Equal(t, int64(15), funcs["fib"].Count)
+	assert.Equal(t, int64(1), funcs["floor"].Count)
+	assert.Equal(t, int64(1), funcs["&(floor(2d6 / 2))"].Count)
+	assert.True(t, funcs["main"].Time >= funcs["fib"].Time)
+
+	ops := p.Ops()
+	assert.Equal(t, int64(1), ops["dice"].Count)
+	assert.True(t, ops["invoke"].Count >= 15)
+
+	var sb strings.Builder
+	assert.NoError(t, p.WriteFolded(&sb))
+	assert.Contains(t, sb.String(), "main;fib;fib;")
+	// 指令带有所在表达式的位置，computed中从其表达式开头算起
+	assert.Contains(t, sb.String(), "main;&(floor(2d6 / 2));dice@6-9 ")
+	found := false
+	for _, s := range p.Samples() {
+		if s.Op == "dice" {
+			found = true
+			assert.Equal(t, "main;&(floor(2d6 / 2))", s.Stack)
+			assert.Equal(t, IntType(6), s.Begin)
+			assert.Equal(t, IntType(9), s.End)
+			assert.Equal(t, int64(1), s.Count)
+		}
+	}
+	assert.True(t, found)
+	for _, line := range strings.Split(strings.TrimSpace(sb.String()), "\n") {
+		assert.Regexp(t, `^main(;[^;]+)* \d+$`, line)
+	}
+
+	// 多次执行累计，Reset 后清空
+	err = vm.Run("1 + 1")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), p.Functions()["main"].Count)
+	p.Reset()
+	assert.Empty(t, p.Functions())
+}
+
+func TestComputedFrameName(t *testing.T) {
+	assert.Equal(t, "&(d20)", computedFrameName(" d20 "))
+	assert.Equal(t, "&(12345678901234567890...)", computedFrameName("1234567890123456789012"))
+	assert.Equal(t, "<lambda>", funcFrameName(""))
+}
@@ -0,0 +1,267 @@
+package dicescript
+
+import "errors"
+
+// Program 编译好的语句，可以反复执行而无需重新解析，见 Context.Compile 和 Context.RunProgram。
+// 执行时不修改Program，但其中定义的函数等值会被共享，同一个Program不要在多个vm上并发执行
+type Program struct {
+	Expr string
+
+	offset int // 解析结束的位置
+	code   []ByteCode
+	info   ProgramInfo
+}
+
+// Compile 解析语句，得到可以反复执行的Program
+func (ctx *Context) Compile(expr string) (*Program, error) {
+	if err := ctx.Parse(expr); err != nil {
+		return nil, err
+	}
+	code := make([]ByteCode, ctx.codeIndex)
+	copy(code, ctx.code[:ctx.codeIndex])
+	info := *ctx.ProgramInfo()
+	info.code = code
+	return &Program{Expr: expr, offset: ctx.parser.pt.offset, code: code, info: info}, nil
+}
+
+// Info 程序结构信息，与解析后 ctx.ProgramInfo() 的内容相同
+func (p *Program) Info() *ProgramInfo {
+	info := p.info
+	return &info
+}
+
+// RunProgram 执行编译好的程序，结果与 Run(prog.Expr) 相同
+func (ctx *Context) RunProgram(prog *Program) error {
+	if ctx.IsRunning {
+		return errors.New("正在执行中，无法执行新的语句")
+	}
+	ctx.Error = nil
+	ctx.NumOpCount = 0
+	ctx.detailCache = ""
+	ctx.installCode(prog.Expr, prog.offset, prog.code, prog.info)
+	if err := ctx.checkCompilePolicy(); err != nil {
+		return err
+	}
+	return ctx.RunAfterParsed()
+}
+
+// installCode 设置好字节码和解析器状态，如同刚刚解析完expr
+func (ctx *Context) installCode(expr string, offset int, code []ByteCode, info ProgramInfo) {
+	p := newParser("", []byte(expr))
+	p.pt.offset = offset
+	d := p.cur.data
+	d.Config = ctx.Config
+	d.ctx = ctx
+	d.program = info
+	d.program.code = code
+	ctx.parser = p
+	ctx.code = code
+	ctx.codeIndex = len(code)
+}
+
+// Specialize 将vars中给出的变量视为常量代入，并折叠常量运算，得到一个新的Program。
+// 可用于为每个角色预编译常用的宏。只代入数字和字符串，语句中被赋值的变量不会代入，
+// 含有函数定义时函数可能修改变量，此时只折叠常量。计算过程中代入的变量仍然显示为 60[力量] 的形式
+func (p *Program) Specialize(vars map[string]*VMValue) *Program {
+	code := make([]ByteCode, len(p.code))
+	copy(code, p.code)
+
+	skip := map[string]bool{}
+	for _, name := range p.info.Stores {
+		skip[name] = true
+	}
+	substituted := map[string]bool{}
+	for i, c := range code {
+		if p.info.HasFunc {
+			break
+		}
+		if c.T != typeLoadName && c.T != typeLoadNameRaw && c.T != typeLoadNameWithDetail {
+			continue
+		}
+		name := c.Value.(string)
+		v, ok := vars[name]
+		if !ok || skip[name] {
+			continue
+		}
+		var push ByteCode
+		switch v.TypeId {
+		case VMTypeInt:
+			push = ByteCode{T: typePushIntNumber, Value: v.Value}
+		case VMTypeFloat:
+			push = ByteCode{T: typePushFloatNumber, Value: v.Value}
+		case VMTypeBool:
+			push = ByteCode{T: typePushBool, Value: v.Value}
+		case VMTypeString:
+			push = ByteCode{T: typePushString, Value: v.Value}
+		default:
+			continue
+		}
+		if c.T == typeLoadNameWithDetail {
+			code[i] = ByteCode{T: typeLoadConstWithDetail, Value: v.Clone()}
+		} else {
+			code[i] = push
+		}
+		substituted[name] = true
+	}
+
+	code = compactCode(foldConstants(code))
+
+	info := p.info
+	info.Names = nil
+	for _, name := range p.info.Names {
+		if !substituted[name] {
+			info.Names = append(info.Names, name)
+		}
+	}
+	info.code = code
+	return &Program{Expr: p.Expr, offset: p.offset, code: code, info: info}
+}
+
+// jumpTargets 各跳转指令的目标位置，跳转后执行的是目标处的指令
+func jumpTargets(code []ByteCode) map[int]bool {
+	targets := map[int]bool{}
+	for i, c := range code {
+		switch c.T {
+		case typeJmp, typeJe, typeJne, typeJeDup, typeJneDup, typeJnnDup:
+			targets[i+int(c.Value.(IntType))+1] = true
+		case typeJmpTable:
+			for _, offset := range c.Value.([]IntType)[1:] {
+				targets[i+int(offset)+1] = true
+			}
+		}
+	}
+	return targets
+}
+
+// readConst 指令压入的常数，不是数字时ok为false
+func readConst(c ByteCode) (*VMValue, bool) {
+	switch c.T {
+	case typePushIntNumber:
+		return NewIntVal(c.Value.(IntType)), true
+	case typePushFloatNumber:
+		return NewFloatVal(c.Value.(float64)), true
+	}
+	return nil, false
+}
+
+func constCode(v *VMValue) (ByteCode, bool) {
+	switch v.TypeId {
+	case VMTypeInt:
+		return ByteCode{T: typePushIntNumber, Value: v.Value}, true
+	case VMTypeFloat:
+		return ByteCode{T: typePushFloatNumber, Value: v.Value}, true
+	}
+	return ByteCode{}, false
+}
+
+// foldConstants 将两个常数的四则运算、乘方和常数取负替换为结果。
+// 结果写在第一个操作数的位置，其余指令改为nop，因此跳转位置不变；窗口中间是跳转目标时不折叠
+func foldConstants(code []ByteCode) []ByteCode {
+	targets := jumpTargets(code)
+	ctx := NewVM()
+	// prev 向前找到第一条不是nop的指令，中间不能有跳转目标
+	prev := func(i int) int {
+		for j := i - 1; j >= 0; j-- {
+			if targets[j+1] {
+				return -1
+			}
+			if code[j].T != typeNop {
+				return j
+			}
+		}
+		return -1
+	}
+
+	for changed := true; changed; {
+		changed = false
+		for i, c := range code {
+			var ret *VMValue
+			first := -1
+			switch c.T {
+			case typeAdd, typeSubtract, typeMultiply, typeDivide, typeModulus, typeExponentiation:
+				b := prev(i)
+				if b < 0 {
+					continue
+				}
+				a := prev(b)
+				if a < 0 {
+					continue
+				}
+				v1, ok1 := readConst(code[a])
+				v2, ok2 := readConst(code[b])
+				if !ok1 || !ok2 {
+					continue
+				}
+				ctx.Error = nil
+				ret = binOperator[c.T-typeAdd](v1, ctx, v2)
+				first = a
+			case typeNegation, typePositive:
+				a := prev(i)
+				if a < 0 {
+					continue
+				}
+				v, ok := readConst(code[a])
+				if !ok {
+					continue
+				}
+				if c.T == typeNegation {
+					ret = v.OpNegation()
+				} else {
+					ret = v.OpPositive()
+				}
+				first = a
+			default:
+				continue
+			}
+			if ret == nil || ctx.Error != nil {
+				// 如除以0，留到执行时报错
+				continue
+			}
+			push, ok := constCode(ret)
+			if !ok {
+				continue
+			}
+			code[first] = push
+			for j := first + 1; j <= i; j++ {
+				code[j] = ByteCode{T: typeNop}
+			}
+			changed = true
+		}
+	}
+	return code
+}
+
+// compactCode 去掉nop并修正跳转的距离
+func compactCode(code []ByteCode) []ByteCode {
+	// newIndex[i] 为原来第i条指令(或其后第一条保留的指令)的新位置
+	newIndex := make([]int, len(code)+1)
+	n := 0
+	for i, c := range code {
+		newIndex[i] = n
+		if c.T != typeNop {
+			n++
+		}
+	}
+	newIndex[len(code)] = n
+
+	ret := make([]ByteCode, 0, n)
+	for i, c := range code {
+		switch c.T {
+		case typeNop:
+			continue
+		case typeJmp, typeJe, typeJne, typeJeDup, typeJneDup, typeJnnDup:
+			target := i + int(c.Value.(IntType)) + 1
+			c.Value = IntType(newIndex[target] - newIndex[i] - 1)
+		case typeJmpTable:
+			// 原来的表可能被其他Program共享，不能就地修改
+			table := append([]IntType{}, c.Value.([]IntType)...)
+			for j := 1; j < len(table); j++ {
+				target := i + int(table[j]) + 1
+				table[j] = IntType(newIndex[target] - newIndex[i] - 1)
+			}
+			c.Value = table
+		}
+		ret = append(ret, c)
+	}
+	return ret
+}
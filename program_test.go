@@ -0,0 +1,82 @@
+package dicescript
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProgramRun(t *testing.T) {
+	vm := NewVM()
+	vm.Config.DiceMaxMode = true
+	assert.NoError(t, vm.Run("d20 + 5 谢谢"))
+	detail := vm.GetDetailText()
+	prog, err := vm.Compile("d20 + 5 谢谢")
+	if !assert.NoError(t, err) {
+		return
+	}
+	for i := 0; i < 2; i++ {
+		vm2 := NewVM()
+		vm2.Config.DiceMaxMode = true
+		if assert.NoError(t, vm2.RunProgram(prog)) {
+			assert.True(t, valueEqual(vm2.Ret, ni(25)))
+			assert.Equal(t, "d20 + 5", vm2.Matched)
+			assert.Equal(t, " 谢谢", vm2.RestInput)
+			assert.Equal(t, detail, vm2.GetDetailText())
+		}
+	}
+}
+
+func TestProgramSpecialize(t *testing.T) {
+	expr := "力量/2 + 3*4 + (1 ? 2 : 3) + (0 ? 4 : -5) + 敏捷"
+	vars := map[string]*VMValue{"力量": ni(60), "敏捷": ni(7)}
+
+	vm := NewVM()
+	for k, v := range vars {
+		vm.StoreName(k, v, false)
+	}
+	assert.NoError(t, vm.Run(expr))
+	detail := vm.GetDetailText()
+
+	prog, err := NewVM().Compile(expr)
+	if !assert.NoError(t, err) {
+		return
+	}
+	sp := prog.Specialize(vars)
+	assert.Less(t, len(sp.code), len(prog.code))
+	assert.Empty(t, sp.Info().Names)
+	assert.ElementsMatch(t, []string{"力量", "敏捷"}, prog.Info().Names)
+
+	vm = NewVM()
+	if assert.NoError(t, vm.RunProgram(sp)) {
+		assert.True(t, valueEqual(vm.Ret, ni(46)))
+		assert.Equal(t, detail, vm.GetDetailText())
+	}
+
+	// 原来的Program不受影响
+	vm = NewVM()
+	vm.StoreName("力量", ni(10), false)
+	vm.StoreName("敏捷", ni(0), false)
+	if assert.NoError(t, vm.RunProgram(prog)) {
+		assert.True(t, valueEqual(vm.Ret, ni(14)))
+	}
+}
+
+func TestProgramSpecializeSkip(t *testing.T) {
+	// 被赋值的变量不代入
+	prog, err := NewVM().Compile("力量 = 力量 + 1; 力量 * 2")
+	if assert.NoError(t, err) {
+		vm := NewVM()
+		vm.StoreName("力量", ni(5), false)
+		if assert.NoError(t, vm.RunProgram(prog.Specialize(map[string]*VMValue{"力量": ni(60)}))) {
+			assert.True(t, valueEqual(vm.Ret, ni(12)))
+		}
+	}
+
+	// 除以0留到执行时报错
+	prog, err = NewVM().Compile("1 / 0")
+	if assert.NoError(t, err) {
+		vm := NewVM()
+		assert.Error(t, vm.RunProgram(prog.Specialize(nil)))
+	}
+}
@@ -0,0 +1,22 @@
+package dicescript
+
+// quotaReportInterval 算力每增长这么多向 QuotaFunc 报告一次，执行结束时报告余下的部分
+const quotaReportInterval = 1000
+
+// chargeQuota 向 RollConfig.QuotaFunc 报告上次报告以来消耗的算力。
+// 函数调用等在子vm中执行，算力延续自上层vm，因此统一记录在最外层vm上
+func (ctx *Context) chargeQuota(final bool) error {
+	if ctx.Config.QuotaFunc == nil {
+		return nil
+	}
+	root := ctx.rootCtx()
+	cost := ctx.NumOpCount - root.quotaReported
+	if cost <= 0 || (!final && cost < quotaReportInterval) {
+		return nil
+	}
+	root.quotaReported = ctx.NumOpCount
+	if err := ctx.Config.QuotaFunc(int64(cost)); err != nil {
+		return &quotaError{err}
+	}
+	return nil
+}
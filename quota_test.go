@@ -0,0 +1,45 @@
+package dicescript
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQuotaFunc(t *testing.T) {
+	var used int64
+	quota := func(cost int64) error {
+		used += cost
+		if used > 5000 {
+			return errors.New("算力配额不足")
+		}
+		return nil
+	}
+
+	vm := NewVM()
+	vm.Config.QuotaFunc = quota
+	assert.NoError(t, vm.Run("d20 + 1"))
+	assert.Equal(t, int64(vm.NumOpCount), used)
+
+	// 函数中消耗的算力同样计入，且不重复计算
+	vm = NewVM(WithQuota(quota))
+	used = 0
+	assert.NoError(t, vm.Run("func f(n) { i = 0; while i < n { i = i + 1 }; i }; f(100)"))
+	assert.Equal(t, int64(vm.NumOpCount), used)
+
+	// 跨多次执行累计
+	err := vm.Run("i = 0; while i < 1000 { i = i + 1 }")
+	if assert.Error(t, err) {
+		assert.Equal(t, "算力配额不足", err.Error())
+	}
+
+	// 执行出错时余下的算力同样计入
+	for _, expr := range []string{"i = 0; while i < 10 { i = i + 1 }; 1 + 'a'", "while 1 {}"} {
+		vm = NewVM(WithQuota(quota))
+		vm.Config.OpCountLimit = 500
+		used = 0
+		assert.Error(t, vm.Run(expr), expr)
+		assert.Equal(t, int64(vm.NumOpCount), used, expr)
+	}
+}
@@ -20,6 +20,7 @@ type ParserCustomData struct {
 	ctx               *Context
 	pendingCustomDice *customDiceMatch
 	stream            CustomDiceStream
+	pendingAliasLen   int // DiceAliasAhead 匹配到的别名长度
 }
 
 func toStr(x []byte) string {
@@ -47,9 +48,9 @@ var g = &grammar{
 				run: (*parser).call_ondicescript_1,
 				expr: &seqExpr{
 					exprs: []any{
-						&ruleIRefExpr{index: 123 /* sp */},
+						&ruleIRefExpr{index: 165 /* sp */},
 						&ruleIRefExpr{index: 1 /* stmtSt */},
-						&ruleIRefExpr{index: 123 /* sp */},
+						&ruleIRefExpr{index: 165 /* sp */},
 					},
 				},
 			},
@@ -61,7 +62,7 @@ var g = &grammar{
 					&seqExpr{
 						exprs: []any{
 							&litMatcher{val: "^st", want: "\"^st\""},
-							&ruleIRefExpr{index: 130 /* st_expr */},
+							&ruleIRefExpr{index: 172 /* st_expr */},
 						},
 					},
 					&ruleIRefExpr{index: 2 /* stmtRoot */},
@@ -73,7 +74,7 @@ var g = &grammar{
 			expr: &seqExpr{
 				exprs: []any{
 					&ruleIRefExpr{index: 3 /* stmtLines */},
-					&ruleIRefExpr{index: 123 /* sp */},
+					&ruleIRefExpr{index: 165 /* sp */},
 				},
 			},
 		},
@@ -100,8 +101,8 @@ var g = &grammar{
 					},
 					&seqExpr{
 						exprs: []any{
-							&ruleIRefExpr{index: 127 /* comment */},
-							&ruleIRefExpr{index: 123 /* sp */},
+							&ruleIRefExpr{index: 169 /* comment */},
+							&ruleIRefExpr{index: 165 /* sp */},
 							&zeroOrOneExpr{
 								expr: &ruleIRefExpr{index: 3 /* stmtLines */},
 							},
@@ -121,7 +122,7 @@ var g = &grammar{
 								expr: &seqExpr{
 									exprs: []any{
 										&litMatcher{val: ";", want: "\";\""},
-										&ruleIRefExpr{index: 123 /* sp */},
+										&ruleIRefExpr{index: 165 /* sp */},
 									},
 								},
 							},
@@ -141,14 +142,14 @@ var g = &grammar{
 				expr: &seqExpr{
 					exprs: []any{
 						&litMatcher{val: "//", want: "\"//\""},
-						&ruleIRefExpr{index: 123 /* sp */},
+						&ruleIRefExpr{index: 165 /* sp */},
 						&litMatcher{val: "#EnableDice", want: "\"#EnableDice\""},
-						&ruleIRefExpr{index: 125 /* sp1x */},
+						&ruleIRefExpr{index: 167 /* sp1x */},
 						&labeledExpr{
 							label: "id",
-							expr:  &ruleIRefExpr{index: 98 /* identifier */},
+							expr:  &ruleIRefExpr{index: 135 /* identifier */},
 						},
-						&ruleIRefExpr{index: 125 /* sp1x */},
+						&ruleIRefExpr{index: 167 /* sp1x */},
 						&labeledExpr{
 							label: "on",
 							expr: &choiceExpr{
@@ -159,7 +160,7 @@ var g = &grammar{
 							},
 							textCapture: true,
 						},
-						&ruleIRefExpr{index: 128 /* commentLineRest */},
+						&ruleIRefExpr{index: 170 /* commentLineRest */},
 					},
 				},
 			},
@@ -170,7 +171,9 @@ var g = &grammar{
 				alternatives: []any{
 					&ruleIRefExpr{index: 8 /* stmtBreak */},
 					&ruleIRefExpr{index: 9 /* stmtContinue */},
-					&ruleIRefExpr{index: 26 /* exprRoot */},
+					&ruleIRefExpr{index: 10 /* stmtDel */},
+					&ruleIRefExpr{index: 11 /* stmtConst */},
+					&ruleIRefExpr{index: 34 /* exprRoot */},
 				},
 			},
 		},
@@ -178,10 +181,12 @@ var g = &grammar{
 			name: "stmtWithBlock",
 			expr: &choiceExpr{
 				alternatives: []any{
-					&ruleIRefExpr{index: 14 /* stmtIf */},
-					&ruleIRefExpr{index: 16 /* stmtFunc */},
-					&ruleIRefExpr{index: 11 /* stmtWhile */},
-					&ruleIRefExpr{index: 10 /* stmtReturn */},
+					&ruleIRefExpr{index: 18 /* stmtIf */},
+					&ruleIRefExpr{index: 20 /* stmtFunc */},
+					&ruleIRefExpr{index: 14 /* stmtWhile */},
+					&ruleIRefExpr{index: 15 /* stmtFor */},
+					&ruleIRefExpr{index: 12 /* stmtReturn */},
+					&ruleIRefExpr{index: 13 /* stmtYield */},
 				},
 			},
 		},
@@ -196,19 +201,19 @@ var g = &grammar{
 									alternatives: []any{
 										&seqExpr{
 											exprs: []any{
-												&ruleIRefExpr{index: 126 /* spNoCR */},
+												&ruleIRefExpr{index: 168 /* spNoCR */},
 												&litMatcher{val: "\n", want: "\"\\n\""},
 											},
 										},
 										&seqExpr{
 											exprs: []any{
-												&ruleIRefExpr{index: 123 /* sp */},
+												&ruleIRefExpr{index: 165 /* sp */},
 												&litMatcher{val: ";", want: "\";\""},
 											},
 										},
 									},
 								},
-								&ruleIRefExpr{index: 123 /* sp */},
+								&ruleIRefExpr{index: 165 /* sp */},
 							},
 						},
 					},
@@ -225,7 +230,7 @@ var g = &grammar{
 				expr: &seqExpr{
 					exprs: []any{
 						&litMatcher{val: "break", want: "\"break\""},
-						&ruleIRefExpr{index: 123 /* sp */},
+						&ruleIRefExpr{index: 165 /* sp */},
 					},
 				},
 			},
@@ -237,7 +242,62 @@ var g = &grammar{
 				expr: &seqExpr{
 					exprs: []any{
 						&litMatcher{val: "continue", want: "\"continue\""},
-						&ruleIRefExpr{index: 123 /* sp */},
+						&ruleIRefExpr{index: 165 /* sp */},
+					},
+				},
+			},
+		},
+		{
+			name:      "stmtDel",
+			varExists: true,
+			expr: &actionExpr{
+				run: (*parser).call_onstmtDel_1,
+				expr: &seqExpr{
+					exprs: []any{
+						&andCodeExpr{run: (*parser).call_onstmtDel_3},
+						&litMatcher{val: "del", want: "\"del\""},
+						&ruleIRefExpr{index: 167 /* sp1x */},
+						&labeledExpr{
+							label: "id",
+							expr:  &ruleIRefExpr{index: 135 /* identifier */},
+						},
+						&ruleIRefExpr{index: 165 /* sp */},
+					},
+				},
+			},
+		},
+		{
+			name:      "stmtConst",
+			varExists: true,
+			expr: &seqExpr{
+				exprs: []any{
+					&actionExpr{
+						run: (*parser).call_onstmtConst_2,
+						expr: &seqExpr{
+							exprs: []any{
+								&andCodeExpr{run: (*parser).call_onstmtConst_4},
+								&litMatcher{val: "const", want: "\"const\""},
+								&ruleIRefExpr{index: 167 /* sp1x */},
+								&labeledExpr{
+									label: "id",
+									expr:  &ruleIRefExpr{index: 135 /* identifier */},
+								},
+								&ruleIRefExpr{index: 165 /* sp */},
+								&andExpr{
+									expr: &litMatcher{val: "=", want: "\"=\""},
+								},
+							},
+						},
+					},
+					&actionExpr{
+						run: (*parser).call_onstmtConst_12,
+						expr: &seqExpr{
+							exprs: []any{
+								&litMatcher{val: "=", want: "\"=\""},
+								&ruleIRefExpr{index: 165 /* sp */},
+								&ruleIRefExpr{index: 34 /* exprRoot */},
+							},
+						},
 					},
 				},
 			},
@@ -251,8 +311,8 @@ var g = &grammar{
 						expr: &seqExpr{
 							exprs: []any{
 								&litMatcher{val: "return", want: "\"return\""},
-								&ruleIRefExpr{index: 125 /* sp1x */},
-								&ruleIRefExpr{index: 26 /* exprRoot */},
+								&ruleIRefExpr{index: 167 /* sp1x */},
+								&ruleIRefExpr{index: 34 /* exprRoot */},
 							},
 						},
 					},
@@ -261,7 +321,33 @@ var g = &grammar{
 						expr: &seqExpr{
 							exprs: []any{
 								&litMatcher{val: "return", want: "\"return\""},
-								&ruleIRefExpr{index: 123 /* sp */},
+								&ruleIRefExpr{index: 165 /* sp */},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "stmtYield",
+			expr: &choiceExpr{
+				alternatives: []any{
+					&actionExpr{
+						run: (*parser).call_onstmtYield_2,
+						expr: &seqExpr{
+							exprs: []any{
+								&litMatcher{val: "yield", want: "\"yield\""},
+								&ruleIRefExpr{index: 167 /* sp1x */},
+								&ruleIRefExpr{index: 34 /* exprRoot */},
+							},
+						},
+					},
+					&actionExpr{
+						run: (*parser).call_onstmtYield_7,
+						expr: &seqExpr{
+							exprs: []any{
+								&litMatcher{val: "yield", want: "\"yield\""},
+								&ruleIRefExpr{index: 165 /* sp */},
 							},
 						},
 					},
@@ -277,7 +363,7 @@ var g = &grammar{
 						expr: &seqExpr{
 							exprs: []any{
 								&litMatcher{val: "while", want: "\"while\""},
-								&ruleIRefExpr{index: 125 /* sp1x */},
+								&ruleIRefExpr{index: 167 /* sp1x */},
 							},
 						},
 					},
@@ -285,14 +371,54 @@ var g = &grammar{
 						run: (*parser).call_onstmtWhile_6,
 						expr: &seqExpr{
 							exprs: []any{
-								&ruleIRefExpr{index: 26 /* exprRoot */},
-								&ruleIRefExpr{index: 123 /* sp */},
+								&ruleIRefExpr{index: 34 /* exprRoot */},
+								&ruleIRefExpr{index: 165 /* sp */},
 							},
 						},
 					},
 					&actionExpr{
 						run:  (*parser).call_onstmtWhile_10,
-						expr: &ruleIRefExpr{index: 12 /* block */},
+						expr: &ruleIRefExpr{index: 16 /* block */},
+					},
+				},
+			},
+		},
+		{
+			name:      "stmtFor",
+			varExists: true,
+			expr: &seqExpr{
+				exprs: []any{
+					&actionExpr{
+						run: (*parser).call_onstmtFor_2,
+						expr: &seqExpr{
+							exprs: []any{
+								&litMatcher{val: "for", want: "\"for\""},
+								&ruleIRefExpr{index: 167 /* sp1x */},
+								&labeledExpr{
+									label: "id",
+									expr:  &ruleIRefExpr{index: 135 /* identifier */},
+								},
+								&ruleIRefExpr{index: 167 /* sp1x */},
+								&litMatcher{val: "in", want: "\"in\""},
+								&notExpr{
+									expr: &ruleIRefExpr{index: 138 /* xidContinue */},
+								},
+								&ruleIRefExpr{index: 165 /* sp */},
+							},
+						},
+					},
+					&actionExpr{
+						run: (*parser).call_onstmtFor_13,
+						expr: &seqExpr{
+							exprs: []any{
+								&ruleIRefExpr{index: 34 /* exprRoot */},
+								&ruleIRefExpr{index: 165 /* sp */},
+							},
+						},
+					},
+					&actionExpr{
+						run:  (*parser).call_onstmtFor_17,
+						expr: &ruleIRefExpr{index: 16 /* block */},
 					},
 				},
 			},
@@ -306,21 +432,21 @@ var g = &grammar{
 							&seqExpr{
 								exprs: []any{
 									&litMatcher{val: "{", want: "\"{\""},
-									&ruleIRefExpr{index: 123 /* sp */},
+									&ruleIRefExpr{index: 165 /* sp */},
 									&litMatcher{val: "}", want: "\"}\""},
 								},
 							},
 							&seqExpr{
 								exprs: []any{
 									&litMatcher{val: "{", want: "\"{\""},
-									&ruleIRefExpr{index: 123 /* sp */},
+									&ruleIRefExpr{index: 165 /* sp */},
 									&ruleIRefExpr{index: 2 /* stmtRoot */},
 									&litMatcher{val: "}", want: "\"}\""},
 								},
 							},
 						},
 					},
-					&ruleIRefExpr{index: 123 /* sp */},
+					&ruleIRefExpr{index: 165 /* sp */},
 				},
 			},
 		},
@@ -333,14 +459,14 @@ var g = &grammar{
 						alternatives: []any{
 							&seqExpr{
 								exprs: []any{
-									&ruleIRefExpr{index: 123 /* sp */},
-									&ruleIRefExpr{index: 12 /* block */},
+									&ruleIRefExpr{index: 165 /* sp */},
+									&ruleIRefExpr{index: 16 /* block */},
 								},
 							},
 							&seqExpr{
 								exprs: []any{
-									&ruleIRefExpr{index: 125 /* sp1x */},
-									&ruleIRefExpr{index: 14 /* stmtIf */},
+									&ruleIRefExpr{index: 167 /* sp1x */},
+									&ruleIRefExpr{index: 18 /* stmtIf */},
 								},
 							},
 						},
@@ -353,7 +479,7 @@ var g = &grammar{
 			expr: &seqExpr{
 				exprs: []any{
 					&litMatcher{val: "if", want: "\"if\""},
-					&ruleIRefExpr{index: 125 /* sp1x */},
+					&ruleIRefExpr{index: 167 /* sp1x */},
 					&choiceExpr{
 						alternatives: []any{
 							&seqExpr{
@@ -362,19 +488,19 @@ var g = &grammar{
 										run: (*parser).call_onstmtIf_6,
 										expr: &seqExpr{
 											exprs: []any{
-												&ruleIRefExpr{index: 26 /* exprRoot */},
-												&ruleIRefExpr{index: 123 /* sp */},
+												&ruleIRefExpr{index: 34 /* exprRoot */},
+												&ruleIRefExpr{index: 165 /* sp */},
 											},
 										},
 									},
 									&actionExpr{
 										run:  (*parser).call_onstmtIf_10,
-										expr: &ruleIRefExpr{index: 12 /* block */},
+										expr: &ruleIRefExpr{index: 16 /* block */},
 									},
 									&actionExpr{
 										run: (*parser).call_onstmtIf_12,
 										expr: &zeroOrOneExpr{
-											expr: &ruleIRefExpr{index: 13 /* stmtElse */},
+											expr: &ruleIRefExpr{index: 17 /* stmtElse */},
 										},
 									},
 								},
@@ -395,9 +521,9 @@ var g = &grammar{
 						expr: &seqExpr{
 							exprs: []any{
 								&litMatcher{val: "(", want: "\"(\""},
-								&ruleIRefExpr{index: 123 /* sp */},
+								&ruleIRefExpr{index: 165 /* sp */},
 								&litMatcher{val: ")", want: "\")\""},
-								&ruleIRefExpr{index: 123 /* sp */},
+								&ruleIRefExpr{index: 165 /* sp */},
 							},
 						},
 					},
@@ -408,7 +534,7 @@ var g = &grammar{
 								expr: &seqExpr{
 									exprs: []any{
 										&litMatcher{val: "(", want: "\"(\""},
-										&ruleIRefExpr{index: 123 /* sp */},
+										&ruleIRefExpr{index: 165 /* sp */},
 									},
 								},
 							},
@@ -418,9 +544,9 @@ var g = &grammar{
 									exprs: []any{
 										&labeledExpr{
 											label: "id",
-											expr:  &ruleIRefExpr{index: 98 /* identifier */},
+											expr:  &ruleIRefExpr{index: 135 /* identifier */},
 										},
-										&ruleIRefExpr{index: 123 /* sp */},
+										&ruleIRefExpr{index: 165 /* sp */},
 									},
 								},
 							},
@@ -434,12 +560,12 @@ var g = &grammar{
 													expr: &seqExpr{
 														exprs: []any{
 															&litMatcher{val: ",", want: "\",\""},
-															&ruleIRefExpr{index: 123 /* sp */},
+															&ruleIRefExpr{index: 165 /* sp */},
 															&labeledExpr{
 																label: "id2",
-																expr:  &ruleIRefExpr{index: 98 /* identifier */},
+																expr:  &ruleIRefExpr{index: 135 /* identifier */},
 															},
-															&ruleIRefExpr{index: 123 /* sp */},
+															&ruleIRefExpr{index: 165 /* sp */},
 														},
 													},
 												},
@@ -450,7 +576,7 @@ var g = &grammar{
 										},
 									},
 									&litMatcher{val: ")", want: "\")\""},
-									&ruleIRefExpr{index: 123 /* sp */},
+									&ruleIRefExpr{index: 165 /* sp */},
 								},
 							},
 						},
@@ -468,12 +594,12 @@ var g = &grammar{
 						expr: &seqExpr{
 							exprs: []any{
 								&litMatcher{val: "func", want: "\"func\""},
-								&ruleIRefExpr{index: 125 /* sp1x */},
+								&ruleIRefExpr{index: 167 /* sp1x */},
 								&labeledExpr{
 									label: "id",
-									expr:  &ruleIRefExpr{index: 98 /* identifier */},
+									expr:  &ruleIRefExpr{index: 135 /* identifier */},
 								},
-								&ruleIRefExpr{index: 123 /* sp */},
+								&ruleIRefExpr{index: 165 /* sp */},
 							},
 						},
 					},
@@ -481,9 +607,9 @@ var g = &grammar{
 						run: (*parser).call_onstmtFunc_9,
 						expr: &seqExpr{
 							exprs: []any{
-								&ruleIRefExpr{index: 15 /* func_def_params */},
+								&ruleIRefExpr{index: 19 /* func_def_params */},
 								&litMatcher{val: "{", want: "\"{\""},
-								&ruleIRefExpr{index: 123 /* sp */},
+								&ruleIRefExpr{index: 165 /* sp */},
 							},
 						},
 					},
@@ -499,7 +625,7 @@ var g = &grammar{
 									textCapture: true,
 								},
 								&litMatcher{val: "}", want: "\"}\""},
-								&ruleIRefExpr{index: 123 /* sp */},
+								&ruleIRefExpr{index: 165 /* sp */},
 							},
 						},
 					},
@@ -517,9 +643,9 @@ var g = &grammar{
 							exprs: []any{
 								&labeledExpr{
 									label: "id",
-									expr:  &ruleIRefExpr{index: 98 /* identifier */},
+									expr:  &ruleIRefExpr{index: 135 /* identifier */},
 								},
-								&ruleIRefExpr{index: 123 /* sp */},
+								&ruleIRefExpr{index: 165 /* sp */},
 							},
 						},
 					},
@@ -528,8 +654,8 @@ var g = &grammar{
 						expr: &seqExpr{
 							exprs: []any{
 								&litMatcher{val: "=", want: "\"=\""},
-								&ruleIRefExpr{index: 123 /* sp */},
-								&ruleIRefExpr{index: 26 /* exprRoot */},
+								&ruleIRefExpr{index: 165 /* sp */},
+								&ruleIRefExpr{index: 34 /* exprRoot */},
 							},
 						},
 					},
@@ -548,9 +674,9 @@ var g = &grammar{
 								&litMatcher{val: "&", want: "\"&\""},
 								&labeledExpr{
 									label: "id",
-									expr:  &ruleIRefExpr{index: 98 /* identifier */},
+									expr:  &ruleIRefExpr{index: 135 /* identifier */},
 								},
-								&ruleIRefExpr{index: 123 /* sp */},
+								&ruleIRefExpr{index: 165 /* sp */},
 							},
 						},
 					},
@@ -559,7 +685,7 @@ var g = &grammar{
 						expr: &seqExpr{
 							exprs: []any{
 								&litMatcher{val: "=", want: "\"=\""},
-								&ruleIRefExpr{index: 123 /* sp */},
+								&ruleIRefExpr{index: 165 /* sp */},
 							},
 						},
 					},
@@ -567,7 +693,46 @@ var g = &grammar{
 						run: (*parser).call_onstmtAssignType2_12,
 						expr: &labeledExpr{
 							label:       "expr",
-							expr:        &ruleIRefExpr{index: 26 /* exprRoot */},
+							expr:        &ruleIRefExpr{index: 34 /* exprRoot */},
+							textCapture: true,
+						},
+					},
+				},
+			},
+		},
+		{
+			name:      "stmtAssignType9",
+			varExists: true,
+			expr: &seqExpr{
+				exprs: []any{
+					&actionExpr{
+						run: (*parser).call_onstmtAssignType9_2,
+						expr: &seqExpr{
+							exprs: []any{
+								&litMatcher{val: "&", want: "\"&\""},
+								&labeledExpr{
+									label: "id",
+									expr:  &ruleIRefExpr{index: 135 /* identifier */},
+								},
+								&ruleIRefExpr{index: 165 /* sp */},
+							},
+						},
+					},
+					&actionExpr{
+						run: (*parser).call_onstmtAssignType9_8,
+						expr: &seqExpr{
+							exprs: []any{
+								&ruleIRefExpr{index: 19 /* func_def_params */},
+								&litMatcher{val: "=", want: "\"=\""},
+								&ruleIRefExpr{index: 165 /* sp */},
+							},
+						},
+					},
+					&actionExpr{
+						run: (*parser).call_onstmtAssignType9_13,
+						expr: &labeledExpr{
+							label:       "expr",
+							expr:        &ruleIRefExpr{index: 34 /* exprRoot */},
 							textCapture: true,
 						},
 					},
@@ -586,9 +751,9 @@ var g = &grammar{
 								&litMatcher{val: "&", want: "\"&\""},
 								&labeledExpr{
 									label: "id",
-									expr:  &ruleIRefExpr{index: 98 /* identifier */},
+									expr:  &ruleIRefExpr{index: 135 /* identifier */},
 								},
-								&ruleIRefExpr{index: 123 /* sp */},
+								&ruleIRefExpr{index: 165 /* sp */},
 							},
 						},
 					},
@@ -599,9 +764,9 @@ var g = &grammar{
 								&litMatcher{val: ".", want: "\".\""},
 								&labeledExpr{
 									label: "id2",
-									expr:  &ruleIRefExpr{index: 98 /* identifier */},
+									expr:  &ruleIRefExpr{index: 135 /* identifier */},
 								},
-								&ruleIRefExpr{index: 123 /* sp */},
+								&ruleIRefExpr{index: 165 /* sp */},
 							},
 						},
 					},
@@ -609,10 +774,10 @@ var g = &grammar{
 						run: (*parser).call_onstmtAssignType3_14,
 						expr: &seqExpr{
 							exprs: []any{
-								&ruleIRefExpr{index: 123 /* sp */},
+								&ruleIRefExpr{index: 165 /* sp */},
 								&litMatcher{val: "=", want: "\"=\""},
-								&ruleIRefExpr{index: 123 /* sp */},
-								&ruleIRefExpr{index: 26 /* exprRoot */},
+								&ruleIRefExpr{index: 165 /* sp */},
+								&ruleIRefExpr{index: 34 /* exprRoot */},
 							},
 						},
 					},
@@ -629,14 +794,14 @@ var g = &grammar{
 						expr: &seqExpr{
 							exprs: []any{
 								&litMatcher{val: "this", want: "\"this\""},
-								&ruleIRefExpr{index: 123 /* sp */},
+								&ruleIRefExpr{index: 165 /* sp */},
 								&litMatcher{val: ".", want: "\".\""},
-								&ruleIRefExpr{index: 123 /* sp */},
+								&ruleIRefExpr{index: 165 /* sp */},
 								&labeledExpr{
 									label: "id",
-									expr:  &ruleIRefExpr{index: 98 /* identifier */},
+									expr:  &ruleIRefExpr{index: 135 /* identifier */},
 								},
-								&ruleIRefExpr{index: 123 /* sp */},
+								&ruleIRefExpr{index: 165 /* sp */},
 							},
 						},
 					},
@@ -645,8 +810,8 @@ var g = &grammar{
 						expr: &seqExpr{
 							exprs: []any{
 								&litMatcher{val: "=", want: "\"=\""},
-								&ruleIRefExpr{index: 123 /* sp */},
-								&ruleIRefExpr{index: 26 /* exprRoot */},
+								&ruleIRefExpr{index: 165 /* sp */},
+								&ruleIRefExpr{index: 34 /* exprRoot */},
 							},
 						},
 					},
@@ -664,9 +829,9 @@ var g = &grammar{
 							exprs: []any{
 								&labeledExpr{
 									label: "id",
-									expr:  &ruleIRefExpr{index: 98 /* identifier */},
+									expr:  &ruleIRefExpr{index: 135 /* identifier */},
 								},
-								&ruleIRefExpr{index: 123 /* sp */},
+								&ruleIRefExpr{index: 165 /* sp */},
 							},
 						},
 					},
@@ -675,12 +840,12 @@ var g = &grammar{
 						expr: &seqExpr{
 							exprs: []any{
 								&litMatcher{val: ".", want: "\".\""},
-								&ruleIRefExpr{index: 123 /* sp */},
+								&ruleIRefExpr{index: 165 /* sp */},
 								&labeledExpr{
 									label: "id2",
-									expr:  &ruleIRefExpr{index: 98 /* identifier */},
+									expr:  &ruleIRefExpr{index: 135 /* identifier */},
 								},
-								&ruleIRefExpr{index: 123 /* sp */},
+								&ruleIRefExpr{index: 165 /* sp */},
 							},
 						},
 					},
@@ -689,8 +854,54 @@ var g = &grammar{
 						expr: &seqExpr{
 							exprs: []any{
 								&litMatcher{val: "=", want: "\"=\""},
-								&ruleIRefExpr{index: 123 /* sp */},
-								&ruleIRefExpr{index: 26 /* exprRoot */},
+								&ruleIRefExpr{index: 165 /* sp */},
+								&ruleIRefExpr{index: 34 /* exprRoot */},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name:      "stmtAssignType8",
+			varExists: true,
+			expr: &seqExpr{
+				exprs: []any{
+					&actionExpr{
+						run: (*parser).call_onstmtAssignType8_2,
+						expr: &seqExpr{
+							exprs: []any{
+								&labeledExpr{
+									label: "id",
+									expr:  &ruleIRefExpr{index: 135 /* identifier */},
+								},
+								&ruleIRefExpr{index: 165 /* sp */},
+							},
+						},
+					},
+					&actionExpr{
+						run: (*parser).call_onstmtAssignType8_7,
+						expr: &seqExpr{
+							exprs: []any{
+								&oneOrMoreExpr{
+									expr: &actionExpr{
+										run: (*parser).call_onstmtAssignType8_10,
+										expr: &seqExpr{
+											exprs: []any{
+												&litMatcher{val: ".", want: "\".\""},
+												&ruleIRefExpr{index: 165 /* sp */},
+												&labeledExpr{
+													label: "id2",
+													expr:  &ruleIRefExpr{index: 135 /* identifier */},
+												},
+												&ruleIRefExpr{index: 165 /* sp */},
+											},
+										},
+									},
+								},
+								&litMatcher{val: "=", want: "\"=\""},
+								&ruleIRefExpr{index: 165 /* sp */},
+								&ruleIRefExpr{index: 34 /* exprRoot */},
 							},
 						},
 					},
@@ -703,15 +914,15 @@ var g = &grammar{
 				run: (*parser).call_onstmtAssignType6_1,
 				expr: &seqExpr{
 					exprs: []any{
-						&ruleIRefExpr{index: 30 /* exprSlice */},
+						&ruleIRefExpr{index: 38 /* exprSlice */},
 						&litMatcher{val: "[", want: "\"[\""},
-						&ruleIRefExpr{index: 123 /* sp */},
-						&ruleIRefExpr{index: 26 /* exprRoot */},
+						&ruleIRefExpr{index: 165 /* sp */},
+						&ruleIRefExpr{index: 34 /* exprRoot */},
 						&litMatcher{val: "]", want: "\"]\""},
-						&ruleIRefExpr{index: 123 /* sp */},
+						&ruleIRefExpr{index: 165 /* sp */},
 						&litMatcher{val: "=", want: "\"=\""},
-						&ruleIRefExpr{index: 123 /* sp */},
-						&ruleIRefExpr{index: 26 /* exprRoot */},
+						&ruleIRefExpr{index: 165 /* sp */},
+						&ruleIRefExpr{index: 34 /* exprRoot */},
 					},
 				},
 			},
@@ -722,11 +933,104 @@ var g = &grammar{
 				run: (*parser).call_onstmtAssignType7_1,
 				expr: &seqExpr{
 					exprs: []any{
-						&ruleIRefExpr{index: 30 /* exprSlice */},
-						&ruleIRefExpr{index: 28 /* _sliceSuffix */},
+						&ruleIRefExpr{index: 38 /* exprSlice */},
+						&ruleIRefExpr{index: 36 /* _sliceSuffix */},
 						&litMatcher{val: "=", want: "\"=\""},
-						&ruleIRefExpr{index: 123 /* sp */},
-						&ruleIRefExpr{index: 26 /* exprRoot */},
+						&ruleIRefExpr{index: 165 /* sp */},
+						&ruleIRefExpr{index: 34 /* exprRoot */},
+					},
+				},
+			},
+		},
+		{
+			name:      "stmtAssignType11",
+			varExists: true,
+			expr: &seqExpr{
+				exprs: []any{
+					&actionExpr{
+						run: (*parser).call_onstmtAssignType11_2,
+						expr: &seqExpr{
+							exprs: []any{
+								&labeledExpr{
+									label: "id",
+									expr:  &ruleIRefExpr{index: 135 /* identifier */},
+								},
+								&ruleIRefExpr{index: 165 /* sp */},
+							},
+						},
+					},
+					&actionExpr{
+						run: (*parser).call_onstmtAssignType11_7,
+						expr: &seqExpr{
+							exprs: []any{
+								&labeledExpr{
+									label: "op",
+									expr: &charClassMatcher{
+										val:   "[-+*/%]",
+										chars: []rune{'-', '+', '*', '/', '%'},
+									},
+									textCapture: true,
+								},
+								&litMatcher{val: "=", want: "\"=\""},
+								&ruleIRefExpr{index: 165 /* sp */},
+								&ruleIRefExpr{index: 34 /* exprRoot */},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name:      "stmtAssignType10",
+			varExists: true,
+			expr: &seqExpr{
+				exprs: []any{
+					&actionExpr{
+						run:  (*parser).call_onstmtAssignType10_2,
+						expr: &ruleIRefExpr{index: 141 /* parenOpen */},
+					},
+					&actionExpr{
+						run: (*parser).call_onstmtAssignType10_4,
+						expr: &seqExpr{
+							exprs: []any{
+								&labeledExpr{
+									label: "id",
+									expr:  &ruleIRefExpr{index: 135 /* identifier */},
+								},
+								&ruleIRefExpr{index: 165 /* sp */},
+							},
+						},
+					},
+					&actionExpr{
+						run: (*parser).call_onstmtAssignType10_9,
+						expr: &seqExpr{
+							exprs: []any{
+								&oneOrMoreExpr{
+									expr: &actionExpr{
+										run: (*parser).call_onstmtAssignType10_12,
+										expr: &seqExpr{
+											exprs: []any{
+												&litMatcher{val: ",", want: "\",\""},
+												&ruleIRefExpr{index: 165 /* sp */},
+												&labeledExpr{
+													label: "id2",
+													expr:  &ruleIRefExpr{index: 135 /* identifier */},
+												},
+												&ruleIRefExpr{index: 165 /* sp */},
+											},
+										},
+									},
+								},
+								&zeroOrOneExpr{
+									expr: &litMatcher{val: ",", want: "\",\""},
+								},
+								&ruleIRefExpr{index: 165 /* sp */},
+								&ruleIRefExpr{index: 142 /* parenClose */},
+								&litMatcher{val: "=", want: "\"=\""},
+								&ruleIRefExpr{index: 165 /* sp */},
+								&ruleIRefExpr{index: 34 /* exprRoot */},
+							},
+						},
 					},
 				},
 			},
@@ -738,57 +1042,89 @@ var g = &grammar{
 					&seqExpr{
 						exprs: []any{
 							&andExpr{
-								expr: &ruleIRefExpr{index: 17 /* stmtAssignType1 */},
+								expr: &ruleIRefExpr{index: 21 /* stmtAssignType1 */},
+							},
+							&ruleIRefExpr{index: 21 /* stmtAssignType1 */},
+						},
+					},
+					&seqExpr{
+						exprs: []any{
+							&andExpr{
+								expr: &ruleIRefExpr{index: 30 /* stmtAssignType11 */},
+							},
+							&ruleIRefExpr{index: 30 /* stmtAssignType11 */},
+						},
+					},
+					&seqExpr{
+						exprs: []any{
+							&andExpr{
+								expr: &ruleIRefExpr{index: 22 /* stmtAssignType2 */},
+							},
+							&ruleIRefExpr{index: 22 /* stmtAssignType2 */},
+						},
+					},
+					&seqExpr{
+						exprs: []any{
+							&andExpr{
+								expr: &ruleIRefExpr{index: 23 /* stmtAssignType9 */},
+							},
+							&ruleIRefExpr{index: 23 /* stmtAssignType9 */},
+						},
+					},
+					&seqExpr{
+						exprs: []any{
+							&andExpr{
+								expr: &ruleIRefExpr{index: 24 /* stmtAssignType3 */},
 							},
-							&ruleIRefExpr{index: 17 /* stmtAssignType1 */},
+							&ruleIRefExpr{index: 24 /* stmtAssignType3 */},
 						},
 					},
 					&seqExpr{
 						exprs: []any{
 							&andExpr{
-								expr: &ruleIRefExpr{index: 18 /* stmtAssignType2 */},
+								expr: &ruleIRefExpr{index: 25 /* stmtAssignType4 */},
 							},
-							&ruleIRefExpr{index: 18 /* stmtAssignType2 */},
+							&ruleIRefExpr{index: 25 /* stmtAssignType4 */},
 						},
 					},
 					&seqExpr{
 						exprs: []any{
 							&andExpr{
-								expr: &ruleIRefExpr{index: 19 /* stmtAssignType3 */},
+								expr: &ruleIRefExpr{index: 31 /* stmtAssignType10 */},
 							},
-							&ruleIRefExpr{index: 19 /* stmtAssignType3 */},
+							&ruleIRefExpr{index: 31 /* stmtAssignType10 */},
 						},
 					},
 					&seqExpr{
 						exprs: []any{
 							&andExpr{
-								expr: &ruleIRefExpr{index: 20 /* stmtAssignType4 */},
+								expr: &ruleIRefExpr{index: 26 /* stmtAssignType5 */},
 							},
-							&ruleIRefExpr{index: 20 /* stmtAssignType4 */},
+							&ruleIRefExpr{index: 26 /* stmtAssignType5 */},
 						},
 					},
 					&seqExpr{
 						exprs: []any{
 							&andExpr{
-								expr: &ruleIRefExpr{index: 21 /* stmtAssignType5 */},
+								expr: &ruleIRefExpr{index: 27 /* stmtAssignType8 */},
 							},
-							&ruleIRefExpr{index: 21 /* stmtAssignType5 */},
+							&ruleIRefExpr{index: 27 /* stmtAssignType8 */},
 						},
 					},
 					&seqExpr{
 						exprs: []any{
 							&andExpr{
-								expr: &ruleIRefExpr{index: 22 /* stmtAssignType6 */},
+								expr: &ruleIRefExpr{index: 28 /* stmtAssignType6 */},
 							},
-							&ruleIRefExpr{index: 22 /* stmtAssignType6 */},
+							&ruleIRefExpr{index: 28 /* stmtAssignType6 */},
 						},
 					},
 					&seqExpr{
 						exprs: []any{
 							&andExpr{
-								expr: &ruleIRefExpr{index: 23 /* stmtAssignType7 */},
+								expr: &ruleIRefExpr{index: 29 /* stmtAssignType7 */},
 							},
-							&ruleIRefExpr{index: 23 /* stmtAssignType7 */},
+							&ruleIRefExpr{index: 29 /* stmtAssignType7 */},
 						},
 					},
 				},
@@ -803,8 +1139,8 @@ var g = &grammar{
 							&andExpr{
 								expr: &seqExpr{
 									exprs: []any{
-										&ruleIRefExpr{index: 103 /* subX */},
-										&ruleIRefExpr{index: 123 /* sp */},
+										&ruleIRefExpr{index: 140 /* subX */},
+										&ruleIRefExpr{index: 165 /* sp */},
 										&charClassMatcher{
 											val:   "[-+*/%^dDcCaA&|?<>=]",
 											chars: []rune{'-', '+', '*', '/', '%', '^', 'd', 'D', 'c', 'C', 'a', 'A', '&', '|', '?', '<', '>', '='},
@@ -814,8 +1150,8 @@ var g = &grammar{
 							},
 							&choiceExpr{
 								alternatives: []any{
-									&ruleIRefExpr{index: 24 /* stmtAssign */},
-									&ruleIRefExpr{index: 30 /* exprSlice */},
+									&ruleIRefExpr{index: 32 /* stmtAssign */},
+									&ruleIRefExpr{index: 38 /* exprSlice */},
 								},
 							},
 						},
@@ -823,9 +1159,9 @@ var g = &grammar{
 					&seqExpr{
 						exprs: []any{
 							&andExpr{
-								expr: &ruleIRefExpr{index: 103 /* subX */},
+								expr: &ruleIRefExpr{index: 140 /* subX */},
 							},
-							&ruleIRefExpr{index: 103 /* subX */},
+							&ruleIRefExpr{index: 140 /* subX */},
 						},
 					},
 				},
@@ -835,9 +1171,9 @@ var g = &grammar{
 			name: "exprRoot",
 			expr: &choiceExpr{
 				alternatives: []any{
-					&ruleIRefExpr{index: 25 /* nestedBoost */},
-					&ruleIRefExpr{index: 24 /* stmtAssign */},
-					&ruleIRefExpr{index: 30 /* exprSlice */},
+					&ruleIRefExpr{index: 33 /* nestedBoost */},
+					&ruleIRefExpr{index: 32 /* stmtAssign */},
+					&ruleIRefExpr{index: 38 /* exprSlice */},
 				},
 			},
 		},
@@ -848,13 +1184,13 @@ var g = &grammar{
 					&seqExpr{
 						exprs: []any{
 							&litMatcher{val: ":", want: "\":\""},
-							&ruleIRefExpr{index: 123 /* sp */},
+							&ruleIRefExpr{index: 165 /* sp */},
 							&choiceExpr{
 								alternatives: []any{
-									&ruleIRefExpr{index: 26 /* exprRoot */},
+									&ruleIRefExpr{index: 34 /* exprRoot */},
 									&actionExpr{
 										run:  (*parser).call_on_step_7,
-										expr: &ruleIRefExpr{index: 123 /* sp */},
+										expr: &ruleIRefExpr{index: 165 /* sp */},
 									},
 								},
 							},
@@ -862,7 +1198,7 @@ var g = &grammar{
 					},
 					&actionExpr{
 						run:  (*parser).call_on_step_9,
-						expr: &ruleIRefExpr{index: 123 /* sp */},
+						expr: &ruleIRefExpr{index: 165 /* sp */},
 					},
 				},
 			},
@@ -872,31 +1208,31 @@ var g = &grammar{
 			expr: &seqExpr{
 				exprs: []any{
 					&litMatcher{val: "[", want: "\"[\""},
-					&ruleIRefExpr{index: 123 /* sp */},
+					&ruleIRefExpr{index: 165 /* sp */},
 					&choiceExpr{
 						alternatives: []any{
-							&ruleIRefExpr{index: 26 /* exprRoot */},
+							&ruleIRefExpr{index: 34 /* exprRoot */},
 							&actionExpr{
 								run:  (*parser).call_on_sliceSuffix_6,
-								expr: &ruleIRefExpr{index: 123 /* sp */},
+								expr: &ruleIRefExpr{index: 165 /* sp */},
 							},
 						},
 					},
 					&litMatcher{val: ":", want: "\":\""},
-					&ruleIRefExpr{index: 123 /* sp */},
+					&ruleIRefExpr{index: 165 /* sp */},
 					&choiceExpr{
 						alternatives: []any{
-							&ruleIRefExpr{index: 26 /* exprRoot */},
+							&ruleIRefExpr{index: 34 /* exprRoot */},
 							&actionExpr{
 								run:  (*parser).call_on_sliceSuffix_12,
-								expr: &ruleIRefExpr{index: 123 /* sp */},
+								expr: &ruleIRefExpr{index: 165 /* sp */},
 							},
 						},
 					},
-					&ruleIRefExpr{index: 27 /* _step */},
-					&ruleIRefExpr{index: 123 /* sp */},
+					&ruleIRefExpr{index: 35 /* _step */},
+					&ruleIRefExpr{index: 165 /* sp */},
 					&litMatcher{val: "]", want: "\"]\""},
-					&ruleIRefExpr{index: 123 /* sp */},
+					&ruleIRefExpr{index: 165 /* sp */},
 				},
 			},
 		},
@@ -906,8 +1242,8 @@ var g = &grammar{
 				run: (*parser).call_onexprSliceType1_1,
 				expr: &seqExpr{
 					exprs: []any{
-						&ruleIRefExpr{index: 34 /* exprTernary */},
-						&ruleIRefExpr{index: 28 /* _sliceSuffix */},
+						&ruleIRefExpr{index: 42 /* exprTernary */},
+						&ruleIRefExpr{index: 36 /* _sliceSuffix */},
 						&notExpr{
 							expr: &litMatcher{val: "=", want: "\"=\""},
 						},
@@ -922,12 +1258,12 @@ var g = &grammar{
 					&seqExpr{
 						exprs: []any{
 							&andExpr{
-								expr: &ruleIRefExpr{index: 29 /* exprSliceType1 */},
+								expr: &ruleIRefExpr{index: 37 /* exprSliceType1 */},
 							},
-							&ruleIRefExpr{index: 29 /* exprSliceType1 */},
+							&ruleIRefExpr{index: 37 /* exprSliceType1 */},
 						},
 					},
-					&ruleIRefExpr{index: 34 /* exprTernary */},
+					&ruleIRefExpr{index: 42 /* exprTernary */},
 				},
 			},
 		},
@@ -939,10 +1275,10 @@ var g = &grammar{
 						run: (*parser).call_onexprValueIfExists_2,
 						expr: &seqExpr{
 							exprs: []any{
-								&ruleIRefExpr{index: 35 /* exprLogicOr */},
-								&ruleIRefExpr{index: 123 /* sp */},
+								&ruleIRefExpr{index: 46 /* exprLogicOr */},
+								&ruleIRefExpr{index: 165 /* sp */},
 								&litMatcher{val: "?", want: "\"?\""},
-								&ruleIRefExpr{index: 123 /* sp */},
+								&ruleIRefExpr{index: 165 /* sp */},
 							},
 						},
 					},
@@ -950,8 +1286,8 @@ var g = &grammar{
 						run: (*parser).call_onexprValueIfExists_8,
 						expr: &seqExpr{
 							exprs: []any{
-								&ruleIRefExpr{index: 35 /* exprLogicOr */},
-								&ruleIRefExpr{index: 123 /* sp */},
+								&ruleIRefExpr{index: 46 /* exprLogicOr */},
+								&ruleIRefExpr{index: 165 /* sp */},
 							},
 						},
 					},
@@ -966,10 +1302,10 @@ var g = &grammar{
 						run: (*parser).call_onexprTernaryType1_2,
 						expr: &seqExpr{
 							exprs: []any{
-								&ruleIRefExpr{index: 35 /* exprLogicOr */},
-								&ruleIRefExpr{index: 123 /* sp */},
+								&ruleIRefExpr{index: 46 /* exprLogicOr */},
+								&ruleIRefExpr{index: 165 /* sp */},
 								&litMatcher{val: "?", want: "\"?\""},
-								&ruleIRefExpr{index: 123 /* sp */},
+								&ruleIRefExpr{index: 165 /* sp */},
 							},
 						},
 					},
@@ -977,10 +1313,10 @@ var g = &grammar{
 						run: (*parser).call_onexprTernaryType1_8,
 						expr: &seqExpr{
 							exprs: []any{
-								&ruleIRefExpr{index: 35 /* exprLogicOr */},
-								&ruleIRefExpr{index: 123 /* sp */},
+								&ruleIRefExpr{index: 46 /* exprLogicOr */},
+								&ruleIRefExpr{index: 165 /* sp */},
 								&litMatcher{val: ":", want: "\":\""},
-								&ruleIRefExpr{index: 123 /* sp */},
+								&ruleIRefExpr{index: 165 /* sp */},
 							},
 						},
 					},
@@ -988,8 +1324,8 @@ var g = &grammar{
 						run: (*parser).call_onexprTernaryType1_14,
 						expr: &seqExpr{
 							exprs: []any{
-								&ruleIRefExpr{index: 35 /* exprLogicOr */},
-								&ruleIRefExpr{index: 123 /* sp */},
+								&ruleIRefExpr{index: 46 /* exprLogicOr */},
+								&ruleIRefExpr{index: 165 /* sp */},
 							},
 						},
 					},
@@ -1002,7 +1338,7 @@ var g = &grammar{
 				exprs: []any{
 					&actionExpr{
 						run:  (*parser).call_onexprTernaryType2_2,
-						expr: &ruleIRefExpr{index: 31 /* exprValueIfExists */},
+						expr: &ruleIRefExpr{index: 39 /* exprValueIfExists */},
 					},
 					&actionExpr{
 						run: (*parser).call_onexprTernaryType2_4,
@@ -1012,8 +1348,8 @@ var g = &grammar{
 								expr: &seqExpr{
 									exprs: []any{
 										&litMatcher{val: ",", want: "\",\""},
-										&ruleIRefExpr{index: 123 /* sp */},
-										&ruleIRefExpr{index: 31 /* exprValueIfExists */},
+										&ruleIRefExpr{index: 165 /* sp */},
+										&ruleIRefExpr{index: 39 /* exprValueIfExists */},
 									},
 								},
 							},
@@ -1029,47 +1365,60 @@ var g = &grammar{
 					&seqExpr{
 						exprs: []any{
 							&andExpr{
-								expr: &ruleIRefExpr{index: 32 /* exprTernaryType1 */},
+								expr: &ruleIRefExpr{index: 40 /* exprTernaryType1 */},
 							},
-							&ruleIRefExpr{index: 32 /* exprTernaryType1 */},
+							&ruleIRefExpr{index: 40 /* exprTernaryType1 */},
 						},
 					},
 					&seqExpr{
 						exprs: []any{
 							&andExpr{
-								expr: &ruleIRefExpr{index: 33 /* exprTernaryType2 */},
+								expr: &ruleIRefExpr{index: 41 /* exprTernaryType2 */},
 							},
-							&ruleIRefExpr{index: 33 /* exprTernaryType2 */},
+							&ruleIRefExpr{index: 41 /* exprTernaryType2 */},
 						},
 					},
-					&ruleIRefExpr{index: 35 /* exprLogicOr */},
+					&ruleIRefExpr{index: 46 /* exprLogicOr */},
 				},
 			},
 		},
 		{
-			name: "exprLogicOr",
+			name: "exprMatch",
 			expr: &seqExpr{
 				exprs: []any{
-					&ruleIRefExpr{index: 36 /* exprLogicAnd */},
-					&zeroOrMoreExpr{
+					&actionExpr{
+						run: (*parser).call_onexprMatch_2,
 						expr: &seqExpr{
 							exprs: []any{
-								&actionExpr{
-									run: (*parser).call_onexprLogicOr_5,
+								&litMatcher{val: "match", want: "\"match\""},
+								&ruleIRefExpr{index: 167 /* sp1x */},
+								&ruleIRefExpr{index: 46 /* exprLogicOr */},
+								&ruleIRefExpr{index: 165 /* sp */},
+								&litMatcher{val: "{", want: "\"{\""},
+								&ruleIRefExpr{index: 165 /* sp */},
+							},
+						},
+					},
+					&actionExpr{
+						run: (*parser).call_onexprMatch_10,
+						expr: &seqExpr{
+							exprs: []any{
+								&ruleIRefExpr{index: 44 /* matchArm */},
+								&zeroOrMoreExpr{
 									expr: &seqExpr{
 										exprs: []any{
-											&ruleIRefExpr{index: 123 /* sp */},
-											&ruleIRefExpr{index: 115 /* logicOr */},
+											&litMatcher{val: ",", want: "\",\""},
+											&ruleIRefExpr{index: 165 /* sp */},
+											&ruleIRefExpr{index: 44 /* matchArm */},
 										},
 									},
 								},
-								&actionExpr{
-									run:  (*parser).call_onexprLogicOr_9,
-									expr: &ruleIRefExpr{index: 36 /* exprLogicAnd */},
-								},
-								&codeExpr{
-									run: (*parser).call_onexprLogicOr_11,
+								&zeroOrOneExpr{
+									expr: &litMatcher{val: ",", want: "\",\""},
 								},
+								&ruleIRefExpr{index: 165 /* sp */},
+								&litMatcher{val: "}", want: "\"}\""},
+								&ruleIRefExpr{index: 165 /* sp */},
 							},
 						},
 					},
@@ -1077,19 +1426,146 @@ var g = &grammar{
 			},
 		},
 		{
-			name: "exprLogicAnd",
-			expr: &seqExpr{
-				exprs: []any{
-					&ruleIRefExpr{index: 37 /* exprBitwiseOr */},
-					&zeroOrMoreExpr{
-						expr: &actionExpr{
-							run: (*parser).call_onexprLogicAnd_4,
-							expr: &seqExpr{
-								exprs: []any{
-									&ruleIRefExpr{index: 123 /* sp */},
-									&ruleIRefExpr{index: 116 /* logicAnd */},
-									&ruleIRefExpr{index: 37 /* exprBitwiseOr */},
-								},
+			name: "matchArm",
+			expr: &choiceExpr{
+				alternatives: []any{
+					&seqExpr{
+						exprs: []any{
+							&actionExpr{
+								run: (*parser).call_onmatchArm_3,
+								expr: &seqExpr{
+									exprs: []any{
+										&litMatcher{val: "_", want: "\"_\""},
+										&notExpr{
+											expr: &ruleIRefExpr{index: 138 /* xidContinue */},
+										},
+										&ruleIRefExpr{index: 165 /* sp */},
+										&litMatcher{val: "=>", want: "\"=>\""},
+										&ruleIRefExpr{index: 165 /* sp */},
+									},
+								},
+							},
+							&actionExpr{
+								run:  (*parser).call_onmatchArm_11,
+								expr: &ruleIRefExpr{index: 45 /* matchBody */},
+							},
+						},
+					},
+					&seqExpr{
+						exprs: []any{
+							&codeExpr{
+								run: (*parser).call_onmatchArm_14,
+							},
+							&actionExpr{
+								run: (*parser).call_onmatchArm_15,
+								expr: &seqExpr{
+									exprs: []any{
+										&ruleIRefExpr{index: 54 /* exprAdditive */},
+										&ruleIRefExpr{index: 165 /* sp */},
+										&choiceExpr{
+											alternatives: []any{
+												&actionExpr{
+													run: (*parser).call_onmatchArm_20,
+													expr: &seqExpr{
+														exprs: []any{
+															&litMatcher{val: "..", want: "\"..\""},
+															&ruleIRefExpr{index: 165 /* sp */},
+															&ruleIRefExpr{index: 54 /* exprAdditive */},
+															&ruleIRefExpr{index: 165 /* sp */},
+														},
+													},
+												},
+												&codeExpr{
+													run: (*parser).call_onmatchArm_26,
+												},
+											},
+										},
+										&litMatcher{val: "=>", want: "\"=>\""},
+										&ruleIRefExpr{index: 165 /* sp */},
+									},
+								},
+							},
+							&actionExpr{
+								run:  (*parser).call_onmatchArm_29,
+								expr: &ruleIRefExpr{index: 45 /* matchBody */},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "matchBody",
+			expr: &seqExpr{
+				exprs: []any{
+					&choiceExpr{
+						alternatives: []any{
+							&seqExpr{
+								exprs: []any{
+									&andExpr{
+										expr: &ruleIRefExpr{index: 40 /* exprTernaryType1 */},
+									},
+									&ruleIRefExpr{index: 40 /* exprTernaryType1 */},
+								},
+							},
+							&ruleIRefExpr{index: 46 /* exprLogicOr */},
+						},
+					},
+					&ruleIRefExpr{index: 165 /* sp */},
+				},
+			},
+		},
+		{
+			name: "exprLogicOr",
+			expr: &seqExpr{
+				exprs: []any{
+					&ruleIRefExpr{index: 47 /* exprLogicAnd */},
+					&zeroOrMoreExpr{
+						expr: &seqExpr{
+							exprs: []any{
+								&actionExpr{
+									run: (*parser).call_onexprLogicOr_5,
+									expr: &seqExpr{
+										exprs: []any{
+											&ruleIRefExpr{index: 165 /* sp */},
+											&ruleIRefExpr{index: 155 /* logicOr */},
+										},
+									},
+								},
+								&actionExpr{
+									run:  (*parser).call_onexprLogicOr_9,
+									expr: &ruleIRefExpr{index: 47 /* exprLogicAnd */},
+								},
+								&codeExpr{
+									run: (*parser).call_onexprLogicOr_11,
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "exprLogicAnd",
+			expr: &seqExpr{
+				exprs: []any{
+					&ruleIRefExpr{index: 48 /* exprBitwiseOr */},
+					&zeroOrMoreExpr{
+						expr: &seqExpr{
+							exprs: []any{
+								&actionExpr{
+									run: (*parser).call_onexprLogicAnd_5,
+									expr: &seqExpr{
+										exprs: []any{
+											&ruleIRefExpr{index: 165 /* sp */},
+											&ruleIRefExpr{index: 156 /* logicAnd */},
+										},
+									},
+								},
+								&actionExpr{
+									run:  (*parser).call_onexprLogicAnd_9,
+									expr: &ruleIRefExpr{index: 48 /* exprBitwiseOr */},
+								},
 							},
 						},
 					},
@@ -1103,20 +1579,20 @@ var g = &grammar{
 					&seqExpr{
 						exprs: []any{
 							&andCodeExpr{run: (*parser).call_onexprBitwiseOr_3},
-							&ruleIRefExpr{index: 39 /* exprCompare */},
+							&ruleIRefExpr{index: 51 /* exprCompare */},
 						},
 					},
 					&seqExpr{
 						exprs: []any{
-							&ruleIRefExpr{index: 38 /* exprBitwiseAnd */},
+							&ruleIRefExpr{index: 49 /* exprBitwiseXor */},
 							&zeroOrMoreExpr{
 								expr: &actionExpr{
 									run: (*parser).call_onexprBitwiseOr_8,
 									expr: &seqExpr{
 										exprs: []any{
-											&ruleIRefExpr{index: 123 /* sp */},
-											&ruleIRefExpr{index: 113 /* bitwiseOr */},
-											&ruleIRefExpr{index: 38 /* exprBitwiseAnd */},
+											&ruleIRefExpr{index: 165 /* sp */},
+											&ruleIRefExpr{index: 150 /* bitwiseOr */},
+											&ruleIRefExpr{index: 49 /* exprBitwiseXor */},
 										},
 									},
 								},
@@ -1126,19 +1602,40 @@ var g = &grammar{
 				},
 			},
 		},
+		{
+			name: "exprBitwiseXor",
+			expr: &seqExpr{
+				exprs: []any{
+					&ruleIRefExpr{index: 50 /* exprBitwiseAnd */},
+					&zeroOrMoreExpr{
+						expr: &actionExpr{
+							run: (*parser).call_onexprBitwiseXor_4,
+							expr: &seqExpr{
+								exprs: []any{
+									&andCodeExpr{run: (*parser).call_onexprBitwiseXor_6},
+									&ruleIRefExpr{index: 165 /* sp */},
+									&ruleIRefExpr{index: 152 /* bitwiseXor */},
+									&ruleIRefExpr{index: 50 /* exprBitwiseAnd */},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
 		{
 			name: "exprBitwiseAnd",
 			expr: &seqExpr{
 				exprs: []any{
-					&ruleIRefExpr{index: 39 /* exprCompare */},
+					&ruleIRefExpr{index: 51 /* exprCompare */},
 					&zeroOrMoreExpr{
 						expr: &actionExpr{
 							run: (*parser).call_onexprBitwiseAnd_4,
 							expr: &seqExpr{
 								exprs: []any{
-									&ruleIRefExpr{index: 123 /* sp */},
-									&ruleIRefExpr{index: 114 /* bitwiseAnd */},
-									&ruleIRefExpr{index: 39 /* exprCompare */},
+									&ruleIRefExpr{index: 165 /* sp */},
+									&ruleIRefExpr{index: 151 /* bitwiseAnd */},
+									&ruleIRefExpr{index: 51 /* exprCompare */},
 								},
 							},
 						},
@@ -1150,19 +1647,19 @@ var g = &grammar{
 			name: "exprCompare",
 			expr: &seqExpr{
 				exprs: []any{
-					&ruleIRefExpr{index: 40 /* exprAdditive */},
+					&ruleIRefExpr{index: 52 /* exprRange */},
 					&zeroOrMoreExpr{
 						expr: &seqExpr{
 							exprs: []any{
-								&ruleIRefExpr{index: 123 /* sp */},
+								&ruleIRefExpr{index: 165 /* sp */},
 								&choiceExpr{
 									alternatives: []any{
 										&actionExpr{
 											run: (*parser).call_onexprCompare_7,
 											expr: &seqExpr{
 												exprs: []any{
-													&ruleIRefExpr{index: 117 /* lt */},
-													&ruleIRefExpr{index: 40 /* exprAdditive */},
+													&ruleIRefExpr{index: 158 /* lt */},
+													&ruleIRefExpr{index: 52 /* exprRange */},
 												},
 											},
 										},
@@ -1170,8 +1667,8 @@ var g = &grammar{
 											run: (*parser).call_onexprCompare_11,
 											expr: &seqExpr{
 												exprs: []any{
-													&ruleIRefExpr{index: 119 /* le */},
-													&ruleIRefExpr{index: 40 /* exprAdditive */},
+													&ruleIRefExpr{index: 160 /* le */},
+													&ruleIRefExpr{index: 52 /* exprRange */},
 												},
 											},
 										},
@@ -1179,8 +1676,8 @@ var g = &grammar{
 											run: (*parser).call_onexprCompare_15,
 											expr: &seqExpr{
 												exprs: []any{
-													&ruleIRefExpr{index: 121 /* eq */},
-													&ruleIRefExpr{index: 40 /* exprAdditive */},
+													&ruleIRefExpr{index: 163 /* eq */},
+													&ruleIRefExpr{index: 52 /* exprRange */},
 												},
 											},
 										},
@@ -1188,8 +1685,8 @@ var g = &grammar{
 											run: (*parser).call_onexprCompare_19,
 											expr: &seqExpr{
 												exprs: []any{
-													&ruleIRefExpr{index: 122 /* ne */},
-													&ruleIRefExpr{index: 40 /* exprAdditive */},
+													&ruleIRefExpr{index: 164 /* ne */},
+													&ruleIRefExpr{index: 52 /* exprRange */},
 												},
 											},
 										},
@@ -1197,8 +1694,8 @@ var g = &grammar{
 											run: (*parser).call_onexprCompare_23,
 											expr: &seqExpr{
 												exprs: []any{
-													&ruleIRefExpr{index: 120 /* ge */},
-													&ruleIRefExpr{index: 40 /* exprAdditive */},
+													&ruleIRefExpr{index: 161 /* ge */},
+													&ruleIRefExpr{index: 52 /* exprRange */},
 												},
 											},
 										},
@@ -1206,8 +1703,17 @@ var g = &grammar{
 											run: (*parser).call_onexprCompare_27,
 											expr: &seqExpr{
 												exprs: []any{
-													&ruleIRefExpr{index: 118 /* gt */},
-													&ruleIRefExpr{index: 40 /* exprAdditive */},
+													&ruleIRefExpr{index: 159 /* gt */},
+													&ruleIRefExpr{index: 52 /* exprRange */},
+												},
+											},
+										},
+										&actionExpr{
+											run: (*parser).call_onexprCompare_31,
+											expr: &seqExpr{
+												exprs: []any{
+													&ruleIRefExpr{index: 162 /* inOp */},
+													&ruleIRefExpr{index: 52 /* exprRange */},
 												},
 											},
 										},
@@ -1220,31 +1726,56 @@ var g = &grammar{
 			},
 		},
 		{
-			name: "exprAdditive",
+			name: "exprRange",
+			expr: &seqExpr{
+				exprs: []any{
+					&ruleIRefExpr{index: 53 /* exprShift */},
+					&zeroOrOneExpr{
+						expr: &actionExpr{
+							run: (*parser).call_onexprRange_4,
+							expr: &seqExpr{
+								exprs: []any{
+									&ruleIRefExpr{index: 165 /* sp */},
+									&litMatcher{val: "..", want: "\"..\""},
+									&notExpr{
+										expr: &litMatcher{val: ".", want: "\".\""},
+									},
+									&ruleIRefExpr{index: 165 /* sp */},
+									&ruleIRefExpr{index: 53 /* exprShift */},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "exprShift",
 			expr: &seqExpr{
 				exprs: []any{
-					&ruleIRefExpr{index: 41 /* exprMultiplicative */},
+					&ruleIRefExpr{index: 54 /* exprAdditive */},
 					&zeroOrMoreExpr{
 						expr: &seqExpr{
 							exprs: []any{
-								&ruleIRefExpr{index: 123 /* sp */},
+								&andCodeExpr{run: (*parser).call_onexprShift_5},
+								&ruleIRefExpr{index: 165 /* sp */},
 								&choiceExpr{
 									alternatives: []any{
 										&actionExpr{
-											run: (*parser).call_onexprAdditive_7,
+											run: (*parser).call_onexprShift_8,
 											expr: &seqExpr{
 												exprs: []any{
-													&ruleIRefExpr{index: 106 /* add */},
-													&ruleIRefExpr{index: 41 /* exprMultiplicative */},
+													&ruleIRefExpr{index: 153 /* shiftLeft */},
+													&ruleIRefExpr{index: 54 /* exprAdditive */},
 												},
 											},
 										},
 										&actionExpr{
-											run: (*parser).call_onexprAdditive_11,
+											run: (*parser).call_onexprShift_12,
 											expr: &seqExpr{
 												exprs: []any{
-													&ruleIRefExpr{index: 107 /* minus */},
-													&ruleIRefExpr{index: 41 /* exprMultiplicative */},
+													&ruleIRefExpr{index: 154 /* shiftRight */},
+													&ruleIRefExpr{index: 54 /* exprAdditive */},
 												},
 											},
 										},
@@ -1257,42 +1788,95 @@ var g = &grammar{
 			},
 		},
 		{
-			name: "exprMultiplicative",
+			name: "exprAdditive",
 			expr: &seqExpr{
 				exprs: []any{
-					&ruleIRefExpr{index: 42 /* exprNullCoalescing */},
+					&ruleIRefExpr{index: 55 /* exprMultiplicative */},
 					&zeroOrMoreExpr{
 						expr: &seqExpr{
 							exprs: []any{
-								&ruleIRefExpr{index: 123 /* sp */},
+								&ruleIRefExpr{index: 165 /* sp */},
 								&choiceExpr{
 									alternatives: []any{
 										&actionExpr{
-											run: (*parser).call_onexprMultiplicative_7,
+											run: (*parser).call_onexprAdditive_7,
 											expr: &seqExpr{
 												exprs: []any{
-													&ruleIRefExpr{index: 108 /* multiply */},
-													&ruleIRefExpr{index: 43 /* exprExp */},
+													&ruleIRefExpr{index: 143 /* add */},
+													&ruleIRefExpr{index: 55 /* exprMultiplicative */},
 												},
 											},
 										},
 										&actionExpr{
-											run: (*parser).call_onexprMultiplicative_11,
+											run: (*parser).call_onexprAdditive_11,
 											expr: &seqExpr{
 												exprs: []any{
-													&ruleIRefExpr{index: 109 /* divide */},
-													&ruleIRefExpr{index: 43 /* exprExp */},
+													&ruleIRefExpr{index: 144 /* minus */},
+													&ruleIRefExpr{index: 55 /* exprMultiplicative */},
 												},
 											},
 										},
-										&actionExpr{
-											run: (*parser).call_onexprMultiplicative_15,
-											expr: &seqExpr{
-												exprs: []any{
-													&ruleIRefExpr{index: 110 /* modulus */},
-													&ruleIRefExpr{index: 43 /* exprExp */},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "exprMultiplicative",
+			expr: &seqExpr{
+				exprs: []any{
+					&ruleIRefExpr{index: 56 /* exprNullCoalescing */},
+					&zeroOrMoreExpr{
+						expr: &choiceExpr{
+							alternatives: []any{
+								&seqExpr{
+									exprs: []any{
+										&ruleIRefExpr{index: 165 /* sp */},
+										&choiceExpr{
+											alternatives: []any{
+												&actionExpr{
+													run: (*parser).call_onexprMultiplicative_8,
+													expr: &seqExpr{
+														exprs: []any{
+															&ruleIRefExpr{index: 145 /* multiply */},
+															&ruleIRefExpr{index: 57 /* exprExp */},
+														},
+													},
+												},
+												&actionExpr{
+													run: (*parser).call_onexprMultiplicative_12,
+													expr: &seqExpr{
+														exprs: []any{
+															&ruleIRefExpr{index: 146 /* divide */},
+															&ruleIRefExpr{index: 57 /* exprExp */},
+														},
+													},
 												},
+												&actionExpr{
+													run: (*parser).call_onexprMultiplicative_16,
+													expr: &seqExpr{
+														exprs: []any{
+															&ruleIRefExpr{index: 147 /* modulus */},
+															&ruleIRefExpr{index: 57 /* exprExp */},
+														},
+													},
+												},
+											},
+										},
+									},
+								},
+								&actionExpr{
+									run: (*parser).call_onexprMultiplicative_20,
+									expr: &seqExpr{
+										exprs: []any{
+											&andCodeExpr{run: (*parser).call_onexprMultiplicative_22},
+											&andExpr{
+												expr: &ruleIRefExpr{index: 141 /* parenOpen */},
 											},
+											&ruleIRefExpr{index: 57 /* exprExp */},
 										},
 									},
 								},
@@ -1306,15 +1890,22 @@ var g = &grammar{
 			name: "exprNullCoalescing",
 			expr: &seqExpr{
 				exprs: []any{
-					&ruleIRefExpr{index: 43 /* exprExp */},
+					&ruleIRefExpr{index: 57 /* exprExp */},
 					&zeroOrMoreExpr{
-						expr: &actionExpr{
-							run: (*parser).call_onexprNullCoalescing_4,
-							expr: &seqExpr{
-								exprs: []any{
-									&ruleIRefExpr{index: 123 /* sp */},
-									&ruleIRefExpr{index: 112 /* nullCoalescing */},
-									&ruleIRefExpr{index: 43 /* exprExp */},
+						expr: &seqExpr{
+							exprs: []any{
+								&actionExpr{
+									run: (*parser).call_onexprNullCoalescing_5,
+									expr: &seqExpr{
+										exprs: []any{
+											&ruleIRefExpr{index: 165 /* sp */},
+											&ruleIRefExpr{index: 149 /* nullCoalescing */},
+										},
+									},
+								},
+								&actionExpr{
+									run:  (*parser).call_onexprNullCoalescing_9,
+									expr: &ruleIRefExpr{index: 57 /* exprExp */},
 								},
 							},
 						},
@@ -1326,15 +1917,15 @@ var g = &grammar{
 			name: "exprExp",
 			expr: &seqExpr{
 				exprs: []any{
-					&ruleIRefExpr{index: 44 /* exprUnaryNeg */},
+					&ruleIRefExpr{index: 58 /* exprUnaryNeg */},
 					&zeroOrMoreExpr{
 						expr: &actionExpr{
 							run: (*parser).call_onexprExp_4,
 							expr: &seqExpr{
 								exprs: []any{
-									&ruleIRefExpr{index: 123 /* sp */},
-									&ruleIRefExpr{index: 111 /* exponentiation */},
-									&ruleIRefExpr{index: 44 /* exprUnaryNeg */},
+									&ruleIRefExpr{index: 165 /* sp */},
+									&ruleIRefExpr{index: 148 /* exponentiation */},
+									&ruleIRefExpr{index: 58 /* exprUnaryNeg */},
 								},
 							},
 						},
@@ -1350,12 +1941,21 @@ var g = &grammar{
 						run: (*parser).call_onexprUnaryNeg_2,
 						expr: &seqExpr{
 							exprs: []any{
-								&ruleIRefExpr{index: 107 /* minus */},
-								&ruleIRefExpr{index: 69 /* exprDice */},
+								&ruleIRefExpr{index: 144 /* minus */},
+								&ruleIRefExpr{index: 95 /* exprDice */},
+							},
+						},
+					},
+					&actionExpr{
+						run: (*parser).call_onexprUnaryNeg_6,
+						expr: &seqExpr{
+							exprs: []any{
+								&ruleIRefExpr{index: 157 /* logicNot */},
+								&ruleIRefExpr{index: 58 /* exprUnaryNeg */},
 							},
 						},
 					},
-					&ruleIRefExpr{index: 45 /* exprUnaryPos */},
+					&ruleIRefExpr{index: 59 /* exprUnaryPos */},
 				},
 			},
 		},
@@ -1367,12 +1967,12 @@ var g = &grammar{
 						run: (*parser).call_onexprUnaryPos_2,
 						expr: &seqExpr{
 							exprs: []any{
-								&ruleIRefExpr{index: 106 /* add */},
-								&ruleIRefExpr{index: 69 /* exprDice */},
+								&ruleIRefExpr{index: 143 /* add */},
+								&ruleIRefExpr{index: 95 /* exprDice */},
 							},
 						},
 					},
-					&ruleIRefExpr{index: 69 /* exprDice */},
+					&ruleIRefExpr{index: 95 /* exprDice */},
 				},
 			},
 		},
@@ -1380,8 +1980,8 @@ var g = &grammar{
 			name: "nos",
 			expr: &choiceExpr{
 				alternatives: []any{
-					&ruleIRefExpr{index: 82 /* number */},
-					&ruleIRefExpr{index: 102 /* sub */},
+					&ruleIRefExpr{index: 112 /* number */},
+					&ruleIRefExpr{index: 139 /* sub */},
 				},
 			},
 		},
@@ -1398,62 +1998,184 @@ var g = &grammar{
 			},
 		},
 		{
-			name: "_diceMod",
+			name: "_kwKL",
 			expr: &choiceExpr{
 				alternatives: []any{
-					&actionExpr{
-						run: (*parser).call_on_diceMod_2,
-						expr: &seqExpr{
-							exprs: []any{
-								&choiceExpr{
-									alternatives: []any{
-										&litMatcher{val: "kl", want: "\"kl\""},
-										&charClassMatcher{
-											val:   "[qQ]",
-											chars: []rune{'q', 'Q'},
-										},
-									},
-								},
-								&ruleIRefExpr{index: 46 /* nos */},
-							},
-						},
+					&litMatcher{
+						val:        "kl",
+						ignoreCase: true,
+						want:       "\"kl\"i",
 					},
 					&actionExpr{
-						run: (*parser).call_on_diceMod_8,
-						expr: &choiceExpr{
-							alternatives: []any{
-								&litMatcher{val: "kl", want: "\"kl\""},
-								&charClassMatcher{
-									val:   "[qQ]",
-									chars: []rune{'q', 'Q'},
-								},
-							},
-						},
+						run:  (*parser).call_on_kwKL_3,
+						expr: &andCodeExpr{run: (*parser).call_on_kwKL_4},
 					},
-					&actionExpr{
-						run: (*parser).call_on_diceMod_12,
-						expr: &seqExpr{
-							exprs: []any{
-								&choiceExpr{
-									alternatives: []any{
-										&litMatcher{val: "kh", want: "\"kh\""},
-										&charClassMatcher{
-											val:   "[kK]",
-											chars: []rune{'k', 'K'},
-										},
-									},
-								},
-								&ruleIRefExpr{index: 46 /* nos */},
-							},
-						},
+				},
+			},
+		},
+		{
+			name: "_kwKH",
+			expr: &choiceExpr{
+				alternatives: []any{
+					&litMatcher{
+						val:        "kh",
+						ignoreCase: true,
+						want:       "\"kh\"i",
 					},
 					&actionExpr{
-						run: (*parser).call_on_diceMod_18,
-						expr: &choiceExpr{
-							alternatives: []any{
-								&litMatcher{val: "kh", want: "\"kh\""},
-								&charClassMatcher{
-									val:   "[kK]",
+						run:  (*parser).call_on_kwKH_3,
+						expr: &andCodeExpr{run: (*parser).call_on_kwKH_4},
+					},
+				},
+			},
+		},
+		{
+			name: "_kwDH",
+			expr: &choiceExpr{
+				alternatives: []any{
+					&litMatcher{
+						val:        "dh",
+						ignoreCase: true,
+						want:       "\"dh\"i",
+					},
+					&actionExpr{
+						run:  (*parser).call_on_kwDH_3,
+						expr: &andCodeExpr{run: (*parser).call_on_kwDH_4},
+					},
+				},
+			},
+		},
+		{
+			name: "_kwDL",
+			expr: &choiceExpr{
+				alternatives: []any{
+					&litMatcher{
+						val:        "dl",
+						ignoreCase: true,
+						want:       "\"dl\"i",
+					},
+					&actionExpr{
+						run:  (*parser).call_on_kwDL_3,
+						expr: &andCodeExpr{run: (*parser).call_on_kwDL_4},
+					},
+				},
+			},
+		},
+		{
+			name: "_kwMin",
+			expr: &choiceExpr{
+				alternatives: []any{
+					&litMatcher{
+						val:        "min",
+						ignoreCase: true,
+						want:       "\"min\"i",
+					},
+					&actionExpr{
+						run:  (*parser).call_on_kwMin_3,
+						expr: &andCodeExpr{run: (*parser).call_on_kwMin_4},
+					},
+				},
+			},
+		},
+		{
+			name: "_kwMax",
+			expr: &choiceExpr{
+				alternatives: []any{
+					&litMatcher{
+						val:        "max",
+						ignoreCase: true,
+						want:       "\"max\"i",
+					},
+					&actionExpr{
+						run:  (*parser).call_on_kwMax_3,
+						expr: &andCodeExpr{run: (*parser).call_on_kwMax_4},
+					},
+				},
+			},
+		},
+		{
+			name: "_kwAdv",
+			expr: &choiceExpr{
+				alternatives: []any{
+					&litMatcher{val: "优势", want: "\"优势\""},
+					&litMatcher{val: "優勢", want: "\"優勢\""},
+					&actionExpr{
+						run:  (*parser).call_on_kwAdv_4,
+						expr: &andCodeExpr{run: (*parser).call_on_kwAdv_5},
+					},
+				},
+			},
+		},
+		{
+			name: "_kwDisadv",
+			expr: &choiceExpr{
+				alternatives: []any{
+					&litMatcher{val: "劣势", want: "\"劣势\""},
+					&litMatcher{val: "劣勢", want: "\"劣勢\""},
+					&actionExpr{
+						run:  (*parser).call_on_kwDisadv_4,
+						expr: &andCodeExpr{run: (*parser).call_on_kwDisadv_5},
+					},
+				},
+			},
+		},
+		{
+			name: "_diceMod",
+			expr: &choiceExpr{
+				alternatives: []any{
+					&actionExpr{
+						run: (*parser).call_on_diceMod_2,
+						expr: &seqExpr{
+							exprs: []any{
+								&choiceExpr{
+									alternatives: []any{
+										&ruleIRefExpr{index: 63 /* _kwKL */},
+										&charClassMatcher{
+											val:   "[qQ]",
+											chars: []rune{'q', 'Q'},
+										},
+									},
+								},
+								&ruleIRefExpr{index: 60 /* nos */},
+							},
+						},
+					},
+					&actionExpr{
+						run: (*parser).call_on_diceMod_8,
+						expr: &choiceExpr{
+							alternatives: []any{
+								&ruleIRefExpr{index: 63 /* _kwKL */},
+								&charClassMatcher{
+									val:   "[qQ]",
+									chars: []rune{'q', 'Q'},
+								},
+							},
+						},
+					},
+					&actionExpr{
+						run: (*parser).call_on_diceMod_12,
+						expr: &seqExpr{
+							exprs: []any{
+								&choiceExpr{
+									alternatives: []any{
+										&ruleIRefExpr{index: 64 /* _kwKH */},
+										&charClassMatcher{
+											val:   "[kK]",
+											chars: []rune{'k', 'K'},
+										},
+									},
+								},
+								&ruleIRefExpr{index: 60 /* nos */},
+							},
+						},
+					},
+					&actionExpr{
+						run: (*parser).call_on_diceMod_18,
+						expr: &choiceExpr{
+							alternatives: []any{
+								&ruleIRefExpr{index: 64 /* _kwKH */},
+								&charClassMatcher{
+									val:   "[kK]",
 									chars: []rune{'k', 'K'},
 								},
 							},
@@ -1463,27 +2185,27 @@ var g = &grammar{
 						run: (*parser).call_on_diceMod_22,
 						expr: &seqExpr{
 							exprs: []any{
-								&litMatcher{val: "dh", want: "\"dh\""},
-								&ruleIRefExpr{index: 46 /* nos */},
+								&ruleIRefExpr{index: 65 /* _kwDH */},
+								&ruleIRefExpr{index: 60 /* nos */},
 							},
 						},
 					},
 					&actionExpr{
 						run:  (*parser).call_on_diceMod_26,
-						expr: &litMatcher{val: "dh", want: "\"dh\""},
+						expr: &ruleIRefExpr{index: 65 /* _kwDH */},
 					},
 					&actionExpr{
 						run: (*parser).call_on_diceMod_28,
 						expr: &seqExpr{
 							exprs: []any{
-								&litMatcher{val: "dl", want: "\"dl\""},
-								&ruleIRefExpr{index: 46 /* nos */},
+								&ruleIRefExpr{index: 66 /* _kwDL */},
+								&ruleIRefExpr{index: 60 /* nos */},
 							},
 						},
 					},
 					&actionExpr{
 						run:  (*parser).call_on_diceMod_32,
-						expr: &litMatcher{val: "dl", want: "\"dl\""},
+						expr: &ruleIRefExpr{index: 66 /* _kwDL */},
 					},
 				},
 			},
@@ -1496,8 +2218,8 @@ var g = &grammar{
 						run: (*parser).call_on_diceModType2_2,
 						expr: &seqExpr{
 							exprs: []any{
-								&litMatcher{val: "min", want: "\"min\""},
-								&ruleIRefExpr{index: 46 /* nos */},
+								&ruleIRefExpr{index: 67 /* _kwMin */},
+								&ruleIRefExpr{index: 60 /* nos */},
 							},
 						},
 					},
@@ -1505,35 +2227,39 @@ var g = &grammar{
 						run: (*parser).call_on_diceModType2_6,
 						expr: &seqExpr{
 							exprs: []any{
-								&litMatcher{val: "max", want: "\"max\""},
-								&ruleIRefExpr{index: 46 /* nos */},
+								&ruleIRefExpr{index: 68 /* _kwMax */},
+								&ruleIRefExpr{index: 60 /* nos */},
 							},
 						},
 					},
 				},
 			},
 		},
+		{
+			name: "_diceExplode",
+			expr: &actionExpr{
+				run: (*parser).call_on_diceExplode_1,
+				expr: &seqExpr{
+					exprs: []any{
+						&litMatcher{val: "!", want: "\"!\""},
+						&notExpr{
+							expr: &litMatcher{val: "=", want: "\"=\""},
+						},
+					},
+				},
+			},
+		},
 		{
 			name: "_dicePearMod",
 			expr: &choiceExpr{
 				alternatives: []any{
 					&actionExpr{
-						run: (*parser).call_on_dicePearMod_2,
-						expr: &choiceExpr{
-							alternatives: []any{
-								&litMatcher{val: "优势", want: "\"优势\""},
-								&litMatcher{val: "優勢", want: "\"優勢\""},
-							},
-						},
+						run:  (*parser).call_on_dicePearMod_2,
+						expr: &ruleIRefExpr{index: 69 /* _kwAdv */},
 					},
 					&actionExpr{
-						run: (*parser).call_on_dicePearMod_6,
-						expr: &choiceExpr{
-							alternatives: []any{
-								&litMatcher{val: "劣势", want: "\"劣势\""},
-								&litMatcher{val: "劣勢", want: "\"劣勢\""},
-							},
-						},
+						run:  (*parser).call_on_dicePearMod_4,
+						expr: &ruleIRefExpr{index: 70 /* _kwDisadv */},
 					},
 				},
 			},
@@ -1542,12 +2268,12 @@ var g = &grammar{
 			name: "_diceType1",
 			expr: &seqExpr{
 				exprs: []any{
-					&ruleIRefExpr{index: 46 /* nos */},
+					&ruleIRefExpr{index: 60 /* nos */},
 					&charClassMatcher{
 						val:   "[dD]",
 						chars: []rune{'d', 'D'},
 					},
-					&ruleIRefExpr{index: 46 /* nos */},
+					&ruleIRefExpr{index: 79 /* _diceSidesType */},
 				},
 			},
 		},
@@ -1559,7 +2285,7 @@ var g = &grammar{
 						val:   "[dD]",
 						chars: []rune{'d', 'D'},
 					},
-					&ruleIRefExpr{index: 46 /* nos */},
+					&ruleIRefExpr{index: 79 /* _diceSidesType */},
 				},
 			},
 		},
@@ -1567,7 +2293,7 @@ var g = &grammar{
 			name: "_diceType3",
 			expr: &seqExpr{
 				exprs: []any{
-					&ruleIRefExpr{index: 46 /* nos */},
+					&ruleIRefExpr{index: 60 /* nos */},
 					&charClassMatcher{
 						val:   "[dD]",
 						chars: []rune{'d', 'D'},
@@ -1585,12 +2311,100 @@ var g = &grammar{
 					},
 					&choiceExpr{
 						alternatives: []any{
-							&litMatcher{val: "优势", want: "\"优势\""},
-							&litMatcher{val: "優勢", want: "\"優勢\""},
-							&litMatcher{val: "劣势", want: "\"劣势\""},
-							&litMatcher{val: "劣勢", want: "\"劣勢\""},
+							&ruleIRefExpr{index: 69 /* _kwAdv */},
+							&ruleIRefExpr{index: 70 /* _kwDisadv */},
+							&notExpr{
+								expr: &ruleIRefExpr{index: 137 /* xidStart */},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "_diceSidesType",
+			expr: &choiceExpr{
+				alternatives: []any{
+					&ruleIRefExpr{index: 60 /* nos */},
+					&litMatcher{val: "[", want: "\"[\""},
+					&seqExpr{
+						exprs: []any{
+							&andCodeExpr{run: (*parser).call_on_diceSidesType_4},
+							&litMatcher{val: "%", want: "\"%\""},
 							&notExpr{
-								expr: &ruleIRefExpr{index: 100 /* xidStart */},
+								expr: &seqExpr{
+									exprs: []any{
+										&ruleIRefExpr{index: 165 /* sp */},
+										&choiceExpr{
+											alternatives: []any{
+												&charClassMatcher{
+													val:    "[0-9(]",
+													chars:  []rune{'('},
+													ranges: []rune{'0', '9'},
+												},
+												&ruleIRefExpr{index: 137 /* xidStart */},
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name:      "_diceSides",
+			varExists: true,
+			expr: &choiceExpr{
+				alternatives: []any{
+					&actionExpr{
+						run: (*parser).call_on_diceSides_2,
+						expr: &labeledExpr{
+							label:       "sides",
+							expr:        &ruleIRefExpr{index: 60 /* nos */},
+							textCapture: true,
+						},
+					},
+					&seqExpr{
+						exprs: []any{
+							&andExpr{
+								expr: &ruleIRefExpr{index: 105 /* value_array_range */},
+							},
+							&ruleIRefExpr{index: 105 /* value_array_range */},
+						},
+					},
+					&seqExpr{
+						exprs: []any{
+							&andExpr{
+								expr: &ruleIRefExpr{index: 107 /* value_array */},
+							},
+							&ruleIRefExpr{index: 107 /* value_array */},
+						},
+					},
+					&actionExpr{
+						run: (*parser).call_on_diceSides_5,
+						expr: &seqExpr{
+							exprs: []any{
+								&andCodeExpr{run: (*parser).call_on_diceSides_7},
+								&litMatcher{val: "%", want: "\"%\""},
+								&notExpr{
+									expr: &seqExpr{
+										exprs: []any{
+											&ruleIRefExpr{index: 165 /* sp */},
+											&choiceExpr{
+												alternatives: []any{
+													&charClassMatcher{
+														val:    "[0-9(]",
+														chars:  []rune{'('},
+														ranges: []rune{'0', '9'},
+													},
+													&ruleIRefExpr{index: 137 /* xidStart */},
+												},
+											},
+										},
+									},
+								},
 							},
 						},
 					},
@@ -1610,12 +2424,15 @@ var g = &grammar{
 					},
 					&seqExpr{
 						exprs: []any{
-							&ruleIRefExpr{index: 46 /* nos */},
+							&ruleIRefExpr{index: 80 /* _diceSides */},
+							&zeroOrOneExpr{
+								expr: &ruleIRefExpr{index: 73 /* _diceExplode */},
+							},
 							&zeroOrOneExpr{
-								expr: &ruleIRefExpr{index: 49 /* _diceMod */},
+								expr: &ruleIRefExpr{index: 71 /* _diceMod */},
 							},
 							&zeroOrOneExpr{
-								expr: &ruleIRefExpr{index: 50 /* _diceModType2 */},
+								expr: &ruleIRefExpr{index: 72 /* _diceModType2 */},
 							},
 						},
 					},
@@ -1635,17 +2452,20 @@ var g = &grammar{
 					},
 					&seqExpr{
 						exprs: []any{
-							&ruleIRefExpr{index: 46 /* nos */},
+							&ruleIRefExpr{index: 80 /* _diceSides */},
+							&zeroOrOneExpr{
+								expr: &ruleIRefExpr{index: 73 /* _diceExplode */},
+							},
 							&zeroOrOneExpr{
 								expr: &choiceExpr{
 									alternatives: []any{
-										&ruleIRefExpr{index: 51 /* _dicePearMod */},
-										&ruleIRefExpr{index: 49 /* _diceMod */},
+										&ruleIRefExpr{index: 74 /* _dicePearMod */},
+										&ruleIRefExpr{index: 71 /* _diceMod */},
 									},
 								},
 							},
 							&zeroOrOneExpr{
-								expr: &ruleIRefExpr{index: 50 /* _diceModType2 */},
+								expr: &ruleIRefExpr{index: 72 /* _diceModType2 */},
 							},
 						},
 					},
@@ -1666,10 +2486,10 @@ var g = &grammar{
 					&seqExpr{
 						exprs: []any{
 							&zeroOrOneExpr{
-								expr: &ruleIRefExpr{index: 49 /* _diceMod */},
+								expr: &ruleIRefExpr{index: 71 /* _diceMod */},
 							},
 							&zeroOrOneExpr{
-								expr: &ruleIRefExpr{index: 50 /* _diceModType2 */},
+								expr: &ruleIRefExpr{index: 72 /* _diceModType2 */},
 							},
 						},
 					},
@@ -1692,13 +2512,13 @@ var g = &grammar{
 							&zeroOrOneExpr{
 								expr: &choiceExpr{
 									alternatives: []any{
-										&ruleIRefExpr{index: 51 /* _dicePearMod */},
-										&ruleIRefExpr{index: 49 /* _diceMod */},
+										&ruleIRefExpr{index: 74 /* _dicePearMod */},
+										&ruleIRefExpr{index: 71 /* _diceMod */},
 									},
 								},
 							},
 							&zeroOrOneExpr{
-								expr: &ruleIRefExpr{index: 50 /* _diceModType2 */},
+								expr: &ruleIRefExpr{index: 72 /* _diceModType2 */},
 							},
 						},
 					},
@@ -1712,11 +2532,11 @@ var g = &grammar{
 				expr: &seqExpr{
 					exprs: []any{
 						&andExpr{
-							expr: &ruleIRefExpr{index: 53 /* _diceType2 */},
+							expr: &ruleIRefExpr{index: 76 /* _diceType2 */},
 						},
-						&ruleIRefExpr{index: 47 /* detailStart */},
-						&ruleIRefExpr{index: 56 /* _diceExpr1 */},
-						&ruleIRefExpr{index: 48 /* detailEnd */},
+						&ruleIRefExpr{index: 61 /* detailStart */},
+						&ruleIRefExpr{index: 81 /* _diceExpr1 */},
+						&ruleIRefExpr{index: 62 /* detailEnd */},
 					},
 				},
 			},
@@ -1729,7 +2549,7 @@ var g = &grammar{
 						val:   "[aA]",
 						chars: []rune{'a', 'A'},
 					},
-					&ruleIRefExpr{index: 46 /* nos */},
+					&ruleIRefExpr{index: 60 /* nos */},
 					&zeroOrMoreExpr{
 						expr: &choiceExpr{
 							alternatives: []any{
@@ -1739,7 +2559,7 @@ var g = &grammar{
 											val:   "[mM]",
 											chars: []rune{'m', 'M'},
 										},
-										&ruleIRefExpr{index: 46 /* nos */},
+										&ruleIRefExpr{index: 60 /* nos */},
 									},
 								},
 								&seqExpr{
@@ -1748,7 +2568,7 @@ var g = &grammar{
 											val:   "[kK]",
 											chars: []rune{'k', 'K'},
 										},
-										&ruleIRefExpr{index: 46 /* nos */},
+										&ruleIRefExpr{index: 60 /* nos */},
 									},
 								},
 								&seqExpr{
@@ -1757,7 +2577,7 @@ var g = &grammar{
 											val:   "[qQ]",
 											chars: []rune{'q', 'Q'},
 										},
-										&ruleIRefExpr{index: 46 /* nos */},
+										&ruleIRefExpr{index: 60 /* nos */},
 									},
 								},
 							},
@@ -1772,15 +2592,15 @@ var g = &grammar{
 				alternatives: []any{
 					&seqExpr{
 						exprs: []any{
-							&ruleIRefExpr{index: 46 /* nos */},
-							&ruleIRefExpr{index: 61 /* _wodTypeMain */},
+							&ruleIRefExpr{index: 60 /* nos */},
+							&ruleIRefExpr{index: 86 /* _wodTypeMain */},
 						},
 					},
 					&seqExpr{
 						exprs: []any{
-							&ruleIRefExpr{index: 61 /* _wodTypeMain */},
+							&ruleIRefExpr{index: 86 /* _wodTypeMain */},
 							&notExpr{
-								expr: &ruleIRefExpr{index: 101 /* xidContinue */},
+								expr: &ruleIRefExpr{index: 138 /* xidContinue */},
 							},
 						},
 					},
@@ -1795,7 +2615,7 @@ var g = &grammar{
 						val:   "[aA]",
 						chars: []rune{'a', 'A'},
 					},
-					&ruleIRefExpr{index: 46 /* nos */},
+					&ruleIRefExpr{index: 60 /* nos */},
 					&zeroOrMoreExpr{
 						expr: &choiceExpr{
 							alternatives: []any{
@@ -1807,7 +2627,7 @@ var g = &grammar{
 												val:   "[mM]",
 												chars: []rune{'m', 'M'},
 											},
-											&ruleIRefExpr{index: 46 /* nos */},
+											&ruleIRefExpr{index: 60 /* nos */},
 										},
 									},
 								},
@@ -1819,7 +2639,7 @@ var g = &grammar{
 												val:   "[kK]",
 												chars: []rune{'k', 'K'},
 											},
-											&ruleIRefExpr{index: 46 /* nos */},
+											&ruleIRefExpr{index: 60 /* nos */},
 										},
 									},
 								},
@@ -1831,7 +2651,7 @@ var g = &grammar{
 												val:   "[qQ]",
 												chars: []rune{'q', 'Q'},
 											},
-											&ruleIRefExpr{index: 46 /* nos */},
+											&ruleIRefExpr{index: 60 /* nos */},
 										},
 									},
 								},
@@ -1853,14 +2673,14 @@ var g = &grammar{
 						alternatives: []any{
 							&seqExpr{
 								exprs: []any{
-									&ruleIRefExpr{index: 46 /* nos */},
+									&ruleIRefExpr{index: 60 /* nos */},
 									&notExpr{
-										expr: &ruleIRefExpr{index: 101 /* xidContinue */},
+										expr: &ruleIRefExpr{index: 138 /* xidContinue */},
 									},
 								},
 							},
 							&notExpr{
-								expr: &ruleIRefExpr{index: 101 /* xidContinue */},
+								expr: &ruleIRefExpr{index: 138 /* xidContinue */},
 							},
 						},
 					},
@@ -1881,21 +2701,21 @@ var g = &grammar{
 							alternatives: []any{
 								&seqExpr{
 									exprs: []any{
-										&ruleIRefExpr{index: 46 /* nos */},
+										&ruleIRefExpr{index: 60 /* nos */},
 										&notExpr{
-											expr: &ruleIRefExpr{index: 101 /* xidContinue */},
+											expr: &ruleIRefExpr{index: 138 /* xidContinue */},
 										},
 									},
 								},
 								&actionExpr{
 									run: (*parser).call_on_diceCocBonus_9,
 									expr: &notExpr{
-										expr: &ruleIRefExpr{index: 101 /* xidContinue */},
+										expr: &ruleIRefExpr{index: 138 /* xidContinue */},
 									},
 								},
 							},
 						},
-						&ruleIRefExpr{index: 48 /* detailEnd */},
+						&ruleIRefExpr{index: 62 /* detailEnd */},
 					},
 				},
 			},
@@ -1914,21 +2734,21 @@ var g = &grammar{
 							alternatives: []any{
 								&seqExpr{
 									exprs: []any{
-										&ruleIRefExpr{index: 46 /* nos */},
+										&ruleIRefExpr{index: 60 /* nos */},
 										&notExpr{
-											expr: &ruleIRefExpr{index: 101 /* xidContinue */},
+											expr: &ruleIRefExpr{index: 138 /* xidContinue */},
 										},
 									},
 								},
 								&actionExpr{
 									run: (*parser).call_on_diceCocPenalty_9,
 									expr: &notExpr{
-										expr: &ruleIRefExpr{index: 101 /* xidContinue */},
+										expr: &ruleIRefExpr{index: 138 /* xidContinue */},
 									},
 								},
 							},
 						},
-						&ruleIRefExpr{index: 48 /* detailEnd */},
+						&ruleIRefExpr{index: 62 /* detailEnd */},
 					},
 				},
 			},
@@ -1937,12 +2757,12 @@ var g = &grammar{
 			name: "_dcDiceType",
 			expr: &seqExpr{
 				exprs: []any{
-					&ruleIRefExpr{index: 46 /* nos */},
+					&ruleIRefExpr{index: 60 /* nos */},
 					&charClassMatcher{
 						val:   "[cC]",
 						chars: []rune{'c', 'C'},
 					},
-					&ruleIRefExpr{index: 46 /* nos */},
+					&ruleIRefExpr{index: 60 /* nos */},
 					&zeroOrMoreExpr{
 						expr: &seqExpr{
 							exprs: []any{
@@ -1950,7 +2770,7 @@ var g = &grammar{
 									val:   "[mM]",
 									chars: []rune{'m', 'M'},
 								},
-								&ruleIRefExpr{index: 46 /* nos */},
+								&ruleIRefExpr{index: 60 /* nos */},
 							},
 						},
 					},
@@ -1966,7 +2786,28 @@ var g = &grammar{
 						chars: []rune{'f', 'F'},
 					},
 					&notExpr{
-						expr: &ruleIRefExpr{index: 101 /* xidContinue */},
+						expr: &ruleIRefExpr{index: 138 /* xidContinue */},
+					},
+				},
+			},
+		},
+		{
+			name: "_fateDiceTypeN",
+			expr: &seqExpr{
+				exprs: []any{
+					&zeroOrOneExpr{
+						expr: &ruleIRefExpr{index: 60 /* nos */},
+					},
+					&charClassMatcher{
+						val:   "[dD]",
+						chars: []rune{'d', 'D'},
+					},
+					&charClassMatcher{
+						val:   "[fF]",
+						chars: []rune{'f', 'F'},
+					},
+					&notExpr{
+						expr: &ruleIRefExpr{index: 138 /* xidContinue */},
 					},
 				},
 			},
@@ -1982,13 +2823,13 @@ var g = &grammar{
 								expr: &seqExpr{
 									exprs: []any{
 										&andCodeExpr{run: (*parser).call_onexprDice_5},
-										&ruleIRefExpr{index: 47 /* detailStart */},
+										&ruleIRefExpr{index: 61 /* detailStart */},
 									},
 								},
 							},
 							&actionExpr{
 								run:  (*parser).call_onexprDice_7,
-								expr: &ruleIRefExpr{index: 48 /* detailEnd */},
+								expr: &ruleIRefExpr{index: 62 /* detailEnd */},
 							},
 						},
 					},
@@ -1998,102 +2839,136 @@ var g = &grammar{
 								run: (*parser).call_onexprDice_10,
 								expr: &seqExpr{
 									exprs: []any{
+										&andCodeExpr{run: (*parser).call_onexprDice_12},
 										&andExpr{
-											expr: &ruleIRefExpr{index: 52 /* _diceType1 */},
+											expr: &ruleIRefExpr{index: 94 /* _fateDiceTypeN */},
 										},
-										&ruleIRefExpr{index: 47 /* detailStart */},
-										&ruleIRefExpr{index: 46 /* nos */},
-										&ruleIRefExpr{index: 56 /* _diceExpr1 */},
-										&ruleIRefExpr{index: 48 /* detailEnd */},
+										&ruleIRefExpr{index: 61 /* detailStart */},
 									},
 								},
 							},
-							&zeroOrMoreExpr{
-								expr: &ruleIRefExpr{index: 60 /* _diceExprX */},
+							&actionExpr{
+								run: (*parser).call_onexprDice_16,
+								expr: &seqExpr{
+									exprs: []any{
+										&zeroOrOneExpr{
+											expr: &actionExpr{
+												run:  (*parser).call_onexprDice_19,
+												expr: &ruleIRefExpr{index: 60 /* nos */},
+											},
+										},
+										&charClassMatcher{
+											val:   "[dD]",
+											chars: []rune{'d', 'D'},
+										},
+										&charClassMatcher{
+											val:   "[fF]",
+											chars: []rune{'f', 'F'},
+										},
+										&notExpr{
+											expr: &ruleIRefExpr{index: 138 /* xidContinue */},
+										},
+										&ruleIRefExpr{index: 62 /* detailEnd */},
+									},
+								},
 							},
 						},
 					},
 					&seqExpr{
 						exprs: []any{
 							&actionExpr{
-								run: (*parser).call_onexprDice_21,
+								run: (*parser).call_onexprDice_27,
 								expr: &seqExpr{
 									exprs: []any{
 										&andExpr{
-											expr: &ruleIRefExpr{index: 53 /* _diceType2 */},
+											expr: &ruleIRefExpr{index: 75 /* _diceType1 */},
 										},
-										&ruleIRefExpr{index: 47 /* detailStart */},
-										&ruleIRefExpr{index: 57 /* _diceExpr2 */},
-										&ruleIRefExpr{index: 48 /* detailEnd */},
+										&ruleIRefExpr{index: 61 /* detailStart */},
+										&ruleIRefExpr{index: 60 /* nos */},
+										&ruleIRefExpr{index: 81 /* _diceExpr1 */},
+										&ruleIRefExpr{index: 62 /* detailEnd */},
 									},
 								},
 							},
 							&zeroOrMoreExpr{
-								expr: &ruleIRefExpr{index: 60 /* _diceExprX */},
+								expr: &ruleIRefExpr{index: 85 /* _diceExprX */},
 							},
 						},
 					},
 					&seqExpr{
 						exprs: []any{
 							&actionExpr{
-								run: (*parser).call_onexprDice_31,
+								run: (*parser).call_onexprDice_38,
 								expr: &seqExpr{
 									exprs: []any{
-										&andCodeExpr{run: (*parser).call_onexprDice_33},
 										&andExpr{
-											expr: &ruleIRefExpr{index: 54 /* _diceType3 */},
+											expr: &ruleIRefExpr{index: 76 /* _diceType2 */},
 										},
-										&ruleIRefExpr{index: 47 /* detailStart */},
-										&ruleIRefExpr{index: 46 /* nos */},
-										&ruleIRefExpr{index: 58 /* _diceExpr3 */},
-										&ruleIRefExpr{index: 48 /* detailEnd */},
+										&ruleIRefExpr{index: 61 /* detailStart */},
+										&ruleIRefExpr{index: 82 /* _diceExpr2 */},
+										&ruleIRefExpr{index: 62 /* detailEnd */},
 									},
 								},
 							},
 							&zeroOrMoreExpr{
-								expr: &ruleIRefExpr{index: 60 /* _diceExprX */},
+								expr: &ruleIRefExpr{index: 85 /* _diceExprX */},
 							},
 						},
 					},
 					&seqExpr{
 						exprs: []any{
 							&actionExpr{
-								run: (*parser).call_onexprDice_43,
+								run: (*parser).call_onexprDice_48,
 								expr: &seqExpr{
 									exprs: []any{
-										&andCodeExpr{run: (*parser).call_onexprDice_45},
+										&andCodeExpr{run: (*parser).call_onexprDice_50},
 										&andExpr{
-											expr: &ruleIRefExpr{index: 55 /* _diceType4 */},
+											expr: &ruleIRefExpr{index: 77 /* _diceType3 */},
 										},
-										&ruleIRefExpr{index: 47 /* detailStart */},
+										&ruleIRefExpr{index: 61 /* detailStart */},
+										&ruleIRefExpr{index: 60 /* nos */},
+										&ruleIRefExpr{index: 83 /* _diceExpr3 */},
+										&ruleIRefExpr{index: 62 /* detailEnd */},
 									},
 								},
 							},
+							&zeroOrMoreExpr{
+								expr: &ruleIRefExpr{index: 85 /* _diceExprX */},
+							},
+						},
+					},
+					&seqExpr{
+						exprs: []any{
 							&actionExpr{
-								run: (*parser).call_onexprDice_49,
+								run: (*parser).call_onexprDice_60,
 								expr: &seqExpr{
 									exprs: []any{
-										&ruleIRefExpr{index: 59 /* _diceExpr4 */},
-										&ruleIRefExpr{index: 48 /* detailEnd */},
+										&andCodeExpr{run: (*parser).call_onexprDice_62},
+										&andExpr{
+											expr: &ruleIRefExpr{index: 78 /* _diceType4 */},
+										},
+										&ruleIRefExpr{index: 61 /* detailStart */},
+										&ruleIRefExpr{index: 84 /* _diceExpr4 */},
+										&ruleIRefExpr{index: 62 /* detailEnd */},
 									},
 								},
 							},
 							&zeroOrMoreExpr{
-								expr: &ruleIRefExpr{index: 60 /* _diceExprX */},
+								expr: &ruleIRefExpr{index: 85 /* _diceExprX */},
 							},
 						},
 					},
 					&seqExpr{
 						exprs: []any{
-							&andCodeExpr{run: (*parser).call_onexprDice_56},
+							&andCodeExpr{run: (*parser).call_onexprDice_71},
 							&andExpr{
-								expr: &ruleIRefExpr{index: 64 /* _cocDiceType */},
+								expr: &ruleIRefExpr{index: 89 /* _cocDiceType */},
 							},
-							&ruleIRefExpr{index: 47 /* detailStart */},
+							&ruleIRefExpr{index: 61 /* detailStart */},
 							&choiceExpr{
 								alternatives: []any{
-									&ruleIRefExpr{index: 65 /* _diceCocBonus */},
-									&ruleIRefExpr{index: 66 /* _diceCocPenalty */},
+									&ruleIRefExpr{index: 90 /* _diceCocBonus */},
+									&ruleIRefExpr{index: 91 /* _diceCocPenalty */},
 								},
 							},
 						},
@@ -2101,19 +2976,19 @@ var g = &grammar{
 					&seqExpr{
 						exprs: []any{
 							&actionExpr{
-								run: (*parser).call_onexprDice_64,
+								run: (*parser).call_onexprDice_79,
 								expr: &seqExpr{
 									exprs: []any{
-										&andCodeExpr{run: (*parser).call_onexprDice_66},
+										&andCodeExpr{run: (*parser).call_onexprDice_81},
 										&andExpr{
-											expr: &ruleIRefExpr{index: 62 /* _wodDiceType */},
+											expr: &ruleIRefExpr{index: 87 /* _wodDiceType */},
 										},
-										&ruleIRefExpr{index: 47 /* detailStart */},
+										&ruleIRefExpr{index: 61 /* detailStart */},
 									},
 								},
 							},
 							&actionExpr{
-								run: (*parser).call_onexprDice_70,
+								run: (*parser).call_onexprDice_85,
 								expr: &seqExpr{
 									exprs: []any{
 										&choiceExpr{
@@ -2121,23 +2996,23 @@ var g = &grammar{
 												&seqExpr{
 													exprs: []any{
 														&actionExpr{
-															run:  (*parser).call_onexprDice_74,
-															expr: &ruleIRefExpr{index: 46 /* nos */},
+															run:  (*parser).call_onexprDice_89,
+															expr: &ruleIRefExpr{index: 60 /* nos */},
 														},
-														&ruleIRefExpr{index: 63 /* _wodMain */},
+														&ruleIRefExpr{index: 88 /* _wodMain */},
 													},
 												},
 												&seqExpr{
 													exprs: []any{
-														&ruleIRefExpr{index: 63 /* _wodMain */},
+														&ruleIRefExpr{index: 88 /* _wodMain */},
 														&notExpr{
-															expr: &ruleIRefExpr{index: 101 /* xidContinue */},
+															expr: &ruleIRefExpr{index: 138 /* xidContinue */},
 														},
 													},
 												},
 											},
 										},
-										&ruleIRefExpr{index: 48 /* detailEnd */},
+										&ruleIRefExpr{index: 62 /* detailEnd */},
 									},
 								},
 							},
@@ -2146,71 +3021,71 @@ var g = &grammar{
 					&seqExpr{
 						exprs: []any{
 							&actionExpr{
-								run: (*parser).call_onexprDice_83,
+								run: (*parser).call_onexprDice_98,
 								expr: &seqExpr{
 									exprs: []any{
-										&andCodeExpr{run: (*parser).call_onexprDice_85},
+										&andCodeExpr{run: (*parser).call_onexprDice_100},
 										&andExpr{
-											expr: &ruleIRefExpr{index: 67 /* _dcDiceType */},
+											expr: &ruleIRefExpr{index: 92 /* _dcDiceType */},
 										},
-										&ruleIRefExpr{index: 47 /* detailStart */},
+										&ruleIRefExpr{index: 61 /* detailStart */},
 									},
 								},
 							},
 							&actionExpr{
-								run:  (*parser).call_onexprDice_89,
-								expr: &ruleIRefExpr{index: 46 /* nos */},
+								run:  (*parser).call_onexprDice_104,
+								expr: &ruleIRefExpr{index: 60 /* nos */},
 							},
 							&actionExpr{
-								run: (*parser).call_onexprDice_91,
+								run: (*parser).call_onexprDice_106,
 								expr: &seqExpr{
 									exprs: []any{
 										&charClassMatcher{
 											val:   "[cC]",
 											chars: []rune{'c', 'C'},
 										},
-										&ruleIRefExpr{index: 46 /* nos */},
+										&ruleIRefExpr{index: 60 /* nos */},
 										&zeroOrMoreExpr{
 											expr: &actionExpr{
-												run: (*parser).call_onexprDice_96,
+												run: (*parser).call_onexprDice_111,
 												expr: &seqExpr{
 													exprs: []any{
 														&charClassMatcher{
 															val:   "[mM]",
 															chars: []rune{'m', 'M'},
 														},
-														&ruleIRefExpr{index: 46 /* nos */},
+														&ruleIRefExpr{index: 60 /* nos */},
 													},
 												},
 											},
 										},
-										&ruleIRefExpr{index: 48 /* detailEnd */},
+										&ruleIRefExpr{index: 62 /* detailEnd */},
 									},
 								},
 							},
 						},
 					},
 					&actionExpr{
-						run: (*parser).call_onexprDice_101,
+						run: (*parser).call_onexprDice_116,
 						expr: &seqExpr{
 							exprs: []any{
-								&andCodeExpr{run: (*parser).call_onexprDice_103},
+								&andCodeExpr{run: (*parser).call_onexprDice_118},
 								&andExpr{
-									expr: &ruleIRefExpr{index: 68 /* _fateDiceType */},
+									expr: &ruleIRefExpr{index: 93 /* _fateDiceType */},
 								},
-								&ruleIRefExpr{index: 47 /* detailStart */},
+								&ruleIRefExpr{index: 61 /* detailStart */},
 								&charClassMatcher{
 									val:   "[fF]",
 									chars: []rune{'f', 'F'},
 								},
 								&notExpr{
-									expr: &ruleIRefExpr{index: 101 /* xidContinue */},
+									expr: &ruleIRefExpr{index: 138 /* xidContinue */},
 								},
-								&ruleIRefExpr{index: 48 /* detailEnd */},
+								&ruleIRefExpr{index: 62 /* detailEnd */},
 							},
 						},
 					},
-					&ruleIRefExpr{index: 81 /* value */},
+					&ruleIRefExpr{index: 111 /* value */},
 				},
 			},
 		},
@@ -2228,7 +3103,7 @@ var g = &grammar{
 								alternatives: []any{
 									&actionExpr{
 										run:  (*parser).call_onarray_call_6,
-										expr: &ruleIRefExpr{index: 82 /* number */},
+										expr: &ruleIRefExpr{index: 112 /* number */},
 									},
 									&codeExpr{
 										run: (*parser).call_onarray_call_8,
@@ -2247,7 +3122,7 @@ var g = &grammar{
 								alternatives: []any{
 									&actionExpr{
 										run:  (*parser).call_onarray_call_13,
-										expr: &ruleIRefExpr{index: 82 /* number */},
+										expr: &ruleIRefExpr{index: 112 /* number */},
 									},
 									&codeExpr{
 										run: (*parser).call_onarray_call_15,
@@ -2256,24 +3131,62 @@ var g = &grammar{
 							},
 						},
 					},
-					&oneOrMoreExpr{
-						expr: &actionExpr{
-							run: (*parser).call_onarray_call_17,
-							expr: &seqExpr{
-								exprs: []any{
-									&litMatcher{val: "[", want: "\"[\""},
-									&ruleIRefExpr{index: 123 /* sp */},
-									&ruleIRefExpr{index: 26 /* exprRoot */},
-									&ruleIRefExpr{index: 123 /* sp */},
-									&litMatcher{val: "]", want: "\"]\""},
-									&ruleIRefExpr{index: 123 /* sp */},
+					&seqExpr{
+						exprs: []any{
+							&actionExpr{
+								run:  (*parser).call_onarray_call_17,
+								expr: &litMatcher{val: "dh", want: "\"dh\""},
+							},
+							&choiceExpr{
+								alternatives: []any{
+									&actionExpr{
+										run:  (*parser).call_onarray_call_20,
+										expr: &ruleIRefExpr{index: 112 /* number */},
+									},
+									&codeExpr{
+										run: (*parser).call_onarray_call_22,
+									},
 								},
 							},
 						},
 					},
-				},
-			},
-		},
+					&seqExpr{
+						exprs: []any{
+							&actionExpr{
+								run:  (*parser).call_onarray_call_24,
+								expr: &litMatcher{val: "dl", want: "\"dl\""},
+							},
+							&choiceExpr{
+								alternatives: []any{
+									&actionExpr{
+										run:  (*parser).call_onarray_call_27,
+										expr: &ruleIRefExpr{index: 112 /* number */},
+									},
+									&codeExpr{
+										run: (*parser).call_onarray_call_29,
+									},
+								},
+							},
+						},
+					},
+					&oneOrMoreExpr{
+						expr: &actionExpr{
+							run: (*parser).call_onarray_call_31,
+							expr: &seqExpr{
+								exprs: []any{
+									&litMatcher{val: "[", want: "\"[\""},
+									&ruleIRefExpr{index: 165 /* sp */},
+									&ruleIRefExpr{index: 34 /* exprRoot */},
+									&ruleIRefExpr{index: 165 /* sp */},
+									&litMatcher{val: "]", want: "\"]\""},
+									&ruleIRefExpr{index: 165 /* sp */},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
 		{
 			name: "item_getX",
 			expr: &zeroOrMoreExpr{
@@ -2284,11 +3197,11 @@ var g = &grammar{
 							expr: &seqExpr{
 								exprs: []any{
 									&litMatcher{val: "[", want: "\"[\""},
-									&ruleIRefExpr{index: 123 /* sp */},
-									&ruleIRefExpr{index: 26 /* exprRoot */},
-									&ruleIRefExpr{index: 123 /* sp */},
+									&ruleIRefExpr{index: 165 /* sp */},
+									&ruleIRefExpr{index: 34 /* exprRoot */},
+									&ruleIRefExpr{index: 165 /* sp */},
 									&litMatcher{val: "]", want: "\"]\""},
-									&ruleIRefExpr{index: 123 /* sp */},
+									&ruleIRefExpr{index: 165 /* sp */},
 									&notExpr{
 										expr: &litMatcher{val: "=", want: "\"=\""},
 									},
@@ -2296,7 +3209,7 @@ var g = &grammar{
 							},
 						},
 						&zeroOrOneExpr{
-							expr: &ruleIRefExpr{index: 76 /* func_invoke */},
+							expr: &ruleIRefExpr{index: 102 /* func_invoke */},
 						},
 					},
 				},
@@ -2308,9 +3221,9 @@ var g = &grammar{
 				expr: &seqExpr{
 					exprs: []any{
 						&andLogicalExpr{
-							expr: &ruleIRefExpr{index: 71 /* item_getX */},
+							expr: &ruleIRefExpr{index: 97 /* item_getX */},
 						},
-						&ruleIRefExpr{index: 71 /* item_getX */},
+						&ruleIRefExpr{index: 97 /* item_getX */},
 					},
 				},
 			},
@@ -2326,17 +3239,17 @@ var g = &grammar{
 							run: (*parser).call_onattr_getX_4,
 							expr: &seqExpr{
 								exprs: []any{
-									&ruleIRefExpr{index: 123 /* sp */},
+									&ruleIRefExpr{index: 165 /* sp */},
 									&labeledExpr{
 										label: "id",
-										expr:  &ruleIRefExpr{index: 98 /* identifier */},
+										expr:  &ruleIRefExpr{index: 135 /* identifier */},
 									},
-									&ruleIRefExpr{index: 123 /* sp */},
+									&ruleIRefExpr{index: 165 /* sp */},
 								},
 							},
 						},
 						&zeroOrOneExpr{
-							expr: &ruleIRefExpr{index: 76 /* func_invoke */},
+							expr: &ruleIRefExpr{index: 102 /* func_invoke */},
 						},
 					},
 				},
@@ -2348,9 +3261,9 @@ var g = &grammar{
 				expr: &seqExpr{
 					exprs: []any{
 						&andLogicalExpr{
-							expr: &ruleIRefExpr{index: 73 /* attr_getX */},
+							expr: &ruleIRefExpr{index: 99 /* attr_getX */},
 						},
-						&ruleIRefExpr{index: 73 /* attr_getX */},
+						&ruleIRefExpr{index: 99 /* attr_getX */},
 					},
 				},
 			},
@@ -2364,7 +3277,7 @@ var g = &grammar{
 						expr: &seqExpr{
 							exprs: []any{
 								&litMatcher{val: "(", want: "\"(\""},
-								&ruleIRefExpr{index: 123 /* sp */},
+								&ruleIRefExpr{index: 165 /* sp */},
 							},
 						},
 					},
@@ -2372,21 +3285,21 @@ var g = &grammar{
 						run: (*parser).call_onfunc_invoke2_6,
 						expr: &seqExpr{
 							exprs: []any{
-								&ruleIRefExpr{index: 26 /* exprRoot */},
-								&ruleIRefExpr{index: 123 /* sp */},
+								&ruleIRefExpr{index: 34 /* exprRoot */},
+								&ruleIRefExpr{index: 165 /* sp */},
 								&zeroOrMoreExpr{
 									expr: &actionExpr{
 										run: (*parser).call_onfunc_invoke2_11,
 										expr: &seqExpr{
 											exprs: []any{
 												&litMatcher{val: ",", want: "\",\""},
-												&ruleIRefExpr{index: 123 /* sp */},
-												&ruleIRefExpr{index: 26 /* exprRoot */},
+												&ruleIRefExpr{index: 165 /* sp */},
+												&ruleIRefExpr{index: 34 /* exprRoot */},
 											},
 										},
 									},
 								},
-								&ruleIRefExpr{index: 123 /* sp */},
+								&ruleIRefExpr{index: 165 /* sp */},
 								&litMatcher{val: ")", want: "\")\""},
 							},
 						},
@@ -2403,7 +3316,7 @@ var g = &grammar{
 						expr: &seqExpr{
 							exprs: []any{
 								&litMatcher{val: "(", want: "\"(\""},
-								&ruleIRefExpr{index: 123 /* sp */},
+								&ruleIRefExpr{index: 165 /* sp */},
 								&litMatcher{val: ")", want: "\")\""},
 							},
 						},
@@ -2411,9 +3324,9 @@ var g = &grammar{
 					&seqExpr{
 						exprs: []any{
 							&andExpr{
-								expr: &ruleIRefExpr{index: 75 /* func_invoke2 */},
+								expr: &ruleIRefExpr{index: 101 /* func_invoke2 */},
 							},
-							&ruleIRefExpr{index: 75 /* func_invoke2 */},
+							&ruleIRefExpr{index: 101 /* func_invoke2 */},
 						},
 					},
 				},
@@ -2429,17 +3342,37 @@ var g = &grammar{
 							exprs: []any{
 								&choiceExpr{
 									alternatives: []any{
-										&ruleIRefExpr{index: 78 /* value_id_without_colon */},
-										&ruleIRefExpr{index: 26 /* exprRoot */},
+										&seqExpr{
+											exprs: []any{
+												&notExpr{
+													expr: &seqExpr{
+														exprs: []any{
+															&choiceExpr{
+																alternatives: []any{
+																	&litMatcher{val: "true", want: "\"true\""},
+																	&litMatcher{val: "false", want: "\"false\""},
+																	&litMatcher{val: "null", want: "\"null\""},
+																},
+															},
+															&notExpr{
+																expr: &ruleIRefExpr{index: 138 /* xidContinue */},
+															},
+														},
+													},
+												},
+												&ruleIRefExpr{index: 104 /* value_id_without_colon */},
+											},
+										},
+										&ruleIRefExpr{index: 34 /* exprRoot */},
 									},
 								},
-								&ruleIRefExpr{index: 123 /* sp */},
+								&ruleIRefExpr{index: 165 /* sp */},
 								&litMatcher{val: ":", want: "\":\""},
-								&ruleIRefExpr{index: 123 /* sp */},
-								&ruleIRefExpr{index: 26 /* exprRoot */},
+								&ruleIRefExpr{index: 165 /* sp */},
+								&ruleIRefExpr{index: 34 /* exprRoot */},
 							},
 						},
-						&ruleIRefExpr{index: 123 /* sp */},
+						&ruleIRefExpr{index: 165 /* sp */},
 					},
 				},
 			},
@@ -2455,19 +3388,19 @@ var g = &grammar{
 							exprs: []any{
 								&labeledExpr{
 									label: "id",
-									expr:  &ruleIRefExpr{index: 99 /* identifierWithoutColon */},
+									expr:  &ruleIRefExpr{index: 136 /* identifierWithoutColon */},
 								},
-								&ruleIRefExpr{index: 123 /* sp */},
+								&ruleIRefExpr{index: 165 /* sp */},
 							},
 						},
 					},
 					&seqExpr{
 						exprs: []any{
 							&zeroOrOneExpr{
-								expr: &ruleIRefExpr{index: 76 /* func_invoke */},
+								expr: &ruleIRefExpr{index: 102 /* func_invoke */},
 							},
-							&ruleIRefExpr{index: 72 /* item_get */},
-							&ruleIRefExpr{index: 74 /* attr_get */},
+							&ruleIRefExpr{index: 98 /* item_get */},
+							&ruleIRefExpr{index: 100 /* attr_get */},
 						},
 					},
 				},
@@ -2480,17 +3413,37 @@ var g = &grammar{
 				expr: &seqExpr{
 					exprs: []any{
 						&litMatcher{val: "[", want: "\"[\""},
-						&ruleIRefExpr{index: 123 /* sp */},
-						&ruleIRefExpr{index: 26 /* exprRoot */},
+						&ruleIRefExpr{index: 165 /* sp */},
+						&ruleIRefExpr{index: 53 /* exprShift */},
+						&ruleIRefExpr{index: 165 /* sp */},
 						&litMatcher{val: "..", want: "\"..\""},
-						&ruleIRefExpr{index: 123 /* sp */},
-						&ruleIRefExpr{index: 26 /* exprRoot */},
+						&ruleIRefExpr{index: 165 /* sp */},
+						&ruleIRefExpr{index: 53 /* exprShift */},
+						&ruleIRefExpr{index: 165 /* sp */},
 						&litMatcher{val: "]", want: "\"]\""},
-						&ruleIRefExpr{index: 123 /* sp */},
+						&ruleIRefExpr{index: 165 /* sp */},
 					},
 				},
 			},
 		},
+		{
+			name: "value_array_item",
+			expr: &choiceExpr{
+				alternatives: []any{
+					&actionExpr{
+						run: (*parser).call_onvalue_array_item_2,
+						expr: &seqExpr{
+							exprs: []any{
+								&litMatcher{val: "...", want: "\"...\""},
+								&ruleIRefExpr{index: 165 /* sp */},
+								&ruleIRefExpr{index: 34 /* exprRoot */},
+							},
+						},
+					},
+					&ruleIRefExpr{index: 34 /* exprRoot */},
+				},
+			},
+		},
 		{
 			name: "value_array",
 			expr: &seqExpr{
@@ -2500,29 +3453,225 @@ var g = &grammar{
 						expr: &seqExpr{
 							exprs: []any{
 								&litMatcher{val: "[", want: "\"[\""},
-								&ruleIRefExpr{index: 123 /* sp */},
+								&ruleIRefExpr{index: 165 /* sp */},
 							},
 						},
 					},
+					&seqExpr{
+						exprs: []any{
+							&ruleIRefExpr{index: 106 /* value_array_item */},
+							&zeroOrMoreExpr{
+								expr: &actionExpr{
+									run: (*parser).call_onvalue_array_9,
+									expr: &seqExpr{
+										exprs: []any{
+											&litMatcher{val: ",", want: "\",\""},
+											&ruleIRefExpr{index: 165 /* sp */},
+											&ruleIRefExpr{index: 106 /* value_array_item */},
+										},
+									},
+								},
+							},
+							&choiceExpr{
+								alternatives: []any{
+									&actionExpr{
+										run: (*parser).call_onvalue_array_15,
+										expr: &seqExpr{
+											exprs: []any{
+												&litMatcher{val: "]", want: "\"]\""},
+												&ruleIRefExpr{index: 165 /* sp */},
+											},
+										},
+									},
+									&seqExpr{
+										exprs: []any{
+											&actionExpr{
+												run: (*parser).call_onvalue_array_20,
+												expr: &seqExpr{
+													exprs: []any{
+														&litMatcher{val: ";", want: "\";\""},
+														&ruleIRefExpr{index: 165 /* sp */},
+													},
+												},
+											},
+											&actionExpr{
+												run: (*parser).call_onvalue_array_24,
+												expr: &seqExpr{
+													exprs: []any{
+														&zeroOrOneExpr{
+															expr: &seqExpr{
+																exprs: []any{
+																	&actionExpr{
+																		run:  (*parser).call_onvalue_array_28,
+																		expr: &ruleIRefExpr{index: 108 /* value_table_row */},
+																	},
+																	&seqExpr{
+																		exprs: []any{
+																			&zeroOrMoreExpr{
+																				expr: &actionExpr{
+																					run: (*parser).call_onvalue_array_32,
+																					expr: &seqExpr{
+																						exprs: []any{
+																							&litMatcher{val: ";", want: "\";\""},
+																							&ruleIRefExpr{index: 165 /* sp */},
+																							&ruleIRefExpr{index: 108 /* value_table_row */},
+																						},
+																					},
+																				},
+																			},
+																			&zeroOrOneExpr{
+																				expr: &litMatcher{val: ";", want: "\";\""},
+																			},
+																			&ruleIRefExpr{index: 165 /* sp */},
+																		},
+																	},
+																},
+															},
+														},
+														&litMatcher{val: "]", want: "\"]\""},
+														&ruleIRefExpr{index: 165 /* sp */},
+													},
+												},
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "value_table_row",
+			expr: &seqExpr{
+				exprs: []any{
+					&actionExpr{
+						run:  (*parser).call_onvalue_table_row_2,
+						expr: &ruleIRefExpr{index: 106 /* value_array_item */},
+					},
 					&actionExpr{
-						run: (*parser).call_onvalue_array_6,
+						run: (*parser).call_onvalue_table_row_4,
+						expr: &zeroOrMoreExpr{
+							expr: &actionExpr{
+								run: (*parser).call_onvalue_table_row_6,
+								expr: &seqExpr{
+									exprs: []any{
+										&litMatcher{val: ",", want: "\",\""},
+										&ruleIRefExpr{index: 165 /* sp */},
+										&ruleIRefExpr{index: 106 /* value_array_item */},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "value_set",
+			expr: &seqExpr{
+				exprs: []any{
+					&actionExpr{
+						run: (*parser).call_onvalue_set_2,
+						expr: &seqExpr{
+							exprs: []any{
+								&litMatcher{val: "{", want: "\"{\""},
+								&ruleIRefExpr{index: 165 /* sp */},
+							},
+						},
+					},
+					&actionExpr{
+						run:  (*parser).call_onvalue_set_6,
+						expr: &ruleIRefExpr{index: 34 /* exprRoot */},
+					},
+					&actionExpr{
+						run: (*parser).call_onvalue_set_8,
 						expr: &seqExpr{
 							exprs: []any{
-								&ruleIRefExpr{index: 26 /* exprRoot */},
 								&zeroOrMoreExpr{
 									expr: &actionExpr{
-										run: (*parser).call_onvalue_array_10,
+										run: (*parser).call_onvalue_set_11,
 										expr: &seqExpr{
 											exprs: []any{
 												&litMatcher{val: ",", want: "\",\""},
-												&ruleIRefExpr{index: 123 /* sp */},
-												&ruleIRefExpr{index: 26 /* exprRoot */},
+												&ruleIRefExpr{index: 165 /* sp */},
+												&ruleIRefExpr{index: 34 /* exprRoot */},
+											},
+										},
+									},
+								},
+								&zeroOrOneExpr{
+									expr: &litMatcher{val: ",", want: "\",\""},
+								},
+								&ruleIRefExpr{index: 165 /* sp */},
+								&litMatcher{val: "}", want: "\"}\""},
+								&ruleIRefExpr{index: 165 /* sp */},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "value_tuple",
+			expr: &seqExpr{
+				exprs: []any{
+					&actionExpr{
+						run:  (*parser).call_onvalue_tuple_2,
+						expr: &ruleIRefExpr{index: 141 /* parenOpen */},
+					},
+					&actionExpr{
+						run: (*parser).call_onvalue_tuple_4,
+						expr: &seqExpr{
+							exprs: []any{
+								&zeroOrOneExpr{
+									expr: &seqExpr{
+										exprs: []any{
+											&actionExpr{
+												run: (*parser).call_onvalue_tuple_8,
+												expr: &seqExpr{
+													exprs: []any{
+														&ruleIRefExpr{index: 34 /* exprRoot */},
+														&litMatcher{val: ",", want: "\",\""},
+														&ruleIRefExpr{index: 165 /* sp */},
+													},
+												},
+											},
+											&zeroOrOneExpr{
+												expr: &seqExpr{
+													exprs: []any{
+														&actionExpr{
+															run:  (*parser).call_onvalue_tuple_15,
+															expr: &ruleIRefExpr{index: 34 /* exprRoot */},
+														},
+														&seqExpr{
+															exprs: []any{
+																&zeroOrMoreExpr{
+																	expr: &actionExpr{
+																		run: (*parser).call_onvalue_tuple_19,
+																		expr: &seqExpr{
+																			exprs: []any{
+																				&litMatcher{val: ",", want: "\",\""},
+																				&ruleIRefExpr{index: 165 /* sp */},
+																				&ruleIRefExpr{index: 34 /* exprRoot */},
+																			},
+																		},
+																	},
+																},
+																&zeroOrOneExpr{
+																	expr: &litMatcher{val: ",", want: "\",\""},
+																},
+																&ruleIRefExpr{index: 165 /* sp */},
+															},
+														},
+													},
+												},
 											},
 										},
 									},
 								},
-								&litMatcher{val: "]", want: "\"]\""},
-								&ruleIRefExpr{index: 123 /* sp */},
+								&ruleIRefExpr{index: 142 /* parenClose */},
 							},
 						},
 					},
@@ -2539,7 +3688,7 @@ var g = &grammar{
 						expr: &seqExpr{
 							exprs: []any{
 								&litMatcher{val: "true", want: "\"true\""},
-								&ruleIRefExpr{index: 123 /* sp */},
+								&ruleIRefExpr{index: 165 /* sp */},
 							},
 						},
 					},
@@ -2548,7 +3697,7 @@ var g = &grammar{
 						expr: &seqExpr{
 							exprs: []any{
 								&litMatcher{val: "false", want: "\"false\""},
-								&ruleIRefExpr{index: 123 /* sp */},
+								&ruleIRefExpr{index: 165 /* sp */},
 							},
 						},
 					},
@@ -2557,7 +3706,7 @@ var g = &grammar{
 						expr: &seqExpr{
 							exprs: []any{
 								&litMatcher{val: "null", want: "\"null\""},
-								&ruleIRefExpr{index: 123 /* sp */},
+								&ruleIRefExpr{index: 165 /* sp */},
 							},
 						},
 					},
@@ -2568,14 +3717,14 @@ var g = &grammar{
 								expr: &seqExpr{
 									exprs: []any{
 										&litMatcher{val: "this", want: "\"this\""},
-										&ruleIRefExpr{index: 123 /* sp */},
+										&ruleIRefExpr{index: 165 /* sp */},
 									},
 								},
 							},
 							&seqExpr{
 								exprs: []any{
-									&ruleIRefExpr{index: 72 /* item_get */},
-									&ruleIRefExpr{index: 74 /* attr_get */},
+									&ruleIRefExpr{index: 98 /* item_get */},
+									&ruleIRefExpr{index: 100 /* attr_get */},
 								},
 							},
 						},
@@ -2589,79 +3738,257 @@ var g = &grammar{
 										&litMatcher{val: "&", want: "\"&\""},
 										&labeledExpr{
 											label: "id",
-											expr:  &ruleIRefExpr{index: 98 /* identifier */},
+											expr:  &ruleIRefExpr{index: 135 /* identifier */},
 										},
-										&ruleIRefExpr{index: 123 /* sp */},
+										&ruleIRefExpr{index: 165 /* sp */},
 									},
 								},
 							},
-							&ruleIRefExpr{index: 74 /* attr_get */},
+							&ruleIRefExpr{index: 100 /* attr_get */},
 						},
 					},
-					&ruleIRefExpr{index: 83 /* float */},
-					&ruleIRefExpr{index: 82 /* number */},
 					&seqExpr{
 						exprs: []any{
 							&actionExpr{
-								run: (*parser).call_onvalue_33,
+								run: (*parser).call_onvalue_31,
 								expr: &seqExpr{
 									exprs: []any{
 										&andExpr{
 											expr: &seqExpr{
 												exprs: []any{
-													&ruleIRefExpr{index: 98 /* identifier */},
-													&ruleIRefExpr{index: 126 /* spNoCR */},
+													&litMatcher{val: "&", want: "\"&\""},
+													&ruleIRefExpr{index: 141 /* parenOpen */},
+													&ruleIRefExpr{index: 34 /* exprRoot */},
+													&ruleIRefExpr{index: 142 /* parenClose */},
 												},
 											},
 										},
-										&ruleIRefExpr{index: 47 /* detailStart */},
+										&litMatcher{val: "&", want: "\"&\""},
+										&ruleIRefExpr{index: 141 /* parenOpen */},
+									},
+								},
+							},
+							&actionExpr{
+								run: (*parser).call_onvalue_41,
+								expr: &seqExpr{
+									exprs: []any{
+										&labeledExpr{
+											label:       "expr",
+											expr:        &ruleIRefExpr{index: 34 /* exprRoot */},
+											textCapture: true,
+										},
+										&ruleIRefExpr{index: 142 /* parenClose */},
+									},
+								},
+							},
+						},
+					},
+					&seqExpr{
+						exprs: []any{
+							&andExpr{
+								expr: &ruleIRefExpr{index: 43 /* exprMatch */},
+							},
+							&ruleIRefExpr{index: 43 /* exprMatch */},
+						},
+					},
+					&ruleIRefExpr{index: 116 /* percent */},
+					&ruleIRefExpr{index: 118 /* money */},
+					&ruleIRefExpr{index: 119 /* quantity */},
+					&ruleIRefExpr{index: 120 /* duration */},
+					&ruleIRefExpr{index: 113 /* float */},
+					&ruleIRefExpr{index: 112 /* number */},
+					&seqExpr{
+						exprs: []any{
+							&actionExpr{
+								run: (*parser).call_onvalue_57,
+								expr: &seqExpr{
+									exprs: []any{
+										&andExpr{
+											expr: &seqExpr{
+												exprs: []any{
+													&choiceExpr{
+														alternatives: []any{
+															&litMatcher{val: "max", want: "\"max\""},
+															&litMatcher{val: "min", want: "\"min\""},
+														},
+													},
+													&ruleIRefExpr{index: 165 /* sp */},
+													&ruleIRefExpr{index: 141 /* parenOpen */},
+													&ruleIRefExpr{index: 34 /* exprRoot */},
+													&ruleIRefExpr{index: 142 /* parenClose */},
+												},
+											},
+										},
+										&labeledExpr{
+											label: "mode",
+											expr: &choiceExpr{
+												alternatives: []any{
+													&litMatcher{val: "max", want: "\"max\""},
+													&litMatcher{val: "min", want: "\"min\""},
+												},
+											},
+											textCapture: true,
+										},
+										&ruleIRefExpr{index: 165 /* sp */},
+										&ruleIRefExpr{index: 141 /* parenOpen */},
+									},
+								},
+							},
+							&actionExpr{
+								run: (*parser).call_onvalue_74,
+								expr: &seqExpr{
+									exprs: []any{
+										&ruleIRefExpr{index: 34 /* exprRoot */},
+										&ruleIRefExpr{index: 142 /* parenClose */},
+									},
+								},
+							},
+						},
+					},
+					&actionExpr{
+						run: (*parser).call_onvalue_78,
+						expr: &seqExpr{
+							exprs: []any{
+								&andExpr{
+									expr: &seqExpr{
+										exprs: []any{
+											&litMatcher{val: "note", want: "\"note\""},
+											&ruleIRefExpr{index: 165 /* sp */},
+											&ruleIRefExpr{index: 141 /* parenOpen */},
+											&ruleIRefExpr{index: 34 /* exprRoot */},
+											&ruleIRefExpr{index: 142 /* parenClose */},
+										},
+									},
+								},
+								&ruleIRefExpr{index: 61 /* detailStart */},
+								&litMatcher{val: "note", want: "\"note\""},
+								&ruleIRefExpr{index: 165 /* sp */},
+								&ruleIRefExpr{index: 141 /* parenOpen */},
+								&ruleIRefExpr{index: 34 /* exprRoot */},
+								&litMatcher{val: ")", want: "\")\""},
+								&ruleIRefExpr{index: 62 /* detailEnd */},
+								&ruleIRefExpr{index: 165 /* sp */},
+							},
+						},
+					},
+					&actionExpr{
+						run: (*parser).call_onvalue_95,
+						expr: &seqExpr{
+							exprs: []any{
+								&andExpr{
+									expr: &seqExpr{
+										exprs: []any{
+											&litMatcher{val: "quiet", want: "\"quiet\""},
+											&ruleIRefExpr{index: 165 /* sp */},
+											&ruleIRefExpr{index: 141 /* parenOpen */},
+											&ruleIRefExpr{index: 34 /* exprRoot */},
+											&ruleIRefExpr{index: 142 /* parenClose */},
+										},
+									},
+								},
+								&ruleIRefExpr{index: 61 /* detailStart */},
+								&litMatcher{val: "quiet", want: "\"quiet\""},
+								&ruleIRefExpr{index: 165 /* sp */},
+								&ruleIRefExpr{index: 141 /* parenOpen */},
+								&ruleIRefExpr{index: 34 /* exprRoot */},
+								&litMatcher{val: ")", want: "\")\""},
+								&ruleIRefExpr{index: 62 /* detailEnd */},
+								&ruleIRefExpr{index: 165 /* sp */},
+							},
+						},
+					},
+					&seqExpr{
+						exprs: []any{
+							&actionExpr{
+								run: (*parser).call_onvalue_113,
+								expr: &seqExpr{
+									exprs: []any{
+										&andExpr{
+											expr: &seqExpr{
+												exprs: []any{
+													&ruleIRefExpr{index: 135 /* identifier */},
+													&ruleIRefExpr{index: 168 /* spNoCR */},
+												},
+											},
+										},
+										&ruleIRefExpr{index: 61 /* detailStart */},
 										&labeledExpr{
 											label: "id",
-											expr:  &ruleIRefExpr{index: 98 /* identifier */},
+											expr:  &ruleIRefExpr{index: 135 /* identifier */},
 										},
-										&ruleIRefExpr{index: 48 /* detailEnd */},
-										&ruleIRefExpr{index: 126 /* spNoCR */},
+										&ruleIRefExpr{index: 62 /* detailEnd */},
+										&ruleIRefExpr{index: 168 /* spNoCR */},
 									},
 								},
 							},
 							&seqExpr{
 								exprs: []any{
 									&zeroOrOneExpr{
-										expr: &ruleIRefExpr{index: 76 /* func_invoke */},
+										expr: &ruleIRefExpr{index: 102 /* func_invoke */},
 									},
-									&ruleIRefExpr{index: 72 /* item_get */},
-									&ruleIRefExpr{index: 74 /* attr_get */},
+									&ruleIRefExpr{index: 98 /* item_get */},
+									&ruleIRefExpr{index: 100 /* attr_get */},
 								},
 							},
 						},
 					},
-					&ruleIRefExpr{index: 95 /* fstring */},
 					&seqExpr{
 						exprs: []any{
-							&ruleIRefExpr{index: 102 /* sub */},
-							&ruleIRefExpr{index: 72 /* item_get */},
-							&ruleIRefExpr{index: 74 /* attr_get */},
+							&ruleIRefExpr{index: 132 /* fstring */},
+							&ruleIRefExpr{index: 100 /* attr_get */},
+						},
+					},
+					&seqExpr{
+						exprs: []any{
+							&andExpr{
+								expr: &seqExpr{
+									exprs: []any{
+										&ruleIRefExpr{index: 141 /* parenOpen */},
+										&choiceExpr{
+											alternatives: []any{
+												&ruleIRefExpr{index: 142 /* parenClose */},
+												&seqExpr{
+													exprs: []any{
+														&ruleIRefExpr{index: 34 /* exprRoot */},
+														&litMatcher{val: ",", want: "\",\""},
+													},
+												},
+											},
+										},
+									},
+								},
+							},
+							&ruleIRefExpr{index: 110 /* value_tuple */},
+							&ruleIRefExpr{index: 98 /* item_get */},
+							&ruleIRefExpr{index: 100 /* attr_get */},
+						},
+					},
+					&seqExpr{
+						exprs: []any{
+							&ruleIRefExpr{index: 139 /* sub */},
+							&ruleIRefExpr{index: 98 /* item_get */},
+							&ruleIRefExpr{index: 100 /* attr_get */},
 						},
 					},
 					&seqExpr{
 						exprs: []any{
 							&actionExpr{
-								run: (*parser).call_onvalue_55,
+								run: (*parser).call_onvalue_149,
 								expr: &seqExpr{
 									exprs: []any{
 										&litMatcher{val: "[", want: "\"[\""},
-										&ruleIRefExpr{index: 123 /* sp */},
+										&ruleIRefExpr{index: 165 /* sp */},
 										&litMatcher{val: "]", want: "\"]\""},
-										&ruleIRefExpr{index: 123 /* sp */},
+										&ruleIRefExpr{index: 165 /* sp */},
 									},
 								},
 							},
 							&seqExpr{
 								exprs: []any{
 									&zeroOrOneExpr{
-										expr: &ruleIRefExpr{index: 70 /* array_call */},
+										expr: &ruleIRefExpr{index: 96 /* array_call */},
 									},
-									&ruleIRefExpr{index: 74 /* attr_get */},
+									&ruleIRefExpr{index: 100 /* attr_get */},
 								},
 							},
 						},
@@ -2669,85 +3996,269 @@ var g = &grammar{
 					&seqExpr{
 						exprs: []any{
 							&andExpr{
-								expr: &ruleIRefExpr{index: 79 /* value_array_range */},
+								expr: &ruleIRefExpr{index: 105 /* value_array_range */},
 							},
-							&ruleIRefExpr{index: 79 /* value_array_range */},
+							&ruleIRefExpr{index: 105 /* value_array_range */},
 							&zeroOrOneExpr{
-								expr: &ruleIRefExpr{index: 70 /* array_call */},
+								expr: &ruleIRefExpr{index: 96 /* array_call */},
 							},
-							&ruleIRefExpr{index: 74 /* attr_get */},
+							&ruleIRefExpr{index: 100 /* attr_get */},
 						},
 					},
 					&seqExpr{
 						exprs: []any{
 							&andExpr{
-								expr: &ruleIRefExpr{index: 80 /* value_array */},
+								expr: &ruleIRefExpr{index: 107 /* value_array */},
 							},
-							&ruleIRefExpr{index: 80 /* value_array */},
+							&ruleIRefExpr{index: 107 /* value_array */},
 							&zeroOrOneExpr{
-								expr: &ruleIRefExpr{index: 70 /* array_call */},
+								expr: &ruleIRefExpr{index: 96 /* array_call */},
+							},
+							&ruleIRefExpr{index: 100 /* attr_get */},
+						},
+					},
+					&seqExpr{
+						exprs: []any{
+							&actionExpr{
+								run: (*parser).call_onvalue_174,
+								expr: &seqExpr{
+									exprs: []any{
+										&litMatcher{val: "{", want: "\"{\""},
+										&ruleIRefExpr{index: 165 /* sp */},
+										&litMatcher{val: "}", want: "\"}\""},
+										&ruleIRefExpr{index: 165 /* sp */},
+									},
+								},
+							},
+							&seqExpr{
+								exprs: []any{
+									&ruleIRefExpr{index: 98 /* item_get */},
+									&ruleIRefExpr{index: 100 /* attr_get */},
+								},
+							},
+						},
+					},
+					&seqExpr{
+						exprs: []any{
+							&andExpr{
+								expr: &seqExpr{
+									exprs: []any{
+										&litMatcher{val: "{", want: "\"{\""},
+										&ruleIRefExpr{index: 165 /* sp */},
+										&notExpr{
+											expr: &ruleIRefExpr{index: 103 /* dict_item */},
+										},
+									},
+								},
 							},
-							&ruleIRefExpr{index: 74 /* attr_get */},
+							&andExpr{
+								expr: &ruleIRefExpr{index: 109 /* value_set */},
+							},
+							&ruleIRefExpr{index: 109 /* value_set */},
+							&ruleIRefExpr{index: 98 /* item_get */},
+							&ruleIRefExpr{index: 100 /* attr_get */},
 						},
 					},
 					&seqExpr{
 						exprs: []any{
 							&actionExpr{
-								run: (*parser).call_onvalue_80,
+								run: (*parser).call_onvalue_196,
 								expr: &seqExpr{
 									exprs: []any{
 										&litMatcher{val: "{", want: "\"{\""},
-										&ruleIRefExpr{index: 123 /* sp */},
+										&ruleIRefExpr{index: 165 /* sp */},
+									},
+								},
+							},
+							&actionExpr{
+								run: (*parser).call_onvalue_200,
+								expr: &seqExpr{
+									exprs: []any{
+										&ruleIRefExpr{index: 103 /* dict_item */},
+										&zeroOrMoreExpr{
+											expr: &seqExpr{
+												exprs: []any{
+													&litMatcher{val: ",", want: "\",\""},
+													&ruleIRefExpr{index: 165 /* sp */},
+													&ruleIRefExpr{index: 103 /* dict_item */},
+												},
+											},
+										},
+										&zeroOrOneExpr{
+											expr: &litMatcher{val: ",", want: "\",\""},
+										},
 										&litMatcher{val: "}", want: "\"}\""},
-										&ruleIRefExpr{index: 123 /* sp */},
+										&ruleIRefExpr{index: 165 /* sp */},
 									},
 								},
 							},
 							&seqExpr{
 								exprs: []any{
-									&ruleIRefExpr{index: 72 /* item_get */},
-									&ruleIRefExpr{index: 74 /* attr_get */},
+									&ruleIRefExpr{index: 98 /* item_get */},
+									&ruleIRefExpr{index: 100 /* attr_get */},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "number",
+			expr: &actionExpr{
+				run: (*parser).call_onnumber_1,
+				expr: &choiceExpr{
+					alternatives: []any{
+						&seqExpr{
+							exprs: []any{
+								&litMatcher{val: "0", want: "\"0\""},
+								&charClassMatcher{
+									val:   "[xX]",
+									chars: []rune{'x', 'X'},
+								},
+								&oneOrMoreExpr{
+									expr: &charClassMatcher{
+										val:    "[0-9a-fA-F]",
+										ranges: []rune{'0', '9', 'a', 'f', 'A', 'F'},
+									},
+								},
+								&zeroOrMoreExpr{
+									expr: &seqExpr{
+										exprs: []any{
+											&litMatcher{val: "_", want: "\"_\""},
+											&oneOrMoreExpr{
+												expr: &charClassMatcher{
+													val:    "[0-9a-fA-F]",
+													ranges: []rune{'0', '9', 'a', 'f', 'A', 'F'},
+												},
+											},
+										},
+									},
+								},
+							},
+						},
+						&ruleIRefExpr{index: 114 /* digits */},
+					},
+				},
+			},
+		},
+		{
+			name: "float",
+			expr: &actionExpr{
+				run: (*parser).call_onfloat_1,
+				expr: &choiceExpr{
+					alternatives: []any{
+						&seqExpr{
+							exprs: []any{
+								&zeroOrOneExpr{
+									expr: &ruleIRefExpr{index: 114 /* digits */},
+								},
+								&litMatcher{val: ".", want: "\".\""},
+								&ruleIRefExpr{index: 114 /* digits */},
+								&zeroOrOneExpr{
+									expr: &ruleIRefExpr{index: 115 /* exponent */},
+								},
+							},
+						},
+						&seqExpr{
+							exprs: []any{
+								&ruleIRefExpr{index: 114 /* digits */},
+								&ruleIRefExpr{index: 115 /* exponent */},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "digits",
+			expr: &seqExpr{
+				exprs: []any{
+					&oneOrMoreExpr{
+						expr: &charClassMatcher{
+							val:    "[0-9]",
+							ranges: []rune{'0', '9'},
+						},
+					},
+					&zeroOrMoreExpr{
+						expr: &seqExpr{
+							exprs: []any{
+								&litMatcher{val: "_", want: "\"_\""},
+								&oneOrMoreExpr{
+									expr: &charClassMatcher{
+										val:    "[0-9]",
+										ranges: []rune{'0', '9'},
+									},
 								},
 							},
 						},
 					},
-					&seqExpr{
-						exprs: []any{
-							&actionExpr{
-								run: (*parser).call_onvalue_90,
-								expr: &seqExpr{
-									exprs: []any{
-										&litMatcher{val: "{", want: "\"{\""},
-										&ruleIRefExpr{index: 123 /* sp */},
-									},
-								},
-							},
-							&actionExpr{
-								run: (*parser).call_onvalue_94,
-								expr: &seqExpr{
+				},
+			},
+		},
+		{
+			name: "exponent",
+			expr: &seqExpr{
+				exprs: []any{
+					&charClassMatcher{
+						val:   "[eE]",
+						chars: []rune{'e', 'E'},
+					},
+					&zeroOrOneExpr{
+						expr: &charClassMatcher{
+							val:   "[+-]",
+							chars: []rune{'+', '-'},
+						},
+					},
+					&oneOrMoreExpr{
+						expr: &charClassMatcher{
+							val:    "[0-9]",
+							ranges: []rune{'0', '9'},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "percent",
+			expr: &actionExpr{
+				run: (*parser).call_onpercent_1,
+				expr: &seqExpr{
+					exprs: []any{
+						&andCodeExpr{run: (*parser).call_onpercent_3},
+						&choiceExpr{
+							alternatives: []any{
+								&seqExpr{
 									exprs: []any{
-										&ruleIRefExpr{index: 77 /* dict_item */},
 										&zeroOrMoreExpr{
-											expr: &seqExpr{
-												exprs: []any{
-													&litMatcher{val: ",", want: "\",\""},
-													&ruleIRefExpr{index: 123 /* sp */},
-													&ruleIRefExpr{index: 77 /* dict_item */},
-												},
+											expr: &charClassMatcher{
+												val:    "[0-9]",
+												ranges: []rune{'0', '9'},
 											},
 										},
-										&zeroOrOneExpr{
-											expr: &litMatcher{val: ",", want: "\",\""},
+										&litMatcher{val: ".", want: "\".\""},
+										&oneOrMoreExpr{
+											expr: &charClassMatcher{
+												val:    "[0-9]",
+												ranges: []rune{'0', '9'},
+											},
 										},
-										&litMatcher{val: "}", want: "\"}\""},
-										&ruleIRefExpr{index: 123 /* sp */},
+									},
+								},
+								&oneOrMoreExpr{
+									expr: &charClassMatcher{
+										val:    "[0-9]",
+										ranges: []rune{'0', '9'},
 									},
 								},
 							},
-							&seqExpr{
+						},
+						&litMatcher{val: "%", want: "\"%\""},
+						&notExpr{
+							expr: &seqExpr{
 								exprs: []any{
-									&ruleIRefExpr{index: 72 /* item_get */},
-									&ruleIRefExpr{index: 74 /* attr_get */},
+									&ruleIRefExpr{index: 168 /* spNoCR */},
+									&ruleIRefExpr{index: 117 /* percentNotFollow */},
 								},
 							},
 						},
@@ -2756,36 +4267,132 @@ var g = &grammar{
 			},
 		},
 		{
-			name: "number",
+			name: "percentNotFollow",
+			expr: &charClassMatcher{
+				val:     "[0-9(\\p{L}_$'\"`\\[{&\\x1e]",
+				chars:   []rune{'(', '_', '$', '\'', '"', '`', '[', '{', '&', '\x1e'},
+				ranges:  []rune{'0', '9'},
+				classes: []*unicode.RangeTable{unicode.L},
+			},
+		},
+		{
+			name: "money",
 			expr: &actionExpr{
-				run: (*parser).call_onnumber_1,
-				expr: &oneOrMoreExpr{
-					expr: &charClassMatcher{
-						val:    "[0-9]",
-						ranges: []rune{'0', '9'},
+				run: (*parser).call_onmoney_1,
+				expr: &seqExpr{
+					exprs: []any{
+						&andCodeExpr{run: (*parser).call_onmoney_3},
+						&oneOrMoreExpr{
+							expr: &seqExpr{
+								exprs: []any{
+									&oneOrMoreExpr{
+										expr: &charClassMatcher{
+											val:    "[0-9]",
+											ranges: []rune{'0', '9'},
+										},
+									},
+									&oneOrMoreExpr{
+										expr: &charClassMatcher{
+											val:     "[\\p{L}]",
+											classes: []*unicode.RangeTable{unicode.L},
+										},
+									},
+								},
+							},
+						},
+						&notExpr{
+							expr: &ruleIRefExpr{index: 138 /* xidContinue */},
+						},
 					},
 				},
 			},
 		},
 		{
-			name: "float",
+			name:      "quantity",
+			varExists: true,
 			expr: &actionExpr{
-				run: (*parser).call_onfloat_1,
+				run: (*parser).call_onquantity_1,
 				expr: &seqExpr{
 					exprs: []any{
-						&zeroOrMoreExpr{
-							expr: &charClassMatcher{
-								val:    "[0-9]",
-								ranges: []rune{'0', '9'},
+						&andCodeExpr{run: (*parser).call_onquantity_3},
+						&labeledExpr{
+							label: "n",
+							expr: &choiceExpr{
+								alternatives: []any{
+									&seqExpr{
+										exprs: []any{
+											&zeroOrMoreExpr{
+												expr: &charClassMatcher{
+													val:    "[0-9]",
+													ranges: []rune{'0', '9'},
+												},
+											},
+											&litMatcher{val: ".", want: "\".\""},
+											&oneOrMoreExpr{
+												expr: &charClassMatcher{
+													val:    "[0-9]",
+													ranges: []rune{'0', '9'},
+												},
+											},
+										},
+									},
+									&oneOrMoreExpr{
+										expr: &charClassMatcher{
+											val:    "[0-9]",
+											ranges: []rune{'0', '9'},
+										},
+									},
+								},
 							},
+							textCapture: true,
 						},
-						&litMatcher{val: ".", want: "\".\""},
+						&labeledExpr{
+							label: "u",
+							expr: &oneOrMoreExpr{
+								expr: &charClassMatcher{
+									val:     "[\\p{L}]",
+									classes: []*unicode.RangeTable{unicode.L},
+								},
+							},
+							textCapture: true,
+						},
+						&notExpr{
+							expr: &ruleIRefExpr{index: 138 /* xidContinue */},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "duration",
+			expr: &actionExpr{
+				run: (*parser).call_onduration_1,
+				expr: &seqExpr{
+					exprs: []any{
 						&oneOrMoreExpr{
-							expr: &charClassMatcher{
-								val:    "[0-9]",
-								ranges: []rune{'0', '9'},
+							expr: &seqExpr{
+								exprs: []any{
+									&oneOrMoreExpr{
+										expr: &charClassMatcher{
+											val:    "[0-9]",
+											ranges: []rune{'0', '9'},
+										},
+									},
+									&choiceExpr{
+										alternatives: []any{
+											&litMatcher{val: "ms", want: "\"ms\""},
+											&charClassMatcher{
+												val:   "[hms]",
+												chars: []rune{'h', 'm', 's'},
+											},
+										},
+									},
+								},
 							},
 						},
+						&notExpr{
+							expr: &ruleIRefExpr{index: 138 /* xidContinue */},
+						},
 					},
 				},
 			},
@@ -2800,8 +4407,8 @@ var g = &grammar{
 					expr: &oneOrMoreExpr{
 						expr: &choiceExpr{
 							alternatives: []any{
-								&ruleIRefExpr{index: 92 /* strEscape */},
-								&ruleIRefExpr{index: 85 /* strPart1Normal */},
+								&ruleIRefExpr{index: 129 /* strEscape */},
+								&ruleIRefExpr{index: 122 /* strPart1Normal */},
 							},
 						},
 					},
@@ -2838,8 +4445,8 @@ var g = &grammar{
 					expr: &oneOrMoreExpr{
 						expr: &choiceExpr{
 							alternatives: []any{
-								&ruleIRefExpr{index: 92 /* strEscape */},
-								&ruleIRefExpr{index: 87 /* strPart2Normal */},
+								&ruleIRefExpr{index: 129 /* strEscape */},
+								&ruleIRefExpr{index: 124 /* strPart2Normal */},
 							},
 						},
 					},
@@ -2876,8 +4483,8 @@ var g = &grammar{
 					expr: &oneOrMoreExpr{
 						expr: &choiceExpr{
 							alternatives: []any{
-								&ruleIRefExpr{index: 92 /* strEscape */},
-								&ruleIRefExpr{index: 89 /* strPart3Normal */},
+								&ruleIRefExpr{index: 129 /* strEscape */},
+								&ruleIRefExpr{index: 126 /* strPart3Normal */},
 							},
 						},
 					},
@@ -2914,8 +4521,8 @@ var g = &grammar{
 					expr: &oneOrMoreExpr{
 						expr: &choiceExpr{
 							alternatives: []any{
-								&ruleIRefExpr{index: 92 /* strEscape */},
-								&ruleIRefExpr{index: 91 /* strPart4Normal */},
+								&ruleIRefExpr{index: 129 /* strEscape */},
+								&ruleIRefExpr{index: 128 /* strPart4Normal */},
 							},
 						},
 					},
@@ -2994,7 +4601,7 @@ var g = &grammar{
 			expr: &seqExpr{
 				exprs: []any{
 					&litMatcher{val: "{%", want: "\"{%\""},
-					&ruleIRefExpr{index: 123 /* sp */},
+					&ruleIRefExpr{index: 165 /* sp */},
 					&choiceExpr{
 						alternatives: []any{
 							&seqExpr{
@@ -3011,7 +4618,7 @@ var g = &grammar{
 							&andCodeExpr{run: (*parser).call_onfstringStmt_9},
 						},
 					},
-					&ruleIRefExpr{index: 123 /* sp */},
+					&ruleIRefExpr{index: 165 /* sp */},
 					&litMatcher{val: "%}", want: "\"%}\""},
 				},
 			},
@@ -3021,7 +4628,7 @@ var g = &grammar{
 			expr: &seqExpr{
 				exprs: []any{
 					&litMatcher{val: "{", want: "\"{\""},
-					&ruleIRefExpr{index: 123 /* sp */},
+					&ruleIRefExpr{index: 165 /* sp */},
 					&choiceExpr{
 						alternatives: []any{
 							&seqExpr{
@@ -3038,7 +4645,7 @@ var g = &grammar{
 							&andCodeExpr{run: (*parser).call_onfstringStmt2_9},
 						},
 					},
-					&ruleIRefExpr{index: 123 /* sp */},
+					&ruleIRefExpr{index: 165 /* sp */},
 					&litMatcher{val: "}", want: "\"}\""},
 				},
 			},
@@ -3096,7 +4703,7 @@ var g = &grammar{
 										expr: &seqExpr{
 											exprs: []any{
 												&zeroOrMoreExpr{
-													expr: &ruleIRefExpr{index: 84 /* strPart1 */},
+													expr: &ruleIRefExpr{index: 121 /* strPart1 */},
 												},
 												&litMatcher{val: "'", want: "\"'\""},
 											},
@@ -3115,7 +4722,7 @@ var g = &grammar{
 										expr: &seqExpr{
 											exprs: []any{
 												&zeroOrMoreExpr{
-													expr: &ruleIRefExpr{index: 86 /* strPart2 */},
+													expr: &ruleIRefExpr{index: 123 /* strPart2 */},
 												},
 												&litMatcher{val: "\"", want: "\"\\\"\""},
 											},
@@ -3136,9 +4743,9 @@ var g = &grammar{
 												&zeroOrMoreExpr{
 													expr: &choiceExpr{
 														alternatives: []any{
-															&ruleIRefExpr{index: 88 /* strPart3 */},
-															&ruleIRefExpr{index: 93 /* fstringStmt */},
-															&ruleIRefExpr{index: 94 /* fstringStmt2 */},
+															&ruleIRefExpr{index: 125 /* strPart3 */},
+															&ruleIRefExpr{index: 130 /* fstringStmt */},
+															&ruleIRefExpr{index: 131 /* fstringStmt2 */},
 														},
 													},
 												},
@@ -3161,9 +4768,9 @@ var g = &grammar{
 												&zeroOrMoreExpr{
 													expr: &choiceExpr{
 														alternatives: []any{
-															&ruleIRefExpr{index: 90 /* strPart4 */},
-															&ruleIRefExpr{index: 93 /* fstringStmt */},
-															&ruleIRefExpr{index: 94 /* fstringStmt2 */},
+															&ruleIRefExpr{index: 127 /* strPart4 */},
+															&ruleIRefExpr{index: 130 /* fstringStmt */},
+															&ruleIRefExpr{index: 131 /* fstringStmt2 */},
 														},
 													},
 												},
@@ -3175,7 +4782,7 @@ var g = &grammar{
 							},
 						},
 					},
-					&ruleIRefExpr{index: 123 /* sp */},
+					&ruleIRefExpr{index: 165 /* sp */},
 				},
 			},
 		},
@@ -3190,6 +4797,8 @@ var g = &grammar{
 					&litMatcher{val: "break", want: "\"break\""},
 					&litMatcher{val: "return", want: "\"return\""},
 					&litMatcher{val: "func", want: "\"func\""},
+					&litMatcher{val: "for", want: "\"for\""},
+					&litMatcher{val: "yield", want: "\"yield\""},
 				},
 			},
 		},
@@ -3199,9 +4808,9 @@ var g = &grammar{
 			expr: &notExpr{
 				expr: &seqExpr{
 					exprs: []any{
-						&ruleIRefExpr{index: 96 /* keywords */},
+						&ruleIRefExpr{index: 133 /* keywords */},
 						&notExpr{
-							expr: &ruleIRefExpr{index: 101 /* xidContinue */},
+							expr: &ruleIRefExpr{index: 138 /* xidContinue */},
 						},
 						&andCodeExpr{run: (*parser).call_onkeywords_test_6},
 					},
@@ -3214,12 +4823,12 @@ var g = &grammar{
 				run: (*parser).call_onidentifier_1,
 				expr: &seqExpr{
 					exprs: []any{
-						&ruleIRefExpr{index: 97 /* keywords_test */},
-						&ruleIRefExpr{index: 100 /* xidStart */},
+						&ruleIRefExpr{index: 134 /* keywords_test */},
+						&ruleIRefExpr{index: 137 /* xidStart */},
 						&zeroOrMoreExpr{
 							expr: &choiceExpr{
 								alternatives: []any{
-									&ruleIRefExpr{index: 101 /* xidContinue */},
+									&ruleIRefExpr{index: 138 /* xidContinue */},
 									&litMatcher{val: ":", want: "\":\""},
 								},
 							},
@@ -3234,10 +4843,10 @@ var g = &grammar{
 				run: (*parser).call_onidentifierWithoutColon_1,
 				expr: &seqExpr{
 					exprs: []any{
-						&ruleIRefExpr{index: 97 /* keywords_test */},
-						&ruleIRefExpr{index: 100 /* xidStart */},
+						&ruleIRefExpr{index: 134 /* keywords_test */},
+						&ruleIRefExpr{index: 137 /* xidStart */},
 						&zeroOrMoreExpr{
-							expr: &ruleIRefExpr{index: 101 /* xidContinue */},
+							expr: &ruleIRefExpr{index: 138 /* xidContinue */},
 						},
 					},
 				},
@@ -3266,15 +4875,15 @@ var g = &grammar{
 					&andExpr{
 						expr: &seqExpr{
 							exprs: []any{
-								&ruleIRefExpr{index: 104 /* parenOpen */},
-								&ruleIRefExpr{index: 26 /* exprRoot */},
-								&ruleIRefExpr{index: 105 /* parenClose */},
+								&ruleIRefExpr{index: 141 /* parenOpen */},
+								&ruleIRefExpr{index: 34 /* exprRoot */},
+								&ruleIRefExpr{index: 142 /* parenClose */},
 							},
 						},
 					},
-					&ruleIRefExpr{index: 104 /* parenOpen */},
-					&ruleIRefExpr{index: 26 /* exprRoot */},
-					&ruleIRefExpr{index: 105 /* parenClose */},
+					&ruleIRefExpr{index: 141 /* parenOpen */},
+					&ruleIRefExpr{index: 34 /* exprRoot */},
+					&ruleIRefExpr{index: 142 /* parenClose */},
 				},
 			},
 		},
@@ -3282,9 +4891,9 @@ var g = &grammar{
 			name: "subX",
 			expr: &seqExpr{
 				exprs: []any{
-					&ruleIRefExpr{index: 102 /* sub */},
-					&ruleIRefExpr{index: 72 /* item_get */},
-					&ruleIRefExpr{index: 74 /* attr_get */},
+					&ruleIRefExpr{index: 139 /* sub */},
+					&ruleIRefExpr{index: 98 /* item_get */},
+					&ruleIRefExpr{index: 100 /* attr_get */},
 				},
 			},
 		},
@@ -3293,7 +4902,7 @@ var g = &grammar{
 			expr: &seqExpr{
 				exprs: []any{
 					&litMatcher{val: "(", want: "\"(\""},
-					&ruleIRefExpr{index: 123 /* sp */},
+					&ruleIRefExpr{index: 165 /* sp */},
 				},
 			},
 		},
@@ -3302,7 +4911,7 @@ var g = &grammar{
 			expr: &seqExpr{
 				exprs: []any{
 					&litMatcher{val: ")", want: "\")\""},
-					&ruleIRefExpr{index: 123 /* sp */},
+					&ruleIRefExpr{index: 165 /* sp */},
 				},
 			},
 		},
@@ -3316,7 +4925,7 @@ var g = &grammar{
 							&litMatcher{val: "＋", want: "\"＋\""},
 						},
 					},
-					&ruleIRefExpr{index: 123 /* sp */},
+					&ruleIRefExpr{index: 165 /* sp */},
 				},
 			},
 		},
@@ -3330,7 +4939,7 @@ var g = &grammar{
 							&litMatcher{val: "－", want: "\"－\""},
 						},
 					},
-					&ruleIRefExpr{index: 123 /* sp */},
+					&ruleIRefExpr{index: 165 /* sp */},
 				},
 			},
 		},
@@ -3344,7 +4953,7 @@ var g = &grammar{
 							&litMatcher{val: "＊", want: "\"＊\""},
 						},
 					},
-					&ruleIRefExpr{index: 123 /* sp */},
+					&ruleIRefExpr{index: 165 /* sp */},
 				},
 			},
 		},
@@ -3358,7 +4967,7 @@ var g = &grammar{
 							&litMatcher{val: "／", want: "\"／\""},
 						},
 					},
-					&ruleIRefExpr{index: 123 /* sp */},
+					&ruleIRefExpr{index: 165 /* sp */},
 				},
 			},
 		},
@@ -3367,7 +4976,7 @@ var g = &grammar{
 			expr: &seqExpr{
 				exprs: []any{
 					&litMatcher{val: "%", want: "\"%\""},
-					&ruleIRefExpr{index: 123 /* sp */},
+					&ruleIRefExpr{index: 165 /* sp */},
 				},
 			},
 		},
@@ -3377,14 +4986,15 @@ var g = &grammar{
 				alternatives: []any{
 					&seqExpr{
 						exprs: []any{
+							&andCodeExpr{run: (*parser).call_onexponentiation_3},
 							&litMatcher{val: "^", want: "\"^\""},
-							&ruleIRefExpr{index: 123 /* sp */},
+							&ruleIRefExpr{index: 165 /* sp */},
 						},
 					},
 					&seqExpr{
 						exprs: []any{
 							&litMatcher{val: "**", want: "\"**\""},
-							&ruleIRefExpr{index: 123 /* sp */},
+							&ruleIRefExpr{index: 165 /* sp */},
 						},
 					},
 				},
@@ -3395,7 +5005,7 @@ var g = &grammar{
 			expr: &seqExpr{
 				exprs: []any{
 					&litMatcher{val: "??", want: "\"??\""},
-					&ruleIRefExpr{index: 123 /* sp */},
+					&ruleIRefExpr{index: 165 /* sp */},
 				},
 			},
 		},
@@ -3404,7 +5014,7 @@ var g = &grammar{
 			expr: &seqExpr{
 				exprs: []any{
 					&litMatcher{val: "|", want: "\"|\""},
-					&ruleIRefExpr{index: 123 /* sp */},
+					&ruleIRefExpr{index: 165 /* sp */},
 				},
 			},
 		},
@@ -3413,7 +5023,34 @@ var g = &grammar{
 			expr: &seqExpr{
 				exprs: []any{
 					&litMatcher{val: "&", want: "\"&\""},
-					&ruleIRefExpr{index: 123 /* sp */},
+					&ruleIRefExpr{index: 165 /* sp */},
+				},
+			},
+		},
+		{
+			name: "bitwiseXor",
+			expr: &seqExpr{
+				exprs: []any{
+					&litMatcher{val: "^", want: "\"^\""},
+					&ruleIRefExpr{index: 165 /* sp */},
+				},
+			},
+		},
+		{
+			name: "shiftLeft",
+			expr: &seqExpr{
+				exprs: []any{
+					&litMatcher{val: "<<", want: "\"<<\""},
+					&ruleIRefExpr{index: 165 /* sp */},
+				},
+			},
+		},
+		{
+			name: "shiftRight",
+			expr: &seqExpr{
+				exprs: []any{
+					&litMatcher{val: ">>", want: "\">>\""},
+					&ruleIRefExpr{index: 165 /* sp */},
 				},
 			},
 		},
@@ -3422,7 +5059,7 @@ var g = &grammar{
 			expr: &seqExpr{
 				exprs: []any{
 					&litMatcher{val: "||", want: "\"||\""},
-					&ruleIRefExpr{index: 123 /* sp */},
+					&ruleIRefExpr{index: 165 /* sp */},
 				},
 			},
 		},
@@ -3431,7 +5068,19 @@ var g = &grammar{
 			expr: &seqExpr{
 				exprs: []any{
 					&litMatcher{val: "&&", want: "\"&&\""},
-					&ruleIRefExpr{index: 123 /* sp */},
+					&ruleIRefExpr{index: 165 /* sp */},
+				},
+			},
+		},
+		{
+			name: "logicNot",
+			expr: &seqExpr{
+				exprs: []any{
+					&litMatcher{val: "!", want: "\"!\""},
+					&notExpr{
+						expr: &litMatcher{val: "=", want: "\"=\""},
+					},
+					&ruleIRefExpr{index: 165 /* sp */},
 				},
 			},
 		},
@@ -3440,7 +5089,7 @@ var g = &grammar{
 			expr: &seqExpr{
 				exprs: []any{
 					&litMatcher{val: "<", want: "\"<\""},
-					&ruleIRefExpr{index: 123 /* sp */},
+					&ruleIRefExpr{index: 165 /* sp */},
 				},
 			},
 		},
@@ -3449,7 +5098,7 @@ var g = &grammar{
 			expr: &seqExpr{
 				exprs: []any{
 					&litMatcher{val: ">", want: "\">\""},
-					&ruleIRefExpr{index: 123 /* sp */},
+					&ruleIRefExpr{index: 165 /* sp */},
 				},
 			},
 		},
@@ -3458,7 +5107,7 @@ var g = &grammar{
 			expr: &seqExpr{
 				exprs: []any{
 					&litMatcher{val: "<=", want: "\"<=\""},
-					&ruleIRefExpr{index: 123 /* sp */},
+					&ruleIRefExpr{index: 165 /* sp */},
 				},
 			},
 		},
@@ -3467,7 +5116,19 @@ var g = &grammar{
 			expr: &seqExpr{
 				exprs: []any{
 					&litMatcher{val: ">=", want: "\">=\""},
-					&ruleIRefExpr{index: 123 /* sp */},
+					&ruleIRefExpr{index: 165 /* sp */},
+				},
+			},
+		},
+		{
+			name: "inOp",
+			expr: &seqExpr{
+				exprs: []any{
+					&litMatcher{val: "in", want: "\"in\""},
+					&notExpr{
+						expr: &ruleIRefExpr{index: 138 /* xidContinue */},
+					},
+					&ruleIRefExpr{index: 165 /* sp */},
 				},
 			},
 		},
@@ -3476,7 +5137,7 @@ var g = &grammar{
 			expr: &seqExpr{
 				exprs: []any{
 					&litMatcher{val: "==", want: "\"==\""},
-					&ruleIRefExpr{index: 123 /* sp */},
+					&ruleIRefExpr{index: 165 /* sp */},
 				},
 			},
 		},
@@ -3485,7 +5146,7 @@ var g = &grammar{
 			expr: &seqExpr{
 				exprs: []any{
 					&litMatcher{val: "!=", want: "\"!=\""},
-					&ruleIRefExpr{index: 123 /* sp */},
+					&ruleIRefExpr{index: 165 /* sp */},
 				},
 			},
 		},
@@ -3510,7 +5171,7 @@ var g = &grammar{
 								val:   "[ \\n\\t\\r]",
 								chars: []rune{' ', '\n', '\t', '\r'},
 							},
-							&ruleIRefExpr{index: 123 /* sp */},
+							&ruleIRefExpr{index: 165 /* sp */},
 						},
 					},
 					&notExpr{
@@ -3523,8 +5184,8 @@ var g = &grammar{
 			name: "sp1x",
 			expr: &seqExpr{
 				exprs: []any{
-					&ruleIRefExpr{index: 124 /* sp1 */},
-					&ruleIRefExpr{index: 123 /* sp */},
+					&ruleIRefExpr{index: 166 /* sp1 */},
+					&ruleIRefExpr{index: 165 /* sp */},
 				},
 			},
 		},
@@ -3541,9 +5202,9 @@ var g = &grammar{
 			name: "comment",
 			expr: &seqExpr{
 				exprs: []any{
-					&ruleIRefExpr{index: 126 /* spNoCR */},
+					&ruleIRefExpr{index: 168 /* spNoCR */},
 					&litMatcher{val: "//", want: "\"//\""},
-					&ruleIRefExpr{index: 128 /* commentLineRest */},
+					&ruleIRefExpr{index: 170 /* commentLineRest */},
 				},
 			},
 		},
@@ -3606,8 +5267,8 @@ var g = &grammar{
 			name: "st_expr",
 			expr: &choiceExpr{
 				alternatives: []any{
-					&ruleIRefExpr{index: 135 /* st_modify_multi_1 */},
-					&ruleIRefExpr{index: 132 /* st_assign_multi */},
+					&ruleIRefExpr{index: 177 /* st_modify_multi_1 */},
+					&ruleIRefExpr{index: 174 /* st_assign_multi */},
 				},
 			},
 		},
@@ -3620,7 +5281,7 @@ var g = &grammar{
 							&andExpr{
 								expr: &litMatcher{val: "(", want: "\"(\""},
 							},
-							&ruleIRefExpr{index: 26 /* exprRoot */},
+							&ruleIRefExpr{index: 34 /* exprRoot */},
 						},
 					},
 					&seqExpr{
@@ -3628,12 +5289,12 @@ var g = &grammar{
 							&actionExpr{
 								run: (*parser).call_onest_7,
 								expr: &andExpr{
-									expr: &ruleIRefExpr{index: 26 /* exprRoot */},
+									expr: &ruleIRefExpr{index: 34 /* exprRoot */},
 								},
 							},
 							&actionExpr{
 								run:  (*parser).call_onest_10,
-								expr: &ruleIRefExpr{index: 26 /* exprRoot */},
+								expr: &ruleIRefExpr{index: 34 /* exprRoot */},
 							},
 						},
 					},
@@ -3645,12 +5306,12 @@ var g = &grammar{
 			expr: &oneOrMoreExpr{
 				expr: &seqExpr{
 					exprs: []any{
-						&ruleIRefExpr{index: 134 /* st_assign */},
-						&ruleIRefExpr{index: 123 /* sp */},
+						&ruleIRefExpr{index: 176 /* st_assign */},
+						&ruleIRefExpr{index: 165 /* sp */},
 						&zeroOrOneExpr{
 							expr: &litMatcher{val: ",", want: "\",\""},
 						},
-						&ruleIRefExpr{index: 123 /* sp */},
+						&ruleIRefExpr{index: 165 /* sp */},
 					},
 				},
 			},
@@ -3660,12 +5321,12 @@ var g = &grammar{
 			expr: &seqExpr{
 				exprs: []any{
 					&litMatcher{val: "*", want: "\"*\""},
-					&ruleIRefExpr{index: 123 /* sp */},
+					&ruleIRefExpr{index: 165 /* sp */},
 					&choiceExpr{
 						alternatives: []any{
-							&ruleIRefExpr{index: 83 /* float */},
-							&ruleIRefExpr{index: 82 /* number */},
-							&ruleIRefExpr{index: 102 /* sub */},
+							&ruleIRefExpr{index: 113 /* float */},
+							&ruleIRefExpr{index: 112 /* number */},
+							&ruleIRefExpr{index: 139 /* sub */},
 						},
 					},
 				},
@@ -3683,29 +5344,29 @@ var g = &grammar{
 								&andExpr{
 									expr: &seqExpr{
 										exprs: []any{
-											&ruleIRefExpr{index: 142 /* st_name2 */},
-											&ruleIRefExpr{index: 123 /* sp */},
+											&ruleIRefExpr{index: 184 /* st_name2 */},
+											&ruleIRefExpr{index: 165 /* sp */},
 											&choiceExpr{
 												alternatives: []any{
 													&litMatcher{val: ":", want: "\":\""},
 													&litMatcher{val: "=", want: "\"=\""},
 												},
 											},
-											&ruleIRefExpr{index: 123 /* sp */},
-											&ruleIRefExpr{index: 131 /* est */},
+											&ruleIRefExpr{index: 165 /* sp */},
+											&ruleIRefExpr{index: 173 /* est */},
 										},
 									},
 								},
-								&ruleIRefExpr{index: 142 /* st_name2 */},
-								&ruleIRefExpr{index: 123 /* sp */},
+								&ruleIRefExpr{index: 184 /* st_name2 */},
+								&ruleIRefExpr{index: 165 /* sp */},
 								&choiceExpr{
 									alternatives: []any{
 										&litMatcher{val: ":", want: "\":\""},
 										&litMatcher{val: "=", want: "\"=\""},
 									},
 								},
-								&ruleIRefExpr{index: 123 /* sp */},
-								&ruleIRefExpr{index: 131 /* est */},
+								&ruleIRefExpr{index: 165 /* sp */},
+								&ruleIRefExpr{index: 173 /* est */},
 							},
 						},
 					},
@@ -3716,13 +5377,13 @@ var g = &grammar{
 								&andExpr{
 									expr: &seqExpr{
 										exprs: []any{
-											&ruleIRefExpr{index: 140 /* st_name1 */},
-											&ruleIRefExpr{index: 131 /* est */},
+											&ruleIRefExpr{index: 182 /* st_name1 */},
+											&ruleIRefExpr{index: 173 /* est */},
 										},
 									},
 								},
-								&ruleIRefExpr{index: 140 /* st_name1 */},
-								&ruleIRefExpr{index: 131 /* est */},
+								&ruleIRefExpr{index: 182 /* st_name1 */},
+								&ruleIRefExpr{index: 173 /* est */},
 							},
 						},
 					},
@@ -3733,33 +5394,33 @@ var g = &grammar{
 								&andExpr{
 									expr: &seqExpr{
 										exprs: []any{
-											&ruleIRefExpr{index: 143 /* st_name2r */},
-											&ruleIRefExpr{index: 123 /* sp */},
-											&ruleIRefExpr{index: 133 /* st_star */},
-											&ruleIRefExpr{index: 123 /* sp */},
+											&ruleIRefExpr{index: 185 /* st_name2r */},
+											&ruleIRefExpr{index: 165 /* sp */},
+											&ruleIRefExpr{index: 175 /* st_star */},
+											&ruleIRefExpr{index: 165 /* sp */},
 											&choiceExpr{
 												alternatives: []any{
 													&litMatcher{val: ":", want: "\":\""},
 													&litMatcher{val: "=", want: "\"=\""},
 												},
 											},
-											&ruleIRefExpr{index: 123 /* sp */},
-											&ruleIRefExpr{index: 131 /* est */},
+											&ruleIRefExpr{index: 165 /* sp */},
+											&ruleIRefExpr{index: 173 /* est */},
 										},
 									},
 								},
-								&ruleIRefExpr{index: 143 /* st_name2r */},
-								&ruleIRefExpr{index: 123 /* sp */},
-								&ruleIRefExpr{index: 133 /* st_star */},
-								&ruleIRefExpr{index: 123 /* sp */},
+								&ruleIRefExpr{index: 185 /* st_name2r */},
+								&ruleIRefExpr{index: 165 /* sp */},
+								&ruleIRefExpr{index: 175 /* st_star */},
+								&ruleIRefExpr{index: 165 /* sp */},
 								&choiceExpr{
 									alternatives: []any{
 										&litMatcher{val: ":", want: "\":\""},
 										&litMatcher{val: "=", want: "\"=\""},
 									},
 								},
-								&ruleIRefExpr{index: 123 /* sp */},
-								&ruleIRefExpr{index: 131 /* est */},
+								&ruleIRefExpr{index: 165 /* sp */},
+								&ruleIRefExpr{index: 173 /* est */},
 							},
 						},
 					},
@@ -3770,33 +5431,33 @@ var g = &grammar{
 								&andExpr{
 									expr: &seqExpr{
 										exprs: []any{
-											&ruleIRefExpr{index: 143 /* st_name2r */},
-											&ruleIRefExpr{index: 123 /* sp */},
+											&ruleIRefExpr{index: 185 /* st_name2r */},
+											&ruleIRefExpr{index: 165 /* sp */},
 											&litMatcher{val: "*", want: "\"*\""},
-											&ruleIRefExpr{index: 123 /* sp */},
+											&ruleIRefExpr{index: 165 /* sp */},
 											&choiceExpr{
 												alternatives: []any{
 													&litMatcher{val: ":", want: "\":\""},
 													&litMatcher{val: "=", want: "\"=\""},
 												},
 											},
-											&ruleIRefExpr{index: 123 /* sp */},
-											&ruleIRefExpr{index: 131 /* est */},
+											&ruleIRefExpr{index: 165 /* sp */},
+											&ruleIRefExpr{index: 173 /* est */},
 										},
 									},
 								},
-								&ruleIRefExpr{index: 143 /* st_name2r */},
-								&ruleIRefExpr{index: 123 /* sp */},
+								&ruleIRefExpr{index: 185 /* st_name2r */},
+								&ruleIRefExpr{index: 165 /* sp */},
 								&litMatcher{val: "*", want: "\"*\""},
-								&ruleIRefExpr{index: 123 /* sp */},
+								&ruleIRefExpr{index: 165 /* sp */},
 								&choiceExpr{
 									alternatives: []any{
 										&litMatcher{val: ":", want: "\":\""},
 										&litMatcher{val: "=", want: "\"=\""},
 									},
 								},
-								&ruleIRefExpr{index: 123 /* sp */},
-								&ruleIRefExpr{index: 131 /* est */},
+								&ruleIRefExpr{index: 165 /* sp */},
+								&ruleIRefExpr{index: 173 /* est */},
 							},
 						},
 					},
@@ -3807,29 +5468,29 @@ var g = &grammar{
 								&andExpr{
 									expr: &seqExpr{
 										exprs: []any{
-											&ruleIRefExpr{index: 143 /* st_name2r */},
-											&ruleIRefExpr{index: 123 /* sp */},
+											&ruleIRefExpr{index: 185 /* st_name2r */},
+											&ruleIRefExpr{index: 165 /* sp */},
 											&choiceExpr{
 												alternatives: []any{
 													&litMatcher{val: ":", want: "\":\""},
 													&litMatcher{val: "=", want: "\"=\""},
 												},
 											},
-											&ruleIRefExpr{index: 123 /* sp */},
-											&ruleIRefExpr{index: 131 /* est */},
+											&ruleIRefExpr{index: 165 /* sp */},
+											&ruleIRefExpr{index: 173 /* est */},
 										},
 									},
 								},
-								&ruleIRefExpr{index: 143 /* st_name2r */},
-								&ruleIRefExpr{index: 123 /* sp */},
+								&ruleIRefExpr{index: 185 /* st_name2r */},
+								&ruleIRefExpr{index: 165 /* sp */},
 								&choiceExpr{
 									alternatives: []any{
 										&litMatcher{val: ":", want: "\":\""},
 										&litMatcher{val: "=", want: "\"=\""},
 									},
 								},
-								&ruleIRefExpr{index: 123 /* sp */},
-								&ruleIRefExpr{index: 131 /* est */},
+								&ruleIRefExpr{index: 165 /* sp */},
+								&ruleIRefExpr{index: 173 /* est */},
 							},
 						},
 					},
@@ -3840,13 +5501,13 @@ var g = &grammar{
 								&andExpr{
 									expr: &seqExpr{
 										exprs: []any{
-											&ruleIRefExpr{index: 141 /* st_name1r */},
-											&ruleIRefExpr{index: 131 /* est */},
+											&ruleIRefExpr{index: 183 /* st_name1r */},
+											&ruleIRefExpr{index: 173 /* est */},
 										},
 									},
 								},
-								&ruleIRefExpr{index: 141 /* st_name1r */},
-								&ruleIRefExpr{index: 131 /* est */},
+								&ruleIRefExpr{index: 183 /* st_name1r */},
+								&ruleIRefExpr{index: 173 /* est */},
 							},
 						},
 					},
@@ -3860,28 +5521,28 @@ var g = &grammar{
 											expr: &seqExpr{
 												exprs: []any{
 													&litMatcher{val: "&", want: "\"&\""},
-													&ruleIRefExpr{index: 142 /* st_name2 */},
-													&ruleIRefExpr{index: 123 /* sp */},
+													&ruleIRefExpr{index: 184 /* st_name2 */},
+													&ruleIRefExpr{index: 165 /* sp */},
 													&choiceExpr{
 														alternatives: []any{
 															&litMatcher{val: ":", want: "\":\""},
 															&litMatcher{val: "=", want: "\"=\""},
 														},
 													},
-													&ruleIRefExpr{index: 131 /* est */},
+													&ruleIRefExpr{index: 173 /* est */},
 												},
 											},
 										},
 										&litMatcher{val: "&", want: "\"&\""},
-										&ruleIRefExpr{index: 142 /* st_name2 */},
-										&ruleIRefExpr{index: 123 /* sp */},
+										&ruleIRefExpr{index: 184 /* st_name2 */},
+										&ruleIRefExpr{index: 165 /* sp */},
 										&choiceExpr{
 											alternatives: []any{
 												&litMatcher{val: ":", want: "\":\""},
 												&litMatcher{val: "=", want: "\"=\""},
 											},
 										},
-										&ruleIRefExpr{index: 123 /* sp */},
+										&ruleIRefExpr{index: 165 /* sp */},
 									},
 								},
 							},
@@ -3889,7 +5550,7 @@ var g = &grammar{
 								run: (*parser).call_onst_assign_117,
 								expr: &labeledExpr{
 									label:       "text",
-									expr:        &ruleIRefExpr{index: 131 /* est */},
+									expr:        &ruleIRefExpr{index: 173 /* est */},
 									textCapture: true,
 								},
 							},
@@ -3905,28 +5566,28 @@ var g = &grammar{
 											expr: &seqExpr{
 												exprs: []any{
 													&litMatcher{val: "&", want: "\"&\""},
-													&ruleIRefExpr{index: 143 /* st_name2r */},
-													&ruleIRefExpr{index: 123 /* sp */},
+													&ruleIRefExpr{index: 185 /* st_name2r */},
+													&ruleIRefExpr{index: 165 /* sp */},
 													&choiceExpr{
 														alternatives: []any{
 															&litMatcher{val: ":", want: "\":\""},
 															&litMatcher{val: "=", want: "\"=\""},
 														},
 													},
-													&ruleIRefExpr{index: 131 /* est */},
+													&ruleIRefExpr{index: 173 /* est */},
 												},
 											},
 										},
 										&litMatcher{val: "&", want: "\"&\""},
-										&ruleIRefExpr{index: 143 /* st_name2r */},
-										&ruleIRefExpr{index: 123 /* sp */},
+										&ruleIRefExpr{index: 185 /* st_name2r */},
+										&ruleIRefExpr{index: 165 /* sp */},
 										&choiceExpr{
 											alternatives: []any{
 												&litMatcher{val: ":", want: "\":\""},
 												&litMatcher{val: "=", want: "\"=\""},
 											},
 										},
-										&ruleIRefExpr{index: 123 /* sp */},
+										&ruleIRefExpr{index: 165 /* sp */},
 									},
 								},
 							},
@@ -3934,7 +5595,7 @@ var g = &grammar{
 								run: (*parser).call_onst_assign_139,
 								expr: &labeledExpr{
 									label:       "text",
-									expr:        &ruleIRefExpr{index: 131 /* est */},
+									expr:        &ruleIRefExpr{index: 173 /* est */},
 									textCapture: true,
 								},
 							},
@@ -3949,15 +5610,15 @@ var g = &grammar{
 				exprs: []any{
 					&seqExpr{
 						exprs: []any{
-							&ruleIRefExpr{index: 136 /* st_modify_lead */},
-							&ruleIRefExpr{index: 123 /* sp */},
+							&ruleIRefExpr{index: 178 /* st_modify_lead */},
+							&ruleIRefExpr{index: 165 /* sp */},
 							&zeroOrOneExpr{
 								expr: &litMatcher{val: ",", want: "\",\""},
 							},
-							&ruleIRefExpr{index: 123 /* sp */},
+							&ruleIRefExpr{index: 165 /* sp */},
 						},
 					},
-					&ruleIRefExpr{index: 137 /* st_modify_multi_rest */},
+					&ruleIRefExpr{index: 179 /* st_modify_multi_rest */},
 				},
 			},
 		},
@@ -3970,13 +5631,13 @@ var g = &grammar{
 							&andExpr{
 								expr: &seqExpr{
 									exprs: []any{
-										&ruleIRefExpr{index: 142 /* st_name2 */},
-										&ruleIRefExpr{index: 138 /* st_modify_rest1 */},
+										&ruleIRefExpr{index: 184 /* st_name2 */},
+										&ruleIRefExpr{index: 180 /* st_modify_rest1 */},
 									},
 								},
 							},
-							&ruleIRefExpr{index: 142 /* st_name2 */},
-							&ruleIRefExpr{index: 138 /* st_modify_rest1 */},
+							&ruleIRefExpr{index: 184 /* st_name2 */},
+							&ruleIRefExpr{index: 180 /* st_modify_rest1 */},
 						},
 					},
 					&seqExpr{
@@ -3984,13 +5645,13 @@ var g = &grammar{
 							&andExpr{
 								expr: &seqExpr{
 									exprs: []any{
-										&ruleIRefExpr{index: 143 /* st_name2r */},
-										&ruleIRefExpr{index: 138 /* st_modify_rest1 */},
+										&ruleIRefExpr{index: 185 /* st_name2r */},
+										&ruleIRefExpr{index: 180 /* st_modify_rest1 */},
 									},
 								},
 							},
-							&ruleIRefExpr{index: 143 /* st_name2r */},
-							&ruleIRefExpr{index: 138 /* st_modify_rest1 */},
+							&ruleIRefExpr{index: 185 /* st_name2r */},
+							&ruleIRefExpr{index: 180 /* st_modify_rest1 */},
 						},
 					},
 					&seqExpr{
@@ -3998,13 +5659,13 @@ var g = &grammar{
 							&andExpr{
 								expr: &seqExpr{
 									exprs: []any{
-										&ruleIRefExpr{index: 140 /* st_name1 */},
-										&ruleIRefExpr{index: 139 /* st_modify_rest */},
+										&ruleIRefExpr{index: 182 /* st_name1 */},
+										&ruleIRefExpr{index: 181 /* st_modify_rest */},
 									},
 								},
 							},
-							&ruleIRefExpr{index: 140 /* st_name1 */},
-							&ruleIRefExpr{index: 139 /* st_modify_rest */},
+							&ruleIRefExpr{index: 182 /* st_name1 */},
+							&ruleIRefExpr{index: 181 /* st_modify_rest */},
 						},
 					},
 					&seqExpr{
@@ -4012,13 +5673,13 @@ var g = &grammar{
 							&andExpr{
 								expr: &seqExpr{
 									exprs: []any{
-										&ruleIRefExpr{index: 141 /* st_name1r */},
-										&ruleIRefExpr{index: 139 /* st_modify_rest */},
+										&ruleIRefExpr{index: 183 /* st_name1r */},
+										&ruleIRefExpr{index: 181 /* st_modify_rest */},
 									},
 								},
 							},
-							&ruleIRefExpr{index: 141 /* st_name1r */},
-							&ruleIRefExpr{index: 139 /* st_modify_rest */},
+							&ruleIRefExpr{index: 183 /* st_name1r */},
+							&ruleIRefExpr{index: 181 /* st_modify_rest */},
 						},
 					},
 				},
@@ -4029,12 +5690,12 @@ var g = &grammar{
 			expr: &zeroOrMoreExpr{
 				expr: &seqExpr{
 					exprs: []any{
-						&ruleIRefExpr{index: 136 /* st_modify_lead */},
-						&ruleIRefExpr{index: 123 /* sp */},
+						&ruleIRefExpr{index: 178 /* st_modify_lead */},
+						&ruleIRefExpr{index: 165 /* sp */},
 						&zeroOrOneExpr{
 							expr: &litMatcher{val: ",", want: "\",\""},
 						},
-						&ruleIRefExpr{index: 123 /* sp */},
+						&ruleIRefExpr{index: 165 /* sp */},
 					},
 				},
 			},
@@ -4044,7 +5705,7 @@ var g = &grammar{
 			varExists: true,
 			expr: &seqExpr{
 				exprs: []any{
-					&ruleIRefExpr{index: 123 /* sp */},
+					&ruleIRefExpr{index: 165 /* sp */},
 					&choiceExpr{
 						alternatives: []any{
 							&actionExpr{
@@ -4052,10 +5713,10 @@ var g = &grammar{
 								expr: &seqExpr{
 									exprs: []any{
 										&litMatcher{val: "+=", want: "\"+=\""},
-										&ruleIRefExpr{index: 123 /* sp */},
+										&ruleIRefExpr{index: 165 /* sp */},
 										&labeledExpr{
 											label:       "text",
-											expr:        &ruleIRefExpr{index: 26 /* exprRoot */},
+											expr:        &ruleIRefExpr{index: 34 /* exprRoot */},
 											textCapture: true,
 										},
 									},
@@ -4066,10 +5727,10 @@ var g = &grammar{
 								expr: &seqExpr{
 									exprs: []any{
 										&litMatcher{val: "-=", want: "\"-=\""},
-										&ruleIRefExpr{index: 123 /* sp */},
+										&ruleIRefExpr{index: 165 /* sp */},
 										&labeledExpr{
 											label:       "text",
-											expr:        &ruleIRefExpr{index: 26 /* exprRoot */},
+											expr:        &ruleIRefExpr{index: 34 /* exprRoot */},
 											textCapture: true,
 										},
 									},
@@ -4085,7 +5746,7 @@ var g = &grammar{
 			varExists: true,
 			expr: &seqExpr{
 				exprs: []any{
-					&ruleIRefExpr{index: 123 /* sp */},
+					&ruleIRefExpr{index: 165 /* sp */},
 					&choiceExpr{
 						alternatives: []any{
 							&actionExpr{
@@ -4096,10 +5757,10 @@ var g = &grammar{
 										&zeroOrOneExpr{
 											expr: &litMatcher{val: "=", want: "\"=\""},
 										},
-										&ruleIRefExpr{index: 123 /* sp */},
+										&ruleIRefExpr{index: 165 /* sp */},
 										&labeledExpr{
 											label:       "text",
-											expr:        &ruleIRefExpr{index: 26 /* exprRoot */},
+											expr:        &ruleIRefExpr{index: 34 /* exprRoot */},
 											textCapture: true,
 										},
 									},
@@ -4110,10 +5771,10 @@ var g = &grammar{
 								expr: &seqExpr{
 									exprs: []any{
 										&litMatcher{val: "-=", want: "\"-=\""},
-										&ruleIRefExpr{index: 123 /* sp */},
+										&ruleIRefExpr{index: 165 /* sp */},
 										&labeledExpr{
 											label:       "text",
-											expr:        &ruleIRefExpr{index: 26 /* exprRoot */},
+											expr:        &ruleIRefExpr{index: 34 /* exprRoot */},
 											textCapture: true,
 										},
 									},
@@ -4126,10 +5787,10 @@ var g = &grammar{
 										&andExpr{
 											expr: &litMatcher{val: "-", want: "\"-\""},
 										},
-										&ruleIRefExpr{index: 123 /* sp */},
+										&ruleIRefExpr{index: 165 /* sp */},
 										&labeledExpr{
 											label:       "text",
-											expr:        &ruleIRefExpr{index: 26 /* exprRoot */},
+											expr:        &ruleIRefExpr{index: 34 /* exprRoot */},
 											textCapture: true,
 										},
 									},
@@ -4150,11 +5811,11 @@ var g = &grammar{
 					expr: &seqExpr{
 						exprs: []any{
 							&oneOrMoreExpr{
-								expr: &ruleIRefExpr{index: 144 /* id_ch */},
+								expr: &ruleIRefExpr{index: 186 /* id_ch */},
 							},
 							&litMatcher{val: ":", want: "\":\""},
 							&oneOrMoreExpr{
-								expr: &ruleIRefExpr{index: 144 /* id_ch */},
+								expr: &ruleIRefExpr{index: 186 /* id_ch */},
 							},
 						},
 					},
@@ -4172,7 +5833,7 @@ var g = &grammar{
 						expr: &labeledExpr{
 							label: "text",
 							expr: &oneOrMoreExpr{
-								expr: &ruleIRefExpr{index: 144 /* id_ch */},
+								expr: &ruleIRefExpr{index: 186 /* id_ch */},
 							},
 							textCapture: true,
 						},
@@ -4187,7 +5848,7 @@ var g = &grammar{
 									expr: &oneOrMoreExpr{
 										expr: &choiceExpr{
 											alternatives: []any{
-												&ruleIRefExpr{index: 144 /* id_ch */},
+												&ruleIRefExpr{index: 186 /* id_ch */},
 												&charClassMatcher{
 													val:    "[0-9]",
 													ranges: []rune{'0', '9'},
@@ -4208,7 +5869,7 @@ var g = &grammar{
 		},
 		{
 			name: "st_name2",
-			expr: &ruleIRefExpr{index: 140 /* st_name1 */},
+			expr: &ruleIRefExpr{index: 182 /* st_name1 */},
 		},
 		{
 			name:      "st_name2r",
@@ -4220,7 +5881,7 @@ var g = &grammar{
 						expr: &labeledExpr{
 							label: "text",
 							expr: &oneOrMoreExpr{
-								expr: &ruleIRefExpr{index: 144 /* id_ch */},
+								expr: &ruleIRefExpr{index: 186 /* id_ch */},
 							},
 							textCapture: true,
 						},
@@ -4235,7 +5896,7 @@ var g = &grammar{
 									expr: &oneOrMoreExpr{
 										expr: &choiceExpr{
 											alternatives: []any{
-												&ruleIRefExpr{index: 144 /* id_ch */},
+												&ruleIRefExpr{index: 186 /* id_ch */},
 												&charClassMatcher{
 													val:    "[0-9]",
 													ranges: []rune{'0', '9'},
@@ -4256,7 +5917,7 @@ var g = &grammar{
 		},
 		{
 			name: "id_ch",
-			expr: &ruleIRefExpr{index: 100 /* xidStart */},
+			expr: &ruleIRefExpr{index: 137 /* xidStart */},
 		},
 	},
 }
@@ -4316,6 +5977,42 @@ func (p *parser) call_onstmtContinue_1() any {
 	})(&p.cur)
 }
 
+func (p *parser) call_onstmtDel_3() bool {
+	return (func(c *current) bool {
+		return !c.data.Config.DisableStmts
+	})(&p.cur)
+}
+
+func (p *parser) call_onstmtDel_1() any {
+	stack := p.vstack[len(p.vstack)-1]
+	return (func(c *current, id any) any {
+		c.data.AddDelete(id.(string))
+		return nil
+	})(&p.cur, stack["id"])
+}
+
+func (p *parser) call_onstmtConst_4() bool {
+	return (func(c *current) bool {
+		return !c.data.Config.DisableStmts
+	})(&p.cur)
+}
+
+func (p *parser) call_onstmtConst_2() any {
+	stack := p.vstack[len(p.vstack)-1]
+	return (func(c *current, id any) any {
+		c.data.NamePush(id.(string))
+		return nil
+	})(&p.cur, stack["id"])
+}
+
+func (p *parser) call_onstmtConst_12() any {
+	stack := p.vstack[len(p.vstack)-1]
+	return (func(c *current, id any) any {
+		c.data.AddStoreConst(c.data.NamePop())
+		return nil
+	})(&p.cur, stack["id"])
+}
+
 func (p *parser) call_onstmtReturn_2() any {
 	return (func(c *current) any {
 		c.data.AddOp(typeReturn)
@@ -4331,6 +6028,21 @@ func (p *parser) call_onstmtReturn_7() any {
 	})(&p.cur)
 }
 
+func (p *parser) call_onstmtYield_2() any {
+	return (func(c *current) any {
+		c.data.AddOp(typeYield)
+		return nil
+	})(&p.cur)
+}
+
+func (p *parser) call_onstmtYield_7() any {
+	return (func(c *current) any {
+		c.data.PushNull()
+		c.data.AddOp(typeYield)
+		return nil
+	})(&p.cur)
+}
+
 func (p *parser) call_onstmtWhile_2() any {
 	return (func(c *current) any {
 		c.data.AddOp(typeBlockPush)
@@ -4363,6 +6075,44 @@ func (p *parser) call_onstmtWhile_10() any {
 	})(&p.cur)
 }
 
+func (p *parser) call_onstmtFor_2() any {
+	stack := p.vstack[len(p.vstack)-1]
+	return (func(c *current, id any) any {
+		c.data.NamePush(id.(string))
+		c.data.AddOp(typeBlockPush)
+		return nil
+	})(&p.cur, stack["id"])
+}
+
+func (p *parser) call_onstmtFor_13() any {
+	stack := p.vstack[len(p.vstack)-1]
+	return (func(c *current, id any) any {
+		c.data.AddOp(typeIterBegin)
+		c.data.LoopBegin()
+		c.data.OffsetPush()
+		c.data.WriteCode(typeIterNext, c.data.NamePop())
+		c.data.AddOp(typeJne)
+		c.data.OffsetPush()
+		return nil
+	})(&p.cur, stack["id"])
+}
+
+func (p *parser) call_onstmtFor_17() any {
+	stack := p.vstack[len(p.vstack)-1]
+	return (func(c *current, id any) any {
+		c.data.AddOp(typeJmp)
+		c.data.OffsetPush()
+		c.data.OffsetJmpSetX(0, 2, true)
+		c.data.OffsetJmpSetX(1, 1, false)
+		c.data.ContinueSet(2)
+		c.data.BreakSet()
+		c.data.OffsetPopN(3)
+		c.data.LoopEnd()
+		c.data.AddOp(typeBlockPop)
+		return nil
+	})(&p.cur, stack["id"])
+}
+
 func (p *parser) call_onstmtIf_6() any {
 	return (func(c *current) any {
 		c.data.AddOp(typeBlockPush)
@@ -4477,10 +6227,34 @@ func (p *parser) call_onstmtAssignType1_7() any {
 	return (func(c *current, id any) any {
 		c.data.AddStore(c.data.NamePop())
 		return nil
-	})(&p.cur, stack["id"])
+	})(&p.cur, stack["id"])
+}
+
+func (p *parser) call_onstmtAssignType2_2() any {
+	stack := p.vstack[len(p.vstack)-1]
+	return (func(c *current, id any) any {
+		c.data.NamePush(id.(string))
+		return nil
+	})(&p.cur, stack["id"])
+}
+
+func (p *parser) call_onstmtAssignType2_8() any {
+	stack := p.vstack[len(p.vstack)-1]
+	return (func(c *current, id any) any {
+		c.data.CodePush(p.pt.offset)
+		return nil
+	})(&p.cur, stack["id"])
+}
+
+func (p *parser) call_onstmtAssignType2_12() any {
+	stack := p.vstack[len(p.vstack)-1]
+	return (func(c *current, id, expr any) any {
+		c.data.AddStoreComputed(c.data.NamePop(), expr.(string))
+		return nil
+	})(&p.cur, stack["id"], stack["expr"])
 }
 
-func (p *parser) call_onstmtAssignType2_2() any {
+func (p *parser) call_onstmtAssignType9_2() any {
 	stack := p.vstack[len(p.vstack)-1]
 	return (func(c *current, id any) any {
 		c.data.NamePush(id.(string))
@@ -4488,7 +6262,7 @@ func (p *parser) call_onstmtAssignType2_2() any {
 	})(&p.cur, stack["id"])
 }
 
-func (p *parser) call_onstmtAssignType2_8() any {
+func (p *parser) call_onstmtAssignType9_8() any {
 	stack := p.vstack[len(p.vstack)-1]
 	return (func(c *current, id any) any {
 		c.data.CodePush(p.pt.offset)
@@ -4496,10 +6270,15 @@ func (p *parser) call_onstmtAssignType2_8() any {
 	})(&p.cur, stack["id"])
 }
 
-func (p *parser) call_onstmtAssignType2_12() any {
+func (p *parser) call_onstmtAssignType9_13() any {
 	stack := p.vstack[len(p.vstack)-1]
 	return (func(c *current, id, expr any) any {
-		c.data.AddStoreComputed(c.data.NamePop(), expr.(string))
+		num := c.data.CounterPop()
+		arr := []string{}
+		for i := IntType(0); i < num; i++ {
+			arr = append(arr, c.data.NamePop())
+		}
+		c.data.AddStoreComputedWithParams(c.data.NamePop(), arr, expr.(string))
 		return nil
 	})(&p.cur, stack["id"], stack["expr"])
 }
@@ -4570,6 +6349,37 @@ func (p *parser) call_onstmtAssignType5_14() any {
 	})(&p.cur, stack["id"], stack["id2"])
 }
 
+func (p *parser) call_onstmtAssignType8_2() any {
+	stack := p.vstack[len(p.vstack)-1]
+	return (func(c *current, id any) any {
+		c.data.NamePush(id.(string))
+		c.data.CounterPush()
+		return nil
+	})(&p.cur, stack["id"])
+}
+
+func (p *parser) call_onstmtAssignType8_10() any {
+	stack := p.vstack[len(p.vstack)-1]
+	return (func(c *current, id2 any) any {
+		c.data.NamePush(id2.(string))
+		c.data.CounterAdd(1)
+		return nil
+	})(&p.cur, stack["id2"])
+}
+
+func (p *parser) call_onstmtAssignType8_7() any {
+	stack := p.vstack[len(p.vstack)-1]
+	return (func(c *current, id any) any {
+		num := c.data.CounterPop()
+		path := make([]string, num)
+		for i := num - 1; i >= 0; i-- {
+			path[i] = c.data.NamePop()
+		}
+		c.data.AddAttrSetPath(c.data.NamePop(), path)
+		return nil
+	})(&p.cur, stack["id"])
+}
+
 func (p *parser) call_onstmtAssignType6_1() any {
 	return (func(c *current) any {
 		c.data.AddOp(typeItemSet)
@@ -4584,6 +6394,61 @@ func (p *parser) call_onstmtAssignType7_1() any {
 	})(&p.cur)
 }
 
+func (p *parser) call_onstmtAssignType11_2() any {
+	stack := p.vstack[len(p.vstack)-1]
+	return (func(c *current, id any) any {
+		c.data.NamePush(id.(string))
+		c.data.AddLoadName(id.(string))
+		return nil
+	})(&p.cur, stack["id"])
+}
+
+func (p *parser) call_onstmtAssignType11_7() any {
+	stack := p.vstack[len(p.vstack)-1]
+	return (func(c *current, id, op any) any {
+		c.data.AddStoreCompound(c.data.NamePop(), op.(string))
+		return nil
+	})(&p.cur, stack["id"], stack["op"])
+}
+
+func (p *parser) call_onstmtAssignType10_2() any {
+	return (func(c *current) any {
+		c.data.CounterPush()
+		return nil
+	})(&p.cur)
+}
+
+func (p *parser) call_onstmtAssignType10_4() any {
+	stack := p.vstack[len(p.vstack)-1]
+	return (func(c *current, id any) any {
+		c.data.NamePush(id.(string))
+		c.data.CounterAdd(1)
+		return nil
+	})(&p.cur, stack["id"])
+}
+
+func (p *parser) call_onstmtAssignType10_12() any {
+	stack := p.vstack[len(p.vstack)-1]
+	return (func(c *current, id2 any) any {
+		c.data.NamePush(id2.(string))
+		c.data.CounterAdd(1)
+		return nil
+	})(&p.cur, stack["id2"])
+}
+
+func (p *parser) call_onstmtAssignType10_9() any {
+	stack := p.vstack[len(p.vstack)-1]
+	return (func(c *current, id any) any {
+		num := c.data.CounterPop()
+		names := make([]string, num)
+		for i := num - 1; i >= 0; i-- {
+			names[i] = c.data.NamePop()
+		}
+		c.data.AddStoreUnpack(names)
+		return nil
+	})(&p.cur, stack["id"])
+}
+
 func (p *parser) call_on_step_7() any {
 	return (func(c *current) any {
 		c.data.PushNull()
@@ -4685,6 +6550,69 @@ func (p *parser) call_onexprTernaryType2_4() any {
 	})(&p.cur)
 }
 
+func (p *parser) call_onexprMatch_2() any {
+	return (func(c *current) any {
+		c.data.MatchBegin()
+		return nil
+	})(&p.cur)
+}
+
+func (p *parser) call_onexprMatch_10() any {
+	return (func(c *current) any {
+		c.data.MatchEnd()
+		return nil
+	})(&p.cur)
+}
+
+func (p *parser) call_onmatchArm_3() any {
+	return (func(c *current) any {
+		c.data.MatchArmBody(true)
+		return nil
+	})(&p.cur)
+}
+
+func (p *parser) call_onmatchArm_11() any {
+	return (func(c *current) any {
+		c.data.MatchArmEnd()
+		return nil
+	})(&p.cur)
+}
+
+func (p *parser) call_onmatchArm_14() any {
+	return (func(c *current) any {
+		c.data.MatchPatternBegin()
+		return nil
+	})(&p.cur)
+}
+
+func (p *parser) call_onmatchArm_20() any {
+	return (func(c *current) any {
+		c.data.AddOp(typeMatchRange)
+		return nil
+	})(&p.cur)
+}
+
+func (p *parser) call_onmatchArm_26() any {
+	return (func(c *current) any {
+		c.data.AddOp(typeMatchValue)
+		return nil
+	})(&p.cur)
+}
+
+func (p *parser) call_onmatchArm_15() any {
+	return (func(c *current) any {
+		c.data.MatchArmBody(false)
+		return nil
+	})(&p.cur)
+}
+
+func (p *parser) call_onmatchArm_29() any {
+	return (func(c *current) any {
+		c.data.MatchArmEnd()
+		return nil
+	})(&p.cur)
+}
+
 func (p *parser) call_onexprLogicOr_5() any {
 	return (func(c *current) any {
 		c.data.AddOp(typeJeDup)
@@ -4710,9 +6638,17 @@ func (p *parser) call_onexprLogicOr_11() any {
 	})(&p.cur)
 }
 
-func (p *parser) call_onexprLogicAnd_4() any {
+func (p *parser) call_onexprLogicAnd_5() any {
+	return (func(c *current) any {
+		c.data.AddOp(typeJneDup)
+		c.data.OffsetPush()
+		return nil
+	})(&p.cur)
+}
+
+func (p *parser) call_onexprLogicAnd_9() any {
 	return (func(c *current) any {
-		c.data.AddOp(typeLogicAnd)
+		c.data.OffsetPopAndSet()
 		return nil
 	})(&p.cur)
 }
@@ -4730,6 +6666,19 @@ func (p *parser) call_onexprBitwiseOr_8() any {
 	})(&p.cur)
 }
 
+func (p *parser) call_onexprBitwiseXor_6() bool {
+	return (func(c *current) bool {
+		return c.data.Config.CaretAsXor
+	})(&p.cur)
+}
+
+func (p *parser) call_onexprBitwiseXor_4() any {
+	return (func(c *current) any {
+		c.data.AddOp(typeBitwiseXor)
+		return nil
+	})(&p.cur)
+}
+
 func (p *parser) call_onexprBitwiseAnd_4() any {
 	return (func(c *current) any {
 		c.data.AddOp(typeBitwiseAnd)
@@ -4779,6 +6728,40 @@ func (p *parser) call_onexprCompare_27() any {
 	})(&p.cur)
 }
 
+func (p *parser) call_onexprCompare_31() any {
+	return (func(c *current) any {
+		c.data.AddOp(typeIn)
+		return nil
+	})(&p.cur)
+}
+
+func (p *parser) call_onexprRange_4() any {
+	return (func(c *current) any {
+		c.data.AddOp(typeRange)
+		return nil
+	})(&p.cur)
+}
+
+func (p *parser) call_onexprShift_5() bool {
+	return (func(c *current) bool {
+		return !c.data.Config.DisableBitwiseOp
+	})(&p.cur)
+}
+
+func (p *parser) call_onexprShift_8() any {
+	return (func(c *current) any {
+		c.data.AddOp(typeShiftLeft)
+		return nil
+	})(&p.cur)
+}
+
+func (p *parser) call_onexprShift_12() any {
+	return (func(c *current) any {
+		c.data.AddOp(typeShiftRight)
+		return nil
+	})(&p.cur)
+}
+
 func (p *parser) call_onexprAdditive_7() any {
 	return (func(c *current) any {
 		c.data.AddOp(typeAdd)
@@ -4793,30 +6776,51 @@ func (p *parser) call_onexprAdditive_11() any {
 	})(&p.cur)
 }
 
-func (p *parser) call_onexprMultiplicative_7() any {
+func (p *parser) call_onexprMultiplicative_8() any {
 	return (func(c *current) any {
 		c.data.AddOp(typeMultiply)
 		return nil
 	})(&p.cur)
 }
 
-func (p *parser) call_onexprMultiplicative_11() any {
+func (p *parser) call_onexprMultiplicative_12() any {
 	return (func(c *current) any {
 		c.data.AddOp(typeDivide)
 		return nil
 	})(&p.cur)
 }
 
-func (p *parser) call_onexprMultiplicative_15() any {
+func (p *parser) call_onexprMultiplicative_16() any {
 	return (func(c *current) any {
 		c.data.AddOp(typeModulus)
 		return nil
 	})(&p.cur)
 }
 
-func (p *parser) call_onexprNullCoalescing_4() any {
+func (p *parser) call_onexprMultiplicative_22() bool {
+	return (func(c *current) bool {
+		return c.data.ImplicitMultiply(p)
+	})(&p.cur)
+}
+
+func (p *parser) call_onexprMultiplicative_20() any {
+	return (func(c *current) any {
+		c.data.AddOp(typeMultiply)
+		return nil
+	})(&p.cur)
+}
+
+func (p *parser) call_onexprNullCoalescing_5() any {
+	return (func(c *current) any {
+		c.data.AddOp(typeJnnDup)
+		c.data.OffsetPush()
+		return nil
+	})(&p.cur)
+}
+
+func (p *parser) call_onexprNullCoalescing_9() any {
 	return (func(c *current) any {
-		c.data.AddOp(typeNullCoalescing)
+		c.data.OffsetPopAndSet()
 		return nil
 	})(&p.cur)
 }
@@ -4835,6 +6839,13 @@ func (p *parser) call_onexprUnaryNeg_2() any {
 	})(&p.cur)
 }
 
+func (p *parser) call_onexprUnaryNeg_6() any {
+	return (func(c *current) any {
+		c.data.AddOp(typeLogicNot)
+		return nil
+	})(&p.cur)
+}
+
 func (p *parser) call_onexprUnaryPos_2() any {
 	return (func(c *current) any {
 		c.data.AddOp(typePositive)
@@ -4850,9 +6861,113 @@ func (p *parser) call_ondetailStart_1() any {
 	})(&p.cur)
 }
 
-func (p *parser) call_ondetailEnd_1() any {
+func (p *parser) call_ondetailEnd_1() any {
+	return (func(c *current) any {
+		c.data.AddDiceDetail(c.data.CounterPop(), IntType(p.pt.offset))
+		return nil
+	})(&p.cur)
+}
+
+func (p *parser) call_on_kwKL_4() bool {
+	return (func(c *current) bool {
+		return c.data.DiceAliasAhead(p, "kl")
+	})(&p.cur)
+}
+
+func (p *parser) call_on_kwKL_3() any {
+	return (func(c *current) any {
+		return c.data.ConsumeDiceAlias(p)
+		return nil
+	})(&p.cur)
+}
+
+func (p *parser) call_on_kwKH_4() bool {
+	return (func(c *current) bool {
+		return c.data.DiceAliasAhead(p, "kh")
+	})(&p.cur)
+}
+
+func (p *parser) call_on_kwKH_3() any {
+	return (func(c *current) any {
+		return c.data.ConsumeDiceAlias(p)
+		return nil
+	})(&p.cur)
+}
+
+func (p *parser) call_on_kwDH_4() bool {
+	return (func(c *current) bool {
+		return c.data.DiceAliasAhead(p, "dh")
+	})(&p.cur)
+}
+
+func (p *parser) call_on_kwDH_3() any {
+	return (func(c *current) any {
+		return c.data.ConsumeDiceAlias(p)
+		return nil
+	})(&p.cur)
+}
+
+func (p *parser) call_on_kwDL_4() bool {
+	return (func(c *current) bool {
+		return c.data.DiceAliasAhead(p, "dl")
+	})(&p.cur)
+}
+
+func (p *parser) call_on_kwDL_3() any {
+	return (func(c *current) any {
+		return c.data.ConsumeDiceAlias(p)
+		return nil
+	})(&p.cur)
+}
+
+func (p *parser) call_on_kwMin_4() bool {
+	return (func(c *current) bool {
+		return c.data.DiceAliasAhead(p, "min")
+	})(&p.cur)
+}
+
+func (p *parser) call_on_kwMin_3() any {
+	return (func(c *current) any {
+		return c.data.ConsumeDiceAlias(p)
+		return nil
+	})(&p.cur)
+}
+
+func (p *parser) call_on_kwMax_4() bool {
+	return (func(c *current) bool {
+		return c.data.DiceAliasAhead(p, "max")
+	})(&p.cur)
+}
+
+func (p *parser) call_on_kwMax_3() any {
+	return (func(c *current) any {
+		return c.data.ConsumeDiceAlias(p)
+		return nil
+	})(&p.cur)
+}
+
+func (p *parser) call_on_kwAdv_5() bool {
+	return (func(c *current) bool {
+		return c.data.DiceAliasAhead(p, "优势")
+	})(&p.cur)
+}
+
+func (p *parser) call_on_kwAdv_4() any {
+	return (func(c *current) any {
+		return c.data.ConsumeDiceAlias(p)
+		return nil
+	})(&p.cur)
+}
+
+func (p *parser) call_on_kwDisadv_5() bool {
+	return (func(c *current) bool {
+		return c.data.DiceAliasAhead(p, "劣势")
+	})(&p.cur)
+}
+
+func (p *parser) call_on_kwDisadv_4() any {
 	return (func(c *current) any {
-		c.data.AddDiceDetail(c.data.CounterPop(), IntType(p.pt.offset))
+		return c.data.ConsumeDiceAlias(p)
 		return nil
 	})(&p.cur)
 }
@@ -4931,6 +7046,13 @@ func (p *parser) call_on_diceModType2_6() any {
 	})(&p.cur)
 }
 
+func (p *parser) call_on_diceExplode_1() any {
+	return (func(c *current) any {
+		c.data.AddOp(typeDiceSetExplode)
+		return nil
+	})(&p.cur)
+}
+
 func (p *parser) call_on_dicePearMod_2() any {
 	return (func(c *current) any {
 		c.data.PushIntNumber("2")
@@ -4941,7 +7063,7 @@ func (p *parser) call_on_dicePearMod_2() any {
 	})(&p.cur)
 }
 
-func (p *parser) call_on_dicePearMod_6() any {
+func (p *parser) call_on_dicePearMod_4() any {
 	return (func(c *current) any {
 		c.data.PushIntNumber("2")
 		c.data.AddOp(typeDiceSetTimes)
@@ -4951,6 +7073,34 @@ func (p *parser) call_on_dicePearMod_6() any {
 	})(&p.cur)
 }
 
+func (p *parser) call_on_diceSidesType_4() bool {
+	return (func(c *current) bool {
+		return c.data.Config.EnablePercentDice
+	})(&p.cur)
+}
+
+func (p *parser) call_on_diceSides_2() any {
+	stack := p.vstack[len(p.vstack)-1]
+	return (func(c *current, sides any) any {
+		c.data.AddDiceSides(sides.(string))
+		return nil
+	})(&p.cur, stack["sides"])
+}
+
+func (p *parser) call_on_diceSides_7() bool {
+	return (func(c *current) bool {
+		return c.data.Config.EnablePercentDice
+	})(&p.cur)
+}
+
+func (p *parser) call_on_diceSides_5() any {
+	return (func(c *current) any {
+		c.data.PushIntNumber("100")
+		c.data.AddDiceSides("100")
+		return nil
+	})(&p.cur)
+}
+
 func (p *parser) call_on_diceExpr1_2() any {
 	return (func(c *current) any {
 		c.data.AddOp(typeDiceInit)
@@ -4977,7 +7127,6 @@ func (p *parser) call_on_diceExpr3_2() any {
 func (p *parser) call_on_diceExpr4_2() any {
 	return (func(c *current) any {
 		c.data.AddOp(typeDiceInit)
-		c.data.AddOp(typeDiceSetTimes)
 		return nil
 	})(&p.cur)
 }
@@ -5058,48 +7207,68 @@ func (p *parser) call_onexprDice_7() any {
 	})(&p.cur)
 }
 
+func (p *parser) call_onexprDice_12() bool {
+	return (func(c *current) bool {
+		return c.data.Config.EnableDiceFate
+	})(&p.cur)
+}
+
 func (p *parser) call_onexprDice_10() any {
 	return (func(c *current) any {
-		c.data.AddOp(typeDice)
+		c.data.AddOp(typeDiceInit)
 		return nil
 	})(&p.cur)
 }
 
-func (p *parser) call_onexprDice_21() any {
+func (p *parser) call_onexprDice_19() any {
 	return (func(c *current) any {
-		c.data.AddOp(typeDice)
+		c.data.AddOp(typeDiceSetTimes)
 		return nil
 	})(&p.cur)
 }
 
-func (p *parser) call_onexprDice_33() bool {
-	return (func(c *current) bool {
-		return !c.data.Config.DisableNDice
+func (p *parser) call_onexprDice_16() any {
+	return (func(c *current) any {
+		c.data.AddOp(typeDiceFateN)
+		return nil
 	})(&p.cur)
 }
 
-func (p *parser) call_onexprDice_31() any {
+func (p *parser) call_onexprDice_27() any {
+	return (func(c *current) any {
+		c.data.AddOp(typeDice)
+		return nil
+	})(&p.cur)
+}
+
+func (p *parser) call_onexprDice_38() any {
 	return (func(c *current) any {
-		c.data.AddOp(typePushDefaultExpr)
 		c.data.AddOp(typeDice)
 		return nil
 	})(&p.cur)
 }
 
-func (p *parser) call_onexprDice_45() bool {
+func (p *parser) call_onexprDice_50() bool {
 	return (func(c *current) bool {
 		return !c.data.Config.DisableNDice
 	})(&p.cur)
 }
 
-func (p *parser) call_onexprDice_43() any {
+func (p *parser) call_onexprDice_48() any {
 	return (func(c *current) any {
-		c.data.PushIntNumber("1")
+		c.data.AddOp(typePushDefaultExpr)
+		c.data.AddOp(typeDice)
 		return nil
 	})(&p.cur)
 }
 
-func (p *parser) call_onexprDice_49() any {
+func (p *parser) call_onexprDice_62() bool {
+	return (func(c *current) bool {
+		return !c.data.Config.DisableNDice
+	})(&p.cur)
+}
+
+func (p *parser) call_onexprDice_60() any {
 	return (func(c *current) any {
 		c.data.AddOp(typePushDefaultExpr)
 		c.data.AddOp(typeDice)
@@ -5107,80 +7276,80 @@ func (p *parser) call_onexprDice_49() any {
 	})(&p.cur)
 }
 
-func (p *parser) call_onexprDice_56() bool {
+func (p *parser) call_onexprDice_71() bool {
 	return (func(c *current) bool {
 		return c.data.Config.EnableDiceCoC
 	})(&p.cur)
 }
 
-func (p *parser) call_onexprDice_66() bool {
+func (p *parser) call_onexprDice_81() bool {
 	return (func(c *current) bool {
 		return c.data.Config.EnableDiceWoD
 	})(&p.cur)
 }
 
-func (p *parser) call_onexprDice_64() any {
+func (p *parser) call_onexprDice_79() any {
 	return (func(c *current) any {
 		c.data.AddOp(typeWodSetInit)
 		return nil
 	})(&p.cur)
 }
 
-func (p *parser) call_onexprDice_74() any {
+func (p *parser) call_onexprDice_89() any {
 	return (func(c *current) any {
 		c.data.AddOp(typeWodSetPool)
 		return nil
 	})(&p.cur)
 }
 
-func (p *parser) call_onexprDice_70() any {
+func (p *parser) call_onexprDice_85() any {
 	return (func(c *current) any {
 		c.data.AddOp(typeDiceWod)
 		return nil
 	})(&p.cur)
 }
 
-func (p *parser) call_onexprDice_85() bool {
+func (p *parser) call_onexprDice_100() bool {
 	return (func(c *current) bool {
 		return c.data.Config.EnableDiceDoubleCross
 	})(&p.cur)
 }
 
-func (p *parser) call_onexprDice_83() any {
+func (p *parser) call_onexprDice_98() any {
 	return (func(c *current) any {
 		c.data.AddOp(typeDCSetInit)
 		return nil
 	})(&p.cur)
 }
 
-func (p *parser) call_onexprDice_89() any {
+func (p *parser) call_onexprDice_104() any {
 	return (func(c *current) any {
 		c.data.AddOp(typeDCSetPool)
 		return nil
 	})(&p.cur)
 }
 
-func (p *parser) call_onexprDice_96() any {
+func (p *parser) call_onexprDice_111() any {
 	return (func(c *current) any {
 		c.data.AddOp(typeDCSetPoints)
 		return nil
 	})(&p.cur)
 }
 
-func (p *parser) call_onexprDice_91() any {
+func (p *parser) call_onexprDice_106() any {
 	return (func(c *current) any {
 		c.data.AddOp(typeDiceDC)
 		return nil
 	})(&p.cur)
 }
 
-func (p *parser) call_onexprDice_103() bool {
+func (p *parser) call_onexprDice_118() bool {
 	return (func(c *current) bool {
 		return c.data.Config.EnableDiceFate
 	})(&p.cur)
 }
 
-func (p *parser) call_onexprDice_101() any {
+func (p *parser) call_onexprDice_116() any {
 	return (func(c *current) any {
 		c.data.AddOp(typeDiceFate)
 		return nil
@@ -5230,6 +7399,48 @@ func (p *parser) call_onarray_call_15() any {
 }
 
 func (p *parser) call_onarray_call_17() any {
+	return (func(c *current) any {
+		c.data.WriteCode(typeAttrGet, string("dh"))
+		return nil
+	})(&p.cur)
+}
+
+func (p *parser) call_onarray_call_20() any {
+	return (func(c *current) any {
+		c.data.AddInvoke(1)
+		return nil
+	})(&p.cur)
+}
+
+func (p *parser) call_onarray_call_22() any {
+	return (func(c *current) any {
+		c.data.AddInvoke(0)
+		return nil
+	})(&p.cur)
+}
+
+func (p *parser) call_onarray_call_24() any {
+	return (func(c *current) any {
+		c.data.WriteCode(typeAttrGet, string("dl"))
+		return nil
+	})(&p.cur)
+}
+
+func (p *parser) call_onarray_call_27() any {
+	return (func(c *current) any {
+		c.data.AddInvoke(1)
+		return nil
+	})(&p.cur)
+}
+
+func (p *parser) call_onarray_call_29() any {
+	return (func(c *current) any {
+		c.data.AddInvoke(0)
+		return nil
+	})(&p.cur)
+}
+
+func (p *parser) call_onarray_call_31() any {
 	return (func(c *current) any {
 		c.data.AddOp(typeItemGet)
 		return nil
@@ -5302,6 +7513,13 @@ func (p *parser) call_onvalue_array_range_1() any {
 	})(&p.cur)
 }
 
+func (p *parser) call_onvalue_array_item_2() any {
+	return (func(c *current) any {
+		c.data.AddOp(typeSpread)
+		return nil
+	})(&p.cur)
+}
+
 func (p *parser) call_onvalue_array_2() any {
 	return (func(c *current) any {
 		c.data.CounterPush()
@@ -5310,30 +7528,145 @@ func (p *parser) call_onvalue_array_2() any {
 	})(&p.cur)
 }
 
-func (p *parser) call_onvalue_array_10() any {
+func (p *parser) call_onvalue_array_9() any {
+	return (func(c *current) any {
+		c.data.CounterAdd(1)
+		return nil
+	})(&p.cur)
+}
+
+func (p *parser) call_onvalue_array_15() any {
+	return (func(c *current) any {
+		c.data.PushArray(c.data.CounterPop())
+		return nil
+	})(&p.cur)
+}
+
+func (p *parser) call_onvalue_array_20() any {
+	return (func(c *current) any {
+		c.data.PushArray(c.data.CounterPop())
+		c.data.CounterPush()
+		c.data.CounterAdd(1)
+		return nil
+	})(&p.cur)
+}
+
+func (p *parser) call_onvalue_array_28() any {
+	return (func(c *current) any {
+		c.data.CounterAdd(1)
+		return nil
+	})(&p.cur)
+}
+
+func (p *parser) call_onvalue_array_32() any {
+	return (func(c *current) any {
+		c.data.CounterAdd(1)
+		return nil
+	})(&p.cur)
+}
+
+func (p *parser) call_onvalue_array_24() any {
+	return (func(c *current) any {
+		c.data.PushTable(c.data.CounterPop())
+		return nil
+	})(&p.cur)
+}
+
+func (p *parser) call_onvalue_table_row_2() any {
+	return (func(c *current) any {
+		c.data.CounterPush()
+		c.data.CounterAdd(1)
+		return nil
+	})(&p.cur)
+}
+
+func (p *parser) call_onvalue_table_row_6() any {
 	return (func(c *current) any {
 		c.data.CounterAdd(1)
 		return nil
 	})(&p.cur)
 }
 
-func (p *parser) call_onvalue_array_6() any {
+func (p *parser) call_onvalue_table_row_4() any {
 	return (func(c *current) any {
 		c.data.PushArray(c.data.CounterPop())
 		return nil
 	})(&p.cur)
 }
 
+func (p *parser) call_onvalue_set_2() any {
+	return (func(c *current) any {
+		c.data.CounterPush()
+		return nil
+	})(&p.cur)
+}
+
+func (p *parser) call_onvalue_set_6() any {
+	return (func(c *current) any {
+		c.data.CounterAdd(1)
+		return nil
+	})(&p.cur)
+}
+
+func (p *parser) call_onvalue_set_11() any {
+	return (func(c *current) any {
+		c.data.CounterAdd(1)
+		return nil
+	})(&p.cur)
+}
+
+func (p *parser) call_onvalue_set_8() any {
+	return (func(c *current) any {
+		c.data.PushSet(c.data.CounterPop())
+		return nil
+	})(&p.cur)
+}
+
+func (p *parser) call_onvalue_tuple_2() any {
+	return (func(c *current) any {
+		c.data.CounterPush()
+		return nil
+	})(&p.cur)
+}
+
+func (p *parser) call_onvalue_tuple_8() any {
+	return (func(c *current) any {
+		c.data.CounterAdd(1)
+		return nil
+	})(&p.cur)
+}
+
+func (p *parser) call_onvalue_tuple_15() any {
+	return (func(c *current) any {
+		c.data.CounterAdd(1)
+		return nil
+	})(&p.cur)
+}
+
+func (p *parser) call_onvalue_tuple_19() any {
+	return (func(c *current) any {
+		c.data.CounterAdd(1)
+		return nil
+	})(&p.cur)
+}
+
+func (p *parser) call_onvalue_tuple_4() any {
+	return (func(c *current) any {
+		c.data.PushTuple(c.data.CounterPop())
+		return nil
+	})(&p.cur)
+}
+
 func (p *parser) call_onvalue_2() any {
 	return (func(c *current) any {
-		c.data.PushIntNumber("1")
+		c.data.PushBool(true)
 		return nil
 	})(&p.cur)
 }
 
 func (p *parser) call_onvalue_6() any {
 	return (func(c *current) any {
-		c.data.PushIntNumber("0")
+		c.data.PushBool(false)
 		return nil
 	})(&p.cur)
 }
@@ -5360,7 +7693,52 @@ func (p *parser) call_onvalue_23() any {
 	})(&p.cur, stack["id"])
 }
 
-func (p *parser) call_onvalue_33() any {
+func (p *parser) call_onvalue_31() any {
+	return (func(c *current) any {
+		c.data.CodePush(p.pt.offset)
+		return nil
+	})(&p.cur)
+}
+
+func (p *parser) call_onvalue_41() any {
+	stack := p.vstack[len(p.vstack)-1]
+	return (func(c *current, expr any) any {
+		c.data.AddStoreComputedOnStack(expr.(string))
+		return nil
+	})(&p.cur, stack["expr"])
+}
+
+func (p *parser) call_onvalue_57() any {
+	stack := p.vstack[len(p.vstack)-1]
+	return (func(c *current, mode any) any {
+		c.data.PushRollMode(mode.(string))
+		return nil
+	})(&p.cur, stack["mode"])
+}
+
+func (p *parser) call_onvalue_74() any {
+	stack := p.vstack[len(p.vstack)-1]
+	return (func(c *current, mode any) any {
+		c.data.AddOp(typeRollModePop)
+		return nil
+	})(&p.cur, stack["mode"])
+}
+
+func (p *parser) call_onvalue_78() any {
+	return (func(c *current) any {
+		c.data.AddOp(typeDetailNote)
+		return nil
+	})(&p.cur)
+}
+
+func (p *parser) call_onvalue_95() any {
+	return (func(c *current) any {
+		c.data.AddOp(typeDetailQuiet)
+		return nil
+	})(&p.cur)
+}
+
+func (p *parser) call_onvalue_113() any {
 	stack := p.vstack[len(p.vstack)-1]
 	return (func(c *current, id any) any {
 		c.data.WriteCode(typeLoadNameWithDetail, id.(string))
@@ -5368,28 +7746,28 @@ func (p *parser) call_onvalue_33() any {
 	})(&p.cur, stack["id"])
 }
 
-func (p *parser) call_onvalue_55() any {
+func (p *parser) call_onvalue_149() any {
 	return (func(c *current) any {
 		c.data.PushArray(0)
 		return nil
 	})(&p.cur)
 }
 
-func (p *parser) call_onvalue_80() any {
+func (p *parser) call_onvalue_174() any {
 	return (func(c *current) any {
 		c.data.PushDict(0)
 		return nil
 	})(&p.cur)
 }
 
-func (p *parser) call_onvalue_90() any {
+func (p *parser) call_onvalue_196() any {
 	return (func(c *current) any {
 		c.data.CounterPush()
 		return nil
 	})(&p.cur)
 }
 
-func (p *parser) call_onvalue_94() any {
+func (p *parser) call_onvalue_200() any {
 	return (func(c *current) any {
 		c.data.PushDict(c.data.CounterPop())
 		return nil
@@ -5410,6 +7788,53 @@ func (p *parser) call_onfloat_1() any {
 	})(&p.cur)
 }
 
+func (p *parser) call_onpercent_3() bool {
+	return (func(c *current) bool {
+		return c.data.Config.EnablePercentLiteral
+	})(&p.cur)
+}
+
+func (p *parser) call_onpercent_1() any {
+	return (func(c *current) any {
+		c.data.PushPercent(toStr(c.text))
+		return nil
+	})(&p.cur)
+}
+
+func (p *parser) call_onmoney_3() bool {
+	return (func(c *current) bool {
+		return c.data.IsMoneyAhead(p)
+	})(&p.cur)
+}
+
+func (p *parser) call_onmoney_1() any {
+	return (func(c *current) any {
+		c.data.PushMoney(toStr(c.text))
+		return nil
+	})(&p.cur)
+}
+
+func (p *parser) call_onquantity_3() bool {
+	return (func(c *current) bool {
+		return c.data.IsQuantityAhead(p)
+	})(&p.cur)
+}
+
+func (p *parser) call_onquantity_1() any {
+	stack := p.vstack[len(p.vstack)-1]
+	return (func(c *current, n, u any) any {
+		c.data.PushQuantity(n.(string), u.(string))
+		return nil
+	})(&p.cur, stack["n"], stack["u"])
+}
+
+func (p *parser) call_onduration_1() any {
+	return (func(c *current) any {
+		c.data.PushDuration(toStr(c.text))
+		return nil
+	})(&p.cur)
+}
+
 func (p *parser) call_onstrPart1_1() any {
 	stack := p.vstack[len(p.vstack)-1]
 	return (func(c *current, items any) any {
@@ -5693,6 +8118,12 @@ func (p *parser) call_onidentifierWithoutColon_1() any {
 	})(&p.cur)
 }
 
+func (p *parser) call_onexponentiation_3() bool {
+	return (func(c *current) bool {
+		return !c.data.Config.CaretAsXor
+	})(&p.cur)
+}
+
 func (p *parser) call_onest_7() any {
 	return (func(c *current) any {
 		c.data.FlagsPush()
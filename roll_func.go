@@ -287,10 +287,135 @@ func RollDoubleCross(src *rand.PCGSource, addLine IntType, pool IntType, points
 	return resultDice, allRollCount, IntType(addTimes), lastDetail
 }
 
+// rollFaces 骰times个以faces为各面的骰子，如 3d[2,4,8]，各面等概率，可以重复。
+// 只有一个骰子时结果为骰出的面；有多个骰子时，各面都为int则结果为其和，否则为各骰子结果组成的数组。
+// 结果中的面是复制出来的，修改结果不影响原来的数组
+func rollFaces(ctx *Context, faces []*VMValue, times IntType, mode int) (*VMValue, string) {
+	rolled := make([]*VMValue, times)
+	allInt := true
+	var sum IntType
+	texts := make([]string, times)
+	for i := range rolled {
+		face := cloneDieFace(faces[Roll(ctx.RandSrc, IntType(len(faces)), mode)-1], map[any]*VMValue{})
+		rolled[i] = face
+		texts[i] = ctx.ToString(face)
+		if n, ok := face.ReadInt(); ok {
+			sum += n
+		} else {
+			allInt = false
+		}
+	}
+	if times == 1 {
+		return rolled[0], ""
+	}
+	if allInt {
+		return NewIntVal(sum), strings.Join(texts, "+")
+	}
+	return NewArrayValRaw(rolled), strings.Join(texts, ",")
+}
+
+// cloneDieFace 复制骰子的一面，其中的数组、字典、元组逐层复制。
+// copied 记录已经复制过的数组和字典，同一个数组只复制一次，也不会因循环引用而无限复制
+func cloneDieFace(v *VMValue, copied map[any]*VMValue) *VMValue {
+	if v.TypeId == VMTypeArray || v.TypeId == VMTypeDict {
+		if c, ok := copied[v.Value]; ok {
+			return c
+		}
+	}
+	switch v.TypeId {
+	case VMTypeArray:
+		ad, _ := v.ReadArray()
+		data := &ArrayData{List: make([]*VMValue, len(ad.List))}
+		c := &VMValue{TypeId: VMTypeArray, Value: data}
+		copied[v.Value] = c
+		for i, item := range ad.List {
+			data.List[i] = cloneDieFace(item, copied)
+		}
+		return c
+	case VMTypeTuple:
+		td, _ := v.ReadTuple()
+		lst := make([]*VMValue, len(td.List))
+		for i, item := range td.List {
+			lst[i] = cloneDieFace(item, copied)
+		}
+		return NewTupleVal(lst...)
+	case VMTypeDict:
+		dd, _ := v.ReadDictData()
+		m := &ValueMap{}
+		c := NewDictVal(m).V()
+		copied[v.Value] = c
+		dd.Dict.Range(func(key string, value *VMValue) bool {
+			m.Store(key, cloneDieFace(value, copied))
+			return true
+		})
+		return c
+	}
+	return v.Clone()
+}
+
+// defaultDiceExplodeLimit 未设置 DiceExplodeLimit 时爆炸骰每颗骰子至多追加的次数
+const defaultDiceExplodeLimit = 100
+
+// DicePool 一组骰子的结果。显示时骰子可能被重新排序(如kh/kl，或开启 SortDiceDetail)，
+// Rolls 保留骰出的顺序，Order[i] 为显示的第i个骰子在 Rolls 中的下标，用于核对显示的结果与实际的骰点
+type DicePool struct {
+	Rolls []IntType `json:"rolls"`
+	Order []int     `json:"order"`
+	Kept  int       `json:"kept"` // 按显示顺序，前 Kept 个骰子计入结果
+	// 爆炸骰中各骰子追加骰点的次数，与 Rolls 对应，不是爆炸骰时为nil。Rolls 中为累加后的值
+	Exploded []IntType `json:"exploded,omitempty"`
+}
+
+// explodedCount 追加骰点的总次数
+func (p *DicePool) explodedCount() IntType {
+	var n IntType
+	for _, i := range p.Exploded {
+		n += i
+	}
+	return n
+}
+
+// dieText 显示的第i个骰子的文本，爆炸骰显示各次骰点，如 (6!+6!+2)
+func (p *DicePool) dieText(i int, sides IntType) string {
+	idx := p.Order[i]
+	if p.Exploded == nil || p.Exploded[idx] == 0 {
+		return strconv.FormatInt(int64(p.Rolls[idx]), 10)
+	}
+	n := p.Exploded[idx]
+	var sb strings.Builder
+	sb.WriteString("(")
+	for j := IntType(0); j < n; j++ {
+		sb.WriteString(strconv.FormatInt(int64(sides), 10))
+		sb.WriteString("!+")
+	}
+	sb.WriteString(strconv.FormatInt(int64(p.Rolls[idx]-n*sides), 10))
+	sb.WriteString(")")
+	return sb.String()
+}
+
+// Sorted 按显示顺序排列的骰点
+func (p *DicePool) Sorted() []IntType {
+	ret := make([]IntType, len(p.Order))
+	for i, idx := range p.Order {
+		ret[i] = p.Rolls[idx]
+	}
+	return ret
+}
+
 // RollCommon (times)d(dicePoints)kl(lowNum) 或 (times)d(dicePoints)kh(highNum)
 func RollCommon(src *rand.PCGSource, times, dicePoints IntType, diceMin, diceMax *IntType, isKeepLH, lowNum, highNum IntType, mode int) (IntType, string) {
-	var nums []IntType
-	for i := IntType(0); i < times; i += 1 {
+	num, text, _ := RollCommonPool(src, times, dicePoints, diceMin, diceMax, isKeepLH, lowNum, highNum, mode, false)
+	return num, text
+}
+
+// RollCommonPool 与 RollCommon 相同，同时给出骰池的原始顺序。sortDetail 为真时没有kh/kl也按从大到小显示
+func RollCommonPool(src *rand.PCGSource, times, dicePoints IntType, diceMin, diceMax *IntType, isKeepLH, lowNum, highNum IntType, mode int, sortDetail bool) (IntType, string, *DicePool) {
+	return rollCommonPool(src, times, dicePoints, diceMin, diceMax, isKeepLH, lowNum, highNum, mode, sortDetail, 0)
+}
+
+// rollCommonPool explodeLimit大于0时为爆炸骰: 骰出最大面时追加一次骰点并累加到这颗骰子上，每颗骰子至多追加explodeLimit次
+func rollCommonPool(src *rand.PCGSource, times, dicePoints IntType, diceMin, diceMax *IntType, isKeepLH, lowNum, highNum IntType, mode int, sortDetail bool, explodeLimit IntType) (IntType, string, *DicePool) {
+	rollOne := func() IntType {
 		die := Roll(src, dicePoints, mode)
 		if diceMax != nil {
 			if die > *diceMax {
@@ -302,7 +427,28 @@ func RollCommon(src *rand.PCGSource, times, dicePoints IntType, diceMin, diceMax
 				die = *diceMin
 			}
 		}
-		nums = append(nums, die)
+		return die
+	}
+
+	var rolls []IntType
+	var exploded []IntType
+	for i := IntType(0); i < times; i += 1 {
+		die := rollOne()
+		if explodeLimit > 0 {
+			n := IntType(0)
+			for last := die; last == dicePoints && n < explodeLimit; n++ {
+				last = rollOne()
+				die += last
+			}
+			exploded = append(exploded, n)
+		}
+		rolls = append(rolls, die)
+	}
+
+	// 排序时只调整下标，骰出的顺序保留在 rolls 中
+	order := make([]int, len(rolls))
+	for i := range order {
+		order[i] = i
 	}
 
 	// 默认pickNum为全部，稍后由kh或kl做削减
@@ -311,9 +457,9 @@ func RollCommon(src *rand.PCGSource, times, dicePoints IntType, diceMin, diceMax
 	if isKeepLH != 0 {
 		// 为1对应取低个数，为2对应取高个数，3为丢弃低个数，4为丢弃高个数
 		if isKeepLH == 1 || isKeepLH == 4 {
-			sort.Slice(nums, func(i, j int) bool { return nums[i] < nums[j] }) // 从小到大
+			sort.SliceStable(order, func(i, j int) bool { return rolls[order[i]] < rolls[order[j]] }) // 从小到大
 		} else {
-			sort.Slice(nums, func(i, j int) bool { return nums[i] > nums[j] }) // 从大到小
+			sort.SliceStable(order, func(i, j int) bool { return rolls[order[i]] > rolls[order[j]] }) // 从大到小
 		}
 
 		switch isKeepLH {
@@ -334,8 +480,13 @@ func RollCommon(src *rand.PCGSource, times, dicePoints IntType, diceMin, diceMax
 		if pickNum > times {
 			pickNum = times
 		}
+	} else if sortDetail {
+		sort.SliceStable(order, func(i, j int) bool { return rolls[order[i]] > rolls[order[j]] })
 	}
 
+	pool := &DicePool{Rolls: rolls, Order: order, Kept: int(pickNum), Exploded: exploded}
+	nums := pool.Sorted()
+
 	num := IntType(0)
 	for i := IntType(0); i < pickNum; i++ {
 		// 当取数大于上限 跳过
@@ -351,7 +502,7 @@ func RollCommon(src *rand.PCGSource, times, dicePoints IntType, diceMin, diceMax
 	if pickNum == times {
 		text = ""
 		for i := 0; i < len(nums); i++ {
-			text += fmt.Sprintf("%d+", nums[i])
+			text += pool.dieText(i, dicePoints) + "+"
 		}
 		if len(nums) > 0 {
 			text = text[:len(text)-1]
@@ -362,7 +513,7 @@ func RollCommon(src *rand.PCGSource, times, dicePoints IntType, diceMin, diceMax
 			if i == pickNum {
 				text += "| "
 			}
-			text += fmt.Sprintf("%d ", nums[i])
+			text += pool.dieText(int(i), dicePoints) + " "
 		}
 		if len(nums) > 0 {
 			text = text[:len(text)-1]
@@ -370,7 +521,7 @@ func RollCommon(src *rand.PCGSource, times, dicePoints IntType, diceMin, diceMax
 		text += "}"
 	}
 
-	return num, text
+	return num, text, pool
 }
 
 func RollCoC(src *rand.PCGSource, isBonus bool, diceNum IntType, mode int) (IntType, string) {
@@ -423,20 +574,26 @@ func RollCoC(src *rand.PCGSource, isBonus bool, diceNum IntType, mode int) (IntT
 	}
 }
 
+// RollFate 骰4颗命运骰，即 f
 func RollFate(src *rand.PCGSource, mode int) (IntType, string) {
-	detail := ""
+	return RollFateN(src, 4, mode)
+}
+
+// RollFateN 骰times颗命运骰并求和，每颗的结果为-1 0 1，detail中依次记为- 0 +，如 +0-+
+func RollFateN(src *rand.PCGSource, times IntType, mode int) (IntType, string) {
+	var detail strings.Builder
 	sum := IntType(0)
-	for i := 0; i < 4; i++ {
+	for i := IntType(0); i < times; i++ {
 		n := Roll(src, 3, mode) - 2
 		sum += n
 		switch n {
 		case -1:
-			detail += "-"
+			detail.WriteByte('-')
 		case 0:
-			detail += "0"
+			detail.WriteByte('0')
 		case +1:
-			detail += "+"
+			detail.WriteByte('+')
 		}
 	}
-	return sum, detail
+	return sum, detail.String()
 }
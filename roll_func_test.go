@@ -1,8 +1,11 @@
 package dicescript
 
 import (
-	"github.com/stretchr/testify/assert"
+	"fmt"
+	"strings"
 	"testing"
+
+	"github.com/stretchr/testify/assert"
 )
 
 func TestRollCommon(t *testing.T) {
@@ -29,3 +32,102 @@ func TestRollWoD(t *testing.T) {
 	ret, _, _, _ := RollWoD(nil, 11, 8, 10, 1, true, 0) // 8a11m10k1
 	assert.Equal(t, IntType(8), ret)
 }
+
+func TestRollFateN(t *testing.T) {
+	sum, detail := RollFateN(nil, 20, 0)
+	assert.Len(t, detail, 20)
+	assert.Equal(t, IntType(strings.Count(detail, "+")-strings.Count(detail, "-")), sum)
+	assert.Equal(t, 20, strings.Count(detail, "+")+strings.Count(detail, "-")+strings.Count(detail, "0"))
+}
+
+func TestRollCoC(t *testing.T) {
+	ret, detail := RollCoC(nil, true, 2, -1)
+	assert.Equal(t, IntType(1), ret)
+	assert.Equal(t, "(D100=1,奖励1 1)", detail)
+	ret, _ = RollCoC(nil, false, 2, -1)
+	assert.Equal(t, IntType(11), ret)
+
+	// 十位和个位都为0时是100，奖励骰的0不能使其变好
+	ret, detail = RollCoC(nil, true, 2, 1)
+	assert.Equal(t, IntType(100), ret)
+	assert.Equal(t, "(D100=100,奖励0 0)", detail)
+	ret, _ = RollCoC(nil, false, 1, 1)
+	assert.Equal(t, IntType(100), ret)
+
+	// 结果总是在原本的十位和各个奖惩骰的十位中取最好/最差的一个，被舍弃的十位保留在细节中
+	for i := 0; i < 500; i++ {
+		isBonus := i%2 == 0
+		ret, detail := RollCoC(nil, isBonus, 3, 0)
+		var base IntType
+		_, _ = fmt.Sscanf(detail, "(D100=%d,", &base)
+		tensList := strings.Fields(strings.TrimSuffix(detail[strings.IndexByte(detail, ',')+len(",奖励"):], ")"))
+		assert.Len(t, tensList, 3)
+		units := base % 10
+		expected := base
+		for _, tens := range tensList {
+			v := IntType(tens[0]-'0')*10 + units
+			if v == 0 {
+				v = 100
+			}
+			if (isBonus && v < expected) || (!isBonus && v > expected) {
+				expected = v
+			}
+		}
+		assert.Equal(t, expected, ret, detail)
+	}
+}
+
+func TestRollCommonPool(t *testing.T) {
+	for _, keep := range []IntType{0, 1, 2, 3, 4} {
+		num, _, pool := RollCommonPool(nil, 8, 6, nil, nil, keep, 3, 3, 0, true)
+		assert.Len(t, pool.Rolls, 8)
+
+		// Order 是 Rolls 下标的一个排列
+		seen := map[int]bool{}
+		for _, idx := range pool.Order {
+			seen[idx] = true
+		}
+		assert.Len(t, seen, 8)
+
+		sorted := pool.Sorted()
+		var sum IntType
+		for i := 0; i < pool.Kept; i++ {
+			sum += sorted[i]
+		}
+		assert.Equal(t, num, sum)
+		for i := 1; i < len(sorted); i++ {
+			if keep == 1 || keep == 4 {
+				assert.LessOrEqual(t, sorted[i-1], sorted[i])
+			} else {
+				assert.GreaterOrEqual(t, sorted[i-1], sorted[i])
+			}
+		}
+	}
+
+	// 不排序时按骰出的顺序显示
+	_, _, pool := RollCommonPool(nil, 5, 6, nil, nil, 0, 0, 0, 0, false)
+	assert.Equal(t, []int{0, 1, 2, 3, 4}, pool.Order)
+	assert.Equal(t, 5, pool.Kept)
+}
+
+func TestRollCommonPoolExplode(t *testing.T) {
+	// 最大值结算时每颗骰子都追加到上限
+	num, text, pool := rollCommonPool(nil, 2, 6, nil, nil, 0, 0, 0, 1, false, 2)
+	assert.Equal(t, IntType(36), num)
+	assert.Equal(t, "(6!+6!+6)+(6!+6!+6)", text)
+	assert.Equal(t, []IntType{2, 2}, pool.Exploded)
+	assert.Equal(t, IntType(4), pool.explodedCount())
+
+	num, _, pool = rollCommonPool(nil, 20, 6, nil, nil, 0, 0, 0, 0, false, 100)
+	for i, v := range pool.Rolls {
+		// 追加了n次时，值在 6n+1 到 6n+6 之间
+		n := pool.Exploded[i]
+		assert.True(t, v > n*6 && v <= n*6+6)
+		assert.True(t, n == 0 || v > 6)
+	}
+	assert.True(t, num >= 20)
+
+	// 取最小值时不会爆炸
+	_, _, pool = rollCommonPool(nil, 3, 6, nil, nil, 0, 0, 0, -1, false, 100)
+	assert.Equal(t, IntType(0), pool.explodedCount())
+}
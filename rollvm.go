@@ -24,14 +24,20 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 	"unicode"
+
+	"golang.org/x/exp/rand"
 )
 
-func NewVM() *Context {
+func NewVM(opts ...Option) *Context {
 	// 创建parser
 	p := &Context{}
 	p.Init()
+	for _, opt := range opts {
+		opt(p)
+	}
 	return p
 }
 
@@ -69,7 +75,43 @@ func (ctx *Context) Parse(value string) error {
 	if ctx.IsRunning {
 		return errors.New("正在执行中，无法执行新的语句")
 	}
+	if ctx.optionErr != nil {
+		ctx.Error = ctx.optionErr
+		return ctx.optionErr
+	}
+
+	ctx.Error = nil
+	ctx.NumOpCount = 0
+	ctx.detailCache = ""
+
+	var cacheKey string
+	if ctx.codeCacheable() {
+		cacheKey = ctx.codeCacheKey(value)
+		if ctx.loadCodeCache(cacheKey, value) {
+			return ctx.checkCompilePolicy()
+		}
+	}
+
+	var err error
+	if ctx.Config.LenientParse {
+		err = ctx.parseLenient(value)
+	} else {
+		err = ctx.parseCode(value)
+	}
+	if err != nil {
+		err = ctx.withSuggestions(value, err)
+		ctx.Error = err
+		return err
+	}
+
+	if cacheKey != "" {
+		ctx.saveCodeCache(cacheKey, ctx.parser.cur.data)
+	}
+	return ctx.checkCompilePolicy()
+}
 
+// parseCode 解析语句，编译字节码
+func (ctx *Context) parseCode(value string) error {
 	p := newParser("", []byte(value), memoized(true))
 	ctx.parser = p
 	d := p.cur.data
@@ -81,9 +123,6 @@ func (ctx *Context) Parse(value string) error {
 	d.Config = ctx.Config
 	d.ctx = ctx
 	d.pendingCustomDice = nil
-	ctx.Error = nil
-	ctx.NumOpCount = 0
-	ctx.detailCache = ""
 
 	// 开始解析，编译字节码
 	if ctx.Config.ParseExprLimit != 0 {
@@ -92,14 +131,31 @@ func (ctx *Context) Parse(value string) error {
 	// 设置错误消息语言
 	SetParseErrorLanguage(ctx.Config.ParseErrorLanguage)
 	_, err := p.parse(nil)
+	if d.numberErr != nil {
+		// 比语法错误更具体，优先返回
+		return d.numberErr
+	}
 	if err != nil {
-		ctx.Error = err
 		return err
 	}
 
 	ctx.code = p.cur.data.code
 	ctx.codeIndex = p.cur.data.codeIndex
+	if ctx.Config.EliminateCommonSubexpr {
+		ctx.code = eliminateCommonSubexpr(ctx.code[:ctx.codeIndex])
+		ctx.codeIndex = len(ctx.code)
+	}
+	d.program.code = ctx.code[:ctx.codeIndex]
+	return nil
+}
 
+func (ctx *Context) checkCompilePolicy() error {
+	if ctx.Config.CompilePolicy != nil {
+		if err := ctx.Config.CompilePolicy(ctx, ctx.ProgramInfo()); err != nil {
+			ctx.Error = err
+			return err
+		}
+	}
 	return nil
 }
 
@@ -107,7 +163,7 @@ func (ctx *Context) Parse(value string) error {
 func (ctx *Context) IsCalculateExists() bool {
 	for _, i := range ctx.code {
 		switch i.T {
-		case typeDice, typeDiceDC, typeDiceWod, typeDiceFate, typeDiceCocBonus, typeDiceCocPenalty, typeCustomDice:
+		case typeDice, typeDiceDC, typeDiceWod, typeDiceFate, typeDiceFateN, typeDiceCocBonus, typeDiceCocPenalty, typeCustomDice:
 			return true
 		case typeAdd, typeSubtract, typeMultiply, typeDivide, typeModulus, typeExponentiation:
 			return true
@@ -118,10 +174,119 @@ func (ctx *Context) IsCalculateExists() bool {
 	return false
 }
 
+// rollMode 当前的结算模式，-1为取最小值，1为取最大值。max(...) min(...) 中优先于全局设置
+func (ctx *Context) rollMode() int {
+	if len(ctx.rollModes) > 0 {
+		return ctx.rollModes[len(ctx.rollModes)-1]
+	}
+	if ctx.Config.DiceMinMode {
+		return -1
+	}
+	if ctx.Config.DiceMaxMode {
+		return 1
+	}
+	return 0
+}
+
+// AddOpCount 原生函数和骰池算法按工作量计入算力，如 pool() 每颗骰子计1。
+// 超出 OpCountLimit、时限或配额，或执行被中断时设置 ctx.Error 并返回false，此时应立即返回
+func (ctx *Context) AddOpCount(count IntType) bool {
+	ctx.NumOpCount += count
+	if ctx.isInterrupted() {
+		ctx.Error = ErrInterrupted
+		return false
+	}
+	if ctx.Config.OpCountLimit > 0 && ctx.NumOpCount > ctx.Config.OpCountLimit {
+		ctx.Error = errOpCountLimit
+		return false
+	}
+	if deadline := ctx.rootCtx().deadline; !deadline.IsZero() && time.Now().After(deadline) {
+		ctx.Error = ErrTimeLimit
+		return false
+	}
+	if err := ctx.chargeQuota(false); err != nil {
+		ctx.Error = err
+		return false
+	}
+	return true
+}
+
+// setCallDetail 原生函数为本次调用写入计算过程，如 pool() 的各个骰点。
+// 直接调用时由vm放在调用处，span的范围由vm填写，Ret为nil时使用返回值
+func (ctx *Context) setCallDetail(span BufferSpan) {
+	ctx.callDetail = &span
+}
+
+// callEnd 原文中pos处(函数名之后)的调用的结束位置，即对应的右括号之后。找不到时返回-1
+func (ctx *Context) callEnd(pos IntType) IntType {
+	if ctx.parser == nil {
+		return -1
+	}
+	data := ctx.parser.data
+	i := int(pos)
+	for i < len(data) && (data[i] == ' ' || data[i] == '\t') {
+		i++
+	}
+	if i >= len(data) || data[i] != '(' {
+		return -1
+	}
+	depth := 0
+	var quote byte
+	for ; i < len(data); i++ {
+		c := data[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+			if depth == 0 {
+				return IntType(i + 1)
+			}
+		}
+	}
+	return -1
+}
+
 func (ctx *Context) RunAfterParsed() error {
 	ctx.IsComputedLoaded = false
+	// 执行前的随机源状态，重骰和继续执行时以此重现同样的骰点。这里只复制状态，需要时才序列化
+	randState := ctx.randState()
+	ctx.Outputs = nil
+	ctx.Events = nil
+	ctx.KarmaSpends = nil
+	ctx.Rolls = nil
+	ctx.quotaReported = ctx.NumOpCount
+	if ctx.UpCtx == nil {
+		ctx.memoStack = nil
+		ctx.closeGenerators()
+		defer ctx.closeGenerators()
+		ctx.resetInterrupt()
+		ctx.deadline = time.Time{}
+		if ctx.Config.TimeLimit > 0 {
+			ctx.deadline = time.Now().Add(ctx.Config.TimeLimit)
+		}
+	}
+	if ctx.UpCtx == nil && len(ctx.runVars) > 0 {
+		defer ctx.bindRunVars()()
+	}
+	ctx.prefetchGlobals()
+	defer func() { ctx.prefetch = nil }()
+	ctx.beginAwait()
 	// 以下为eval
 	ctx.evaluate()
+	ctx.endAwait(&randState)
+	// 出错时同样报告余下的算力，但不覆盖原本的错误
+	if err := ctx.chargeQuota(true); err != nil && ctx.Error == nil {
+		ctx.Error = err
+	}
 	if ctx.Error != nil {
 		return ctx.Error
 	}
@@ -140,6 +305,12 @@ func (ctx *Context) RunAfterParsed() error {
 	})
 	ctx.Matched = matched
 	ctx.RestInput = string(ctx.parser.data[len(matched):])
+	ctx.recordHistory()
+	ctx.lastRun = nil
+	if ctx.Config.HistorySize > 0 {
+		seed, _ := randState.MarshalBinary()
+		ctx.lastRun = &lastRunInfo{expr: matched, seed: seed}
+	}
 	return nil
 }
 
@@ -151,6 +322,135 @@ func (ctx *Context) Run(value string) error {
 	return ctx.RunAfterParsed()
 }
 
+// RollResult 一次执行的结果，与vm的状态无关，vm复用后依然有效
+type RollResult struct {
+	Value     *VMValue // 结果值
+	Detail    string   // 计算过程
+	Matched   string   // 匹配的字符串
+	RestInput string   // 剩余字符串
+	OpCount   IntType  // 消耗的算力
+
+	Spans   []BufferSpan // 计算过程的各个部分，quiet()中和开启 QuietDetail 时不显示的骰点也会记录
+	Outputs []OutputItem // output() 给出的带标签的结果，按调用顺序排列
+	Events  []EventItem  // emit() 给出的事件，按调用顺序排列
+
+	KarmaSpends []KarmaSpend // RuleSet.Karma 消耗资源修改骰点的记录
+	Rolls       []RollRecord // 每一次骰点，见 Context.Rolls
+}
+
+// Evaluate 执行给定语句并返回结果，是 Run 的另一种形式，无需再读取 ctx.Ret、ctx.Error 等字段
+func (ctx *Context) Evaluate(expr string) (*RollResult, error) {
+	if err := ctx.Run(expr); err != nil {
+		return nil, err
+	}
+	return ctx.result(), nil
+}
+
+func (ctx *Context) result() *RollResult {
+	return &RollResult{
+		Value:     ctx.Ret.Clone(),
+		Detail:    ctx.GetDetailText(),
+		Matched:   ctx.Matched,
+		RestInput: ctx.RestInput,
+		OpCount:   ctx.NumOpCount,
+		Spans:     ctx.DetailSpans,
+		Outputs:   ctx.Outputs,
+		Events:    ctx.Events,
+
+		KarmaSpends: ctx.KarmaSpends,
+		Rolls:       ctx.Rolls,
+	}
+}
+
+// Evaluate 使用给定的选项创建一个新的vm，执行语句并返回结果
+func Evaluate(expr string, opts ...Option) (*RollResult, error) {
+	return NewVM(opts...).Evaluate(expr)
+}
+
+// Bounds 求语句结果的下界和上界，不进行骰点。语句只解析一次，分别以 DiceMinMode 和 DiceMaxMode 执行两遍，
+// 因此赋值等副作用也会执行两遍。各骰子独立取最值，如 d20-d6 的下界为0而非-5，仅适用于校验伤害上限这样的简单场景
+func (ctx *Context) Bounds(expr string) (min *VMValue, max *VMValue, err error) {
+	oldConfig, oldHistory, oldLastRun := ctx.Config, ctx.history, ctx.lastRun
+	defer func() {
+		ctx.Config, ctx.history, ctx.lastRun = oldConfig, oldHistory, oldLastRun
+	}()
+	// 不计入执行记录
+	ctx.Config.HistorySize = 0
+
+	if err := ctx.Parse(expr); err != nil {
+		return nil, nil, err
+	}
+	ctx.Config.DiceMinMode, ctx.Config.DiceMaxMode = true, false
+	if err := ctx.RunAfterParsed(); err != nil {
+		return nil, nil, err
+	}
+	min = ctx.Ret.Clone()
+	ctx.Config.DiceMinMode, ctx.Config.DiceMaxMode = false, true
+	if err := ctx.RunAfterParsed(); err != nil {
+		return nil, nil, err
+	}
+	max = ctx.Ret.Clone()
+
+	// 如 -d6 以最小值结算时反而得到更大的结果
+	if a, ok1 := readNumber(min); ok1 {
+		if b, ok2 := readNumber(max); ok2 && a > b {
+			min, max = max, min
+		}
+	}
+	return min, max, nil
+}
+
+// Bounds 使用给定的选项创建一个新的vm，求语句结果的下界和上界
+func Bounds(expr string, opts ...Option) (min *VMValue, max *VMValue, err error) {
+	return NewVM(opts...).Bounds(expr)
+}
+
+// RunLimits 算力限制，含义与 RollConfig 中的同名字段相同
+type RunLimits struct {
+	OpCountLimit   IntType
+	ParseExprLimit uint64
+	TimeLimit      time.Duration
+}
+
+// RunOptions 单次执行的选项，只在本次执行中生效
+type RunOptions struct {
+	Flags  *RollConfig // 本次执行使用的语法开关等配置，为nil时沿用当前配置。其中的钩子与回调不生效，沿用当前配置的
+	Limits *RunLimits  // 本次执行的算力限制，为nil时沿用配置中的值
+	Seed   []byte      // 本次执行使用的随机种子，16个字节，为nil时沿用当前的随机源
+
+	Capabilities []string // 本次执行允许的能力，为nil时沿用配置中的 AllowedCapabilities
+}
+
+// RunWith 使用给定的选项执行语句，执行完毕后还原vm的配置和随机源，避免复用vm时选项互相影响
+func (ctx *Context) RunWith(value string, opts RunOptions) error {
+	oldConfig := ctx.Config
+	oldRandSrc := ctx.RandSrc
+	defer func() {
+		ctx.Config = oldConfig
+		ctx.RandSrc = oldRandSrc
+	}()
+
+	if opts.Flags != nil {
+		ctx.Config.mergeFlags(opts.Flags)
+	}
+	if opts.Limits != nil {
+		ctx.Config.OpCountLimit = opts.Limits.OpCountLimit
+		ctx.Config.ParseExprLimit = opts.Limits.ParseExprLimit
+		ctx.Config.TimeLimit = opts.Limits.TimeLimit
+	}
+	if opts.Capabilities != nil {
+		ctx.Config.AllowedCapabilities = opts.Capabilities
+	}
+	if opts.Seed != nil {
+		s := rand.PCGSource{}
+		if err := s.UnmarshalBinary(opts.Seed); err != nil {
+			return err
+		}
+		ctx.RandSrc = &s
+	}
+	return ctx.Run(value)
+}
+
 type spanByBegin []BufferSpan
 
 func (a spanByBegin) Len() int           { return len(a) }
@@ -198,6 +498,9 @@ d10=10[d10=10=10]=10 首先进行一次省略，即文本标注如果等于值
 */
 func (ctx *Context) makeDetailStr(details []BufferSpan) string {
 	offset := ctx.parser.pt.offset
+	if ctx.Config.QuietDetail {
+		return ""
+	}
 	if ctx.Config.CustomMakeDetailFunc != nil {
 		return ctx.Config.CustomMakeDetailFunc(ctx, details, ctx.parser.data, offset)
 	}
@@ -246,6 +549,19 @@ func (ctx *Context) makeDetailStr(details []BufferSpan) string {
 		sort.Sort(spanByEnd(item.spans))
 		last := item.spans[size-1]
 
+		if last.Tag == "note" {
+			// 注释替换掉 note(...) 本身，其中的骰点等不再展开
+			writeBuf(detailResult[:item.begin])
+			writeBufStr("[" + last.Text + "]")
+			writeBuf(detailResult[item.end:])
+			detailResult = buf.Bytes()
+			continue
+		}
+		if last.Tag == "quiet" {
+			// 保持原文，不展开其中的骰点
+			continue
+		}
+
 		subDetailsText := ""
 		if size > 1 {
 			// 次级结果，如 (10d3)d5 中，此处为10d3的结果
@@ -253,7 +569,7 @@ func (ctx *Context) makeDetailStr(details []BufferSpan) string {
 			var subDetailParts []string
 			for j := 0; j < len(item.spans)-1; j++ {
 				span := item.spans[j]
-				subDetail := string(detailResult[span.Begin:span.End]) + "=" + span.Ret.ToString()
+				subDetail := string(detailResult[span.Begin:span.End]) + "=" + ctx.ToString(span.Ret)
 				if ctx.Config.CustomDetailSpanRewriteFunc != nil {
 					subDetail = ctx.Config.CustomDetailSpanRewriteFunc(ctx, subDetail, span, false, ctx.parser.data, offset)
 				}
@@ -275,7 +591,7 @@ func (ctx *Context) makeDetailStr(details []BufferSpan) string {
 		writeBuf(detailResult[:item.begin])
 
 		// 主体结果部分，如 (10d3)d5=63[(10d3)d5=2+2+2+5+2+5+5+4+1+3+4+1+4+5+4+3+4+5+2,10d3=19]
-		partRet := last.Ret.ToString()
+		partRet := ctx.ToString(last.Ret)
 
 		detail := "["
 		exprSuffix := last.ExprSuffix
@@ -332,7 +648,7 @@ func (ctx *Context) makeDetailStr(details []BufferSpan) string {
 	}
 
 	detailStr := string(detailResult)
-	if detailStr == ctx.Ret.ToString() {
+	if detailStr == ctx.ToString(ctx.Ret) {
 		detailStr = "" // 如果detail和结果值完全一致，那么将其置空
 	}
 	return strings.TrimSpace(detailStr)
@@ -349,11 +665,35 @@ func (ctx *Context) evaluate() {
 
 	e := ctx
 	// ctx := &e.Context
+	prof := ctx.profiler
+	if prof != nil && len(prof.frames) == 0 {
+		prof.enter("main")
+		defer prof.exit()
+	}
 	var details []BufferSpan
+	deadline := ctx.rootCtx().deadline
+	deadlineChecked := e.NumOpCount
+	interrupted := &ctx.rootCtx().interrupted
 	numOpCountAdd := func(count IntType) bool {
 		e.NumOpCount += count
+		if atomic.LoadInt32(interrupted) != 0 {
+			ctx.Error = ErrInterrupted
+			return true
+		}
 		if ctx.Config.OpCountLimit > 0 && e.NumOpCount > ctx.Config.OpCountLimit {
-			ctx.Error = errors.New("允许算力上限")
+			ctx.Error = errOpCountLimit
+			return true
+		}
+		// 取时间有开销，每隔一段算力检查一次
+		if !deadline.IsZero() && e.NumOpCount-deadlineChecked >= deadlineCheckInterval {
+			deadlineChecked = e.NumOpCount
+			if time.Now().After(deadline) {
+				ctx.Error = ErrTimeLimit
+				return true
+			}
+		}
+		if err := ctx.chargeQuota(false); err != nil {
+			ctx.Error = err
 			return true
 		}
 		return false
@@ -367,6 +707,7 @@ func (ctx *Context) evaluate() {
 		highNum  IntType
 		min      *IntType
 		max      *IntType
+		explode  bool // 爆炸骰
 	}
 
 	diceInit := func() {
@@ -378,9 +719,14 @@ func (ctx *Context) evaluate() {
 			highNum  IntType
 			min      *IntType
 			max      *IntType
+			explode  bool
 		}{
 			times: 1,
 		}
+		if ctx.Config.DefaultDiceCount > 0 {
+			// 省略个数时(d20、d)的个数，写明个数时随后由 typeDiceSetTimes 覆盖
+			data.times = ctx.Config.DefaultDiceCount
+		}
 
 		if diceStateIndex >= len(diceStates) {
 			diceStates = append(diceStates, data)
@@ -422,6 +768,13 @@ func (ctx *Context) evaluate() {
 		ctx.DetailSpans = details
 	}
 
+	// 记录刚完成的骰点，见 Context.Rolls
+	recordRoll := func() {
+		if len(details) > 0 {
+			ctx.recordRoll(&details[len(details)-1])
+		}
+	}
+
 	var lastPop *VMValue
 	stackPop := func() *VMValue {
 		v := &e.stack[e.top-1]
@@ -430,6 +783,11 @@ func (ctx *Context) evaluate() {
 		return v
 	}
 
+	// popOperand 弹出骰子个数、面数等数值参数，见 intOperand
+	popOperand := func() *VMValue {
+		return ctx.intOperand(stackPop())
+	}
+
 	stackPop2 := func() (*VMValue, *VMValue) {
 		v2, v1 := stackPop(), stackPop()
 		lastPop = v1
@@ -450,21 +808,85 @@ func (ctx *Context) evaluate() {
 		return data
 	}
 
+	intBool := ctx.Config.Compat.IntBool
 	stackPush := func(v *VMValue) {
 		e.stack[e.top] = *v
+		if intBool && v.TypeId == VMTypeBool {
+			e.stack[e.top] = *boolToIntVal(v.Value.(bool))
+			e.stack[e.top].Tag = v.Tag
+		}
 		e.top += 1
 	}
+	// pushNew 新产生的值入栈，先经过 OnValueCreate。读取变量等已有的值用 stackPush
+	pushNew := func(v *VMValue) {
+		ctx.valueCreated(v)
+		stackPush(v)
+	}
+	// pushConst 字面量入栈
+	pushConst := func(t VMValueType, val any) {
+		if intBool && t == VMTypeBool {
+			t, val = VMTypeInt, boolToIntVal(val.(bool)).Value
+		}
+		stack[e.top] = VMValue{TypeId: t, Value: val}
+		ctx.valueCreated(&stack[e.top])
+		e.top++
+	}
+
+	ctx.rollModes = nil
+	var rollModeCalls []*VMValue // 与 ctx.rollModes 对应，不为nil时 max(...) 实为对同名函数的调用
 
-	getRollMode := func() int {
-		if ctx.Config.DiceMinMode {
-			return -1
+	// max(...) note(...) 等与函数调用的写法相同，存在同名的函数(包括内置函数)时按函数调用处理
+	shadowingCallable := func(name string) *VMValue {
+		if !ctx.ExistsName(name, false) && ctx.loadInnerVar(name) == nil {
+			return nil
+		}
+		v := ctx.LoadName(name, true, true)
+		if v == nil {
+			return nil
+		}
+		switch v.TypeId {
+		case VMTypeFunction, VMTypeNativeFunction, VMTypeComputedValue:
+			return v
 		}
-		if ctx.Config.DiceMaxMode {
-			return 1
+		return nil
+	}
+	// invokeShadowing 以栈顶的值为参数调用同名的函数
+	invokeShadowing := func(fn *VMValue) bool {
+		arg := stackPop().Clone()
+		ret := invokeCallable(ctx, fn, []*VMValue{arg})
+		if ctx.Error != nil {
+			return false
+		}
+		stackPush(ret)
+		return true
+	}
+	getRollMode := ctx.rollMode
+
+	// attachCallDetail 原生函数留下的计算过程放在调用处，即从函数名到右括号，见 setCallDetail
+	attachCallDetail := func(funcObj *VMValue, ret *VMValue) {
+		span := ctx.callDetail
+		ctx.callDetail = nil
+		for i := len(details) - 1; i >= 0; i-- {
+			d := details[i]
+			if d.Tag != "load" || d.Ret == nil || d.Ret.TypeId != VMTypeNativeFunction || d.Ret.Value != funcObj.Value {
+				continue
+			}
+			end := ctx.callEnd(d.End)
+			if end < 0 {
+				return
+			}
+			span.Begin, span.End = d.Begin, end
+			if span.Ret == nil {
+				span.Ret = ret
+			}
+			details[i] = *span
+			return
 		}
-		return 0
 	}
 
+	// 公共子表达式的结果，见 eliminateCommonSubexpr
+	var cseSlots []cseSlot
+
 	var fstrBlockStack [20]int
 	var fstrBlockIndex int
 
@@ -484,6 +906,9 @@ func (ctx *Context) evaluate() {
 		}
 
 		code := e.code[opIndex]
+		if prof != nil {
+			prof.step(e.code, opIndex)
+		}
 		cIndex := fmt.Sprintf("%d/%d", opIndex+1, e.codeIndex)
 		if ctx.Config.PrintBytecode {
 			var subThread string
@@ -495,21 +920,71 @@ func (ctx *Context) evaluate() {
 
 		switch code.T {
 		case typePushIntNumber:
-			stack[e.top].TypeId = VMTypeInt
-			stack[e.top].Value = code.Value
-			e.top++
+			pushConst(VMTypeInt, code.Value)
 		case typePushFloatNumber:
-			stack[e.top].TypeId = VMTypeFloat
-			stack[e.top].Value = code.Value
-			e.top++
+			pushConst(VMTypeFloat, code.Value)
+		case typePushBool:
+			pushConst(VMTypeBool, code.Value)
+		case typePushDuration:
+			pushConst(VMTypeDuration, code.Value)
+		case typePushQuantity:
+			pushConst(VMTypeQuantity, code.Value)
+		case typePushMoney:
+			pushConst(VMTypeMoney, code.Value)
 		case typePushString:
-			s := code.Value.(string)
-			stack[e.top].TypeId = VMTypeString
-			stack[e.top].Value = s
-			e.top++
+			pushConst(VMTypeString, code.Value.(string))
 		case typePushArray:
 			num := code.Value.(IntType)
-			stackPush(NewArrayVal(stackPopN(num)...))
+			items := stackPopN(num)
+			hasSpread := false
+			for _, i := range items {
+				if i.TypeId == vmTypeSpread {
+					hasSpread = true
+					break
+				}
+			}
+			if hasSpread {
+				var lst []*VMValue
+				for _, i := range items {
+					if i.TypeId == vmTypeSpread {
+						lst = append(lst, ctx.iterToList(i.Value.(*VMValue))...)
+						if ctx.Error != nil {
+							return
+						}
+					} else {
+						lst = append(lst, i)
+					}
+				}
+				items = lst
+			}
+			pushNew(NewArrayValRaw(items))
+		case typeSpread:
+			v := stackPop().Clone()
+			stackPush(&VMValue{TypeId: vmTypeSpread, Value: v})
+		case typePushTable:
+			num := code.Value.(IntType)
+			items := stackPopN(num)
+			rows := make([][]*VMValue, len(items))
+			for i, item := range items {
+				rows[i] = item.MustReadArray().List
+			}
+			v, err := NewTableVal(nil, rows)
+			if err != nil {
+				ctx.Error = err
+				return
+			}
+			pushNew(v)
+		case typePushTuple:
+			num := code.Value.(IntType)
+			pushNew(NewTupleVal(stackPopN(num)...))
+		case typePushSet:
+			num := code.Value.(IntType)
+			v, err := NewSetVal(stackPopN(num)...)
+			if err != nil {
+				ctx.Error = err
+				return
+			}
+			pushNew(v)
 		case typePushDict:
 			num := code.Value.(IntType)
 			items := stackPopN(num * 2)
@@ -518,17 +993,28 @@ func (ctx *Context) evaluate() {
 				e.Error = err
 				return
 			}
-			stackPush(dict.V())
+			pushNew(dict.V())
 		case typePushComputed, typePushFunction:
 			val := code.Value.(*VMValue)
-			stackPush(val)
+			if ctx.OnValueCreate != nil {
+				// 字节码可能被缓存和多次执行，交给回调的是副本，以免标记写入字节码中的常量
+				val = val.Clone()
+			}
+			pushNew(val)
 		case typePushNull:
-			stackPush(NewNullVal())
+			pushNew(NewNullVal())
 		case typePushThis:
 			stackPush(vmValueNewLocal())
 		// case typePushGlobal:
 		//	stackPush(vmValueNewGlobal())
 
+		case typeRange:
+			a, b := stackPop2()
+			ret := newRange(ctx, operandValue(a), operandValue(b))
+			if ctx.Error != nil {
+				return
+			}
+			pushNew(ret)
 		case typePushRange:
 			a, b := stackPop2()
 			_a, ok1 := a.ReadInt()
@@ -560,7 +1046,7 @@ func (ctx *Context) evaluate() {
 					break
 				}
 			}
-			stackPush(NewArrayVal(arr...))
+			pushNew(NewArrayVal(arr...))
 		case typePushLast:
 			if lastPop == nil {
 				ctx.Error = errors.New("非法调用指令 push.last")
@@ -569,7 +1055,7 @@ func (ctx *Context) evaluate() {
 			stackPush(lastPop)
 		case typePushDefaultExpr:
 			// 创建一个函数对象，然后调用它
-			if ctx.Config.DefaultDiceSideExpr != "" {
+			if ctx.Config.DefaultDiceSideExpr != "" && !ctx.Config.Compat.BareDiceD100 {
 				var val *VMValue
 
 				// 检查缓存
@@ -599,7 +1085,7 @@ func (ctx *Context) evaluate() {
 				}
 				stackPush(v)
 			} else {
-				stackPush(NewIntVal(100))
+				pushNew(NewIntVal(100))
 			}
 
 			d := &details[len(details)-1]
@@ -631,13 +1117,9 @@ func (ctx *Context) evaluate() {
 				}
 			}
 
-		case typeLogicAnd:
-			a, b := stackPop2()
-			if !a.AsBool() {
-				stackPush(a)
-			} else {
-				stackPush(b)
-			}
+		case typeLogicNot:
+			v := stackPop()
+			pushNew(NewBoolVal(!v.AsBool()))
 
 		case typeInvoke:
 			paramsNum := code.Value.(IntType)
@@ -651,10 +1133,20 @@ func (ctx *Context) evaluate() {
 				}
 				stackPush(ret)
 			} else if funcObj.TypeId == VMTypeNativeFunction {
+				ctx.callDetail = nil
 				ret := funcObj.FuncInvokeNative(ctx, arr)
 				if ctx.Error != nil {
 					return
 				}
+				if ctx.callDetail != nil {
+					attachCallDetail(funcObj, ret)
+				}
+				stackPush(ret)
+			} else if funcObj.TypeId == VMTypeComputedValue {
+				ret := funcObj.ComputedInvoke(ctx, arr, nil)
+				if ctx.Error != nil {
+					return
+				}
 				stackPush(ret)
 			} else {
 				ctx.Error = fmt.Errorf("类型错误: [%s]无法被调用，必须是一个函数", funcObj.ToString())
@@ -668,7 +1160,7 @@ func (ctx *Context) evaluate() {
 				return
 			}
 			if ret == nil {
-				ret = NewNullVal()
+				ret = ctx.newMissingVal()
 			}
 			stackPush(ret)
 		case typeItemSet:
@@ -697,6 +1189,32 @@ func (ctx *Context) evaluate() {
 			if ctx.Error != nil {
 				return
 			}
+			if ret == nil && obj.IsNullish() {
+				// 读取不存在的属性路径，如 a.b.c 中 a.b 不存在，与是否开启 AttrPathAutoCreate 无关
+				ret = ctx.newMissingVal()
+			}
+			if ret == nil {
+				ctx.Error = errors.New("不支持的类型：当前变量无法用.来取属性")
+				return
+			}
+			stackPush(ret)
+		case typeAttrGetPath:
+			obj := stackPop()
+			attrName := code.Value.(string)
+			ret := obj.AttrGet(ctx, attrName)
+			if ctx.Error != nil {
+				return
+			}
+			if ctx.Config.AttrPathAutoCreate && (ret == nil || ret.IsNullish()) {
+				ret = NewDictVal(nil).V()
+				if obj.AttrSet(ctx, attrName, ret) == nil {
+					ctx.Error = fmt.Errorf("不支持的类型：无法为属性 %s 自动创建字典", attrName)
+					return
+				}
+				if ctx.Error != nil {
+					return
+				}
+			}
 			if ret == nil {
 				ctx.Error = errors.New("不支持的类型：当前变量无法用.来取属性")
 				return
@@ -715,7 +1233,7 @@ func (ctx *Context) evaluate() {
 			if ctx.Error != nil {
 				return
 			}
-			stackPush(ret)
+			pushNew(ret)
 		case typeSliceSet:
 			val := stackPop()
 			step := stackPop() // step
@@ -735,6 +1253,17 @@ func (ctx *Context) evaluate() {
 			solveDetail()
 			ctx.IsRunning = false
 			return
+		case typeYield:
+			v := stackPop().Clone()
+			if ctx.generator == nil {
+				ctx.Error = errors.New("yield只能在函数中使用")
+				return
+			}
+			if !ctx.generator.yield(v) {
+				ctx.Error = errGeneratorClosed
+				return
+			}
+			pushNew(NewNullVal())
 		case typeHalt:
 			solveDetail()
 			ctx.IsRunning = false
@@ -752,13 +1281,11 @@ func (ctx *Context) evaluate() {
 				} else {
 					val = stack[e.top-num+index]
 				}
-				outStr += val.ToString()
+				outStr += ctx.ToString(&val)
 			}
 
 			e.top -= num
-			stack[e.top].TypeId = VMTypeString
-			stack[e.top].Value = outStr
-			e.top++
+			pushConst(VMTypeString, outStr)
 		case typeLoadName, typeLoadNameRaw, typeLoadNameWithDetail:
 			name := code.Value.(string)
 			var val *VMValue
@@ -780,6 +1307,55 @@ func (ctx *Context) evaluate() {
 
 			stackPush(val)
 
+		case typeCSEMark:
+			k := int(code.Value.(IntType))
+			for len(cseSlots) <= k {
+				cseSlots = append(cseSlots, cseSlot{})
+			}
+			slot := &cseSlots[k]
+			slot.valid = false
+			slot.detailStart = len(details)
+			slot.computed = ctx.IsComputedLoaded
+			ctx.IsComputedLoaded = false
+		case typeCSEStore:
+			slot := &cseSlots[code.Value.(IntType)]
+			if !ctx.IsComputedLoaded {
+				// 读取到computed时结果可能每次不同，不保存
+				slot.valid = true
+				slot.val = e.stack[e.top-1].Clone()
+				slot.spans = append([]BufferSpan(nil), details[slot.detailStart:]...)
+			}
+			ctx.IsComputedLoaded = ctx.IsComputedLoaded || slot.computed
+		case typeCSELoad:
+			k := int(code.Value.(IntType))
+			if k >= len(cseSlots) || !cseSlots[k].valid {
+				stackPush(NewIntVal(0))
+				break
+			}
+			// 被跳过的代码中的detail标记使用第一次计算时的结果
+			slot := &cseSlots[k]
+			n := int(e.code[opIndex+1].Value.(IntType))
+			j := 0
+			for _, c := range e.code[opIndex+2 : opIndex+2+n] {
+				if c.T == typeDetailMark && j < len(slot.spans) {
+					span := slot.spans[j]
+					mark := c.Value.(BufferSpan)
+					span.Begin, span.End = mark.Begin, mark.End
+					details = append(details, span)
+					j++
+				}
+			}
+			stackPush(slot.val.Clone())
+			stackPush(NewIntVal(1))
+
+		case typeLoadConstWithDetail:
+			val := code.Value.(*VMValue)
+			detail := &details[len(details)-1]
+			detail.Tag = "load"
+			detail.Text = ""
+			detail.Ret = val
+			stackPush(val)
+
 		case typeStoreName:
 			v := e.stack[e.top-1].Clone()
 			name := code.Value.(string)
@@ -789,6 +1365,33 @@ func (ctx *Context) evaluate() {
 				return
 			}
 
+		case typeStoreNameConst:
+			v := e.stack[e.top-1].Clone()
+			name := code.Value.(string)
+
+			ctx.StoreName(name, v, true)
+			if ctx.Error != nil {
+				return
+			}
+			ctx.RegReadOnlyNames(name)
+
+		case typeUnpack:
+			items := e.stack[e.top-1].unpack(ctx, int(code.Value.(IntType)))
+			if ctx.Error != nil {
+				return
+			}
+			for i := len(items) - 1; i >= 0; i-- {
+				stackPush(items[i])
+			}
+
+		case typeDeleteName:
+			name := code.Value.(string)
+			ctx.DeleteName(name, true)
+			if ctx.Error != nil {
+				return
+			}
+			pushNew(NewNullVal())
+
 		case typeJe, typeJeDup:
 			v := stackPop()
 			if v.AsBool() {
@@ -797,13 +1400,71 @@ func (ctx *Context) evaluate() {
 					stackPush(v)
 				}
 			}
-		case typeJne:
+		case typeJne, typeJneDup:
 			t := stackPop()
 			if !t.AsBool() {
 				opIndex += int(code.Value.(IntType))
+				if code.T == typeJneDup {
+					stackPush(t)
+				}
+			}
+		case typeJnnDup:
+			v := stackPop()
+			if !ctx.isMissing(v) {
+				opIndex += int(code.Value.(IntType))
+				stackPush(v)
 			}
 		case typeJmp:
 			opIndex += int(code.Value.(IntType))
+		case typeJmpTable:
+			// 不是整数时继续逐个分支匹配
+			if n, ok := e.stack[e.top-1].ReadInt(); ok {
+				table := code.Value.([]IntType)
+				if i := n - table[0]; i >= 0 && i < IntType(len(table)-2) {
+					opIndex += int(table[i+2])
+				} else {
+					opIndex += int(table[1])
+				}
+			}
+		case typeMatchValue:
+			pattern := stackPop()
+			if pattern.TypeId == VMTypeRange {
+				// 范围类型的值，如 r = 1..5; match x { r => 1 }
+				pushNew(e.stack[e.top-1].OpIn(ctx, pattern))
+			} else {
+				pushNew(e.stack[e.top-1].OpCompEQ(ctx, pattern))
+			}
+		case typeMatchRange:
+			hi := stackPop()
+			lo := stackPop()
+			v := e.stack[e.top-1]
+			// 无法比较时视为不匹配
+			ge, le := v.OpCompGE(ctx, lo), v.OpCompLE(ctx, hi)
+			pushNew(boolToVMValue(ge != nil && le != nil && ge.AsBool() && le.AsBool()))
+		case typeIterBegin:
+			v := stackPop()
+			it, err := v.iter()
+			if err != nil {
+				ctx.Error = err
+				return
+			}
+			stackPush(NewIteratorVal("for", it))
+		case typeIterNext:
+			// 迭代器位于for-in语句块的起始处，同时丢弃上一轮循环残留在栈上的值
+			base := blockStack[blockIndex-1]
+			e.top = base + 1
+			id, _ := stack[base].ReadIterator()
+			v, ok := id.it.next(ctx)
+			if ctx.Error != nil {
+				return
+			}
+			if ok {
+				ctx.StoreName(code.Value.(string), v.Clone(), true)
+				if ctx.Error != nil {
+					return
+				}
+			}
+			stackPush(boolToVMValue(ok))
 		case typePop:
 			stackPop()
 		case typePopN:
@@ -811,11 +1472,26 @@ func (ctx *Context) evaluate() {
 
 		case typeAdd, typeSubtract, typeMultiply, typeDivide, typeModulus, typeExponentiation, typeNullCoalescing,
 			typeCompLT, typeCompLE, typeCompEQ, typeCompNE, typeCompGE, typeCompGT,
-			typeBitwiseAnd, typeBitwiseOr:
+			typeBitwiseAnd, typeBitwiseOr, typeBitwiseXor, typeShiftLeft, typeShiftRight, typeIn:
 			// 所有二元运算符
 			v1, v2 := stackPop2()
+			if code.T != typeNullCoalescing && code.T != typeIn {
+				v1, v2 = operandValue(v1), operandValue(v2)
+			}
 			opFunc := binOperator[code.T-typeAdd]
 			ret := opFunc(v1, ctx, v2)
+			if ret != nil && ctx.Config.richCheck() {
+				switch code.T {
+				case typeCompLT:
+					ret = ctx.makeCheck("<", v1, v2, ret)
+				case typeCompLE:
+					ret = ctx.makeCheck("<=", v1, v2, ret)
+				case typeCompGE:
+					ret = ctx.makeCheck(">=", v1, v2, ret)
+				case typeCompGT:
+					ret = ctx.makeCheck(">", v1, v2, ret)
+				}
+			}
 			if ctx.Error == nil && ret == nil {
 				// TODO: 整理所有错误类型
 				opErr := fmt.Sprintf("这两种类型无法使用 %s 算符连接: %s, %s", code.CodeString(), v1.GetTypeName(), v2.GetTypeName())
@@ -824,10 +1500,10 @@ func (ctx *Context) evaluate() {
 			if ctx.Error != nil {
 				return
 			}
-			stackPush(ret)
+			pushNew(ret)
 
 		case typePositive, typeNegation:
-			v := stackPop()
+			v := operandValue(stackPop())
 			var ret *VMValue
 			if code.T == typePositive {
 				ret = v.OpPositive()
@@ -842,12 +1518,12 @@ func (ctx *Context) evaluate() {
 			if ctx.Error != nil {
 				return
 			}
-			stackPush(ret)
+			pushNew(ret)
 
 		case typeDiceInit:
 			diceInit()
 		case typeDiceSetTimes:
-			v := stackPop()
+			v := popOperand()
 			times, ok := v.ReadInt()
 			if !ok || times <= 0 {
 				ctx.Error = errors.New("骰点次数不为正整数")
@@ -855,36 +1531,87 @@ func (ctx *Context) evaluate() {
 			}
 			diceStates[diceStateIndex].times = times
 		case typeDiceSetKeepLowNum:
-			v := stackPop()
+			v := popOperand()
 			diceStates[diceStateIndex].isKeepLH = 1
 			diceStates[diceStateIndex].lowNum, _ = v.ReadInt()
 		case typeDiceSetKeepHighNum:
-			v := stackPop()
+			v := popOperand()
 			diceStates[diceStateIndex].isKeepLH = 2
 			diceStates[diceStateIndex].highNum, _ = v.ReadInt()
 		case typeDiceSetDropLowNum:
-			v := stackPop()
+			v := popOperand()
 			diceStates[diceStateIndex].isKeepLH = 3
 			diceStates[diceStateIndex].lowNum, _ = v.ReadInt()
 		case typeDiceSetDropHighNum:
-			v := stackPop()
+			v := popOperand()
 			diceStates[diceStateIndex].isKeepLH = 4
 			diceStates[diceStateIndex].highNum, _ = v.ReadInt()
 		case typeDiceSetMin:
-			v := stackPop()
+			v := popOperand()
 			i, _ := v.ReadInt()
 			diceStates[diceStateIndex].min = &i
 		case typeDiceSetMax:
-			v := stackPop()
+			v := popOperand()
 			i, _ := v.ReadInt()
 			diceStates[diceStateIndex].max = &i
+		case typeDiceSetExplode:
+			diceStates[diceStateIndex].explode = true
 		case typeDetailMark:
 			span := code.Value.(BufferSpan)
 			details = append(details, span)
 		case typeDice:
 			diceState := diceStates[diceStateIndex]
 
-			val := stackPop()
+			val := popOperand()
+			if rd, ok := val.ReadRange(); ok {
+				// d(3..8)
+				if diceState.isKeepLH != 0 || diceState.min != nil || diceState.max != nil || diceState.explode {
+					ctx.Error = errors.New("以范围为面数的骰子不支持取高取低等后缀")
+					return
+				}
+				if rd.Len() <= 0 {
+					ctx.Error = errors.New("骰子的范围为空")
+					return
+				}
+				if numOpCountAdd(diceState.times) {
+					return
+				}
+				diceStateIndex -= 1
+				num, detail := rollRange(ctx.RandSrc, rd, diceState.times, getRollMode())
+				ret := NewIntVal(num)
+				details[len(details)-1].Ret = ret
+				details[len(details)-1].Text = detail
+				details[len(details)-1].Tag = "dice"
+				recordRoll()
+				pushNew(ret)
+				break
+			}
+			if faces, ok := val.ReadArray(); ok {
+				// d[1,1,3,5]
+				if diceState.isKeepLH != 0 || diceState.min != nil || diceState.max != nil || diceState.explode {
+					ctx.Error = errors.New("以数组为各面的骰子不支持取高取低等后缀")
+					return
+				}
+				if len(faces.List) == 0 {
+					ctx.Error = errors.New("骰子的面不能为空")
+					return
+				}
+				if diceState.times <= 0 {
+					ctx.Error = errors.New("骰子个数不为正整数")
+					return
+				}
+				if numOpCountAdd(diceState.times) {
+					return
+				}
+				diceStateIndex -= 1
+				ret, detail := rollFaces(ctx, faces.List, diceState.times, getRollMode())
+				details[len(details)-1].Ret = ret
+				details[len(details)-1].Text = detail
+				details[len(details)-1].Tag = "dice"
+				recordRoll()
+				pushNew(ret)
+				break
+			}
 			bInt, ok := val.ReadInt()
 			if !ok || bInt <= 0 {
 				ctx.Error = errors.New("骰子面数不为正整数")
@@ -904,14 +1631,75 @@ func (ctx *Context) evaluate() {
 				return
 			}
 
-			num, detail := RollCommon(ctx.RandSrc, diceState.times, bInt, diceState.min, diceState.max, diceState.isKeepLH, diceState.lowNum, diceState.highNum, getRollMode())
+			var explodeLimit IntType
+			if diceState.explode {
+				explodeLimit = ctx.Config.DiceExplodeLimit
+				if explodeLimit <= 0 {
+					explodeLimit = defaultDiceExplodeLimit
+				}
+			}
+			num, detail, pool := rollCommonPool(ctx.RandSrc, diceState.times, bInt, diceState.min, diceState.max, diceState.isKeepLH, diceState.lowNum, diceState.highNum, getRollMode(), ctx.Config.SortDiceDetail, explodeLimit)
 			diceStateIndex -= 1
+			// 追加的骰子同样消耗算力
+			if numOpCountAdd(pool.explodedCount()) {
+				return
+			}
+			if getRollMode() == 0 {
+				num, detail = ctx.karma(diceState.times, bInt, pool, num, detail)
+			}
 
 			ret := NewIntVal(num)
 			details[len(details)-1].Ret = ret
 			details[len(details)-1].Text = detail
 			details[len(details)-1].Tag = "dice"
-			stackPush(ret)
+			details[len(details)-1].Pool = pool
+			recordRoll()
+			pushNew(ret)
+
+		case typeRollModePush:
+			mode, name := int(code.Value.(IntType)), "min"
+			if mode > 0 {
+				name = "max"
+			}
+			fn := shadowingCallable(name)
+			if ctx.Error != nil {
+				return
+			}
+			if fn != nil {
+				mode = getRollMode()
+			}
+			ctx.rollModes = append(ctx.rollModes, mode)
+			rollModeCalls = append(rollModeCalls, fn)
+		case typeRollModePop:
+			fn := rollModeCalls[len(rollModeCalls)-1]
+			ctx.rollModes = ctx.rollModes[:len(ctx.rollModes)-1]
+			rollModeCalls = rollModeCalls[:len(rollModeCalls)-1]
+			if fn != nil && !invokeShadowing(fn) {
+				return
+			}
+		case typeDetailNote, typeDetailQuiet:
+			name := "note"
+			if code.T == typeDetailQuiet {
+				name = "quiet"
+			}
+			if fn := shadowingCallable(name); fn != nil {
+				// 不再是计算过程的注释，去掉对应的span
+				details = details[:len(details)-1]
+				if !invokeShadowing(fn) {
+					return
+				}
+			} else if ctx.Error != nil {
+				return
+			} else if code.T == typeDetailNote {
+				v := stackPop()
+				details[len(details)-1].Ret = NewNullVal()
+				details[len(details)-1].Text = ctx.ToString(v)
+				details[len(details)-1].Tag = "note"
+				pushNew(NewNullVal())
+			} else {
+				details[len(details)-1].Ret = e.stack[e.top-1].Clone()
+				details[len(details)-1].Tag = "quiet"
+			}
 
 		case typeCustomDice:
 			compiled := code.Value.(*customDiceCompiled)
@@ -937,7 +1725,8 @@ func (ctx *Context) evaluate() {
 				}
 				detail.Tag = "dice-custom"
 			}
-			stackPush(ret)
+			recordRoll()
+			pushNew(ret)
 
 		case typeDiceFate:
 			sum, detail := RollFate(ctx.RandSrc, getRollMode())
@@ -945,10 +1734,24 @@ func (ctx *Context) evaluate() {
 			details[len(details)-1].Ret = ret
 			details[len(details)-1].Text = detail
 			details[len(details)-1].Tag = "dice-fate"
-			stackPush(ret)
+			recordRoll()
+			pushNew(ret)
+		case typeDiceFateN:
+			times := diceStates[diceStateIndex].times
+			diceStateIndex -= 1
+			if numOpCountAdd(times) {
+				return
+			}
+			sum, detail := RollFateN(ctx.RandSrc, times, getRollMode())
+			ret := NewIntVal(sum)
+			details[len(details)-1].Ret = ret
+			details[len(details)-1].Text = detail
+			details[len(details)-1].Tag = "dice-fate"
+			recordRoll()
+			pushNew(ret)
 
 		case typeDiceCocBonus, typeDiceCocPenalty:
-			t := stackPop()
+			t := popOperand()
 			diceNum := t.MustReadInt()
 
 			if numOpCountAdd(diceNum) {
@@ -965,30 +1768,31 @@ func (ctx *Context) evaluate() {
 			} else {
 				details[len(details)-1].Tag = "dice-coc-penalty"
 			}
-			stackPush(ret)
+			recordRoll()
+			pushNew(ret)
 
 		case typeWodSetInit:
 			// WOD 系列
 			wodInit()
 		case typeWodSetPoints:
-			v := stackPop()
+			v := popOperand()
 			// if v.TypeId != VMTypeInt {
 			//   // ...
 			// }
 			wodState.points = v.MustReadInt()
 		case typeWodSetThreshold:
-			v := stackPop()
+			v := popOperand()
 			wodState.threshold = v.MustReadInt()
 			wodState.isGE = true
 		case typeWodSetThresholdQ:
-			v := stackPop()
+			v := popOperand()
 			wodState.threshold = v.MustReadInt()
 			wodState.isGE = false
 		case typeWodSetPool:
-			v := stackPop()
+			v := popOperand()
 			wodState.pool = v.MustReadInt()
 		case typeDiceWod:
-			v := stackPop() // 加骰线
+			v := popOperand() // 加骰线
 
 			// 变量检查
 			if !wodCheck(ctx, v.MustReadInt(), wodState.pool, wodState.points, wodState.threshold) {
@@ -1000,19 +1804,20 @@ func (ctx *Context) evaluate() {
 			details[len(details)-1].Ret = ret
 			details[len(details)-1].Text = detailText
 			details[len(details)-1].Tag = "dice-wod"
-			stackPush(ret)
+			recordRoll()
+			pushNew(ret)
 
 		case typeDCSetInit:
 			// Double Cross
 			dcInit()
 		case typeDCSetPool:
-			v := stackPop()
+			v := popOperand()
 			dcState.pool = v.MustReadInt()
 		case typeDCSetPoints:
-			v := stackPop()
+			v := popOperand()
 			dcState.points = v.MustReadInt()
 		case typeDiceDC:
-			v := stackPop() // 暴击值 / 也可以理解为加骰线
+			v := popOperand() // 暴击值 / 也可以理解为加骰线
 			if !doubleCrossCheck(ctx, v.MustReadInt(), dcState.pool, dcState.points) {
 				return
 			}
@@ -1021,7 +1826,8 @@ func (ctx *Context) evaluate() {
 			details[len(details)-1].Ret = ret
 			details[len(details)-1].Text = detailText
 			details[len(details)-1].Tag = "dice-dc"
-			stackPush(ret)
+			recordRoll()
+			pushNew(ret)
 
 		case typeBlockPush:
 			if blockIndex > 20 {
@@ -1035,11 +1841,13 @@ func (ctx *Context) evaluate() {
 			e.top = newTop
 			blockIndex -= 1
 			if fstrBlockIndex > 0 {
-				stackPush(NewStrVal("")) // 在fstring中返回空字符串
+				pushNew(NewStrVal("")) // 在fstring中返回空字符串
 			} else {
-				stackPush(NewNullVal())
+				pushNew(NewNullVal())
 			}
 
+		case typeBlockUnwind:
+			blockIndex -= int(code.Value.(IntType))
 		case typeFStringBlockPush:
 			if fstrBlockIndex > 20 {
 				ctx.Error = errors.New("字符串模板嵌套层数过多")
@@ -1059,7 +1867,7 @@ func (ctx *Context) evaluate() {
 			if v != nil {
 				stackPush(v)
 			} else {
-				stackPush(NewStrVal(""))
+				pushNew(NewStrVal(""))
 			}
 
 		case typeStSetName:
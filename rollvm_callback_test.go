@@ -94,3 +94,56 @@ func TestCustomDetailSpanRewrite(t *testing.T) {
 	assert.True(t, loadSeen)
 	assert.True(t, computedSeen)
 }
+
+func TestScopeResolver(t *testing.T) {
+	newScope := func() (*ValueMap, *ScopeResolver) {
+		m := &ValueMap{}
+		return m, &ScopeResolver{
+			Load: func(ctx *Context, name string) *VMValue {
+				v, _ := m.Load(name)
+				return v
+			},
+			Store: func(ctx *Context, name string, v *VMValue) {
+				m.Store(name, v)
+			},
+			Delete: func(ctx *Context, name string) {
+				m.Delete(name)
+			},
+		}
+	}
+
+	vm := NewVM()
+	tmp, r1 := newScope()
+	group, r2 := newScope()
+	assert.NoError(t, vm.RegScopeResolver(ScopeTemp, r1))
+	assert.NoError(t, vm.RegScopeResolver(ScopeGroup, r2))
+	group.Store("计数", ni(5))
+
+	err := vm.Run("$t骰点 = 3; $g计数 = $g计数 + 1; func f() { return $t骰点 * 2 }; f()")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, ni(6)))
+	}
+	assert.True(t, valueEqual(tmp.MustLoad("骰点"), ni(3)))
+	assert.True(t, valueEqual(group.MustLoad("计数"), ni(6)))
+	_, ok := vm.Attrs.Load("$t骰点")
+	assert.False(t, ok)
+
+	err = vm.Run("del $t骰点; [exists('$t骰点'), exists('$g计数'), $m未注册 = 1]")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, na(nb(false), nb(true), ni(1))))
+	}
+	// 未注册的前缀仍为普通变量
+	_, ok = vm.Attrs.Load("$m未注册")
+	assert.True(t, ok)
+
+	// 只读作用域
+	vm = NewVM()
+	assert.NoError(t, vm.RegScopeResolver(ScopeCharacter, &ScopeResolver{
+		Load: func(ctx *Context, name string) *VMValue { return ni(1) },
+	}))
+	err = vm.Run("$m力量 = 2")
+	assert.Error(t, err)
+
+	assert.Error(t, vm.RegScopeResolver("", r1))
+	assert.Error(t, vm.RegScopeResolver(ScopeTemp, &ScopeResolver{}))
+}
@@ -26,6 +26,26 @@ func TestNullCoalescing(t *testing.T) {
 	if assert.NoError(t, err) {
 		assert.True(t, valueEqual(vm.Ret, ns("default")))
 	}
+
+	// 未定义的变量
+	vm = NewVM()
+	err = vm.Run("[勇气 ?? 50, x ?? y ?? 3, 0 ?? 1, 勇气 ?? 50 + 1]")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "[50, 3, 0, 51]", vm.Ret.ToString())
+	}
+
+	// 短路求值，左侧不为空值时不执行右侧
+	vm = NewVM()
+	err = vm.Run("a = [1]; 勇气 = 3; [勇气 ?? a.push(2), 勇气 ?? d100, a]")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "[3, 3, [1]]", vm.Ret.ToString())
+		assert.NotContains(t, vm.GetDetailText(), "[d100]")
+	}
+	vm = NewVM()
+	err = vm.Run("a = [1]; 勇气 ?? a.push(2); a")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "[1, 2]", vm.Ret.ToString())
+	}
 }
 
 func TestNullCoalescingWithGlobalFallback(t *testing.T) {
@@ -4,11 +4,15 @@ import (
 	"errors"
 	"fmt"
 	"regexp"
+	"runtime"
 	"strconv"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"golang.org/x/exp/rand"
 )
 
 func vmValueEqual(vm *Context, aKey string, bValue *VMValue) bool {
@@ -29,13 +33,29 @@ func simpleExecute(t *testing.T, expr string, ret *VMValue) *Context {
 }
 
 func TestValueDefineBool(t *testing.T) {
-	simpleExecute(t, "true", ni(1))
-	simpleExecute(t, "false", ni(0))
+	simpleExecute(t, "true", nb(true))
+	simpleExecute(t, "false", nb(false))
 }
 
 func TestValueDefineNumber(t *testing.T) {
 	simpleExecute(t, "123", ni(123))
 	simpleExecute(t, "1.2", nf(1.2))
+	simpleExecute(t, "1_000_000", ni(1000000))
+	simpleExecute(t, "0xFF", ni(255))
+	simpleExecute(t, "0x7f_ff", ni(0x7fff))
+	simpleExecute(t, "1e6", nf(1e6))
+	simpleExecute(t, "2.5E-3", nf(0.0025))
+	simpleExecute(t, ".5e2", nf(50))
+	simpleExecute(t, "1_000.5", nf(1000.5))
+	simpleExecute(t, "2d0x10 <= 32", nb(true))
+
+	// 不完整的写法不属于数字
+	vm := NewVM()
+	assert.NoError(t, vm.Run("1e"))
+	assert.True(t, valueEqual(vm.Ret, ni(1)))
+	assert.Equal(t, "e", vm.RestInput)
+	assert.NoError(t, vm.Run("1_"))
+	assert.Equal(t, "_", vm.RestInput)
 }
 
 func TestValueIdentifier(t *testing.T) {
@@ -393,6 +413,126 @@ func TestVMDivideModulus(t *testing.T) {
 	}
 }
 
+func TestDiceExplode(t *testing.T) {
+	vm := NewVM()
+	vm.Config.DiceMaxMode = true
+	vm.Config.DiceExplodeLimit = 2
+	err := vm.Run("2d6!k1 + d4!")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, ni(30)))
+		assert.Equal(t, "18[2d6!k1={(6!+6!+6) | (6!+6!+6)}] + 12[d4!=(4!+4!+4)]", vm.GetDetailText())
+	}
+
+	// 默认上限为100，追加的骰子计入算力
+	vm = NewVM()
+	vm.Config.OpCountLimit = 50
+	err = vm.Run("d1!")
+	assert.Error(t, err)
+	vm.Config.OpCountLimit = 0
+	err = vm.Run("d1!")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, ni(101)))
+	}
+
+	// != 不受影响
+	err = vm.Run("d1!=1")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, nb(false)))
+	}
+
+	if assert.NoError(t, vm.Parse("3d6!")) {
+		assert.False(t, vm.ProgramInfo().CostEstimate().Bounded)
+	}
+}
+
+func TestDiceFateN(t *testing.T) {
+	vm := NewVM()
+	vm.Config.EnableDiceFate = true
+	vm.Config.DiceMaxMode = true
+	err := vm.Run("4dF + 1")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, ni(5)))
+		assert.Equal(t, "4[4dF=++++] + 1", vm.GetDetailText())
+	}
+
+	// 省略个数时由 DefaultDiceCount 决定
+	vm.Config.DiceMaxMode = false
+	vm.Config.DiceMinMode = true
+	err = vm.Run("dF + (1+1)df")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, ni(-3)))
+		assert.Equal(t, "-1[dF=-] + -2[(1+1)df=--]", vm.GetDetailText())
+	}
+	vm.Config.DefaultDiceCount = 3
+	err = vm.Run("dF")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, ni(-3)))
+	}
+
+	err = vm.Run("0dF")
+	assert.Error(t, err)
+
+	// dF开头的变量名不受影响
+	err = vm.Run("dFx = 2; dFx")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, ni(2)))
+	}
+
+	if assert.NoError(t, vm.Parse("5dF")) {
+		assert.Equal(t, IntType(5), vm.ProgramInfo().CostEstimate().Dice)
+	}
+}
+
+func TestDiceFaces(t *testing.T) {
+	vm := NewVM()
+	vm.Config.DiceMaxMode = true
+	err := vm.Run("3d[2,4,8]")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, ni(24)))
+		assert.Equal(t, "24[3d[2,4,8]=8+8+8]", vm.GetDetailText())
+	}
+
+	// 面可以是字符串，也可以来自变量
+	err = vm.Run("颜色 = ['红', '蓝']; [d[1,1,3,5], d(颜色), 2d(颜色), d[1..3]]")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, na(ni(5), ns("蓝"), na(ns("蓝"), ns("蓝")), ni(3))))
+	}
+
+	vm.Config.DiceMaxMode = false
+	vm.Config.DiceMinMode = true
+	err = vm.Run("2d['a', 'b']")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, na(ns("a"), ns("a"))))
+		assert.Equal(t, "['a', 'a'][2d['a', 'b']=a,a]", vm.GetDetailText())
+	}
+
+	// 各面为复制出来的值，重复骰出同一面时不视为循环引用，修改结果也不影响原来的数组
+	err = vm.Run("面 = [[1], [2]]; r = 2d(面); r[0][0] = 9; [r, 面]")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "[[[9], [1]], [[1], [2]]]", vm.Ret.ToString())
+	}
+
+	// 计算过程使用 Stringer
+	vm.Stringer = ValueStringerFunc(func(v *VMValue) (string, bool) {
+		if s, ok := v.ReadString(); ok {
+			return "<" + s + ">", true
+		}
+		return "", false
+	})
+	err = vm.Run("2d['a', 'b']")
+	if assert.NoError(t, err) {
+		assert.Contains(t, vm.GetDetailText(), "=<a>,<a>]")
+	}
+	vm.Stringer = nil
+
+	err = vm.Run("2d[1,2]k1")
+	assert.Error(t, err)
+	err = vm.Run("0d[1,2]")
+	assert.Error(t, err)
+	err = vm.Run("a = []; d(a)")
+	assert.Error(t, err)
+}
+
 func TestDiceNoSpaceForModifier(t *testing.T) {
 	vm := NewVM()
 	err := vm.Run("3d1 k2")
@@ -467,6 +607,43 @@ func TestValueStore(t *testing.T) {
 	assert.True(t, vmValueEqual(vm, "d", ni(2)))
 }
 
+func TestCompoundAssign(t *testing.T) {
+	simpleExecute(t, "x = 1; x += 2", ni(3))
+	simpleExecute(t, "x = 10; x -= 2 * 3; x", ni(4))
+	simpleExecute(t, "x = 3; x *= 2 + 1", ni(9)) // 右侧整体参与运算
+	simpleExecute(t, "x = 7; x /= 2.0", nf(3.5))
+	simpleExecute(t, "x = 7; x %= 4", ni(3))
+	simpleExecute(t, "s = 'a'; s += 'b'", ns("ab"))
+	simpleExecute(t, "x = 1; y = 2; y += x += 1; [x, y]", na(ni(2), ni(4)))
+
+	vm := NewVM()
+	err := vm.Run("hp = 10; hp -= 1d6")
+	if assert.NoError(t, err) {
+		hp, _ := vm.Ret.ReadInt()
+		assert.True(t, hp >= 4 && hp <= 9)
+		assert.True(t, vmValueEqual(vm, "hp", vm.Ret))
+	}
+
+	// 与普通的读取和赋值相同，经过变量读写的回调
+	vm = NewVM()
+	stored := map[string]*VMValue{"金币": ni(5)}
+	vm.Config.HookValueLoadPre = func(ctx *Context, name string) (string, *VMValue) {
+		return name, stored[name]
+	}
+	vm.Config.HookValueStore = func(ctx *Context, name string, v *VMValue) (*VMValue, bool) {
+		stored[name] = v
+		return nil, true
+	}
+	err = vm.Run("金币 += 3")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(stored["金币"], ni(8)))
+	}
+
+	vm = NewVM()
+	err = vm.Run("未定义 += 1")
+	assert.Error(t, err)
+}
+
 func TestIfBasic(t *testing.T) {
 	vm := NewVM()
 	err := vm.Run("if 1 { a = 2 } ")
@@ -633,16 +810,16 @@ func TestCompareExpr(t *testing.T) {
 		expr  string
 		value *VMValue
 	}{
-		{"1>0", ni(1)},
-		{"1>=0", ni(1)},
-		{"1==0", ni(0)},
-		{"1==1", ni(1)},
-		{"1<0", ni(0)},
-		{"1<=0", ni(0)},
-		{"1!=0", ni(1)},
+		{"1>0", nb(true)},
+		{"1>=0", nb(true)},
+		{"1==0", nb(false)},
+		{"1==1", nb(true)},
+		{"1<0", nb(false)},
+		{"1<=0", nb(false)},
+		{"1!=0", nb(true)},
 
 		// 带空格
-		{"1 > 0", ni(1)},
+		{"1 > 0", nb(true)},
 
 		// 中断
 		{"5＝+2", ni(5)},
@@ -656,6 +833,28 @@ func TestCompareExpr(t *testing.T) {
 	}
 }
 
+func TestInOperator(t *testing.T) {
+	vm := NewVM()
+	err := vm.Run("[1 in [1, 2], 3 in [1, 2], 2 in (1, 2), 1.0 in [1], 'a' in 'abc', 'd' in 'abc', 'a' in {'a': 1}, 1 in {'a': 1}, 1 in {1, 2}]")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "[true, false, true, true, true, false, true, false, true]", vm.Ret.ToString())
+	}
+
+	// 与比较运算的优先级相同
+	simpleExecute(t, "1 + 1 in [2]", nb(true))
+	simpleExecute(t, "x = [1]; 1 in x && 2 in x", nb(false))
+	simpleExecute(t, "!(1 in [2])", nb(true))
+	simpleExecute(t, "n = 0; for x in ['a', 'b', 'c'] { if x in 'ab' { n = n + 1 } }; n", ni(2))
+
+	// 不是关键字，仍可作为变量名
+	simpleExecute(t, "in = 3; in", ni(3))
+
+	for _, expr := range []string{"1 in 3", "1 in 'abc'", "1 in null"} {
+		vm = NewVM()
+		assert.Error(t, vm.Run(expr), expr)
+	}
+}
+
 func TestTernary(t *testing.T) {
 	vm := NewVM()
 	err := vm.Run("1 == 1 ? 2")
@@ -799,6 +998,18 @@ func TestArray(t *testing.T) {
 		assert.True(t, valueEqual(vm.Ret, nf(1)))
 	}
 
+	vm = NewVM()
+	err = vm.Run("[[4,1,3,2]kh3, [4,1,3,2]kl2, [4,1,3,2]dh1, [4,1,3,2]dl, [4,1,3,2].dl(2), [1,2].dh(5), [1.5,3,2]dl2]")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, na(ni(9), ni(3), ni(6), ni(9), ni(7), ni(0), nf(3))))
+	}
+	for _, expr := range []string{"[1,2].dh('a')", "[1,2].dl(1.5)", "[1,2].kh('a')", "[1,2].kl(null)"} {
+		err = vm.Run(expr)
+		if assert.Error(t, err, expr) {
+			assert.Contains(t, err.Error(), "个数必须为int")
+		}
+	}
+
 	vm = NewVM()
 	err = vm.Run("[4.1,3.1,1]kl")
 	if assert.NoError(t, err) {
@@ -1018,13 +1229,17 @@ func TestBytecodeToString(t *testing.T) {
 		{typeCompGE, nil},
 		{typeCompGT, nil},
 
-		{typeLogicAnd, nil},
 		{typeLogicOr, nil},
 
 		{typeNop, nil},
 
 		{typeBitwiseAnd, nil},
 		{typeBitwiseOr, nil},
+		{typeBitwiseXor, nil},
+		{typeShiftLeft, nil},
+		{typeShiftRight, nil},
+		{typeIn, nil},
+		{typeRange, nil},
 
 		{typeDiceInit, nil},
 		{typeDiceSetTimes, nil},
@@ -1034,10 +1249,16 @@ func TestBytecodeToString(t *testing.T) {
 		{typeDiceSetDropHighNum, nil},
 		{typeDiceSetMin, nil},
 		{typeDiceSetMax, nil},
+		{typeDiceSetExplode, nil},
+		{typeDiceFate, nil},
+		{typeDiceFateN, nil},
 
 		{typeJmp, IntType(0)},
 		{typeJe, IntType(0)},
 		{typeJne, IntType(0)},
+		{typeJmpTable, []IntType{1, 2, 0, 1}},
+		{typeMatchValue, nil},
+		{typeMatchRange, nil},
 	}
 
 	for _, i := range ops {
@@ -1494,6 +1715,48 @@ func TestBitwisePrecedence(t *testing.T) {
 	}
 }
 
+func TestBitwiseShiftXor(t *testing.T) {
+	simpleExecute(t, "1 << 4", ni(16))
+	simpleExecute(t, "256 >> 2", ni(64))
+	simpleExecute(t, "-8 >> 1", ni(-4))
+	simpleExecute(t, "1 << 70", ni(0))
+	simpleExecute(t, "1 << 1 + 1", ni(4)) // 位移的优先级低于加减
+	simpleExecute(t, "1 << 2 < 5", nb(true))
+
+	vm := NewVM()
+	err := vm.Run("1 << -1")
+	assert.ErrorContains(t, err, "位移的位数不能为负数")
+
+	vm = NewVM()
+	err = vm.Run("1.5 << 1")
+	assert.Error(t, err)
+
+	// 默认 ^ 是乘方
+	simpleExecute(t, "2 ^ 3", ni(8))
+
+	vm = NewVM()
+	vm.Config.CaretAsXor = true
+	err = vm.Run("6 ^ 3")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, ni(5)))
+	}
+	err = vm.Run("1 | 6 ^ 3 & 2") // & 优先于 ^ 优先于 |
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, ni(5)))
+	}
+	err = vm.Run("2 ** 3")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, ni(8)))
+	}
+
+	vm = NewVM()
+	vm.Config.DisableBitwiseOp = true
+	err = vm.Run("1 << 4")
+	if assert.NoError(t, err) {
+		assert.Equal(t, " << 4", vm.RestInput)
+	}
+}
+
 func TestLogicOp(t *testing.T) {
 	vm := NewVM()
 	err := vm.Run("a = [1,2]; 5 || a.push(3); a ")
@@ -1528,6 +1791,25 @@ func TestLogicAnd(t *testing.T) {
 	if assert.NoError(t, err) {
 		assert.True(t, valueEqual(vm.Ret, ni(0)))
 	}
+
+	// 短路求值，左侧为假时不计算右侧
+	vm = NewVM()
+	err = vm.Run("a = [1]; 0 && a.push(2); str = 70; dex = 30; [a, str > 60 && dex > 40, 1 && 2 || 3 && 0]")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, na(na(ni(1)), nb(false), ni(2))))
+	}
+}
+
+func TestLogicNot(t *testing.T) {
+	vm := NewVM()
+	err := vm.Run("[!true, !0, !!'a', !-1, !(1 > 2) && 3, !1 == false, 1 != 2]")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, na(nb(false), nb(true), nb(true), nb(false), ni(3), nb(true), nb(true))))
+	}
+
+	vm = NewVM()
+	err = vm.Run("!")
+	assert.Error(t, err)
 }
 
 func TestStackOverFlow(t *testing.T) {
@@ -2064,6 +2346,153 @@ func TestDetailText7(t *testing.T) {
 	}
 }
 
+func TestDetailNote(t *testing.T) {
+	vm := NewVM()
+	vm.Config.DiceMaxMode = true
+	err := vm.Run("x = d20; if x == 20 { note('触发背刺') }; x + 5")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, ni(25)))
+		assert.Equal(t, "x = 20[d20]; if 20[x] == 20 { [触发背刺] }; 20[x] + 5", vm.GetDetailText())
+		assert.Equal(t, "note", vm.DetailSpans[2].Tag)
+	}
+
+	// 未执行的注释不出现
+	vm = NewVM()
+	vm.Config.DiceMaxMode = true
+	err = vm.Run("x = d20; if x < 20 { note('触发背刺') }; x + 5")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "x = 20[d20]; if 20[x] < 20 { note('触发背刺') }; 20[x] + 5", vm.GetDetailText())
+	}
+
+	vm = NewVM()
+	vm.Config.DiceMaxMode = true
+	err = vm.Run("note('伤害' + toStr(d6)); d20")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "[伤害6]; 20[d20]", vm.GetDetailText())
+	}
+
+	// 同名变量不受影响
+	vm = NewVM()
+	err = vm.Run("note = 3; note")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, ni(3)))
+	}
+
+	// 同名函数按函数调用处理
+	vm = NewVM()
+	err = vm.Run("func note(x) { return x + 1 }; func quiet(x) { return x * 2 }; note(1) + quiet(2)")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, ni(6)))
+	}
+}
+
+func TestDetailQuiet(t *testing.T) {
+	vm := NewVM()
+	vm.Config.DiceMaxMode = true
+	err := vm.Run("d20 + quiet(d100 + d6)")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, ni(126)))
+		assert.Equal(t, "20[d20] + quiet(d100 + d6)", vm.GetDetailText())
+		// 隐藏的骰点仍然记录
+		assert.Len(t, vm.DetailSpans, 4)
+		assert.Equal(t, "quiet", vm.DetailSpans[1].Tag)
+		assert.True(t, valueEqual(vm.DetailSpans[1].Ret, ni(106)))
+	}
+
+	vm = NewVM()
+	vm.Config.DiceMaxMode = true
+	vm.Config.QuietDetail = true
+	r, err := vm.Evaluate("d20 + 1")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "", r.Detail)
+		assert.Len(t, r.Spans, 1)
+		assert.Equal(t, "20", r.Spans[0].Text)
+	}
+}
+
+func TestDetailDicePool(t *testing.T) {
+	vm := NewVM()
+	vm.Config.SortDiceDetail = true
+	r, err := vm.Evaluate("8d6")
+	if assert.NoError(t, err) {
+		pool := r.Spans[0].Pool
+		if assert.NotNil(t, pool) {
+			// 显示的是排序后的结果，原本的顺序可由 Order 对应
+			sorted := pool.Sorted()
+			var parts []string
+			for _, n := range sorted {
+				parts = append(parts, strconv.Itoa(int(n)))
+			}
+			assert.Equal(t, strings.Join(parts, "+"), r.Spans[0].Text)
+			assert.Len(t, pool.Rolls, 8)
+			assert.Equal(t, 8, pool.Kept)
+		}
+	}
+
+	vm = NewVM()
+	r, err = vm.Evaluate("4d6kh3 + 1")
+	if assert.NoError(t, err) {
+		pool := r.Spans[0].Pool
+		if assert.NotNil(t, pool) {
+			assert.Equal(t, 3, pool.Kept)
+			assert.Len(t, pool.Order, 4)
+		}
+	}
+}
+
+func TestDiceRollModeExpr(t *testing.T) {
+	vm := NewVM()
+	err := vm.Run("max(3d6) + min(2d4)")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, ni(20)))
+	}
+
+	vm = NewVM()
+	err = vm.Run("max(2d6) + 1d1")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, ni(13)))
+		assert.Equal(t, "", vm.RestInput)
+	}
+
+	// 内层优先
+	vm = NewVM()
+	err = vm.Run("max(min(d6) + d6)")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, ni(7)))
+	}
+
+	// 优先于全局设置
+	vm = NewVM()
+	vm.Config.DiceMaxMode = true
+	err = vm.Run("min(2d6) + d6")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, ni(8)))
+	}
+
+	// 同名变量不受影响
+	vm = NewVM()
+	err = vm.Run("maxHp = 10; min = 2; maxHp + min")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, ni(12)))
+	}
+
+	// 存在同名的函数时为函数调用
+	vm = NewVM()
+	err = vm.Run("func max(a) { return a * 2 }; func min(a, b) { return a - b }; [max(4), min(5, 3), max(d1)]")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, na(ni(8), ni(2), ni(2))))
+	}
+	vm = NewVM(WithBuiltins(map[string]*VMValue{
+		"max": NewNativeFunctionVal(&NativeFunctionData{Name: "max", Params: []string{"a"}, NativeFunc: func(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
+			return NewStrVal("builtin")
+		}}),
+	}))
+	err = vm.Run("max(3d6)")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, ns("builtin")))
+	}
+}
+
 func TestDiceAdvantage(t *testing.T) {
 	vm := NewVM()
 	vm.Config.DefaultDiceSideExpr = "1"
@@ -2105,3 +2534,1013 @@ func TestGetDetailBug2(t *testing.T) {
 	assert.Equal(t, vm.RestInput, "(1+1+23=3")
 	assert.Equal(t, "", vm.GetDetailText())
 }
+
+func TestUndefinedPolicyUnified(t *testing.T) {
+	// 默认策略下缺失值即为null
+	simpleExecute(t, "a ?? 2", ni(2))
+	simpleExecute(t, "a == null", nb(true))
+	simpleExecute(t, "m = {}; m.x == null", nb(true))
+
+	vm := NewVM()
+	vm.Attrs.Store("u", NewUndefinedVal())
+	err := vm.Run("[u == null, u ?? 3, u ? 1 : 0]")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, na(nb(true), ni(3), ni(0))))
+	}
+}
+
+func TestUndefinedPolicyStrict(t *testing.T) {
+	vm := NewVM()
+	vm.Config.StrictUndefined = true
+	err := vm.Run("a")
+	if assert.NoError(t, err) {
+		assert.Equal(t, VMTypeUndefined, vm.Ret.TypeId)
+		assert.Equal(t, "undefined", vm.Ret.ToString())
+	}
+
+	vm = NewVM()
+	vm.Config.StrictUndefined = true
+	err = vm.Run("m = {'n': null}; [m.x == null, m.x == a, m.n == null, m.n ?? 1, m.x ?? 2, m['y'] ?? 3, m.x ? 1 : 0]")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, na(nb(false), nb(true), nb(true), NewNullVal(), ni(2), ni(3), ni(0))))
+	}
+}
+
+func TestStmtDelete(t *testing.T) {
+	vm := NewVM()
+	err := vm.Run("a = 1; del a; exists('a')")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, nb(false)))
+		_, ok := vm.Attrs.Load("a")
+		assert.False(t, ok)
+	}
+
+	deleted := ""
+	vm = NewVM()
+	vm.GlobalValueDeleteFunc = func(name string) {
+		deleted = name
+	}
+	err = vm.Run("del $t临时")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "$t临时", deleted)
+		assert.True(t, valueEqual(vm.Ret, NewNullVal()))
+	}
+
+	vm = NewVM()
+	vm.Config.HookValueDelete = func(ctx *Context, name string) bool {
+		deleted = "hook:" + name
+		return true
+	}
+	vm.Attrs.Store("b", ni(2))
+	err = vm.Run("del b")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "hook:b", deleted)
+		_, ok := vm.Attrs.Load("b")
+		assert.True(t, ok)
+	}
+
+	// 不带空格时视为普通变量
+	simpleExecute(t, "delta = 2; delta", ni(2))
+
+	vm = NewVM()
+	vm.Config.DisableStmts = true
+	err = vm.Run("del a")
+	assert.NoError(t, err)
+	assert.Equal(t, " a", vm.RestInput)
+}
+
+func TestStmtConst(t *testing.T) {
+	vm := NewVM()
+	err := vm.Run("const PI = 3.14159; PI * 2")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, nf(6.28318)))
+	}
+
+	err = vm.Run("PI = 3")
+	var roErr *ReadOnlyError
+	if assert.ErrorAs(t, err, &roErr) {
+		assert.Equal(t, "PI", roErr.Name)
+	}
+
+	// 函数内同样不能修改
+	vm = NewVM()
+	err = vm.Run("const a = 1; func f() { a = 2 }; f()")
+	assert.ErrorAs(t, err, &roErr)
+
+	vm = NewVM()
+	err = vm.Run("const a = 1; del a")
+	assert.ErrorAs(t, err, &roErr)
+
+	vm = NewVM()
+	err = vm.Run("const a = 1; const a = 2")
+	assert.ErrorAs(t, err, &roErr)
+
+	vm = NewVM()
+	err = vm.Run("const = 1; const")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, ni(1)))
+	}
+}
+
+func TestReadOnlyNames(t *testing.T) {
+	vm := NewVM()
+	vm.Attrs.Store("回复模板", ns("{$t玩家}的检定"))
+	vm.RegReadOnlyNames("回复模板")
+	assert.True(t, vm.IsReadOnlyName("回复模板"))
+
+	err := vm.Run("回复模板 = ''")
+	var roErr *ReadOnlyError
+	assert.ErrorAs(t, err, &roErr)
+
+	err = vm.Run("store('回复模板', '')")
+	assert.ErrorAs(t, err, &roErr)
+
+	// 直接调用 StoreNameLocal、StoreNameGlobal 同样检查
+	vm.Error = nil
+	vm.StoreNameLocal("回复模板", ns(""))
+	assert.ErrorAs(t, vm.Error, &roErr)
+	vm.Error = nil
+	vm.GlobalValueStoreFunc = func(name string, v *VMValue) {}
+	vm.StoreNameGlobal("回复模板", ns(""))
+	assert.ErrorAs(t, vm.Error, &roErr)
+
+	err = vm.Run("回复模板")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, ns("{$t玩家}的检定")))
+	}
+}
+
+func TestAttrSetPath(t *testing.T) {
+	vm := NewVM()
+	err := vm.Run("char = {'skills': {}}; char.skills.剑术 = 60; char.skills.剑术")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, ni(60)))
+	}
+
+	// 未开启时中间路径不存在会报错
+	vm = NewVM()
+	err = vm.Run("char = {}; char.skills.剑术 = 60")
+	assert.Error(t, err)
+
+	// 读取不存在的路径与是否开启无关
+	err = vm.Run("char = {}; [char.skills.剑术, char.skills.魔法.火球 ?? 0]")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, na(NewNullVal(), ni(0))))
+	}
+	vm.Config.StrictUndefined = true
+	err = vm.Run("char = {}; char.a.b")
+	if assert.NoError(t, err) {
+		assert.Equal(t, VMTypeUndefined, vm.Ret.TypeId)
+	}
+
+	vm = NewVM()
+	vm.Config.AttrPathAutoCreate = true
+	err = vm.Run("char = {}; char.skills.战斗.剑术 = 60; char")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "{'skills': {'战斗': {'剑术': 60}}}", vm.Ret.ToString())
+	}
+
+	err = vm.Run("[char.skills.战斗.剑术, char.skills.魔法.火球 ?? 0, char.属性.力量]")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, na(ni(60), ni(0), NewNullVal())))
+	}
+
+	vm = NewVM()
+	vm.Config.AttrPathAutoCreate = true
+	vm.Config.StrictUndefined = true
+	err = vm.Run("char = {}; char.a.b")
+	if assert.NoError(t, err) {
+		assert.Equal(t, VMTypeUndefined, vm.Ret.TypeId)
+	}
+
+	vm = NewVM()
+	vm.Config.AttrPathAutoCreate = true
+	err = vm.Run("char = 1; char.a.b = 1")
+	assert.Error(t, err)
+}
+
+func TestComputedWithParams(t *testing.T) {
+	vm := NewVM()
+	err := vm.Run("&val(x) = x * 5; val(3)")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, ni(15)))
+	}
+
+	// 参数不会残留在computed的变量空间中
+	err = vm.Run("&伤害(n, bonus) = n * 2 + bonus + this.base; &伤害.base = 1; [伤害(3, 1), 伤害(1, 0)]")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, na(ni(8), ni(3))))
+		cd, _ := vm.Attrs.MustLoad("伤害").ReadComputed()
+		_, exists := cd.Attrs.Load("n")
+		assert.False(t, exists)
+	}
+
+	// 未调用时不会求值
+	err = vm.Run("val")
+	if assert.NoError(t, err) {
+		assert.Equal(t, VMTypeComputedValue, vm.Ret.TypeId)
+	}
+
+	err = vm.Run("val(1, 2)")
+	assert.Error(t, err)
+
+	// 参数只在本次调用中有效，递归调用时互不影响
+	err = vm.Run("&fact(n) = n <= 1 ? 1 : n * fact(n - 1); &fact.k = 1; [fact(5), fact.k]")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, na(ni(120), ni(1))))
+	}
+
+	// 参数是值的一部分
+	err = vm.Run("&a(x) = x * 5; &b(y) = x * 5; [val, a == val, a == b]")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "[&(x) = x * 5, true, false]", vm.Ret.ToRepr())
+	}
+
+	// 无参数的computed同样可以调用
+	vm = NewVM()
+	ret := NewComputedVal("1 + 2").ComputedInvoke(vm, nil, nil)
+	assert.True(t, valueEqual(ret, ni(3)))
+}
+
+func TestComputedLiteral(t *testing.T) {
+	vm := NewVM()
+	err := vm.Run("a = &(1 + 2); [a, typeId(&(3))]")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, na(ni(3), ni(IntType(VMTypeComputedValue)))))
+	}
+	simpleExecute(t, "3 &(1)", ni(1))
+
+	// 第二次执行时使用缓存的字节码，默认骰子面数需要读取原文
+	vm = NewVM()
+	vm.Config.DefaultDiceSideExpr = "1"
+	err = vm.Run("&a = d; func f() { return d }; [a, a, f(), f()]")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, na(ni(1), ni(1), ni(1), ni(1))))
+	}
+}
+
+func TestComputedMemo(t *testing.T) {
+	vm := NewVM()
+	err := vm.Run("base = 10; 最大hp = memo(&(base + d100)); [最大hp, 最大hp, 最大hp]")
+	if assert.NoError(t, err) {
+		arr := vm.Ret.MustReadArray()
+		assert.True(t, valueEqual(arr.List[0], arr.List[1]))
+		assert.True(t, valueEqual(arr.List[0], arr.List[2]))
+	}
+
+	// 依赖变化后重新计算
+	vm = NewVM()
+	err = vm.Run("base = 10; a = memo(&(base + 1)); x = a; base = 20; [x, a]")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, na(ni(11), ni(21))))
+	}
+
+	// 其中调用的函数、computed读取的变量同样是依赖
+	vm = NewVM()
+	err = vm.Run("base = 10; func f() { return base * 2 }; &inner = base + 1; a = memo(&(f() + inner)); x = a; base = 20; [x, a]")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, na(ni(31), ni(61))))
+	}
+	err = vm.Run("base = 1; b = memo(&(base + 1)); c = memo(&(b * 10)); x = c; base = 2; [x, c]")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, na(ni(20), ni(30))))
+	}
+
+	// invalidate
+	vm = NewVM()
+	vm.Config.DefaultDiceSideExpr = "1000000"
+	err = vm.Run("a = memo(&(d)); x = a; invalidate(&a); y = a; invalidate(); z = a; [x == a, x == y, y == z]")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, na(nb(false), nb(false), nb(false))))
+	}
+
+	err = vm.Run("memo(1)")
+	assert.Error(t, err)
+	err = vm.Run("invalidate(1)")
+	assert.Error(t, err)
+}
+
+func TestEvalAll(t *testing.T) {
+	vm := NewVM()
+	err := vm.Run("base = 10; &a = base + 1; &b = base * 2; eval_all([&a, &b, &(base - 1), 'x'])")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, na(ni(11), ni(20), ni(9), ns("x"))))
+	}
+
+	err = vm.Run("&f(x) = x; eval_all([&f])")
+	assert.Error(t, err)
+	err = vm.Run("eval_all(1)")
+	assert.Error(t, err)
+}
+
+func TestCompilePolicy(t *testing.T) {
+	var info *ProgramInfo
+	vm := NewVM()
+	vm.Config.CompilePolicy = func(ctx *Context, i *ProgramInfo) error {
+		info = i
+		return nil
+	}
+	err := vm.Run("a = 3d20k1 + d1000 + d(10); while 0 {}; ceil(b ?? 1)")
+	if assert.NoError(t, err) {
+		assert.Equal(t, []string{"a"}, info.Stores)
+		assert.Equal(t, []string{"ceil", "b"}, info.Names)
+		assert.Equal(t, []IntType{20, 1000}, info.DiceSides)
+		assert.True(t, info.HasAssign)
+		assert.True(t, info.HasLoop)
+		assert.False(t, info.HasFunc)
+	}
+
+	// 按频道限制: 禁止赋值、禁止loadRaw、禁止大于d1000的骰子
+	policy := func(ctx *Context, i *ProgramInfo) error {
+		if i.HasAssign {
+			return errors.New("本频道禁止赋值")
+		}
+		for _, name := range i.Names {
+			if name == "loadRaw" {
+				return errors.New("本频道禁止调用 loadRaw")
+			}
+		}
+		for _, sides := range i.DiceSides {
+			if sides > 1000 {
+				return errors.New("骰子面数过大")
+			}
+		}
+		return nil
+	}
+	for _, expr := range []string{"a = 1", "x = {}; x.y = 1", "loadRaw('a')", "d1001", "2d10000"} {
+		vm = NewVM()
+		vm.Config.CompilePolicy = policy
+		err = vm.Run(expr)
+		assert.Error(t, err, expr)
+	}
+
+	vm = NewVM()
+	vm.Config.CompilePolicy = policy
+	err = vm.Run("d1000 + load('a') ?? 1")
+	assert.NoError(t, err)
+}
+
+func TestCostEstimate(t *testing.T) {
+	vm := NewVM()
+	assert.Nil(t, vm.ProgramInfo())
+
+	assert.NoError(t, vm.Parse("3d6k2 + (2d6)d6 + 1"))
+	est := vm.ProgramInfo().CostEstimate()
+	assert.True(t, est.Bounded)
+	assert.Equal(t, IntType(3+2+12), est.Dice)
+	assert.Equal(t, IntType(vm.codeIndex)+est.Dice, est.Ops)
+
+	// 上限不小于实际消耗
+	vm.Config.DiceMaxMode = true
+	assert.NoError(t, vm.RunAfterParsed())
+	assert.LessOrEqual(t, vm.NumOpCount, est.Ops)
+
+	for _, expr := range []string{"while 1 {}", "for i in x { d6 }", "for i in 1..10 { while 1 {} }", "[...x, 1]", "func f() { 1 }; f()", "(d)d6", "10000d6 + (x)d6",
+		"pool(x, 6)", "eval_all(x)", "f(1)", "'aaaa' * x", "x * [1, 2]", "[1, 2].map(g)"} {
+		assert.NoError(t, vm.Parse(expr), expr)
+		assert.False(t, vm.ProgramInfo().CostEstimate().Bounded, expr)
+	}
+
+	// 循环次数、内置函数的参数和重复次数为字面量时可以估计
+	for _, item := range []struct {
+		expr string
+		dice IntType
+	}{
+		{"for i in 1..10 { d6 }", 10},
+		{"for i in range(3) { for j in [1, 2] { 2d6; continue } }", 12},
+		{"pool(3, 6).len() + abs(-1)", 3},
+	} {
+		vm = NewVM()
+		vm.Config.DiceMaxMode = true
+		assert.NoError(t, vm.Parse(item.expr), item.expr)
+		est := vm.ProgramInfo().CostEstimate()
+		assert.True(t, est.Bounded, item.expr)
+		assert.Equal(t, item.dice, est.Dice, item.expr)
+		assert.NoError(t, vm.RunAfterParsed(), item.expr)
+		assert.LessOrEqual(t, vm.NumOpCount, est.Ops, item.expr)
+	}
+	for _, expr := range []string{"pool(100000000, 6)", "'aaaa' * 100000000", "[1] * 100000000"} {
+		assert.NoError(t, vm.Parse(expr), expr)
+		assert.GreaterOrEqual(t, vm.ProgramInfo().CostEstimate().Ops, IntType(100000000), expr)
+	}
+
+	// 保存宏时拒绝代价过大的语句
+	vm = NewVM()
+	vm.Config.CompilePolicy = func(ctx *Context, i *ProgramInfo) error {
+		if est := i.CostEstimate(); !est.Bounded || est.Dice > 1000 {
+			return errors.New("语句过于复杂")
+		}
+		return nil
+	}
+	assert.Error(t, vm.Parse("(100d100)d6"))
+	assert.NoError(t, vm.Parse("100d100"))
+}
+
+func TestRunWith(t *testing.T) {
+	vm := NewVM()
+	err := vm.RunWith("f", RunOptions{Flags: &RollConfig{EnableDiceFate: true}})
+	if assert.NoError(t, err) {
+		assert.Equal(t, VMTypeInt, vm.Ret.TypeId)
+	}
+	assert.False(t, vm.Config.EnableDiceFate)
+
+	// 只替换开关，钩子仍然生效
+	stored := 0
+	vm.Config.HookValueStore = func(ctx *Context, name string, v *VMValue) (*VMValue, bool) {
+		stored++
+		return nil, false
+	}
+	err = vm.RunWith("b = f", RunOptions{Flags: &RollConfig{EnableDiceFate: true}})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, stored)
+	assert.NotNil(t, vm.Config.HookValueStore)
+	vm.Config.HookValueStore = nil
+
+	err = vm.RunWith("a = 0; while a < 100 { a = a + 1 }", RunOptions{Limits: &RunLimits{OpCountLimit: 50}})
+	assert.Error(t, err)
+	assert.Equal(t, IntType(0), vm.Config.OpCountLimit)
+	err = vm.Run("a = 0; while a < 100 { a = a + 1 }")
+	assert.NoError(t, err)
+
+	// 相同的种子得到相同的结果
+	seed, _ := (&rand.PCGSource{}).MarshalBinary()
+	err = vm.RunWith("20d100", RunOptions{Seed: seed})
+	assert.NoError(t, err)
+	ret1 := vm.Ret.ToString()
+	err = vm.RunWith("20d100", RunOptions{Seed: seed})
+	assert.NoError(t, err)
+	assert.Equal(t, ret1, vm.Ret.ToString())
+	assert.Nil(t, vm.RandSrc)
+
+	err = vm.RunWith("1", RunOptions{Seed: []byte{1}})
+	assert.Error(t, err)
+}
+
+func TestTimeAndDuration(t *testing.T) {
+	vm := NewVM()
+	err := vm.Run("[3h, 1h30m, 90s, 500ms, 2m + 30s, 1h / 2, 1h * 2, 1h / 30m, -1h]")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, na(
+			NewDurationVal(3*time.Hour),
+			NewDurationVal(90*time.Minute),
+			NewDurationVal(90*time.Second),
+			NewDurationVal(500*time.Millisecond),
+			NewDurationVal(150*time.Second),
+			NewDurationVal(30*time.Minute),
+			NewDurationVal(2*time.Hour),
+			nf(2),
+			NewDurationVal(-time.Hour),
+		)))
+	}
+
+	vm = NewVM()
+	err = vm.Run("过期 = now() + 3h; [过期 > now(), 过期 - 1h < now() + 1h, toInt(过期 - now() + 1s) / 60]")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, na(nb(true), nb(false), ni(180))))
+	}
+
+	vm = NewVM()
+	err = vm.Run("a = toTime('2024-01-01 12:00:00'); [toStr(a + 90m), a + 1h == toTime('2024-01-01 13:00:00'), toStr(toTime('2024-01-02') - a), toDuration(60), toDuration('2h') > 100m]")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, na(ns("2024-01-01 13:30:00"), nb(true), ns("12h0m0s"), NewDurationVal(time.Minute), nb(true))))
+	}
+
+	// 不影响其他语法
+	vm = NewVM()
+	err = vm.Run("3min")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, ni(3)))
+		assert.Equal(t, "min", vm.RestInput)
+	}
+
+	vm = NewVM()
+	err = vm.Run("now() + 1")
+	assert.Error(t, err)
+
+	// repr可以读回
+	for _, d := range []time.Duration{90 * time.Minute, 3 * time.Minute, 1500 * time.Millisecond, time.Microsecond, -time.Hour} {
+		vm = NewVM()
+		err = vm.Run(NewDurationVal(d).ToRepr())
+		if assert.NoError(t, err) {
+			assert.True(t, valueEqual(vm.Ret, NewDurationVal(d)), d.String())
+		}
+	}
+
+	// 注册的单位优先于时长
+	vm = NewVM()
+	vm.Config.Units = NewUnitTable().Register("m", "长度", 1)
+	err = vm.Run("[3m, 1h30m, toDuration('3m')]")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, na(NewQuantityVal(3, "m"), NewDurationVal(90*time.Minute), NewDurationVal(3*time.Minute))))
+	}
+}
+
+func TestForIn(t *testing.T) {
+	vm := NewVM()
+	err := vm.Run("s = 0; for x in [1, 2, 3] { s = s + x }; s")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, ni(6)))
+	}
+
+	vm = NewVM()
+	err = vm.Run("s = ''; for ch in '骰子' { s = ch + s }; s")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, ns("子骰")))
+	}
+
+	vm = NewVM()
+	err = vm.Run("s = ''; for k in {'a': 1, 'b': 2} { s = s + k }; s")
+	if assert.NoError(t, err) {
+		// 字典的遍历顺序不固定
+		assert.Contains(t, []string{"ab", "ba"}, vm.Ret.ToString())
+	}
+
+	// break/continue 位于if中
+	vm = NewVM()
+	err = vm.Run("s = 0; for x in range(100) { if x % 2 == 0 { continue }; if x > 50 { break }; s = s + x }; s")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, ni(625)))
+	}
+
+	vm = NewVM()
+	err = vm.Run("i = 0; while i < 30 { i = i + 1; if i < 25 { continue } }; i")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, ni(30)))
+	}
+
+	// 惰性序列，不会一次生成数组
+	vm = NewVM()
+	err = vm.Run("n = 0; for x in range(1, 1000000) { n = x; if x >= 5000 { break } }; n")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, ni(5000)))
+	}
+
+	vm = NewVM()
+	err = vm.Run("for x in 1 {}")
+	assert.Error(t, err)
+
+	vm = NewVM()
+	err = vm.Run("func f(lst) { s = 0; for x in lst { s = s + x }; return s }; [f([1, 2]), exists('x')]")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, na(ni(3), nb(false))))
+	}
+}
+
+func TestIterators(t *testing.T) {
+	vm := NewVM()
+	err := vm.Run("[...range(3), ...range(5, 1, -2), ...'ab', ...[7]]")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, na(ni(0), ni(1), ni(2), ni(5), ni(3), ns("a"), ns("b"), ni(7))))
+	}
+
+	vm = NewVM()
+	err = vm.Run("func sq(x) { return x * x }; func odd(x) { return x % 2 }; list(map(filter(range(1, 8), odd), sq))")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, na(ni(1), ni(9), ni(25), ni(49))))
+	}
+
+	// 迭代器只能遍历一次
+	vm = NewVM()
+	err = vm.Run("it = range(3); [list(it), list(it)]")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, na(na(ni(0), ni(1), ni(2)), na())))
+	}
+
+	vm = NewVM()
+	vm.Config.OpCountLimit = 1000
+	err = vm.Run("[...range(1, 1000000)]")
+	assert.Error(t, err)
+
+	vm = NewVM()
+	err = vm.Run("range(1, 5, 0)")
+	assert.Error(t, err)
+}
+
+func TestGenerator(t *testing.T) {
+	vm := NewVM()
+	err := vm.Run("func deal(cards) { for c in cards { yield c } }; deck = deal(['A', 'K', 'Q']); [next(deck), next(deck), next(deck), next(deck, 'none')]")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, na(ns("A"), ns("K"), ns("Q"), ns("none"))))
+	}
+
+	// 生成器的状态保存在值中，赋值后仍是同一个
+	vm = NewVM()
+	err = vm.Run("func counter(n) { i = 0; while i < n { i = i + 1; yield i } }; a = counter(4); b = a; [next(a), next(b), ...a]")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, na(ni(1), ni(2), ni(3), ni(4))))
+	}
+
+	vm = NewVM()
+	err = vm.Run("func g() { yield 1; yield; return 5 }; x = 0; for v in g() { x = x + 1 }; x")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, ni(2)))
+	}
+
+	// 生成器函数在取值前不会执行
+	vm = NewVM()
+	err = vm.Run("func g() { $tFlag = 1; yield 1 }; $tFlag = 0; it = g(); $tFlag")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, ni(0)))
+	}
+
+	vm = NewVM()
+	err = vm.Run("func g() { yield 1; 1 + 'a' }; list(g())")
+	assert.Error(t, err)
+
+	vm = NewVM()
+	vm.Config.OpCountLimit = 1000
+	err = vm.Run("func g() { while 1 { yield 1 } }; list(g())")
+	assert.Error(t, err)
+
+	vm = NewVM()
+	err = vm.Run("yield 1")
+	assert.Error(t, err)
+
+	vm = NewVM()
+	err = vm.Run("next([1, 2])")
+	assert.Error(t, err)
+
+	// 没有取完的生成器在执行结束时关闭
+	before := runtime.NumGoroutine()
+	vm = NewVM()
+	err = vm.Run("func g() { while 1 { yield 1 } }; a = g(); [next(a), next(a)]")
+	assert.NoError(t, err)
+	assert.Nil(t, vm.generators)
+	for i := 0; i < 100 && runtime.NumGoroutine() > before; i++ {
+		time.Sleep(time.Millisecond)
+	}
+	assert.LessOrEqual(t, runtime.NumGoroutine(), before)
+	err = vm.Run("next(a, 'closed')")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, ns("closed")))
+	}
+}
+
+func TestPercentLiteral(t *testing.T) {
+	vm := NewVM()
+	vm.Config.EnablePercentLiteral = true
+	err := vm.Run("[35%, 12.5%, 100%+5%, 7%2, 7 % 2, 7%(4), 10%3 * 2]")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, na(nf(0.35), nf(0.125), nf(1.05), ni(1), ni(1), ni(3), ni(2))))
+	}
+
+	vm = NewVM()
+	vm.Config.EnablePercentLiteral = true
+	err = vm.Run("[100 * 50% + 3, 50% + 5%]")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, na(nf(53), nf(0.55))))
+	}
+
+	vm = NewVM()
+	vm.Config.EnablePercentLiteral = true
+	err = vm.Run("x = 4; 7%x")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, ni(3)))
+	}
+
+	vm = NewVM()
+	vm.Config.EnablePercentLiteral = true
+	vm.Config.PercentAsInt = true
+	err = vm.Run("[35%, 2.5%]")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, na(ni(35), nf(2.5))))
+	}
+
+	// 默认不开启，% 总是取余
+	vm = NewVM()
+	err = vm.Run("[10%+3, 10%-3, 10% +3, 100 * 50% + 3]")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, na(ni(1), ni(1), ni(1), ni(2))))
+	}
+	vm = NewVM()
+	err = vm.Run("35%")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, ni(35)))
+		assert.Equal(t, "%", vm.RestInput)
+	}
+}
+
+func TestCompatFlags(t *testing.T) {
+	vm := NewVM()
+	err := vm.Run("3(2)")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, ni(3)))
+		assert.Equal(t, "(2)", vm.RestInput)
+	}
+
+	vm = NewVM()
+	vm.Config.Compat.ImplicitMultiply = true
+	err = vm.Run("3(1d1+1) + (2)(3)")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, ni(12)))
+		assert.Equal(t, "", vm.RestInput)
+	}
+
+	// 中间有空白时不视为乘法
+	vm = NewVM()
+	vm.Config.Compat.ImplicitMultiply = true
+	err = vm.Run("3 (2)")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, ni(3)))
+		assert.Equal(t, " (2)", vm.RestInput)
+	}
+
+	// 函数调用不受影响
+	vm = NewVM()
+	vm.Config.Compat.ImplicitMultiply = true
+	err = vm.Run("abs(-2)")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, ni(2)))
+	}
+
+	vm = NewVM()
+	vm.Config.DefaultDiceSideExpr = "1"
+	err = vm.Run("max(2d)")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, ni(2)))
+	}
+
+	vm = NewVM()
+	vm.Config.DefaultDiceSideExpr = "1"
+	vm.Config.Compat.BareDiceD100 = true
+	err = vm.Run("max(2d)")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, ni(200)))
+	}
+}
+
+func TestDefaultDiceCount(t *testing.T) {
+	vm := NewVM()
+	vm.Config.DefaultDiceCount = 3
+	err := vm.Run("max(d6) + max(2d6)")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, ni(30)))
+	}
+
+	vm = NewVM()
+	vm.Config.DefaultDiceCount = 2
+	vm.Config.DiceMaxMode = true
+	err = vm.Run("d")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, ni(200)))
+		assert.Equal(t, "200[2D100=100+100]", vm.GetDetailText())
+	}
+
+	// 优势劣势不受影响
+	vm = NewVM()
+	vm.Config.DefaultDiceCount = 3
+	err = vm.Run("d1优势")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, ni(1)))
+	}
+
+	vm = NewVM()
+	vm.Config.DefaultDiceCount = 3
+	err = vm.Parse("d6 + 2d4")
+	if assert.NoError(t, err) {
+		assert.Equal(t, IntType(5), vm.ProgramInfo().CostEstimate().Dice)
+	}
+}
+
+func TestPercentDice(t *testing.T) {
+	vm := NewVM()
+	err := vm.Run("d%")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "%", vm.RestInput)
+	}
+
+	vm = NewVM()
+	vm.Config.EnablePercentDice = true
+	vm.Config.DiceMaxMode = true
+	err = vm.Run("d% + 2d%")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, ni(300)))
+		assert.Equal(t, []IntType{100, 100}, vm.ProgramInfo().DiceSides)
+	}
+
+	// 取余不受影响
+	vm = NewVM()
+	vm.Config.EnablePercentDice = true
+	vm.Config.DefaultDiceSideExpr = "7"
+	vm.Config.DiceMaxMode = true
+	err = vm.Run("d % 4")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, ni(3)))
+	}
+}
+
+func TestDiceKeywordCase(t *testing.T) {
+	vm := NewVM()
+	vm.Config.DiceMaxMode = true
+	err := vm.Run("3D6KH2 + 3d6DL1 + 2d20MAX10")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, ni(44)))
+		assert.Equal(t, "", vm.RestInput)
+	}
+}
+
+func TestDiceAliases(t *testing.T) {
+	rs := &RuleSet{DiceAliases: map[string]string{
+		"adv":  "优势",
+		"dis":  "劣势",
+		"keep": "kh",
+		"保留":   "kh",
+	}}
+
+	vm := NewVM()
+	vm.Config.RuleSet = rs
+	vm.Config.DiceMaxMode = true
+	err := vm.Run("d20ADV + 4d6keep3 + 2d6保留1")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, ni(44)))
+		assert.Equal(t, "", vm.RestInput)
+	}
+
+	vm = NewVM()
+	vm.Config.RuleSet = rs
+	vm.Config.DefaultDiceSideExpr = "20"
+	vm.Config.DiceMinMode = true
+	err = vm.Run("d dis")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, ni(1)))
+		assert.Equal(t, " dis", vm.RestInput)
+	}
+
+	vm = NewVM()
+	vm.Config.RuleSet = rs
+	vm.Config.DefaultDiceSideExpr = "20"
+	err = vm.Run("ddis")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "", vm.RestInput)
+		assert.Contains(t, vm.GetDetailText(), "2D20kl1")
+	}
+
+	// 未登记时不识别
+	vm = NewVM()
+	err = vm.Run("d20adv")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "adv", vm.RestInput)
+	}
+}
+
+func TestRuleSetKarma(t *testing.T) {
+	luck := IntType(30)
+	rs := &RuleSet{
+		// 花费幸运把d100降到50
+		Karma: func(ctx *Context, roll KarmaRoll) *KarmaSpend {
+			if roll.Sides != 100 || roll.Value <= 50 || roll.Value-50 > luck {
+				return nil
+			}
+			cost := roll.Value - 50
+			luck -= cost
+			return &KarmaSpend{Resource: "幸运", Amount: cost, To: 50}
+		},
+	}
+
+	for i := 0; i < 200; i++ {
+		before := luck
+		vm := NewVM()
+		vm.Config.RuleSet = rs
+		r, err := vm.Evaluate("d100 + d6")
+		if !assert.NoError(t, err) {
+			break
+		}
+		if len(r.KarmaSpends) == 0 {
+			assert.Equal(t, before, luck)
+			continue
+		}
+		s := r.KarmaSpends[0]
+		assert.Len(t, r.KarmaSpends, 1)
+		assert.Equal(t, s.From-50, s.Amount)
+		assert.Equal(t, before-s.Amount, luck)
+		assert.Contains(t, r.Detail, fmt.Sprintf("50[d100=%d，幸运-%d]", s.From, s.Amount))
+		v, _ := r.Value.ReadInt()
+		assert.True(t, v >= 51 && v <= 56)
+	}
+	assert.Less(t, luck, IntType(30))
+
+	// 以最大值结算时不调用
+	vm := NewVM()
+	vm.Config.RuleSet = rs
+	vm.Config.DiceMaxMode = true
+	assert.NoError(t, vm.Run("d100"))
+	assert.True(t, valueEqual(vm.Ret, ni(100)))
+	assert.Empty(t, vm.KarmaSpends)
+}
+
+func TestMatch(t *testing.T) {
+	vm := NewVM()
+	expr := "match x { 1..5 => '低', 6..10 => '中', _ => '高' }"
+	for _, c := range []struct {
+		x    *VMValue
+		want string
+	}{{ni(3), "低"}, {ni(6), "中"}, {ni(10), "中"}, {ni(0), "高"}, {ni(300), "高"}, {nf(5.5), "高"}, {nf(4.5), "低"}, {ns("a"), "高"}} {
+		vm.StoreName("x", c.x, false)
+		if assert.NoError(t, vm.Run(expr)) {
+			assert.True(t, valueEqual(vm.Ret, ns(c.want)), c.x.ToRepr())
+		}
+	}
+
+	// 分支都是整数常量且较密集时使用跳转表
+	hasTable := func(code []ByteCode) bool {
+		for _, c := range code {
+			if c.T == typeJmpTable {
+				return true
+			}
+		}
+		return false
+	}
+	assert.NoError(t, vm.Parse(expr))
+	assert.True(t, hasTable(vm.code[:vm.codeIndex]))
+	assert.NoError(t, vm.Parse("match x { 1 => 'a', 1000 => 'b' }"))
+	assert.False(t, hasTable(vm.code[:vm.codeIndex]))
+	assert.NoError(t, vm.Parse("match x { 1 => 'a', y => 'b', 2 => 'c' }"))
+	assert.False(t, hasTable(vm.code[:vm.codeIndex]))
+
+	err := vm.Run("match 'b' { 'a' => 1, 'b' => 2 }")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, ni(2)))
+	}
+
+	// 没有匹配的分支时为null，重叠时前面的分支优先
+	err = vm.Run("[match 9 { 1 => 'a', 2 => 'b' }, match 2 { 1..3 => 'a', 2 => 'b' }, match -2 { -3..-1 => 'neg', 0 => 'zero', 1 => 'one' }]")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, na(NewNullVal(), ns("a"), ns("neg"))))
+	}
+
+	// 嵌套、分支中的三元算符，以及match作为变量名
+	err = vm.Run("func f(n) { match n % 3 { 0 => match n { 3 => 'x', _ => 'y' }, 1 => n > 3 ? 'p' : 'q', _ => 'z', } }; [f(3), f(6), f(4), f(1), f(2)]")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, na(ns("x"), ns("y"), ns("p"), ns("q"), ns("z"))))
+	}
+	// 分支到达整数上限时跳转表仍能建立
+	err = vm.Run("[match 9223372036854775807 { 9223372036854775806 => 1, 9223372036854775807 => 2 }, match 1 { 9223372036854775806..9223372036854775807 => 1, _ => 3 }]")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, na(ni(2), ni(3))))
+	}
+	assert.NoError(t, vm.Parse("match x { 9223372036854775806 => 1, 9223372036854775807 => 2 }"))
+	assert.True(t, hasTable(vm.code[:vm.codeIndex]))
+
+	err = vm.Run("match = 3; match + 1")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, ni(4)))
+	}
+
+	// 跳转表在代入常量后仍然正确
+	prog, err := NewVM().Compile("match 力量 / 10 { 0..2 => '弱', 3..5 => '普通', _ => '强' }")
+	if assert.NoError(t, err) {
+		sp := prog.Specialize(map[string]*VMValue{"力量": ni(40)})
+		assert.True(t, hasTable(sp.code))
+		if assert.NoError(t, vm.RunProgram(sp)) {
+			assert.True(t, valueEqual(vm.Ret, ns("普通")))
+		}
+	}
+}
+
+func TestComputedMemoConcurrent(t *testing.T) {
+	vm := NewVM()
+	err := vm.Run("a = memo(&(base * 2)); 0")
+	if !assert.NoError(t, err) {
+		return
+	}
+	v := vm.Attrs.MustLoad("a")
+
+	// 各个vm的缓存互不影响
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			sub := NewVM()
+			sub.Attrs.Store("base", ni(IntType(i)))
+			for j := 0; j < 50; j++ {
+				ret := v.ComputedInvoke(sub, nil, nil)
+				assert.True(t, valueEqual(ret, ni(IntType(i*2))))
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestComputedWithParamsConcurrent(t *testing.T) {
+	vm := NewVM()
+	err := vm.Run("&val(x) = x * 5; val(0)")
+	if !assert.NoError(t, err) {
+		return
+	}
+	v := vm.Attrs.MustLoad("val")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			sub := NewVM()
+			for j := 0; j < 50; j++ {
+				ret := v.ComputedInvoke(sub, []*VMValue{ni(IntType(i))}, nil)
+				assert.True(t, valueEqual(ret, ni(IntType(i*5))))
+			}
+		}(i)
+	}
+	wg.Wait()
+}
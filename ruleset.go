@@ -0,0 +1,314 @@
+package dicescript
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// RuleSet 规则集，由宿主程序提供，用于定制检定等与具体游戏规则相关的行为，放入 RollConfig.RuleSet
+type RuleSet struct {
+	Name string
+
+	// 开启后 d100 <= 技能 这样的数值比较得到检定结果(check)而非0/1，
+	// 可以读取 .success .margin .level 等属性，在if、三目运算等处仍按是否成功当作真假
+	RichCheck bool
+	// 计算检定的成功等级，op为比较算符(<、<=、>=、>)，返回空字符串时使用默认的 成功/失败
+	CheckLevel func(ctx *Context, op string, value float64, target float64, success bool) string
+	// 成功等级从低到高排列，对抗检定时等级高者胜
+	LevelOrder []string
+	// 对抗检定的胜负判定，返回1为a胜，2为b胜，0为平局。为nil时使用默认规则:
+	// 检定结果先比较是否成功，再比较成功等级和差值；数字直接比较大小
+	Opposed func(ctx *Context, a *VMValue, b *VMValue) int
+
+	// 命中部位表，按骰点上限从小到大排列，供 hit_location() 使用
+	HitLocations []HitLocation
+	// 伤害类型修正，如 DamageMods["火焰"]["皮甲"] = 1.5，供 damage_type_mod() 使用，未设置的组合为1
+	DamageMods map[string]map[string]float64
+	// 规则集提供的内置变量/函数，优先于默认的内置函数，可用于覆盖 hit_location() 等
+	Builtins map[string]*VMValue
+
+	// 重骰时改写上一次的语句，如将 d20 改为 d20优势。为nil时将mod直接接在语句后面
+	RerollRewrite func(ctx *Context, expr string, mod string) string
+	// 重骰时不沿用上一次的骰点，全部重新投掷
+	RerollNewDice bool
+
+	// 骰子后缀的别名，如 DiceAliases["adv"] = "优势"、DiceAliases["keep"] = "kh"，匹配时不区分大小写。
+	// 可以作为别名目标的有 kh kl dh dl min max 优势 劣势
+	DiceAliases map[string]string
+
+	// 常规骰子(如 d100、3d6k2)投掷后、结果参与计算前调用，可以消耗宿主程序管理的资源修改骰点，如花费幸运降低d100的结果。
+	// 返回nil时不做修改，否则骰子的结果改为 To，并记录在 ctx.KarmaSpends 中。以最大/最小值结算时不调用
+	Karma func(ctx *Context, roll KarmaRoll) *KarmaSpend
+}
+
+// KarmaRoll 交给 RuleSet.Karma 的一次骰点
+type KarmaRoll struct {
+	Times IntType   // 骰子个数
+	Sides IntType   // 骰子面数
+	Rolls []IntType // 各骰子的骰点，按骰出的顺序
+	Value IntType   // 骰子的结果
+}
+
+// KarmaSpend 一次消耗资源修改骰点的记录
+type KarmaSpend struct {
+	Resource string  // 资源名，如 幸运
+	Amount   IntType // 消耗的数量
+	From     IntType // 修改前的结果
+	To       IntType // 修改后的结果
+}
+
+// HitLocation 命中部位，骰点不大于Max且大于上一项的Max时命中该部位
+type HitLocation struct {
+	Max  IntType
+	Name string
+}
+
+// RuleSetCoC COC7版规则，d100 <= 技能 时给出 大成功/极难成功/困难成功/成功/失败/大失败
+var RuleSetCoC = &RuleSet{
+	Name:       "coc7",
+	RichCheck:  true,
+	CheckLevel: cocCheckLevel,
+	LevelOrder: []string{"大失败", "失败", "成功", "困难成功", "极难成功", "大成功"},
+	Opposed:    cocOpposed,
+}
+
+func cocCheckLevel(ctx *Context, op string, value float64, target float64, success bool) string {
+	if op != "<=" {
+		return ""
+	}
+	switch {
+	case value == 1:
+		return "大成功"
+	case value == 100 || (value >= 96 && target < 50):
+		return "大失败"
+	case value <= target/5:
+		return "极难成功"
+	case value <= target/2:
+		return "困难成功"
+	case success:
+		return "成功"
+	}
+	return "失败"
+}
+
+// cocOpposed COC对抗检定，成功等级高者胜，等级相同时技能值高者胜，双方都失败时为平局
+func cocOpposed(ctx *Context, a *VMValue, b *VMValue) int {
+	c1, ok1 := a.ReadCheck()
+	c2, ok2 := b.ReadCheck()
+	if !ok1 || !ok2 {
+		ctx.Error = errors.New("类型错误: COC对抗检定的双方必须为检定结果，如 d100 <= 技能")
+		return 0
+	}
+	if !c1.Success && !c2.Success {
+		return 0
+	}
+	rs := ctx.Config.RuleSet
+	if ret := compareInt(rs.levelRank(c1.Level), rs.levelRank(c2.Level)); ret != 0 {
+		return ret
+	}
+	t1, _ := readNumber(c1.Target)
+	t2, _ := readNumber(c2.Target)
+	return compareFloat(t1, t2)
+}
+
+// defaultOpposed 检定结果先比较是否成功，再比较成功等级和差值；数字直接比较大小
+func (rs *RuleSet) defaultOpposed(ctx *Context, a *VMValue, b *VMValue) int {
+	c1, ok1 := a.ReadCheck()
+	c2, ok2 := b.ReadCheck()
+	if ok1 && ok2 {
+		if c1.Success != c2.Success {
+			if c1.Success {
+				return 1
+			}
+			return 2
+		}
+		if ret := compareInt(rs.levelRank(c1.Level), rs.levelRank(c2.Level)); ret != 0 {
+			return ret
+		}
+		m1, _ := readNumber(c1.Margin())
+		m2, _ := readNumber(c2.Margin())
+		return compareFloat(m1, m2)
+	}
+	if ok1 || ok2 {
+		ctx.Error = errors.New("类型错误: 对抗检定的双方必须同为检定结果或同为数字")
+		return 0
+	}
+	x, ok1 := readNumber(a)
+	y, ok2 := readNumber(b)
+	if !ok1 || !ok2 {
+		ctx.Error = errors.New("类型错误: 对抗检定的双方必须为数字或检定结果")
+		return 0
+	}
+	return compareFloat(x, y)
+}
+
+// levelRank 成功等级的排名，不在 LevelOrder 中时为-1
+func (rs *RuleSet) levelRank(level string) int {
+	if rs == nil {
+		return -1
+	}
+	for i, l := range rs.LevelOrder {
+		if l == level {
+			return i
+		}
+	}
+	return -1
+}
+
+func compareInt(a, b int) int {
+	switch {
+	case a > b:
+		return 1
+	case a < b:
+		return 2
+	}
+	return 0
+}
+
+func compareFloat(a, b float64) int {
+	switch {
+	case a > b:
+		return 1
+	case a < b:
+		return 2
+	}
+	return 0
+}
+
+// funcOpposed 对抗检定，如 opposed(d100 <= 力量, d100 <= 体型) 或 opposed(d20+5, d20+3)
+// 返回 {winner, tie, margin}，winner为1或2，平局时为0；margin为双方骰点(检定结果为差值)之差的绝对值
+func funcOpposed(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
+	a, b := params[0], params[1]
+	rs := ctx.Config.RuleSet
+	var winner int
+	if rs != nil && rs.Opposed != nil {
+		winner = rs.Opposed(ctx, a, b)
+	} else {
+		winner = rs.defaultOpposed(ctx, a, b)
+	}
+	if ctx.Error != nil {
+		ctx.Error = errors.New("(opposed)" + ctx.Error.Error())
+		return nil
+	}
+
+	var margin *VMValue
+	c1, ok1 := a.ReadCheck()
+	c2, ok2 := b.ReadCheck()
+	if ok1 && ok2 {
+		margin = c1.Margin().OpSub(ctx, c2.Margin())
+	} else {
+		margin = a.OpSub(ctx, b)
+	}
+	switch {
+	case margin == nil:
+		// 自定义规则中可能出现无法相减的值
+		margin = NewIntVal(0)
+	case margin.TypeId == VMTypeFloat:
+		margin = NewFloatVal(math.Abs(margin.MustReadFloat()))
+	case margin.TypeId == VMTypeInt && margin.MustReadInt() < 0:
+		margin = NewIntVal(-margin.MustReadInt())
+	}
+
+	return NewDictValWithArrayMust(
+		NewStrVal("winner"), NewIntVal(IntType(winner)),
+		NewStrVal("tie"), boolToVMValue(winner == 0),
+		NewStrVal("margin"), margin,
+	).V()
+}
+
+// funcHitLocation 按规则集的命中部位表决定命中部位，不给出骰点时骰1d表中最大值
+func funcHitLocation(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
+	rs := ctx.Config.RuleSet
+	if rs == nil || len(rs.HitLocations) == 0 {
+		ctx.Error = errors.New("(hit_location)规则错误: 当前规则集没有命中部位表")
+		return nil
+	}
+	var roll IntType
+	if params[0].IsNullish() {
+		sides := rs.HitLocations[len(rs.HitLocations)-1].Max
+		roll = Roll(ctx.RandSrc, sides, 0)
+		ctx.recordBuiltinRoll(fmt.Sprintf("d%d", sides), NewIntVal(roll), "dice")
+	} else {
+		var ok bool
+		if roll, ok = params[0].ReadInt(); !ok {
+			ctx.Error = errors.New("(hit_location)类型错误: 骰点必须为int")
+			return nil
+		}
+	}
+	for _, loc := range rs.HitLocations {
+		if roll <= loc.Max {
+			return NewStrVal(loc.Name)
+		}
+	}
+	ctx.Error = fmt.Errorf("(hit_location)值错误: %d 超出了命中部位表的范围", roll)
+	return nil
+}
+
+// funcDamageTypeMod 取得某类伤害对某种护甲的倍率
+func funcDamageTypeMod(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
+	damageType, ok1 := params[0].ReadString()
+	armor, ok2 := params[1].ReadString()
+	if !ok1 || !ok2 {
+		ctx.Error = errors.New("(damage_type_mod)类型错误: 伤害类型和护甲必须为str")
+		return nil
+	}
+	mod := 1.0
+	if rs := ctx.Config.RuleSet; rs != nil {
+		if m, ok := rs.DamageMods[damageType][armor]; ok {
+			mod = m
+		}
+	}
+	if mod == math.Trunc(mod) {
+		return NewIntVal(IntType(mod))
+	}
+	return NewFloatVal(mod)
+}
+
+// karma 由 RuleSet.Karma 修改骰子的结果，返回修改后的结果和计算过程
+func (ctx *Context) karma(times, sides IntType, pool *DicePool, num IntType, detail string) (IntType, string) {
+	rs := ctx.Config.RuleSet
+	if rs == nil || rs.Karma == nil {
+		return num, detail
+	}
+	spend := rs.Karma(ctx, KarmaRoll{Times: times, Sides: sides, Rolls: append([]IntType(nil), pool.Rolls...), Value: num})
+	if spend == nil {
+		return num, detail
+	}
+	spend.From = num
+	root := ctx.rootCtx()
+	root.KarmaSpends = append(root.KarmaSpends, *spend)
+	return spend.To, fmt.Sprintf("%s，%s-%d", detail, spend.Resource, spend.Amount)
+}
+
+func (c *RollConfig) richCheck() bool {
+	return c.RuleSet != nil && c.RuleSet.RichCheck
+}
+
+// DiceAliasAhead 接下来的输入是否为关键字kw的别名，有多个别名符合时取最长的
+func (d *ParserCustomData) DiceAliasAhead(p *parser, kw string) bool {
+	d.pendingAliasLen = 0
+	rs := d.Config.RuleSet
+	if rs == nil {
+		return false
+	}
+	rest := p.data[p.pt.offset:]
+	for alias, target := range rs.DiceAliases {
+		n := len(alias)
+		if target == kw && n > d.pendingAliasLen && n <= len(rest) && strings.EqualFold(string(rest[:n]), alias) {
+			d.pendingAliasLen = n
+		}
+	}
+	return d.pendingAliasLen > 0
+}
+
+// ConsumeDiceAlias 跳过 DiceAliasAhead 匹配到的别名
+func (d *ParserCustomData) ConsumeDiceAlias(p *parser) any {
+	target := p.pt.offset + d.pendingAliasLen
+	for p.pt.offset < target {
+		p.read()
+	}
+	d.pendingAliasLen = 0
+	return nil
+}
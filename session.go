@@ -0,0 +1,74 @@
+package dicescript
+
+// NewSession 创建一个会话vm，用于一条聊天指令中先后执行的多个表达式，如先骰伤害、再据此扣除生命值。
+// 会话中赋值的变量在多次 Run 之间保留，但只存在于会话中，会话结束后直接丢弃即可:
+// 不会调用 GlobalValueStoreFunc、GlobalValueDeleteFunc，以及 HookValueStore、HookValueDelete，
+// 对全局变量的赋值同样只写入会话。读取时先查找会话中的变量，再使用ctx的读取回调。
+// 会话与ctx共用设置、内置变量、作用域变量、随机源与 OnOutput 等回调
+func (ctx *Context) NewSession() *Context {
+	s := NewVM()
+	s.Config = ctx.Config
+	s.Config.HookValueStore = nil
+	s.Config.HookValueDelete = nil
+
+	// 在会话中删除的全局变量，此后读取时视为不存在
+	deleted := map[string]bool{}
+	if load := ctx.GlobalValueLoadFunc; load != nil {
+		s.GlobalValueLoadFunc = func(name string) *VMValue {
+			if deleted[name] {
+				return nil
+			}
+			return load(name)
+		}
+	}
+	if exists := ctx.GlobalValueExistsFunc; exists != nil {
+		s.GlobalValueExistsFunc = func(name string) bool {
+			return !deleted[name] && exists(name)
+		}
+	}
+	if batch := ctx.GlobalValueBatchLoadFunc; batch != nil {
+		s.GlobalValueBatchLoadFunc = func(names []string) map[string]*VMValue {
+			ret := batch(names)
+			for name := range deleted {
+				delete(ret, name)
+			}
+			return ret
+		}
+	}
+	if prefetch := ctx.GlobalValuePrefetchFunc; prefetch != nil {
+		s.GlobalValuePrefetchFunc = func(names []string) func(name string) *VMValue {
+			wait := prefetch(names)
+			return func(name string) *VMValue {
+				if deleted[name] {
+					return nil
+				}
+				return wait(name)
+			}
+		}
+	}
+	s.GlobalValueLoadOverwriteFunc = ctx.GlobalValueLoadOverwriteFunc
+	s.GlobalValueStoreFunc = func(name string, v *VMValue) {
+		delete(deleted, name)
+		s.Attrs.Store(name, v)
+	}
+	s.GlobalValueDeleteFunc = func(name string) {
+		deleted[name] = true
+		s.Attrs.Delete(name)
+	}
+
+	s.scopeResolvers = ctx.scopeResolvers
+	s.builtins = ctx.builtins
+	s.capTags = ctx.capTags
+	s.mocks = ctx.mocks
+	for name := range ctx.readOnlyNames {
+		s.RegReadOnlyNames(name)
+	}
+	s.CustomDiceInfo = ctx.CustomDiceInfo
+	s.CustomFlag = ctx.CustomFlag
+	s.OnValueCreate = ctx.OnValueCreate
+	s.OnOutput = ctx.OnOutput
+	s.Stringer = ctx.Stringer
+	s.profiler = ctx.profiler
+	s.RandSrc = ctx.RandSrc
+	return s
+}
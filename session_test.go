@@ -0,0 +1,48 @@
+package dicescript
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSession(t *testing.T) {
+	p := &testBatchAttrProvider{}
+	p.m.Store("hp", ni(20))
+	p.m.Store("力量", ni(60))
+
+	vm := NewVM(WithAttrProvider(p))
+	vm.Config.DiceMaxMode = true
+	s := vm.NewSession()
+	err := s.Run("伤害 = d6 + 1; hp = hp - 伤害")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(s.Ret, ni(13)))
+	}
+	// 临时变量在同一会话的下一次执行中仍然存在
+	err = s.Run("[伤害, hp, 力量]")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(s.Ret, na(ni(7), ni(13), ni(60))))
+	}
+	// 删除只在会话中生效
+	err = s.Run("del 力量; exists('力量')")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(s.Ret, nb(false)))
+	}
+	err = s.Run("力量 ?? 0")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(s.Ret, ni(0)))
+	}
+
+	// 不会写入持久化的变量
+	assert.True(t, valueEqual(p.m.MustLoad("hp"), ni(20)))
+	assert.True(t, valueEqual(p.m.MustLoad("力量"), ni(60)))
+	_, ok := p.m.Load("伤害")
+	assert.False(t, ok)
+
+	// 另一个会话互不影响
+	s2 := vm.NewSession()
+	err = s2.Run("[hp, 伤害 ?? 0]")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(s2.Ret, na(ni(20), ni(0))))
+	}
+}
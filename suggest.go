@@ -0,0 +1,141 @@
+package dicescript
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Suggestion 对输入中一处笔误的修改建议
+type Suggestion struct {
+	From   string
+	To     string
+	Reason string
+}
+
+// ParseError 附带修改建议的解析错误，可以用 errors.As 取出，向用户提示“你是不是想输入”
+type ParseError struct {
+	Err         error
+	Fixed       string // 按建议修改后的语句
+	Suggestions []Suggestion
+}
+
+func (e *ParseError) Error() string {
+	switch parseErrorLanguage {
+	case ParseErrorLanguageChinese:
+		return e.Err.Error() + "\n  你是不是想输入: " + e.Fixed
+	case ParseErrorLanguageEnglish:
+		return e.Err.Error() + "\n  Did you mean: " + e.Fixed
+	default:
+		return e.Err.Error() + "\n  你是不是想输入 Did you mean: " + e.Fixed
+	}
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// suggestRule 一种常见笔误，ok为false时不适用于当前配置
+type suggestRule struct {
+	re     *regexp.Regexp
+	repl   func(ctx *Context, m []string) (to string, ok bool)
+	reason string
+}
+
+var suggestRules = []suggestRule{
+	{
+		// 全角字符
+		re: regexp.MustCompile(`[\x{FF01}-\x{FF5E}]+`),
+		repl: func(ctx *Context, m []string) (string, bool) {
+			return strings.Map(func(r rune) rune { return r - 0xFEE0 }, m[0]), true
+		},
+		reason: "全角字符",
+	},
+	{
+		// 3b6 3f6 3s6 等，d附近的键位或形近字母
+		re: regexp.MustCompile(`(^|[^\p{L}\p{N}_])(\d+)([bBsSrRfFcCvV])(\d+)`),
+		repl: func(ctx *Context, m []string) (string, bool) {
+			switch m[3] {
+			case "b", "B":
+				if ctx.Config.EnableDiceCoC {
+					return "", false
+				}
+			case "c", "C":
+				if ctx.Config.EnableDiceDoubleCross {
+					return "", false
+				}
+			}
+			return m[1] + m[2] + "d" + m[4], true
+		},
+		reason: "骰子算符为d",
+	},
+	{
+		re: regexp.MustCompile(`(?i)(^|[^\p{L}\p{N}_])1?d(\d*) ?(advantage|adv)\b`),
+		repl: func(ctx *Context, m []string) (string, bool) {
+			return m[1] + "d" + m[2] + "优势", true
+		},
+		reason: "优势骰写作 d20优势",
+	},
+	{
+		re: regexp.MustCompile(`(?i)(^|[^\p{L}\p{N}_])1?d(\d*) ?(disadvantage|disadv|dis)\b`),
+		repl: func(ctx *Context, m []string) (string, bool) {
+			return m[1] + "d" + m[2] + "劣势", true
+		},
+		reason: "劣势骰写作 d20劣势",
+	},
+	{
+		re: regexp.MustCompile(`([dD])%`),
+		repl: func(ctx *Context, m []string) (string, bool) {
+			return m[1] + "100", !ctx.Config.EnablePercentDice
+		},
+		reason: "百分骰写作 d100",
+	},
+	{
+		re: regexp.MustCompile(`(\d+|\)) ?([xX×]) ?(\d|\()`),
+		repl: func(ctx *Context, m []string) (string, bool) {
+			// 0x10 是十六进制数
+			if m[1] == "0" && m[2] != "×" && m[0] == "0"+m[2]+m[3] {
+				return "", false
+			}
+			return m[1] + "*" + m[3], true
+		},
+		reason: "乘号为 *",
+	},
+}
+
+// SuggestFixes 检查输入中常见的笔误，如 3b6(3d6)、1d20adv(d20优势)、全角字符，返回修改后的语句和各处修改。
+// 只有修改后能够完整解析时才给出建议，否则fixed为空。
+// 解析出错时 Parse 会自动调用，此外也可以在 RestInput 不为空时调用，提示用户是否输错了
+func (ctx *Context) SuggestFixes(expr string) (fixed string, items []Suggestion) {
+	fixed = expr
+	for _, rule := range suggestRules {
+		fixed = rule.re.ReplaceAllStringFunc(fixed, func(s string) string {
+			to, ok := rule.repl(ctx, rule.re.FindStringSubmatch(s))
+			if !ok || to == s {
+				return s
+			}
+			items = append(items, Suggestion{From: s, To: to, Reason: rule.reason})
+			return to
+		})
+	}
+	if len(items) == 0 {
+		return "", nil
+	}
+
+	// 验证修改后的语句，使用同样的配置但不执行
+	vm := NewVM()
+	vm.Config = ctx.Config
+	vm.CustomDiceInfo = ctx.CustomDiceInfo
+	if err := vm.parseCode(fixed); err != nil || strings.TrimSpace(fixed[vm.parser.pt.offset:]) != "" {
+		return "", nil
+	}
+	return fixed, items
+}
+
+// withSuggestions 为解析错误附加修改建议
+func (ctx *Context) withSuggestions(expr string, err error) error {
+	fixed, items := ctx.SuggestFixes(expr)
+	if fixed == "" {
+		return err
+	}
+	return &ParseError{Err: err, Fixed: fixed, Suggestions: items}
+}
@@ -0,0 +1,63 @@
+package dicescript
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSuggestFixes(t *testing.T) {
+	cases := []struct {
+		expr  string
+		fixed string
+	}{
+		{"3b6", "3d6"},
+		{"1d20adv", "d20优势"},
+		{"d20 dis+2", "d20劣势+2"},
+		{"３ｄ６＋１", "3d6+1"},
+		{"2x3", "2*3"},
+		{"10 x 3", "10*3"},
+		{"0x8000000000000000", ""}, // 十六进制数，不是乘法
+		{"d%", "d100"},
+		{"3d6", ""},   // 没有笔误
+		{"3b6+", ""},  // 修改后仍无法解析
+		{"ab3c6", ""}, // 变量名的一部分
+	}
+	for _, c := range cases {
+		vm := NewVM()
+		fixed, items := vm.SuggestFixes(c.expr)
+		assert.Equal(t, c.fixed, fixed, c.expr)
+		if c.fixed != "" {
+			assert.NotEmpty(t, items, c.expr)
+		}
+	}
+
+	// 开启了对应语法时不给建议
+	vm := NewVM()
+	vm.Config.EnableDiceCoC = true
+	fixed, _ := vm.SuggestFixes("3b6")
+	assert.Equal(t, "", fixed)
+
+	vm = NewVM()
+	fixed, items := vm.SuggestFixes("1d20adv+3b6")
+	assert.Equal(t, "d20优势+3d6", fixed)
+	assert.Len(t, items, 2)
+}
+
+func TestParseErrorSuggestion(t *testing.T) {
+	vm := NewVM()
+	err := vm.Run("（1d6）")
+	var pe *ParseError
+	if assert.True(t, errors.As(err, &pe)) {
+		assert.Equal(t, "(1d6)", pe.Fixed)
+		assert.Contains(t, err.Error(), "(1d6)")
+		assert.Error(t, pe.Unwrap())
+	}
+
+	// 没有建议时仍为原来的错误
+	vm = NewVM()
+	err = vm.Run("+")
+	assert.Error(t, err)
+	assert.False(t, errors.As(err, &pe))
+}
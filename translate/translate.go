@@ -0,0 +1,275 @@
+// Package translate 将其他骰子引擎(OneDice、旧版海豹rollvm)的常见写法转换为dicescript语法，
+// 并报告无法转换的部分，便于机器人更换引擎时迁移已有的宏
+package translate
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	ds "github.com/sealdice/dicescript"
+)
+
+// Dialect 来源语法
+type Dialect int
+
+const (
+	OneDice  Dialect = iota // OneDice标准
+	LegacyVM                // 旧版海豹rollvm
+)
+
+// Change 一处改写
+type Change struct {
+	Pos    int // 在原文中的位置(按字符计)
+	From   string
+	To     string
+	Reason string
+}
+
+// Issue 一处无法转换的写法，原样保留在结果中
+type Issue struct {
+	Pos    int // 在原文中的位置，转换后解析时发现的问题为-1
+	Text   string
+	Reason string
+}
+
+// Result 转换结果
+type Result struct {
+	Expr        string
+	Changes     []Change
+	Unsupported []Issue
+	// 需要开启的语法，如 EnableDiceFate，对应 RollConfig 中的同名字段
+	RequiredFlags []string
+}
+
+// OK 是否完整转换，没有无法支持的写法
+func (r *Result) OK() bool {
+	return len(r.Unsupported) == 0
+}
+
+// Config 按 RequiredFlags 开启了所需语法的配置
+func (r *Result) Config() ds.RollConfig {
+	var cfg ds.RollConfig
+	for _, flag := range r.RequiredFlags {
+		switch flag {
+		case "EnableDiceCoC":
+			cfg.EnableDiceCoC = true
+		case "EnableDiceWoD":
+			cfg.EnableDiceWoD = true
+		case "EnableDiceFate":
+			cfg.EnableDiceFate = true
+		case "EnableDiceDoubleCross":
+			cfg.EnableDiceDoubleCross = true
+		}
+	}
+	return cfg
+}
+
+// fullWidth 语法不支持的全角符号
+var fullWidth = map[rune]string{
+	'（': "(", '）': ")", '＝': "=", '＜': "<", '＞': ">", '！': "!",
+	'，': ",", '％': "%", '＾': "^", '－': "-", '＋': "+", '＊': "*", '／': "/",
+	'×': "*", '÷': "/",
+}
+
+type translator struct {
+	src     []rune
+	pos     int
+	out     strings.Builder
+	result  *Result
+	dialect Dialect
+}
+
+// Translate 将expr从给定的语法转换为dicescript语法。
+// 转换后会试着解析一次，解析不完的部分同样记入 Unsupported
+func Translate(expr string, from Dialect) *Result {
+	t := &translator{src: []rune(expr), result: &Result{}, dialect: from}
+	t.run()
+	t.result.Expr = t.out.String()
+	t.verify()
+	return t.result
+}
+
+func (t *translator) peek(offset int) rune {
+	if i := t.pos + offset; i >= 0 && i < len(t.src) {
+		return t.src[i]
+	}
+	return 0
+}
+
+// lastOut 输出中最后一个非空白字符
+func (t *translator) lastOut() rune {
+	s := []rune(t.out.String())
+	for i := len(s) - 1; i >= 0; i-- {
+		if !unicode.IsSpace(s[i]) {
+			return s[i]
+		}
+	}
+	return 0
+}
+
+// nextIn 原文中offset之后第一个非空白字符
+func (t *translator) nextIn(offset int) rune {
+	for i := t.pos + offset; i < len(t.src); i++ {
+		if !unicode.IsSpace(t.src[i]) {
+			return t.src[i]
+		}
+	}
+	return 0
+}
+
+func (t *translator) rewrite(n int, to string, reason string) {
+	from := string(t.src[t.pos : t.pos+n])
+	t.result.Changes = append(t.result.Changes, Change{Pos: t.pos, From: from, To: to, Reason: reason})
+	t.out.WriteString(to)
+	t.pos += n
+}
+
+func (t *translator) unsupported(n int, reason string) {
+	text := string(t.src[t.pos : t.pos+n])
+	t.result.Unsupported = append(t.result.Unsupported, Issue{Pos: t.pos, Text: text, Reason: reason})
+	t.out.WriteString(text)
+	t.pos += n
+}
+
+func (t *translator) require(flag string) {
+	for _, f := range t.result.RequiredFlags {
+		if f == flag {
+			return
+		}
+	}
+	t.result.RequiredFlags = append(t.result.RequiredFlags, flag)
+}
+
+func isDigit(r rune) bool {
+	return r >= '0' && r <= '9'
+}
+
+func isWordChar(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// leadingCount 输出末尾紧挨着的数字，如 "1+4" 中的 "4"
+func (t *translator) leadingCount() string {
+	s := []rune(t.out.String())
+	i := len(s)
+	for i > 0 && isDigit(s[i-1]) {
+		i--
+	}
+	if i > 0 && isWordChar(s[i-1]) {
+		return ""
+	}
+	return string(s[i:])
+}
+
+func (t *translator) run() {
+	for t.pos < len(t.src) {
+		r := t.src[t.pos]
+		switch {
+		case r >= '０' && r <= '９':
+			t.rewrite(1, string('0'+(r-'０')), "全角数字")
+		case fullWidth[r] != "":
+			t.rewrite(1, fullWidth[r], "全角符号")
+
+		case (r == 'x' || r == 'X') && isMulOperand(t.lastOut(), true) && isMulOperand(t.nextIn(1), false):
+			// 2x3、(1d6)x2 中的x为乘号
+			t.rewrite(1, "*", "以x表示乘法")
+
+		case (r == 'd' || r == 'D') && (!isWordChar(t.peek(-1)) || isDigit(t.peek(-1))):
+			t.dice()
+
+		case r == '#' && t.leadingCount() != "":
+			// 3#d20 为多轮骰点，是指令层面的写法
+			t.unsupported(1, "多轮骰点(如 3#d20)需由宿主程序实现，可改为循环")
+		default:
+			t.out.WriteRune(r)
+			t.pos++
+		}
+	}
+}
+
+// isMulOperand x两侧是否为数字、括号或骰子结果
+func isMulOperand(r rune, left bool) bool {
+	if isDigit(r) {
+		return true
+	}
+	if left {
+		return r == ')'
+	}
+	return r == '(' || r == '（'
+}
+
+// dice 处理以d开头的写法
+func (t *translator) dice() {
+	next := t.peek(1)
+	switch {
+	case next == '%' || next == '％':
+		t.rewrite(2, "d100", "d%即d100")
+		return
+	case (next == 'F' || next == 'f') && !isWordChar(t.peek(2)):
+		// 本引擎同样支持 4dF，只需开启命运骰语法
+		t.require("EnableDiceFate")
+		out := t.out.String()
+		if t.dialect == OneDice && t.leadingCount() == "" && !strings.HasSuffix(out, ")") {
+			t.rewrite(2, "4dF", "省略个数的dF为4个命运骰")
+		} else {
+			t.out.WriteString(string(t.src[t.pos : t.pos+2]))
+			t.pos += 2
+		}
+		return
+	}
+
+	// 普通骰子，检查其后的后缀
+	t.out.WriteRune(t.src[t.pos])
+	t.pos++
+	for t.pos < len(t.src) && isDigit(t.src[t.pos]) {
+		t.out.WriteRune(t.src[t.pos])
+		t.pos++
+	}
+	if t.peek(0) == '!' && t.peek(1) != '=' {
+		n := 1
+		if t.peek(1) == '!' {
+			n = 2
+		}
+		switch {
+		case isWordChar(t.peek(n)):
+			// 这里追加的骰点累加到同一颗骰子上，与取高取低等后缀同用时结果不同
+			t.unsupported(n, "爆炸骰(!)不能与其他后缀同时使用")
+		case n == 2:
+			t.rewrite(2, "!", "复合爆炸骰(!!)即 !")
+		default:
+			t.out.WriteRune('!')
+			t.pos++
+		}
+	}
+}
+
+// verify 开启所需语法后解析一次结果，记录解析不完的部分
+func (t *translator) verify() {
+	r := t.result
+	cfg := r.Config()
+	vm := ds.NewVM(ds.WithConfig(&cfg))
+	if err := vm.Parse(r.Expr); err != nil {
+		r.Unsupported = append(r.Unsupported, Issue{Pos: -1, Text: r.Expr, Reason: "转换结果无法解析: " + err.Error()})
+		return
+	}
+	if rest := strings.TrimSpace(r.Expr[vm.GetParsedOffset():]); rest != "" && len(r.Unsupported) == 0 {
+		r.Unsupported = append(r.Unsupported, Issue{Pos: -1, Text: rest, Reason: "无法解析的部分"})
+	}
+}
+
+// String 转换报告，每行一条
+func (r *Result) String() string {
+	var sb strings.Builder
+	sb.WriteString(r.Expr)
+	for _, c := range r.Changes {
+		fmt.Fprintf(&sb, "\n改写 %s -> %s: %s", c.From, c.To, c.Reason)
+	}
+	for _, i := range r.Unsupported {
+		fmt.Fprintf(&sb, "\n不支持 %s: %s", i.Text, i.Reason)
+	}
+	if len(r.RequiredFlags) > 0 {
+		fmt.Fprintf(&sb, "\n需要开启: %s", strings.Join(r.RequiredFlags, ", "))
+	}
+	return sb.String()
+}
@@ -0,0 +1,54 @@
+package translate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTranslate(t *testing.T) {
+	cases := []struct {
+		expr, want string
+	}{
+		{"（1d6＋2）×３", "(1d6+2)*3"},
+		{"2x3 + 1d6X2", "2*3 + 1d6*2"},
+		{"d% + D%", "d100 + d100"},
+		{"4dF+1", "4dF+1"},
+		{"dF + 2dF + (1+1)dF", "4dF + 2dF + (1+1)dF"},
+		{"3d20k2 ÷ 2", "3d20k2 / 2"},
+		{"max + x", "max + x"},
+		{"1d6 != 3", "1d6 != 3"},
+	}
+	for _, c := range cases {
+		r := Translate(c.expr, OneDice)
+		assert.Equal(t, c.want, r.Expr, c.expr)
+		assert.True(t, r.OK(), r.String())
+	}
+
+	r := Translate("dF", LegacyVM)
+	assert.Equal(t, "dF", r.Expr)
+	assert.Equal(t, []string{"EnableDiceFate"}, r.RequiredFlags)
+	assert.True(t, r.Config().EnableDiceFate)
+	assert.Len(t, r.Changes, 0)
+}
+
+func TestTranslateUnsupported(t *testing.T) {
+	r := Translate("3#d20", OneDice)
+	assert.False(t, r.OK())
+	assert.Equal(t, "3#d20", r.Expr)
+	assert.Equal(t, 1, r.Unsupported[0].Pos)
+
+	r = Translate("5d6!k3 + 2dF", OneDice)
+	if assert.Len(t, r.Unsupported, 1) {
+		assert.Equal(t, "!", r.Unsupported[0].Text)
+	}
+
+	r = Translate("5d6! + 2d6!!", OneDice)
+	assert.True(t, r.OK())
+	assert.Equal(t, "5d6! + 2d6!", r.Expr)
+
+	// 转换后解析不完的部分
+	r = Translate("1d6 + 【2】", OneDice)
+	assert.False(t, r.OK())
+	assert.Contains(t, r.String(), "不支持")
+}
@@ -24,6 +24,8 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"golang.org/x/exp/rand"
 )
@@ -36,6 +38,7 @@ const (
 	VMTypeInt            VMValueType = 0
 	VMTypeFloat          VMValueType = 1
 	VMTypeString         VMValueType = 2
+	VMTypeUndefined      VMValueType = 3
 	VMTypeNull           VMValueType = 4
 	VMTypeComputedValue  VMValueType = 5
 	VMTypeArray          VMValueType = 6
@@ -43,10 +46,28 @@ const (
 	VMTypeFunction       VMValueType = 8
 	VMTypeNativeFunction VMValueType = 9
 	VMTypeNativeObject   VMValueType = 10
+	VMTypeTime           VMValueType = 11 // 时间点
+	VMTypeDuration       VMValueType = 12 // 时长
+	VMTypeIterator       VMValueType = 13 // 迭代器
+	VMTypeDeck           VMValueType = 14 // 牌堆
+	VMTypeTable          VMValueType = 15 // 二维表格
+	VMTypeQuantity       VMValueType = 16 // 带单位的数
+	VMTypeMoney          VMValueType = 17 // 金额
+	VMTypeCheck          VMValueType = 18 // 检定结果
+	VMTypeOrder          VMValueType = 19 // 先攻顺序
 
 	// 内部对象
 	vmTypeLocal  VMValueType = 20
 	vmTypeGlobal VMValueType = 21
+	vmTypeSpread VMValueType = 22 // 展开语法 [...x] 中的待展开项
+
+	// 20至29留给内部对象，此后的类型从30开始
+	VMTypeResource VMValueType = 30 // 有上下限的资源
+	VMTypeTuple    VMValueType = 31 // 元组
+	VMTypeSet      VMValueType = 32 // 集合
+	VMTypeBool     VMValueType = 33 // 布尔值，比较运算的结果
+	VMTypeError    VMValueType = 34 // 错误值
+	VMTypeRange    VMValueType = 35 // 范围 1..20
 )
 
 var binOperator = []func(*VMValue, *Context, *VMValue) *VMValue{
@@ -67,17 +88,32 @@ var binOperator = []func(*VMValue, *Context, *VMValue) *VMValue{
 
 	(*VMValue).OpBitwiseAnd,
 	(*VMValue).OpBitwiseOr,
+	(*VMValue).OpBitwiseXor,
+	(*VMValue).OpShiftLeft,
+	(*VMValue).OpShiftRight,
+	(*VMValue).OpIn,
 }
 
 type RollConfig struct {
 	EnableDiceWoD         bool // 启用WOD骰子语法，即XaYmZkNqM，X个数，Y加骰线，Z面数，N阈值(>=)，M阈值(<=)
 	EnableDiceCoC         bool // 启用COC骰子语法，即bX/pX奖惩骰
-	EnableDiceFate        bool // 启用Fate骰语法，即fX
+	EnableDiceFate        bool // 启用Fate骰语法，即f和4dF
 	EnableDiceDoubleCross bool // 启用双十字骰语法，即XcY
+	EnablePercentDice     bool // 启用百分骰写法，即d%，视为d100
+	EnablePercentLiteral  bool // 启用百分数写法，即35%，视为0.35。其后紧跟数字、变量、括号时仍是取余
 
 	DisableBitwiseOp bool // 禁用位运算，用于st，如 &a=1d4
 	DisableStmts     bool // 禁用语句语法(如if while等)，仅允许表达式
 	DisableNDice     bool // 禁用Nd语法，即只能2d6这样写，不能写2d
+	CaretAsXor       bool // ^ 为按位异或，此时乘方只能写作 **。默认 ^ 为乘方
+
+	DisableRandomTextFuncs bool // 禁用 uuid()、randstr()、pick_name() 等随机文本函数
+
+	// 允许的能力，如 CapTime。为nil时不限制，否则需要其中没有的能力的函数(见 WithCapabilityTags)无法调用。
+	// 可以为管理员的宏和公开的指令分别设置，或通过 RunOptions.Capabilities 为单次执行设置
+	AllowedCapabilities []string
+
+	Compat CompatFlags // 兼容旧版骰子机器人的写法，默认全部关闭
 
 	ValueStoreSource string // ValueStoreSource 用于区分来源以便于 HookValueStore 的调用判断持久化方式
 
@@ -88,6 +124,16 @@ type RollConfig struct {
 	HookValueLoadPre func(ctx *Context, name string) (newName string, overwrite *VMValue)
 	// 读取后回调(返回值将覆盖之前读到的值。如果之前未读取到值curVal将为nil)，用户需要在里面调用doCompute保证结果正确
 	HookValueLoadPost func(ctx *Context, name string, curVal *VMValue, doCompute func(curVal *VMValue) *VMValue, detail *BufferSpan) *VMValue
+	// exists()检查回调，如果solved为true，直接使用exists作为结果
+	HookValueExists func(ctx *Context, name string) (exists bool, solved bool)
+	// del语句回调，如果返回值为true，那么跳过剩下的删除流程
+	HookValueDelete func(ctx *Context, name string) (solved bool)
+
+	// reply()的模板渲染，vars为模板中 {名字} 对应的值。由宿主程序负责转义、格式等处理，为nil时直接替换为值的文本
+	ReplyTemplateFunc func(ctx *Context, tmpl string, vars map[string]*VMValue) (string, error)
+
+	// pick_name()的名字列表，culture为调用时给出的参数。返回nil时使用内置的列表
+	NameListFunc func(ctx *Context, culture string) []string
 
 	// st回调，注意val和extra都经过clone，可以放心储存
 	CallbackSt                  func(_type string, name string, val *VMValue, extra *VMValue, op string, detail string)                                  // st回调
@@ -95,10 +141,23 @@ type RollConfig struct {
 	CustomDetailSpanRewriteFunc func(ctx *Context, defaultDetail string, detailSpan BufferSpan, isRoot bool, dataBuffer []byte, parsedOffset int) string // 自定义任意一项detail改写
 	CustomDetailRewriteFunc     func(ctx *Context, curDetail string, detailSpan BufferSpan, dataBuffer []byte, parsedOffset int) string                  // 自定义单项detail重写
 
-	ParseExprLimit               uint64   // 解析算力限制，防止构造特殊语句进行DOS攻击，0为无限，建议值1000万
-	OpCountLimit                 IntType  // 算力限制，超过这个值会报错，0为无限，建议值30000
-	DefaultDiceSideExpr          string   // 默认骰子面数
-	defaultDiceSideExprCacheFunc *VMValue // expr的缓存函数
+	// 算力配额，执行中算力增长时分批报告消耗量，返回错误时中止执行。用于跨多次执行限制单个用户/群的算力
+	QuotaFunc func(cost int64) error
+
+	ParseExprLimit               uint64          // 解析算力限制，防止构造特殊语句进行DOS攻击，0为无限，建议值1000万
+	OpCountLimit                 IntType         // 算力限制，超过这个值会报错，0为无限，建议值30000
+	TimeLimit                    time.Duration   // 单次执行的时间限制，超时会报错，0为无限。同样约束 ctx.CallExternal 中的外部调用
+	MaxStringLen                 int             // md5()、b64encode()等字符串函数允许处理和产生的最大长度(字节)，0为无限
+	HistorySize                  int             // 保留最近几次执行的结果，供 ctx.History() 和 lastroll() 使用，0为不保留
+	Units                        *UnitTable      // 单位换算表，注册过的单位可以写作 5kg 这样带单位的数
+	PercentAsInt                 bool            // 开启 EnablePercentLiteral 时，百分数 35% 的值为35，默认为0.35
+	RoundMode                    RoundMode       // 小数转为整数的方式，用于 toInt()、整数相除和金额乘小数等，见 RoundMode
+	Currency                     *CurrencySystem // 货币面额，金额可以写作 3gp5sp，为nil时使用 gp/sp/cp
+	RuleSet                      *RuleSet        // 规则集，用于定制检定结果等规则相关的行为
+	DefaultDiceSideExpr          string          // 默认骰子面数
+	DefaultDiceCount             IntType         // 省略个数的骰子(d20、d)的个数，0为1
+	DiceExplodeLimit             IntType         // 爆炸骰(d6!)每颗骰子至多追加骰点的次数，0为100
+	defaultDiceSideExprCacheFunc *VMValue        // expr的缓存函数
 
 	PrintBytecode bool // 执行时打印字节码
 	IgnoreDiv0    bool // 当div0时暂不报错
@@ -107,6 +166,58 @@ type RollConfig struct {
 
 	DiceMinMode bool // 骰子以最小值结算，用于获取下界
 	DiceMaxMode bool // 以最大值结算 获取上界
+
+	QuietDetail bool // 不生成计算过程(如暗骰)，DetailSpans 中仍有记录
+
+	SortDiceDetail bool // 计算过程中的骰池按从大到小显示，如 4d6=6+4+3+1。骰出的顺序见 BufferSpan.Pool
+
+	// 多级属性赋值(如 a.b.c = 1)时自动创建不存在的中间字典。读取不存在的属性路径总是得到空值，不受此项影响
+	AttrPathAutoCreate bool
+
+	// 宽松解析: 忽略妨碍解析的空格、逗号等杂散字符，如 "3 d6"、"d20+ ,5"、"，d20"，用于直接解析聊天消息。
+	// 此时 Matched 为去掉这些字符后实际执行的部分，末尾无法解析的内容仍在 RestInput 中
+	LenientParse bool
+
+	// 公共子表达式消除: 如 (力量+敏捷)/2 + (力量+敏捷)%2 中 力量+敏捷 只计算一次，减少读取变量的次数。
+	// 含有赋值、函数调用、循环的语句不做处理，计算过程不受影响
+	EliminateCommonSubexpr bool
+
+	// 字节码缓存，再次解析同样的语句时直接读取，见 NewDirCodeCache
+	CodeCache CodeCache
+
+	// 编译期策略检查，在解析完成、执行之前调用，返回错误时拒绝执行
+	// 可用于按频道限制语法，如禁止赋值、禁止调用某些函数、禁止面数过大的骰子。
+	// info 是字节码的摘要而非语法树，见 ProgramInfo
+	CompilePolicy func(ctx *Context, info *ProgramInfo) error
+
+	// 严格区分 undefined 与 null: 开启后读取不存在的变量/属性得到undefined，null仅代表显式的空值，
+	// == 中二者不再相等，?? 也只对undefined生效。默认关闭，此时缺失值一律为null，二者视为同一个值
+	StrictUndefined bool
+}
+
+// mergeFlags 使用 flags 中的开关与数值设置替换当前配置，函数类型的字段(各种钩子与回调)保持不变
+func (c *RollConfig) mergeFlags(flags *RollConfig) {
+	dst := reflect.ValueOf(c).Elem()
+	src := reflect.ValueOf(flags).Elem()
+	for i := 0; i < dst.NumField(); i++ {
+		f := dst.Type().Field(i)
+		if f.PkgPath != "" || f.Type.Kind() == reflect.Func {
+			continue
+		}
+		dst.Field(i).Set(src.Field(i))
+	}
+}
+
+// CompatFlags 兼容旧版骰子机器人的一些特殊行为。
+// 从其他引擎迁移时，按需开启对应的项，避免同样的语句得出不同的结果
+type CompatFlags struct {
+	// 省略面数的骰子(d、3d)总是视为d100，不受 DefaultDiceSideExpr 影响
+	BareDiceD100 bool
+	// 数字或右括号后紧跟左括号时视为乘法，如 3(1d6) 即 3*(1d6)
+	ImplicitMultiply bool
+	// 比较、逻辑运算、in 和 true/false 等得到的布尔值入栈时转为整数1或0，与0.3.0之前没有布尔类型时相同，
+	// 如 1 < 2 显示为1，宿主程序可以继续用 MustReadInt 读取结果
+	IntBool bool
 }
 
 type CustomDiceHandler func(ctx *Context, groups []string, payload any) (*VMValue, string, error)
@@ -161,7 +272,12 @@ type Context struct {
 	stack []VMValue
 	top   int
 
-	NumOpCount IntType // 算力计数
+	NumOpCount    IntType   // 算力计数
+	quotaReported IntType   // 已向 QuotaFunc 报告的算力
+	deadline      time.Time // 本次执行的截止时间，由 TimeLimit 得出，零值为不限
+	interrupted   int32     // 由 Interrupt 在其他goroutine中设置，原子读写
+	interruptMu   sync.Mutex
+	interruptCh   chan struct{} // 中止时关闭，用于等待外部调用时及时返回，由 interruptMu 保护
 	// CocFlagVarPrefix string // 解析过程中出现，当VarNumber开启时有效，可以是困难极难常规大成功
 
 	Config RollConfig // 标记
@@ -171,7 +287,11 @@ type Context struct {
 	RestInput        string   // 剩余字符串
 	Matched          string   // 匹配的字符串
 	DetailSpans      []BufferSpan
-	detailCache      string // 计算过程
+	Outputs          []OutputItem // output() 给出的带标签的结果，按调用顺序排列
+	Events           []EventItem  // emit() 给出的事件，按调用顺序排列
+	KarmaSpends      []KarmaSpend // RuleSet.Karma 消耗资源修改骰点的记录，按骰点的顺序排列
+	Rolls            []RollRecord // 执行中的每一次骰点，包括函数和计算类型中的，按骰点的顺序排列
+	detailCache      string       // 计算过程
 	IsComputedLoaded bool
 
 	Seed    []byte          // 随机种子，16个字节，即双uint64
@@ -180,20 +300,91 @@ type Context struct {
 	IsRunning      bool // 是否正在运行，Run时会置为true，halt时会置为false
 	CustomDiceInfo []*customDiceItem
 
-	forceSolveDetail bool // 一个辅助属性，用于computed时强制获取计算过程
+	forceSolveDetail bool                // 一个辅助属性，用于computed时强制获取计算过程
+	optionErr        error               // 创建vm时选项产生的错误，执行时返回
+	runVars          map[string]*VMValue // 每次执行时临时设置的变量，见 WithVars
 
 	/** 自定义标志位 */
 	CustomFlag map[string]any
 
 	/** 全局变量 */
 	globalNames *ValueMap
+	// 顶层(不在函数中)的赋值同样写入全局scope，见 WithAttrProvider
+	storeTopLevelGlobal bool
 
 	// 全局scope的写入回调
 	GlobalValueStoreFunc func(name string, v *VMValue)
 	// 全局scope的读取回调
 	GlobalValueLoadFunc func(name string) *VMValue
+	// 全局scope的存在检查回调(可选)，用于 exists()。未设置时以 GlobalValueLoadFunc 的结果判断
+	GlobalValueExistsFunc func(name string) bool
 	// 全局scope的读取后回调(返回值将覆盖之前读到的值。如果之前未读取到值curVal将为nil)
 	GlobalValueLoadOverwriteFunc func(name string, curVal *VMValue) *VMValue
+	// 全局scope的删除回调
+	GlobalValueDeleteFunc func(name string)
+	// 全局scope的批量读取回调(可选)，执行前以语句中读取的变量名调用一次，执行中优先使用其结果
+	GlobalValueBatchLoadFunc func(names []string) map[string]*VMValue
+	// 全局scope的预读回调(可选)，执行前以语句中读取的变量名调用，不等待结果；
+	// 执行中需要其中的变量时调用返回的wait，阻塞到该变量读取完成。设置后不再使用 GlobalValueBatchLoadFunc
+	GlobalValuePrefetchFunc func(names []string) (wait func(name string) *VMValue)
+
+	// 执行中每产生一个新值(字面量、运算结果、骰点、原生函数的返回值等)调用一次，可用于污点标记或统计分配量。
+	// 读取变量、原生函数返回参数或其中的一项(如 Array.find)等已有的值不会调用。可以设置 v.Tag 作为标记，标记随值的复制保留；不要修改v的类型和值
+	OnValueCreate func(v *VMValue)
+	// output()、reply() 执行时立即以其文本调用，用于在较长的脚本执行完毕前逐段发送回复。
+	// output('命中', 15) 的文本为 "命中: 15"。只需在最外层的vm上设置
+	OnOutput func(part string)
+	// 计算过程、格式化字符串、join()、output() 等处显示值时优先使用的文本，用于本地化，见 ValueStringer。
+	// 不影响 ToString、ToRepr 与 toStr()
+	Stringer ValueStringer
+
+	// 作用域变量，如 $t临时 $g群组 $m角色
+	scopeResolvers map[string]*ScopeResolver
+	// 只读变量(常量)
+	readOnlyNames map[string]bool
+
+	builtins  map[string]*VMValue   // 额外的内置变量/函数，见 WithBuiltins
+	capTags   map[string][]string   // 函数所需的能力，见 WithCapabilityTags
+	mocks     map[string][]*VMValue // 测试中替换的内置函数，后替换的优先，见 MockNative
+	memoEpoch int                   // 调用 invalidate() 时自增，使所有memo失效
+	memoStack []map[string]*VMValue // 正在计算的各个memo读取的外部变量，只在根vm上使用
+	prefetch  *globalPrefetch       // 本次执行批量读取或预读的全局变量
+
+	awaitInputs []*VMValue    // 继续执行时 await_input 依次得到的输入，见 Resume
+	awaitIndex  int           // 下一次 await_input 使用的输入
+	journal     *writeJournal // 执行中被修改的变量，暂停时还原，只在根vm上记录
+
+	rollModes  []int       // max(...) min(...) 中的结算模式，优先于全局设置，见 rollMode
+	callDetail *BufferSpan // 原生函数为本次调用留下的计算过程，见 setCallDetail
+
+	generator  *generatorChannel    // 当前vm是生成器函数的执行环境时不为nil
+	generators []*generatorIterator // 本次执行中开始执行的生成器，只在根vm上使用
+	history    *rollHistory         // 最近几次执行的结果，见 RollConfig.HistorySize
+	profiler   *Profiler            // 性能分析，见 WithProfiler
+	lastRun    *lastRunInfo         // 上一次成功执行的语句，用于 RerollLast
+}
+
+// ReadOnlyError 对只读变量(常量)进行赋值或删除时产生的错误
+type ReadOnlyError struct {
+	Name string
+}
+
+func (e *ReadOnlyError) Error() string {
+	return fmt.Sprintf("变量 %s 为只读，无法修改", e.Name)
+}
+
+const (
+	ScopeTemp      = "$t" // 临时变量
+	ScopeGroup     = "$g" // 群组变量
+	ScopeCharacter = "$m" // 角色变量
+)
+
+// ScopeResolver 作用域变量的读写接口，由使用者注册，用于将带有特定前缀的变量交给不同的储存
+// 回调中的name为去掉前缀后的名字
+type ScopeResolver struct {
+	Load   func(ctx *Context, name string) *VMValue // 不存在时返回nil
+	Store  func(ctx *Context, name string, v *VMValue)
+	Delete func(ctx *Context, name string)
 }
 
 func (ctx *Context) GetDetailText() string {
@@ -239,7 +430,34 @@ func (ctx *Context) GetCurSeed() ([]byte, error) {
 	return randSource.MarshalBinary()
 }
 
+// randState 复制当前随机源的状态
+func (ctx *Context) randState() rand.PCGSource {
+	if ctx.RandSrc != nil {
+		return *ctx.RandSrc
+	}
+	return *randSource
+}
+
+// newMissingVal 根据 StrictUndefined 策略生成代表“值不存在”的值
+func (ctx *Context) newMissingVal() *VMValue {
+	if ctx != nil && ctx.Config.StrictUndefined {
+		return NewUndefinedVal()
+	}
+	return NewNullVal()
+}
+
 func (ctx *Context) loadInnerVar(name string) *VMValue {
+	if stack := ctx.mocks[name]; len(stack) > 0 {
+		return stack[len(stack)-1]
+	}
+	if v, ok := ctx.builtins[name]; ok {
+		return v
+	}
+	if rs := ctx.Config.RuleSet; rs != nil {
+		if v, ok := rs.Builtins[name]; ok {
+			return v
+		}
+	}
 	return builtinValues[name]
 }
 
@@ -248,7 +466,7 @@ func (ctx *Context) solveLoadPostAndComputed(name string, val *VMValue, isRaw bo
 
 	// 计算真实结果
 	doCompute := func(val *VMValue) *VMValue {
-		if !isRaw && val.TypeId == VMTypeComputedValue {
+		if !isRaw && val.isAutoComputed() {
 			if withDetail {
 				val = val.ComputedExecute(ctx, detail)
 			} else {
@@ -284,16 +502,11 @@ func (ctx *Context) solveLoadPostAndComputed(name string, val *VMValue, isRaw bo
 }
 
 func (ctx *Context) LoadNameGlobalWithDetail(name string, isRaw bool, detail *BufferSpan) *VMValue {
-	var loadFunc func(name string) *VMValue
-	if loadFunc == nil {
-		loadFunc = ctx.GlobalValueLoadFunc
-	}
-
 	// 检测全局表
-	if loadFunc != nil {
-		val := loadFunc(name)
+	if ctx.GlobalValueLoadFunc != nil || ctx.prefetch != nil {
+		val := ctx.loadGlobalValue(name)
 		if val != nil {
-			if !isRaw && val.TypeId == VMTypeComputedValue {
+			if !isRaw && val.isAutoComputed() {
 				val = val.ComputedExecute(ctx, detail)
 				if ctx.Error != nil {
 					return nil
@@ -313,7 +526,7 @@ func (ctx *Context) LoadNameGlobalWithDetail(name string, isRaw bool, detail *Bu
 		val = ctx.GlobalValueLoadOverwriteFunc(name, val)
 	}
 	if val == nil {
-		val = NewNullVal()
+		val = ctx.newMissingVal()
 	}
 
 	val = ctx.solveLoadPostAndComputed(name, val, isRaw, detail)
@@ -343,7 +556,7 @@ func (ctx *Context) LoadNameLocalWithDetail(name string, isRaw bool, detail *Buf
 func (ctx *Context) LoadNameLocal(name string, isRaw bool) *VMValue {
 	ret := ctx.LoadNameLocalWithDetail(name, isRaw, nil)
 	if ret == nil {
-		return NewNullVal()
+		return ctx.newMissingVal()
 	}
 	return ret
 }
@@ -360,6 +573,19 @@ func (ctx *Context) LoadNameWithDetail(name string, isRaw bool, useHook bool, de
 		}
 	}
 
+	ctx.recordMemoDep(name)
+
+	if r, key := ctx.getScopeResolver(name); r != nil {
+		val := r.Load(ctx, key)
+		if ctx.Error != nil {
+			return nil
+		}
+		if val == nil {
+			val = ctx.newMissingVal()
+		}
+		return ctx.solveLoadPostAndComputed(name, val, isRaw, detail)
+	}
+
 	// 先local再global
 	curCtx := ctx
 	for {
@@ -386,8 +612,34 @@ func (ctx *Context) LoadName(name string, isRaw bool, useHook bool) *VMValue {
 	return ctx.LoadNameWithDetail(name, isRaw, useHook, nil)
 }
 
+// RegReadOnlyNames 将变量标记为只读，此后对其赋值或删除都会产生 ReadOnlyError
+// 子vm(函数、computed)会继承上层的只读标记
+func (ctx *Context) RegReadOnlyNames(names ...string) {
+	if ctx.readOnlyNames == nil {
+		ctx.readOnlyNames = map[string]bool{}
+	}
+	for _, i := range names {
+		ctx.readOnlyNames[i] = true
+	}
+}
+
+func (ctx *Context) IsReadOnlyName(name string) bool {
+	for curCtx := ctx; curCtx != nil; curCtx = curCtx.UpCtx {
+		if curCtx.readOnlyNames[name] {
+			return true
+		}
+	}
+	return false
+}
+
 // StoreName 储存变量
 func (ctx *Context) StoreName(name string, v *VMValue, useHook bool) {
+	if ctx.IsReadOnlyName(name) {
+		ctx.Error = &ReadOnlyError{Name: name}
+		return
+	}
+	ctx.journalWrite(name, useHook)
+	ctx.prefetch.forget(name)
 	if useHook && ctx.Config.HookValueStore != nil {
 		overwrite, solved := ctx.Config.HookValueStore(ctx, name, v)
 		if solved {
@@ -397,18 +649,290 @@ func (ctx *Context) StoreName(name string, v *VMValue, useHook bool) {
 			v = overwrite
 		}
 	}
-	if _, ok := ctx.globalNames.Load(name); ok {
+	if r, key := ctx.getScopeResolver(name); r != nil {
+		if r.Store == nil {
+			ctx.Error = fmt.Errorf("变量 %s 所在的作用域不可写入", name)
+			return
+		}
+		r.Store(ctx, key, v)
+		return
+	}
+	if _, ok := ctx.globalNames.Load(name); ok || (ctx.storeTopLevelGlobal && ctx.Depth() == 0) {
 		ctx.StoreNameGlobal(name, v)
 	} else {
 		ctx.StoreNameLocal(name, v)
 	}
 }
 
+// ExistsName 检查变量是否存在，不会执行computed，也不会触发读取钩子(以免产生默认值等副作用)
+func (ctx *Context) ExistsName(name string, useHook bool) bool {
+	if useHook && ctx.Config.HookValueExists != nil {
+		exists, solved := ctx.Config.HookValueExists(ctx, name)
+		if solved {
+			return exists
+		}
+	}
+
+	if r, key := ctx.getScopeResolver(name); r != nil {
+		return r.Load(ctx, key) != nil
+	}
+	for curCtx := ctx; curCtx != nil; curCtx = curCtx.UpCtx {
+		if _, ok := curCtx.Attrs.Load(name); ok {
+			return true
+		}
+	}
+	if val, ok := ctx.prefetch.get(name); ok {
+		return val != nil
+	}
+	// 读取回调可能有副作用(如生成默认值)，优先使用专门的存在检查
+	if ctx.GlobalValueExistsFunc != nil {
+		return ctx.GlobalValueExistsFunc(name)
+	}
+	return ctx.loadGlobalValue(name) != nil
+}
+
+// peekName 获取变量的原始值，不触发任何钩子，不存在时返回nil
+func (ctx *Context) peekName(name string) *VMValue {
+	if r, key := ctx.getScopeResolver(name); r != nil {
+		return r.Load(ctx, key)
+	}
+
+	// 先local再global
+	for curCtx := ctx; curCtx != nil; curCtx = curCtx.UpCtx {
+		if val, ok := curCtx.Attrs.Load(name); ok {
+			return val
+		}
+	}
+	return ctx.loadGlobalValue(name)
+}
+
+// loadGlobalValue 从全局scope读取变量，优先使用批量读取的结果
+func (ctx *Context) loadGlobalValue(name string) *VMValue {
+	if val, ok := ctx.prefetch.get(name); ok {
+		return val
+	}
+	if ctx.GlobalValueLoadFunc != nil {
+		return ctx.GlobalValueLoadFunc(name)
+	}
+	return nil
+}
+
+// globalPrefetch 执行前批量读取或预读的全局变量，见 GlobalValueBatchLoadFunc 和 GlobalValuePrefetchFunc
+type globalPrefetch struct {
+	values  map[string]*VMValue // 已取得的值，nil表示不存在
+	pending map[string]bool     // 已开始预读，尚未取得结果
+	wait    func(name string) *VMValue
+	parent  *globalPrefetch // 其中没有的变量从parent中查找，见 eval_all()
+}
+
+func (p *globalPrefetch) get(name string) (*VMValue, bool) {
+	if p == nil {
+		return nil, false
+	}
+	if val, ok := p.values[name]; ok {
+		return val, true
+	}
+	if !p.pending[name] {
+		return p.parent.get(name)
+	}
+	val := p.wait(name)
+	delete(p.pending, name)
+	p.values[name] = val
+	return val, true
+}
+
+// pendingGlobal 变量为尚在预读的全局变量时，返回等待其结果的函数，否则返回nil
+func (ctx *Context) pendingGlobal(name string) func() *VMValue {
+	if r, _ := ctx.getScopeResolver(name); r != nil {
+		return nil
+	}
+	for curCtx := ctx; curCtx != nil; curCtx = curCtx.UpCtx {
+		if _, ok := curCtx.Attrs.Load(name); ok {
+			return nil
+		}
+	}
+	for p := ctx.prefetch; p != nil; p = p.parent {
+		if _, ok := p.values[name]; ok {
+			return nil
+		}
+		if p.pending[name] {
+			wait := p.wait
+			return func() *VMValue { return wait(name) }
+		}
+	}
+	return nil
+}
+
+// forget 变量被修改后不再使用之前读到的值
+func (p *globalPrefetch) forget(name string) {
+	if p != nil {
+		delete(p.values, name)
+		delete(p.pending, name)
+		p.parent.forget(name)
+	}
+}
+
+// has 是否已经读取或开始预读变量
+func (p *globalPrefetch) has(name string) bool {
+	if p == nil {
+		return false
+	}
+	if _, ok := p.values[name]; ok || p.pending[name] {
+		return true
+	}
+	return p.parent.has(name)
+}
+
+// prefetchGlobals 执行前批量读取或预读语句中用到的全局变量，本地变量和作用域变量除外
+func (ctx *Context) prefetchGlobals() {
+	ctx.prefetch = nil
+	if (ctx.GlobalValueBatchLoadFunc == nil && ctx.GlobalValuePrefetchFunc == nil) || ctx.parser == nil {
+		return
+	}
+	var names []string
+	seen := map[string]bool{}
+	for _, name := range ctx.ProgramInfo().Names {
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		if _, ok := ctx.Attrs.Load(name); ok {
+			continue
+		}
+		if r, _ := ctx.getScopeResolver(name); r != nil {
+			continue
+		}
+		names = append(names, name)
+	}
+	ctx.prefetch = ctx.loadPrefetch(names, nil)
+}
+
+// loadPrefetch 批量读取或开始预读names中的全局变量，其余变量从parent中查找。没有要读取的变量时返回parent
+func (ctx *Context) loadPrefetch(names []string, parent *globalPrefetch) *globalPrefetch {
+	if len(names) == 0 || (ctx.GlobalValueBatchLoadFunc == nil && ctx.GlobalValuePrefetchFunc == nil) {
+		return parent
+	}
+	p := &globalPrefetch{values: make(map[string]*VMValue, len(names)), parent: parent}
+	if ctx.GlobalValuePrefetchFunc != nil {
+		p.wait = ctx.GlobalValuePrefetchFunc(names)
+		p.pending = make(map[string]bool, len(names))
+		for _, name := range names {
+			p.pending[name] = true
+		}
+	} else {
+		loaded := ctx.GlobalValueBatchLoadFunc(names)
+		for _, name := range names {
+			p.values[name] = loaded[name]
+		}
+	}
+	return p
+}
+
+// rootCtx 最外层的vm，函数调用等在子vm中执行时用于取得整次执行的状态
+func (ctx *Context) rootCtx() *Context {
+	curCtx := ctx
+	for curCtx.UpCtx != nil {
+		curCtx = curCtx.UpCtx
+	}
+	return curCtx
+}
+
+// recordMemoDep 读取变量时，记录到正在计算的各个memo的依赖中。
+// memo中调用的函数、嵌套的computed读取的变量同样记录，除非变量位于当前的局部变量空间
+func (ctx *Context) recordMemoDep(name string) {
+	cur := ctx
+	for ; cur.UpCtx != nil; cur = cur.UpCtx {
+		if _, ok := cur.Attrs.Load(name); ok {
+			return
+		}
+	}
+	if len(cur.memoStack) == 0 {
+		return
+	}
+	val := cur.peekName(name)
+	for _, deps := range cur.memoStack {
+		if _, ok := deps[name]; !ok {
+			deps[name] = val
+		}
+	}
+}
+
+// memoValid 检查memo缓存是否仍然有效
+func (ctx *Context) memoValid(m *computedMemo) bool {
+	root := ctx.rootCtx()
+	if m.root != root || m.epoch != root.memoEpoch {
+		return false
+	}
+	for name, old := range m.deps {
+		cur := root.peekName(name)
+		if cur == nil || old == nil {
+			if cur != old {
+				return false
+			}
+			continue
+		}
+		if cur != old && !ValueEqual(cur, old, false) {
+			return false
+		}
+	}
+	return true
+}
+
+// DeleteName 删除变量
+func (ctx *Context) DeleteName(name string, useHook bool) {
+	if ctx.IsReadOnlyName(name) {
+		ctx.Error = &ReadOnlyError{Name: name}
+		return
+	}
+	ctx.journalWrite(name, useHook)
+	ctx.prefetch.forget(name)
+	if useHook && ctx.Config.HookValueDelete != nil {
+		if ctx.Config.HookValueDelete(ctx, name) {
+			return
+		}
+	}
+	if r, key := ctx.getScopeResolver(name); r != nil {
+		if r.Delete == nil {
+			ctx.Error = fmt.Errorf("变量 %s 所在的作用域不可删除", name)
+			return
+		}
+		r.Delete(ctx, key)
+		return
+	}
+	if _, ok := ctx.globalNames.Load(name); ok {
+		ctx.DeleteNameGlobal(name)
+		return
+	}
+	if _, ok := ctx.Attrs.LoadAndDelete(name); !ok {
+		// 本地没有，尝试删除全局变量
+		if ctx.GlobalValueDeleteFunc != nil {
+			ctx.GlobalValueDeleteFunc(name)
+		}
+	}
+}
+
+func (ctx *Context) DeleteNameGlobal(name string) {
+	deleteFunc := ctx.GlobalValueDeleteFunc
+	if deleteFunc != nil {
+		deleteFunc(name)
+	} else {
+		ctx.Error = errors.New("未设置 GlobalValueDeleteFunc，无法删除变量")
+	}
+}
+
 func (ctx *Context) StoreNameLocal(name string, v *VMValue) {
+	if ctx.IsReadOnlyName(name) {
+		ctx.Error = &ReadOnlyError{Name: name}
+		return
+	}
 	ctx.Attrs.Store(name, v)
 }
 
 func (ctx *Context) StoreNameGlobal(name string, v *VMValue) {
+	if ctx.IsReadOnlyName(name) {
+		ctx.Error = &ReadOnlyError{Name: name}
+		return
+	}
 	storeFunc := ctx.GlobalValueStoreFunc
 	if storeFunc != nil {
 		storeFunc(name, v)
@@ -418,6 +942,37 @@ func (ctx *Context) StoreNameGlobal(name string, v *VMValue) {
 	}
 }
 
+// RegScopeResolver 注册作用域变量前缀，如 ScopeTemp，此后形如 $t临时 的变量会由resolver处理
+func (ctx *Context) RegScopeResolver(prefix string, resolver *ScopeResolver) error {
+	if prefix == "" {
+		return errors.New("作用域前缀不能为空")
+	}
+	if resolver == nil || resolver.Load == nil {
+		return errors.New("作用域解析器至少需要实现Load")
+	}
+	// 复制一份，避免影响已经派生出的子vm
+	m := map[string]*ScopeResolver{}
+	for k, v := range ctx.scopeResolvers {
+		m[k] = v
+	}
+	m[prefix] = resolver
+	ctx.scopeResolvers = m
+	return nil
+}
+
+// getScopeResolver 按最长前缀匹配作用域，返回解析器与去掉前缀后的名字
+func (ctx *Context) getScopeResolver(name string) (*ScopeResolver, string) {
+	var ret *ScopeResolver
+	prefixLen := 0
+	for prefix, r := range ctx.scopeResolvers {
+		if len(prefix) > prefixLen && len(name) > len(prefix) && strings.HasPrefix(name, prefix) {
+			ret = r
+			prefixLen = len(prefix)
+		}
+	}
+	return ret, name[prefixLen:]
+}
+
 func (ctx *Context) RegCustomDice(pattern string, handler CustomDiceHandler) error {
 	if handler == nil {
 		return errors.New("自定义骰子回调不能为空")
@@ -446,6 +1001,8 @@ func (ctx *Context) RegCustomDiceParser(parser CustomDiceParserFunc, handler Cus
 type VMValue struct {
 	TypeId VMValueType `json:"t"`
 	Value  any         `json:"v"`
+	// 宿主程序附加的标记，如在 Context.OnValueCreate 中标记值的来源。随 Clone 保留，不参与比较和序列化
+	Tag any `json:"-"`
 	// ExpiredTime int64       `json:"expiredTime"`
 }
 
@@ -460,12 +1017,51 @@ type DictData struct {
 }
 
 type ComputedData struct {
-	Expr string
+	Expr   string
+	Params []string // 带参数的computed，如 &val(x) = x * 5，需要以 val(3) 的形式调用
+	Memo   bool     // 缓存计算结果，直到调用 invalidate() 或依赖的变量发生变化
 
 	/* 缓存数据 */
 	Attrs     *ValueMap
 	code      []ByteCode
 	codeIndex int
+	memo      *computedMemo
+}
+
+// computedMemoMu 保护 ComputedData.memo 与 Attrs 的初始化。同一个computed可能被多个vm在不同的goroutine中调用
+var computedMemoMu sync.Mutex
+
+// getAttrs 获取computed的变量空间，没有时创建
+func (cd *ComputedData) getAttrs() *ValueMap {
+	computedMemoMu.Lock()
+	defer computedMemoMu.Unlock()
+	if cd.Attrs == nil {
+		cd.Attrs = &ValueMap{}
+	}
+	return cd.Attrs
+}
+
+// getMemo 读取memo()的缓存结果
+func (cd *ComputedData) getMemo() *computedMemo {
+	computedMemoMu.Lock()
+	defer computedMemoMu.Unlock()
+	return cd.memo
+}
+
+// setMemo 设置memo()的缓存结果，m为nil时使其失效
+func (cd *ComputedData) setMemo(m *computedMemo) {
+	computedMemoMu.Lock()
+	cd.memo = m
+	computedMemoMu.Unlock()
+}
+
+// computedMemo memo() 的缓存结果
+type computedMemo struct {
+	ret        *VMValue
+	detailText string
+	root       *Context            // 计算时所在的顶层vm
+	epoch      int                 // 计算时顶层vm的 memoEpoch
+	deps       map[string]*VMValue // 计算时读取的外部变量及其值
 }
 
 type FunctionData struct {
@@ -475,9 +1071,10 @@ type FunctionData struct {
 	Defaults []*VMValue
 
 	/* 缓存数据 */
-	Self      *VMValue // 若存在self，即为bound method
-	code      []ByteCode
-	codeIndex int
+	Self        *VMValue // 若存在self，即为bound method
+	code        []ByteCode
+	codeIndex   int
+	isGenerator bool // 函数体中含有yield
 	// ctx       *Context
 }
 
@@ -508,19 +1105,21 @@ func (v *VMValue) Clone() *VMValue {
 	// case VMTypeDict, VMTypeArray:
 	//	return v
 	// default:
-	return &VMValue{TypeId: v.TypeId, Value: v.Value}
+	return &VMValue{TypeId: v.TypeId, Value: v.Value, Tag: v.Tag}
 	// }
 }
 
 func (v *VMValue) AsBool() bool {
 	switch v.TypeId {
+	case VMTypeBool:
+		return v.Value.(bool)
 	case VMTypeInt:
 		return v.Value != IntType(0)
 	case VMTypeFloat:
 		return v.Value != 0.0
 	case VMTypeString:
 		return v.Value != ""
-	case VMTypeNull:
+	case VMTypeNull, VMTypeUndefined:
 		return false
 	case VMTypeComputedValue:
 		vd := v.Value.(*ComputedData)
@@ -528,18 +1127,63 @@ func (v *VMValue) AsBool() bool {
 	case VMTypeArray:
 		ad := v.MustReadArray()
 		return len(ad.List) != 0
+	case VMTypeTuple:
+		return len(v.Value.(*TupleData).List) != 0
+	case VMTypeSet:
+		return v.Value.(*SetData).Len() != 0
 	case VMTypeDict:
 		dd := v.MustReadDictData()
 		return dd.Dict.Length() != 0
-	case VMTypeFunction, VMTypeNativeFunction, VMTypeNativeObject:
+	case VMTypeFunction, VMTypeNativeFunction, VMTypeNativeObject, VMTypeIterator:
 		return true
+	case VMTypeDeck:
+		return len(v.Value.(*DeckData).Cards) != 0
+	case VMTypeOrder:
+		return len(v.Value.(*OrderData).Entries) != 0
+	case VMTypeResource:
+		return v.Value.(*ResourceData).Value != 0
+	case VMTypeTable:
+		return len(v.Value.(*TableData).Rows) != 0
+	case VMTypeQuantity:
+		return v.Value.(QuantityData).Value != 0
+	case VMTypeMoney:
+		return v.Value.(MoneyData).Amount != 0
+	case VMTypeCheck:
+		return v.Value.(*CheckData).Success
+	case VMTypeError:
+		return false
+	case VMTypeRange:
+		return v.Value.(*RangeData).Len() > 0
+	case VMTypeTime:
+		return !v.Value.(time.Time).IsZero()
+	case VMTypeDuration:
+		return v.Value.(time.Duration) != 0
 	default:
 		return false
 	}
 }
 
+// IsNullish 值为 null 或 undefined
+func (v *VMValue) IsNullish() bool {
+	return v.TypeId == VMTypeNull || v.TypeId == VMTypeUndefined
+}
+
 type recursionInfo struct {
-	exists map[interface{}]bool
+	exists   map[interface{}]bool
+	stringer ValueStringer // 见 Context.Stringer
+}
+
+// ValueStringer 自定义值在计算过程、格式化字符串等处显示的文本，如布尔值显示为成功/失败、小数使用逗号。
+// ok为false时使用默认的文本。数组等容器中的项同样会经过它
+type ValueStringer interface {
+	ValueString(v *VMValue) (text string, ok bool)
+}
+
+// ValueStringerFunc 以函数实现 ValueStringer
+type ValueStringerFunc func(v *VMValue) (string, bool)
+
+func (f ValueStringerFunc) ValueString(v *VMValue) (string, bool) {
+	return f(v)
 }
 
 func (v *VMValue) ToString() string {
@@ -547,19 +1191,34 @@ func (v *VMValue) ToString() string {
 	return v.toStringRaw(ri)
 }
 
+// ToString 与 VMValue.ToString 相同，设置了 Stringer 时优先使用其给出的文本
+func (ctx *Context) ToString(v *VMValue) string {
+	ri := &recursionInfo{exists: map[interface{}]bool{}, stringer: ctx.Stringer}
+	return v.toStringRaw(ri)
+}
+
 func (v *VMValue) toStringRaw(ri *recursionInfo) string {
 	if v == nil {
 		return "NIL"
 	}
+	if ri.stringer != nil {
+		if text, ok := ri.stringer.ValueString(v); ok {
+			return text
+		}
+	}
 	switch v.TypeId {
 	case VMTypeInt:
 		return strconv.FormatInt(int64(v.Value.(IntType)), 10)
 	case VMTypeFloat:
-		return strconv.FormatFloat(v.Value.(float64), 'f', -1, 64)
+		return formatFloat(v.Value.(float64))
+	case VMTypeBool:
+		return strconv.FormatBool(v.Value.(bool))
 	case VMTypeString:
 		return v.Value.(string)
 	case VMTypeNull:
 		return "null"
+	case VMTypeUndefined:
+		return "undefined"
 	case VMTypeArray:
 		// 避免循环重复
 		if _, exists := ri.exists[v.Value]; exists {
@@ -578,9 +1237,23 @@ func (v *VMValue) toStringRaw(ri *recursionInfo) string {
 		}
 		s += "]"
 		return s
+	case VMTypeTuple:
+		td, _ := v.ReadTuple()
+		return td.toStringRaw(ri)
+	case VMTypeSet:
+		sd, _ := v.ReadSet()
+		return sd.toStringRaw(ri)
 	case VMTypeComputedValue:
 		cd, _ := v.ReadComputed()
+		if len(cd.Params) > 0 {
+			// 同声明时的写法，如 &(x, y) = x * y
+			return "&(" + strings.Join(cd.Params, ", ") + ") = " + cd.Expr
+		}
 		return "&(" + cd.Expr + ")"
+	case VMTypeError:
+		return v.Value.(*ErrorData).String()
+	case VMTypeRange:
+		return v.Value.(*RangeData).String()
 	case VMTypeDict:
 		// 避免循环重复
 		if _, exists := ri.exists[v.Value]; exists {
@@ -600,7 +1273,7 @@ func (v *VMValue) toStringRaw(ri *recursionInfo) string {
 			// } else {
 			//	txt = value.ToRepr()
 			// }
-			items = append(items, fmt.Sprintf("'%s': %s", key, txt))
+			items = append(items, dictKeyRepr(key)+": "+txt)
 			return true
 		})
 		return "{" + strings.Join(items, ", ") + "}"
@@ -613,11 +1286,151 @@ func (v *VMValue) toStringRaw(ri *recursionInfo) string {
 	case VMTypeNativeObject:
 		od, _ := v.ReadNativeObjectData()
 		return "nobject " + od.Name
+	case VMTypeIterator:
+		id, _ := v.ReadIterator()
+		return "iterator " + id.Name
+	case VMTypeTable:
+		// 避免循环重复
+		if _, exists := ri.exists[v.Value]; exists {
+			return "[...]"
+		}
+		ri.exists[v.Value] = true
+		t, _ := v.ReadTable()
+		return t.toStringRaw(ri)
+	case VMTypeDeck:
+		d, _ := v.ReadDeck()
+		return fmt.Sprintf("deck(%d/%d)", len(d.Cards), len(d.Cards)+len(d.Drawn))
+	case VMTypeOrder:
+		return v.Value.(*OrderData).String()
+	case VMTypeResource:
+		return v.Value.(*ResourceData).String()
+	case VMTypeTime:
+		return v.Value.(time.Time).Format("2006-01-02 15:04:05")
+	case VMTypeDuration:
+		return v.Value.(time.Duration).String()
+	case VMTypeQuantity:
+		return v.Value.(QuantityData).String()
+	case VMTypeMoney:
+		return v.Value.(MoneyData).String()
+	case VMTypeCheck:
+		return v.Value.(*CheckData).Level
 	default:
 		return "a value"
 	}
 }
 
+// RoundMode 小数转为整数的方式，用于 toInt()、整数相除、金额乘除小数，以及下标、骰子个数等需要整数处的小数。
+// 不同规则的取整方式不同，如CoC多为四舍五入，DnD多为向下取整
+type RoundMode int
+
+const (
+	RoundDefault  RoundMode = iota // 沿用各处原来的方式: toInt() 和整数相除向零取整，金额四舍五入，下标等处不接受小数
+	RoundTruncate                  // 向零取整，如 -2.5 为 -2
+	RoundFloor                     // 向下取整，如 -2.5 为 -3
+	RoundHalfUp                    // 四舍五入，.5 远离零，如 2.5 为 3、-2.5 为 -3
+	RoundHalfEven                  // 银行家舍入，.5 取偶数，如 2.5 为 2、3.5 为 4
+)
+
+// roundToInt 按mode将f转为整数，mode为RoundDefault时使用def
+func roundToInt(f float64, mode RoundMode, def RoundMode) IntType {
+	if mode == RoundDefault {
+		mode = def
+	}
+	switch mode {
+	case RoundFloor:
+		f = math.Floor(f)
+	case RoundHalfUp:
+		f = math.Round(f)
+	case RoundHalfEven:
+		f = math.RoundToEven(f)
+	}
+	return IntType(f)
+}
+
+// mulInt 整数乘法，溢出时ok为false
+func mulInt(a, b IntType) (IntType, bool) {
+	if a == 0 || b == 0 {
+		return 0, true
+	}
+	c := a * b
+	if c/b != a || (a == -1 && b == math.MinInt64) || (b == -1 && a == math.MinInt64) {
+		return c, false
+	}
+	return c, true
+}
+
+// addIntSaturated 整数加法，溢出时取整数的最大值或最小值
+func addIntSaturated(a, b IntType) IntType {
+	c := a + b
+	if b > 0 && c < a {
+		return math.MaxInt64
+	}
+	if b < 0 && c > a {
+		return math.MinInt64
+	}
+	return c
+}
+
+// divideToInt 整数除法，按mode取整，mode为RoundDefault时向零取整
+func divideToInt(a, b IntType, mode RoundMode) IntType {
+	q, r := a/b, a%b
+	if r == 0 {
+		return q
+	}
+	// 结果为负数时远离零为减一
+	away := IntType(1)
+	if (r < 0) != (b < 0) {
+		away = -1
+	}
+	// 余数与除数的另一部分比较，即余数是否超过一半，不会溢出
+	rest, other := r, b
+	if rest < 0 {
+		rest = -rest
+	}
+	if other < 0 {
+		other = -other
+	}
+	other -= rest
+	switch mode {
+	case RoundFloor:
+		if away < 0 {
+			q--
+		}
+	case RoundHalfUp:
+		if rest >= other {
+			q += away
+		}
+	case RoundHalfEven:
+		if rest > other || (rest == other && q%2 != 0) {
+			q += away
+		}
+	}
+	return q
+}
+
+// intOperand 下标、骰子个数等需要整数的位置使用的值: 布尔值等按数字处理，如 (1>0)d6；
+// 设置了 RoundMode 时小数按其转为整数，否则保持原样(之后报类型错误)
+func (ctx *Context) intOperand(v *VMValue) *VMValue {
+	v = operandValue(v)
+	if v.TypeId == VMTypeFloat && ctx != nil && ctx.Config.RoundMode != RoundDefault {
+		return NewIntVal(roundToInt(v.Value.(float64), ctx.Config.RoundMode, RoundDefault))
+	}
+	return v
+}
+
+// formatFloat 浮点数的文本。整数值的小数直接按整数输出；
+// 绝对值过大或过小时使用科学计数法，如 1e+21，避免输出几百位的数字
+func formatFloat(f float64) string {
+	abs := math.Abs(f)
+	if abs < 1e15 && f == math.Trunc(f) {
+		return strconv.FormatInt(int64(f), 10)
+	}
+	if abs >= 1e21 || (abs < 1e-6 && abs != 0) {
+		return strconv.FormatFloat(f, 'g', -1, 64)
+	}
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
 func (v *VMValue) toReprRaw(ri *recursionInfo) string {
 	if v == nil {
 		return "NIL"
@@ -626,8 +1439,23 @@ func (v *VMValue) toReprRaw(ri *recursionInfo) string {
 	case VMTypeString:
 		// TODO: 检测其中是否有"
 		return "'" + v.toStringRaw(ri) + "'"
-	case VMTypeInt, VMTypeFloat, VMTypeNull, VMTypeArray, VMTypeComputedValue, VMTypeDict, VMTypeFunction, VMTypeNativeFunction, VMTypeNativeObject:
+	case VMTypeInt, VMTypeFloat, VMTypeNull, VMTypeUndefined, VMTypeArray, VMTypeComputedValue, VMTypeDict, VMTypeFunction, VMTypeNativeFunction, VMTypeNativeObject, VMTypeIterator, VMTypeDeck, VMTypeTable, VMTypeQuantity, VMTypeOrder, VMTypeResource, VMTypeTuple, VMTypeSet, VMTypeBool:
 		return v.toStringRaw(ri)
+	case VMTypeMoney:
+		// 带空格的 3gp 2sp 不能被直接读取，写作 3gp2sp
+		return strings.ReplaceAll(v.toStringRaw(ri), " ", "")
+	case VMTypeTime:
+		return "toTime('" + v.toStringRaw(ri) + "')"
+	case VMTypeDuration:
+		// 1m30.5s、1µs 等不能写作字面量，3m 也可能被注册的单位占用，因此总是使用 toDuration()
+		return "toDuration('" + v.toStringRaw(ri) + "')"
+	case VMTypeCheck:
+		return "'" + v.toStringRaw(ri) + "'"
+	case VMTypeError:
+		ed, _ := v.ReadError()
+		return "error(" + NewStrVal(ed.Message).toReprRaw(ri) + ", " + NewStrVal(ed.Code).toReprRaw(ri) + ")"
+	case VMTypeRange:
+		return v.Value.(*RangeData).String()
 	default:
 		return "<a value>"
 	}
@@ -645,6 +1473,13 @@ func (v *VMValue) ReadInt() (IntType, bool) {
 	return 0, false
 }
 
+func (v *VMValue) ReadBool() (bool, bool) {
+	if v.TypeId == VMTypeBool {
+		return v.Value.(bool), true
+	}
+	return false, false
+}
+
 func (v *VMValue) ReadFloat() (float64, bool) {
 	if v.TypeId == VMTypeFloat {
 		return v.Value.(float64), true
@@ -680,6 +1515,14 @@ func (v *VMValue) ReadDictData() (*DictData, bool) {
 	return nil, false
 }
 
+// ReadDict 以 VMDictValue 的形式读取字典，便于宿主程序用 Load、Store、Range 按字符串键读写
+func (v *VMValue) ReadDict() (*VMDictValue, bool) {
+	if v.TypeId == VMTypeDict {
+		return (*VMDictValue)(v), true
+	}
+	return nil, false
+}
+
 func (v *VMValue) MustReadDictData() *DictData {
 	if v.TypeId == VMTypeDict {
 		return v.Value.(*DictData)
@@ -694,11 +1537,15 @@ func (v *VMValue) MustReadArray() *ArrayData {
 	panic("错误: 不正确的类型")
 }
 
+// MustReadInt 读取整数，布尔值视为1或0，与比较运算的结果为整数时的用法相同。其他类型时panic
 func (v *VMValue) MustReadInt() IntType {
 	val, ok := v.ReadInt()
 	if ok {
 		return val
 	}
+	if b, ok := v.ReadBool(); ok {
+		return operandValue(NewBoolVal(b)).Value.(IntType)
+	}
 	panic("错误: 不正确的类型")
 }
 
@@ -724,6 +1571,20 @@ func (v *VMValue) ReadNativeFunctionData() (*NativeFunctionData, bool) {
 	return nil, false
 }
 
+func (v *VMValue) ReadTime() (time.Time, bool) {
+	if v.TypeId == VMTypeTime {
+		return v.Value.(time.Time), true
+	}
+	return time.Time{}, false
+}
+
+func (v *VMValue) ReadDuration() (time.Duration, bool) {
+	if v.TypeId == VMTypeDuration {
+		return v.Value.(time.Duration), true
+	}
+	return 0, false
+}
+
 func (v *VMValue) ReadNativeObjectData() (*NativeObjectData, bool) {
 	if v.TypeId == VMTypeNativeObject {
 		return v.Value.(*NativeObjectData), true
@@ -776,6 +1637,37 @@ func (v *VMValue) OpAdd(ctx *Context, v2 *VMValue) *VMValue {
 			}
 			return NewArrayVal(arrFinal...)
 		}
+	case VMTypeTuple:
+		switch v2.TypeId {
+		case VMTypeTuple:
+			td, _ := v.ReadTuple()
+			td2, _ := v2.ReadTuple()
+			if len(td.List)+len(td2.List) > 512 {
+				ctx.Error = errors.New("不能一次性创建过长的元组")
+				return nil
+			}
+			return NewTupleVal(append(append([]*VMValue(nil), td.List...), td2.List...)...)
+		}
+	case VMTypeTime:
+		switch v2.TypeId {
+		case VMTypeDuration:
+			return NewTimeVal(v.Value.(time.Time).Add(v2.Value.(time.Duration)))
+		}
+	case VMTypeDuration:
+		switch v2.TypeId {
+		case VMTypeDuration:
+			return NewDurationVal(v.Value.(time.Duration) + v2.Value.(time.Duration))
+		case VMTypeTime:
+			return NewTimeVal(v2.Value.(time.Time).Add(v.Value.(time.Duration)))
+		}
+	case VMTypeQuantity:
+		if a, b, ok := v.alignQuantity(ctx, v2); ok {
+			return NewQuantityVal(a+b, v.Value.(QuantityData).Unit)
+		}
+	case VMTypeMoney:
+		if a, b, ok := v.alignMoney(ctx, v2); ok {
+			return NewMoneyVal(a+b, v.Value.(MoneyData).System)
+		}
 	}
 
 	return nil
@@ -801,6 +1693,30 @@ func (v *VMValue) OpSub(ctx *Context, v2 *VMValue) *VMValue {
 			val := v.Value.(float64) - v2.Value.(float64)
 			return NewFloatVal(val)
 		}
+	case VMTypeTime:
+		switch v2.TypeId {
+		case VMTypeDuration:
+			return NewTimeVal(v.Value.(time.Time).Add(-v2.Value.(time.Duration)))
+		case VMTypeTime:
+			return NewDurationVal(v.Value.(time.Time).Sub(v2.Value.(time.Time)))
+		}
+	case VMTypeSet:
+		if v2.TypeId == VMTypeSet {
+			return setDifference(v.Value.(*SetData), v2.Value.(*SetData))
+		}
+	case VMTypeDuration:
+		switch v2.TypeId {
+		case VMTypeDuration:
+			return NewDurationVal(v.Value.(time.Duration) - v2.Value.(time.Duration))
+		}
+	case VMTypeQuantity:
+		if a, b, ok := v.alignQuantity(ctx, v2); ok {
+			return NewQuantityVal(a-b, v.Value.(QuantityData).Unit)
+		}
+	case VMTypeMoney:
+		if a, b, ok := v.alignMoney(ctx, v2); ok {
+			return NewMoneyVal(a-b, v.Value.(MoneyData).System)
+		}
 	}
 
 	return nil
@@ -819,6 +1735,12 @@ func (v *VMValue) OpMultiply(ctx *Context, v2 *VMValue) *VMValue {
 			return NewFloatVal(val)
 		case VMTypeArray:
 			return v2.ArrayRepeatTimesEx(ctx, v)
+		case VMTypeDuration:
+			return NewDurationVal(v2.Value.(time.Duration) * time.Duration(v.Value.(IntType)))
+		case VMTypeQuantity:
+			return v2.quantityMultiply(v)
+		case VMTypeMoney:
+			return v2.moneyMultiply(ctx, v)
 		}
 	case VMTypeFloat:
 		switch v2.TypeId {
@@ -828,9 +1750,26 @@ func (v *VMValue) OpMultiply(ctx *Context, v2 *VMValue) *VMValue {
 		case VMTypeFloat:
 			val := v.Value.(float64) * v2.Value.(float64)
 			return NewFloatVal(val)
+		case VMTypeDuration:
+			return NewDurationVal(time.Duration(float64(v2.Value.(time.Duration)) * v.Value.(float64)))
+		case VMTypeQuantity:
+			return v2.quantityMultiply(v)
+		case VMTypeMoney:
+			return v2.moneyMultiply(ctx, v)
 		}
 	case VMTypeArray:
 		return v.ArrayRepeatTimesEx(ctx, v2)
+	case VMTypeDuration:
+		switch v2.TypeId {
+		case VMTypeInt:
+			return NewDurationVal(v.Value.(time.Duration) * time.Duration(v2.Value.(IntType)))
+		case VMTypeFloat:
+			return NewDurationVal(time.Duration(float64(v.Value.(time.Duration)) * v2.Value.(float64)))
+		}
+	case VMTypeQuantity:
+		return v.quantityMultiply(v2)
+	case VMTypeMoney:
+		return v.moneyMultiply(ctx, v2)
 	}
 
 	return nil
@@ -852,8 +1791,7 @@ func (v *VMValue) OpDivide(ctx *Context, v2 *VMValue) *VMValue {
 			if v2.Value.(IntType) == 0 {
 				return setDivideZero()
 			}
-			val := v.Value.(IntType) / v2.Value.(IntType)
-			return NewIntVal(val)
+			return NewIntVal(divideToInt(v.Value.(IntType), v2.Value.(IntType), ctx.Config.RoundMode))
 		case VMTypeFloat:
 			if v2.Value.(float64) == 0 {
 				return setDivideZero()
@@ -876,6 +1814,67 @@ func (v *VMValue) OpDivide(ctx *Context, v2 *VMValue) *VMValue {
 			val := v.Value.(float64) / v2.Value.(float64)
 			return NewFloatVal(val)
 		}
+	case VMTypeDuration:
+		switch v2.TypeId {
+		case VMTypeInt:
+			if v2.Value.(IntType) == 0 {
+				return setDivideZero()
+			}
+			return NewDurationVal(v.Value.(time.Duration) / time.Duration(v2.Value.(IntType)))
+		case VMTypeFloat:
+			if v2.Value.(float64) == 0 {
+				return setDivideZero()
+			}
+			return NewDurationVal(time.Duration(float64(v.Value.(time.Duration)) / v2.Value.(float64)))
+		case VMTypeDuration:
+			if v2.Value.(time.Duration) == 0 {
+				return setDivideZero()
+			}
+			return NewFloatVal(float64(v.Value.(time.Duration)) / float64(v2.Value.(time.Duration)))
+		}
+	case VMTypeQuantity:
+		if v2.TypeId == VMTypeQuantity {
+			// 同量纲的两个量相除得到比值
+			a, b, ok := v.alignQuantity(ctx, v2)
+			if !ok {
+				return nil
+			}
+			if b == 0 {
+				return setDivideZero()
+			}
+			return NewFloatVal(a / b)
+		}
+		if n, ok := readNumber(v2); ok {
+			if n == 0 {
+				return setDivideZero()
+			}
+			q := v.Value.(QuantityData)
+			return NewQuantityVal(q.Value/n, q.Unit)
+		}
+	case VMTypeMoney:
+		m := v.Value.(MoneyData)
+		switch v2.TypeId {
+		case VMTypeInt:
+			// 不足最小面额的部分舍去，需要不损失总数时使用split()
+			if v2.Value.(IntType) == 0 {
+				return setDivideZero()
+			}
+			return NewMoneyVal(m.Amount/v2.Value.(IntType), m.System)
+		case VMTypeFloat:
+			if v2.Value.(float64) == 0 {
+				return setDivideZero()
+			}
+			return NewMoneyVal(roundToInt(float64(m.Amount)/v2.Value.(float64), ctx.Config.RoundMode, RoundHalfUp), m.System)
+		case VMTypeMoney:
+			a, b, ok := v.alignMoney(ctx, v2)
+			if !ok {
+				return nil
+			}
+			if b == 0 {
+				return setDivideZero()
+			}
+			return NewFloatVal(float64(a) / float64(b))
+		}
 	}
 
 	return nil
@@ -928,19 +1927,42 @@ func (v *VMValue) OpPower(ctx *Context, v2 *VMValue) *VMValue {
 }
 
 func (v *VMValue) OpNullCoalescing(ctx *Context, v2 *VMValue) *VMValue {
-	if v.TypeId == VMTypeNull {
+	if ctx.isMissing(v) {
 		return v2
-	} else {
-		return v
 	}
+	return v
+}
+
+// isMissing 是否为 ?? 视为缺失的值。严格模式下显式的null会被保留
+func (ctx *Context) isMissing(v *VMValue) bool {
+	if ctx != nil && ctx.Config.StrictUndefined {
+		return v.TypeId == VMTypeUndefined
+	}
+	return v.IsNullish()
+}
+
+// operandValue 布尔值和检定结果参与其他运算时视为0/1，资源视为其数值
+func operandValue(v *VMValue) *VMValue {
+	switch v.TypeId {
+	case VMTypeBool:
+		return boolToIntVal(v.Value.(bool))
+	case VMTypeCheck:
+		return boolToIntVal(v.Value.(*CheckData).Success)
+	case VMTypeResource:
+		return NewIntVal(v.Value.(*ResourceData).Value)
+	}
+	return v
 }
 
 func boolToVMValue(v bool) *VMValue {
-	var val IntType
+	return NewBoolVal(v)
+}
+
+func boolToIntVal(v bool) *VMValue {
 	if v {
-		val = 1
+		return NewIntVal(1)
 	}
-	return NewIntVal(val)
+	return NewIntVal(0)
 }
 
 func (v *VMValue) OpCompLT(ctx *Context, v2 *VMValue) *VMValue {
@@ -959,6 +1981,23 @@ func (v *VMValue) OpCompLT(ctx *Context, v2 *VMValue) *VMValue {
 		case VMTypeFloat:
 			return boolToVMValue(v.Value.(float64) < v2.Value.(float64))
 		}
+	case VMTypeTime:
+		if t2, ok := v2.ReadTime(); ok {
+			t1 := v.Value.(time.Time)
+			return boolToVMValue(t1.Before(t2))
+		}
+	case VMTypeDuration:
+		if d2, ok := v2.ReadDuration(); ok {
+			return boolToVMValue(v.Value.(time.Duration) < d2)
+		}
+	case VMTypeQuantity:
+		if a, b, ok := v.alignQuantity(ctx, v2); ok {
+			return boolToVMValue(a < b)
+		}
+	case VMTypeMoney:
+		if a, b, ok := v.alignMoney(ctx, v2); ok {
+			return boolToVMValue(a < b)
+		}
 	}
 
 	return nil
@@ -980,12 +2019,44 @@ func (v *VMValue) OpCompLE(ctx *Context, v2 *VMValue) *VMValue {
 		case VMTypeFloat:
 			return boolToVMValue(v.Value.(float64) <= v2.Value.(float64))
 		}
+	case VMTypeTime:
+		if t2, ok := v2.ReadTime(); ok {
+			t1 := v.Value.(time.Time)
+			return boolToVMValue(!t1.After(t2))
+		}
+	case VMTypeDuration:
+		if d2, ok := v2.ReadDuration(); ok {
+			return boolToVMValue(v.Value.(time.Duration) <= d2)
+		}
+	case VMTypeQuantity:
+		if a, b, ok := v.alignQuantity(ctx, v2); ok {
+			return boolToVMValue(a <= b)
+		}
+	case VMTypeMoney:
+		if a, b, ok := v.alignMoney(ctx, v2); ok {
+			return boolToVMValue(a <= b)
+		}
 	}
 
 	return nil
 }
 
 func (v *VMValue) OpCompEQ(ctx *Context, v2 *VMValue) *VMValue {
+	if ctx == nil || !ctx.Config.StrictUndefined {
+		if v.IsNullish() && v2.IsNullish() {
+			return boolToVMValue(true)
+		}
+	}
+	if v.TypeId == VMTypeQuantity && v2.TypeId == VMTypeQuantity && ctx != nil {
+		// 换算后比较，如 1km == 1000m
+		q1, q2 := v.Value.(QuantityData), v2.Value.(QuantityData)
+		b, err := ctx.Config.Units.Convert(q2.Value, q2.Unit, q1.Unit)
+		return boolToVMValue(err == nil && q1.Value == b)
+	}
+	if v.TypeId == VMTypeMoney && v2.TypeId == VMTypeMoney {
+		m1, m2 := v.Value.(MoneyData), v2.Value.(MoneyData)
+		return boolToVMValue(m1.Amount == m2.Amount && m1.System.equal(m2.System))
+	}
 	return boolToVMValue(ValueEqual(v, v2, true))
 }
 
@@ -1010,6 +2081,23 @@ func (v *VMValue) OpCompGE(ctx *Context, v2 *VMValue) *VMValue {
 		case VMTypeFloat:
 			return boolToVMValue(v.Value.(float64) >= v2.Value.(float64))
 		}
+	case VMTypeTime:
+		if t2, ok := v2.ReadTime(); ok {
+			t1 := v.Value.(time.Time)
+			return boolToVMValue(!t1.Before(t2))
+		}
+	case VMTypeDuration:
+		if d2, ok := v2.ReadDuration(); ok {
+			return boolToVMValue(v.Value.(time.Duration) >= d2)
+		}
+	case VMTypeQuantity:
+		if a, b, ok := v.alignQuantity(ctx, v2); ok {
+			return boolToVMValue(a >= b)
+		}
+	case VMTypeMoney:
+		if a, b, ok := v.alignMoney(ctx, v2); ok {
+			return boolToVMValue(a >= b)
+		}
 	}
 
 	return nil
@@ -1031,6 +2119,23 @@ func (v *VMValue) OpCompGT(ctx *Context, v2 *VMValue) *VMValue {
 		case VMTypeFloat:
 			return boolToVMValue(v.Value.(float64) > v2.Value.(float64))
 		}
+	case VMTypeTime:
+		if t2, ok := v2.ReadTime(); ok {
+			t1 := v.Value.(time.Time)
+			return boolToVMValue(t1.After(t2))
+		}
+	case VMTypeDuration:
+		if d2, ok := v2.ReadDuration(); ok {
+			return boolToVMValue(v.Value.(time.Duration) > d2)
+		}
+	case VMTypeQuantity:
+		if a, b, ok := v.alignQuantity(ctx, v2); ok {
+			return boolToVMValue(a > b)
+		}
+	case VMTypeMoney:
+		if a, b, ok := v.alignMoney(ctx, v2); ok {
+			return boolToVMValue(a > b)
+		}
 	}
 
 	return nil
@@ -1043,6 +2148,10 @@ func (v *VMValue) OpBitwiseAnd(ctx *Context, v2 *VMValue) *VMValue {
 		case VMTypeInt:
 			return NewIntVal(v.Value.(IntType) & v2.Value.(IntType))
 		}
+	case VMTypeSet:
+		if v2.TypeId == VMTypeSet {
+			return setIntersect(v.Value.(*SetData), v2.Value.(*SetData))
+		}
 	}
 	return nil
 }
@@ -1054,6 +2163,80 @@ func (v *VMValue) OpBitwiseOr(ctx *Context, v2 *VMValue) *VMValue {
 		case VMTypeInt:
 			return NewIntVal(v.Value.(IntType) | v2.Value.(IntType))
 		}
+	case VMTypeSet:
+		if v2.TypeId == VMTypeSet {
+			return setUnion(v.Value.(*SetData), v2.Value.(*SetData))
+		}
+	}
+	return nil
+}
+
+func (v *VMValue) OpBitwiseXor(ctx *Context, v2 *VMValue) *VMValue {
+	if v.TypeId == VMTypeInt && v2.TypeId == VMTypeInt {
+		return NewIntVal(v.Value.(IntType) ^ v2.Value.(IntType))
+	}
+	return nil
+}
+
+// shiftCount 位移的位数，不能为负数
+func shiftCount(ctx *Context, n IntType) (uint, bool) {
+	if n < 0 {
+		ctx.Error = errors.New("位移的位数不能为负数")
+		return 0, false
+	}
+	return uint(n), true
+}
+
+func (v *VMValue) OpShiftLeft(ctx *Context, v2 *VMValue) *VMValue {
+	if v.TypeId == VMTypeInt && v2.TypeId == VMTypeInt {
+		n, ok := shiftCount(ctx, v2.Value.(IntType))
+		if !ok {
+			return nil
+		}
+		return NewIntVal(v.Value.(IntType) << n)
+	}
+	return nil
+}
+
+func (v *VMValue) OpShiftRight(ctx *Context, v2 *VMValue) *VMValue {
+	if v.TypeId == VMTypeInt && v2.TypeId == VMTypeInt {
+		n, ok := shiftCount(ctx, v2.Value.(IntType))
+		if !ok {
+			return nil
+		}
+		return NewIntVal(v.Value.(IntType) >> n)
+	}
+	return nil
+}
+
+// OpIn v in v2，v2为数组或元组时检查其中是否有与v相等的项，为字符串时检查是否含有子串v，为字典时检查键，为集合时检查元素
+func (v *VMValue) OpIn(ctx *Context, v2 *VMValue) *VMValue {
+	switch v2.TypeId {
+	case VMTypeArray, VMTypeTuple:
+		items, _ := v2.readSequence()
+		for _, item := range items {
+			if item.OpCompEQ(ctx, v).AsBool() {
+				return NewBoolVal(true)
+			}
+		}
+		return NewBoolVal(false)
+	case VMTypeString:
+		if s, ok := v.ReadString(); ok {
+			return NewBoolVal(strings.Contains(v2.Value.(string), s))
+		}
+	case VMTypeDict:
+		key, err := (*VMDictValue)(v2).keyOf(v)
+		if err != nil {
+			ctx.Error = err
+			return nil
+		}
+		_, ok := (*VMDictValue)(v2).Load(key)
+		return NewBoolVal(ok)
+	case VMTypeSet:
+		return NewBoolVal(v2.Value.(*SetData).Has(v))
+	case VMTypeRange:
+		n, ok := v.ReadInt()
+		return NewBoolVal(ok && v2.Value.(*RangeData).Has(n))
 	}
 	return nil
 }
@@ -1064,6 +2247,12 @@ func (v *VMValue) OpPositive() *VMValue {
 		return NewIntVal(v.Value.(IntType))
 	case VMTypeFloat:
 		return NewFloatVal(v.Value.(float64))
+	case VMTypeDuration:
+		return NewDurationVal(v.Value.(time.Duration))
+	case VMTypeQuantity:
+		return NewQuantityVal(v.Value.(QuantityData).Value, v.Value.(QuantityData).Unit)
+	case VMTypeMoney:
+		return NewMoneyVal(v.Value.(MoneyData).Amount, v.Value.(MoneyData).System)
 	}
 	return nil
 }
@@ -1074,6 +2263,12 @@ func (v *VMValue) OpNegation() *VMValue {
 		return NewIntVal(-v.Value.(IntType))
 	case VMTypeFloat:
 		return NewFloatVal(-v.Value.(float64))
+	case VMTypeDuration:
+		return NewDurationVal(-v.Value.(time.Duration))
+	case VMTypeQuantity:
+		return NewQuantityVal(-v.Value.(QuantityData).Value, v.Value.(QuantityData).Unit)
+	case VMTypeMoney:
+		return NewMoneyVal(-v.Value.(MoneyData).Amount, v.Value.(MoneyData).System)
 	}
 	return nil
 }
@@ -1110,7 +2305,7 @@ func (v *VMValue) AttrGet(ctx *Context, name string) *VMValue {
 			ret, _ = cd.Attrs.Load(name)
 		}
 		if ret == nil {
-			ret = NewNullVal()
+			ret = ctx.newMissingVal()
 		}
 		return ret
 	case VMTypeDict:
@@ -1147,15 +2342,30 @@ func (v *VMValue) AttrGet(ctx *Context, name string) *VMValue {
 		// 加载全局变量
 		ret := ctx.LoadNameGlobal(name, false)
 		if ret == nil {
-			ret = NewNullVal()
+			ret = ctx.newMissingVal()
 		}
 		return ret
 	case vmTypeLocal:
 		ret := ctx.LoadNameLocal(name, false)
 		if ret == nil {
-			ret = NewNullVal()
+			ret = ctx.newMissingVal()
 		}
 		return ret
+	case VMTypeCheck:
+		cd, _ := v.ReadCheck()
+		if ret := cd.attrGet(name); ret != nil {
+			return ret
+		}
+	case VMTypeError:
+		ed, _ := v.ReadError()
+		if ret := ed.attrGet(name); ret != nil {
+			return ret
+		}
+	case VMTypeRange:
+		rd, _ := v.ReadRange()
+		if ret := rd.attrGet(name); ret != nil {
+			return ret
+		}
 	case VMTypeNativeObject:
 		od, _ := v.ReadNativeObjectData()
 		ret := od.AttrGet(ctx, name)
@@ -1174,14 +2384,19 @@ func (v *VMValue) AttrGet(ctx *Context, name string) *VMValue {
 	// 给少数几个类明确设定为不支持，返回nil
 	// 其他一律返回 undefined
 	switch v.TypeId {
-	case VMTypeInt, VMTypeFloat, VMTypeString, VMTypeNull:
+	case VMTypeInt, VMTypeFloat, VMTypeString, VMTypeNull, VMTypeUndefined:
 		return nil
 	}
 
-	return NewNullVal()
+	return ctx.newMissingVal()
 }
 
 func (v *VMValue) ItemGet(ctx *Context, index *VMValue) *VMValue {
+	switch v.TypeId {
+	case VMTypeArray, VMTypeTuple, VMTypeString:
+		// 数字下标，如 [1, 2][1 > 0]
+		index = ctx.intOperand(index)
+	}
 	switch v.TypeId {
 	case VMTypeArray:
 		if index.TypeId != VMTypeInt {
@@ -1189,13 +2404,30 @@ func (v *VMValue) ItemGet(ctx *Context, index *VMValue) *VMValue {
 		} else {
 			return v.ArrayItemGet(ctx, index.MustReadInt())
 		}
+	case VMTypeTuple:
+		if index.TypeId != VMTypeInt {
+			ctx.Error = fmt.Errorf("类型错误: 数字下标必须为数字，不能为 %s", index.GetTypeName())
+		} else {
+			td, _ := v.ReadTuple()
+			i := getRealIndex(ctx, index.MustReadInt(), IntType(len(td.List)))
+			if ctx.Error != nil {
+				return nil
+			}
+			return td.List[i]
+		}
 	case VMTypeDict:
-		if key, err := index.AsDictKey(); err != nil {
+		if key, err := (*VMDictValue)(v).keyOf(index); err != nil {
 			ctx.Error = err
 		} else {
 			val, _ := (*VMDictValue)(v).Load(key)
 			return val
 		}
+	case VMTypeTable:
+		// 按行取，得到该行的数组
+		t, _ := v.ReadTable()
+		if i, ok := t.rowIndex(ctx, index); ok {
+			return NewArrayValRaw(t.Rows[i])
+		}
 	case VMTypeString:
 		if index.TypeId != VMTypeInt {
 			ctx.Error = fmt.Errorf("类型错误: 数字下标必须为数字，不能为 %s", index.GetTypeName())
@@ -1213,7 +2445,7 @@ func (v *VMValue) ItemGet(ctx *Context, index *VMValue) *VMValue {
 		od, _ := v.ReadNativeObjectData()
 		ret := od.ItemGet(ctx, index)
 		if ret == nil {
-			ret = NewNullVal()
+			ret = ctx.newMissingVal()
 		}
 		return ret
 	default:
@@ -1224,6 +2456,9 @@ func (v *VMValue) ItemGet(ctx *Context, index *VMValue) *VMValue {
 }
 
 func (v *VMValue) ItemSet(ctx *Context, index *VMValue, val *VMValue) bool {
+	if v.TypeId == VMTypeArray {
+		index = ctx.intOperand(index)
+	}
 	switch v.TypeId {
 	case VMTypeArray:
 		if index.TypeId != VMTypeInt {
@@ -1232,7 +2467,7 @@ func (v *VMValue) ItemSet(ctx *Context, index *VMValue, val *VMValue) bool {
 			return v.ArrayItemSet(ctx, index.MustReadInt(), val)
 		}
 	case VMTypeDict:
-		if key, err := index.AsDictKey(); err != nil {
+		if key, err := (*VMDictValue)(v).keyOf(index); err != nil {
 			ctx.Error = err
 		} else {
 			(*VMDictValue)(v).Store(key, val)
@@ -1244,6 +2479,8 @@ func (v *VMValue) ItemSet(ctx *Context, index *VMValue, val *VMValue) bool {
 		if ctx.Error == nil {
 			return true
 		}
+	case VMTypeTuple:
+		ctx.Error = errTupleImmutable
 	default:
 		ctx.Error = errors.New("此类型无法赋值下标")
 	}
@@ -1298,6 +2535,9 @@ func (v *VMValue) GetSlice(ctx *Context, a IntType, b IntType, step IntType) *VM
 		arr, _ := v.ReadArray()
 		newArr := arr.List[_a:_b]
 		return NewArrayVal(newArr...)
+	case VMTypeTuple:
+		td, _ := v.ReadTuple()
+		return NewTupleVal(td.List[_a:_b]...)
 	default:
 		ctx.Error = errors.New("这个类型无法取得分片")
 		return nil
@@ -1311,12 +2551,21 @@ func (v *VMValue) Length(ctx *Context) IntType {
 	case VMTypeArray:
 		arr, _ := v.ReadArray()
 		length = IntType(len(arr.List))
+	case VMTypeTuple:
+		td, _ := v.ReadTuple()
+		length = IntType(len(td.List))
+	case VMTypeSet:
+		sd, _ := v.ReadSet()
+		length = IntType(sd.Len())
 	case VMTypeDict:
 		d := v.MustReadDictData()
 		length = IntType(d.Dict.Length())
 	case VMTypeString:
 		str, _ := v.ReadString()
 		length = IntType(len([]rune(str)))
+	case VMTypeRange:
+		rd, _ := v.ReadRange()
+		length = rd.Len()
 	default:
 		ctx.Error = errors.New("这个类型无法取得长度")
 		return 0
@@ -1339,13 +2588,13 @@ func (v *VMValue) GetSliceEx(ctx *Context, a *VMValue, b *VMValue) *VMValue {
 		b = NewIntVal(length)
 	}
 
-	valA, ok := a.ReadInt()
+	valA, ok := ctx.intOperand(a).ReadInt()
 	if !ok {
 		ctx.Error = errors.New("第一个值类型错误")
 		return nil
 	}
 
-	valB, ok := b.ReadInt()
+	valB, ok := ctx.intOperand(b).ReadInt()
 	if !ok {
 		ctx.Error = errors.New("第二个值类型错误")
 		return nil
@@ -1450,50 +2699,191 @@ func (v *VMValue) GetTypeName() string {
 		return "int"
 	case VMTypeFloat:
 		return "float"
+	case VMTypeBool:
+		return "bool"
 	case VMTypeString:
 		return "str"
 	case VMTypeNull:
 		return "null"
+	case VMTypeUndefined:
+		return "undefined"
 	case VMTypeComputedValue:
 		return "computed"
 	case VMTypeArray:
 		return "array"
+	case VMTypeTuple:
+		return "tuple"
+	case VMTypeSet:
+		return "set"
 	case VMTypeFunction:
 		return "function"
 	case VMTypeNativeFunction:
 		return "nfunction"
 	case VMTypeNativeObject:
 		return "nobject"
+	case VMTypeTime:
+		return "time"
+	case VMTypeDuration:
+		return "duration"
+	case VMTypeIterator:
+		return "iterator"
+	case VMTypeDeck:
+		return "deck"
+	case VMTypeOrder:
+		return "order"
+	case VMTypeResource:
+		return "resource"
+	case VMTypeTable:
+		return "table"
+	case VMTypeQuantity:
+		return "quantity"
+	case VMTypeMoney:
+		return "money"
+	case VMTypeCheck:
+		return "check"
+	case VMTypeError:
+		return "error"
+	case VMTypeRange:
+		return "range"
 	}
 	return "unknown"
 }
 
+// isAutoComputed 读取时是否自动求值，带参数的computed需要调用后才求值
+func (v *VMValue) isAutoComputed() bool {
+	if cd, ok := v.ReadComputed(); ok {
+		return len(cd.Params) == 0
+	}
+	return false
+}
+
 func (v *VMValue) ComputedExecute(ctx *Context, detail *BufferSpan) *VMValue {
+	return v.ComputedInvoke(ctx, nil, detail)
+}
+
+// ComputedInvoke 以参数调用computed，参数仅在本次执行中有效
+func (v *VMValue) ComputedInvoke(ctx *Context, params []*VMValue, detail *BufferSpan) *VMValue {
 	cd, _ := v.ReadComputed()
+	return cd.invoke(ctx, ctx.newComputedVM(), params, detail)
+}
 
+// newComputedVM 执行computed的子vm，继承ctx的回调、随机源等设置。可以依次执行多个computed，见 eval_all()
+func (ctx *Context) newComputedVM() *Context {
 	vm := NewVM()
 	vm.Config = ctx.Config
-	if cd.Attrs == nil {
-		cd.Attrs = &ValueMap{}
-	}
-	vm.Attrs = cd.Attrs
-
 	vm.GlobalValueStoreFunc = ctx.GlobalValueStoreFunc
 	vm.GlobalValueLoadFunc = ctx.GlobalValueLoadFunc
+	vm.GlobalValueExistsFunc = ctx.GlobalValueExistsFunc
 	vm.GlobalValueLoadOverwriteFunc = ctx.GlobalValueLoadOverwriteFunc
+	vm.GlobalValueBatchLoadFunc = ctx.GlobalValueBatchLoadFunc
+	vm.GlobalValuePrefetchFunc = ctx.GlobalValuePrefetchFunc
+	vm.prefetch = ctx.prefetch
+	vm.GlobalValueDeleteFunc = ctx.GlobalValueDeleteFunc
+	vm.scopeResolvers = ctx.scopeResolvers
+	vm.builtins = ctx.builtins
+	vm.capTags = ctx.capTags
+	vm.mocks = ctx.mocks
+	vm.OnValueCreate = ctx.OnValueCreate
+	vm.Stringer = ctx.Stringer
+	vm.profiler = ctx.profiler
 	vm.subThreadDepth = ctx.subThreadDepth + 1
 	vm.UpCtx = ctx
-	vm.NumOpCount = ctx.NumOpCount + 100
-	ctx.NumOpCount = vm.NumOpCount // 防止无限递归
 	vm.RandSrc = ctx.RandSrc
 	vm.forceSolveDetail = true
 	vm.CustomFlag = ctx.CustomFlag
+	return vm
+}
+
+// compile 解析computed的代码，已解析过时不做任何事
+func (cd *ComputedData) compile(ctx *Context) error {
+	if cd.code != nil {
+		return nil
+	}
+	vm := ctx.newComputedVM()
+	if err := vm.Parse(cd.Expr); err != nil {
+		return err
+	}
+	cd.code = vm.code
+	cd.codeIndex = vm.codeIndex
+	return nil
+}
+
+// invoke 在vm中执行computed，vm由 newComputedVM 创建
+func (cd *ComputedData) invoke(ctx *Context, vm *Context, params []*VMValue, detail *BufferSpan) *VMValue {
+	vm.Error = nil
+	vm.Attrs = cd.getAttrs()
+
+	// 设置参数
+	if len(cd.Params) != len(params) {
+		ctx.Error = fmt.Errorf("调用参数个数与函数定义不符，需求%d，传入%d", len(cd.Params), len(params))
+		return nil
+	}
+	useMemo := cd.Memo && len(params) == 0
+	if m := cd.getMemo(); useMemo && m != nil && ctx.memoValid(m) {
+		// 外层正在计算的memo同样依赖于这些变量
+		for name := range m.deps {
+			ctx.recordMemoDep(name)
+		}
+		if detail != nil {
+			detail.Tag = "load.computed"
+			detail.Text = m.detailText
+		}
+		return m.ret.Clone()
+	}
+	var memoDeps map[string]*VMValue
+	if useMemo {
+		memoDeps = map[string]*VMValue{}
+		root := ctx.rootCtx()
+		root.memoStack = append(root.memoStack, memoDeps)
+		defer func() {
+			root.memoStack = root.memoStack[:len(root.memoStack)-1]
+		}()
+	}
+
+	if len(params) > 0 {
+		// 参数放在本次执行独有的变量空间中，不修改共用的 cd.Attrs，因此可以递归或并发调用。
+		// 执行中对 this.x 等的修改在执行完毕后写回
+		local := &ValueMap{}
+		olds := map[string]*VMValue{}
+		cd.Attrs.Range(func(key string, value *VMValue) bool {
+			local.Store(key, value)
+			olds[key] = value
+			return true
+		})
+		isParam := map[string]bool{}
+		for index, i := range cd.Params {
+			local.Store(i, params[index])
+			isParam[i] = true
+		}
+		vm.Attrs = local
+		defer func() {
+			local.Range(func(key string, value *VMValue) bool {
+				if !isParam[key] && olds[key] != value {
+					cd.Attrs.Store(key, value)
+				}
+				return true
+			})
+			for key := range olds {
+				if _, ok := local.Load(key); !ok && !isParam[key] {
+					cd.Attrs.Delete(key)
+				}
+			}
+		}()
+	}
+
+	vm.NumOpCount = ctx.NumOpCount + 100
+	ctx.NumOpCount = vm.NumOpCount // 防止无限递归
 	if ctx.Config.OpCountLimit > 0 && vm.NumOpCount > vm.Config.OpCountLimit {
-		vm.Error = errors.New("允许算力上限")
+		vm.Error = errOpCountLimit
 		ctx.Error = vm.Error
 		return nil
 	}
 
+	if p := ctx.profiler; p != nil {
+		p.enter(computedFrameName(cd.Expr))
+		defer p.exit()
+	}
+
 	if cd.code == nil {
 		_ = vm.Run(cd.Expr)
 		cd.code = vm.code
@@ -1501,6 +2891,9 @@ func (v *VMValue) ComputedExecute(ctx *Context, detail *BufferSpan) *VMValue {
 	} else {
 		vm.code = cd.code
 		vm.codeIndex = cd.codeIndex
+		// 兼容: 如果没有parser填充一个避免报错(例如默认骰子面数和detail都需要读取原文)，不过会占用一些额外的内存
+		vm.parser = &parser{data: []byte(cd.Expr)}
+		vm.parser.pt.offset = len(vm.parser.data)
 		vm.evaluate()
 	}
 
@@ -1513,11 +2906,6 @@ func (v *VMValue) ComputedExecute(ctx *Context, detail *BufferSpan) *VMValue {
 	var detailText string
 	if vm.top != 0 {
 		ret = vm.stack[vm.top-1].Clone()
-		if vm.parser == nil {
-			// 兼容: 如果没有parser填充一个避免报错，不过会占用一些额外的内存
-			vm.parser = &parser{data: []byte(cd.Expr)}
-			vm.parser.pt.offset = len(vm.parser.data)
-		}
 		detailText = vm.makeDetailStr(vm.DetailSpans)
 	} else {
 		ret = NewNullVal()
@@ -1526,6 +2914,11 @@ func (v *VMValue) ComputedExecute(ctx *Context, detail *BufferSpan) *VMValue {
 	ctx.NumOpCount = vm.NumOpCount
 	ctx.IsComputedLoaded = true
 
+	if useMemo {
+		root := ctx.rootCtx()
+		cd.setMemo(&computedMemo{ret: ret.Clone(), detailText: detailText, root: root, epoch: root.memoEpoch, deps: memoDeps})
+	}
+
 	if detail != nil {
 		// detail.Expr = cd.Expr
 		detail.Tag = "load.computed"
@@ -1565,7 +2958,19 @@ func (v *VMValue) FuncInvokeRaw(ctx *Context, params []*VMValue, useUpCtxLocal b
 	// vm.Config.PrintBytecode = false
 	vm.GlobalValueStoreFunc = ctx.GlobalValueStoreFunc
 	vm.GlobalValueLoadFunc = ctx.GlobalValueLoadFunc
+	vm.GlobalValueExistsFunc = ctx.GlobalValueExistsFunc
 	vm.GlobalValueLoadOverwriteFunc = ctx.GlobalValueLoadOverwriteFunc
+	vm.GlobalValueBatchLoadFunc = ctx.GlobalValueBatchLoadFunc
+	vm.GlobalValuePrefetchFunc = ctx.GlobalValuePrefetchFunc
+	vm.prefetch = ctx.prefetch
+	vm.GlobalValueDeleteFunc = ctx.GlobalValueDeleteFunc
+	vm.scopeResolvers = ctx.scopeResolvers
+	vm.builtins = ctx.builtins
+	vm.capTags = ctx.capTags
+	vm.mocks = ctx.mocks
+	vm.OnValueCreate = ctx.OnValueCreate
+	vm.Stringer = ctx.Stringer
+	vm.profiler = ctx.profiler
 	vm.subThreadDepth = ctx.subThreadDepth + 1
 	vm.UpCtx = ctx
 	vm.NumOpCount = ctx.NumOpCount + 100 // 递归视为消耗 + 100
@@ -1573,18 +2978,33 @@ func (v *VMValue) FuncInvokeRaw(ctx *Context, params []*VMValue, useUpCtxLocal b
 	vm.RandSrc = ctx.RandSrc
 	vm.CustomFlag = ctx.CustomFlag
 	if ctx.Config.OpCountLimit > 0 && vm.NumOpCount > vm.Config.OpCountLimit {
-		vm.Error = errors.New("允许算力上限")
+		vm.Error = errOpCountLimit
 		ctx.Error = vm.Error
 		return nil
 	}
 
 	if cd.code == nil {
-		_ = vm.Run(cd.Expr)
-		cd.code = vm.code
-		cd.codeIndex = vm.codeIndex
+		if vm.Parse(cd.Expr) == nil {
+			cd.code = vm.code
+			cd.codeIndex = vm.codeIndex
+			// 匿名函数(如RunExpr)不作为生成器，其中的yield按顶层处理
+			cd.isGenerator = cd.Name != "" && containsYield(cd.code[:cd.codeIndex])
+		}
 	} else {
 		vm.code = cd.code
 		vm.codeIndex = cd.codeIndex
+		vm.parser = &parser{data: []byte(cd.Expr)}
+		vm.parser.pt.offset = len(vm.parser.data)
+	}
+	if p := ctx.profiler; p != nil {
+		p.enter(funcFrameName(cd.Name))
+		defer p.exit()
+	}
+	if vm.Error == nil {
+		if cd.isGenerator {
+			// 生成器函数调用时不执行，而是返回一个迭代器
+			return newGeneratorVal(cd.Name, vm)
+		}
 		vm.evaluate()
 	}
 
@@ -1627,7 +3047,16 @@ func (v *VMValue) FuncInvokeNative(ctx *Context, params []*VMValue) *VMValue {
 		ctx.Error = fmt.Errorf("调用参数个数与函数定义不符，需求%d，传入%d", len(cd.Params), len(params))
 		return nil
 	}
+	if !ctx.checkCapabilities(cd.Name) {
+		return nil
+	}
+	if p := ctx.profiler; p != nil {
+		p.enter(cd.Name)
+	}
 	ret := cd.NativeFunc(ctx, cd.Self, params)
+	if p := ctx.profiler; p != nil {
+		p.exit()
+	}
 
 	if ctx.Error != nil {
 		return nil
@@ -1636,17 +3065,59 @@ func (v *VMValue) FuncInvokeNative(ctx *Context, params []*VMValue) *VMValue {
 	if ret == nil {
 		ret = NewNullVal()
 	}
+	if !vmValueIn(ret, cd.Self) && !vmValueIn(ret, params...) {
+		ctx.valueCreated(ret)
+	}
 	return ret
 }
 
-func (v *VMValue) AsDictKey() (string, error) {
-	if v.TypeId == VMTypeString || v.TypeId == VMTypeInt || v.TypeId == VMTypeFloat {
-		return v.ToString(), nil
-	} else {
-		return "", fmt.Errorf("类型错误: 字典键只能为字符串或数字，不支持 %s", v.GetTypeName())
+// valueCreated 产生新值时调用 OnValueCreate
+func (ctx *Context) valueCreated(v *VMValue) {
+	if ctx.OnValueCreate != nil {
+		ctx.OnValueCreate(v)
 	}
 }
 
+// vmValueIn 指针v是否为items中的一项，或其中的数组、元组、字典、集合直接包含的一项，如 Array.find 返回的项。
+// 用于区分原生函数返回的是新值还是已有的值
+func vmValueIn(v *VMValue, items ...*VMValue) bool {
+	for _, item := range items {
+		if item == nil {
+			continue
+		}
+		if item == v {
+			return true
+		}
+		found := false
+		var list []*VMValue
+		switch item.TypeId {
+		case VMTypeArray:
+			ad, _ := item.ReadArray()
+			list = ad.List
+		case VMTypeTuple:
+			list = item.Value.(*TupleData).List
+		case VMTypeDict:
+			dd, _ := item.ReadDictData()
+			dd.Dict.Range(func(_ string, value *VMValue) bool {
+				found = value == v
+				return !found
+			})
+		case VMTypeSet:
+			item.Value.(*SetData).Items.Range(func(_ string, value *VMValue) bool {
+				found = value == v
+				return !found
+			})
+		}
+		for _, x := range list {
+			found = found || x == v
+		}
+		if found {
+			return true
+		}
+	}
+	return false
+}
+
 func ValueEqual(a *VMValue, b *VMValue, autoConvert bool) bool {
 	if a == b {
 		return true
@@ -1657,14 +3128,14 @@ func ValueEqual(a *VMValue, b *VMValue, autoConvert bool) bool {
 
 	if a.TypeId == b.TypeId {
 		switch a.TypeId {
-		case VMTypeArray:
-			arr1, _ := a.ReadArray()
-			arr2, _ := b.ReadArray()
-			if len(arr1.List) != len(arr2.List) {
+		case VMTypeArray, VMTypeTuple:
+			arr1, _ := a.readSequence()
+			arr2, _ := b.readSequence()
+			if len(arr1) != len(arr2) {
 				return false
 			}
-			for index, i := range arr1.List {
-				if !ValueEqual(i, arr2.List[index], autoConvert) {
+			for index, i := range arr1 {
+				if !ValueEqual(i, arr2[index], autoConvert) {
 					return false
 				}
 			}
@@ -1672,7 +3143,7 @@ func ValueEqual(a *VMValue, b *VMValue, autoConvert bool) bool {
 		case VMTypeDict:
 			d1 := a.MustReadDictData()
 			d2 := b.MustReadDictData()
-			if len(d1.Dict.dirty) != len(d2.Dict.dirty) {
+			if d1.Dict.Length() != d2.Dict.Length() {
 				return false
 			}
 			isSame := true
@@ -1685,19 +3156,31 @@ func ValueEqual(a *VMValue, b *VMValue, autoConvert bool) bool {
 				return true
 			})
 			return isSame
+		case VMTypeSet:
+			return setEqual(a.Value.(*SetData), b.Value.(*SetData))
 		case VMTypeComputedValue:
 			c1, _ := a.ReadComputed()
 			c2, _ := b.ReadComputed()
-			return c1.Expr == c2.Expr
+			return c1.Expr == c2.Expr && strings.Join(c1.Params, ",") == strings.Join(c2.Params, ",")
 		case VMTypeNativeFunction:
 			fd1, _ := a.ReadNativeFunctionData()
 			fd2, _ := b.ReadNativeFunctionData()
 			return reflect.ValueOf(fd1.NativeFunc).Pointer() == reflect.ValueOf(fd2.NativeFunc).Pointer()
+		case VMTypeTime:
+			return a.Value.(time.Time).Equal(b.Value.(time.Time))
+		case VMTypeError:
+			return *a.Value.(*ErrorData) == *b.Value.(*ErrorData)
+		case VMTypeRange:
+			return *a.Value.(*RangeData) == *b.Value.(*RangeData)
 		default:
 			return a.Value == b.Value
 		}
 	} else {
 		if autoConvert {
+			if a.TypeId == VMTypeBool || b.TypeId == VMTypeBool {
+				// 布尔值视为0/1
+				return ValueEqual(operandValue(a), operandValue(b), true)
+			}
 			switch a.TypeId {
 			case VMTypeInt:
 				switch b.TypeId {
@@ -1720,6 +3203,10 @@ func NewIntVal(i IntType) *VMValue {
 	return &VMValue{TypeId: VMTypeInt, Value: i}
 }
 
+func NewBoolVal(b bool) *VMValue {
+	return &VMValue{TypeId: VMTypeBool, Value: b}
+}
+
 func NewFloatVal(i float64) *VMValue {
 	return &VMValue{TypeId: VMTypeFloat, Value: i}
 }
@@ -1740,6 +3227,18 @@ func NewNullVal() *VMValue {
 	return &VMValue{TypeId: VMTypeNull}
 }
 
+func NewUndefinedVal() *VMValue {
+	return &VMValue{TypeId: VMTypeUndefined}
+}
+
+func NewTimeVal(t time.Time) *VMValue {
+	return &VMValue{TypeId: VMTypeTime, Value: t}
+}
+
+func NewDurationVal(d time.Duration) *VMValue {
+	return &VMValue{TypeId: VMTypeDuration, Value: d}
+}
+
 func NewArrayValRaw(data []*VMValue) *VMValue {
 	return &VMValue{TypeId: VMTypeArray, Value: &ArrayData{data}}
 }
@@ -1764,7 +3263,7 @@ func NewDictValWithArray(arr ...*VMValue) (*VMDictValue, error) {
 		if err != nil {
 			return nil, err
 		}
-		data.Store(kName, arr[i+1])
+		data.Store(dictResolveKey(data, kName), arr[i+1])
 	}
 	return &VMDictValue{TypeId: VMTypeDict, Value: &DictData{data}}, nil
 }
@@ -0,0 +1,74 @@
+package dicescript
+
+// CheckData 检定结果，由开启了 RuleSet.RichCheck 的数值比较产生
+type CheckData struct {
+	Success bool
+	Op      string   // 比较算符
+	Value   *VMValue // 左侧的值，通常为骰点
+	Target  *VMValue // 右侧的值，通常为技能值
+	Level   string   // 成功等级
+}
+
+func NewCheckVal(cd *CheckData) *VMValue {
+	return &VMValue{TypeId: VMTypeCheck, Value: cd}
+}
+
+func (v *VMValue) ReadCheck() (*CheckData, bool) {
+	if v.TypeId == VMTypeCheck {
+		return v.Value.(*CheckData), true
+	}
+	return nil, false
+}
+
+// Margin 成功的幅度，失败时为负数。如 d100=30 <= 50 时为20，d20=8 >= 12 时为-4
+func (cd *CheckData) Margin() *VMValue {
+	var ret *VMValue
+	switch cd.Op {
+	case "<", "<=":
+		ret = cd.Target.OpSub(nil, cd.Value)
+	default:
+		ret = cd.Value.OpSub(nil, cd.Target)
+	}
+	if ret == nil {
+		return NewIntVal(0)
+	}
+	return ret
+}
+
+func (cd *CheckData) attrGet(name string) *VMValue {
+	switch name {
+	case "success":
+		return boolToVMValue(cd.Success)
+	case "margin":
+		return cd.Margin()
+	case "level":
+		return NewStrVal(cd.Level)
+	case "value":
+		return cd.Value
+	case "target":
+		return cd.Target
+	}
+	return nil
+}
+
+// makeCheck 按规则集将比较的结果包装为检定结果，只处理数字之间的比较
+func (ctx *Context) makeCheck(op string, v1 *VMValue, v2 *VMValue, ret *VMValue) *VMValue {
+	a, ok1 := readNumber(v1)
+	b, ok2 := readNumber(v2)
+	if !ok1 || !ok2 {
+		return ret
+	}
+	// v1、v2 可能指向栈上的位置，需要复制
+	cd := &CheckData{Success: ret.AsBool(), Op: op, Value: v1.Clone(), Target: v2.Clone()}
+	if f := ctx.Config.RuleSet.CheckLevel; f != nil {
+		cd.Level = f(ctx, op, a, b, cd.Success)
+	}
+	if cd.Level == "" {
+		if cd.Success {
+			cd.Level = "成功"
+		} else {
+			cd.Level = "失败"
+		}
+	}
+	return NewCheckVal(cd)
+}
@@ -0,0 +1,77 @@
+package dicescript
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckResult(t *testing.T) {
+	// 未设置规则集时仍为普通的布尔值
+	vm := NewVM()
+	err := vm.Run("30 <= 50")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, nb(true)))
+	}
+
+	vm = NewVM()
+	vm.Config.RuleSet = &RuleSet{RichCheck: true}
+	err = vm.Run("r = 30 <= 50; [r.success, r.margin, r.level, r.value, r.target]")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "[true, 20, '成功', 30, 50]", vm.Ret.ToString())
+	}
+
+	vm = NewVM()
+	vm.Config.RuleSet = &RuleSet{RichCheck: true}
+	err = vm.Run("r = 8 >= 12; `{r.level} {r.margin}`")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "失败 -4", vm.Ret.ToString())
+	}
+
+	// 仍可当作真假及0/1使用
+	vm = NewVM()
+	vm.Config.RuleSet = &RuleSet{RichCheck: true}
+	err = vm.Run("[(3 < 5) ? 'a' : 'b', (3 > 5) + 1, (3 < 5) == 1, 1 < 2 < 3]")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "['a', 1, true, '成功']", vm.Ret.ToString())
+	}
+
+	vm = NewVM()
+	vm.Config.RuleSet = RuleSetCoC
+	err = vm.Run("[1 <= 40, 8 <= 40, 20 <= 40, 40 <= 40, 41 <= 40, 97 <= 40, 97 <= 60, 100 <= 60]")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "['大成功', '极难成功', '困难成功', '成功', '失败', '大失败', '失败', '大失败']", vm.Ret.ToString())
+	}
+
+	vm = NewVM()
+	vm.Config.RuleSet = &RuleSet{
+		RichCheck: true,
+		CheckLevel: func(ctx *Context, op string, value float64, target float64, success bool) string {
+			if success && value-target >= 10 {
+				return "大胜"
+			}
+			return ""
+		},
+	}
+	err = vm.Run("[25 >= 15, 16 >= 15, 1 == 1]")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "['大胜', '成功', true]", vm.Ret.ToString())
+	}
+}
+
+func TestCheckResultJSON(t *testing.T) {
+	v := NewCheckVal(&CheckData{Success: true, Op: "<=", Value: ni(30), Target: ni(50), Level: "困难成功"})
+	data, err := v.ToJSON()
+	if assert.NoError(t, err) {
+		assert.Equal(t, `{"t":18,"v":{"success":true,"op":"\u003c=","value":{"t":0,"v":30},"target":{"t":0,"v":50},"level":"困难成功"}}`, string(data))
+	}
+	v2, err := VMValueFromJSON(data)
+	if assert.NoError(t, err) {
+		cd, ok := v2.ReadCheck()
+		if assert.True(t, ok) {
+			assert.True(t, cd.Success)
+			assert.Equal(t, "困难成功", cd.Level)
+			assert.True(t, valueEqual(cd.Margin(), ni(20)))
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package dicescript
+
+import (
+	"errors"
+	"fmt"
+)
+
+// DeckData 牌堆，抽出的牌不放回，直到reset
+type DeckData struct {
+	Cards []*VMValue // 剩余的牌，首项为牌堆顶
+	Drawn []*VMValue // 已抽出的牌，按抽出顺序排列
+}
+
+func NewDeckVal(cards []*VMValue) *VMValue {
+	return &VMValue{TypeId: VMTypeDeck, Value: &DeckData{Cards: cards}}
+}
+
+func (v *VMValue) ReadDeck() (*DeckData, bool) {
+	if v.TypeId == VMTypeDeck {
+		return v.Value.(*DeckData), true
+	}
+	return nil, false
+}
+
+// Shuffle 洗乱剩余的牌
+func (d *DeckData) Shuffle(ctx *Context) {
+	lst := d.Cards
+	for i := len(lst) - 1; i > 0; i-- { // Fisher–Yates shuffle
+		j := Roll(ctx.RandSrc, IntType(i+1), 0) - 1
+		lst[i], lst[j] = lst[j], lst[i]
+	}
+}
+
+// Draw 从牌堆顶抽出num张牌，剩余的牌不足时返回错误
+func (d *DeckData) Draw(num int) ([]*VMValue, error) {
+	if num < 0 {
+		return nil, errors.New("抽牌数量不能为负数")
+	}
+	if num > len(d.Cards) {
+		return nil, fmt.Errorf("牌堆中只剩%d张牌，无法抽出%d张", len(d.Cards), num)
+	}
+	ret := make([]*VMValue, num)
+	copy(ret, d.Cards[:num])
+	d.Cards = d.Cards[num:]
+	d.Drawn = append(d.Drawn, ret...)
+	return ret, nil
+}
+
+// Reset 将抽出的牌放回牌堆，并重新洗牌
+func (d *DeckData) Reset(ctx *Context) {
+	d.Cards = append(d.Drawn, d.Cards...)
+	d.Drawn = nil
+	d.Shuffle(ctx)
+}
+
+func funcDeck(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
+	lst := ctx.iterToList(params[0])
+	if ctx.Error != nil {
+		return nil
+	}
+	v := NewDeckVal(lst)
+	if params[1].AsBool() {
+		v.Value.(*DeckData).Shuffle(ctx)
+	}
+	return v
+}
+
+func funcDeckDraw(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
+	d, _ := this.ReadDeck()
+	if params[0].IsNullish() {
+		// 不指定数量时抽出一张，牌堆空了返回null
+		if len(d.Cards) == 0 {
+			return NewNullVal()
+		}
+		cards, _ := d.Draw(1)
+		return cards[0]
+	}
+	num, ok := params[0].ReadInt()
+	if !ok {
+		ctx.Error = errors.New("(deck.draw)类型错误: 数量必须为int")
+		return nil
+	}
+	cards, err := d.Draw(int(num))
+	if err != nil {
+		ctx.Error = errors.New("(deck.draw)值错误: " + err.Error())
+		return nil
+	}
+	return NewArrayValRaw(cards)
+}
+
+func funcDeckShuffle(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
+	d, _ := this.ReadDeck()
+	d.Shuffle(ctx)
+	return this
+}
+
+func funcDeckReset(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
+	d, _ := this.ReadDeck()
+	d.Reset(ctx)
+	return this
+}
+
+func funcDeckLen(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
+	d, _ := this.ReadDeck()
+	return NewIntVal(IntType(len(d.Cards)))
+}
+
+func funcDeckDrawn(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
+	d, _ := this.ReadDeck()
+	lst := make([]*VMValue, len(d.Drawn))
+	copy(lst, d.Drawn)
+	return NewArrayValRaw(lst)
+}
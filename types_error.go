@@ -0,0 +1,198 @@
+package dicescript
+
+import (
+	"errors"
+	"fmt"
+	"unicode/utf8"
+)
+
+// 错误值的类别，也可以使用自定义的类别
+const (
+	ErrorCodeError   = "error"   // error() 和 raise('...') 默认的类别
+	ErrorCodeSyntax  = "syntax"  // try() 执行的字符串有语法错误
+	ErrorCodeRuntime = "runtime" // 执行中的其他错误，如类型错误
+	ErrorCodeAssert  = "assert"  // assert() 的条件不成立
+	ErrorCodeType    = "type"    // require_int() 等的参数类型不符
+)
+
+// ErrorData 错误值，由 error() 创建或由 try() 捕获得到，原生函数也可以直接返回错误值来表示失败。
+// 同时实现了 error 接口，赋给 ctx.Error 时中止执行，被 try() 捕获时保留原有的类别
+type ErrorData struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Pos     int    `json:"pos"` // 出错位置(第几个字符，从0开始)，未知时为-1
+}
+
+func (ed *ErrorData) Error() string {
+	return ed.Message
+}
+
+func NewErrorVal(code string, message string, pos int) *VMValue {
+	return &VMValue{TypeId: VMTypeError, Value: &ErrorData{Code: code, Message: message, Pos: pos}}
+}
+
+func (v *VMValue) ReadError() (*ErrorData, bool) {
+	if v.TypeId == VMTypeError {
+		return v.Value.(*ErrorData), true
+	}
+	return nil, false
+}
+
+func (ed *ErrorData) attrGet(name string) *VMValue {
+	switch name {
+	case "code":
+		return NewStrVal(ed.Code)
+	case "message":
+		return NewStrVal(ed.Message)
+	case "pos":
+		return NewIntVal(IntType(ed.Pos))
+	}
+	return nil
+}
+
+func (ed *ErrorData) String() string {
+	return fmt.Sprintf("error(%s): %s", ed.Code, ed.Message)
+}
+
+// quotaError QuotaFunc 返回的错误，与算力、时间上限一样不能被 try() 捕获
+type quotaError struct {
+	err error
+}
+
+func (e *quotaError) Error() string { return e.err.Error() }
+func (e *quotaError) Unwrap() error { return e.err }
+
+// errorDataFrom 将执行中的错误转为错误值，expr为出错的语句，用于计算语法错误的位置。
+// 算力上限、超时、被中止和配额不足的错误不能被捕获，返回nil
+func errorDataFrom(err error, expr string) *ErrorData {
+	var qe *quotaError
+	if err == errOpCountLimit || err == ErrTimeLimit || err == ErrInterrupted || errors.As(err, &qe) {
+		return nil
+	}
+	var ed *ErrorData
+	if errors.As(err, &ed) {
+		return ed
+	}
+	var pe *ParseError
+	if errors.As(err, &pe) {
+		err = pe.Err
+	}
+	if lst, ok := err.(errList); ok && len(lst) > 0 {
+		pos := -1
+		if e, ok := lst[0].(*parserError); ok && e.pos.offset <= len(expr) {
+			pos = utf8.RuneCountInString(expr[:e.pos.offset])
+		}
+		return &ErrorData{Code: ErrorCodeSyntax, Message: err.Error(), Pos: pos}
+	}
+	return &ErrorData{Code: ErrorCodeRuntime, Message: err.Error(), Pos: -1}
+}
+
+// funcError 创建错误值，如 error('找不到物品', 'not_found')
+func funcError(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
+	msg, ok := params[0].ReadString()
+	if !ok {
+		ctx.Error = errors.New("(error)类型错误: message必须为str")
+		return nil
+	}
+	code, ok := params[1].ReadString()
+	if !ok {
+		ctx.Error = errors.New("(error)类型错误: code必须为str")
+		return nil
+	}
+	return NewErrorVal(code, msg, -1)
+}
+
+// funcRaise 以错误值或字符串中止执行，可以被 try() 捕获
+func funcRaise(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
+	switch params[0].TypeId {
+	case VMTypeError:
+		ed, _ := params[0].ReadError()
+		ctx.Error = ed
+	case VMTypeString:
+		msg, _ := params[0].ReadString()
+		ctx.Error = &ErrorData{Code: ErrorCodeError, Message: msg, Pos: -1}
+	default:
+		ctx.Error = errors.New("(raise)类型错误: 参数必须为错误值或str")
+	}
+	return nil
+}
+
+func funcIsError(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
+	return boolToVMValue(params[0].TypeId == VMTypeError)
+}
+
+// funcTry 执行expr，出错时不中止执行，而是返回错误值；给出handler时以错误值调用handler，返回其结果
+func funcTry(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
+	fn := params[0]
+	var ret *VMValue
+	var expr string
+	switch fn.TypeId {
+	case VMTypeString:
+		expr, _ = fn.ReadString()
+		ret = NewComputedValRaw(&ComputedData{Expr: expr}).ComputedInvoke(ctx, nil, nil)
+	case VMTypeComputedValue:
+		cd, _ := fn.ReadComputed()
+		expr = cd.Expr
+		ret = fn.ComputedInvoke(ctx, nil, nil)
+	case VMTypeFunction, VMTypeNativeFunction:
+		ret = invokeCallable(ctx, fn, nil)
+	default:
+		ctx.Error = errors.New("(try)类型错误: expr必须为str、computed或函数，如 try(&(a + 1))")
+		return nil
+	}
+	if ctx.Error == nil {
+		return ret
+	}
+
+	ed := errorDataFrom(ctx.Error, expr)
+	if ed == nil {
+		return nil
+	}
+	ctx.Error = nil
+	errVal := &VMValue{TypeId: VMTypeError, Value: ed}
+	if params[1].IsNullish() {
+		return errVal
+	}
+	return invokeCallable(ctx, params[1], []*VMValue{errVal})
+}
+
+// funcAssert 条件不成立时以 assert 类别的错误中止执行，成立时返回cond本身
+func funcAssert(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
+	if params[0].AsBool() {
+		return params[0]
+	}
+	msg := "断言失败"
+	if !params[1].IsNullish() {
+		msg = params[1].ToString()
+	}
+	ctx.Error = &ErrorData{Code: ErrorCodeAssert, Message: msg, Pos: -1}
+	return nil
+}
+
+// requireType 值的类型不在types中时以 type 类别的错误中止执行，name为报错时使用的参数名
+func requireType(ctx *Context, params []*VMValue, want string, types ...VMValueType) *VMValue {
+	v := params[0]
+	for _, t := range types {
+		if v.TypeId == t {
+			return v
+		}
+	}
+	name := "参数"
+	if !params[1].IsNullish() {
+		name = params[1].ToString()
+	}
+	ctx.Error = &ErrorData{Code: ErrorCodeType, Message: fmt.Sprintf("类型错误: %s必须为%s，不能为 %s", name, want, v.GetTypeName()), Pos: -1}
+	return nil
+}
+
+func funcRequireInt(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
+	return requireType(ctx, params, "int", VMTypeInt)
+}
+
+func funcRequireNum(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
+	return requireType(ctx, params, "数字", VMTypeInt, VMTypeFloat)
+}
+
+func funcRequireStr(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
+	return requireType(ctx, params, "str", VMTypeString)
+}
@@ -0,0 +1,135 @@
+package dicescript
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestErrorValue(t *testing.T) {
+	vm := NewVM()
+	err := vm.Run("e = error('找不到物品', 'not_found'); [e.code, e.message, e.pos, is_error(e), is_error(1), !e]")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, na(ns("not_found"), ns("找不到物品"), ni(-1), nb(true), nb(false), nb(true))))
+	}
+
+	err = vm.Run("error('x').code")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, ns(ErrorCodeError)))
+	}
+
+	e := NewErrorVal("not_found", "找不到物品", -1)
+	assert.Equal(t, "error(not_found): 找不到物品", e.ToString())
+	assert.Equal(t, "error('找不到物品', 'not_found')", e.ToRepr())
+	assert.Equal(t, "error", e.GetTypeName())
+
+	data, err := e.ToJSON()
+	if assert.NoError(t, err) {
+		v, err := VMValueFromJSON(data)
+		if assert.NoError(t, err) {
+			assert.True(t, valueEqual(v, e))
+		}
+	}
+
+	err = vm.Run("error(1)")
+	assert.Error(t, err)
+}
+
+func TestTryRaise(t *testing.T) {
+	vm := NewVM()
+	err := vm.Run("try(&(d20 + 1))")
+	if assert.NoError(t, err) {
+		assert.Equal(t, VMTypeInt, vm.Ret.TypeId)
+	}
+
+	err = vm.Run("r = try('1 + \"a\"'); [r.code, r.pos]")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, na(ns(ErrorCodeRuntime), ni(-1))))
+	}
+
+	err = vm.Run("r = try(')'); [r.code, r.pos]")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, na(ns(ErrorCodeSyntax), ni(0))))
+	}
+
+	// 函数中raise的错误保留原有的类别
+	err = vm.Run("func f() { raise(error('没有弹药', 'no_ammo')) }; r = try(f); [r.code, r.message]")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, na(ns("no_ammo"), ns("没有弹药"))))
+	}
+
+	err = vm.Run("func h(e) { '失败: ' + e.message }; [try(&(raise('卡壳')), h), try('2', h)]")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, na(ns("失败: 卡壳"), ni(2))))
+	}
+
+	// 未捕获时宿主程序可以取得错误值
+	err = vm.Run("raise(error('没有弹药', 'no_ammo'))")
+	var ed *ErrorData
+	if assert.True(t, errors.As(err, &ed)) {
+		assert.Equal(t, "no_ammo", ed.Code)
+		assert.Equal(t, "没有弹药", err.Error())
+	}
+
+	err = vm.Run("try(1)")
+	assert.Error(t, err)
+}
+
+func TestTryLimits(t *testing.T) {
+	// 算力上限和配额不足不能被捕获
+	vm := NewVM()
+	vm.Config.OpCountLimit = 1000
+	err := vm.Run("try('while 1 {}'); 1")
+	if assert.Error(t, err) {
+		assert.Equal(t, "允许算力上限", err.Error())
+	}
+
+	vm = NewVM(WithQuota(func(cost int64) error {
+		return errors.New("算力配额不足")
+	}))
+	err = vm.Run("try('i = 0; while i < 2000 { i = i + 1 }'); 1")
+	if assert.Error(t, err) {
+		assert.Equal(t, "算力配额不足", err.Error())
+	}
+
+	// 原生函数返回错误值
+	lookup := NewNativeFunctionVal(&NativeFunctionData{
+		Name:   "lookup",
+		Params: []string{"name"},
+		NativeFunc: func(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
+			return NewErrorVal("not_found", "找不到 "+params[0].ToString(), -1)
+		},
+	})
+	vm = NewVM(WithBuiltins(map[string]*VMValue{"lookup": lookup}))
+	err = vm.Run("r = lookup('剑'); is_error(r) ? r.code : r")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, ns("not_found")))
+	}
+}
+
+func TestAssertRequire(t *testing.T) {
+	vm := NewVM()
+	err := vm.Run("require_int(3) + require_num(1.5) + assert(2)")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, nf(6.5)))
+	}
+
+	err = vm.Run("assert(1 > 2, '目标必须在射程内')")
+	var ed *ErrorData
+	if assert.True(t, errors.As(err, &ed)) {
+		assert.Equal(t, ErrorCodeAssert, ed.Code)
+		assert.Equal(t, "目标必须在射程内", ed.Message)
+	}
+
+	err = vm.Run("func 伤害(n) { require_int(n, 'n') * 2 }; 伤害('a')")
+	if assert.True(t, errors.As(err, &ed)) {
+		assert.Equal(t, ErrorCodeType, ed.Code)
+		assert.Equal(t, "类型错误: n必须为int，不能为 str", ed.Message)
+	}
+
+	err = vm.Run("r = try(&(require_str(1))); [r.code, r.message]")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, na(ns(ErrorCodeType), ns("类型错误: 参数必须为str，不能为 int"))))
+	}
+}
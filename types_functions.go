@@ -100,3 +100,25 @@ func (v *VMValue) ArrayFuncKeepHigh(ctx *Context, pickNum IntType) (isAllInt boo
 func (v *VMValue) ArrayFuncKeepLow(ctx *Context, pickNum IntType) (isAllInt bool, ret float64) {
 	return v.ArrayFuncKeepBase(ctx, pickNum, 1)
 }
+
+// ArrayFuncDropHigh 去掉最高的dropNum个值，其余的相加
+func (v *VMValue) ArrayFuncDropHigh(ctx *Context, dropNum IntType) (isAllInt bool, ret float64) {
+	return v.ArrayFuncKeepBase(ctx, v.arrayNumCount()-dropNum, 1)
+}
+
+// ArrayFuncDropLow 去掉最低的dropNum个值，其余的相加
+func (v *VMValue) ArrayFuncDropLow(ctx *Context, dropNum IntType) (isAllInt bool, ret float64) {
+	return v.ArrayFuncKeepBase(ctx, v.arrayNumCount()-dropNum, 0)
+}
+
+// arrayNumCount 数组中数字的个数，取高取低时忽略其他类型的项
+func (v *VMValue) arrayNumCount() IntType {
+	arr, _ := v.ReadArray()
+	var n IntType
+	for _, i := range arr.List {
+		if i.TypeId == VMTypeInt || i.TypeId == VMTypeFloat {
+			n++
+		}
+	}
+	return n
+}
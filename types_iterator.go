@@ -0,0 +1,295 @@
+package dicescript
+
+import (
+	"errors"
+	"fmt"
+)
+
+// valueIterator 迭代器接口，for-in、展开语法以及 map()/filter() 等都通过它逐个取值
+type valueIterator interface {
+	next(ctx *Context) (*VMValue, bool)
+}
+
+// IteratorData 迭代器类型的数据，迭代器是惰性的，且只能被遍历一次
+type IteratorData struct {
+	Name string
+	it   valueIterator
+}
+
+type arrayIterator struct {
+	list  []*VMValue
+	index int
+}
+
+func (it *arrayIterator) next(ctx *Context) (*VMValue, bool) {
+	if it.index >= len(it.list) {
+		return nil, false
+	}
+	v := it.list[it.index]
+	it.index++
+	return v, true
+}
+
+type stringIterator struct {
+	runes []rune
+	index int
+}
+
+func (it *stringIterator) next(ctx *Context) (*VMValue, bool) {
+	if it.index >= len(it.runes) {
+		return nil, false
+	}
+	v := NewStrVal(string(it.runes[it.index]))
+	it.index++
+	return v, true
+}
+
+type rangeIterator struct {
+	cur, end, step IntType
+}
+
+func (it *rangeIterator) next(ctx *Context) (*VMValue, bool) {
+	if (it.step > 0 && it.cur >= it.end) || (it.step < 0 && it.cur <= it.end) {
+		return nil, false
+	}
+	v := NewIntVal(it.cur)
+	it.cur += it.step
+	return v, true
+}
+
+type mapIterator struct {
+	src valueIterator
+	fn  *VMValue
+}
+
+func (it *mapIterator) next(ctx *Context) (*VMValue, bool) {
+	v, ok := it.src.next(ctx)
+	if !ok || ctx.Error != nil {
+		return nil, false
+	}
+	ret := invokeCallable(ctx, it.fn, []*VMValue{v})
+	if ctx.Error != nil {
+		return nil, false
+	}
+	return ret, true
+}
+
+type filterIterator struct {
+	src valueIterator
+	fn  *VMValue
+}
+
+func (it *filterIterator) next(ctx *Context) (*VMValue, bool) {
+	for {
+		v, ok := it.src.next(ctx)
+		if !ok || ctx.Error != nil {
+			return nil, false
+		}
+		ret := invokeCallable(ctx, it.fn, []*VMValue{v})
+		if ctx.Error != nil {
+			return nil, false
+		}
+		if ret.AsBool() {
+			return v, true
+		}
+	}
+}
+
+// generatorChannel 生成器函数与调用方之间的通道。生成器函数在单独的goroutine中执行，
+// 每次yield时交出值并等待下一次恢复，两边不会同时运行。
+// 开始执行后的生成器记录在根vm中，顶层的执行结束时关闭，因此生成器只在创建它的那次执行中有效
+type generatorChannel struct {
+	resume chan struct{} // 被关闭时生成器终止
+	out    chan *VMValue // 执行结束时被关闭
+}
+
+var errGeneratorClosed = errors.New("生成器已被关闭")
+
+// yield 交出一个值，并等待调用方取下一个值。返回false代表生成器已被关闭，应当结束执行
+func (ch *generatorChannel) yield(v *VMValue) bool {
+	ch.out <- v
+	_, ok := <-ch.resume
+	return ok
+}
+
+type generatorIterator struct {
+	vm      *Context
+	ch      *generatorChannel
+	started bool
+	done    bool
+}
+
+func (it *generatorIterator) next(ctx *Context) (*VMValue, bool) {
+	if it.done {
+		return nil, false
+	}
+	vm := it.vm
+	vm.NumOpCount = ctx.NumOpCount
+	if !it.started {
+		it.started = true
+		root := ctx.rootCtx()
+		root.generators = append(root.generators, it)
+		go runGenerator(vm, it.ch)
+	} else {
+		it.ch.resume <- struct{}{}
+	}
+	v, ok := <-it.ch.out
+	ctx.NumOpCount = vm.NumOpCount
+	if !ok {
+		it.done = true
+		if vm.Error != nil {
+			ctx.Error = vm.Error
+		}
+		return nil, false
+	}
+	return v, true
+}
+
+// close 结束没有遍历完的生成器，等待其goroutine退出
+func (it *generatorIterator) close() {
+	if !it.started || it.done {
+		return
+	}
+	it.done = true
+	close(it.ch.resume)
+	// 生成器捕获了关闭的错误并继续yield时，丢弃这些值
+	for range it.ch.out {
+	}
+}
+
+// closeGenerators 关闭执行中启动的所有生成器，在顶层的执行结束时调用
+func (ctx *Context) closeGenerators() {
+	for _, it := range ctx.generators {
+		it.close()
+	}
+	ctx.generators = nil
+}
+
+func runGenerator(vm *Context, ch *generatorChannel) {
+	defer func() {
+		if r := recover(); r != nil {
+			vm.Error = fmt.Errorf("生成器执行出错: %v", r)
+		}
+		close(ch.out)
+	}()
+	vm.evaluate()
+}
+
+func newGeneratorVal(name string, vm *Context) *VMValue {
+	ch := &generatorChannel{resume: make(chan struct{}), out: make(chan *VMValue)}
+	vm.generator = ch
+	return NewIteratorVal(name, &generatorIterator{vm: vm, ch: ch})
+}
+
+func containsYield(code []ByteCode) bool {
+	for _, c := range code {
+		if c.T == typeYield {
+			return true
+		}
+	}
+	return false
+}
+
+func NewIteratorVal(name string, it valueIterator) *VMValue {
+	return &VMValue{TypeId: VMTypeIterator, Value: &IteratorData{Name: name, it: it}}
+}
+
+func (v *VMValue) ReadIterator() (*IteratorData, bool) {
+	if v.TypeId == VMTypeIterator {
+		return v.Value.(*IteratorData), true
+	}
+	return nil, false
+}
+
+// iter 获取值的迭代器: 数组、元组、集合逐项，字符串逐字，字典逐键，迭代器返回其自身
+func (v *VMValue) iter() (valueIterator, error) {
+	switch v.TypeId {
+	case VMTypeArray:
+		ad, _ := v.ReadArray()
+		return &arrayIterator{list: ad.List}, nil
+	case VMTypeTuple:
+		td, _ := v.ReadTuple()
+		return &arrayIterator{list: td.List}, nil
+	case VMTypeSet:
+		sd, _ := v.ReadSet()
+		return &arrayIterator{list: sd.List()}, nil
+	case VMTypeString:
+		s, _ := v.ReadString()
+		return &stringIterator{runes: []rune(s)}, nil
+	case VMTypeDict:
+		var keys []*VMValue
+		v.MustReadDictData().Dict.Range(func(key string, value *VMValue) bool {
+			keys = append(keys, DictKeyValue(key))
+			return true
+		})
+		return &arrayIterator{list: keys}, nil
+	case VMTypeIterator:
+		id, _ := v.ReadIterator()
+		return id.it, nil
+	case VMTypeRange:
+		rd, _ := v.ReadRange()
+		return rd.iter(), nil
+	case VMTypeTable:
+		// 逐行遍历
+		t, _ := v.ReadTable()
+		lst := make([]*VMValue, len(t.Rows))
+		for i, row := range t.Rows {
+			lst[i] = NewArrayValRaw(row)
+		}
+		return &arrayIterator{list: lst}, nil
+	case VMTypeDeck:
+		// 遍历剩余的牌，不会抽出
+		d, _ := v.ReadDeck()
+		lst := make([]*VMValue, len(d.Cards))
+		copy(lst, d.Cards)
+		return &arrayIterator{list: lst}, nil
+	case VMTypeOrder:
+		// 按先攻顺序遍历名字
+		od, _ := v.ReadOrder()
+		lst := make([]*VMValue, len(od.Entries))
+		for i, e := range od.Entries {
+			lst[i] = NewStrVal(e.Name)
+		}
+		return &arrayIterator{list: lst}, nil
+	}
+	return nil, fmt.Errorf("类型错误: %s类型不能被迭代", v.GetTypeName())
+}
+
+// iterToList 将可迭代的值全部取出，每取出一项消耗1点算力
+func (ctx *Context) iterToList(v *VMValue) []*VMValue {
+	it, err := v.iter()
+	if err != nil {
+		ctx.Error = err
+		return nil
+	}
+	var lst []*VMValue
+	for {
+		item, ok := it.next(ctx)
+		if ctx.Error != nil {
+			return nil
+		}
+		if !ok {
+			return lst
+		}
+		ctx.NumOpCount++
+		if ctx.Config.OpCountLimit > 0 && ctx.NumOpCount > ctx.Config.OpCountLimit {
+			ctx.Error = errOpCountLimit
+			return nil
+		}
+		lst = append(lst, item)
+	}
+}
+
+// invokeCallable 调用函数、原生函数或带参数的计算类型
+func invokeCallable(ctx *Context, fn *VMValue, params []*VMValue) *VMValue {
+	switch fn.TypeId {
+	case VMTypeFunction:
+		return fn.FuncInvoke(ctx, params)
+	case VMTypeNativeFunction:
+		return fn.FuncInvokeNative(ctx, params)
+	case VMTypeComputedValue:
+		return fn.ComputedInvoke(ctx, params, nil)
+	}
+	ctx.Error = fmt.Errorf("类型错误: [%s]无法被调用，必须是一个函数", fn.ToString())
+	return nil
+}
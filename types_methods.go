@@ -2,6 +2,8 @@ package dicescript
 
 import (
 	"errors"
+	"strings"
+	"unicode/utf8"
 
 	"golang.org/x/exp/rand"
 )
@@ -10,8 +12,21 @@ func funcComputedCompute(ctx *Context, this *VMValue, params []*VMValue) *VMValu
 	return this.ComputedExecute(ctx, nil)
 }
 
+// readKeepCount 读取 kh、kl、dh、dl 的个数参数，不是整数时设置错误
+func readKeepCount(ctx *Context, name string, v *VMValue) (IntType, bool) {
+	n, ok := operandValue(v).ReadInt()
+	if !ok {
+		ctx.Error = errors.New("(Array." + name + ")类型错误: 个数必须为int，不能为 " + v.GetTypeName())
+	}
+	return n, ok
+}
+
 func funcArrayKeepLow(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
-	isAllInt, ret := this.ArrayFuncKeepLow(ctx, params[0].MustReadInt())
+	n, ok := readKeepCount(ctx, "kl", params[0])
+	if !ok {
+		return nil
+	}
+	isAllInt, ret := this.ArrayFuncKeepLow(ctx, n)
 	if isAllInt {
 		return NewIntVal(IntType(ret))
 	} else {
@@ -20,7 +35,37 @@ func funcArrayKeepLow(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
 }
 
 func funcArrayKeepHigh(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
-	isAllInt, ret := this.ArrayFuncKeepHigh(ctx, params[0].MustReadInt())
+	n, ok := readKeepCount(ctx, "kh", params[0])
+	if !ok {
+		return nil
+	}
+	isAllInt, ret := this.ArrayFuncKeepHigh(ctx, n)
+	if isAllInt {
+		return NewIntVal(IntType(ret))
+	} else {
+		return NewFloatVal(ret)
+	}
+}
+
+func funcArrayDropHigh(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
+	n, ok := readKeepCount(ctx, "dh", params[0])
+	if !ok {
+		return nil
+	}
+	isAllInt, ret := this.ArrayFuncDropHigh(ctx, n)
+	if isAllInt {
+		return NewIntVal(IntType(ret))
+	} else {
+		return NewFloatVal(ret)
+	}
+}
+
+func funcArrayDropLow(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
+	n, ok := readKeepCount(ctx, "dl", params[0])
+	if !ok {
+		return nil
+	}
+	isAllInt, ret := this.ArrayFuncDropLow(ctx, n)
 	if isAllInt {
 		return NewIntVal(IntType(ret))
 	} else {
@@ -111,11 +156,98 @@ func funcArrayPush(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
 	return this
 }
 
+// funcArrayIndexOf 第一个与value相等(同 == )的项的下标，没有时为-1
+func funcArrayIndexOf(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
+	arr, _ := this.ReadArray()
+	for i, item := range arr.List {
+		if item.OpCompEQ(ctx, params[0]).AsBool() {
+			return NewIntVal(IntType(i))
+		}
+	}
+	return NewIntVal(-1)
+}
+
+// funcArrayCount 计算成功数: 不小于threshold的项各计1个成功，如 pool(8, 10).count(7)。
+// 给出double时不小于double的项计2个成功(如WoD中10计两次)；botch为真时每个1抵消1个成功，结果可能为负数，负数即大失败
+func funcArrayCount(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
+	arr, _ := this.ReadArray()
+	threshold, ok := params[0].ReadInt()
+	if !ok {
+		ctx.Error = errors.New("(Array.count)类型错误: 成功线必须为int")
+		return nil
+	}
+	double, hasDouble := params[1].ReadInt()
+	if !hasDouble && params[1].TypeId != VMTypeNull {
+		ctx.Error = errors.New("(Array.count)类型错误: 双倍成功线必须为int")
+		return nil
+	}
+	botch := params[2].AsBool()
+
+	var n IntType
+	for _, item := range arr.List {
+		v, ok := operandValue(item).ReadInt()
+		if !ok {
+			ctx.Error = errors.New("(Array.count)类型错误: 数组的项必须为int，不能为 " + item.GetTypeName())
+			return nil
+		}
+		switch {
+		case hasDouble && v >= double:
+			n += 2
+		case v >= threshold:
+			n++
+		case botch && v == 1:
+			n--
+		}
+	}
+	return NewIntVal(n)
+}
+
+// funcArrayFind 第一个使fn结果为真的项，没有时为空值
+func funcArrayFind(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
+	arr, _ := this.ReadArray()
+	for _, item := range arr.List {
+		ret := invokeCallable(ctx, params[0], []*VMValue{item})
+		if ctx.Error != nil {
+			return nil
+		}
+		if ret.AsBool() {
+			return item
+		}
+	}
+	return ctx.newMissingVal()
+}
+
+// funcStrIndexOf 子串第一次出现的位置，按字符而非字节计算，没有时为-1
+func funcStrIndexOf(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
+	s, _ := this.ReadString()
+	sub, ok := params[0].ReadString()
+	if !ok {
+		ctx.Error = errors.New("(Str.indexOf)类型错误: 参数必须为str")
+		return nil
+	}
+	i := strings.Index(s, sub)
+	if i < 0 {
+		return NewIntVal(-1)
+	}
+	return NewIntVal(IntType(utf8.RuneCountInString(s[:i])))
+}
+
+// funcStrCount 子串不重叠地出现的次数
+func funcStrCount(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
+	s, _ := this.ReadString()
+	sub, ok := params[0].ReadString()
+	if !ok {
+		ctx.Error = errors.New("(Str.count)类型错误: 参数必须为str")
+		return nil
+	}
+	return NewIntVal(IntType(strings.Count(s, sub)))
+}
+
 func funcDictKeys(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
 	d := this.MustReadDictData()
 	var arr []*VMValue
 	d.Dict.Range(func(key string, value *VMValue) bool {
-		arr = append(arr, NewStrVal(key))
+		arr = append(arr, DictKeyValue(key))
 		return true
 	})
 	return NewArrayValRaw(arr)
@@ -135,7 +267,7 @@ func funcDictItems(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
 	d := this.MustReadDictData()
 	var arr []*VMValue
 	d.Dict.Range(func(key string, value *VMValue) bool {
-		arr = append(arr, NewArrayVal(NewStrVal(key), value))
+		arr = append(arr, NewArrayVal(DictKeyValue(key), value))
 		return true
 	})
 	return NewArrayValRaw(arr)
@@ -146,6 +278,13 @@ func funcDictLen(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
 	return NewIntVal(IntType(d.Dict.Length()))
 }
 
+// funcDictSortKeys 将字典的键按字典序重新排列，影响之后的遍历、显示和序列化，返回字典本身
+func funcDictSortKeys(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
+	d := this.MustReadDictData()
+	d.Dict.SortKeys()
+	return this
+}
+
 var builtinProto = map[VMValueType]*VMDictValue{
 	VMTypeComputedValue: NewDictValWithArrayMust(
 		NewStrVal("compute"), nnf(&ndf{"Computed.compute", []string{}, nil, nil, nil}),
@@ -153,6 +292,8 @@ var builtinProto = map[VMValueType]*VMDictValue{
 	VMTypeArray: NewDictValWithArrayMust(
 		NewStrVal("kh"), nnf(&ndf{"Array.kh", []string{"num"}, []*VMValue{NewIntVal(1)}, nil, funcArrayKeepHigh}),
 		NewStrVal("kl"), nnf(&ndf{"Array.kl", []string{"num"}, []*VMValue{NewIntVal(1)}, nil, funcArrayKeepLow}),
+		NewStrVal("dh"), nnf(&ndf{"Array.dh", []string{"num"}, []*VMValue{NewIntVal(1)}, nil, funcArrayDropHigh}),
+		NewStrVal("dl"), nnf(&ndf{"Array.dl", []string{"num"}, []*VMValue{NewIntVal(1)}, nil, funcArrayDropLow}),
 		NewStrVal("sum"), nnf(&ndf{"Array.sum", []string{}, nil, nil, funcArraySum}),
 		NewStrVal("len"), nnf(&ndf{"Array.len", []string{}, nil, nil, funcArrayLen}),
 		NewStrVal("shuffle"), nnf(&ndf{"Array.shuffle", []string{}, nil, nil, funcArrayShuttle}),
@@ -161,12 +302,78 @@ var builtinProto = map[VMValueType]*VMDictValue{
 		NewStrVal("pop"), nnf(&ndf{"Array.pop", []string{}, nil, nil, funcArrayPop}),
 		NewStrVal("shift"), nnf(&ndf{"Array.shift", []string{}, nil, nil, funcArrayShift}),
 		NewStrVal("push"), nnf(&ndf{"Array.push", []string{"value"}, nil, nil, funcArrayPush}),
+		NewStrVal("indexOf"), nnf(&ndf{"Array.indexOf", []string{"value"}, nil, nil, funcArrayIndexOf}),
+		NewStrVal("count"), nnf(&ndf{"Array.count", []string{"threshold", "double", "botch"}, []*VMValue{nil, NewNullVal(), NewIntVal(0)}, nil, funcArrayCount}),
+	),
+	VMTypeString: NewDictValWithArrayMust(
+		NewStrVal("indexOf"), nnf(&ndf{"Str.indexOf", []string{"sub"}, nil, nil, funcStrIndexOf}),
+		NewStrVal("count"), nnf(&ndf{"Str.count", []string{"sub"}, nil, nil, funcStrCount}),
+	),
+	VMTypeResource: NewDictValWithArrayMust(
+		NewStrVal("spend"), nnf(&ndf{"Resource.spend", []string{"num"}, nil, nil, funcResourceSpend}),
+		NewStrVal("heal"), nnf(&ndf{"Resource.heal", []string{"num"}, nil, nil, funcResourceHeal}),
+		NewStrVal("set"), nnf(&ndf{"Resource.set", []string{"value"}, nil, nil, funcResourceSet}),
+		NewStrVal("value"), nnf(&ndf{"Resource.value", []string{}, nil, nil, funcResourceValue}),
+		NewStrVal("max"), nnf(&ndf{"Resource.max", []string{}, nil, nil, funcResourceMax}),
+		NewStrVal("min"), nnf(&ndf{"Resource.min", []string{}, nil, nil, funcResourceMin}),
+		NewStrVal("change"), nnf(&ndf{"Resource.change", []string{}, nil, nil, funcResourceChange}),
+	),
+	VMTypeTuple: NewDictValWithArrayMust(
+		NewStrVal("len"), nnf(&ndf{"Tuple.len", []string{}, nil, nil, funcTupleLen}),
+		NewStrVal("list"), nnf(&ndf{"Tuple.list", []string{}, nil, nil, funcTupleList}),
+	),
+	VMTypeRange: NewDictValWithArrayMust(
+		NewStrVal("len"), nnf(&ndf{"Range.len", []string{}, nil, nil, funcRangeLen}),
+		NewStrVal("list"), nnf(&ndf{"Range.list", []string{}, nil, nil, funcRangeList}),
+	),
+	VMTypeSet: NewDictValWithArrayMust(
+		NewStrVal("add"), nnf(&ndf{"Set.add", []string{"item"}, nil, nil, funcSetAdd}),
+		NewStrVal("remove"), nnf(&ndf{"Set.remove", []string{"item"}, nil, nil, funcSetRemove}),
+		NewStrVal("has"), nnf(&ndf{"Set.has", []string{"item"}, nil, nil, funcSetHas}),
+		NewStrVal("len"), nnf(&ndf{"Set.len", []string{}, nil, nil, funcSetLen}),
+		NewStrVal("list"), nnf(&ndf{"Set.list", []string{}, nil, nil, funcSetList}),
+	),
+	VMTypeOrder: NewDictValWithArrayMust(
+		NewStrVal("next"), nnf(&ndf{"Order.next", []string{}, nil, nil, funcOrderNext}),
+		NewStrVal("current"), nnf(&ndf{"Order.current", []string{}, nil, nil, funcOrderCurrent}),
+		NewStrVal("round"), nnf(&ndf{"Order.round", []string{}, nil, nil, funcOrderRound}),
+		NewStrVal("list"), nnf(&ndf{"Order.list", []string{}, nil, nil, funcOrderList}),
+		NewStrVal("add"), nnf(&ndf{"Order.add", []string{"name", "init"}, nil, nil, funcOrderAdd}),
+		NewStrVal("remove"), nnf(&ndf{"Order.remove", []string{"name"}, nil, nil, funcOrderRemove}),
+		NewStrVal("reset"), nnf(&ndf{"Order.reset", []string{}, nil, nil, funcOrderReset}),
+	),
+	VMTypeDeck: NewDictValWithArrayMust(
+		NewStrVal("draw"), nnf(&ndf{"Deck.draw", []string{"num"}, []*VMValue{NewNullVal()}, nil, funcDeckDraw}),
+		NewStrVal("shuffle"), nnf(&ndf{"Deck.shuffle", []string{}, nil, nil, funcDeckShuffle}),
+		NewStrVal("reset"), nnf(&ndf{"Deck.reset", []string{}, nil, nil, funcDeckReset}),
+		NewStrVal("len"), nnf(&ndf{"Deck.len", []string{}, nil, nil, funcDeckLen}),
+		NewStrVal("drawn"), nnf(&ndf{"Deck.drawn", []string{}, nil, nil, funcDeckDrawn}),
+	),
+	VMTypeTable: NewDictValWithArrayMust(
+		NewStrVal("lookup"), nnf(&ndf{"Table.lookup", []string{"row", "col"}, nil, nil, funcTableLookup}),
+		NewStrVal("lookupRange"), nnf(&ndf{"Table.lookupRange", []string{"value", "col"}, []*VMValue{nil, NewIntVal(1)}, nil, funcTableLookupRange}),
+		NewStrVal("row"), nnf(&ndf{"Table.row", []string{"row"}, nil, nil, funcTableRow}),
+		NewStrVal("col"), nnf(&ndf{"Table.col", []string{"col"}, nil, nil, funcTableCol}),
+		NewStrVal("rows"), nnf(&ndf{"Table.rows", []string{}, nil, nil, funcTableRows}),
+		NewStrVal("cols"), nnf(&ndf{"Table.cols", []string{}, nil, nil, funcTableCols}),
+		NewStrVal("header"), nnf(&ndf{"Table.header", []string{}, nil, nil, funcTableHeader}),
+	),
+	VMTypeQuantity: NewDictValWithArrayMust(
+		NewStrVal("to"), nnf(&ndf{"Quantity.to", []string{"unit"}, nil, nil, funcQuantityTo}),
+		NewStrVal("value"), nnf(&ndf{"Quantity.value", []string{}, nil, nil, funcQuantityValue}),
+		NewStrVal("unit"), nnf(&ndf{"Quantity.unit", []string{}, nil, nil, funcQuantityUnit}),
+	),
+	VMTypeMoney: NewDictValWithArrayMust(
+		NewStrVal("split"), nnf(&ndf{"Money.split", []string{"num"}, nil, nil, funcMoneySplit}),
+		NewStrVal("to"), nnf(&ndf{"Money.to", []string{"denomination"}, nil, nil, funcMoneyTo}),
+		NewStrVal("value"), nnf(&ndf{"Money.value", []string{}, nil, nil, funcMoneyValue}),
 	),
 	VMTypeDict: NewDictValWithArrayMust(
 		NewStrVal("keys"), nnf(&ndf{"Dict.keys", []string{}, nil, nil, funcDictKeys}),
 		NewStrVal("values"), nnf(&ndf{"Dict.values", []string{}, nil, nil, funcDictValues}),
 		NewStrVal("items"), nnf(&ndf{"Dict.items", []string{}, nil, nil, funcDictItems}),
 		NewStrVal("len"), nnf(&ndf{"Dict.len", []string{}, nil, nil, funcDictLen}),
+		NewStrVal("sortKeys"), nnf(&ndf{"Dict.sortKeys", []string{}, nil, nil, funcDictSortKeys}),
 	),
 }
 
@@ -198,6 +405,7 @@ func _init2() bool {
 	// 因循环引用问题无法在上面声明
 	funcCompute := nnf(&ndf{"Computed.compute", []string{}, nil, nil, funcComputedCompute})
 	builtinProto[VMTypeComputedValue].Store("compute", funcCompute)
+	builtinProto[VMTypeArray].Store("find", nnf(&ndf{"Array.find", []string{"fn"}, nil, nil, funcArrayFind}))
 	return false
 }
 
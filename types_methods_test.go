@@ -42,23 +42,60 @@ func TestTypesMethodArrayRandSize(t *testing.T) {
 	assert.Equal(t, v.Length(nil), IntType(1))
 }
 
+func TestTypesMethodArrayFind(t *testing.T) {
+	vm := NewVM()
+	err := vm.Run("func 大于(x) { return x > 1 }; func 否(x) { return 0 }; a = [1, 2, 3]; [a.indexOf(2), a.indexOf(2.0), a.indexOf(5), a.find(大于), a.find(否)]")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, na(ni(1), ni(1), ni(-1), ni(2), NewNullVal())))
+	}
+}
+
+func TestTypesMethodArrayCount(t *testing.T) {
+	vm := NewVM()
+	err := vm.Run("a = [10, 7, 3, 1, 8, 1]; [a.count(7), a.count(7, 10), a.count(7, 10, 1), [1, 1, 5].count(7, null, 1), [].count(7)]")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, na(ni(3), ni(4), ni(2), ni(-2), ni(0))))
+	}
+
+	err = vm.Run("p = pool(8, 10); [p.len(), p.count(1), p.count(11)]")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, na(ni(8), ni(8), ni(0))))
+	}
+
+	err = vm.Run("['a'].count(7)")
+	assert.Error(t, err)
+	err = vm.Run("pool(0, 10)")
+	assert.Error(t, err)
+}
+
+func TestTypesMethodStrIndexOf(t *testing.T) {
+	vm := NewVM()
+	err := vm.Run("['中毒眩晕'.indexOf('眩晕'), 'abc'.indexOf('d'), 'abab'.count('ab'), 'aaa'.count('aa'), `{1}x`.count('x')]")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, na(ni(2), ni(-1), ni(2), ni(1), ni(1))))
+	}
+
+	vm = NewVM()
+	err = vm.Run("'a'.indexOf(1)")
+	assert.Error(t, err)
+}
+
 func TestTypesMethodDictKeys(t *testing.T) {
 	d := NewDictValWithArrayMust(ns("a"), ni(1), ns("b"), ni(2))
 	v := funcDictKeys(nil, d.V(), nil)
-	assert.True(t, valueEqual(v, na(ns("a"), ns("b"))) || valueEqual(v, na(ns("b"), ns("a"))))
+	assert.True(t, valueEqual(v, na(ns("a"), ns("b"))))
 }
 
 func TestTypesMethodDictValues(t *testing.T) {
 	d := NewDictValWithArrayMust(ns("a"), ni(1), ns("b"), ni(2))
 	v := funcDictValues(nil, d.V(), nil)
-	assert.True(t, valueEqual(v, na(ni(1), ni(2))) || valueEqual(v, na(ni(2), ni(1))))
+	assert.True(t, valueEqual(v, na(ni(1), ni(2))))
 }
 
 func TestTypesMethodDictItems(t *testing.T) {
 	d := NewDictValWithArrayMust(ns("a"), ni(1), ns("b"), ni(2))
 	v := funcDictItems(nil, d.V(), nil)
-	assert.True(t, valueEqual(v, na(na(ns("a"), ni(1)), na(ns("b"), ni(2)))) ||
-		valueEqual(v, na(na(ns("b"), ni(2)), na(ns("a"), ni(1)))))
+	assert.True(t, valueEqual(v, na(na(ns("a"), ni(1)), na(ns("b"), ni(2)))))
 }
 
 func TestTypesMethodDictLen(t *testing.T) {
@@ -66,3 +103,40 @@ func TestTypesMethodDictLen(t *testing.T) {
 	v := funcDictLen(nil, d.V(), nil)
 	assert.Equal(t, v.MustReadInt(), IntType(2))
 }
+
+func TestTypesMethodDictSortKeys(t *testing.T) {
+	vm := NewVM()
+	err := vm.Run("m = {'c': 1, 'a': 2, 'b': 3}; x = `{m}`; m.sortKeys(); m['0'] = 4; [x, `{m}`, m.keys()]")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, na(
+			ns("{'c': 1, 'a': 2, 'b': 3}"),
+			ns("{'a': 2, 'b': 3, 'c': 1, '0': 4}"),
+			na(ns("a"), ns("b"), ns("c"), ns("0")),
+		)))
+	}
+}
+
+func TestTypesMethodDeck(t *testing.T) {
+	vm := NewVM()
+	err := vm.Run("牌堆 = deck(list(range(1, 53))); 手牌 = 牌堆.draw(5); [手牌.len(), 牌堆.len(), 牌堆.drawn().len()]")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, na(ni(5), ni(47), ni(5))))
+	}
+
+	// 抽出的牌不放回
+	vm = NewVM()
+	err = vm.Run("牌堆 = deck(['A', 'B', 'C'], 0); [牌堆.draw(), 牌堆.draw(2), 牌堆.draw(), 牌堆.len()]")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, na(ns("A"), na(ns("B"), ns("C")), NewNullVal(), ni(0))))
+	}
+
+	vm = NewVM()
+	err = vm.Run("牌堆 = deck([1, 2, 3]); 牌堆.draw(3); 牌堆.reset(); [牌堆.len(), 牌堆.drawn(), 牌堆.shuffle().draw(3).sum()]")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, na(ni(3), na(), ni(6))))
+	}
+
+	vm = NewVM()
+	err = vm.Run("牌堆 = deck([1, 2]); 牌堆.draw(3)")
+	assert.Error(t, err)
+}
@@ -0,0 +1,276 @@
+package dicescript
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Denomination 货币面额
+type Denomination struct {
+	Name  string  `json:"name"`
+	Value IntType `json:"value"` // 折合为最小面额的数量
+}
+
+// CurrencySystem 一套货币，面额按从大到小排列，最后一项为最小面额(其Value应为1)
+type CurrencySystem struct {
+	Denominations []Denomination
+}
+
+// DefaultCurrency 默认的金币/银币/铜币，1gp = 10sp = 100cp
+var DefaultCurrency = &CurrencySystem{Denominations: []Denomination{
+	{"gp", 100},
+	{"sp", 10},
+	{"cp", 1},
+}}
+
+// NewCurrencySystem 创建货币，面额会按从大到小排序。面额的Value必须为正整数
+func NewCurrencySystem(denoms ...Denomination) (*CurrencySystem, error) {
+	lst := make([]Denomination, len(denoms))
+	copy(lst, denoms)
+	for _, d := range lst {
+		if d.Value <= 0 {
+			return nil, fmt.Errorf("面额 %s 的值必须为正整数", d.Name)
+		}
+	}
+	for i := 1; i < len(lst); i++ {
+		for j := i; j > 0 && lst[j].Value > lst[j-1].Value; j-- {
+			lst[j], lst[j-1] = lst[j-1], lst[j]
+		}
+	}
+	return &CurrencySystem{Denominations: lst}, nil
+}
+
+func (cs *CurrencySystem) Get(name string) (Denomination, bool) {
+	for _, d := range cs.Denominations {
+		if d.Name == name {
+			return d, true
+		}
+	}
+	return Denomination{}, false
+}
+
+func (cs *CurrencySystem) equal(cs2 *CurrencySystem) bool {
+	if cs == cs2 {
+		return true
+	}
+	if len(cs.Denominations) != len(cs2.Denominations) {
+		return false
+	}
+	for i, d := range cs.Denominations {
+		if d != cs2.Denominations[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Format 将最小面额的数量格式化为各面额的组合，如 325 为 3gp 2sp 5cp
+func (cs *CurrencySystem) Format(amount IntType) string {
+	if len(cs.Denominations) == 0 {
+		return strconv.FormatInt(int64(amount), 10)
+	}
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+	var parts []string
+	for _, d := range cs.Denominations {
+		if d.Value <= 0 {
+			continue
+		}
+		if n := amount / d.Value; n != 0 {
+			parts = append(parts, strconv.FormatInt(int64(n), 10)+d.Name)
+			amount -= n * d.Value
+		}
+	}
+	if len(parts) == 0 {
+		return "0" + cs.Denominations[len(cs.Denominations)-1].Name
+	}
+	return sign + strings.Join(parts, " ")
+}
+
+// parse 解析 3gp5sp 这样的文本，得到最小面额的数量
+func (cs *CurrencySystem) parse(text string) (IntType, bool) {
+	var amount IntType
+	data := text
+	for data != "" {
+		i := 0
+		for i < len(data) && data[i] >= '0' && data[i] <= '9' {
+			i++
+		}
+		j := i
+		for j < len(data) {
+			r, size := utf8.DecodeRuneInString(data[j:])
+			if !unicode.IsLetter(r) {
+				break
+			}
+			j += size
+		}
+		if i == 0 || j == i {
+			return amount, false
+		}
+		d, ok := cs.Get(data[i:j])
+		if !ok {
+			return amount, false
+		}
+		n, err := strconv.ParseInt(data[:i], 10, 64)
+		if err != nil {
+			return amount, false
+		}
+		part, ok := mulInt(IntType(n), d.Value)
+		if !ok || amount > math.MaxInt64-part {
+			return amount, false
+		}
+		amount += part
+		data = data[j:]
+	}
+	return amount, text != ""
+}
+
+func (c *RollConfig) currency() *CurrencySystem {
+	if c.Currency != nil {
+		return c.Currency
+	}
+	return DefaultCurrency
+}
+
+// MoneyData 金额，以最小面额的数量储存，因此总是规整的
+type MoneyData struct {
+	Amount IntType
+	System *CurrencySystem
+}
+
+func NewMoneyVal(amount IntType, system *CurrencySystem) *VMValue {
+	if system == nil {
+		system = DefaultCurrency
+	}
+	return &VMValue{TypeId: VMTypeMoney, Value: MoneyData{Amount: amount, System: system}}
+}
+
+func (v *VMValue) ReadMoney() (MoneyData, bool) {
+	if v.TypeId == VMTypeMoney {
+		return v.Value.(MoneyData), true
+	}
+	return MoneyData{}, false
+}
+
+func (m MoneyData) String() string {
+	return m.System.Format(m.Amount)
+}
+
+// alignMoney 取得两个金额，货币不同时报错。v2不是金额时ok为false
+func (v *VMValue) alignMoney(ctx *Context, v2 *VMValue) (a IntType, b IntType, ok bool) {
+	m1 := v.Value.(MoneyData)
+	m2, ok := v2.ReadMoney()
+	if !ok {
+		return 0, 0, false
+	}
+	if !m1.System.equal(m2.System) {
+		ctx.Error = errors.New("货币错误: 两个金额属于不同的货币")
+		return 0, 0, false
+	}
+	return m1.Amount, m2.Amount, true
+}
+
+func (v *VMValue) moneyMultiply(ctx *Context, v2 *VMValue) *VMValue {
+	m := v.Value.(MoneyData)
+	switch v2.TypeId {
+	case VMTypeInt:
+		amount, ok := mulInt(m.Amount, v2.Value.(IntType))
+		if !ok {
+			ctx.Error = errors.New("货币错误: 金额超出范围")
+			return nil
+		}
+		return NewMoneyVal(amount, m.System)
+	case VMTypeFloat:
+		// 不足最小面额的部分默认四舍五入
+		f := float64(m.Amount) * v2.Value.(float64)
+		if math.IsNaN(f) || f >= math.MaxInt64 || f < math.MinInt64 {
+			ctx.Error = errors.New("货币错误: 金额超出范围")
+			return nil
+		}
+		return NewMoneyVal(roundToInt(f, ctx.Config.RoundMode, RoundHalfUp), m.System)
+	}
+	return nil
+}
+
+func funcToMoney(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
+	cs := ctx.Config.currency()
+	if len(cs.Denominations) == 0 {
+		ctx.Error = errors.New("(toMoney)货币错误: 没有设置面额")
+		return nil
+	}
+	n, ok := readNumber(params[0])
+	if !ok {
+		ctx.Error = errors.New("(toMoney)类型错误: 数值必须为int或float")
+		return nil
+	}
+	d := cs.Denominations[len(cs.Denominations)-1]
+	if !params[1].IsNullish() {
+		name, ok := params[1].ReadString()
+		if !ok {
+			ctx.Error = errors.New("(toMoney)类型错误: 面额必须为str")
+			return nil
+		}
+		if d, ok = cs.Get(name); !ok {
+			ctx.Error = fmt.Errorf("(toMoney)货币错误: 未知的面额 %s", name)
+			return nil
+		}
+	}
+	return NewMoneyVal(roundToInt(n*float64(d.Value), ctx.Config.RoundMode, RoundHalfUp), cs)
+}
+
+// funcMoneySplit 平分金额，除不尽的部分从前往后每份多分一个最小面额，保证总数不变
+func funcMoneySplit(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
+	m, _ := this.ReadMoney()
+	n, ok := params[0].ReadInt()
+	if !ok || n <= 0 {
+		ctx.Error = errors.New("(Money.split)值错误: 份数必须为正整数")
+		return nil
+	}
+	if n > 512 {
+		ctx.Error = errors.New("(Money.split)值错误: 不能一次性创建过长的数组")
+		return nil
+	}
+	share, rest := m.Amount/n, m.Amount%n
+	lst := make([]*VMValue, n)
+	for i := IntType(0); i < n; i++ {
+		amount := share
+		if i < rest {
+			amount++
+		} else if i < -rest {
+			amount--
+		}
+		lst[i] = NewMoneyVal(amount, m.System)
+	}
+	return NewArrayValRaw(lst)
+}
+
+func funcMoneyTo(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
+	m, _ := this.ReadMoney()
+	name, ok := params[0].ReadString()
+	if !ok {
+		ctx.Error = errors.New("(Money.to)类型错误: 面额必须为str")
+		return nil
+	}
+	d, ok := m.System.Get(name)
+	if !ok || d.Value <= 0 {
+		ctx.Error = fmt.Errorf("(Money.to)货币错误: 未知的面额 %s", name)
+		return nil
+	}
+	if m.Amount%d.Value == 0 {
+		return NewIntVal(m.Amount / d.Value)
+	}
+	return NewFloatVal(float64(m.Amount) / float64(d.Value))
+}
+
+func funcMoneyValue(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
+	m, _ := this.ReadMoney()
+	return NewIntVal(m.Amount)
+}
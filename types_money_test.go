@@ -0,0 +1,59 @@
+package dicescript
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMoney(t *testing.T) {
+	vm := NewVM()
+	err := vm.Run("[3gp5sp + 27cp, 1gp - 3cp, 2sp * 3, 1gp * 1.5, 1gp / 3, 1gp / 5sp, -3gp2cp, 0gp, 10cp == 1sp, 1gp > 9sp9cp]")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "[3gp7sp7cp, 9sp7cp, 6sp, 1gp5sp, 3sp3cp, 2, -3gp2cp, 0cp, true, true]", vm.Ret.ToString())
+	}
+
+	vm = NewVM()
+	err = vm.Run("战利品 = 10gp; 战利品.split(3)")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "[3gp3sp4cp, 3gp3sp3cp, 3gp3sp3cp]", vm.Ret.ToString())
+	}
+
+	vm = NewVM()
+	err = vm.Run("[(25cp).to('sp'), (3gp).to('sp'), (1gp2cp).value(), toMoney(2.5, 'gp'), toMoney(7)]")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "[2.5, 30, 102, 2gp5sp, 7cp]", vm.Ret.ToString())
+	}
+
+	// 自定义货币
+	vm = NewVM()
+	cs, err := NewCurrencySystem(Denomination{"文", 1}, Denomination{"两", 1000}, Denomination{"钱", 100})
+	assert.NoError(t, err)
+	vm.Config.Currency = cs
+	err = vm.Run("1两2钱 + 850文")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "2两 50文", vm.Ret.ToString())
+		assert.Equal(t, "2两50文", vm.Ret.ToRepr())
+	}
+
+	_, err = NewCurrencySystem(Denomination{"gp", 100}, Denomination{"x", 0})
+	assert.Error(t, err)
+
+	for _, expr := range []string{"1gp + 1", "1gp * 1gp", "1gp / 0", "(1gp).split(0)", "toMoney(1, 'pp')", "1gp * 100000000000000000", "1gp * 1e30"} {
+		vm = NewVM()
+		err = vm.Run(expr)
+		assert.Error(t, err, expr)
+	}
+}
+
+func TestMoneyJSON(t *testing.T) {
+	v := NewMoneyVal(325, nil)
+	data, err := v.ToJSON()
+	if assert.NoError(t, err) {
+		assert.Equal(t, `{"t":17,"v":{"amount":325,"denoms":[{"name":"gp","value":100},{"name":"sp","value":10},{"name":"cp","value":1}]}}`, string(data))
+	}
+	v2, err := VMValueFromJSON(data)
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(v, v2))
+	}
+}
@@ -0,0 +1,219 @@
+package dicescript
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// OrderEntry 先攻顺序中的一项
+type OrderEntry struct {
+	Name  string  `json:"name"`
+	Init  IntType `json:"init"`  // 先攻值，骰点加调整值
+	Bonus IntType `json:"bonus"` // 先攻调整值，先攻值相同时调整值高者在前
+}
+
+// OrderData 先攻顺序，按先攻值从高到低排列
+type OrderData struct {
+	Entries []*OrderEntry `json:"entries"`
+	Index   int           `json:"index"` // 当前行动者的下标
+	Round   IntType       `json:"round"` // 当前轮数，从1开始
+}
+
+func NewOrderVal(entries []*OrderEntry) *VMValue {
+	od := &OrderData{Entries: entries, Round: 1}
+	od.sort()
+	return &VMValue{TypeId: VMTypeOrder, Value: od}
+}
+
+func (v *VMValue) ReadOrder() (*OrderData, bool) {
+	if v.TypeId == VMTypeOrder {
+		return v.Value.(*OrderData), true
+	}
+	return nil, false
+}
+
+func (od *OrderData) sort() {
+	sort.SliceStable(od.Entries, func(i, j int) bool {
+		a, b := od.Entries[i], od.Entries[j]
+		if a.Init != b.Init {
+			return a.Init > b.Init
+		}
+		return a.Bonus > b.Bonus
+	})
+}
+
+// Current 当前行动者，顺序为空时返回nil
+func (od *OrderData) Current() *OrderEntry {
+	if od.Index < len(od.Entries) {
+		return od.Entries[od.Index]
+	}
+	return nil
+}
+
+// Next 轮到下一位行动者，所有人都行动过后进入下一轮
+func (od *OrderData) Next() *OrderEntry {
+	if len(od.Entries) == 0 {
+		return nil
+	}
+	od.Index++
+	if od.Index >= len(od.Entries) {
+		od.Index = 0
+		od.Round++
+	}
+	return od.Entries[od.Index]
+}
+
+// Add 加入一位行动者，按先攻值插入，不影响当前行动者
+func (od *OrderData) Add(e *OrderEntry) {
+	cur := od.Current()
+	od.Entries = append(od.Entries, e)
+	od.sort()
+	od.seek(cur)
+}
+
+// Remove 移出一位行动者，移出的是当前行动者时轮到其下一位。移出的是最后一位时回到第一位，但不进入下一轮
+func (od *OrderData) Remove(name string) bool {
+	for i, e := range od.Entries {
+		if e.Name != name {
+			continue
+		}
+		od.Entries = append(od.Entries[:i], od.Entries[i+1:]...)
+		if i < od.Index {
+			od.Index--
+		}
+		if od.Index >= len(od.Entries) {
+			od.Index = 0
+		}
+		return true
+	}
+	return false
+}
+
+func (od *OrderData) seek(e *OrderEntry) {
+	for i, x := range od.Entries {
+		if x == e {
+			od.Index = i
+			return
+		}
+	}
+}
+
+func (od *OrderData) String() string {
+	items := make([]string, len(od.Entries))
+	for i, e := range od.Entries {
+		items[i] = e.Name + " " + strconv.FormatInt(int64(e.Init), 10)
+		if i == od.Index {
+			items[i] = "[" + items[i] + "]"
+		}
+	}
+	return fmt.Sprintf("第%d轮: %s", od.Round, strings.Join(items, ", "))
+}
+
+func (e *OrderEntry) toValue() *VMValue {
+	return NewArrayValRaw([]*VMValue{NewStrVal(e.Name), NewIntVal(e.Init)})
+}
+
+// readOrderActor 读取行动者，可以是名字，或 [名字, 调整值]
+func readOrderActor(v *VMValue) (string, IntType, bool) {
+	if name, ok := v.ReadString(); ok {
+		return name, 0, true
+	}
+	if ad, ok := v.ReadArray(); ok && len(ad.List) == 2 {
+		name, ok1 := ad.List[0].ReadString()
+		bonus, ok2 := ad.List[1].ReadInt()
+		return name, bonus, ok1 && ok2
+	}
+	return "", 0, false
+}
+
+// funcInitRoll 投掷先攻，每位行动者骰 1d面数 加上调整值，如 init_roll(['战士', ['游荡者', 4], ['哥布林', 2]])
+func funcInitRoll(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
+	actors := ctx.iterToList(params[0])
+	if ctx.Error != nil {
+		ctx.Error = errors.New("(init_roll)" + ctx.Error.Error())
+		return nil
+	}
+	sides, ok := params[1].ReadInt()
+	if !ok || sides <= 0 {
+		ctx.Error = errors.New("(init_roll)值错误: 面数必须为正整数")
+		return nil
+	}
+	entries := make([]*OrderEntry, len(actors))
+	for i, actor := range actors {
+		name, bonus, ok := readOrderActor(actor)
+		if !ok {
+			ctx.Error = fmt.Errorf("(init_roll)类型错误: 行动者必须为名字或 [名字, 调整值]，不能为 %s", actor.ToRepr())
+			return nil
+		}
+		roll := Roll(ctx.RandSrc, sides, 0)
+		ctx.recordBuiltinRoll(fmt.Sprintf("d%d", sides), NewIntVal(roll), "dice")
+		entries[i] = &OrderEntry{Name: name, Init: roll + bonus, Bonus: bonus}
+	}
+	return NewOrderVal(entries)
+}
+
+func funcOrderNext(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
+	od, _ := this.ReadOrder()
+	if e := od.Next(); e != nil {
+		return NewStrVal(e.Name)
+	}
+	return NewNullVal()
+}
+
+func funcOrderCurrent(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
+	od, _ := this.ReadOrder()
+	if e := od.Current(); e != nil {
+		return NewStrVal(e.Name)
+	}
+	return NewNullVal()
+}
+
+func funcOrderRound(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
+	od, _ := this.ReadOrder()
+	return NewIntVal(od.Round)
+}
+
+func funcOrderList(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
+	od, _ := this.ReadOrder()
+	lst := make([]*VMValue, len(od.Entries))
+	for i, e := range od.Entries {
+		lst[i] = e.toValue()
+	}
+	return NewArrayValRaw(lst)
+}
+
+func funcOrderAdd(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
+	od, _ := this.ReadOrder()
+	name, ok := params[0].ReadString()
+	if !ok {
+		ctx.Error = errors.New("(Order.add)类型错误: 名字必须为str")
+		return nil
+	}
+	init, ok := params[1].ReadInt()
+	if !ok {
+		ctx.Error = errors.New("(Order.add)类型错误: 先攻值必须为int")
+		return nil
+	}
+	od.Add(&OrderEntry{Name: name, Init: init})
+	return this
+}
+
+func funcOrderRemove(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
+	od, _ := this.ReadOrder()
+	name, ok := params[0].ReadString()
+	if !ok {
+		ctx.Error = errors.New("(Order.remove)类型错误: 名字必须为str")
+		return nil
+	}
+	return boolToVMValue(od.Remove(name))
+}
+
+func funcOrderReset(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
+	od, _ := this.ReadOrder()
+	od.Index = 0
+	od.Round = 1
+	return this
+}
@@ -0,0 +1,93 @@
+package dicescript
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInitRoll(t *testing.T) {
+	vm := NewVM()
+	err := vm.Run("init_roll(['战士', ['游荡者', 4], ['哥布林', -2]]).list()")
+	if assert.NoError(t, err) {
+		lst := vm.Ret.MustReadArray().List
+		assert.Len(t, lst, 3)
+		last := IntType(1 << 30)
+		for _, item := range lst {
+			init := item.MustReadArray().List[1].MustReadInt()
+			assert.True(t, init <= last)
+			last = init
+		}
+	}
+
+	// 面数为1时先攻值固定，相同时调整值高者在前
+	vm = NewVM()
+	err = vm.Run("o = init_roll(['战士', ['游荡者', 4], ['法师', 4], ['哥布林', -2]], 1); o")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "第1轮: [游荡者 5], 法师 5, 战士 1, 哥布林 -1", vm.Ret.ToString())
+	}
+
+	vm = NewVM()
+	err = vm.Run("o = init_roll(['甲', ['乙', 1]], 1); [o.current(), o.next(), o.round(), o.next(), o.round()]")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, na(ns("乙"), ns("甲"), ni(1), ns("乙"), ni(2))))
+	}
+
+	// 加入与移出不影响当前行动者
+	vm = NewVM()
+	err = vm.Run("o = init_roll([['甲', 10], ['乙', 5]], 1); o.next(); o.add('丙', 20); o.remove('甲'); [o.current(), list(o), o.next(), o.round()]")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, na(ns("乙"), na(ns("丙"), ns("乙")), ns("丙"), ni(2))))
+	}
+
+	vm = NewVM()
+	err = vm.Run("o = init_roll([['甲', 10], ['乙', 5]], 1); o.next(); o.remove('乙'); [o.current(), o.round()]")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, na(ns("甲"), ni(1))))
+	}
+
+	vm = NewVM()
+	err = vm.Run("o = init_roll([]); [o.current(), o.next(), o ? 1 : 0]")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, na(NewNullVal(), NewNullVal(), ni(0))))
+	}
+
+	for _, expr := range []string{"init_roll([1])", "init_roll(['a'], 0)", "init_roll(1)", "init_roll(['a']).add('b', 'c')"} {
+		vm = NewVM()
+		err = vm.Run(expr)
+		assert.Error(t, err, expr)
+	}
+}
+
+func TestOrderJSON(t *testing.T) {
+	p := &testAttrProvider{}
+	vm := NewVM(WithAttrProvider(p))
+	err := vm.Run("先攻 = init_roll([['甲', 10], ['乙', 5], ['丙', 1]], 1); 先攻.next()")
+	assert.NoError(t, err)
+	data, err := p.m.MustLoad("先攻").ToJSON()
+	if assert.NoError(t, err) {
+		assert.Equal(t, `{"t":19,"v":{"entries":[{"name":"甲","init":11,"bonus":10},{"name":"乙","init":6,"bonus":5},{"name":"丙","init":2,"bonus":1}],"index":1,"round":1}}`, string(data))
+	}
+	v, err := VMValueFromJSON(data)
+	assert.NoError(t, err)
+	p.m.Store("先攻", v)
+
+	// 下次执行时继续
+	vm = NewVM(WithAttrProvider(p))
+	err = vm.Run("[先攻.next(), 先攻.next(), 先攻.round()]")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, na(ns("丙"), ns("甲"), ni(2))))
+	}
+}
+
+func TestOrderJSONInvalid(t *testing.T) {
+	_, err := VMValueFromJSON([]byte(`{"t":19,"v":{"entries":[null],"index":0,"round":1}}`))
+	assert.Error(t, err)
+
+	v, err := VMValueFromJSON([]byte(`{"t":19,"v":{"entries":[{"name":"甲","init":1}],"index":5,"round":0}}`))
+	if assert.NoError(t, err) {
+		od, _ := v.ReadOrder()
+		assert.Equal(t, 0, od.Index)
+		assert.Equal(t, IntType(1), od.Round)
+	}
+}
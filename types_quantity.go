@@ -0,0 +1,142 @@
+package dicescript
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Unit 计量单位，同一量纲的单位之间按倍数换算
+type Unit struct {
+	Name      string
+	Dimension string  // 量纲，如长度、重量，不同量纲的量之间不能运算
+	Factor    float64 // 折合为该量纲基准单位的倍数，如以米为基准时km为1000
+}
+
+// UnitTable 单位换算表，由宿主程序注册后放入 RollConfig.Units
+type UnitTable struct {
+	units map[string]*Unit
+}
+
+func NewUnitTable() *UnitTable {
+	return &UnitTable{units: map[string]*Unit{}}
+}
+
+// Register 注册一个单位，同名的单位会被覆盖
+func (t *UnitTable) Register(name string, dimension string, factor float64) *UnitTable {
+	t.units[name] = &Unit{Name: name, Dimension: dimension, Factor: factor}
+	return t
+}
+
+func (t *UnitTable) Get(name string) (*Unit, bool) {
+	if t == nil {
+		return nil, false
+	}
+	u, ok := t.units[name]
+	return u, ok
+}
+
+// Convert 将value从from单位换算为to单位
+func (t *UnitTable) Convert(value float64, from string, to string) (float64, error) {
+	if from == to {
+		return value, nil
+	}
+	u1, ok := t.Get(from)
+	if !ok {
+		return 0, fmt.Errorf("单位错误: 未知的单位 %s", from)
+	}
+	u2, ok := t.Get(to)
+	if !ok {
+		return 0, fmt.Errorf("单位错误: 未知的单位 %s", to)
+	}
+	if u1.Dimension != u2.Dimension {
+		return 0, fmt.Errorf("单位错误: %s 和 %s 无法换算", from, to)
+	}
+	return value * u1.Factor / u2.Factor, nil
+}
+
+// QuantityData 带单位的数
+type QuantityData struct {
+	Value float64 `json:"value"`
+	Unit  string  `json:"unit"`
+}
+
+func NewQuantityVal(value float64, unit string) *VMValue {
+	return &VMValue{TypeId: VMTypeQuantity, Value: QuantityData{Value: value, Unit: unit}}
+}
+
+func (v *VMValue) ReadQuantity() (QuantityData, bool) {
+	if v.TypeId == VMTypeQuantity {
+		return v.Value.(QuantityData), true
+	}
+	return QuantityData{}, false
+}
+
+func (q QuantityData) String() string {
+	return formatFloat(q.Value) + q.Unit
+}
+
+// alignQuantity 将v2换算为v的单位，返回两者的数值。v2不是带单位的数时ok为false
+func (v *VMValue) alignQuantity(ctx *Context, v2 *VMValue) (a float64, b float64, ok bool) {
+	q1 := v.Value.(QuantityData)
+	q2, ok := v2.ReadQuantity()
+	if !ok {
+		return 0, 0, false
+	}
+	b, err := ctx.Config.Units.Convert(q2.Value, q2.Unit, q1.Unit)
+	if err != nil {
+		ctx.Error = err
+		return 0, 0, false
+	}
+	return q1.Value, b, true
+}
+
+func (v *VMValue) quantityMultiply(v2 *VMValue) *VMValue {
+	q := v.Value.(QuantityData)
+	if n, ok := readNumber(v2); ok {
+		return NewQuantityVal(q.Value*n, q.Unit)
+	}
+	return nil
+}
+
+func funcToQuantity(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
+	n, ok := readNumber(params[0])
+	if !ok {
+		ctx.Error = errors.New("(toQuantity)类型错误: 数值必须为int或float")
+		return nil
+	}
+	unit, ok := params[1].ReadString()
+	if !ok {
+		ctx.Error = errors.New("(toQuantity)类型错误: 单位必须为str")
+		return nil
+	}
+	if _, ok := ctx.Config.Units.Get(unit); !ok {
+		ctx.Error = fmt.Errorf("(toQuantity)单位错误: 未知的单位 %s", unit)
+		return nil
+	}
+	return NewQuantityVal(n, unit)
+}
+
+func funcQuantityTo(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
+	q, _ := this.ReadQuantity()
+	unit, ok := params[0].ReadString()
+	if !ok {
+		ctx.Error = errors.New("(Quantity.to)类型错误: 单位必须为str")
+		return nil
+	}
+	val, err := ctx.Config.Units.Convert(q.Value, q.Unit, unit)
+	if err != nil {
+		ctx.Error = errors.New("(Quantity.to)" + err.Error())
+		return nil
+	}
+	return NewQuantityVal(val, unit)
+}
+
+func funcQuantityValue(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
+	q, _ := this.ReadQuantity()
+	return NewFloatVal(q.Value)
+}
+
+func funcQuantityUnit(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
+	q, _ := this.ReadQuantity()
+	return NewStrVal(q.Unit)
+}
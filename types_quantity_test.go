@@ -0,0 +1,58 @@
+package dicescript
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newUnitTestVM() *Context {
+	vm := NewVM()
+	vm.Config.Units = NewUnitTable().
+		Register("m", "长度", 1).
+		Register("km", "长度", 1000).
+		Register("尺", "长度", 1.0/3).
+		Register("kg", "重量", 1).
+		Register("g", "重量", 0.001)
+	return vm
+}
+
+func TestQuantity(t *testing.T) {
+	vm := newUnitTestVM()
+	err := vm.Run("[5kg + 500g, 1km - 200m, 2.5kg * 2, 3 * 2m, 6m / 4, 1km / 500m, -3m]")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "[5.5kg, 0.8km, 5kg, 6m, 1.5m, 2, -3m]", vm.Ret.ToString())
+	}
+
+	vm = newUnitTestVM()
+	err = vm.Run("[1km == 1000m, 1km > 999m, 3尺 <= 1m, 1kg == 1m, (1200g).to('kg'), (5kg).value(), (5kg).unit(), toQuantity(d1 + 2, 'kg')]")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "[true, true, true, false, 1.2kg, 5, 'kg', 3kg]", vm.Ret.ToString())
+	}
+
+	// 未注册的m仍为时长
+	vm = NewVM()
+	err = vm.Run("3m")
+	if assert.NoError(t, err) {
+		assert.Equal(t, VMTypeDuration, vm.Ret.TypeId)
+	}
+
+	// 不同量纲、带单位与不带单位的数不能混用
+	for _, expr := range []string{"5kg + 3m", "5kg + 3", "5kg * 2kg", "5kg < 3m", "(5kg).to('m')", "toQuantity(1, 'lb')", "5kg / 0"} {
+		vm = newUnitTestVM()
+		err = vm.Run(expr)
+		assert.Error(t, err, expr)
+	}
+}
+
+func TestQuantityJSON(t *testing.T) {
+	v := NewQuantityVal(2.5, "kg")
+	data, err := v.ToJSON()
+	if assert.NoError(t, err) {
+		assert.Equal(t, `{"t":16,"v":{"value":2.5,"unit":"kg"}}`, string(data))
+	}
+	v2, err := VMValueFromJSON(data)
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(v, v2))
+	}
+}
@@ -0,0 +1,122 @@
+package dicescript
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"golang.org/x/exp/rand"
+)
+
+// RangeData 范围 a..b，包含两端的整数。只记录两端，不会创建数组，因此 1..1000000 也不占空间
+type RangeData struct {
+	Start IntType `json:"start"`
+	End   IntType `json:"end"`
+}
+
+func NewRangeVal(start IntType, end IntType) *VMValue {
+	return &VMValue{TypeId: VMTypeRange, Value: &RangeData{Start: start, End: end}}
+}
+
+func (v *VMValue) ReadRange() (*RangeData, bool) {
+	if v.TypeId == VMTypeRange {
+		return v.Value.(*RangeData), true
+	}
+	return nil, false
+}
+
+// Len 范围中整数的个数，End小于Start时为0
+func (rd *RangeData) Len() IntType {
+	if rd.End < rd.Start {
+		return 0
+	}
+	return rd.End - rd.Start + 1
+}
+
+func (rd *RangeData) Has(n IntType) bool {
+	return rd.Start <= n && n <= rd.End
+}
+
+func (rd *RangeData) String() string {
+	return fmt.Sprintf("%d..%d", rd.Start, rd.End)
+}
+
+func (rd *RangeData) attrGet(name string) *VMValue {
+	switch name {
+	case "start":
+		return NewIntVal(rd.Start)
+	case "end":
+		return NewIntVal(rd.End)
+	}
+	return nil
+}
+
+func (rd *RangeData) iter() valueIterator {
+	if rd.Len() == 0 {
+		return &arrayIterator{}
+	}
+	return &rangeInclusiveIterator{cur: rd.Start, end: rd.End}
+}
+
+// rangeInclusiveIterator 与 rangeIterator 不同，包含end，end为最大的整数时也不会溢出
+type rangeInclusiveIterator struct {
+	cur, end IntType
+	done     bool
+}
+
+func (it *rangeInclusiveIterator) next(ctx *Context) (*VMValue, bool) {
+	if it.done {
+		return nil, false
+	}
+	v := NewIntVal(it.cur)
+	if it.cur == it.end {
+		it.done = true
+	} else {
+		it.cur++
+	}
+	return v, true
+}
+
+// newRange 创建范围，两端必须为int
+func newRange(ctx *Context, a *VMValue, b *VMValue) *VMValue {
+	start, ok1 := a.ReadInt()
+	end, ok2 := b.ReadInt()
+	if !ok1 || !ok2 {
+		ctx.Error = fmt.Errorf("类型错误: 范围的两端必须为int，不能为 %s..%s", a.GetTypeName(), b.GetTypeName())
+		return nil
+	}
+	return NewRangeVal(start, end)
+}
+
+// rollRange 以范围为面数投掷times个骰子，每个骰子等概率得到范围中的一个数，如 d(3..8)。范围不能为空
+func rollRange(src *rand.PCGSource, rd *RangeData, times IntType, mode int) (IntType, string) {
+	var text strings.Builder
+	sum := IntType(0)
+	for i := IntType(0); i < times; i++ {
+		v := rd.Start + Roll(src, rd.Len(), mode) - 1
+		sum += v
+		if i > 0 {
+			text.WriteByte('+')
+		}
+		text.WriteString(strconv.FormatInt(int64(v), 10))
+	}
+	if times == 1 {
+		// 与普通骰子一致，只有一个骰子时不显示骰点
+		return sum, ""
+	}
+	return sum, text.String()
+}
+
+func funcRangeLen(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
+	rd, _ := this.ReadRange()
+	return NewIntVal(rd.Len())
+}
+
+// funcRangeList 转为数组，每一项消耗1点算力
+func funcRangeList(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
+	lst := ctx.iterToList(this)
+	if ctx.Error != nil {
+		return nil
+	}
+	return NewArrayValRaw(lst)
+}
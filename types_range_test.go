@@ -0,0 +1,72 @@
+package dicescript
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRangeValue(t *testing.T) {
+	vm := NewVM()
+	err := vm.Run("r = 1..20; [r, 5 in r, 25 in r, 1+1..2+3, r.len(), r.start, r.end, `{r}`]")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, na(
+			NewRangeVal(1, 20), nb(true), nb(false), NewRangeVal(2, 5),
+			ni(20), ni(1), ni(20), ns("1..20"),
+		)))
+	}
+
+	// 不会生成数组
+	err = vm.Run("(1..1000000000).len()")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, ni(1000000000)))
+	}
+
+	err = vm.Run("a = 0; for i in 1..4 { a += i }; [a, (3..5).list(), [...(1..3)], [1..3], (5..1).len(), 1..3 == 1..3]")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, na(
+			ni(10), na(ni(3), ni(4), ni(5)), na(ni(1), ni(2), ni(3)), na(ni(1), ni(2), ni(3)), ni(0), nb(true),
+		)))
+	}
+
+	err = vm.Run("r = 1..5; [match 3 { r => 'x', _ => 'y' }, match 6 { r => 'x', _ => 'y' }]")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, na(ns("x"), ns("y"))))
+	}
+
+	err = vm.Run("1..'a'")
+	assert.Error(t, err)
+}
+
+func TestRangeDice(t *testing.T) {
+	vm := NewVM()
+	vm.Config.DiceMaxMode = true
+	err := vm.Run("3d(5..8)")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, ni(24)))
+		assert.Equal(t, "24[3d(5..8)=8+8+8]", vm.GetDetailText())
+	}
+
+	vm.Config.DiceMaxMode = false
+	vm.Config.DiceMinMode = true
+	err = vm.Run("d(5..8)")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, ni(5)))
+	}
+
+	err = vm.Run("d(5..1)")
+	assert.Error(t, err)
+	err = vm.Run("2d(1..6)k1")
+	assert.Error(t, err)
+}
+
+func TestRangeJSON(t *testing.T) {
+	v := NewRangeVal(-2, 7)
+	data, err := v.ToJSON()
+	if assert.NoError(t, err) {
+		v2, err := VMValueFromJSON(data)
+		if assert.NoError(t, err) {
+			assert.True(t, valueEqual(v, v2))
+		}
+	}
+}
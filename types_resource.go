@@ -0,0 +1,151 @@
+package dicescript
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ResourceData 有上下限的资源，如HP、SAN、MP，数值总是被限制在 [Min, Max] 之间
+type ResourceData struct {
+	Name  string  `json:"name,omitempty"` // 变量名，设置后每次变动都会写回该变量，从而经过 HookValueStore
+	Value IntType `json:"value"`
+	Min   IntType `json:"min"`
+	Max   IntType `json:"max"`
+
+	Change IntType `json:"-"` // 最近一次变动的实际数值，受上下限影响可能与请求的不同
+	Reason string  `json:"-"` // 最近一次变动的原因: spend heal set
+}
+
+// NewResourceVal 创建资源，name不为空时，脚本中的变动会写回同名变量
+func NewResourceVal(name string, value IntType, min IntType, max IntType) *VMValue {
+	rd := &ResourceData{Name: name, Min: min, Max: max}
+	rd.Value = rd.clamp(value)
+	return &VMValue{TypeId: VMTypeResource, Value: rd}
+}
+
+func (v *VMValue) ReadResource() (*ResourceData, bool) {
+	if v.TypeId == VMTypeResource {
+		return v.Value.(*ResourceData), true
+	}
+	return nil, false
+}
+
+func (rd *ResourceData) clamp(value IntType) IntType {
+	if value < rd.Min {
+		return rd.Min
+	}
+	if value > rd.Max {
+		return rd.Max
+	}
+	return value
+}
+
+// Set 设置数值，返回实际的变动量
+func (rd *ResourceData) Set(value IntType, reason string) IntType {
+	old := rd.Value
+	rd.Value = rd.clamp(value)
+	rd.Change = rd.Value - old
+	rd.Reason = reason
+	return rd.Change
+}
+
+func (rd *ResourceData) String() string {
+	return fmt.Sprintf("%d/%d", rd.Value, rd.Max)
+}
+
+// resourceChanged 资源变动后写回变量，使宿主程序可以在 HookValueStore 中记录变动
+func (ctx *Context) resourceChanged(v *VMValue) {
+	rd, _ := v.ReadResource()
+	if rd.Name != "" {
+		ctx.StoreName(rd.Name, v, true)
+	}
+}
+
+func funcResource(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
+	value, ok1 := params[0].ReadInt()
+	max, ok2 := params[1].ReadInt()
+	min, ok3 := params[2].ReadInt()
+	if !ok1 || !ok2 || !ok3 {
+		ctx.Error = errors.New("(resource)类型错误: 数值和上下限必须为int")
+		return nil
+	}
+	if min > max {
+		ctx.Error = fmt.Errorf("(resource)值错误: 下限%d大于上限%d", min, max)
+		return nil
+	}
+	var name string
+	if !params[3].IsNullish() {
+		if name, ok1 = params[3].ReadString(); !ok1 {
+			ctx.Error = errors.New("(resource)类型错误: 名字必须为str")
+			return nil
+		}
+	}
+	return NewResourceVal(name, value, min, max)
+}
+
+func resourceAmount(ctx *Context, method string, v *VMValue) (IntType, bool) {
+	n, ok := v.ReadInt()
+	if !ok {
+		ctx.Error = fmt.Errorf("(Resource.%s)类型错误: 数量必须为int", method)
+		return 0, false
+	}
+	if n < 0 {
+		ctx.Error = fmt.Errorf("(Resource.%s)值错误: 数量不能为负数", method)
+		return 0, false
+	}
+	return n, true
+}
+
+func funcResourceSpend(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
+	rd, _ := this.ReadResource()
+	n, ok := resourceAmount(ctx, "spend", params[0])
+	if !ok {
+		return nil
+	}
+	rd.Set(addIntSaturated(rd.Value, -n), "spend")
+	ctx.resourceChanged(this)
+	return this
+}
+
+func funcResourceHeal(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
+	rd, _ := this.ReadResource()
+	n, ok := resourceAmount(ctx, "heal", params[0])
+	if !ok {
+		return nil
+	}
+	rd.Set(addIntSaturated(rd.Value, n), "heal")
+	ctx.resourceChanged(this)
+	return this
+}
+
+func funcResourceSet(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
+	rd, _ := this.ReadResource()
+	n, ok := params[0].ReadInt()
+	if !ok {
+		ctx.Error = errors.New("(Resource.set)类型错误: 数值必须为int")
+		return nil
+	}
+	rd.Set(n, "set")
+	ctx.resourceChanged(this)
+	return this
+}
+
+func funcResourceValue(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
+	rd, _ := this.ReadResource()
+	return NewIntVal(rd.Value)
+}
+
+func funcResourceMax(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
+	rd, _ := this.ReadResource()
+	return NewIntVal(rd.Max)
+}
+
+func funcResourceMin(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
+	rd, _ := this.ReadResource()
+	return NewIntVal(rd.Min)
+}
+
+func funcResourceChange(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
+	rd, _ := this.ReadResource()
+	return NewIntVal(rd.Change)
+}
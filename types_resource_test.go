@@ -0,0 +1,69 @@
+package dicescript
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResource(t *testing.T) {
+	vm := NewVM()
+	err := vm.Run("hp = resource(10, 12); [hp.spend(3).value(), hp.heal(20).value(), hp.change(), hp.spend(100).value(), hp.min(), hp.max()]")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, na(ni(7), ni(12), ni(5), ni(0), ni(0), ni(12))))
+	}
+
+	// 参与运算时视为其数值
+	vm = NewVM()
+	err = vm.Run("san = resource(60, 99); san.spend(1d1); [san, san + 1, san <= 59, san ? 1 : 0, san ?? 1]")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "[59/99, 60, true, 1, 59/99]", vm.Ret.ToString())
+	}
+
+	vm = NewVM()
+	err = vm.Run("r = resource(5, 10, -5); r.set(-20); r.value()")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, ni(-5)))
+	}
+
+	// 数量很大时不会溢出
+	vm = NewVM()
+	err = vm.Run("hp = resource(5, 10); r = resource(-5, 10, -10); [hp.heal(9223372036854775807).value(), r.spend(9223372036854775807).value()]")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, na(ni(10), ni(-10))))
+	}
+
+	for _, expr := range []string{"resource(1, 0, 2)", "resource('a', 1)", "resource(1, 5).spend(-1)", "resource(1, 5).heal('a')"} {
+		vm = NewVM()
+		err = vm.Run(expr)
+		assert.Error(t, err, expr)
+	}
+}
+
+func TestResourceStoreHook(t *testing.T) {
+	// 每次变动都写回变量，经过 HookValueStore
+	var changes []IntType
+	p := &testAttrProvider{}
+	p.m.Store("hp", NewResourceVal("hp", 10, 0, 10))
+	vm := NewVM(WithAttrProvider(p))
+	vm.Config.HookValueStore = func(ctx *Context, name string, v *VMValue) (*VMValue, bool) {
+		if rd, ok := v.ReadResource(); ok && name == "hp" {
+			changes = append(changes, rd.Change)
+		}
+		return nil, false
+	}
+	err := vm.Run("hp.spend(4); hp.heal(6)")
+	if assert.NoError(t, err) {
+		assert.Equal(t, []IntType{-4, 4}, changes)
+	}
+
+	data, err := p.m.MustLoad("hp").ToJSON()
+	if assert.NoError(t, err) {
+		assert.Equal(t, `{"t":30,"v":{"name":"hp","value":10,"min":0,"max":10}}`, string(data))
+	}
+	v, err := VMValueFromJSON(data)
+	if assert.NoError(t, err) {
+		rd, _ := v.ReadResource()
+		assert.Equal(t, ResourceData{Name: "hp", Value: 10, Min: 0, Max: 10}, *rd)
+	}
+}
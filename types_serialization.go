@@ -4,6 +4,10 @@ import (
 	"bytes"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
 )
 
 func (v *VMValue) ToJSONRaw(save map[*VMValue]bool) ([]byte, error) {
@@ -15,10 +19,23 @@ func (v *VMValue) ToJSONRaw(save map[*VMValue]bool) ([]byte, error) {
 		fallthrough
 	case VMTypeFloat:
 		fallthrough
-	case VMTypeString:
+	case VMTypeString, VMTypeTime, VMTypeDuration, VMTypeQuantity, VMTypeOrder, VMTypeResource, VMTypeBool, VMTypeError, VMTypeRange:
 		return json.Marshal(v)
 
-	case VMTypeNull:
+	case VMTypeMoney:
+		m, _ := v.ReadMoney()
+		x := struct {
+			TypeId VMValueType `json:"t"`
+			Value  struct {
+				Amount IntType        `json:"amount"`
+				Denoms []Denomination `json:"denoms"`
+			} `json:"v"`
+		}{TypeId: v.TypeId}
+		x.Value.Amount = m.Amount
+		x.Value.Denoms = m.System.Denominations
+		return json.Marshal(x)
+
+	case VMTypeNull, VMTypeUndefined:
 		return json.Marshal(struct {
 			TypeId VMValueType `json:"t"`
 		}{v.TypeId})
@@ -28,12 +45,16 @@ func (v *VMValue) ToJSONRaw(save map[*VMValue]bool) ([]byte, error) {
 		x := struct {
 			TypeId VMValueType `json:"t"`
 			Value  struct {
-				Expr  string          `json:"expr"`
-				Attrs json.RawMessage `json:"attrs,omitempty"`
+				Expr   string          `json:"expr"`
+				Params []string        `json:"params,omitempty"`
+				Memo   bool            `json:"memo,omitempty"`
+				Attrs  json.RawMessage `json:"attrs,omitempty"`
 			} `json:"v"`
 		}{}
 		x.TypeId = v.TypeId
 		x.Value.Expr = cd.Expr
+		x.Value.Params = cd.Params
+		x.Value.Memo = cd.Memo
 		if cd.Attrs != nil {
 			attrJson, err := cd.Attrs.ToJSON()
 			if err != nil {
@@ -67,6 +88,31 @@ func (v *VMValue) ToJSONRaw(save map[*VMValue]bool) ([]byte, error) {
 
 		return bytes.Join(lst2, []byte("")), nil
 
+	case VMTypeTuple:
+		// 元组不可修改，不会出现循环引用
+		td, _ := v.ReadTuple()
+		lst := [][]byte{}
+		for _, i := range td.List {
+			jsonData, err := i.ToJSONRaw(save)
+			if err != nil {
+				return nil, err
+			}
+			lst = append(lst, jsonData)
+		}
+		return []byte(`{"t":31,"v":{"list":[` + string(bytes.Join(lst, []byte(","))) + `]}}`), nil
+
+	case VMTypeSet:
+		sd, _ := v.ReadSet()
+		lst := [][]byte{}
+		for _, i := range sd.List() {
+			jsonData, err := i.ToJSONRaw(save)
+			if err != nil {
+				return nil, err
+			}
+			lst = append(lst, jsonData)
+		}
+		return []byte(`{"t":32,"v":{"list":[` + string(bytes.Join(lst, []byte(","))) + `]}}`), nil
+
 	case VMTypeDict:
 		if save == nil {
 			save = map[*VMValue]bool{}
@@ -77,13 +123,29 @@ func (v *VMValue) ToJSONRaw(save map[*VMValue]bool) ([]byte, error) {
 		save[v] = true
 		cd := v.MustReadDictData()
 
-		dictJson, err := cd.Dict.ToJSON()
+		// 键以文本形式储存，数字等非字符串键另外记录
+		plain, typed, err := dictTextKeys(cd.Dict)
+		if err != nil {
+			return nil, err
+		}
+		dictJson, err := plain.ToJSON()
 		if err != nil {
 			return nil, err
 		}
 
 		lst2 := [][]byte{[]byte(`{"t":7,"v":{"dict":`)}
 		lst2 = append(lst2, dictJson)
+		if len(typed) > 0 {
+			var keys [][]byte
+			for _, k := range typed {
+				keyJson, err := k.ToJSONRaw(save)
+				if err != nil {
+					return nil, err
+				}
+				keys = append(keys, keyJson)
+			}
+			lst2 = append(lst2, []byte(`,"keys":[`), bytes.Join(keys, []byte(",")), []byte("]"))
+		}
 		lst2 = append(lst2, []byte("}}"))
 
 		return bytes.Join(lst2, []byte("")), nil
@@ -119,6 +181,65 @@ func (v *VMValue) ToJSONRaw(save map[*VMValue]bool) ([]byte, error) {
 				Name string `json:"name"`
 			}{fd.Name},
 		})
+	case VMTypeIterator:
+		return nil, errors.New("值错误: 迭代器无法被序列化")
+	case VMTypeCheck:
+		cd, _ := v.ReadCheck()
+		x := struct {
+			TypeId VMValueType `json:"t"`
+			Value  struct {
+				Success bool            `json:"success"`
+				Op      string          `json:"op"`
+				Value   json.RawMessage `json:"value"`
+				Target  json.RawMessage `json:"target"`
+				Level   string          `json:"level"`
+			} `json:"v"`
+		}{TypeId: v.TypeId}
+		x.Value.Success, x.Value.Op, x.Value.Level = cd.Success, cd.Op, cd.Level
+		var err error
+		if x.Value.Value, err = cd.Value.ToJSONRaw(save); err != nil {
+			return nil, err
+		}
+		if x.Value.Target, err = cd.Target.ToJSONRaw(save); err != nil {
+			return nil, err
+		}
+		return json.Marshal(x)
+	case VMTypeDeck:
+		d, _ := v.ReadDeck()
+		x := struct {
+			TypeId VMValueType `json:"t"`
+			Value  struct {
+				Cards json.RawMessage `json:"cards"`
+				Drawn json.RawMessage `json:"drawn"`
+			} `json:"v"`
+		}{TypeId: v.TypeId}
+		var err error
+		if x.Value.Cards, err = NewArrayValRaw(d.Cards).ToJSONRaw(save); err != nil {
+			return nil, err
+		}
+		if x.Value.Drawn, err = NewArrayValRaw(d.Drawn).ToJSONRaw(save); err != nil {
+			return nil, err
+		}
+		return json.Marshal(x)
+	case VMTypeTable:
+		t, _ := v.ReadTable()
+		x := struct {
+			TypeId VMValueType `json:"t"`
+			Value  struct {
+				Header []string          `json:"header,omitempty"`
+				Rows   []json.RawMessage `json:"rows"`
+			} `json:"v"`
+		}{TypeId: v.TypeId}
+		x.Value.Header = t.Header
+		x.Value.Rows = make([]json.RawMessage, len(t.Rows))
+		for i, row := range t.Rows {
+			data, err := NewArrayValRaw(row).ToJSONRaw(save)
+			if err != nil {
+				return nil, err
+			}
+			x.Value.Rows[i] = data
+		}
+		return json.Marshal(x)
 	case VMTypeNativeObject:
 		fd, _ := v.ReadNativeObjectData()
 		return json.Marshal(struct {
@@ -171,6 +292,15 @@ func (v *VMValue) UnmarshalJSON(input []byte) error {
 			v.Value = NewFloatVal(v1.Value).Value
 		}
 		return err
+	case VMTypeBool:
+		var v1 struct {
+			Value bool `json:"v"`
+		}
+		err := json.Unmarshal(input, &v1)
+		if err == nil {
+			v.Value = v1.Value
+		}
+		return err
 	case VMTypeString:
 		var v1 struct {
 			Value string `json:"v"`
@@ -180,19 +310,69 @@ func (v *VMValue) UnmarshalJSON(input []byte) error {
 			v.Value = NewStrVal(v1.Value).Value
 		}
 		return err
-	case VMTypeNull:
+	case VMTypeTime:
+		var v1 struct {
+			Value time.Time `json:"v"`
+		}
+		err := json.Unmarshal(input, &v1)
+		if err == nil {
+			v.Value = v1.Value
+		}
+		return err
+	case VMTypeDuration:
+		var v1 struct {
+			Value time.Duration `json:"v"`
+		}
+		err := json.Unmarshal(input, &v1)
+		if err == nil {
+			v.Value = v1.Value
+		}
+		return err
+	case VMTypeQuantity:
+		var v1 struct {
+			Value QuantityData `json:"v"`
+		}
+		err := json.Unmarshal(input, &v1)
+		if err == nil {
+			v.Value = v1.Value
+		}
+		return err
+	case VMTypeMoney:
+		var v1 struct {
+			Value struct {
+				Amount IntType        `json:"amount"`
+				Denoms []Denomination `json:"denoms"`
+			} `json:"v"`
+		}
+		if err := json.Unmarshal(input, &v1); err != nil {
+			return err
+		}
+		cs, err := NewCurrencySystem(v1.Value.Denoms...)
+		if err != nil {
+			return err
+		}
+		if cs.equal(DefaultCurrency) {
+			cs = DefaultCurrency
+		}
+		v.Value = MoneyData{Amount: v1.Value.Amount, System: cs}
+		return nil
+	case VMTypeNull, VMTypeUndefined:
 		return nil
 	case VMTypeComputedValue:
 		var v1 struct {
 			Value struct {
-				Expr  string          `json:"expr"`
-				Attrs json.RawMessage `json:"attrs,omitempty"`
+				Expr   string          `json:"expr"`
+				Params []string        `json:"params,omitempty"`
+				Memo   bool            `json:"memo,omitempty"`
+				Attrs  json.RawMessage `json:"attrs,omitempty"`
 			} `json:"v"`
 		}
 		err := json.Unmarshal(input, &v1)
 		if err == nil {
 			cd := &ComputedData{
-				Expr: v1.Value.Expr,
+				Expr:   v1.Value.Expr,
+				Params: v1.Value.Params,
+				Memo:   v1.Value.Memo,
 			}
 			if v1.Value.Attrs != nil {
 				cd.Attrs = &ValueMap{}
@@ -214,17 +394,185 @@ func (v *VMValue) UnmarshalJSON(input []byte) error {
 			v.Value = NewArrayValRaw(v1.Value.List).Value
 		}
 		return err
+	case VMTypeTuple:
+		var v1 struct {
+			Value struct {
+				List []*VMValue `json:"list"`
+			} `json:"v"`
+		}
+		err := json.Unmarshal(input, &v1)
+		if err == nil {
+			v.Value = &TupleData{v1.Value.List}
+		}
+		return err
+	case VMTypeSet:
+		var v1 struct {
+			Value struct {
+				List []*VMValue `json:"list"`
+			} `json:"v"`
+		}
+		if err := json.Unmarshal(input, &v1); err != nil {
+			return err
+		}
+		sv, err := NewSetVal(v1.Value.List...)
+		if err != nil {
+			return err
+		}
+		v.Value = sv.Value
+		return nil
 	case VMTypeDict:
 		var v1 struct {
 			Value struct {
-				Dict ValueMap `json:"dict"`
+				Dict ValueMap   `json:"dict"`
+				Keys []*VMValue `json:"keys"`
+			} `json:"v"`
+		}
+
+		if err := json.Unmarshal(input, &v1); err != nil {
+			return err
+		}
+		dict, err := dictFromTextKeys(&v1.Value.Dict, v1.Value.Keys)
+		if err != nil {
+			return err
+		}
+		v.Value = NewDictVal(dict).Value
+		return nil
+
+	case VMTypeDeck:
+		var v1 struct {
+			Value struct {
+				Cards *VMValue `json:"cards"`
+				Drawn *VMValue `json:"drawn"`
+			} `json:"v"`
+		}
+		if err := json.Unmarshal(input, &v1); err != nil {
+			return err
+		}
+		d := &DeckData{}
+		if v1.Value.Cards != nil {
+			if ad, ok := v1.Value.Cards.ReadArray(); ok {
+				d.Cards = ad.List
+			}
+		}
+		if v1.Value.Drawn != nil {
+			if ad, ok := v1.Value.Drawn.ReadArray(); ok {
+				d.Drawn = ad.List
+			}
+		}
+		v.Value = d
+		return nil
+
+	case VMTypeResource:
+		var v1 struct {
+			Value ResourceData `json:"v"`
+		}
+		if err := json.Unmarshal(input, &v1); err != nil {
+			return err
+		}
+		if v1.Value.Min > v1.Value.Max {
+			return errors.New("值错误: 资源的下限大于上限")
+		}
+		rd := v1.Value
+		v.Value = &rd
+		return nil
+
+	case VMTypeError:
+		var v1 struct {
+			Value ErrorData `json:"v"`
+		}
+		if err := json.Unmarshal(input, &v1); err != nil {
+			return err
+		}
+		ed := v1.Value
+		v.Value = &ed
+		return nil
+
+	case VMTypeRange:
+		var v1 struct {
+			Value RangeData `json:"v"`
+		}
+		if err := json.Unmarshal(input, &v1); err != nil {
+			return err
+		}
+		rd := v1.Value
+		v.Value = &rd
+		return nil
+
+	case VMTypeOrder:
+		var v1 struct {
+			Value *OrderData `json:"v"`
+		}
+		if err := json.Unmarshal(input, &v1); err != nil {
+			return err
+		}
+		if v1.Value == nil {
+			v1.Value = &OrderData{Round: 1}
+		}
+		for _, e := range v1.Value.Entries {
+			if e == nil {
+				return errors.New("值错误: 先攻顺序中有空的行动者")
+			}
+		}
+		if v1.Value.Index < 0 || v1.Value.Index >= len(v1.Value.Entries) {
+			v1.Value.Index = 0
+		}
+		if v1.Value.Round < 1 {
+			v1.Value.Round = 1
+		}
+		v.Value = v1.Value
+		return nil
+
+	case VMTypeCheck:
+		var v1 struct {
+			Value struct {
+				Success bool     `json:"success"`
+				Op      string   `json:"op"`
+				Value   *VMValue `json:"value"`
+				Target  *VMValue `json:"target"`
+				Level   string   `json:"level"`
 			} `json:"v"`
 		}
+		if err := json.Unmarshal(input, &v1); err != nil {
+			return err
+		}
+		if v1.Value.Value == nil || v1.Value.Target == nil {
+			return errors.New("值错误: 检定结果缺少比较的值")
+		}
+		v.Value = &CheckData{
+			Success: v1.Value.Success,
+			Op:      v1.Value.Op,
+			Value:   v1.Value.Value,
+			Target:  v1.Value.Target,
+			Level:   v1.Value.Level,
+		}
+		return nil
 
+	case VMTypeTable:
+		var v1 struct {
+			Value struct {
+				Header []string   `json:"header,omitempty"`
+				Rows   []*VMValue `json:"rows"`
+			} `json:"v"`
+		}
 		if err := json.Unmarshal(input, &v1); err != nil {
 			return err
 		}
-		v.Value = NewDictVal(&v1.Value.Dict).Value
+		rows := make([][]*VMValue, len(v1.Value.Rows))
+		for i, row := range v1.Value.Rows {
+			if row == nil {
+				return errors.New("值错误: 表格的每行必须为数组")
+			}
+			ad, ok := row.ReadArray()
+			if !ok {
+				return errors.New("值错误: 表格的每行必须为数组")
+			}
+			rows[i] = ad.List
+		}
+		t, err := NewTableVal(v1.Value.Header, rows)
+		if err != nil {
+			return err
+		}
+		v.Value = t.Value
 		return nil
 
 	case VMTypeFunction:
@@ -280,3 +628,435 @@ func VMValueFromJSON(data []byte) (*VMValue, error) {
 	err := json.Unmarshal(data, &v)
 	return &v, err
 }
+
+// JSONLoadOptions 反序列化选项
+// 函数和计算类型序列化后只保留源码，加载后会在首次执行时重新编译，
+// 因此读取不可信来源的数据时应当禁止它们，以免其中夹带的代码被执行
+type JSONLoadOptions struct {
+	DisallowFunction bool // 禁止加载函数
+	DisallowComputed bool // 禁止加载计算类型
+}
+
+func (opts *JSONLoadOptions) check(v *VMValue) error {
+	if v == nil {
+		return nil
+	}
+	switch v.TypeId {
+	case VMTypeFunction:
+		if opts.DisallowFunction {
+			return errors.New("值错误: 不允许加载函数")
+		}
+	case VMTypeComputedValue:
+		if opts.DisallowComputed {
+			return errors.New("值错误: 不允许加载计算类型")
+		}
+		cd, _ := v.ReadComputed()
+		if cd.Attrs != nil {
+			return opts.checkMap(cd.Attrs)
+		}
+	case VMTypeArray, VMTypeTuple:
+		lst, _ := v.readSequence()
+		for _, i := range lst {
+			if err := opts.check(i); err != nil {
+				return err
+			}
+		}
+	case VMTypeDict:
+		dd, _ := v.ReadDictData()
+		return opts.checkMap(dd.Dict)
+	}
+	return nil
+}
+
+func (opts *JSONLoadOptions) checkMap(m *ValueMap) error {
+	var err error
+	m.Range(func(key string, value *VMValue) bool {
+		err = opts.check(value)
+		return err == nil
+	})
+	return err
+}
+
+// VMValueFromJSONWithOptions 与 VMValueFromJSON 相同，但会按照opts检查加载的内容
+func VMValueFromJSONWithOptions(data []byte, opts *JSONLoadOptions) (*VMValue, error) {
+	v, err := VMValueFromJSON(data)
+	if err != nil {
+		return nil, err
+	}
+	if opts != nil {
+		if err := opts.check(v); err != nil {
+			return nil, err
+		}
+	}
+	return v, nil
+}
+
+// ValueMapFromJSONWithOptions 从json加载ValueMap，并按照opts检查加载的内容
+func ValueMapFromJSONWithOptions(data []byte, opts *JSONLoadOptions) (*ValueMap, error) {
+	m := &ValueMap{}
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, err
+	}
+	if opts != nil {
+		if err := opts.checkMap(m); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// VMValueFromGo 将go中的值转换为VMValue，支持整数、浮点、字符串、布尔、nil、时间、*VMValue，以及由它们组成的切片和以字符串为键的map
+func VMValueFromGo(v any) (*VMValue, error) {
+	switch x := v.(type) {
+	case nil:
+		return NewNullVal(), nil
+	case *VMValue:
+		return x, nil
+	case bool:
+		return NewBoolVal(x), nil
+	case string:
+		return NewStrVal(x), nil
+	case float32:
+		return NewFloatVal(float64(x)), nil
+	case float64:
+		return NewFloatVal(x), nil
+	case time.Time:
+		return NewTimeVal(x), nil
+	case time.Duration:
+		return NewDurationVal(x), nil
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return NewIntVal(IntType(rv.Int())), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return NewIntVal(IntType(rv.Uint())), nil
+	case reflect.Slice, reflect.Array:
+		items := make([]*VMValue, rv.Len())
+		for i := 0; i < rv.Len(); i++ {
+			item, err := VMValueFromGo(rv.Index(i).Interface())
+			if err != nil {
+				return nil, err
+			}
+			items[i] = item
+		}
+		return NewArrayValRaw(items), nil
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("不支持的字典键类型: %s", rv.Type().Key())
+		}
+		// go的map没有顺序，按键排序以保证结果稳定
+		keys := rv.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+		m := &ValueMap{}
+		for _, k := range keys {
+			item, err := VMValueFromGo(rv.MapIndex(k).Interface())
+			if err != nil {
+				return nil, err
+			}
+			m.Store(k.String(), item)
+		}
+		return NewDictVal(m).V(), nil
+	}
+	return nil, fmt.Errorf("不支持的类型: %T", v)
+}
+
+// toPlainValue 将VMValue转换为go中的基础类型，用于输出普通的json
+func (v *VMValue) toPlainValue(save map[*VMValue]bool) (any, error) {
+	switch v.TypeId {
+	case VMTypeInt:
+		return int64(v.Value.(IntType)), nil
+	case VMTypeFloat, VMTypeString, VMTypeTime, VMTypeDuration, VMTypeBool:
+		return v.Value, nil
+	case VMTypeNull, VMTypeUndefined:
+		return nil, nil
+	case VMTypeArray:
+		if save[v] {
+			return nil, errors.New("值错误: 序列化时检测到循环引用")
+		}
+		save[v] = true
+		defer delete(save, v)
+		ad, _ := v.ReadArray()
+		lst := make([]any, len(ad.List))
+		for i, item := range ad.List {
+			x, err := item.toPlainValue(save)
+			if err != nil {
+				return nil, err
+			}
+			lst[i] = x
+		}
+		return lst, nil
+	case VMTypeTuple, VMTypeSet:
+		// 转为普通的数组
+		var items []*VMValue
+		if sd, ok := v.ReadSet(); ok {
+			items = sd.List()
+		} else {
+			items = v.Value.(*TupleData).List
+		}
+		lst := make([]any, len(items))
+		for i, item := range items {
+			x, err := item.toPlainValue(save)
+			if err != nil {
+				return nil, err
+			}
+			lst[i] = x
+		}
+		return lst, nil
+	case VMTypeDict:
+		if save[v] {
+			return nil, errors.New("值错误: 序列化时检测到循环引用")
+		}
+		save[v] = true
+		defer delete(save, v)
+		m := map[string]any{}
+		var err error
+		v.MustReadDictData().Dict.Range(func(key string, value *VMValue) bool {
+			var x any
+			text := dictKeyText(key)
+			if _, dup := m[text]; dup {
+				err = fmt.Errorf("值错误: 字典的键 %s 转换为文本后重复", dictKeyRepr(key))
+				return false
+			}
+			x, err = value.toPlainValue(save)
+			m[text] = x
+			return err == nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		return m, nil
+	}
+	return nil, fmt.Errorf("值错误: 不支持转换的类型 %s", v.GetTypeName())
+}
+
+// ToPlainJSON 输出普通的json，不带类型信息，只支持数字、字符串、布尔值、null、数组和字典
+func (v *VMValue) ToPlainJSON() ([]byte, error) {
+	x, err := v.toPlainValue(map[*VMValue]bool{})
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(x)
+}
+
+// VMValueFromPlainJSON 读取普通的json，对象会成为字典(键的顺序与json中相同)，数字中不含小数点和指数的会成为int
+func VMValueFromPlainJSON(data []byte) (*VMValue, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	v, err := decodePlainJSON(dec)
+	if err != nil {
+		return nil, err
+	}
+	if dec.More() {
+		return nil, errors.New("值错误: json末尾存在多余内容")
+	}
+	return v, nil
+}
+
+// decodePlainJSON 逐个token读取，以保留对象中键的顺序
+func decodePlainJSON(dec *json.Decoder) (*VMValue, error) {
+	t, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch t {
+	case json.Delim('['):
+		var items []*VMValue
+		for dec.More() {
+			v, err := decodePlainJSON(dec)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, v)
+		}
+		_, err = dec.Token()
+		return NewArrayValRaw(items), err
+	case json.Delim('{'):
+		m := &ValueMap{}
+		for dec.More() {
+			k, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			v, err := decodePlainJSON(dec)
+			if err != nil {
+				return nil, err
+			}
+			m.Store(k.(string), v)
+		}
+		_, err = dec.Token()
+		return NewDictVal(m).V(), err
+	}
+	return vmValueFromPlain(t)
+}
+
+// vmValueFromPlain 转换json中的单个值
+func vmValueFromPlain(x any) (*VMValue, error) {
+	switch val := x.(type) {
+	case json.Number:
+		if i, err := val.Int64(); err == nil {
+			return NewIntVal(IntType(i)), nil
+		}
+		f, err := val.Float64()
+		if err != nil {
+			return nil, err
+		}
+		return NewFloatVal(f), nil
+	}
+	return VMValueFromGo(x)
+}
+
+// ToGo 将VMValue转换为go中的值: int为int64，float为float64，bool为bool，数组为[]any，字典为map[string]any，null为nil
+func (v *VMValue) ToGo() (any, error) {
+	return v.toPlainValue(map[*VMValue]bool{})
+}
+
+var (
+	typeVMValuePtr = reflect.TypeOf((*VMValue)(nil))
+	typeDuration   = reflect.TypeOf(time.Duration(0))
+	typeTime       = reflect.TypeOf(time.Time{})
+)
+
+// As 将VMValue转换为指定的go类型，如 As[int64](v)、As[[]string](v)、As[map[string]int](v)
+// 数字之间只允许int转float，不会进行截断；类型不符或溢出时返回错误
+func As[T any](v *VMValue) (T, error) {
+	var ret T
+	err := assignGo(reflect.ValueOf(&ret).Elem(), v)
+	return ret, err
+}
+
+func assignGo(dst reflect.Value, v *VMValue) error {
+	t := dst.Type()
+	mismatch := func() error {
+		return fmt.Errorf("类型错误: 无法将%s转换为%s", v.GetTypeName(), t)
+	}
+	if v == nil {
+		return errors.New("值错误: nil pointer")
+	}
+
+	switch t {
+	case typeVMValuePtr:
+		dst.Set(reflect.ValueOf(v))
+		return nil
+	case typeDuration:
+		d, ok := v.ReadDuration()
+		if !ok {
+			return mismatch()
+		}
+		dst.SetInt(int64(d))
+		return nil
+	case typeTime:
+		tm, ok := v.ReadTime()
+		if !ok {
+			return mismatch()
+		}
+		dst.Set(reflect.ValueOf(tm))
+		return nil
+	}
+
+	switch t.Kind() {
+	case reflect.Interface:
+		x, err := v.ToGo()
+		if err != nil {
+			return err
+		}
+		if x != nil {
+			xv := reflect.ValueOf(x)
+			if !xv.Type().AssignableTo(t) {
+				return mismatch()
+			}
+			dst.Set(xv)
+		}
+		return nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, ok := v.ReadInt()
+		if !ok {
+			return mismatch()
+		}
+		if dst.OverflowInt(int64(i)) {
+			return fmt.Errorf("值错误: %d超出了%s的范围", i, t)
+		}
+		dst.SetInt(int64(i))
+		return nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		i, ok := v.ReadInt()
+		if !ok {
+			return mismatch()
+		}
+		if i < 0 || dst.OverflowUint(uint64(i)) {
+			return fmt.Errorf("值错误: %d超出了%s的范围", i, t)
+		}
+		dst.SetUint(uint64(i))
+		return nil
+	case reflect.Float32, reflect.Float64:
+		switch v.TypeId {
+		case VMTypeInt:
+			dst.SetFloat(float64(v.Value.(IntType)))
+		case VMTypeFloat:
+			dst.SetFloat(v.Value.(float64))
+		default:
+			return mismatch()
+		}
+		return nil
+	case reflect.Bool:
+		if b, ok := v.ReadBool(); ok {
+			dst.SetBool(b)
+			return nil
+		}
+		// 兼容以0/1表示的布尔值
+		i, ok := v.ReadInt()
+		if !ok {
+			return mismatch()
+		}
+		dst.SetBool(i != 0)
+		return nil
+	case reflect.String:
+		s, ok := v.ReadString()
+		if !ok {
+			return mismatch()
+		}
+		dst.SetString(s)
+		return nil
+	case reflect.Slice:
+		items, ok := v.readSequence()
+		if !ok {
+			return mismatch()
+		}
+		lst := reflect.MakeSlice(t, len(items), len(items))
+		for i, item := range items {
+			if err := assignGo(lst.Index(i), item); err != nil {
+				return fmt.Errorf("[%d]: %w", i, err)
+			}
+		}
+		dst.Set(lst)
+		return nil
+	case reflect.Map:
+		dd, ok := v.ReadDictData()
+		if !ok || t.Key().Kind() != reflect.String {
+			return mismatch()
+		}
+		m := reflect.MakeMapWithSize(t, dd.Dict.Length())
+		var err error
+		dd.Dict.Range(func(key string, value *VMValue) bool {
+			k := reflect.ValueOf(dictKeyText(key)).Convert(t.Key())
+			if m.MapIndex(k).IsValid() {
+				err = fmt.Errorf("值错误: 字典的键 %s 转换为文本后重复", dictKeyRepr(key))
+				return false
+			}
+			item := reflect.New(t.Elem()).Elem()
+			if err = assignGo(item, value); err != nil {
+				err = fmt.Errorf("[%s]: %w", dictKeyText(key), err)
+				return false
+			}
+			m.SetMapIndex(k, item)
+			return true
+		})
+		if err != nil {
+			return err
+		}
+		dst.Set(m)
+		return nil
+	}
+	return mismatch()
+}
@@ -3,6 +3,7 @@ package dicescript
 import (
 	"github.com/stretchr/testify/assert"
 	"testing"
+	"time"
 )
 
 func TestDumps(t *testing.T) {
@@ -207,3 +208,167 @@ func TestNativeTypes(t *testing.T) {
 		assert.Equal(t, v.Value.(*NativeObjectData).Name, "obj1")
 	}
 }
+
+func TestComputedWithParamsJSON(t *testing.T) {
+	v, err := NewComputedValRaw(&ComputedData{Expr: "x * 5", Params: []string{"x"}}).ToJSON()
+	if assert.NoError(t, err) {
+		assert.Equal(t, `{"t":5,"v":{"expr":"x * 5","params":["x"]}}`, string(v))
+	}
+
+	v2, err := VMValueFromJSON(v)
+	if assert.NoError(t, err) {
+		vm := NewVM()
+		vm.Attrs.Store("val", v2)
+		err = vm.Run("val(3)")
+		if assert.NoError(t, err) {
+			assert.True(t, valueEqual(vm.Ret, ni(15)))
+		}
+	}
+}
+
+func TestLoadsWithOptions(t *testing.T) {
+	vm := NewVM()
+	err := vm.Run(`func add(a, b) { return a + b }; &hp = 10 + this.x; {'f': [add], 'hp': &hp, 'n': 1}`)
+	if !assert.NoError(t, err) {
+		return
+	}
+	data, err := vm.Ret.ToJSON()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	// 函数重新加载后可以正常调用
+	v, err := VMValueFromJSONWithOptions(data, &JSONLoadOptions{})
+	if assert.NoError(t, err) {
+		vm = NewVM()
+		vm.Attrs.Store("obj", v)
+		err = vm.Run("lst = obj.f; lst[0](1, 2)")
+		if assert.NoError(t, err) {
+			assert.True(t, valueEqual(vm.Ret, ni(3)))
+		}
+	}
+
+	_, err = VMValueFromJSONWithOptions(data, &JSONLoadOptions{DisallowFunction: true})
+	assert.Error(t, err)
+	_, err = VMValueFromJSONWithOptions(data, &JSONLoadOptions{DisallowComputed: true})
+	assert.Error(t, err)
+
+	m, err := ValueMapFromJSONWithOptions([]byte(`{"a":{"t":0,"v":1}}`), &JSONLoadOptions{DisallowFunction: true})
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(m.MustLoad("a"), ni(1)))
+	}
+	_, err = ValueMapFromJSONWithOptions([]byte(`{"a":{"t":5,"v":{"expr":"1","attrs":{"f":{"t":8,"v":{"expr":"1","name":"f","params":[]}}}}}}`), &JSONLoadOptions{DisallowFunction: true})
+	assert.Error(t, err)
+}
+
+func TestTimeJSON(t *testing.T) {
+	tm := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	v, err := NewTimeVal(tm).ToJSON()
+	if assert.NoError(t, err) {
+		assert.Equal(t, `{"t":11,"v":"2024-01-01T12:00:00Z"}`, string(v))
+		v2, err := VMValueFromJSON(v)
+		if assert.NoError(t, err) {
+			assert.True(t, valueEqual(v2, NewTimeVal(tm)))
+		}
+	}
+
+	v, err = NewDurationVal(90 * time.Second).ToJSON()
+	if assert.NoError(t, err) {
+		assert.Equal(t, `{"t":12,"v":90000000000}`, string(v))
+		v2, err := VMValueFromJSON(v)
+		if assert.NoError(t, err) {
+			assert.True(t, valueEqual(v2, NewDurationVal(90*time.Second)))
+		}
+	}
+}
+
+func TestAs(t *testing.T) {
+	i, err := As[int64](ni(3))
+	if assert.NoError(t, err) {
+		assert.Equal(t, int64(3), i)
+	}
+
+	f, err := As[float64](ni(3))
+	if assert.NoError(t, err) {
+		assert.Equal(t, 3.0, f)
+	}
+
+	_, err = As[int](nf(3.5))
+	assert.Error(t, err)
+
+	_, err = As[int8](ni(300))
+	assert.Error(t, err)
+
+	_, err = As[uint](ni(-1))
+	assert.Error(t, err)
+
+	lst, err := As[[]int64](na(ni(1), ni(2)))
+	if assert.NoError(t, err) {
+		assert.Equal(t, []int64{1, 2}, lst)
+	}
+
+	_, err = As[[]int64](na(ni(1), ns("x")))
+	assert.EqualError(t, err, "[1]: 类型错误: 无法将str转换为int64")
+
+	vm := NewVM()
+	err = vm.Run("{'name': 'Alice', 'job': '侦探'}")
+	if assert.NoError(t, err) {
+		m, err := As[map[string]string](vm.Ret)
+		if assert.NoError(t, err) {
+			assert.Equal(t, map[string]string{"name": "Alice", "job": "侦探"}, m)
+		}
+	}
+
+	err = vm.Run("[1, 2.5, 'x', null, {'a': [true]}]")
+	if assert.NoError(t, err) {
+		x, err := As[any](vm.Ret)
+		if assert.NoError(t, err) {
+			assert.Equal(t, []any{int64(1), 2.5, "x", nil, map[string]any{"a": []any{true}}}, x)
+		}
+	}
+
+	d, err := As[time.Duration](NewDurationVal(time.Hour))
+	if assert.NoError(t, err) {
+		assert.Equal(t, time.Hour, d)
+	}
+
+	v, err := As[*VMValue](ni(1))
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(v, ni(1)))
+	}
+}
+
+func TestDeckJSON(t *testing.T) {
+	d := NewDeckVal([]*VMValue{ni(1), ni(2), ni(3)})
+	d.Value.(*DeckData).Draw(1)
+	data, err := d.ToJSON()
+	if assert.NoError(t, err) {
+		assert.Equal(t, `{"t":14,"v":{"cards":{"t":6,"v":{"list":[{"t":0,"v":2},{"t":0,"v":3}]}},"drawn":{"t":6,"v":{"list":[{"t":0,"v":1}]}}}}`, string(data))
+	}
+
+	v, err := VMValueFromJSON(data)
+	if assert.NoError(t, err) {
+		dd, ok := v.ReadDeck()
+		if assert.True(t, ok) {
+			assert.True(t, valueEqual(NewArrayValRaw(dd.Cards), na(ni(2), ni(3))))
+			assert.True(t, valueEqual(NewArrayValRaw(dd.Drawn), na(ni(1))))
+		}
+	}
+
+	// 通过存储保存的牌堆，在下次执行时继续抽牌
+	p := &testAttrProvider{}
+	vm := NewVM(WithAttrProvider(p))
+	err = vm.Run("牌堆 = deck([1, 2, 3], 0); 牌堆.draw()")
+	assert.NoError(t, err)
+	data, err = p.m.MustLoad("牌堆").ToJSON()
+	assert.NoError(t, err)
+	v, err = VMValueFromJSON(data)
+	assert.NoError(t, err)
+	p.m.Store("牌堆", v)
+
+	vm = NewVM(WithAttrProvider(p))
+	err = vm.Run("牌堆.draw(2)")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, na(ni(2), ni(3))))
+	}
+}
@@ -0,0 +1,187 @@
+package dicescript
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SetData 集合，各项不重复且保持加入的顺序，如 {'中毒', '眩晕'}。
+// 其中的值与字典的键规则相同，只能为字符串、数字或元组，1 与 '1' 同样视为同一项
+type SetData struct {
+	Items *ValueMap // 键为 AsDictKey 的结果，值为还原后的值
+}
+
+// NewSetVal 创建集合，重复的值只保留一个
+func NewSetVal(items ...*VMValue) (*VMValue, error) {
+	sd := &SetData{Items: &ValueMap{}}
+	for _, item := range items {
+		if err := sd.Add(item); err != nil {
+			return nil, err
+		}
+	}
+	return &VMValue{TypeId: VMTypeSet, Value: sd}, nil
+}
+
+func (v *VMValue) ReadSet() (*SetData, bool) {
+	if v.TypeId == VMTypeSet {
+		return v.Value.(*SetData), true
+	}
+	return nil, false
+}
+
+func (sd *SetData) Add(v *VMValue) error {
+	key, err := v.AsDictKey()
+	if err != nil {
+		return fmt.Errorf("类型错误: 集合中只能有字符串、数字或元组，不支持 %s", v.GetTypeName())
+	}
+	if _, exists := sd.Items.Load(dictResolveKey(sd.Items, key)); !exists {
+		// 与字典的键相同，数组会被视为元组
+		sd.Items.Store(key, DictKeyValue(key))
+	}
+	return nil
+}
+
+// Remove 移除一项，返回其是否存在
+func (sd *SetData) Remove(v *VMValue) bool {
+	key, err := v.AsDictKey()
+	if err != nil {
+		return false
+	}
+	_, exists := sd.Items.LoadAndDelete(dictResolveKey(sd.Items, key))
+	return exists
+}
+
+func (sd *SetData) Has(v *VMValue) bool {
+	key, err := v.AsDictKey()
+	if err != nil {
+		return false
+	}
+	return sd.hasKey(key)
+}
+
+// hasKey 是否有key或其别名，见 dictResolveKey
+func (sd *SetData) hasKey(key string) bool {
+	_, exists := sd.Items.Load(dictResolveKey(sd.Items, key))
+	return exists
+}
+
+func (sd *SetData) Len() int {
+	return sd.Items.Length()
+}
+
+// List 按加入的顺序取出各项
+func (sd *SetData) List() []*VMValue {
+	lst := make([]*VMValue, 0, sd.Len())
+	sd.Items.Range(func(key string, value *VMValue) bool {
+		lst = append(lst, value)
+		return true
+	})
+	return lst
+}
+
+// filter 按顺序保留 keep 为真的项，得到新的集合
+func (sd *SetData) filter(keep func(key string) bool) *VMValue {
+	ret := &SetData{Items: &ValueMap{}}
+	sd.Items.Range(func(key string, value *VMValue) bool {
+		if keep(key) {
+			ret.Items.Store(key, value)
+		}
+		return true
+	})
+	return &VMValue{TypeId: VMTypeSet, Value: ret}
+}
+
+// setUnion 并集 a | b
+func setUnion(a, b *SetData) *VMValue {
+	ret := a.filter(func(string) bool { return true })
+	rd, _ := ret.ReadSet()
+	b.Items.Range(func(key string, value *VMValue) bool {
+		if !rd.hasKey(key) {
+			rd.Items.Store(key, value)
+		}
+		return true
+	})
+	return ret
+}
+
+// setIntersect 交集 a & b
+func setIntersect(a, b *SetData) *VMValue {
+	return a.filter(b.hasKey)
+}
+
+// setDifference 差集 a - b
+func setDifference(a, b *SetData) *VMValue {
+	return a.filter(func(key string) bool {
+		return !b.hasKey(key)
+	})
+}
+
+func (sd *SetData) toStringRaw(ri *recursionInfo) string {
+	if sd.Len() == 0 {
+		// {} 是空字典
+		return "set()"
+	}
+	var items []string
+	sd.Items.Range(func(key string, value *VMValue) bool {
+		items = append(items, value.toReprRaw(ri))
+		return true
+	})
+	return "{" + strings.Join(items, ", ") + "}"
+}
+
+func setEqual(a, b *SetData) bool {
+	if a.Len() != b.Len() {
+		return false
+	}
+	same := true
+	a.Items.Range(func(key string, value *VMValue) bool {
+		same = b.hasKey(key)
+		return same
+	})
+	return same
+}
+
+func funcSet(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
+	var lst []*VMValue
+	if !params[0].IsNullish() {
+		lst = ctx.iterToList(params[0])
+		if ctx.Error != nil {
+			return nil
+		}
+	}
+	v, err := NewSetVal(lst...)
+	if err != nil {
+		ctx.Error = err
+		return nil
+	}
+	return v
+}
+
+func funcSetAdd(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
+	sd, _ := this.ReadSet()
+	if err := sd.Add(params[0]); err != nil {
+		ctx.Error = fmt.Errorf("(Set.add)%w", err)
+		return nil
+	}
+	return this
+}
+
+func funcSetRemove(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
+	sd, _ := this.ReadSet()
+	return boolToVMValue(sd.Remove(params[0]))
+}
+
+func funcSetHas(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
+	sd, _ := this.ReadSet()
+	return boolToVMValue(sd.Has(params[0]))
+}
+
+func funcSetLen(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
+	sd, _ := this.ReadSet()
+	return NewIntVal(IntType(sd.Len()))
+}
+
+func funcSetList(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
+	sd, _ := this.ReadSet()
+	return NewArrayValRaw(sd.List())
+}
@@ -0,0 +1,77 @@
+package dicescript
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func nset(items ...*VMValue) *VMValue {
+	v, _ := NewSetVal(items...)
+	return v
+}
+
+func TestSet(t *testing.T) {
+	vm := NewVM()
+	err := vm.Run("状态 = {'中毒', '眩晕', '中毒'}; [状态, 状态 & {'眩晕', '流血'}, 状态 | {'流血'}, 状态 - {'中毒'}, 状态.len()]")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, na(
+			nset(ns("中毒"), ns("眩晕")),
+			nset(ns("眩晕")),
+			nset(ns("中毒"), ns("眩晕"), ns("流血")),
+			nset(ns("眩晕")),
+			ni(2),
+		)))
+	}
+
+	vm = NewVM()
+	err = vm.Run("s = set(); s.add('x').add((1, 2)).add([1, 2]); [s.has('x'), s.has('y'), s.remove('x'), s.remove('x'), s.list(), `{s}`, `{set()}`]")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, na(
+			nb(true), nb(false), nb(true), nb(false),
+			na(NewTupleVal(ni(1), ni(2))),
+			ns("{(1, 2)}"), ns("set()"),
+		)))
+	}
+
+	vm = NewVM()
+	err = vm.Run("s = 0; for i in set([3, 1, 3]) { s = s + i }; [s, {1, 2} == {2, 1}, {1} == {1, 2}, set() ? 1 : 0]")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, na(ni(4), nb(true), nb(false), ni(0))))
+	}
+
+	// 与字典的键相同，1 与 '1' 是同一项
+	vm = NewVM()
+	err = vm.Run("s = {1, '1', 2}; [s.len(), '1' in {1, 2}, s.has('2'), {1} == {'1'}, ({1, 2} - {'1'}).len(), s.remove('1'), s.len()]")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, na(ni(2), nb(true), nb(true), nb(true), ni(1), nb(true), ni(1))))
+	}
+
+	// 仍然是字典
+	vm = NewVM()
+	err = vm.Run("v1 = 'a'; [{v1: 1}, {'b': 2}]")
+	if assert.NoError(t, err) {
+		assert.Equal(t, VMTypeDict, vm.Ret.MustReadArray().List[0].TypeId)
+		assert.Equal(t, VMTypeDict, vm.Ret.MustReadArray().List[1].TypeId)
+	}
+
+	vm = NewVM()
+	err = vm.Run("{{}}")
+	assert.Error(t, err)
+}
+
+func TestSetJSON(t *testing.T) {
+	v := nset(ni(1), ns("a"))
+	data, err := v.ToJSON()
+	if assert.NoError(t, err) {
+		v2, err := VMValueFromJSON(data)
+		if assert.NoError(t, err) {
+			assert.True(t, valueEqual(v, v2))
+		}
+	}
+
+	plain, err := v.ToPlainJSON()
+	if assert.NoError(t, err) {
+		assert.Equal(t, `[1,"a"]`, string(plain))
+	}
+}
@@ -0,0 +1,253 @@
+package dicescript
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// TableData 二维表格，每行的列数相同
+type TableData struct {
+	Header []string // 列名，可以为空
+	Rows   [][]*VMValue
+}
+
+// NewTableVal 创建表格，各行列数不同时返回错误
+func NewTableVal(header []string, rows [][]*VMValue) (*VMValue, error) {
+	td := &TableData{Header: header, Rows: rows}
+	cols := td.Cols()
+	if len(header) != 0 && len(rows) != 0 && len(header) != cols {
+		return nil, fmt.Errorf("值错误: 表头有%d列，但数据有%d列", len(header), cols)
+	}
+	for i, row := range rows {
+		if len(row) != cols {
+			return nil, fmt.Errorf("值错误: 表格每行的列数必须相同，第%d行有%d列，第1行有%d列", i+1, len(row), cols)
+		}
+	}
+	return &VMValue{TypeId: VMTypeTable, Value: td}, nil
+}
+
+func (v *VMValue) ReadTable() (*TableData, bool) {
+	if v.TypeId == VMTypeTable {
+		return v.Value.(*TableData), true
+	}
+	return nil, false
+}
+
+// LoadTableCSV 读取csv或tsv格式的随机表，首行中含有制表符时按tsv读取。以#开头的行为注释。
+// 根据列数决定得到的值:
+// 一列时为数组；两列时为字典，首列为键，例如 "1,大成功" 可以用 t[d6] 取出；
+// 三列及以上时为表格，首行作为列名。单元格能解析为数字的转为int或float，其余为字符串
+func LoadTableCSV(r io.Reader) (*VMValue, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.Comment = '#'
+	reader.TrimLeadingSpace = true
+	firstLine := data
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		firstLine = data[:i]
+	}
+	if bytes.Count(firstLine, []byte{'\t'}) > bytes.Count(firstLine, []byte{','}) {
+		reader.Comma = '\t'
+		reader.LazyQuotes = true
+	}
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return NewArrayValRaw(nil), nil
+	}
+
+	switch len(records[0]) {
+	case 1:
+		lst := make([]*VMValue, len(records))
+		for i, record := range records {
+			lst[i] = csvCellToValue(record[0])
+		}
+		return NewArrayValRaw(lst), nil
+	case 2:
+		d := &ValueMap{}
+		for _, record := range records {
+			d.Store(strings.TrimSpace(record[0]), csvCellToValue(record[1]))
+		}
+		return NewDictVal(d).V(), nil
+	default:
+		return NewTableVal(records[0], csvRecordsToRows(records[1:]))
+	}
+}
+
+func csvRecordsToRows(records [][]string) [][]*VMValue {
+	rows := make([][]*VMValue, len(records))
+	for i, record := range records {
+		row := make([]*VMValue, len(record))
+		for j, cell := range record {
+			row[j] = csvCellToValue(cell)
+		}
+		rows[i] = row
+	}
+	return rows
+}
+
+func csvCellToValue(cell string) *VMValue {
+	s := strings.TrimSpace(cell)
+	if i, err := strconv.ParseInt(s, 10, 64); err == nil && int64(IntType(i)) == i {
+		return NewIntVal(IntType(i))
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		return NewFloatVal(f)
+	}
+	return NewStrVal(cell)
+}
+
+func readNumber(v *VMValue) (float64, bool) {
+	v = operandValue(v)
+	switch v.TypeId {
+	case VMTypeInt:
+		return float64(v.Value.(IntType)), true
+	case VMTypeFloat:
+		return v.Value.(float64), true
+	}
+	return 0, false
+}
+
+func (t *TableData) Cols() int {
+	if len(t.Rows) == 0 {
+		return len(t.Header)
+	}
+	return len(t.Rows[0])
+}
+
+// colIndex 取得列下标，col可以是数字或列名
+func (t *TableData) colIndex(ctx *Context, col *VMValue) (int, bool) {
+	switch col.TypeId {
+	case VMTypeInt:
+		index := getRealIndex(ctx, col.MustReadInt(), IntType(t.Cols()))
+		return int(index), ctx.Error == nil
+	case VMTypeString:
+		name, _ := col.ReadString()
+		for i, h := range t.Header {
+			if h == name {
+				return i, true
+			}
+		}
+		ctx.Error = fmt.Errorf("值错误: 表格中没有名为 %s 的列", name)
+	default:
+		ctx.Error = fmt.Errorf("类型错误: 列必须为数字或列名，不能为 %s", col.GetTypeName())
+	}
+	return 0, false
+}
+
+func (t *TableData) rowIndex(ctx *Context, row *VMValue) (int, bool) {
+	if row.TypeId != VMTypeInt {
+		ctx.Error = fmt.Errorf("类型错误: 行必须为数字，不能为 %s", row.GetTypeName())
+		return 0, false
+	}
+	index := getRealIndex(ctx, row.MustReadInt(), IntType(len(t.Rows)))
+	return int(index), ctx.Error == nil
+}
+
+func (t *TableData) toStringRaw(ri *recursionInfo) string {
+	var rows []string
+	for _, row := range t.Rows {
+		items := make([]string, len(row))
+		for i, v := range row {
+			items[i] = v.toReprRaw(ri)
+		}
+		rows = append(rows, strings.Join(items, ", "))
+	}
+	return "[" + strings.Join(rows, "; ") + "]"
+}
+
+func funcTableLookup(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
+	t, _ := this.ReadTable()
+	i, ok := t.rowIndex(ctx, params[0])
+	if !ok {
+		ctx.Error = errors.New("(table.lookup)" + ctx.Error.Error())
+		return nil
+	}
+	j, ok := t.colIndex(ctx, params[1])
+	if !ok {
+		ctx.Error = errors.New("(table.lookup)" + ctx.Error.Error())
+		return nil
+	}
+	return t.Rows[i][j]
+}
+
+// funcTableLookupRange 在首列中找到第一个不小于value的行，返回该行col列的值。
+// 用于 [4, '右腿'; 8, '左腿'; 20, '躯干'] 这样按区间上限排列的表
+func funcTableLookupRange(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
+	t, _ := this.ReadTable()
+	x, ok := readNumber(params[0])
+	if !ok {
+		ctx.Error = errors.New("(table.lookupRange)类型错误: 查找的值必须为数字")
+		return nil
+	}
+	j, ok := t.colIndex(ctx, params[1])
+	if !ok {
+		ctx.Error = errors.New("(table.lookupRange)" + ctx.Error.Error())
+		return nil
+	}
+	for _, row := range t.Rows {
+		bound, ok := readNumber(row[0])
+		if !ok {
+			ctx.Error = errors.New("(table.lookupRange)类型错误: 表格首列必须为数字")
+			return nil
+		}
+		if x <= bound {
+			return row[j]
+		}
+	}
+	ctx.Error = fmt.Errorf("(table.lookupRange)值错误: %v 超出了表格的范围", params[0].ToString())
+	return nil
+}
+
+func funcTableRow(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
+	t, _ := this.ReadTable()
+	i, ok := t.rowIndex(ctx, params[0])
+	if !ok {
+		ctx.Error = errors.New("(table.row)" + ctx.Error.Error())
+		return nil
+	}
+	return NewArrayValRaw(t.Rows[i])
+}
+
+func funcTableCol(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
+	t, _ := this.ReadTable()
+	j, ok := t.colIndex(ctx, params[0])
+	if !ok {
+		ctx.Error = errors.New("(table.col)" + ctx.Error.Error())
+		return nil
+	}
+	lst := make([]*VMValue, len(t.Rows))
+	for i, row := range t.Rows {
+		lst[i] = row[j]
+	}
+	return NewArrayValRaw(lst)
+}
+
+func funcTableRows(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
+	t, _ := this.ReadTable()
+	return NewIntVal(IntType(len(t.Rows)))
+}
+
+func funcTableCols(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
+	t, _ := this.ReadTable()
+	return NewIntVal(IntType(t.Cols()))
+}
+
+func funcTableHeader(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
+	t, _ := this.ReadTable()
+	lst := make([]*VMValue, len(t.Header))
+	for i, h := range t.Header {
+		lst[i] = NewStrVal(h)
+	}
+	return NewArrayValRaw(lst)
+}
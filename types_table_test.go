@@ -0,0 +1,133 @@
+package dicescript
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTableLiteral(t *testing.T) {
+	vm := NewVM()
+	err := vm.Run("t = [1, 'a'; 2, 'b'; 3, 'c']; [t.rows(), t.cols(), t[1], t.lookup(2, 1), t.lookup(-1, 0)]")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, na(ni(3), ni(2), na(ni(2), ns("b")), ns("c"), ni(3))))
+	}
+
+	vm = NewVM()
+	err = vm.Run("[1, 2;\n 3, 4;\n]")
+	if assert.NoError(t, err) {
+		assert.Equal(t, VMTypeTable, vm.Ret.TypeId)
+		assert.Equal(t, "[1, 2; 3, 4]", vm.Ret.ToString())
+	}
+
+	vm = NewVM()
+	err = vm.Run("t = [1, 2; 3, 4]; [t.row(0), t.col(1), t.rows()]")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, na(na(ni(1), ni(2)), na(ni(2), ni(4)), ni(2))))
+	}
+
+	// 各行列数不同
+	vm = NewVM()
+	err = vm.Run("[1, 2; 3]")
+	assert.Error(t, err)
+
+	// 越界
+	vm = NewVM()
+	err = vm.Run("[1, 2; 3, 4].lookup(2, 0)")
+	assert.Error(t, err)
+
+	vm = NewVM()
+	err = vm.Run("[1, 2; 3, 4].lookup(0, 2)")
+	assert.Error(t, err)
+
+	vm = NewVM()
+	err = vm.Run("[1, 2; 3, 4][5]")
+	assert.Error(t, err)
+}
+
+func TestTableLookupRange(t *testing.T) {
+	vm := NewVM()
+	err := vm.Run("部位 = [4, '右腿'; 8, '左腿'; 11, '腹部'; 12, '胸部'; 20, '头部']; [部位.lookupRange(1), 部位.lookupRange(8), 部位.lookupRange(9), 部位.lookupRange(20)]")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, na(ns("右腿"), ns("左腿"), ns("腹部"), ns("头部"))))
+	}
+
+	vm = NewVM()
+	err = vm.Run("[4, '右腿'; 8, '左腿'].lookupRange(9)")
+	assert.Error(t, err)
+}
+
+func TestTableFromCSV(t *testing.T) {
+	v, err := LoadTableCSV(strings.NewReader("上限,天气,温度\n3,晴,25.5\n5,雨,18\n6,雪,-3\n"))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	vm := NewVM()
+	vm.StoreName("天气表", v, false)
+	err = vm.Run("[天气表.lookup(0, '天气'), 天气表.lookupRange(4, '天气'), 天气表.col('温度'), 天气表.header()]")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, na(ns("晴"), ns("雨"), na(nf(25.5), ni(18), ni(-3)), na(ns("上限"), ns("天气"), ns("温度")))))
+	}
+
+	vm = NewVM()
+	vm.StoreName("天气表", v, false)
+	err = vm.Run("天气表.lookup(0, '湿度')")
+	assert.Error(t, err)
+
+	_, err = LoadTableCSV(strings.NewReader("a,b,c\n1,2\n"))
+	assert.Error(t, err)
+}
+
+func TestTableJSON(t *testing.T) {
+	v, err := NewTableVal([]string{"a", "b"}, [][]*VMValue{{ni(1), ns("x")}, {ni(2), ns("y")}})
+	if !assert.NoError(t, err) {
+		return
+	}
+	data, err := v.ToJSON()
+	if assert.NoError(t, err) {
+		assert.Equal(t, `{"t":15,"v":{"header":["a","b"],"rows":[{"t":6,"v":{"list":[{"t":0,"v":1},{"t":2,"v":"x"}]}},{"t":6,"v":{"list":[{"t":0,"v":2},{"t":2,"v":"y"}]}}]}}`, string(data))
+	}
+	v2, err := VMValueFromJSON(data)
+	if assert.NoError(t, err) {
+		t2, _ := v2.ReadTable()
+		assert.Equal(t, []string{"a", "b"}, t2.Header)
+		assert.True(t, valueEqual(NewArrayValRaw(t2.Rows[1]), na(ni(2), ns("y"))))
+	}
+}
+
+func TestLoadTableCSV(t *testing.T) {
+	v, err := LoadTableCSV(strings.NewReader("# 遭遇表\n哥布林\n狼群\n商队\n"))
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(v, na(ns("哥布林"), ns("狼群"), ns("商队"))))
+	}
+
+	v, err = LoadTableCSV(strings.NewReader("1,大失败\n2, 失败\n3,成功\n"))
+	if assert.NoError(t, err) {
+		vm := NewVM()
+		vm.StoreName("结果表", v, false)
+		err = vm.Run("[结果表[1], 结果表['3'], 结果表.len()]")
+		if assert.NoError(t, err) {
+			assert.True(t, valueEqual(vm.Ret, na(ns("大失败"), ns("成功"), ni(3))))
+		}
+	}
+
+	v, err = LoadTableCSV(strings.NewReader("上限\t天气\t温度\n3\t晴\t25\n6\t雨, 有雾\t18\n"))
+	if assert.NoError(t, err) {
+		vm := NewVM()
+		vm.StoreName("天气表", v, false)
+		err = vm.Run("[天气表.lookupRange(5, '天气'), 天气表.lookup(0, '温度')]")
+		if assert.NoError(t, err) {
+			assert.True(t, valueEqual(vm.Ret, na(ns("雨, 有雾"), ni(25))))
+		}
+	}
+
+	v, err = LoadTableCSV(strings.NewReader(""))
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(v, na()))
+	}
+
+	_, err = LoadTableCSV(strings.NewReader("1,a\n2\n"))
+	assert.Error(t, err)
+}
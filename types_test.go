@@ -35,6 +35,7 @@ func valueEqual(a *VMValue, b *VMValue) bool {
 
 var ni = NewIntVal
 var nf = NewFloatVal
+var nb = NewBoolVal
 var ns = NewStrVal
 var na = NewArrayVal
 var nd = NewDictValWithArrayMust
@@ -88,24 +89,24 @@ func TestCompare(t *testing.T) {
 	// lt 小于
 	var compLTTest = compareTestData{
 		// int, int
-		{ni(0), ni(0), ni(0)}, // 0 < 0, false
-		{ni(0), ni(2), ni(1)}, // 0 < 2, true
-		{ni(2), ni(0), ni(0)}, // 2 < 0, false
+		{ni(0), ni(0), nb(false)}, // 0 < 0, false
+		{ni(0), ni(2), nb(true)},  // 0 < 2, true
+		{ni(2), ni(0), nb(false)}, // 2 < 0, false
 
 		// int float
-		{ni(0), nf(0), ni(0)}, // 0 < 0, false
-		{ni(0), nf(2), ni(1)}, // 0 < 2, true
-		{ni(2), nf(0), ni(0)}, // 2 < 0, false
+		{ni(0), nf(0), nb(false)}, // 0 < 0, false
+		{ni(0), nf(2), nb(true)},  // 0 < 2, true
+		{ni(2), nf(0), nb(false)}, // 2 < 0, false
 
 		// float int
-		{nf(0), ni(0), ni(0)}, // 0 < 0, false
-		{nf(0), ni(2), ni(1)}, // 0 < 2, true
-		{nf(2), ni(0), ni(0)}, // 2 < 0, false
+		{nf(0), ni(0), nb(false)}, // 0 < 0, false
+		{nf(0), ni(2), nb(true)},  // 0 < 2, true
+		{nf(2), ni(0), nb(false)}, // 2 < 0, false
 
 		// float float
-		{nf(0), nf(0), ni(0)}, // 0 < 0, false
-		{nf(0), nf(2), ni(1)}, // 0 < 2, true
-		{nf(2), nf(0), ni(0)}, // 2 < 0, false
+		{nf(0), nf(0), nb(false)}, // 0 < 0, false
+		{nf(0), nf(2), nb(true)},  // 0 < 2, true
+		{nf(2), nf(0), nb(false)}, // 2 < 0, false
 
 		// int str
 		{ni(0), ns("2"), nil}, // 0 < '2', ERR
@@ -121,24 +122,24 @@ func TestCompare(t *testing.T) {
 	// le 小于等于
 	var compLETest = compareTestData{
 		// int, int
-		{ni(0), ni(0), ni(1)}, // 0 <= 0, true
-		{ni(0), ni(2), ni(1)}, // 0 <= 2, true
-		{ni(2), ni(0), ni(0)}, // 2 <= 0, false
+		{ni(0), ni(0), nb(true)},  // 0 <= 0, true
+		{ni(0), ni(2), nb(true)},  // 0 <= 2, true
+		{ni(2), ni(0), nb(false)}, // 2 <= 0, false
 
 		// int float
-		{ni(0), nf(0), ni(1)}, // 0 <= 0, true
-		{ni(0), nf(2), ni(1)}, // 0 <= 2, true
-		{ni(2), nf(0), ni(0)}, // 2 <= 0, false
+		{ni(0), nf(0), nb(true)},  // 0 <= 0, true
+		{ni(0), nf(2), nb(true)},  // 0 <= 2, true
+		{ni(2), nf(0), nb(false)}, // 2 <= 0, false
 
 		// float int
-		{nf(0), ni(0), ni(1)}, // 0 <= 0, true
-		{nf(0), ni(2), ni(1)}, // 0 <= 2, true
-		{nf(2), ni(0), ni(0)}, // 2 <= 0, false
+		{nf(0), ni(0), nb(true)},  // 0 <= 0, true
+		{nf(0), ni(2), nb(true)},  // 0 <= 2, true
+		{nf(2), ni(0), nb(false)}, // 2 <= 0, false
 
 		// float float
-		{nf(0), nf(0), ni(1)}, // 0 <= 0, true
-		{nf(0), nf(2), ni(1)}, // 0 <= 2, true
-		{nf(2), nf(0), ni(0)}, // 2 <= 0, false
+		{nf(0), nf(0), nb(true)},  // 0 <= 0, true
+		{nf(0), nf(2), nb(true)},  // 0 <= 2, true
+		{nf(2), nf(0), nb(false)}, // 2 <= 0, false
 
 		// int str
 		{ni(0), ns("2"), nil}, // 0 <= '2', ERR
@@ -154,24 +155,24 @@ func TestCompare(t *testing.T) {
 	// ge 大于等于
 	var compGETest = compareTestData{
 		// int, int
-		{ni(0), ni(0), ni(1)}, // 0 >= 0, true
-		{ni(0), ni(2), ni(0)}, // 0 >= 2, false
-		{ni(2), ni(0), ni(1)}, // 2 >= 0, true
+		{ni(0), ni(0), nb(true)},  // 0 >= 0, true
+		{ni(0), ni(2), nb(false)}, // 0 >= 2, false
+		{ni(2), ni(0), nb(true)},  // 2 >= 0, true
 
 		// int float
-		{ni(0), nf(0), ni(1)}, // 0 >= 0, true
-		{ni(0), nf(2), ni(0)}, // 0 >= 2, false
-		{ni(2), nf(0), ni(1)}, // 2 >= 0, true
+		{ni(0), nf(0), nb(true)},  // 0 >= 0, true
+		{ni(0), nf(2), nb(false)}, // 0 >= 2, false
+		{ni(2), nf(0), nb(true)},  // 2 >= 0, true
 
 		// float int
-		{nf(0), ni(0), ni(1)}, // 0 >= 0, true
-		{nf(0), ni(2), ni(0)}, // 0 >= 2, false
-		{nf(2), ni(0), ni(1)}, // 2 >= 0, true
+		{nf(0), ni(0), nb(true)},  // 0 >= 0, true
+		{nf(0), ni(2), nb(false)}, // 0 >= 2, false
+		{nf(2), ni(0), nb(true)},  // 2 >= 0, true
 
 		// float float
-		{nf(0), nf(0), ni(1)}, // 0 >= 0, true
-		{nf(0), nf(2), ni(0)}, // 0 >= 2, false
-		{nf(2), nf(0), ni(1)}, // 2 >= 0, true
+		{nf(0), nf(0), nb(true)},  // 0 >= 0, true
+		{nf(0), nf(2), nb(false)}, // 0 >= 2, false
+		{nf(2), nf(0), nb(true)},  // 2 >= 0, true
 
 		// int str
 		{ni(0), ns("2"), nil}, // 0 >= '2', ERR
@@ -187,24 +188,24 @@ func TestCompare(t *testing.T) {
 	// gt 大于
 	var compGTTest = compareTestData{
 		// int, int
-		{ni(0), ni(0), ni(0)}, // 0 > 0, false
-		{ni(0), ni(2), ni(0)}, // 0 > 2, false
-		{ni(2), ni(0), ni(1)}, // 2 > 0, true
+		{ni(0), ni(0), nb(false)}, // 0 > 0, false
+		{ni(0), ni(2), nb(false)}, // 0 > 2, false
+		{ni(2), ni(0), nb(true)},  // 2 > 0, true
 
 		// int float
-		{ni(0), nf(0), ni(0)}, // 0 > 0, false
-		{ni(0), nf(2), ni(0)}, // 0 > 2, false
-		{ni(2), nf(0), ni(1)}, // 2 > 0, true
+		{ni(0), nf(0), nb(false)}, // 0 > 0, false
+		{ni(0), nf(2), nb(false)}, // 0 > 2, false
+		{ni(2), nf(0), nb(true)},  // 2 > 0, true
 
 		// float int
-		{nf(0), ni(0), ni(0)}, // 0 > 0, false
-		{nf(0), ni(2), ni(0)}, // 0 > 2, false
-		{nf(2), ni(0), ni(1)}, // 2 > 0, true
+		{nf(0), ni(0), nb(false)}, // 0 > 0, false
+		{nf(0), ni(2), nb(false)}, // 0 > 2, false
+		{nf(2), ni(0), nb(true)},  // 2 > 0, true
 
 		// float float
-		{nf(0), nf(0), ni(0)}, // 0 > 0, false
-		{nf(0), nf(2), ni(0)}, // 0 > 2, false
-		{nf(2), nf(0), ni(1)}, // 2 > 0, true
+		{nf(0), nf(0), nb(false)}, // 0 > 0, false
+		{nf(0), nf(2), nb(false)}, // 0 > 2, false
+		{nf(2), nf(0), nb(true)},  // 2 > 0, true
 
 		// int str
 		{ni(0), ns("2"), nil}, // 0 > '2', ERR
@@ -220,17 +221,17 @@ func TestCompare(t *testing.T) {
 	// EQ
 	theSame := ni(123)
 	var compEQTest = compareTestData{
-		{theSame, theSame, ni(1)},
+		{theSame, theSame, nb(true)},
 		// int, int
-		{ni(0), ni(0), ni(1)},  // 0 == 0, true
-		{ni(-1), ni(1), ni(0)}, // -1 == 1, false
+		{ni(0), ni(0), nb(true)},   // 0 == 0, true
+		{ni(-1), ni(1), nb(false)}, // -1 == 1, false
 		// int, float
-		{ni(0), nf(0), ni(1)}, // 0 == 0, true
-		{ni(0), nf(1), ni(0)}, // 0 == 1, false
+		{ni(0), nf(0), nb(true)},  // 0 == 0, true
+		{ni(0), nf(1), nb(false)}, // 0 == 1, false
 		// float, int
-		{nf(1), ni(0), ni(0)}, // 1 == 0, false
+		{nf(1), ni(0), nb(false)}, // 1 == 0, false
 		// int, str
-		{ni(0), ns(""), ni(0)}, // 0 == '', false
+		{ni(0), ns(""), nb(false)}, // 0 == '', false
 	}
 	for _, i := range compEQTest {
 		r := (*VMValue).OpCompEQ(i.v1, ctx, i.v2)
@@ -240,14 +241,14 @@ func TestCompare(t *testing.T) {
 	}
 
 	var compEQTest2 = compareTestData{
-		{na(ni(1), ni(2), ni(3)), na(ni(1), ni(2), ni(3)), ni(1)},        // [1,2,3] == [1,2,3] true
-		{na(ni(1), ni(2)), na(ni(1), ni(2), ni(3)), ni(0)},               // [1,2] == [1,2,3] false
-		{na(ni(1), ni(2), ni(3)), na(ni(1), ni(2), ni(3), ni(4)), ni(0)}, // [1,2,3] == [1,2,3,4] false
-		{na(ni(1), ni(2), ni(3)), na(ni(1), ni(2), ni(4)), ni(0)},        // [1,2,3] == [1,2,4] false
-
-		{nd(ns("a"), ni(1)).V(), nd(ns("a"), ni(1)).V(), ni(1)},                 // {'a':1} == {'a':1} true
-		{nd(ns("a"), ni(1)).V(), nd(ns("a"), ni(2)).V(), ni(0)},                 // {'a':1} == {'a':2} false
-		{nd(ns("a"), ni(1)).V(), nd(ns("a"), ni(1), ns("b"), ni(2)).V(), ni(0)}, // {'a':1} == {'a':1,'b':2} false
+		{na(ni(1), ni(2), ni(3)), na(ni(1), ni(2), ni(3)), nb(true)},         // [1,2,3] == [1,2,3] true
+		{na(ni(1), ni(2)), na(ni(1), ni(2), ni(3)), nb(false)},               // [1,2] == [1,2,3] false
+		{na(ni(1), ni(2), ni(3)), na(ni(1), ni(2), ni(3), ni(4)), nb(false)}, // [1,2,3] == [1,2,3,4] false
+		{na(ni(1), ni(2), ni(3)), na(ni(1), ni(2), ni(4)), nb(false)},        // [1,2,3] == [1,2,4] false
+
+		{nd(ns("a"), ni(1)).V(), nd(ns("a"), ni(1)).V(), nb(true)},                  // {'a':1} == {'a':1} true
+		{nd(ns("a"), ni(1)).V(), nd(ns("a"), ni(2)).V(), nb(false)},                 // {'a':1} == {'a':2} false
+		{nd(ns("a"), ni(1)).V(), nd(ns("a"), ni(1), ns("b"), ni(2)).V(), nb(false)}, // {'a':1} == {'a':1,'b':2} false
 	}
 
 	for _, i := range compEQTest2 {
@@ -469,3 +470,180 @@ func TestAsBool(t *testing.T) {
 
 	assert.Equal(t, builtinValues["toStr"].AsBool(), true)
 }
+
+func TestReadDict(t *testing.T) {
+	vm := NewVM()
+	err := vm.Run("角色 = {'name': '甲', 'hp': 10}; 角色.hp = 角色['hp'] - 3; 角色")
+	if assert.NoError(t, err) {
+		d, ok := vm.Ret.ReadDict()
+		if assert.True(t, ok) {
+			hp, _ := d.Load("hp")
+			assert.True(t, valueEqual(hp, ni(7)))
+			d.Store("mp", ni(5))
+			mp, _ := vm.Ret.MustReadDictData().Dict.Load("mp")
+			assert.True(t, valueEqual(mp, ni(5)))
+		}
+	}
+
+	_, ok := ni(1).ReadDict()
+	assert.False(t, ok)
+}
+
+func TestOnValueCreate(t *testing.T) {
+	vm := NewVM()
+	var strs []string
+	count := 0
+	vm.OnValueCreate = func(v *VMValue) {
+		count++
+		if s, ok := v.ReadString(); ok {
+			strs = append(strs, s)
+		}
+	}
+	err := vm.Run("1 + 2")
+	if assert.NoError(t, err) {
+		assert.Equal(t, 3, count) // 1、2、3
+	}
+
+	// 函数体内产生的值也会经过回调
+	strs = nil
+	err = vm.Run("func f(x) { return x + '!' }; f('a')")
+	if assert.NoError(t, err) {
+		assert.Contains(t, strs, "!")
+		assert.Contains(t, strs, "a!")
+	}
+
+	// 读取变量不是新值
+	count = 0
+	vm.StoreName("x", ni(1), false)
+	err = vm.Run("x")
+	if assert.NoError(t, err) {
+		assert.Equal(t, 0, count)
+	}
+
+	// 原生函数的返回值
+	strs = nil
+	err = vm.Run("toStr(12)")
+	if assert.NoError(t, err) {
+		assert.Contains(t, strs, "12")
+	}
+
+	// 标记随值保留，包括赋值后再读取
+	vm.OnValueCreate = func(v *VMValue) {
+		if s, ok := v.ReadString(); ok && s == "secret" {
+			v.Tag = "tainted"
+		}
+	}
+	err = vm.Run("a = 'secret'; a")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "tainted", vm.Ret.Tag)
+		assert.Equal(t, "tainted", vm.Attrs.MustLoad("a").Tag)
+	}
+
+	// 标记不会写入字节码中的常量，多次执行同一字节码时互不影响
+	vm = NewVM()
+	vm.OnValueCreate = func(v *VMValue) {
+		if v.TypeId == VMTypeFunction {
+			n, _ := v.Tag.(int)
+			v.Tag = n + 1
+		}
+	}
+	assert.NoError(t, vm.Parse("func f() { 1 }; f"))
+	for i := 0; i < 3; i++ {
+		if assert.NoError(t, vm.RunAfterParsed()) {
+			assert.Equal(t, 1, vm.Ret.Tag)
+		}
+	}
+
+	// 原生函数返回的已有的值不是新值
+	vm = NewVM()
+	assert.NoError(t, vm.Run("a = [1, 2]; func g(v) { v == 2 }"))
+	vm.OnValueCreate = func(v *VMValue) {
+		v.Tag = "new"
+	}
+	err = vm.Run("a.find(g)")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, ni(2)))
+		assert.Nil(t, vm.Ret.Tag)
+		assert.Nil(t, vm.Attrs.MustLoad("a").MustReadArray().List[1].Tag)
+	}
+
+	vm.OnValueCreate = nil
+	err = vm.Run("1 + 2")
+	assert.NoError(t, err)
+}
+
+func TestBoolValue(t *testing.T) {
+	vm := NewVM()
+	err := vm.Run("[true, 1 < 2, (3 > 2) + (4 > 1), true == 1, -true, toInt(false), toBool('')]")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, na(nb(true), nb(true), ni(2), nb(true), ni(-1), ni(0), nb(false))))
+		assert.Equal(t, "[true, true, 2, true, -1, 0, false]", vm.Ret.ToString())
+	}
+
+	// 与整数可以区分
+	b, ok := nb(true).ReadBool()
+	assert.True(t, ok && b)
+	_, ok = ni(1).ReadBool()
+	assert.False(t, ok)
+	assert.Equal(t, "bool", nb(false).GetTypeName())
+
+	// true 与 1 是同一个键
+	vm = NewVM()
+	err = vm.Run("m = {}; m[true] = 'a'; [m[1], [1, 2].indexOf(true)]")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, na(ns("a"), ni(0))))
+	}
+
+	// 下标、骰子个数等数字位置按数字处理
+	vm = NewVM()
+	err = vm.Run("a = [1, 2]; a[1 > 0] = 3; s = 'ab'; [a[1 > 0], s[true], a[false:true], d(2 > 1)]")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, na(ni(3), ns("b"), na(ni(1)), ni(1))))
+	}
+	err = vm.Run("(1 > 0)d6")
+	if assert.NoError(t, err) {
+		n := vm.Ret.MustReadInt()
+		assert.True(t, n >= 1 && n <= 6)
+	}
+
+	// 数值函数接受布尔值，字典字面量中的 true 不是变量名
+	vm = NewVM()
+	err = vm.Run("[abs(true), floor(1 > 0), ceil(true), round(false), {true: 'a'}[1]]")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, na(ni(1), ni(1), ni(1), ni(0), ns("a"))))
+	}
+	assert.Equal(t, IntType(1), nb(true).MustReadInt())
+
+	// 兼容旧版，布尔值转为整数
+	vm = NewVM()
+	vm.Config.Compat.IntBool = true
+	err = vm.Run("[1 < 2, !1, true, 'a' in 'ab']")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, na(ni(1), ni(0), ni(1), ni(1))))
+	}
+	err = vm.Run("3 > 2")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "1", vm.Ret.ToString())
+	}
+
+	data, err := nb(true).ToJSON()
+	if assert.NoError(t, err) {
+		v, err := VMValueFromJSON(data)
+		if assert.NoError(t, err) {
+			assert.True(t, valueEqual(v, nb(true)))
+		}
+	}
+	x, err := As[bool](nb(true))
+	assert.NoError(t, err)
+	assert.True(t, x)
+}
+
+func TestFormatFloat(t *testing.T) {
+	assert.Equal(t, "6", nf(6).ToString())
+	assert.Equal(t, "-3", nf(-3).ToString())
+	assert.Equal(t, "2.5", nf(2.5).ToString())
+	assert.Equal(t, "1.5e+22", nf(1.5e22).ToString())
+	assert.Equal(t, "1e-10", nf(1e-10).ToString())
+	assert.Equal(t, "0.000001", nf(1e-6).ToString())
+	assert.Equal(t, "123456789012345680000", nf(123456789012345678901).ToString())
+}
@@ -0,0 +1,86 @@
+package dicescript
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// TupleData 元组，创建后不可修改，可以作为字典的键，如 (十位, 个位)
+type TupleData struct {
+	List []*VMValue
+}
+
+// NewTupleVal 创建元组
+func NewTupleVal(values ...*VMValue) *VMValue {
+	data := make([]*VMValue, len(values))
+	copy(data, values)
+	return &VMValue{TypeId: VMTypeTuple, Value: &TupleData{data}}
+}
+
+func (v *VMValue) ReadTuple() (*TupleData, bool) {
+	if v.TypeId == VMTypeTuple {
+		return v.Value.(*TupleData), true
+	}
+	return nil, false
+}
+
+func (td *TupleData) toStringRaw(ri *recursionInfo) string {
+	items := make([]string, len(td.List))
+	for i, item := range td.List {
+		items[i] = item.toReprRaw(ri)
+	}
+	if len(items) == 1 {
+		// 与带括号的普通表达式区分
+		return "(" + items[0] + ",)"
+	}
+	return "(" + strings.Join(items, ", ") + ")"
+}
+
+// readSequence 数组或元组的各项
+func (v *VMValue) readSequence() ([]*VMValue, bool) {
+	switch v.TypeId {
+	case VMTypeArray:
+		return v.Value.(*ArrayData).List, true
+	case VMTypeTuple:
+		return v.Value.(*TupleData).List, true
+	}
+	return nil, false
+}
+
+// unpack 解构赋值 (a, b) = x，x 必须为项数相同的元组或数组
+func (v *VMValue) unpack(ctx *Context, num int) []*VMValue {
+	items, ok := v.readSequence()
+	if !ok {
+		ctx.Error = fmt.Errorf("类型错误: 只有元组和数组可以解构，不支持 %s", v.GetTypeName())
+		return nil
+	}
+	if len(items) != num {
+		ctx.Error = fmt.Errorf("值错误: 需要 %d 项，但有 %d 项", num, len(items))
+		return nil
+	}
+	return items
+}
+
+func funcTuple(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
+	if params[0].TypeId == VMTypeTuple {
+		return params[0]
+	}
+	lst := ctx.iterToList(params[0])
+	if ctx.Error != nil {
+		return nil
+	}
+	return NewTupleVal(lst...)
+}
+
+func funcTupleLen(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
+	td, _ := this.ReadTuple()
+	return NewIntVal(IntType(len(td.List)))
+}
+
+func funcTupleList(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
+	td, _ := this.ReadTuple()
+	return NewArrayVal(td.List...)
+}
+
+var errTupleImmutable = errors.New("类型错误: 元组不能修改")
@@ -0,0 +1,85 @@
+package dicescript
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTuple(t *testing.T) {
+	vm := NewVM()
+	err := vm.Run("[(1, 2), (1,), (), (1), (1 + 2, 'a')]")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, na(
+			NewTupleVal(ni(1), ni(2)),
+			NewTupleVal(ni(1)),
+			NewTupleVal(),
+			ni(1),
+			NewTupleVal(ni(3), ns("a")),
+		)))
+	}
+
+	vm = NewVM()
+	err = vm.Run("t = (1, 2, 3); [t[0], t[-1], t[1:], t.len(), t + (4,), `{(1,)}`, t == (1, 2, 3), t == [1, 2, 3]]")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, na(
+			ni(1), ni(3), NewTupleVal(ni(2), ni(3)), ni(3),
+			NewTupleVal(ni(1), ni(2), ni(3), ni(4)), ns("(1,)"), nb(true), nb(false),
+		)))
+	}
+
+	vm = NewVM()
+	err = vm.Run("s = 0; for i in (1, 2, 3) { s = s + i }; [s, tuple([1, 2]), (1, 2).list()]")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, na(ni(6), NewTupleVal(ni(1), ni(2)), na(ni(1), ni(2)))))
+	}
+}
+
+func TestTupleImmutable(t *testing.T) {
+	vm := NewVM()
+	err := vm.Run("t = (1, 2); t[0] = 3")
+	assert.ErrorIs(t, err, errTupleImmutable)
+}
+
+func TestTupleUnpack(t *testing.T) {
+	vm := NewVM()
+	err := vm.Run("(十位, 个位) = (3, 4); 十位 * 10 + 个位")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, ni(34)))
+	}
+
+	vm = NewVM()
+	err = vm.Run("(a, b) = [1, 2]; (a, b) = (b, a); [a, b]")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, na(ni(2), ni(1))))
+	}
+
+	vm = NewVM()
+	err = vm.Run("(a, b) = (1, 2, 3)")
+	assert.Error(t, err)
+
+	vm = NewVM()
+	err = vm.Run("(a, b) = 1")
+	assert.Error(t, err)
+}
+
+func TestTupleJSON(t *testing.T) {
+	v := NewTupleVal(ni(1), ns("a"))
+	data, err := v.ToJSON()
+	if assert.NoError(t, err) {
+		v2, err := VMValueFromJSON(data)
+		if assert.NoError(t, err) {
+			assert.True(t, valueEqual(v, v2))
+		}
+	}
+
+	plain, err := v.ToPlainJSON()
+	if assert.NoError(t, err) {
+		assert.Equal(t, `[1,"a"]`, string(plain))
+	}
+
+	lst, err := As[[]any](v)
+	if assert.NoError(t, err) {
+		assert.Equal(t, []any{int64(1), "a"}, lst)
+	}
+}
@@ -7,6 +7,8 @@ package dicescript
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
+	"sort"
 	"sync"
 	"sync/atomic"
 	"unsafe"
@@ -60,6 +62,11 @@ type ValueMap struct {
 	// map, the dirty map will be promoted to the read map (in the unamended
 	// state) and the next store to the map will make a new dirty copy.
 	misses int
+
+	// 以下为本项目添加: 记录键的插入顺序，Range 按插入顺序遍历，使 ToString、ToJSON 的结果稳定。
+	// 写入操作都在 orderMu 下进行，以保证顺序与实际写入一致
+	orderMu sync.Mutex
+	keys    []string
 }
 
 // readOnly is an immutable struct stored atomically in the Map.read field.
@@ -129,16 +136,15 @@ func (m *ValueMap) Load(key string) (value *VMValue, ok bool) {
 }
 
 func (m *ValueMap) Length() int {
-	read, _ := m.read.Load().(readOnlyValueMap)
-	if read.amended {
-		m.mu.Lock()
-		defer m.mu.Unlock()
-		return len(m.dirty)
-	}
-	return len(read.m)
+	m.orderMu.Lock()
+	defer m.orderMu.Unlock()
+	return len(m.keys)
 }
 
 func (m *ValueMap) Clear() {
+	m.orderMu.Lock()
+	defer m.orderMu.Unlock()
+	m.keys = nil
 	read, _ := m.read.Load().(readOnlyValueMap)
 	if len(read.m) == 0 && !read.amended {
 		// Avoid allocating a new readOnly when the map is already clear.
@@ -170,8 +176,8 @@ func (e *entryValueMap) load() (value *VMValue, ok bool) {
 	return *(**VMValue)(p), true
 }
 
-// Store sets the value for a key.
-func (m *ValueMap) Store(key string, value *VMValue) {
+// store sets the value for a key.
+func (m *ValueMap) store(key string, value *VMValue) {
 	read, _ := m.read.Load().(readOnlyValueMap)
 	if e, ok := read.m[key]; ok && e.tryStore(&value) {
 		return
@@ -231,10 +237,10 @@ func (e *entryValueMap) storeLocked(i **VMValue) {
 	atomic.StorePointer(&e.p, unsafe.Pointer(i))
 }
 
-// LoadOrStore returns the existing value for the key if present.
+// loadOrStore returns the existing value for the key if present.
 // Otherwise, it stores and returns the given value.
 // The loaded result is true if the value was loaded, false if stored.
-func (m *ValueMap) LoadOrStore(key string, value *VMValue) (actual *VMValue, loaded bool) {
+func (m *ValueMap) loadOrStore(key string, value *VMValue) (actual *VMValue, loaded bool) {
 	// Avoid locking if it's a clean hit.
 	read, _ := m.read.Load().(readOnlyValueMap)
 	if e, ok := read.m[key]; ok {
@@ -301,9 +307,9 @@ func (e *entryValueMap) tryLoadOrStore(i *VMValue) (actual *VMValue, loaded, ok
 	}
 }
 
-// LoadAndDelete deletes the value for a key, returning the previous value if any.
+// loadAndDelete deletes the value for a key, returning the previous value if any.
 // The loaded result reports whether the key was present.
-func (m *ValueMap) LoadAndDelete(key string) (value *VMValue, loaded bool) {
+func (m *ValueMap) loadAndDelete(key string) (value *VMValue, loaded bool) {
 	read, _ := m.read.Load().(readOnlyValueMap)
 	e, ok := read.m[key]
 	if !ok && read.amended {
@@ -376,7 +382,15 @@ func (m *ValueMap) Range(f func(key string, value *VMValue) bool) {
 		m.mu.Unlock()
 	}
 
-	for k, e := range read.m {
+	// 按插入顺序遍历开始时已有的键
+	m.orderMu.Lock()
+	keys := append([]string(nil), m.keys...)
+	m.orderMu.Unlock()
+	for _, k := range keys {
+		e, ok := read.m[k]
+		if !ok {
+			continue
+		}
 		v, ok := e.load()
 		if !ok {
 			continue
@@ -422,6 +436,60 @@ func (e *entryValueMap) tryExpungeLocked() (isExpunged bool) {
 	return p == expungedValueMap
 }
 
+// Store 设置键的值，新的键排在最后
+func (m *ValueMap) Store(key string, value *VMValue) {
+	m.orderMu.Lock()
+	defer m.orderMu.Unlock()
+	if _, ok := m.Load(key); !ok {
+		m.keys = append(m.keys, key)
+	}
+	m.store(key, value)
+}
+
+// LoadOrStore 键存在时返回已有的值，否则存入value并排在最后，loaded表示键是否已存在
+func (m *ValueMap) LoadOrStore(key string, value *VMValue) (actual *VMValue, loaded bool) {
+	m.orderMu.Lock()
+	defer m.orderMu.Unlock()
+	actual, loaded = m.loadOrStore(key, value)
+	if !loaded {
+		m.keys = append(m.keys, key)
+	}
+	return actual, loaded
+}
+
+// LoadAndDelete 删除键并返回原来的值，loaded表示键是否存在。再次存入时排在最后
+func (m *ValueMap) LoadAndDelete(key string) (value *VMValue, loaded bool) {
+	m.orderMu.Lock()
+	defer m.orderMu.Unlock()
+	value, loaded = m.loadAndDelete(key)
+	if loaded {
+		for i, k := range m.keys {
+			if k == key {
+				m.keys = append(m.keys[:i], m.keys[i+1:]...)
+				break
+			}
+		}
+	}
+	return value, loaded
+}
+
+// Keys 按插入顺序返回全部键
+func (m *ValueMap) Keys() []string {
+	var keys []string
+	m.Range(func(key string, value *VMValue) bool {
+		keys = append(keys, key)
+		return true
+	})
+	return keys
+}
+
+// SortKeys 将键按字典序重新排列，此后新的键仍排在最后
+func (m *ValueMap) SortKeys() {
+	m.orderMu.Lock()
+	defer m.orderMu.Unlock()
+	sort.SliceStable(m.keys, func(i, j int) bool { return dictKeyLess(m.keys[i], m.keys[j]) })
+}
+
 func (m *ValueMap) ToJSON() ([]byte, error) {
 	var lst [][]byte
 	var err error
@@ -455,15 +523,31 @@ func (m *ValueMap) ToJSON() ([]byte, error) {
 	return bytes.Join(lst2, []byte("")), nil
 }
 
+// UnmarshalJSON 按json中的顺序读取各个键
 func (m *ValueMap) UnmarshalJSON(input []byte) error {
-	var dict map[string]*VMValue
-	if err := json.Unmarshal(input, &dict); err != nil {
+	dec := json.NewDecoder(bytes.NewReader(input))
+	if t, err := dec.Token(); err != nil {
 		return err
+	} else if t == nil {
+		m.Clear()
+		return nil
+	} else if t != json.Delim('{') {
+		return errors.New("值错误: 字典的json必须为对象")
 	}
 
 	m.Clear()
-	for k, v := range dict {
-		m.Store(k, v)
+	for dec.More() {
+		t, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		key, _ := t.(string)
+		var v *VMValue
+		if err := dec.Decode(&v); err != nil {
+			return err
+		}
+		m.Store(key, v)
 	}
-	return nil
+	_, err := dec.Token()
+	return err
 }
@@ -53,3 +53,38 @@ func TestValueMapClear(t *testing.T) {
 	v.Clear()
 	assert.Equal(t, v.Length(), 0)
 }
+
+func TestValueMapOrder(t *testing.T) {
+	v := ValueMap{}
+	for _, k := range []string{"c", "a", "b", "d"} {
+		v.Store(k, ni(1))
+	}
+	v.Load("a")
+	v.Store("c", ni(2))
+	v.Delete("a")
+	v.LoadOrStore("a", ni(3))
+	v.LoadOrStore("b", ni(4))
+	assert.Equal(t, []string{"c", "b", "d", "a"}, v.Keys())
+	assert.Equal(t, 4, v.Length())
+
+	data, err := v.ToJSON()
+	assert.NoError(t, err)
+	assert.Equal(t, `{"c":{"t":0,"v":2},"b":{"t":0,"v":1},"d":{"t":0,"v":1},"a":{"t":0,"v":3}}`, string(data))
+	v2 := ValueMap{}
+	assert.NoError(t, v2.UnmarshalJSON(data))
+	assert.Equal(t, v.Keys(), v2.Keys())
+
+	v.SortKeys()
+	assert.Equal(t, []string{"a", "b", "c", "d"}, v.Keys())
+}
+
+func TestValueMapOrderPlain(t *testing.T) {
+	v, err := VMValueFromPlainJSON([]byte(`{"z": 1, "y": [true, {"b": 2, "a": null}], "x": 1.5}`))
+	if assert.NoError(t, err) {
+		assert.Equal(t, "{'z': 1, 'y': [true, {'b': 2, 'a': null}], 'x': 1.5}", v.ToString())
+	}
+	v, err = VMValueFromGo(map[string]int{"b": 1, "c": 2, "a": 3})
+	if assert.NoError(t, err) {
+		assert.Equal(t, "{'a': 3, 'b': 1, 'c': 2}", v.ToString())
+	}
+}
@@ -0,0 +1,123 @@
+package dicescript
+
+import "sort"
+
+// engineVersion 引擎版本，随发布更新
+const engineVersion = "0.3.0"
+
+// Version 引擎版本，如 "0.3.0"
+func Version() string {
+	return engineVersion
+}
+
+// features 当前版本支持的特性。新增语法、类型等时在此登记，
+// 使多个版本共存的部署和序列化的数据可以事先确认对方是否支持，而不是在旧版本上报出难以理解的错误
+var features = map[string]bool{
+	// 语法
+	"loops":     true, // while、for in、break、continue
+	"functions": true, // func 定义与调用
+	"generator": true, // 含有yield的生成器函数
+	"computed":  true, // &a = d6 计算类型
+	"fstring":   true, // `{a}` 格式化字符串
+	"roll_mode": true, // max(3d6) min(2d4)
+	"note":      true, // note() 计算过程注释
+	"quiet":     true, // quiet() 隐藏骰点
+	"percent":   true, // 35% 百分数，需开启 EnablePercentLiteral
+
+	// 骰子
+	"dice.coc":     true, // b/p 奖惩骰，需开启 EnableDiceCoC
+	"dice.wod":     true, // XaY 无限规则骰点，需开启 EnableDiceWoD
+	"dice.fate":    true, // f 命运骰，需开启 EnableDiceFate
+	"dice.df":      true, // 4dF 指定个数的命运骰，需开启 EnableDiceFate
+	"dice.dc":      true, // XcY 双十字骰点，需开启 EnableDiceDoubleCross
+	"dice.custom":  true, // 宿主程序注册的自定义骰子
+	"dice.percent": true, // d% 百分骰，需开启 EnablePercentDice
+	"dice.aliases": true, // RuleSet.DiceAliases 骰子后缀别名
+	"dice.faces":   true, // 3d[2,4,8] 以数组为各面的骰子
+
+	// 类型
+	"arrays":   true,
+	"dicts":    true,
+	"tables":   true,
+	"decks":    true,
+	"time":     true, // 时间和时长
+	"quantity": true, // 带单位的数
+	"money":    true, // 金额
+	"check":    true, // 检定结果
+	"order":    true, // 先攻顺序
+	"resource": true, // 有上下限的资源
+
+	// 宿主程序接口
+	"history":     true, // History() 与 lastroll()
+	"reroll":      true, // RerollLast
+	"bounds":      true, // Bounds
+	"moments":     true, // mean() variance()
+	"output":      true, // output() 多项结果
+	"reply":       true, // reply() 与 ReplyTemplateFunc
+	"quota":       true, // QuotaFunc
+	"cost":        true, // ProgramInfo.CostEstimate
+	"code_cache":  true, // CodeCache 字节码缓存
+	"program":     true, // Compile、RunProgram 与 Program.Specialize
+	"compat":      true, // RollConfig.Compat 旧版兼容行为
+	"lenient":     true, // LenientParse 宽松解析
+	"suggest":     true, // ParseError 与 SuggestFixes 修改建议
+	"cse":         true, // EliminateCommonSubexpr 公共子表达式消除
+	"attr_batch":  true, // AttrBatchLoader 批量读取变量
+	"prefetch":    true, // AttrPrefetcher 异步预读变量
+	"await":       true, // await_input 与 Checkpoint、Resume
+	"emit":        true, // emit() 事件
+	"caps":        true, // WithCapabilityTags 与 AllowedCapabilities
+	"mock":        true, // MockNative
+	"dict.order":  true, // 字典保持插入顺序，dict.sortKeys()
+	"dict.keys":   true, // 整数、元组作为字典键
+	"tuple":       true, // 元组与解构赋值
+	"set":         true, // 集合
+	"find":        true, // array.indexOf/find，str.indexOf/count
+	"join":        true, // join()、joinf()
+	"dice.pool":   true, // SortDiceDetail 与 BufferSpan.Pool
+	"value.hook":  true, // Context.OnValueCreate
+	"bool":        true, // 布尔类型，比较运算得到 true/false，0.3.0起。Compat.IntBool 恢复为整数
+	"profile":     true, // WithProfiler
+	"num.limit":   true, // NumberLiteralError
+	"logic":       true, // && 短路求值，一元 !
+	"num.literal": true, // 1e6、1_000、0xFF 形式的数字
+	"bitwise":     true, // << >>，以及 CaretAsXor 开启时的 ^
+	"round.mode":  true, // RollConfig.RoundMode
+	"assign.op":   true, // += -= *= /= %=
+	"karma":       true, // RuleSet.Karma
+	"group_roll":  true, // group_roll()
+	"nullish":     true, // ?? 短路求值
+	"output.hook": true, // Context.OnOutput
+	"in":          true, // x in 数组/字符串/字典/集合
+	"external":    true, // Context.CallExternal 与 RollConfig.TimeLimit
+	"error.value": true, // 错误值，error()、raise()、try()
+	"explode":     true, // 爆炸骰 d6!
+	"assert":      true, // assert()、require_int() 等参数检查
+	"array.drop":  true, // 数组的 dh、dl
+	"match":       true, // match 表达式
+	"range":       true, // 1..20 范围
+	"fmt.number":  true, // fmt_thousands()、fmt_cn()
+	"pool.count":  true, // pool() 与 Array.count 成功数
+	"stringer":    true, // Context.Stringer 本地化显示
+	"eval_all":    true, // eval_all() 批量执行计算类型
+	"session":     true, // Context.NewSession 临时会话
+	"dice.algo":   true, // RegDiceAlgorithm 骰池算法
+	"rolls":       true, // Context.Rolls 骰点记录
+	"interrupt":   true, // Context.Interrupt 中止执行
+	"detail.tree": true, // Context.GetDetailTree 计算过程树
+}
+
+// HasFeature 当前版本是否支持某个特性，如 HasFeature("loops")，特性名见 Features()
+func HasFeature(name string) bool {
+	return features[name]
+}
+
+// Features 当前版本支持的全部特性，按名字排序
+func Features() []string {
+	ret := make([]string, 0, len(features))
+	for name := range features {
+		ret = append(ret, name)
+	}
+	sort.Strings(ret)
+	return ret
+}
@@ -0,0 +1,23 @@
+package dicescript
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVersion(t *testing.T) {
+	assert.Regexp(t, regexp.MustCompile(`^\d+\.\d+\.\d+$`), Version())
+
+	assert.True(t, HasFeature("loops"))
+	assert.True(t, HasFeature("dicts"))
+	assert.False(t, HasFeature("teleport"))
+
+	names := Features()
+	assert.Contains(t, names, "loops")
+	assert.IsNonDecreasing(t, names)
+	for _, name := range names {
+		assert.True(t, HasFeature(name), name)
+	}
+}
@@ -0,0 +1,179 @@
+package dicescript
+
+import (
+	"fmt"
+
+	"golang.org/x/exp/rand"
+)
+
+// Option 创建vm时使用的选项，见 NewVM
+type Option func(ctx *Context)
+
+// AttrProvider 变量储存，用于将脚本中的变量读写交给使用者
+type AttrProvider interface {
+	Load(name string) *VMValue // 不存在时返回nil
+	Store(name string, v *VMValue)
+	Delete(name string)
+}
+
+// AttrBatchLoader AttrProvider 可以额外实现的接口。执行前根据语句中读取的变量名一次性读取，
+// 适用于远程储存的属性，将每个变量一次读取减少为每条语句一次。结果中没有的变量视为不存在
+type AttrBatchLoader interface {
+	LoadBatch(names []string) map[string]*VMValue
+}
+
+// AttrExister AttrProvider 可以额外实现的接口，exists() 检查变量是否存在时使用，而不是读取变量的值。
+// 适用于读取有开销或副作用(如生成默认值)的储存
+type AttrExister interface {
+	Exists(name string) bool
+}
+
+// AttrPrefetcher AttrProvider 可以额外实现的接口，用于异步读取的储存。执行前以语句中读取的变量名调用 Prefetch，
+// provider在后台开始读取并立即返回；执行中需要某个变量时才调用wait，阻塞到该变量读取完成。
+// wait只会在执行语句的goroutine中调用，每个变量至多一次。同时实现 AttrBatchLoader 时只使用 Prefetch
+type AttrPrefetcher interface {
+	Prefetch(names []string) (wait func(name string) *VMValue)
+}
+
+// WithConfig 使用给定的配置，会覆盖排在前面的选项对配置的修改
+func WithConfig(cfg *RollConfig) Option {
+	return func(ctx *Context) {
+		ctx.Config = *cfg
+	}
+}
+
+// WithLimits 设置算力限制
+func WithLimits(limits RunLimits) Option {
+	return func(ctx *Context) {
+		ctx.Config.OpCountLimit = limits.OpCountLimit
+		ctx.Config.ParseExprLimit = limits.ParseExprLimit
+		ctx.Config.TimeLimit = limits.TimeLimit
+	}
+}
+
+// WithQuota 设置算力配额，见 RollConfig.QuotaFunc。同一个fn可以用于多个vm，以限制某个用户的总算力
+func WithQuota(fn func(cost int64) error) Option {
+	return func(ctx *Context) {
+		ctx.Config.QuotaFunc = fn
+	}
+}
+
+// WithCodeCache 使用字节码缓存，见 RollConfig.CodeCache
+func WithCodeCache(cache CodeCache) Option {
+	return func(ctx *Context) {
+		ctx.Config.CodeCache = cache
+	}
+}
+
+// WithProfiler 执行时进行性能分析，结果记录在p中，见 Profiler。分析本身有一定开销，不建议长期开启
+func WithProfiler(p *Profiler) Option {
+	return func(ctx *Context) {
+		ctx.profiler = p
+	}
+}
+
+// WithRandSource 使用给定的随机源
+func WithRandSource(src *rand.PCGSource) Option {
+	return func(ctx *Context) {
+		ctx.RandSrc = src
+	}
+}
+
+// WithAttrProvider 将变量的读取、赋值和删除交给provider，函数内的局部变量不受影响。
+// 赋值时先经过 HookValueStore 与作用域变量的解析器，不会被 WithConfig 等选项覆盖
+func WithAttrProvider(provider AttrProvider) Option {
+	return func(ctx *Context) {
+		ctx.GlobalValueLoadFunc = provider.Load
+		ctx.GlobalValueStoreFunc = provider.Store
+		ctx.GlobalValueDeleteFunc = provider.Delete
+		if exister, ok := provider.(AttrExister); ok {
+			ctx.GlobalValueExistsFunc = exister.Exists
+		}
+		if loader, ok := provider.(AttrBatchLoader); ok {
+			ctx.GlobalValueBatchLoadFunc = loader.LoadBatch
+		}
+		if prefetcher, ok := provider.(AttrPrefetcher); ok {
+			ctx.GlobalValuePrefetchFunc = prefetcher.Prefetch
+		}
+		ctx.storeTopLevelGlobal = true
+	}
+}
+
+// WithBuiltins 注册额外的内置变量/函数，同名时会覆盖默认的内置函数
+func WithBuiltins(values map[string]*VMValue) Option {
+	return func(ctx *Context) {
+		m := map[string]*VMValue{}
+		for k, v := range ctx.builtins {
+			m[k] = v
+		}
+		for k, v := range values {
+			m[k] = v
+		}
+		ctx.builtins = m
+	}
+}
+
+// WithCapabilityTags 为函数标记所需的能力，键为函数名(NativeFunctionData.Name)，如 {"http_get": {CapNet}}。
+// 覆盖默认的标记，如 now() 需要 CapTime。执行时按 RollConfig.AllowedCapabilities 检查
+func WithCapabilityTags(tags map[string][]string) Option {
+	return func(ctx *Context) {
+		m := map[string][]string{}
+		for k, v := range ctx.capTags {
+			m[k] = v
+		}
+		for k, v := range tags {
+			m[k] = v
+		}
+		ctx.capTags = m
+	}
+}
+
+// WithVars 预先设置一组变量，可被脚本读取和覆盖。变量只在每次执行中有效，执行完毕后还原，不会留在 vm.Attrs 中
+func WithVars(vars map[string]*VMValue) Option {
+	return func(ctx *Context) {
+		ctx.addRunVars(vars)
+	}
+}
+
+// WithGoVars 同 WithVars，但使用go中的值，转换规则见 VMValueFromGo。如有无法转换的值，执行时会报错
+func WithGoVars(vars map[string]any) Option {
+	return func(ctx *Context) {
+		m := make(map[string]*VMValue, len(vars))
+		for k, v := range vars {
+			val, err := VMValueFromGo(v)
+			if err != nil {
+				ctx.optionErr = fmt.Errorf("变量 %s: %w", k, err)
+				return
+			}
+			m[k] = val
+		}
+		ctx.addRunVars(m)
+	}
+}
+
+func (ctx *Context) addRunVars(vars map[string]*VMValue) {
+	if ctx.runVars == nil {
+		ctx.runVars = make(map[string]*VMValue, len(vars))
+	}
+	for k, v := range vars {
+		ctx.runVars[k] = v
+	}
+}
+
+// bindRunVars 执行前将 WithVars 设置的变量放入 Attrs，返回的函数在执行完毕后还原
+func (ctx *Context) bindRunVars() func() {
+	olds := make(map[string]*VMValue, len(ctx.runVars))
+	for k, v := range ctx.runVars {
+		olds[k], _ = ctx.Attrs.Load(k)
+		ctx.Attrs.Store(k, v)
+	}
+	return func() {
+		for k, old := range olds {
+			if old != nil {
+				ctx.Attrs.Store(k, old)
+			} else {
+				ctx.Attrs.Delete(k)
+			}
+		}
+	}
+}
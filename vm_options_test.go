@@ -0,0 +1,336 @@
+package dicescript
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/exp/rand"
+)
+
+type testAttrProvider struct {
+	m ValueMap
+}
+
+func (p *testAttrProvider) Load(name string) *VMValue {
+	v, _ := p.m.Load(name)
+	return v
+}
+
+func (p *testAttrProvider) Store(name string, v *VMValue) {
+	p.m.Store(name, v)
+}
+
+func (p *testAttrProvider) Delete(name string) {
+	p.m.Delete(name)
+}
+
+func TestNewVMWithOptions(t *testing.T) {
+	vm := NewVM(WithConfig(&RollConfig{EnableDiceFate: true}), WithLimits(RunLimits{OpCountLimit: 30000}))
+	assert.True(t, vm.Config.EnableDiceFate)
+	assert.Equal(t, IntType(30000), vm.Config.OpCountLimit)
+
+	src := &rand.PCGSource{}
+	vm = NewVM(WithRandSource(src))
+	assert.Same(t, src, vm.RandSrc)
+}
+
+func TestNewVMWithAttrProvider(t *testing.T) {
+	p := &testAttrProvider{}
+	p.m.Store("力量", ni(60))
+
+	vm := NewVM(WithAttrProvider(p))
+	err := vm.Run("func f(x) { y = x; return y * 2 }; 体质 = f(力量); del 力量; [exists('力量'), 体质]")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, na(nb(false), ni(120))))
+	}
+	assert.True(t, valueEqual(p.m.MustLoad("体质"), ni(120)))
+	_, ok := p.m.Load("y")
+	assert.False(t, ok)
+	assert.Equal(t, 0, vm.Attrs.Length())
+}
+
+func TestNewVMWithAttrProviderAndHooks(t *testing.T) {
+	p := &testAttrProvider{}
+	// 排在后面的 WithConfig 不影响provider
+	vm := NewVM(WithAttrProvider(p), WithConfig(&RollConfig{}))
+	var stored []string
+	vm.Config.HookValueStore = func(ctx *Context, name string, v *VMValue) (*VMValue, bool) {
+		stored = append(stored, name)
+		return nil, false
+	}
+	tmp := &ValueMap{}
+	assert.NoError(t, vm.RegScopeResolver(ScopeTemp, &ScopeResolver{
+		Load: func(ctx *Context, name string) *VMValue {
+			v, _ := tmp.Load(name)
+			return v
+		},
+		Store: func(ctx *Context, name string, v *VMValue) {
+			tmp.Store(name, v)
+		},
+	}))
+
+	err := vm.Run("a = 1; $t临时 = 2")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "$t临时"}, stored)
+	assert.True(t, valueEqual(p.m.MustLoad("a"), ni(1)))
+	assert.True(t, valueEqual(tmp.MustLoad("临时"), ni(2)))
+	_, ok := p.m.Load("$t临时")
+	assert.False(t, ok)
+}
+
+type testBatchAttrProvider struct {
+	testAttrProvider
+	loads   []string
+	batches [][]string
+}
+
+func (p *testBatchAttrProvider) Load(name string) *VMValue {
+	p.loads = append(p.loads, name)
+	return p.testAttrProvider.Load(name)
+}
+
+func (p *testBatchAttrProvider) LoadBatch(names []string) map[string]*VMValue {
+	p.batches = append(p.batches, names)
+	ret := map[string]*VMValue{}
+	for _, name := range names {
+		if v, ok := p.m.Load(name); ok {
+			ret[name] = v
+		}
+	}
+	return ret
+}
+
+func TestNewVMWithAttrBatchLoader(t *testing.T) {
+	p := &testBatchAttrProvider{}
+	p.m.Store("力量", ni(60))
+	p.m.Store("敏捷", ni(50))
+
+	vm := NewVM(WithAttrProvider(p))
+	err := vm.Run("(力量+敏捷)/2 + 力量 + (体质 ?? 3) + ceil(1.5)")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, ni(120)))
+	}
+	assert.Equal(t, [][]string{{"力量", "敏捷", "体质", "ceil"}}, p.batches)
+	assert.Empty(t, p.loads)
+
+	// 赋值后重新读取，不使用批量读取的旧值
+	p.batches = nil
+	err = vm.Run("力量 = 力量 + 1; 力量")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, ni(61)))
+	}
+	assert.Equal(t, [][]string{{"力量"}}, p.batches)
+	assert.Equal(t, []string{"力量"}, p.loads)
+
+	// 执行结束后不再使用
+	p.m.Store("敏捷", ni(10))
+	assert.True(t, valueEqual(vm.LoadName("敏捷", false, false), ni(10)))
+}
+
+type testAsyncAttrProvider struct {
+	testAttrProvider
+	prefetched []string
+	waited     []string
+}
+
+func (p *testAsyncAttrProvider) Prefetch(names []string) func(name string) *VMValue {
+	p.prefetched = append(p.prefetched, names...)
+	results := map[string]chan *VMValue{}
+	for _, name := range names {
+		ch := make(chan *VMValue, 1)
+		results[name] = ch
+		go func(name string) {
+			v, _ := p.m.Load(name)
+			ch <- v
+		}(name)
+	}
+	return func(name string) *VMValue {
+		p.waited = append(p.waited, name)
+		return <-results[name]
+	}
+}
+
+func TestNewVMWithAttrPrefetcher(t *testing.T) {
+	p := &testAsyncAttrProvider{}
+	p.m.Store("力量", ni(60))
+	p.m.Store("敏捷", ni(50))
+
+	vm := NewVM(WithAttrProvider(p))
+	err := vm.Run("力量 > 50 ? 力量 + 力量 : 敏捷")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, ni(120)))
+	}
+	assert.Equal(t, []string{"力量", "敏捷"}, p.prefetched)
+	// 只等待实际用到的变量，每个变量一次
+	assert.Equal(t, []string{"力量"}, p.waited)
+
+	p.prefetched, p.waited = nil, nil
+	err = vm.Run("敏捷 = 1; 敏捷 + 体质 ?? 0")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, ni(1)))
+	}
+	assert.Equal(t, []string{"敏捷", "体质"}, p.prefetched)
+	assert.Equal(t, []string{"体质"}, p.waited)
+}
+
+func TestNewVMWithBuiltins(t *testing.T) {
+	double := NewNativeFunctionVal(&NativeFunctionData{
+		Name:   "double",
+		Params: []string{"x"},
+		NativeFunc: func(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
+			return ni(params[0].MustReadInt() * 2)
+		},
+	})
+	vm := NewVM(WithBuiltins(map[string]*VMValue{"double": double, "PI": nf(3.14)}))
+	err := vm.Run("func f() { return double(2) }; [double(3), f(), PI, ceil(1.5)]")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, na(ni(6), ni(4), nf(3.14), ni(2))))
+	}
+
+	err = NewVM().Run("double(3)")
+	assert.Error(t, err)
+}
+
+func TestNewVMWithCapabilityTags(t *testing.T) {
+	fetch := NewNativeFunctionVal(&NativeFunctionData{
+		Name:   "fetch",
+		Params: []string{"url"},
+		NativeFunc: func(ctx *Context, this *VMValue, params []*VMValue) *VMValue {
+			return ns("ok")
+		},
+	})
+	vm := NewVM(
+		WithBuiltins(map[string]*VMValue{"fetch": fetch}),
+		WithCapabilityTags(map[string][]string{"fetch": {CapNet}}),
+	)
+	// 默认不限制
+	assert.NoError(t, vm.Run("fetch('a'); now()"))
+
+	vm.Config.AllowedCapabilities = []string{CapTime}
+	assert.NoError(t, vm.Run("now(); ceil(1.5)"))
+	err := vm.Run("func f() { return fetch('a') }; f()")
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "net")
+	}
+
+	// 单次执行的设置
+	assert.NoError(t, vm.RunWith("fetch('a')", RunOptions{Capabilities: []string{CapNet}}))
+	assert.Error(t, vm.RunWith("now()", RunOptions{Capabilities: []string{}}))
+	assert.Equal(t, []string{CapTime}, vm.Config.AllowedCapabilities)
+}
+
+func TestEvaluate(t *testing.T) {
+	r, err := Evaluate("1 + 2 // 注释", WithLimits(RunLimits{OpCountLimit: 100}))
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(r.Value, ni(3)))
+		assert.Equal(t, "1 + 2", r.Matched)
+		assert.Equal(t, " // 注释", r.RestInput)
+		assert.True(t, r.OpCount > 0)
+	}
+
+	r, err = Evaluate("while 1 {}", WithLimits(RunLimits{OpCountLimit: 100}))
+	assert.Nil(t, r)
+	assert.Error(t, err)
+
+	vm := NewVM()
+	r, err = vm.Evaluate("d1 + 1")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "1 + 1", r.Detail)
+	}
+	_, _ = vm.Evaluate("5")
+	assert.True(t, valueEqual(r.Value, ni(2)))
+}
+
+func TestBounds(t *testing.T) {
+	for _, c := range []struct {
+		expr     string
+		min, max *VMValue
+	}{
+		{"2d6 + 3", ni(5), ni(15)},
+		{"d20优势 + 1.5", nf(2.5), nf(21.5)},
+		{"-d6", ni(-6), ni(-1)},
+		{"10", ni(10), ni(10)},
+	} {
+		min, max, err := Bounds(c.expr)
+		if assert.NoError(t, err, c.expr) {
+			assert.True(t, valueEqual(min, c.min), c.expr)
+			assert.True(t, valueEqual(max, c.max), c.expr)
+		}
+	}
+
+	vm := NewVM()
+	vm.Config.HistorySize = 5
+	vm.Attrs.Store("伤害", ni(4))
+	assert.NoError(t, vm.Run("1"))
+	min, max, err := vm.Bounds("伤害 + 1d8")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(min, ni(5)))
+		assert.True(t, valueEqual(max, ni(12)))
+	}
+	// 不影响vm的配置和执行记录
+	assert.False(t, vm.Config.DiceMaxMode)
+	assert.Len(t, vm.History(), 1)
+
+	_, _, err = Bounds("d6 + 'a'")
+	assert.Error(t, err)
+}
+
+func TestEvaluateWithVars(t *testing.T) {
+	r, err := Evaluate("力量 + 体质", WithVars(map[string]*VMValue{"力量": ni(50), "体质": ni(60)}))
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(r.Value, ni(110)))
+	}
+
+	// 只在本次执行中有效
+	_, err = Evaluate("力量 + 1", WithConfig(&RollConfig{StrictUndefined: true}))
+	assert.Error(t, err)
+
+	r, err = Evaluate("[hp, name, ok, rate, items[1], info.lv, nothing]", WithGoVars(map[string]any{
+		"hp":      int64(12),
+		"name":    "Alice",
+		"ok":      true,
+		"rate":    float32(0.5),
+		"items":   []string{"剑", "盾"},
+		"info":    map[string]int{"lv": 3},
+		"nothing": nil,
+	}))
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(r.Value, na(ni(12), ns("Alice"), nb(true), nf(0.5), ns("盾"), ni(3), NewNullVal())))
+	}
+
+	_, err = Evaluate("1", WithGoVars(map[string]any{"ch": make(chan int)}))
+	assert.Error(t, err)
+
+	// 复用vm时每次执行都能读取，但不会写入 vm.Attrs
+	vm := NewVM(WithVars(map[string]*VMValue{"力量": ni(50)}))
+	vm.Attrs.Store("体质", ni(60))
+	err = vm.Run("力量 = 力量 + 1; 体质 = 体质 + 1; 力量")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, ni(51)))
+	}
+	err = vm.Run("力量")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, ni(50)))
+	}
+	_, ok := vm.Attrs.Load("力量")
+	assert.False(t, ok)
+	assert.True(t, valueEqual(vm.Attrs.MustLoad("体质"), ni(61)))
+}
+
+func TestEvalAllBatchLoad(t *testing.T) {
+	p := &testBatchAttrProvider{}
+	p.m.Store("体质", ni(60))
+	p.m.Store("体型", ni(40))
+	p.m.Store("意志", ni(50))
+	p.m.Store("hp", NewComputedVal("(体质 + 体型) / 10"))
+	p.m.Store("mp", NewComputedVal("意志 / 5 + 体质 * 0"))
+
+	vm := NewVM(WithAttrProvider(p))
+	err := vm.Run("eval_all([&hp, &mp, 3])")
+	if assert.NoError(t, err) {
+		assert.True(t, valueEqual(vm.Ret, na(ni(10), ni(10), ni(3))))
+	}
+	// 各个computed用到的变量一次读取
+	assert.Equal(t, [][]string{{"eval_all", "hp", "mp"}, {"体质", "体型", "意志"}}, p.batches)
+	assert.Empty(t, p.loads)
+}